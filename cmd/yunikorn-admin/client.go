@@ -0,0 +1,115 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// Client for the core web service. The responses are decoded into the same types the REST handlers encode.
+type client struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+func newClient(endpoint string) *client {
+	return &client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Get the path and decode the response into the value.
+func (c *client) get(path string, query url.Values, value interface{}) error {
+	return c.do(http.MethodGet, path, query, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(value)
+	})
+}
+
+// Get the path and return the raw response.
+func (c *client) getRaw(path string, query url.Values) ([]byte, error) {
+	var out []byte
+	err := c.do(http.MethodGet, path, query, func(body io.Reader) error {
+		var err error
+		out, err = ioutil.ReadAll(body)
+		return err
+	})
+	return out, err
+}
+
+// Get the path that returns a stream of JSON objects, not an array, calling the decode function for each object.
+func (c *client) getStream(path string, decode func(decoder *json.Decoder) error) error {
+	return c.do(http.MethodGet, path, nil, func(body io.Reader) error {
+		decoder := json.NewDecoder(body)
+		for decoder.More() {
+			if err := decode(decoder); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Post to the path, the actions of the web service do not have a body.
+func (c *client) post(path string, query url.Values) error {
+	return c.do(http.MethodPost, path, query, func(io.Reader) error {
+		return nil
+	})
+}
+
+func (c *client) do(method, path string, query url.Values, handle func(body io.Reader) error) error {
+	target := c.endpoint + path
+	if len(query) != 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+	if err = handle(resp.Body); err != nil {
+		return fmt.Errorf("invalid response from %s: %v", path, err)
+	}
+	return nil
+}
+
+// Get the error from the response: the web service returns the error details as JSON.
+func decodeError(resp *http.Response) error {
+	apiError := &dao.YAPIError{}
+	if err := json.NewDecoder(resp.Body).Decode(apiError); err != nil || apiError.Description == "" {
+		return fmt.Errorf("request failed: %s", resp.Status)
+	}
+	return fmt.Errorf("request failed: %s: %s", resp.Status, apiError.Description)
+}
@@ -0,0 +1,233 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+type admin struct {
+	client *client
+	out    io.Writer
+}
+
+func newAdmin(endpoint string, out io.Writer) *admin {
+	return &admin{
+		client: newClient(endpoint),
+		out:    out,
+	}
+}
+
+// Run the command with its arguments.
+func (a *admin) run(command string, args []string) error {
+	var expected int
+	var run func() error
+	switch command {
+	case "queues":
+		run = a.listQueues
+	case "apps":
+		run = a.listApplications
+	case "nodes":
+		run = a.listNodes
+	case "config":
+		run = a.showConfig
+	case "dump":
+		run = a.dumpState
+	case "validate":
+		expected = 1
+		run = func() error { return a.validateConfig(args[0]) }
+	case "pause":
+		expected = 1
+		run = func() error { return a.setPaused(args[0], true) }
+	case "resume":
+		expected = 1
+		run = func() error { return a.setPaused(args[0], false) }
+	case "kill":
+		expected = 2
+		run = func() error { return a.killApplication(args[0], args[1]) }
+	default:
+		return fmt.Errorf("unknown command %s", command)
+	}
+	if len(args) != expected {
+		return fmt.Errorf("command %s expects %d argument(s), got %d", command, expected, len(args))
+	}
+	return run()
+}
+
+func (a *admin) listQueues() error {
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tQUEUE\tSTATUS\tUSED\tMAX")
+	var printQueue func(partition string, queue *dao.QueueDAOInfo, depth int)
+	printQueue = func(partition string, queue *dao.QueueDAOInfo, depth int) {
+		fmt.Fprintf(w, "%s\t%s%s\t%s\t%s\t%s\n", partition, strings.Repeat("  ", depth), queue.QueueName,
+			queue.Status, queue.Capacities.UsedCapacity, queue.Capacities.MaxCapacity)
+		for i := range queue.ChildQueues {
+			printQueue(partition, &queue.ChildQueues[i], depth+1)
+		}
+	}
+	err := a.client.getStream("/ws/v1/queues", func(decoder *json.Decoder) error {
+		partition := &dao.PartitionDAOInfo{}
+		if err := decoder.Decode(partition); err != nil {
+			return err
+		}
+		for i := range partition.Queues {
+			printQueue(partition.PartitionName, &partition.Queues[i], 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (a *admin) listApplications() error {
+	var apps []*dao.ApplicationDAOInfo
+	if err := a.client.get("/ws/v1/apps", nil, &apps); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tAPPLICATION\tQUEUE\tSTATE\tALLOCATIONS\tUSED")
+	for _, app := range apps {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", app.Partition, app.ApplicationID, app.QueueName, app.State,
+			len(app.Allocations), app.UsedResource)
+	}
+	return w.Flush()
+}
+
+func (a *admin) listNodes() error {
+	var partitions []*dao.NodesDAOInfo
+	if err := a.client.get("/ws/v1/nodes", nil, &partitions); err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PARTITION\tNODE\tSCHEDULABLE\tCAPACITY\tALLOCATED\tAVAILABLE")
+	for _, partition := range partitions {
+		for _, node := range partition.Nodes {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\n", partition.PartitionName, node.NodeID, node.Schedulable,
+				node.Capacity, node.Allocated, node.Available)
+		}
+	}
+	return w.Flush()
+}
+
+func (a *admin) showConfig() error {
+	out, err := a.client.getRaw("/ws/v1/config", nil)
+	if err != nil {
+		return err
+	}
+	_, err = a.out.Write(out)
+	return err
+}
+
+// Dump the state of the scheduler as used for replication to a standby instance.
+func (a *admin) dumpState() error {
+	state := &cache.ReplicatedState{}
+	if err := a.client.get("/ws/v1/replication/state", nil, state); err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(a.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(state)
+}
+
+// Validate the configuration file locally, using the same checks as the scheduler when the configuration is loaded.
+func (a *admin) validateConfig(file string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if _, err = configs.LoadSchedulerConfigFromByteArray(content); err != nil {
+		return fmt.Errorf("configuration %s is not valid: %v", file, err)
+	}
+	fmt.Fprintf(a.out, "configuration %s is valid\n", file)
+	return nil
+}
+
+func (a *admin) setPaused(partition string, paused bool) error {
+	name, err := a.resolvePartition(partition)
+	if err != nil {
+		return err
+	}
+	path, action := "/ws/v1/partitions/resume", "resumed"
+	if paused {
+		path, action = "/ws/v1/partitions/pause", "paused"
+	}
+	if err = a.client.post(path, url.Values{"partition": {name}}); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "partition %s %s\n", name, action)
+	return nil
+}
+
+func (a *admin) killApplication(partition, appID string) error {
+	name, err := a.resolvePartition(partition)
+	if err != nil {
+		return err
+	}
+	if err = a.client.post("/ws/v1/apps/kill", url.Values{"partition": {name}, "application": {appID}}); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "application %s in partition %s killed\n", appID, name)
+	return nil
+}
+
+// Get the full partition name for a partition given with or without the cluster ID.
+func (a *admin) resolvePartition(partition string) (string, error) {
+	var names []string
+	err := a.client.getStream("/ws/v1/queues", func(decoder *json.Decoder) error {
+		info := &dao.PartitionDAOInfo{}
+		if err := decoder.Decode(info); err != nil {
+			return err
+		}
+		names = append(names, info.PartitionName)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, name := range names {
+		if name == partition {
+			return name, nil
+		}
+		if common.GetPartitionNameWithoutClusterID(name) == partition {
+			found = append(found, name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("partition %s not found", partition)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("partition %s is not unique, use one of %s", partition, strings.Join(found, ", "))
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+var (
+	endpoint = flag.String("endpoint", "http://localhost:9080", "YuniKorn web service endpoint")
+	verbose  = flag.Bool("verbose", false, "show the log messages of the scheduler code used locally, like config validation")
+)
+
+const usage = `Usage: yunikorn-admin [flags] <command> [arguments]
+
+Commands:
+  queues                          list the queues of all partitions
+  apps                            list the applications of all partitions
+  nodes                           list the nodes of all partitions
+  config                          show the configuration used by the scheduler
+  validate <file>                 validate a scheduler configuration file
+  dump                            dump the scheduler state as JSON
+  pause <partition>               stop scheduling in the partition
+  resume <partition>              resume scheduling in the partition
+  kill <partition> <application>  kill the application and release its allocations
+
+Partitions can be given with or without the cluster ID, for example "default" or "[rm-1]default".
+
+Flags:
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	// the errors are reported by the commands, the logging of the scheduler code is only needed to debug
+	if !*verbose {
+		log.InitAndSetLevel(zapcore.FatalLevel)
+	}
+	admin := newAdmin(*endpoint, os.Stdout)
+	if err := admin.run(flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "yunikorn-admin: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
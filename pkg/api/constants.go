@@ -29,6 +29,9 @@ const (
 	FailureDomainRegion = "si.io/region"
 	LocalImages         = "si.io/local-images"
 	NodePartition       = "si.io/node-partition"
+	NodeTaints          = "si.io/taints" // comma separated list of taints: key=value:effect, the value is optional
+	// digest of the allocations on the node confirmed by the RM, see GetAllocationDigest
+	NodeAllocationDigest = "si.io/allocation-digest"
 )
 
 // Constants for allocation attribtues
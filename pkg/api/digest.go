@@ -0,0 +1,52 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Get the digest of the allocations on a node as set by the RM in the NodeAllocationDigest attribute of a node
+// update. The digest is the number of allocations and the hex encoded sha256 of the sorted allocation UUIDs joined
+// by a comma, separated by a colon: 2:9f86d08...
+func GetAllocationDigest(uuids []string) string {
+	sorted := make([]string, len(uuids))
+	copy(sorted, uuids)
+	sort.Strings(sorted)
+	hash := sha256.Sum256([]byte(strings.Join(sorted, ",")))
+	return strconv.Itoa(len(sorted)) + ":" + hex.EncodeToString(hash[:])
+}
+
+// Get the number of allocations from the digest, returns an error if the digest is not valid.
+func ParseAllocationDigest(digest string) (int, error) {
+	parts := strings.Split(digest, ":")
+	if len(parts) != 2 || len(parts[1]) != 2*sha256.Size {
+		return 0, fmt.Errorf("invalid allocation digest: %s", digest)
+	}
+	count, err := strconv.Atoi(parts[0])
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid allocation count in digest: %s", digest)
+	}
+	return count, nil
+}
@@ -0,0 +1,42 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestAllocationDigest(t *testing.T) {
+	digest := GetAllocationDigest([]string{"uuid-2", "uuid-1"})
+	assert.Equal(t, digest, GetAllocationDigest([]string{"uuid-1", "uuid-2"}), "digest should not depend on the order")
+	assert.Assert(t, digest != GetAllocationDigest([]string{"uuid-1", "uuid-3"}), "digest should depend on the allocations")
+	count, err := ParseAllocationDigest(digest)
+	assert.NilError(t, err, "digest should be valid")
+	assert.Equal(t, count, 2, "unexpected allocation count")
+	count, err = ParseAllocationDigest(GetAllocationDigest(nil))
+	assert.NilError(t, err, "empty digest should be valid")
+	assert.Equal(t, count, 0, "unexpected allocation count")
+
+	for _, invalid := range []string{"", "2", "x:" + digest[2:], "-1:" + digest[2:], "2:abc", digest + ":1"} {
+		_, err = ParseAllocationDigest(invalid)
+		assert.Assert(t, err != nil, "digest '%s' should be invalid", invalid)
+	}
+}
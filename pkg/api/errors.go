@@ -0,0 +1,85 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The kinds of errors returned by the cache and the scheduler that callers need to handle programmatically.
+// Errors of a kind are created with NewError or WrapError, use IsError to check the kind of an error.
+var (
+	ErrQueueNotFound = errors.New("queue not found")
+	ErrOverQueueMax  = errors.New("queue over maximum resource")
+	ErrInvalidState  = errors.New("invalid state")
+	// the update responses requested for replay are no longer buffered, the RM must register again to resync
+	ErrReplayUnavailable = errors.New("replay unavailable")
+)
+
+// All known kinds of errors, in the order they are checked.
+var errorKinds = []error{ErrQueueNotFound, ErrOverQueueMax, ErrInvalidState, ErrReplayUnavailable}
+
+// An error of a known kind with the context of the failure. The error text is the context message only.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func NewError(kind error, format string, args ...interface{}) *Error {
+	return &Error{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Add context to the error: "context: error". The kind of the error, if any, is kept.
+func WrapError(err error, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...) + ": " + err.Error()
+	if kind := GetErrorKind(err); kind != nil {
+		return &Error{
+			Kind:    kind,
+			Message: message,
+		}
+	}
+	return errors.New(message)
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// Check if the error is of the kind. A rejection error is of the kind that matches its rejection code.
+func IsError(err, kind error) bool {
+	return errors.Is(err, kind)
+}
+
+// Get the kind of the error, nil if the error is not of a known kind.
+func GetErrorKind(err error) error {
+	for _, kind := range errorKinds {
+		if IsError(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
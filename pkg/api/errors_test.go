@@ -0,0 +1,54 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestErrorKind(t *testing.T) {
+	err := NewError(ErrOverQueueMax, "allocation puts queue %s over maximum", "root.a")
+	assert.Equal(t, err.Error(), "allocation puts queue root.a over maximum", "error text should be the message only")
+	assert.Assert(t, IsError(err, ErrOverQueueMax), "error should be of its kind")
+	assert.Assert(t, !IsError(err, ErrQueueNotFound), "error should not be of another kind")
+	assert.Equal(t, GetErrorKind(err), ErrOverQueueMax, "unexpected kind")
+	assert.Equal(t, GetRejectionCode(err), RejectionQuotaExceeded, "unexpected code for over max error")
+
+	// wrapping keeps the kind and adds the context
+	wrapped := WrapError(err, "cannot allocate for application %s", "app-1")
+	assert.Equal(t, wrapped.Error(), "cannot allocate for application app-1: allocation puts queue root.a over maximum", "unexpected wrapped text")
+	assert.Assert(t, IsError(wrapped, ErrOverQueueMax), "wrapped error should keep the kind")
+	assert.Equal(t, FormatRejectionReason(wrapped), "[QUOTA_EXCEEDED] cannot allocate for application app-1: allocation puts queue root.a over maximum", "unexpected formatted reason")
+
+	// plain errors have no kind, also not after wrapping
+	plain := WrapError(fmt.Errorf("something failed"), "context")
+	assert.Equal(t, plain.Error(), "context: something failed", "unexpected wrapped plain text")
+	assert.Assert(t, GetErrorKind(plain) == nil, "plain error should not have a kind")
+	assert.Equal(t, GetRejectionCode(plain), RejectionUnknown, "plain error should have unknown code")
+	assert.Equal(t, GetRejectionCode(NewError(ErrInvalidState, "stopped")), RejectionInvalidState, "unexpected code for invalid state error")
+
+	// rejection errors are of the kind matching the code
+	rejection := NewRejectionError(RejectionQueueNotFound, "failed to find queue %s", "root.a")
+	assert.Assert(t, IsError(rejection, ErrQueueNotFound), "rejection should be of the queue not found kind")
+	assert.Assert(t, !IsError(rejection, ErrInvalidState), "rejection should not be of another kind")
+	assert.Assert(t, GetErrorKind(NewRejectionError(RejectionACLDenied, "denied")) == nil, "rejection without kind should not have a kind")
+}
@@ -18,7 +18,11 @@
 
 package api
 
-import "github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
 
 type SchedulerAPI interface {
 	// Register a new RM, if it is a reconnect from previous RM, cleanup
@@ -37,3 +41,106 @@ type SchedulerAPI interface {
 type ResourceManagerCallback interface {
 	RecvUpdateResponse(response *si.UpdateResponse) error
 }
+
+// Optional scheduler API: replay the update responses sent to the RM after the last sequence the RM processed.
+// Used by an RM that lost responses, like after a short network failure, instead of registering again.
+// The responses up to and including the last sequence are dropped from the buffer. An error of the kind
+// ErrReplayUnavailable is returned if the responses are no longer buffered: the RM must register again to resync.
+type ReplayAPI interface {
+	ReplayUpdateResponses(rmID string, lastSequence uint64) error
+}
+
+// Optional RM side API: the callback registered by the RM can implement this to receive the update responses with
+// a sequence number, it is used instead of RecvUpdateResponse. The sequence starts at 1 when the RM registers and
+// increases by one for each response. Only the responses sent via this callback can be replayed, see ReplayAPI.
+type SequencedUpdateCallback interface {
+	RecvSequencedUpdateResponse(sequence uint64, response *si.UpdateResponse) error
+}
+
+// Optional RM side API: the callback registered by the RM can implement this to be notified before an
+// allocation of a checkpointable application is preempted. The release of the allocation follows after the
+// grace period, unless the RM releases the allocation itself before that.
+type PreemptionNotificationCallback interface {
+	RecvPreemptionNotification(notifications []*PreemptionNotification) error
+}
+
+// The notification of an upcoming preemption of an allocation
+type PreemptionNotification struct {
+	UUID          string
+	ApplicationID string
+	PartitionName string
+	GracePeriod   time.Duration
+	Message       string
+}
+
+// Optional RM side API: the callback registered by the RM can implement this to confirm the preemption of allocations
+// in a partition that has a preemption confirm timeout set. The scheduler does not release the allocation until the
+// RM responded via PreemptionConfirmAPI, or until the timeout passed. This prevents a race with an allocation the RM
+// is about to complete. If the callback does not implement this API all intents are accepted directly.
+type PreemptionIntentCallback interface {
+	RecvPreemptionIntents(intents []*PreemptionIntent) error
+}
+
+// The intent to preempt an allocation, the RM must respond before the timeout passes.
+type PreemptionIntent struct {
+	UUID          string
+	ApplicationID string
+	PartitionName string
+	Resource      *si.Resource
+	Timeout       time.Duration
+	Message       string
+}
+
+// Optional scheduler API: the response of the RM to the preemption intents it received.
+// An error is returned if the RM is not registered, the processing of the responses is asynchronous.
+type PreemptionConfirmAPI interface {
+	ConfirmPreemptions(rmID string, responses []*PreemptionResponse) error
+}
+
+// The response to a preemption intent. An accepted intent releases the allocation. If the intent is not accepted the
+// RM can propose alternates: allocations in the same queue that together use at least the resources of the allocation.
+// The alternates are released instead, if they cannot be used the allocation is released. An intent that is not
+// accepted without alternates means the allocation is about to complete: the scheduler does not release it.
+type PreemptionResponse struct {
+	UUID          string
+	PartitionName string
+	Accepted      bool
+	Alternates    []string
+}
+
+// Optional RM side API: the callback registered by the RM can implement this to be notified when the configuration
+// of a queue changes at runtime. Only the queues that changed are sent: new queues are included and a removed queue
+// is sent with its new state.
+type QueueUpdateCallback interface {
+	RecvQueueUpdate(updates []*QueueUpdate) error
+}
+
+// The configuration of a queue after a change
+type QueueUpdate struct {
+	PartitionName      string
+	QueueName          string
+	State              string
+	MaxResource        *si.Resource
+	GuaranteedResource *si.Resource
+	MaxApplications    uint64
+	Properties         map[string]string
+}
+
+// Optional RM side API: the callback registered by the RM can implement this to be notified when the pending resource
+// of a queue stays above the autoscale watermark of the partition for longer than the threshold. The event is repeated
+// each threshold while the queue stays above the watermark, an autoscaler can act on the events instead of polling.
+type AutoscaleEventCallback interface {
+	RecvAutoscaleEvents(events []*AutoscaleEvent) error
+}
+
+// The pending resource of a leaf queue that stayed above the watermark.
+// The unsatisfiable resource is the part of the pending resource, limited by the queue headroom, that does not fit in
+// the resources available in the partition: the capacity that must be added to schedule the pending requests.
+type AutoscaleEvent struct {
+	PartitionName string
+	QueueName     string
+	Pending       *si.Resource
+	Unsatisfiable *si.Resource
+	Watermark     *si.Resource
+	AboveSince    time.Time
+}
@@ -0,0 +1,107 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The code for the reason an application or allocation ask was rejected.
+// The si rejection messages only carry a reason text: the code is added as a prefix to the human readable text.
+// Shims can use ParseRejectionReason to retrieve the code and the text from the reason.
+type RejectionCode string
+
+const (
+	RejectionUnknown             RejectionCode = "UNKNOWN"
+	RejectionACLDenied           RejectionCode = "ACL_DENIED"
+	RejectionQueueNotFound       RejectionCode = "QUEUE_NOT_FOUND"
+	RejectionQuotaExceeded       RejectionCode = "QUOTA_EXCEEDED"
+	RejectionMaxAllocation       RejectionCode = "MAX_ALLOCATION_EXCEEDED"
+	RejectionInvalidResource     RejectionCode = "INVALID_RESOURCE"
+	RejectionInvalidTolerations  RejectionCode = "INVALID_TOLERATIONS"
+	RejectionInvalidGang         RejectionCode = "INVALID_GANG"
+	RejectionInvalidNodeAge      RejectionCode = "INVALID_NODE_AGE"
+	RejectionInvalidUser         RejectionCode = "INVALID_USER"
+	RejectionPartitionNotFound   RejectionCode = "PARTITION_NOT_FOUND"
+	RejectionPartitionStopped    RejectionCode = "PARTITION_STOPPED"
+	RejectionApplicationNotFound RejectionCode = "APPLICATION_NOT_FOUND"
+	RejectionApplicationExists   RejectionCode = "APPLICATION_EXISTS"
+	RejectionInvalidState        RejectionCode = "INVALID_STATE"
+)
+
+// The rejection codes for the kinds of errors, see errors.go
+var errorKindCodes = map[error]RejectionCode{
+	ErrQueueNotFound: RejectionQueueNotFound,
+	ErrOverQueueMax:  RejectionQuotaExceeded,
+	ErrInvalidState:  RejectionInvalidState,
+}
+
+// An error that carries the rejection code. The error text is the human readable message only.
+type RejectionError struct {
+	Code    RejectionCode
+	Message string
+}
+
+func NewRejectionError(code RejectionCode, format string, args ...interface{}) *RejectionError {
+	return &RejectionError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (re *RejectionError) Error() string {
+	return re.Message
+}
+
+// A rejection error is of the kind that has the same rejection code.
+func (re *RejectionError) Is(kind error) bool {
+	code, ok := errorKindCodes[kind]
+	return ok && code == re.Code
+}
+
+// Get the rejection code for the error. An error that is not a rejection error has the code of its kind, or the
+// unknown code if the error is not of a known kind.
+func GetRejectionCode(err error) RejectionCode {
+	if re, ok := err.(*RejectionError); ok {
+		return re.Code
+	}
+	if code, ok := errorKindCodes[GetErrorKind(err)]; ok {
+		return code
+	}
+	return RejectionUnknown
+}
+
+// Create the reason to send to the RM for the error: "[CODE] message"
+func FormatRejectionReason(err error) string {
+	return fmt.Sprintf("[%s] %s", GetRejectionCode(err), err.Error())
+}
+
+// Split the reason received in a rejection into the code and the human readable message.
+// A reason without a code returns the unknown code and the reason unchanged.
+func ParseRejectionReason(reason string) (RejectionCode, string) {
+	if !strings.HasPrefix(reason, "[") {
+		return RejectionUnknown, reason
+	}
+	end := strings.Index(reason, "] ")
+	if end == -1 {
+		return RejectionUnknown, reason
+	}
+	return RejectionCode(reason[1:end]), reason[end+2:]
+}
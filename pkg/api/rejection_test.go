@@ -0,0 +1,49 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestRejectionReason(t *testing.T) {
+	err := NewRejectionError(RejectionACLDenied, "submit access denied on queue %s", "root.a")
+	assert.Equal(t, err.Error(), "submit access denied on queue root.a", "error text should not contain the code")
+	assert.Equal(t, GetRejectionCode(err), RejectionACLDenied, "unexpected code from rejection error")
+	reason := FormatRejectionReason(err)
+	assert.Equal(t, reason, "[ACL_DENIED] submit access denied on queue root.a", "unexpected formatted reason")
+	code, msg := ParseRejectionReason(reason)
+	assert.Equal(t, code, RejectionACLDenied, "unexpected code parsed from reason")
+	assert.Equal(t, msg, "submit access denied on queue root.a", "unexpected message parsed from reason")
+
+	// plain errors are unknown
+	plain := fmt.Errorf("something failed")
+	assert.Equal(t, GetRejectionCode(plain), RejectionUnknown, "plain error should have unknown code")
+	assert.Equal(t, FormatRejectionReason(plain), "[UNKNOWN] something failed", "unexpected formatted reason for plain error")
+
+	// reasons without a code are returned unchanged
+	for _, reason := range []string{"free text", "[no closing bracket", ""} {
+		code, msg = ParseRejectionReason(reason)
+		assert.Equal(t, code, RejectionUnknown, "reason without code should have unknown code: %s", reason)
+		assert.Equal(t, msg, reason, "reason without code should be returned unchanged")
+	}
+}
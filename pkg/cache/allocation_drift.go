@@ -0,0 +1,54 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+)
+
+// The number of consecutive allocation digests of a node that must not match before the node is reported in drift.
+// A single mismatch is expected while allocations are in flight between the core and the RM.
+const driftThreshold = 2
+
+// The types of allocation drift of a node
+const (
+	DriftRMLost   = "rmLost"   // the core has allocations the RM does not know
+	DriftCoreLost = "coreLost" // the RM has allocations the core does not know
+	DriftMismatch = "mismatch" // the number of allocations is the same but the allocations differ
+)
+
+// The drift between the allocations of a node in the core and the allocations confirmed by the RM.
+type AllocationDrift struct {
+	CoreAllocations int       // number of allocations on the node in the core
+	RMAllocations   int       // number of allocations on the node confirmed by the RM
+	Since           time.Time // time of the first digest that did not match
+	Mismatches      int       // number of consecutive digests that did not match
+}
+
+// Get the type of the drift based on the number of allocations.
+func (ad *AllocationDrift) GetType() string {
+	switch {
+	case ad.CoreAllocations > ad.RMAllocations:
+		return DriftRMLost
+	case ad.CoreAllocations < ad.RMAllocations:
+		return DriftCoreLost
+	default:
+		return DriftMismatch
+	}
+}
@@ -19,6 +19,8 @@
 package cache
 
 import (
+	"time"
+
 	"github.com/apache/incubator-yunikorn-core/pkg/common"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
@@ -33,6 +35,9 @@ type AllocationInfo struct {
 	// Other information
 	ApplicationID     string
 	AllocatedResource *resources.Resource
+
+	// time the allocation was created in the scheduler, zero for allocations recovered from a node
+	createTime time.Time
 }
 
 func NewAllocationInfo(uuid string, alloc *commonevents.AllocationProposal) *AllocationInfo {
@@ -50,7 +55,27 @@ func NewAllocationInfo(uuid string, alloc *commonevents.AllocationProposal) *All
 		},
 		ApplicationID:     alloc.ApplicationID,
 		AllocatedResource: alloc.AllocatedResource,
+		createTime:        time.Now(),
 	}
 
 	return allocation
 }
+
+// Get the time the allocation was created. The time is zero if it is not known.
+func (ai *AllocationInfo) GetCreateTime() time.Time {
+	return ai.createTime
+}
+
+// Is the allocation a placement only allocation: it does not use any resources.
+func (ai *AllocationInfo) IsPlacementOnly() bool {
+	return resources.IsZero(ai.AllocatedResource)
+}
+
+// Is the allocation protected from preemption: it has run for less than the minimum runtime.
+// An allocation without a known creation time is never protected.
+func (ai *AllocationInfo) IsPreemptionProtected(minRuntime time.Duration, now time.Time) bool {
+	if minRuntime <= 0 || ai.createTime.IsZero() {
+		return false
+	}
+	return now.Sub(ai.createTime) < minRuntime
+}
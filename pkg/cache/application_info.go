@@ -20,15 +20,32 @@ package cache
 
 import (
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/looplab/fsm"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 )
 
+const (
+	// Number of state changes kept in the history of an application, the oldest change is dropped first
+	stateHistorySize = 20
+	// Names of the hold, release and update operations in the state history, they do not change the state
+	holdEvent    = "HoldApplication"
+	releaseEvent = "ReleaseApplication"
+	updateEvent  = "UpdateApplication"
+)
+
+// A change of the application state, or a hold or release of the application, as kept in the state history.
+type ApplicationStateChange struct {
+	Time  time.Time
+	Event string
+	State string
+}
+
 /* Related to applications */
 type ApplicationInfo struct {
 	ApplicationID  string
@@ -43,7 +60,13 @@ type ApplicationInfo struct {
 	allocatedResource *resources.Resource        // total allocated resources
 	allocations       map[string]*AllocationInfo // list of all allocations
 	stateMachine      *fsm.FSM                   // application state machine
-	lock              sync.RWMutex
+	startTime         time.Time                  // time the application started running, zero if not running yet
+	reuseHints        []reuseHint                // nodes of recently released allocations, oldest first
+	restored          bool                       // restored from replicated state and not yet sent by the RM
+	held              bool                       // asks are not considered for scheduling while held
+	stateHistory      []ApplicationStateChange   // recent state changes, oldest first
+	events            *partitionEvents           // subscribers to the events of the partition, set when added
+	lock              locking.RWMutex
 }
 
 // Create a new application
@@ -73,6 +96,14 @@ func (ai *ApplicationInfo) GetAllAllocations() []*AllocationInfo {
 	return allocations
 }
 
+// Return the allocation with the UUID, nil if the application does not have the allocation.
+func (ai *ApplicationInfo) GetAllocation(uuid string) *AllocationInfo {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
+	return ai.allocations[uuid]
+}
+
 // Return the current state for the application.
 // The state machine handles the locking.
 func (ai *ApplicationInfo) GetApplicationState() string {
@@ -82,14 +113,118 @@ func (ai *ApplicationInfo) GetApplicationState() string {
 // Handle the state event for the application.
 // The state machine handles the locking.
 func (ai *ApplicationInfo) HandleApplicationEvent(event ApplicationEvent) error {
-	err := ai.stateMachine.Event(event.String(), ai.ApplicationID)
+	err := ai.stateMachine.Event(event.String(), ai.ApplicationID, ai.getQueuePath())
 	// handle the same state transition not nil error (limit of fsm).
 	if err != nil && err.Error() == "no transition" {
 		return nil
 	}
+	if err == nil {
+		ai.recordStateChange(event.String())
+		if event == RunApplication {
+			ai.setStartTime()
+		}
+	}
 	return err
 }
 
+// Put the application on hold or release it. The asks of a held application are not considered for scheduling but
+// stay pending, existing allocations are not affected.
+// An application that has finished cannot be put on hold. Holding a held application, or releasing an application that
+// is not held, is a no-op.
+func (ai *ApplicationInfo) SetHeld(held bool) error {
+	state := ai.stateMachine.Current()
+	if held && (state == Completed.String() || state == Killed.String() || state == Rejected.String()) {
+		return api.NewError(api.ErrInvalidState, "application %s in state %s cannot be put on hold", ai.ApplicationID, state)
+	}
+	ai.lock.Lock()
+	if ai.held == held {
+		ai.lock.Unlock()
+		return nil
+	}
+	ai.held = held
+	ai.lock.Unlock()
+	event := releaseEvent
+	if held {
+		event = holdEvent
+	}
+	ai.recordStateChange(event)
+	return nil
+}
+
+// Return true if the application is on hold, see SetHeld.
+func (ai *ApplicationInfo) IsHeld() bool {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
+	return ai.held
+}
+
+// Add the event and the current state to the state history, the oldest change is dropped if the history is full.
+func (ai *ApplicationInfo) recordStateChange(event string) {
+	change := ApplicationStateChange{
+		Time:  time.Now(),
+		Event: event,
+		State: ai.stateMachine.Current(),
+	}
+	ai.lock.Lock()
+	if len(ai.stateHistory) >= stateHistorySize {
+		ai.stateHistory = ai.stateHistory[1:]
+	}
+	ai.stateHistory = append(ai.stateHistory, change)
+	queueName := ai.QueueName
+	events := ai.events
+	ai.lock.Unlock()
+
+	events.publish(&PartitionEvent{
+		Type:          ApplicationStateChanged,
+		Time:          change.Time,
+		Partition:     ai.Partition,
+		ApplicationID: ai.ApplicationID,
+		QueueName:     queueName,
+		Event:         change.Event,
+		State:         change.State,
+	})
+}
+
+// Return a copy of the state history of the application, oldest change first.
+func (ai *ApplicationInfo) GetStateHistory() []ApplicationStateChange {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
+	history := make([]ApplicationStateChange, len(ai.stateHistory))
+	copy(history, ai.stateHistory)
+	return history
+}
+
+// Record the time the application started running, only the first call sets the time.
+func (ai *ApplicationInfo) setStartTime() {
+	ai.lock.Lock()
+	defer ai.lock.Unlock()
+
+	if ai.startTime.IsZero() {
+		ai.startTime = time.Now()
+	}
+}
+
+// Return the time the application started running, zero if it has not started.
+func (ai *ApplicationInfo) GetStartTime() time.Time {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
+	return ai.startTime
+}
+
+// Return the path of the leaf queue the application runs in, empty if the queue is not set or does not exist.
+func (ai *ApplicationInfo) getQueuePath() string {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
+	if ai.leafQueue == nil {
+		return ""
+	}
+	return ai.leafQueue.GetQueuePath()
+}
+
 // Return the total allocated resources for the application.
 func (ai *ApplicationInfo) GetAllocatedResource() *resources.Resource {
 	ai.lock.RLock()
@@ -153,14 +288,51 @@ func (ai *ApplicationInfo) removeAllAllocations() []*AllocationInfo {
 	return allocationsToRelease
 }
 
+// Return true if the application was restored from replicated state and has not been sent by the RM since.
+// The flag is cleared: only the first call after the restore returns true.
+func (ai *ApplicationInfo) takeRestored() bool {
+	ai.lock.Lock()
+	defer ai.lock.Unlock()
+
+	restored := ai.restored
+	ai.restored = false
+	return restored
+}
+
 // get a copy of the user details for the application
 func (ai *ApplicationInfo) GetUser() security.UserGroup {
 	return ai.user
 }
 
+// Replace the tags of the application with the tags sent by the RM after submission.
+// Returns true if the tags changed, the change is recorded in the state history.
+func (ai *ApplicationInfo) UpdateTags(tags map[string]string) bool {
+	ai.lock.Lock()
+	if len(tags) == len(ai.tags) {
+		changed := false
+		for key, val := range tags {
+			if old, ok := ai.tags[key]; !ok || old != val {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			ai.lock.Unlock()
+			return false
+		}
+	}
+	ai.tags = tags
+	ai.lock.Unlock()
+	ai.recordStateChange(updateEvent)
+	return true
+}
+
 // Get a tag from the application
 // Note: Tags are not case sensitive
 func (ai *ApplicationInfo) GetTag(tag string) string {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
 	tagVal := ""
 	for key, val := range ai.tags {
 		if strings.EqualFold(key, tag) {
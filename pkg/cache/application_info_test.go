@@ -21,6 +21,7 @@ package cache
 import (
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"gotest.tools/assert"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
@@ -224,3 +225,144 @@ func TestKilledTransition(t *testing.T) {
 	assert.Assert(t, err == nil)
 	assert.Equal(t, appInfo.GetApplicationState(), Killed.String())
 }
+
+// Get the value of the queue app metric with the state label from the registered metrics, 0 if not found.
+func getQueueAppMetric(t *testing.T, name, state string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NilError(t, err, "failed to gather metrics")
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != "state" || label.GetValue() != state {
+					continue
+				}
+				if metric.GetCounter() != nil {
+					return metric.GetCounter().GetValue()
+				}
+				return metric.GetGauge().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestQueueAppMetrics(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create root queue")
+	var leaf *QueueInfo
+	leaf, err = createManagedQueue(root, "appmetrics", false)
+	assert.NilError(t, err, "failed to create leaf queue")
+	counters := "yunikorn_queue_root_appmetrics_app_metrics"
+	current := "yunikorn_queue_root_appmetrics_app_current"
+
+	// app without a queue does not update queue metrics
+	appInfo := newApplicationInfo("app-00001", "default", "root.appmetrics")
+	err = appInfo.HandleApplicationEvent(RejectApplication)
+	assert.NilError(t, err, "reject transition failed")
+	assert.Equal(t, getQueueAppMetric(t, counters, "rejected"), float64(0), "rejected app without queue should not be counted")
+
+	// full lifecycle: submitted, accepted and pending, running, completed
+	appInfo = newApplicationInfo("app-00002", "default", "root.appmetrics")
+	appInfo.SetQueue(leaf)
+	err = appInfo.HandleApplicationEvent(AcceptApplication)
+	assert.NilError(t, err, "accept transition failed")
+	assert.Equal(t, getQueueAppMetric(t, counters, "submitted"), float64(1), "unexpected submitted count")
+	assert.Equal(t, getQueueAppMetric(t, counters, "accepted"), float64(1), "unexpected accepted count")
+	assert.Equal(t, getQueueAppMetric(t, current, "pending"), float64(1), "unexpected pending apps")
+	err = appInfo.HandleApplicationEvent(RunApplication)
+	assert.NilError(t, err, "run transition failed")
+	// running again is not a transition
+	err = appInfo.HandleApplicationEvent(RunApplication)
+	assert.NilError(t, err, "run transition failed")
+	assert.Equal(t, getQueueAppMetric(t, counters, "running"), float64(1), "unexpected running count")
+	assert.Equal(t, getQueueAppMetric(t, current, "pending"), float64(0), "unexpected pending apps")
+	assert.Equal(t, getQueueAppMetric(t, current, "running"), float64(1), "unexpected running apps")
+	err = appInfo.HandleApplicationEvent(CompleteApplication)
+	assert.NilError(t, err, "complete transition failed")
+	assert.Equal(t, getQueueAppMetric(t, counters, "completed"), float64(1), "unexpected completed count")
+	assert.Equal(t, getQueueAppMetric(t, current, "running"), float64(0), "unexpected running apps")
+
+	// killed while pending
+	appInfo = newApplicationInfo("app-00003", "default", "root.appmetrics")
+	appInfo.SetQueue(leaf)
+	err = appInfo.HandleApplicationEvent(AcceptApplication)
+	assert.NilError(t, err, "accept transition failed")
+	err = appInfo.HandleApplicationEvent(KillApplication)
+	assert.NilError(t, err, "kill transition failed")
+	assert.Equal(t, getQueueAppMetric(t, counters, "submitted"), float64(2), "unexpected submitted count")
+	assert.Equal(t, getQueueAppMetric(t, counters, "killed"), float64(1), "unexpected killed count")
+	assert.Equal(t, getQueueAppMetric(t, current, "pending"), float64(0), "unexpected pending apps")
+}
+
+func TestStartTime(t *testing.T) {
+	appInfo := newApplicationInfo("app-00001", "default", "root.a")
+	assert.Assert(t, appInfo.GetStartTime().IsZero(), "new application should not have a start time")
+	err := appInfo.HandleApplicationEvent(AcceptApplication)
+	assert.NilError(t, err, "failed to accept application")
+	assert.Assert(t, appInfo.GetStartTime().IsZero(), "accepted application should not have a start time")
+	err = appInfo.HandleApplicationEvent(RunApplication)
+	assert.NilError(t, err, "failed to run application")
+	startTime := appInfo.GetStartTime()
+	assert.Assert(t, !startTime.IsZero(), "running application should have a start time")
+	// running again does not change the start time
+	err = appInfo.HandleApplicationEvent(RunApplication)
+	assert.NilError(t, err, "failed to run application")
+	assert.Equal(t, appInfo.GetStartTime(), startTime, "start time should not change")
+}
+
+func TestHoldApplication(t *testing.T) {
+	appInfo := newApplicationInfo("app-00001", "default", "root.a")
+	err := appInfo.HandleApplicationEvent(AcceptApplication)
+	assert.NilError(t, err, "app should have been accepted")
+	assert.Assert(t, !appInfo.IsHeld(), "new app should not be held")
+
+	err = appInfo.SetHeld(true)
+	assert.NilError(t, err, "accepted app should be put on hold")
+	assert.Assert(t, appInfo.IsHeld(), "app should be held")
+	// holding twice is a no-op and not recorded
+	err = appInfo.SetHeld(true)
+	assert.NilError(t, err, "holding a held app should not fail")
+	err = appInfo.SetHeld(false)
+	assert.NilError(t, err, "held app should be released")
+	assert.Assert(t, !appInfo.IsHeld(), "app should not be held after release")
+
+	history := appInfo.GetStateHistory()
+	assert.Equal(t, len(history), 3, "unexpected number of state changes")
+	assert.Equal(t, history[0].Event, AcceptApplication.String())
+	assert.Equal(t, history[1].Event, holdEvent)
+	assert.Equal(t, history[1].State, Accepted.String())
+	assert.Equal(t, history[2].Event, releaseEvent)
+
+	// a finished app cannot be put on hold
+	err = appInfo.HandleApplicationEvent(KillApplication)
+	assert.NilError(t, err, "app should have been killed")
+	err = appInfo.SetHeld(true)
+	assert.Assert(t, err != nil, "killed app should not be put on hold")
+	assert.Assert(t, !appInfo.IsHeld(), "killed app should not be held")
+
+	// the history is bounded
+	for i := 0; i < stateHistorySize; i++ {
+		appInfo.recordStateChange(holdEvent)
+	}
+	history = appInfo.GetStateHistory()
+	assert.Equal(t, len(history), stateHistorySize, "history should be bounded")
+	assert.Equal(t, history[0].Event, holdEvent, "oldest changes should have been dropped")
+}
+
+func TestUpdateTags(t *testing.T) {
+	appInfo := NewApplicationInfo("app-00001", "default", "root.a", security.UserGroup{}, map[string]string{"first": "value"})
+	assert.Assert(t, !appInfo.UpdateTags(map[string]string{"first": "value"}), "same tags should not be an update")
+	assert.Equal(t, len(appInfo.GetStateHistory()), 0, "unchanged tags should not be recorded")
+
+	assert.Assert(t, appInfo.UpdateTags(map[string]string{"First": "other"}), "changed tags should be an update")
+	assert.Equal(t, appInfo.GetTag("first"), "other", "tag should have been updated")
+	assert.Assert(t, appInfo.UpdateTags(nil), "removed tags should be an update")
+	assert.Equal(t, appInfo.GetTag("first"), "", "tag should have been removed")
+	history := appInfo.GetStateHistory()
+	assert.Equal(t, len(history), 2, "updates should be recorded")
+	assert.Equal(t, history[0].Event, updateEvent)
+	assert.Equal(t, history[0].State, New.String())
+}
@@ -63,6 +63,64 @@ func (as ApplicationState) String() string {
 	return [...]string{"New", "Accepted", "Rejected", "Running", "Completed", "Killed"}[as]
 }
 
+// Update the application lifecycle metrics of the queue for the state transition.
+// The queue path is passed in as the second event argument: no queue metrics are updated if the path is empty.
+// Pending applications are accepted but not running yet.
+func updateQueueAppMetrics(event *fsm.Event) {
+	if len(event.Args) < 2 {
+		return
+	}
+	queuePath, ok := event.Args[1].(string)
+	if !ok || queuePath == "" {
+		return
+	}
+	queueMetrics := metrics.GetQueueMetrics(queuePath)
+	switch event.Src {
+	case New.String():
+		queueMetrics.IncApplicationsSubmitted()
+	case Accepted.String():
+		queueMetrics.DecPendingApplications()
+	case Running.String():
+		queueMetrics.DecRunningApplications()
+	}
+	switch event.Dst {
+	case Accepted.String():
+		queueMetrics.IncApplicationsAccepted()
+		queueMetrics.IncPendingApplications()
+	case Rejected.String():
+		queueMetrics.IncApplicationsRejected()
+	case Running.String():
+		queueMetrics.IncApplicationsRunning()
+		queueMetrics.IncRunningApplications()
+	case Completed.String():
+		queueMetrics.IncApplicationsCompleted()
+	case Killed.String():
+		queueMetrics.IncApplicationsKilled()
+	}
+}
+
+// Move the current number of applications gauges for an application in the state from the source to the target queue.
+// Only pending and running applications are tracked in the gauges, no metrics are updated for an empty queue path.
+func moveQueueAppMetrics(state, source, target string) {
+	var dec, inc func(metrics.CoreQueueMetrics)
+	switch state {
+	case Accepted.String():
+		dec = metrics.CoreQueueMetrics.DecPendingApplications
+		inc = metrics.CoreQueueMetrics.IncPendingApplications
+	case Running.String():
+		dec = metrics.CoreQueueMetrics.DecRunningApplications
+		inc = metrics.CoreQueueMetrics.IncRunningApplications
+	default:
+		return
+	}
+	if source != "" {
+		dec(metrics.GetQueueMetrics(source))
+	}
+	if target != "" {
+		inc(metrics.GetQueueMetrics(target))
+	}
+}
+
 func newAppState() *fsm.FSM {
 	return fsm.NewFSM(
 		New.String(), fsm.Events{
@@ -95,6 +153,7 @@ func newAppState() *fsm.FSM {
 					zap.String("source", event.Src),
 					zap.String("destination", event.Dst),
 					zap.String("event", event.Event))
+				updateQueueAppMetrics(event)
 			},
 			fmt.Sprintf("enter_%s", Running.String()): func(event *fsm.Event) {
 				metrics.GetSchedulerMetrics().IncTotalApplicationsRunning()
@@ -41,9 +41,9 @@ type RMRegistrationEvent struct {
 /*************************/
 
 // Allocation proposal include a list of Allocations/Releases
-// They should be considered as an all-or-none bundle.
-// If there're multiple allocations need to be allocated, and there's no
-// relationship between these containers, they should be sent separately.
+// A bundle with releases should be considered as an all-or-none bundle and must contain one allocation.
+// A bundle without releases can contain a batch of allocations on the same node, each allocation in the batch
+// is committed or rejected independently. Committed allocations are delivered to the RM in the proposed order.
 type AllocationProposalBundleEvent struct {
 	PartitionName       string
 	AllocationProposals []*commonevents.AllocationProposal
@@ -81,6 +81,13 @@ func NewReleaseAllocationEventFromProto(proto []*si.AllocationReleaseRequest) *R
 	return event
 }
 
+// From RM proxy, new allocations that could not be delivered to the RM and must be rolled back.
+type RollbackAllocationsEvent struct {
+	RmID        string
+	Allocations []*si.Allocation
+	Reason      string
+}
+
 type ReleaseAllocationsEvent struct {
 	AllocationsToRelease []*commonevents.ReleaseAllocation
 }
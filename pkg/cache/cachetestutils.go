@@ -20,6 +20,7 @@ package cache
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
@@ -38,6 +39,11 @@ func AddAllocationToApp(app *ApplicationInfo, alloc *AllocationInfo) {
 	app.addAllocation(alloc)
 }
 
+// Add a reuse hint for a released allocation to cache app for tests
+func AddReuseHintToApp(app *ApplicationInfo, alloc *AllocationInfo, ttl time.Duration) {
+	app.addReuseHint(alloc, ttl)
+}
+
 // Create a partition for testing from a yaml configuration
 func CreatePartitionInfo(data []byte) (*PartitionInfo, error) {
 	// create config from string
@@ -53,6 +59,11 @@ func CreatePartitionInfo(data []byte) (*PartitionInfo, error) {
 	return pi, nil
 }
 
+// Update the partition with the config as a config reload would
+func UpdatePartitionInfo(info *PartitionInfo, conf configs.PartitionConfig) error {
+	return info.updatePartitionDetails(conf)
+}
+
 // Node to test with sorters (setting available resources)
 func NewNodeForSort(nodeID string, availResource *resources.Resource) *NodeInfo {
 	return newNodeForTest(nodeID, resources.NewResource(), availResource)
@@ -85,3 +96,240 @@ func SetGuaranteedResource(info *QueueInfo, res *resources.Resource) {
 		info.guaranteedResource = res
 	}
 }
+
+// Utility function to allow tests to set the preemption grace period that is not exported
+func SetPreemptionGracePeriod(info *PartitionInfo, gracePeriod time.Duration) {
+	if info != nil {
+		info.preemptionGracePeriod = gracePeriod
+	}
+}
+
+// Utility function to allow tests to set the queue drain timeout and target that are not exported
+func SetQueueDrain(info *PartitionInfo, timeout time.Duration, target string) {
+	if info != nil {
+		info.queueDrainTimeout = timeout
+		info.queueDrainTarget = target
+	}
+}
+
+// Utility function to allow tests to set the queue state time that is not exported
+func SetQueueStateTime(info *QueueInfo, stateTime time.Time) {
+	if info != nil {
+		info.stateTime = stateTime
+	}
+}
+
+// Utility function to allow tests to set the preemption confirm timeout that is not exported
+func SetPreemptionConfirmTimeout(info *PartitionInfo, confirmTimeout time.Duration) {
+	if info != nil {
+		info.preemptionConfirm = confirmTimeout
+	}
+}
+
+// Utility function to allow tests to set the preemption minimum runtime and emergency priority that are not exported
+func SetPreemptionMinRuntime(info *PartitionInfo, minRuntime time.Duration, emergencyPriority int32) {
+	if info != nil {
+		info.preemptionMinRuntime = minRuntime
+		info.emergencyPriority = emergencyPriority
+	}
+}
+
+// Utility function to allow tests to set the preemption settle period that is not exported
+func SetPreemptionSettlePeriod(info *PartitionInfo, settlePeriod time.Duration) {
+	if info != nil {
+		info.preemptionSettle = settlePeriod
+	}
+}
+
+// Utility function to allow tests to set the time a reload changed the limits of a queue that is not exported
+func SetLimitsChangeTime(queue *QueueInfo, changed time.Time) {
+	if queue != nil {
+		queue.Lock()
+		queue.limitsChanged = changed
+		queue.Unlock()
+	}
+}
+
+// Utility function to allow tests to set the creation time of an allocation that is not exported
+func SetAllocationCreateTime(alloc *AllocationInfo, createTime time.Time) {
+	if alloc != nil {
+		alloc.createTime = createTime
+	}
+}
+
+// Utility function to allow tests to set the utilization preemption trigger that is not exported
+func SetUtilizationTrigger(info *PartitionInfo, trigger, release int) {
+	if info != nil {
+		info.utilizationTrigger = configs.PreemptionUtilizationConfig{Trigger: trigger, Release: release}
+	}
+}
+
+// Utility function to allow tests to set the autoscale watermark and threshold that are not exported
+func SetAutoscaleWatermark(info *PartitionInfo, watermark *resources.Resource, threshold time.Duration) {
+	if info != nil {
+		info.autoscaleWatermark = watermark
+		info.autoscaleThreshold = threshold
+	}
+}
+
+// Utility function to allow tests to set the ask budget that is not exported
+func SetAskBudget(info *PartitionInfo, maxTime time.Duration, maxNodes int) {
+	if info != nil {
+		info.askBudgetTime = maxTime
+		info.askBudgetNodes = maxNodes
+	}
+}
+
+// Utility function to allow tests to set the throughput mode that is not exported
+func SetThroughput(info *PartitionInfo, enabled bool, sampleSize int) {
+	if info != nil {
+		info.throughput = enabled
+		info.throughputSampleSize = sampleSize
+	}
+}
+
+// Utility function to allow tests to set the resource types ignored in the fit checks of a partition without nodes
+func SetIgnoredResourceTypes(info *PartitionInfo, types []string) {
+	if info != nil {
+		info.ignored = newIgnoredResources()
+		info.ignored.setTypes(types)
+	}
+}
+
+// Utility function to allow tests to set the resource types ignored in the fit checks of a node without a partition
+func SetNodeIgnoredResourceTypes(node *NodeInfo, types []string) {
+	if node != nil {
+		node.ignored = newIgnoredResources()
+		node.ignored.setTypes(types)
+	}
+}
+
+// Utility function to allow tests to set the preemption scope without setting the queue properties
+func SetPreemptionScoped(info *QueueInfo, scoped bool) {
+	if info != nil {
+		info.preemptionScoped = scoped
+	}
+}
+
+// Utility function to allow tests to opt a queue out of preemption without setting the queue properties
+func SetPreemptionDisabled(info *QueueInfo, disabled bool) {
+	if info != nil {
+		info.preemptionDisabled = disabled
+	}
+}
+
+// Utility function to allow tests to set the time since which the node is ready
+func SetNodeReadySince(node *NodeInfo, readySince time.Time) {
+	if node != nil {
+		node.readySince = readySince
+	}
+}
+
+// Utility function to allow tests to set the queue weight without setting the queue properties
+func SetQueueWeight(info *QueueInfo, weight float64) {
+	if info != nil {
+		info.weight = weight
+	}
+}
+
+// Utility function to allow tests to add an application to the partition
+func AddApplicationToPartition(info *PartitionInfo, app *ApplicationInfo) error {
+	return info.addNewApplication(app, true)
+}
+
+// Utility function to allow tests to set the queue priority without setting the queue properties
+func SetQueuePriority(info *QueueInfo, priority int32) {
+	if info != nil {
+		info.priority = priority
+	}
+}
+
+// Utility function to allow tests to set the application group maximum without setting the queue properties
+func SetApplicationGroupMax(info *QueueInfo, groupMax *resources.Resource) {
+	if info != nil {
+		info.groupMaxResource = groupMax
+	}
+}
+
+// Utility function to allow tests to set the user maximum without setting the queue properties
+func SetQueueUserMax(info *QueueInfo, userMax *resources.Resource) {
+	if info != nil {
+		info.userMaxResource = userMax
+	}
+}
+
+// Utility function to allow tests to set the node sorting resource weights of the partition
+func SetNodeSortWeights(info *PartitionInfo, weights map[string]float64) {
+	if info != nil {
+		info.nodeSortWeights = weights
+	}
+}
+
+// Utility function to allow tests to set the node sorting resource weights without setting the queue properties
+func SetQueueNodeSortWeights(info *QueueInfo, weights map[string]float64) {
+	if info != nil {
+		info.nodeSortWeights = weights
+	}
+}
+
+// Utility function to allow tests to set a maintenance window without a config
+func SetMaintenance(info *PartitionInfo, start time.Time, duration time.Duration, capacity int, leadTime time.Duration) {
+	if info != nil {
+		info.maintenance = []*maintenanceWindow{{
+			start:    start,
+			end:      start.Add(duration),
+			capacity: capacity,
+			leadTime: leadTime,
+		}}
+	}
+}
+
+// Utility function to allow tests to set the total partition resource without adding nodes
+func SetTotalPartitionResource(info *PartitionInfo, total *resources.Resource) {
+	if info != nil {
+		info.totalPartitionResource = total
+	}
+}
+
+// Utility function to allow tests to set the queue idle timeout that is not exported
+func SetQueueIdleTimeout(info *PartitionInfo, timeout time.Duration) {
+	if info != nil {
+		info.queueIdleTimeout = timeout
+	}
+}
+
+// Utility function to allow tests to set the application completion grace period that is not exported
+func SetAppCompletionGracePeriod(info *PartitionInfo, grace time.Duration) {
+	if info != nil {
+		info.appCompletionGrace = grace
+	}
+}
+
+// Utility function to allow tests to set the stale reservation age that is not exported
+func SetStaleReservationAge(info *PartitionInfo, age time.Duration) {
+	if info != nil {
+		info.staleReservationAge = age
+	}
+}
+
+// Utility function to allow tests to set the reservation limits that are not exported
+func SetReservationLimits(info *PartitionInfo, maxReservations int, maxReservedResource *resources.Resource) {
+	if info != nil {
+		info.maxReservations = maxReservations
+		info.maxReservedResource = maxReservedResource
+	}
+}
+
+// Utility function to allow tests to set the scheduler units in one RM unit that are not exported
+func SetRMUnitFactors(info *PartitionInfo, factors map[string]int64) {
+	if info != nil {
+		info.rmUnitFactors = factors
+	}
+}
+
+// Utility function to allow tests to set the resource units that are not exported
+func SetResourceUnits(info *PartitionInfo, units map[string]int64) {
+	if info != nil {
+		info.resourceUnits = units
+	}
+}
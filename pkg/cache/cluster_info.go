@@ -21,7 +21,8 @@ package cache
 import (
 	"fmt"
 	"reflect"
-	"sync"
+	"sort"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -29,6 +30,8 @@ import (
 	"github.com/apache/incubator-yunikorn-core/pkg/cache/cacheevent"
 	"github.com/apache/incubator-yunikorn-core/pkg/common"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/handler"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
 	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
@@ -38,17 +41,19 @@ import (
 )
 
 type ClusterInfo struct {
-	partitions  map[string]*PartitionInfo
-	policyGroup string
+	partitions      map[string]*PartitionInfo
+	policyGroup     string
+	replicatedState *ReplicatedState // state replicated from the active instance, only set on a standby instance
 
 	// Event queues
 	pendingRmEvents        chan interface{}
 	pendingSchedulerEvents chan interface{}
+	pendingRmUpdates       *updateQueue
 
 	// RM Event Handler
 	EventHandlers handler.EventHandlers
 
-	sync.RWMutex
+	locking.RWMutex
 }
 
 func NewClusterInfo() (info *ClusterInfo) {
@@ -56,6 +61,7 @@ func NewClusterInfo() (info *ClusterInfo) {
 		partitions:             make(map[string]*PartitionInfo),
 		pendingRmEvents:        make(chan interface{}, 1024*1024),
 		pendingSchedulerEvents: make(chan interface{}, 1024*1024),
+		pendingRmUpdates:       newUpdateQueue(),
 	}
 	return clusterInfo
 }
@@ -79,6 +85,8 @@ func (m *ClusterInfo) handleSchedulerEvents() {
 			m.processRejectedApplicationEvent(v)
 		case *cacheevent.ReleaseAllocationsEvent:
 			m.handleAllocationReleasesRequestEvent(v)
+		case *cacheevent.RollbackAllocationsEvent:
+			m.processRollbackAllocationsEvent(v)
 		case *cacheevent.RemovedApplicationEvent:
 			m.processRemovedApplication(v)
 		case *commonevents.RemoveRMPartitionsEvent:
@@ -93,18 +101,28 @@ func (m *ClusterInfo) handleRMEvents() {
 	for {
 		ev := <-m.pendingRmEvents
 		switch v := ev.(type) {
-		case *cacheevent.RMUpdateRequestEvent:
-			m.processRMUpdateEvent(v)
-		case *commonevents.RegisterRMEvent:
-			m.processRMRegistrationEvent(v)
-		case *commonevents.ConfigUpdateRMEvent:
-			m.processRMConfigUpdateEvent(v)
+		case updateQueued:
+			m.processRMEvent(m.pendingRmUpdates.next())
 		default:
 			panic(fmt.Sprintf("%s is not an acceptable type for RM event.", reflect.TypeOf(v).String()))
 		}
 	}
 }
 
+// Process the next event taken from the update queue.
+func (m *ClusterInfo) processRMEvent(ev interface{}) {
+	switch v := ev.(type) {
+	case *cacheevent.RMUpdateRequestEvent:
+		m.processRMUpdateEvent(v)
+	case *commonevents.RegisterRMEvent:
+		m.processRMRegistrationEvent(v)
+	case *commonevents.ConfigUpdateRMEvent:
+		m.processRMConfigUpdateEvent(v)
+	default:
+		panic(fmt.Sprintf("%s is not an acceptable type for RM event.", reflect.TypeOf(v).String()))
+	}
+}
+
 // Implement methods for Cache events
 func (m *ClusterInfo) HandleEvent(ev interface{}) {
 	switch v := ev.(type) {
@@ -114,16 +132,22 @@ func (m *ClusterInfo) HandleEvent(ev interface{}) {
 		enqueueAndCheckFull(m.pendingSchedulerEvents, v)
 	case *cacheevent.ReleaseAllocationsEvent:
 		enqueueAndCheckFull(m.pendingSchedulerEvents, v)
+	case *cacheevent.RollbackAllocationsEvent:
+		enqueueAndCheckFull(m.pendingSchedulerEvents, v)
 	case *commonevents.RemoveRMPartitionsEvent:
 		enqueueAndCheckFull(m.pendingSchedulerEvents, v)
 	case *cacheevent.RemovedApplicationEvent:
 		enqueueAndCheckFull(m.pendingSchedulerEvents, v)
+	// the events of the RMs are ordered fairly between the RMs and the applications, not in the order received
 	case *cacheevent.RMUpdateRequestEvent:
-		enqueueAndCheckFull(m.pendingRmEvents, v)
+		m.pendingRmUpdates.add(v.Request.RmID, v)
+		enqueueAndCheckFull(m.pendingRmEvents, updateQueued{})
 	case *commonevents.RegisterRMEvent:
-		enqueueAndCheckFull(m.pendingRmEvents, v)
+		m.pendingRmUpdates.add(v.RMRegistrationRequest.RmID, v)
+		enqueueAndCheckFull(m.pendingRmEvents, updateQueued{})
 	case *commonevents.ConfigUpdateRMEvent:
-		enqueueAndCheckFull(m.pendingRmEvents, v)
+		m.pendingRmUpdates.add(v.RmID, v)
+		enqueueAndCheckFull(m.pendingRmEvents, updateQueued{})
 	default:
 		panic(fmt.Sprintf("Received unexpected event type = %s", reflect.TypeOf(v).String()))
 	}
@@ -175,6 +199,9 @@ func (m *ClusterInfo) addPartition(name string, info *PartitionInfo) {
 func (m *ClusterInfo) removePartition(name string) {
 	m.Lock()
 	defer m.Unlock()
+	if partition := m.partitions[name]; partition != nil {
+		partition.stopUserGroupCache()
+	}
 	delete(m.partitions, name)
 }
 
@@ -185,25 +212,45 @@ func (m *ClusterInfo) processApplicationUpdateFromRMUpdate(request *si.UpdateReq
 		return
 	}
 	addedAppInfosInterface := make([]interface{}, 0)
+	updatedAppInfosInterface := make([]interface{}, 0)
+	acceptedApps := make([]*si.AcceptedApplication, 0)
 	rejectedApps := make([]*si.RejectedApplication, 0)
 
 	for _, app := range request.NewApplications {
 		partitionInfo := m.GetPartition(app.PartitionName)
 		if partitionInfo == nil {
-			msg := fmt.Sprintf("Failed to add application %s to partition %s, partition doesn't exist", app.ApplicationID, app.PartitionName)
-			log.Logger().Info(msg)
+			err := api.NewRejectionError(api.RejectionPartitionNotFound, "Failed to add application %s to partition %s, partition doesn't exist", app.ApplicationID, app.PartitionName)
+			log.Logger().Info(err.Error())
 			rejectedApps = append(rejectedApps, &si.RejectedApplication{
 				ApplicationID: app.ApplicationID,
-				Reason:        msg,
+				Reason:        api.FormatRejectionReason(err),
 			})
 			continue
 		}
+		if existing := partitionInfo.getApplication(app.ApplicationID); existing != nil {
+			// the RM sends the applications again after it registered: accept an application restored from the
+			// replicated state without adding it again
+			if existing.takeRestored() {
+				log.Logger().Info("application restored from replicated state sent by RM",
+					zap.String("appID", app.ApplicationID))
+				acceptedApps = append(acceptedApps, &si.AcceptedApplication{ApplicationID: app.ApplicationID})
+				continue
+			}
+			// an application sent again after submission updates the tags, the queue and user cannot change
+			if existing.UpdateTags(app.Tags) {
+				log.Logger().Info("application tags updated by RM",
+					zap.String("appID", app.ApplicationID),
+					zap.Any("tags", app.Tags))
+				updatedAppInfosInterface = append(updatedAppInfosInterface, existing)
+			}
+			continue
+		}
 		// convert and resolve the user: cache can be set per partition
 		ugi, err := partitionInfo.convertUGI(app.Ugi)
 		if err != nil {
 			rejectedApps = append(rejectedApps, &si.RejectedApplication{
 				ApplicationID: app.ApplicationID,
-				Reason:        err.Error(),
+				Reason:        api.FormatRejectionReason(api.NewRejectionError(api.RejectionInvalidUser, "%v", err)),
 			})
 			continue
 		}
@@ -212,19 +259,19 @@ func (m *ClusterInfo) processApplicationUpdateFromRMUpdate(request *si.UpdateReq
 		if err := partitionInfo.addNewApplication(appInfo, true); err != nil {
 			rejectedApps = append(rejectedApps, &si.RejectedApplication{
 				ApplicationID: app.ApplicationID,
-				Reason:        err.Error(),
+				Reason:        api.FormatRejectionReason(err),
 			})
 			continue
 		}
 		addedAppInfosInterface = append(addedAppInfosInterface, appInfo)
 	}
 
-	// Respond to RMProxy with already rejected apps if needed
-	if len(rejectedApps) > 0 {
+	// Respond to RMProxy with already accepted or rejected apps if needed
+	if len(acceptedApps) > 0 || len(rejectedApps) > 0 {
 		m.EventHandlers.RMProxyEventHandler.HandleEvent(
 			&rmevent.RMApplicationUpdateEvent{
 				RmID:                 request.RmID,
-				AcceptedApplications: make([]*si.AcceptedApplication, 0),
+				AcceptedApplications: acceptedApps,
 				RejectedApplications: rejectedApps,
 			})
 	}
@@ -235,11 +282,12 @@ func (m *ClusterInfo) processApplicationUpdateFromRMUpdate(request *si.UpdateReq
 		// ToDO: need to improve this once we have state in YuniKorn for apps.
 		metrics.GetSchedulerMetrics().AddTotalApplicationsCompleted(len(request.RemoveApplications))
 	}
-	// Send message to Scheduler if we have anything to process (remove and or add)
-	if len(request.RemoveApplications) > 0 || len(addedAppInfosInterface) > 0 {
+	// Send message to Scheduler if we have anything to process (remove, add and or update)
+	if len(request.RemoveApplications) > 0 || len(addedAppInfosInterface) > 0 || len(updatedAppInfosInterface) > 0 {
 		m.EventHandlers.SchedulerEventHandler.HandleEvent(
 			&schedulerevent.SchedulerApplicationsUpdateEvent{
 				AddedApplications:   addedAppInfosInterface,
+				UpdatedApplications: updatedAppInfosInterface,
 				RemovedApplications: request.RemoveApplications,
 			})
 	}
@@ -259,12 +307,12 @@ func (m *ClusterInfo) processNewAndReleaseAllocationRequests(request *si.UpdateR
 		// try to get ApplicationInfo
 		partitionInfo := m.GetPartition(req.PartitionName)
 		if partitionInfo == nil {
-			msg := fmt.Sprintf("Failed to find partition %s, for application %s and allocation %s", req.PartitionName, req.ApplicationID, req.AllocationKey)
-			log.Logger().Info(msg)
+			err := api.NewRejectionError(api.RejectionPartitionNotFound, "Failed to find partition %s, for application %s and allocation %s", req.PartitionName, req.ApplicationID, req.AllocationKey)
+			log.Logger().Info(err.Error())
 			rejectedAsks = append(rejectedAsks, &si.RejectedAllocationAsk{
 				AllocationKey: req.AllocationKey,
 				ApplicationID: req.ApplicationID,
-				Reason:        msg,
+				Reason:        api.FormatRejectionReason(err),
 			})
 			continue
 		}
@@ -272,16 +320,18 @@ func (m *ClusterInfo) processNewAndReleaseAllocationRequests(request *si.UpdateR
 		// if app info doesn't exist, reject the request
 		appInfo := partitionInfo.getApplication(req.ApplicationID)
 		if appInfo == nil {
-			msg := fmt.Sprintf("Failed to find application %s, for allocation %s", req.ApplicationID, req.AllocationKey)
-			log.Logger().Info(msg)
+			err := api.NewRejectionError(api.RejectionApplicationNotFound, "Failed to find application %s, for allocation %s", req.ApplicationID, req.AllocationKey)
+			log.Logger().Info(err.Error())
 			rejectedAsks = append(rejectedAsks,
 				&si.RejectedAllocationAsk{
 					AllocationKey: req.AllocationKey,
 					ApplicationID: req.ApplicationID,
-					Reason:        msg,
+					Reason:        api.FormatRejectionReason(err),
 				})
 			continue
 		}
+		// the shim can use different names for the resource types
+		partitionInfo.canonicalizeAsk(req)
 		// start to process allocation asks from this app
 		// transit app's state to running
 		err := appInfo.HandleApplicationEvent(RunApplication)
@@ -383,8 +433,12 @@ func (m *ClusterInfo) processNodeActions(request *si.UpdateRequest) {
 		if partition == nil {
 			continue
 		}
+		partition.canonicalizeNodeUpdate(update)
 
 		if nodeInfo, ok := partition.nodes[update.NodeID]; ok {
+			if digest, ok := update.Attributes[api.NodeAllocationDigest]; ok {
+				checkAllocationDrift(nodeInfo, digest)
+			}
 			switch update.Action {
 			case si.UpdateNodeInfo_DRAIN_NODE:
 				// set the state to not schedulable
@@ -411,6 +465,28 @@ func (m *ClusterInfo) processNodeActions(request *si.UpdateRequest) {
 	}
 }
 
+// Compare the allocation digest reported by the RM with the allocations of the node in the core.
+// A node that goes into drift is logged and counted, the drift of the nodes is reported via the REST api.
+func checkAllocationDrift(nodeInfo *NodeInfo, digest string) {
+	newDrift, err := nodeInfo.checkAllocationDigest(digest, time.Now())
+	if err != nil {
+		log.Logger().Warn("ignoring node allocation digest",
+			zap.String("nodeID", nodeInfo.NodeID),
+			zap.Error(err))
+		return
+	}
+	if newDrift {
+		drift := nodeInfo.GetAllocationDrift()
+		log.Logger().Warn("node allocations drifted from the RM",
+			zap.String("nodeID", nodeInfo.NodeID),
+			zap.String("driftType", drift.GetType()),
+			zap.Int("coreAllocations", drift.CoreAllocations),
+			zap.Int("rmAllocations", drift.RMAllocations),
+			zap.Time("since", drift.Since))
+		metrics.GetSchedulerMetrics().IncAllocationDrifts()
+	}
+}
+
 // Process the node updates: add and remove nodes as needed.
 // Lock free call, all updates occur on the underlying node which is locked or via events.
 func (m *ClusterInfo) processNodeUpdate(request *si.UpdateRequest) {
@@ -442,7 +518,17 @@ func (m *ClusterInfo) processRMUpdateEvent(event *cacheevent.RMUpdateRequestEven
 // Updated partitions can not fail on the scheduler side.
 // Locking occurs by the methods that are called, this must be lock free.
 func (m *ClusterInfo) processRMRegistrationEvent(event *commonevents.RegisterRMEvent) {
-	updatedPartitions, err := SetClusterInfoFromConfigFile(m, event.RMRegistrationRequest.RmID, event.RMRegistrationRequest.PolicyGroup)
+	rmID := event.RMRegistrationRequest.RmID
+	policyGroup := event.RMRegistrationRequest.PolicyGroup
+	// a standby instance taking over uses the configuration of the active instance
+	var updatedPartitions []*PartitionInfo
+	var err error
+	state := m.takeReplicatedState(rmID, policyGroup)
+	if state != nil {
+		updatedPartitions, err = setClusterInfoFromReplicatedState(m, state)
+	} else {
+		updatedPartitions, err = SetClusterInfoFromConfigFile(m, rmID, policyGroup)
+	}
 	if err != nil {
 		event.Channel <- &commonevents.Result{Succeeded: false, Reason: err.Error()}
 	}
@@ -460,6 +546,10 @@ func (m *ClusterInfo) processRMRegistrationEvent(event *commonevents.RegisterRME
 		UpdatedPartitions: updatedPartitionsInterfaces,
 		ResultChannel:     event.Channel,
 	})
+	// the scheduler processes the events in order: the partitions exist when the applications are added
+	if state != nil && err == nil {
+		m.restoreReplicatedState(state)
+	}
 }
 
 // Process a configuration update.
@@ -467,6 +557,8 @@ func (m *ClusterInfo) processRMRegistrationEvent(event *commonevents.RegisterRME
 // Updated and deleted partitions can not fail on the scheduler side.
 // Locking occurs by the methods that are called, this must be lock free.
 func (m *ClusterInfo) processRMConfigUpdateEvent(event *commonevents.ConfigUpdateRMEvent) {
+	// keep the queue configs to find the changes after the update
+	queuesBefore := m.getQueueUpdates(event.RmID)
 	updatedPartitions, deletedPartitions, err := UpdateClusterInfoFromConfigFile(m, event.RmID)
 	if err != nil {
 		event.Channel <- &commonevents.Result{Succeeded: false, Reason: err.Error()}
@@ -507,11 +599,71 @@ func (m *ClusterInfo) processRMConfigUpdateEvent(event *commonevents.ConfigUpdat
 		return
 	}
 
+	// tell the RM about the changed queues
+	m.notifyRMQueueUpdates(event.RmID, queuesBefore)
+
 	// all succeed
 	event.Channel <- &commonevents.Result{Succeeded: true}
 }
 
+// Get the queue updates for all queues of the partitions of the RM keyed by partition and queue path.
+// Locked call, the partitions are collected under the cluster lock.
+func (m *ClusterInfo) getQueueUpdates(rmID string) map[string]map[string]*api.QueueUpdate {
+	m.RLock()
+	partitions := make([]*PartitionInfo, 0)
+	for _, partition := range m.partitions {
+		if partition.RmID == rmID {
+			partitions = append(partitions, partition)
+		}
+	}
+	m.RUnlock()
+	updates := make(map[string]map[string]*api.QueueUpdate)
+	for _, partition := range partitions {
+		updates[partition.Name] = partition.getQueueUpdates()
+	}
+	return updates
+}
+
+// Send the queues that changed compared to the queue updates collected before the change to the RM.
+// Nothing is sent if no queue changed.
+func (m *ClusterInfo) notifyRMQueueUpdates(rmID string, before map[string]map[string]*api.QueueUpdate) {
+	updates := changedQueueUpdates(before, m.getQueueUpdates(rmID))
+	if len(updates) == 0 {
+		return
+	}
+	log.Logger().Debug("sending queue updates to RM",
+		zap.String("rmID", rmID),
+		zap.Int("updates", len(updates)))
+	m.EventHandlers.RMProxyEventHandler.HandleEvent(&rmevent.RMQueueUpdateEvent{
+		RmID:    rmID,
+		Updates: updates,
+	})
+}
+
+// Get the queue updates that are new or differ from the update before, sorted by partition and queue path.
+func changedQueueUpdates(before, after map[string]map[string]*api.QueueUpdate) []*api.QueueUpdate {
+	changed := make([]*api.QueueUpdate, 0)
+	for partitionName, queues := range after {
+		for queuePath, update := range queues {
+			if !reflect.DeepEqual(before[partitionName][queuePath], update) {
+				changed = append(changed, update)
+			}
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		if changed[i].PartitionName != changed[j].PartitionName {
+			return changed[i].PartitionName < changed[j].PartitionName
+		}
+		return changed[i].QueueName < changed[j].QueueName
+	})
+	return changed
+}
+
 // Process an allocation bundle which could contain release and allocation proposals.
+// A bundle with releases is the result of preemption and only supports one allocation, all but the first allocation
+// are rejected. A bundle without releases can contain a batch of allocations on the same node. The allocations in a
+// batch are committed in order: an allocation that fails to commit is rejected without affecting the others. All
+// committed allocations are sent to the RM in one message, in the same order as proposed.
 // Lock free call, all updates occur on the underlying partition which is locked or via events.
 func (m *ClusterInfo) processAllocationProposalEvent(event *cacheevent.AllocationProposalBundleEvent) {
 	// Release if there is anything to release
@@ -523,46 +675,116 @@ func (m *ClusterInfo) processAllocationProposalEvent(event *cacheevent.Allocatio
 		return
 	}
 
-	// we currently only support 1 allocation in the list, reject all but the first
-	if len(event.AllocationProposals) != 1 {
-		log.Logger().Info("More than 1 allocation proposal rejected all but first",
-			zap.Int("allocPropLength", len(event.AllocationProposals)))
-		// Send reject event back to scheduler for all but first
-		// this can be more than 1
-		m.EventHandlers.SchedulerEventHandler.HandleEvent(&schedulerevent.SchedulerAllocationUpdatesEvent{
-			RejectedAllocations: event.AllocationProposals[1:],
-		})
+	proposals := event.AllocationProposals
+	rejected := make([]*commonevents.AllocationProposal, 0)
+	// we only support 1 allocation in combination with releases, reject all but the first
+	if len(event.ReleaseProposals) > 0 && len(proposals) != 1 {
+		log.Logger().Info("More than 1 allocation proposal with releases rejected all but first",
+			zap.Int("allocPropLength", len(proposals)))
+		rejected = append(rejected, proposals[1:]...)
+		proposals = proposals[:1]
+		for _, proposal := range rejected {
+			logProposalDecision(log.DecisionRejection, proposal, "", "more than 1 allocation proposal with releases")
+		}
 	}
-	// just process the first the rest is rejected already
-	proposal := event.AllocationProposals[0]
-	partitionInfo := m.GetPartition(proposal.PartitionName)
-	allocInfo, err := partitionInfo.addNewAllocation(proposal)
-	if err != nil {
-		log.Logger().Error("failed to add new allocation to partition",
-			zap.String("partition", partitionInfo.Name),
-			zap.String("allocationKey", proposal.AllocationKey),
-			zap.Error(err))
-		// Send reject event back to scheduler
-		// this could be more than 1 for the handler however here we can only have 1
+	accepted := make([]*commonevents.AllocationProposal, 0, len(proposals))
+	allocations := make([]*si.Allocation, 0, len(proposals))
+	for _, proposal := range proposals {
+		partitionInfo := m.GetPartition(proposal.PartitionName)
+		if partitionInfo == nil {
+			log.Logger().Error("failed to find partition for allocation proposal",
+				zap.String("partition", proposal.PartitionName),
+				zap.String("allocationKey", proposal.AllocationKey))
+			rejected = append(rejected, proposal)
+			logProposalDecision(log.DecisionRejection, proposal, "", "partition not found")
+			continue
+		}
+		allocInfo, err := partitionInfo.addNewAllocation(proposal)
+		if err != nil {
+			log.Logger().Error("failed to add new allocation to partition",
+				zap.String("partition", partitionInfo.Name),
+				zap.String("allocationKey", proposal.AllocationKey),
+				zap.Error(err))
+			rejected = append(rejected, proposal)
+			logProposalDecision(log.DecisionRejection, proposal, "", err.Error())
+			continue
+		}
+		accepted = append(accepted, proposal)
+		logProposalDecision(log.DecisionAllocation, proposal, allocInfo.AllocationProto.UUID, "")
+		allocations = append(allocations, partitionInfo.toRMAllocation(allocInfo.AllocationProto))
+	}
+	// Send reject event back to scheduler, this can be more than 1
+	if len(rejected) > 0 {
 		m.EventHandlers.SchedulerEventHandler.HandleEvent(&schedulerevent.SchedulerAllocationUpdatesEvent{
-			RejectedAllocations: event.AllocationProposals[:1],
+			RejectedAllocations: rejected,
 		})
+	}
+	if len(accepted) == 0 {
 		return
 	}
 	// Send accept event back to scheduler
-	// this must be only 1: the handler will ignore all others
 	m.EventHandlers.SchedulerEventHandler.HandleEvent(&schedulerevent.SchedulerAllocationUpdatesEvent{
-		AcceptedAllocations: event.AllocationProposals[:1],
+		AcceptedAllocations: accepted,
 	})
-	rmID := common.GetRMIdFromPartitionName(proposal.PartitionName)
+	rmID := common.GetRMIdFromPartitionName(accepted[0].PartitionName)
 
 	// Send allocation event to RM: rejects are not passed back
 	m.EventHandlers.RMProxyEventHandler.HandleEvent(&rmevent.RMNewAllocationsEvent{
-		Allocations: []*si.Allocation{allocInfo.AllocationProto},
+		Allocations: allocations,
 		RmID:        rmID,
 	})
 }
 
+// Roll back the new allocations that could not be delivered to the RM.
+// The allocations are removed from the partition, which releases the node and queue resources, without notifying
+// the RM. The scheduler is notified of the rolled back allocations to re-queue the asks.
+// Lock free call, all updates occur in the partition which is locked.
+func (m *ClusterInfo) processRollbackAllocationsEvent(event *cacheevent.RollbackAllocationsEvent) {
+	rolledBack := make([]*commonevents.AllocationProposal, 0, len(event.Allocations))
+	for _, alloc := range event.Allocations {
+		// the allocation sent to the RM does not have the cluster ID in the partition name
+		partitionName := common.GetNormalizedPartitionName(alloc.PartitionName, event.RmID)
+		partitionInfo := m.GetPartition(partitionName)
+		if partitionInfo == nil {
+			log.Logger().Info("failed to find partition for allocation rollback",
+				zap.String("partitionName", partitionName),
+				zap.String("allocationKey", alloc.AllocationKey))
+			continue
+		}
+		released := partitionInfo.releaseAllocationsForApplication(commonevents.NewReleaseAllocation(
+			alloc.UUID,
+			alloc.ApplicationID,
+			partitionName,
+			event.Reason,
+			si.AllocationReleaseResponse_STOPPED_BY_RM,
+		))
+		for _, info := range released {
+			log.Logger().Info("rolled back allocation not delivered to the RM",
+				zap.String("rmID", event.RmID),
+				zap.String("appID", info.ApplicationID),
+				zap.String("allocationKey", info.AllocationProto.AllocationKey),
+				zap.String("uuid", info.AllocationProto.UUID),
+				zap.String("reason", event.Reason))
+			rolledBack = append(rolledBack, &commonevents.AllocationProposal{
+				NodeID:            info.AllocationProto.NodeID,
+				ApplicationID:     info.ApplicationID,
+				QueueName:         info.AllocationProto.QueueName,
+				AllocatedResource: info.AllocatedResource,
+				AllocationKey:     info.AllocationProto.AllocationKey,
+				Tags:              info.AllocationProto.AllocationTags,
+				Priority:          info.AllocationProto.Priority,
+				PartitionName:     partitionName,
+			})
+		}
+	}
+	if len(rolledBack) == 0 {
+		return
+	}
+	m.EventHandlers.SchedulerEventHandler.HandleEvent(&schedulerevent.SchedulerAllocationUpdatesEvent{
+		RolledBackAllocations: rolledBack,
+	})
+}
+
 // Rejected application from the scheduler.
 // Cleanup the app from the partition.
 // Lock free call, all updates occur in the partition which is locked.
@@ -626,6 +848,9 @@ func (m *ClusterInfo) processAllocationReleases(toReleases []*commonevents.Relea
 			// if the resources released were preempted update the scheduling node that it is done
 			if toReleaseAllocation.ReleaseType == si.AllocationReleaseResponse_PREEMPTED_BY_SCHEDULER {
 				m.notifySchedNodeAllocReleased(releasedAllocations, toReleaseAllocation.PartitionName)
+				for _, alloc := range releasedAllocations {
+					logPreemptionDecision(toReleaseAllocation.PartitionName, alloc, toReleaseAllocation.Message)
+				}
 			}
 			// whatever was released pass it back to the RM
 			m.notifyRMAllocationReleased(rmID, releasedAllocations, toReleaseAllocation.ReleaseType, toReleaseAllocation.Message)
@@ -633,6 +858,54 @@ func (m *ClusterInfo) processAllocationReleases(toReleases []*commonevents.Relea
 	}
 }
 
+// Write the decision on an allocation proposal to the decision log, the uuid is only known for an allocation.
+func logProposalDecision(decisionType string, proposal *commonevents.AllocationProposal, uuid, reason string) {
+	if !log.IsDecisionLogEnabled() {
+		return
+	}
+	log.LogDecision(&log.Decision{
+		Type:          decisionType,
+		Partition:     proposal.PartitionName,
+		ApplicationID: proposal.ApplicationID,
+		QueueName:     proposal.QueueName,
+		AllocationKey: proposal.AllocationKey,
+		UUID:          uuid,
+		NodeID:        proposal.NodeID,
+		Resource:      getDecisionResource(proposal.AllocatedResource),
+		Reason:        reason,
+	})
+}
+
+// Write the preemption of the allocation to the decision log.
+func logPreemptionDecision(partitionName string, alloc *AllocationInfo, reason string) {
+	if !log.IsDecisionLogEnabled() {
+		return
+	}
+	log.LogDecision(&log.Decision{
+		Type:          log.DecisionPreemption,
+		Partition:     partitionName,
+		ApplicationID: alloc.ApplicationID,
+		QueueName:     alloc.AllocationProto.QueueName,
+		AllocationKey: alloc.AllocationProto.AllocationKey,
+		UUID:          alloc.AllocationProto.UUID,
+		NodeID:        alloc.AllocationProto.NodeID,
+		Resource:      getDecisionResource(alloc.AllocatedResource),
+		Reason:        reason,
+	})
+}
+
+// Convert the resource to the quantities written in the decision log.
+func getDecisionResource(res *resources.Resource) map[string]int64 {
+	if res == nil {
+		return nil
+	}
+	quantities := make(map[string]int64, len(res.Resources))
+	for name, quantity := range res.Resources {
+		quantities[name] = int64(quantity)
+	}
+	return quantities
+}
+
 // Process the allocation release event.
 func (m *ClusterInfo) handleAllocationReleasesRequestEvent(event *cacheevent.ReleaseAllocationsEvent) {
 	// Release if there is anything to release.
@@ -655,6 +928,7 @@ func (m *ClusterInfo) processRemoveRMPartitionsEvent(event *commonevents.RemoveR
 	}
 
 	for partition := range toRemove {
+		m.partitions[partition].stopUserGroupCache()
 		delete(m.partitions, partition)
 	}
 
@@ -666,6 +940,52 @@ func (m *ClusterInfo) processRemoveRMPartitionsEvent(event *commonevents.RemoveR
 
 // Process an application removal send by the RM
 // Lock free call, all updates occur in the partition which is locked
+// Kill the application on request of an administrator, not the RM.
+// The application is removed from the scheduler and the cache, the allocations are released and the RM is notified of
+// the released allocations. Returns an error if the partition or the application cannot be found.
+func (m *ClusterInfo) KillApplication(partitionName, appID string) error {
+	partition := m.GetPartition(partitionName)
+	if partition == nil {
+		return fmt.Errorf("partition %s not found", partitionName)
+	}
+	if err := partition.killApplication(appID); err != nil {
+		return err
+	}
+	m.removeKilledApplication(partitionName, appID)
+	return nil
+}
+
+// Ask the scheduler to remove the killed application, the cache removes it when the scheduler confirms.
+func (m *ClusterInfo) removeKilledApplication(partitionName, appID string) {
+	m.EventHandlers.SchedulerEventHandler.HandleEvent(
+		&schedulerevent.SchedulerApplicationsUpdateEvent{
+			RemovedApplications: []*si.RemoveApplicationRequest{{
+				ApplicationID: appID,
+				PartitionName: partitionName,
+			}},
+		})
+}
+
+// Put an application on hold or release it, see ApplicationInfo.SetHeld.
+func (m *ClusterInfo) SetApplicationHeld(partitionName, appID string, held bool) error {
+	partition := m.GetPartition(partitionName)
+	if partition == nil {
+		return fmt.Errorf("partition %s not found", partitionName)
+	}
+	app := partition.getApplication(appID)
+	if app == nil {
+		return fmt.Errorf("application %s not found in partition %s", appID, partitionName)
+	}
+	if err := app.SetHeld(held); err != nil {
+		return err
+	}
+	log.Logger().Info("application hold changed",
+		zap.String("applicationID", appID),
+		zap.String("partitionName", partitionName),
+		zap.Bool("held", held))
+	return nil
+}
+
 func (m *ClusterInfo) processRemovedApplication(event *cacheevent.RemovedApplicationEvent) {
 	partitionInfo := m.GetPartition(event.PartitionName)
 	if partitionInfo == nil {
@@ -0,0 +1,233 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache/cacheevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/handler"
+	"github.com/apache/incubator-yunikorn-core/pkg/rmproxy/rmevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/schedulerevent"
+)
+
+// Records all events passed to the handler.
+type eventRecorder struct {
+	events []interface{}
+}
+
+func (er *eventRecorder) HandleEvent(ev interface{}) {
+	er.events = append(er.events, ev)
+}
+
+// Create a cluster with the default partition, one node and one application.
+func createClusterForProposals(t *testing.T) (*ClusterInfo, *eventRecorder, *eventRecorder) {
+	partition, err := CreatePartitionInfo([]byte(configDefault))
+	assert.NilError(t, err, "partition create failed")
+	err = partition.addNewApplication(newApplicationInfo("app-1", "default", "root.default"), true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+	node := NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10}))
+	err = partition.addNewNode(node, nil)
+	assert.NilError(t, err, "add node to partition should not have failed")
+
+	clusterInfo := NewClusterInfo()
+	clusterInfo.addPartition(partition.Name, partition)
+	scheduler := &eventRecorder{}
+	rm := &eventRecorder{}
+	clusterInfo.EventHandlers = handler.EventHandlers{
+		SchedulerEventHandler: scheduler,
+		RMProxyEventHandler:   rm,
+	}
+	return clusterInfo, scheduler, rm
+}
+
+func createBatchProposal(allocKey, appID string) *commonevents.AllocationProposal {
+	proposal := createAllocationProposal("root.default", "node-1", allocKey, appID)
+	proposal.PartitionName = "default"
+	return proposal
+}
+
+func TestProcessAllocationBatch(t *testing.T) {
+	clusterInfo, scheduler, rm := createClusterForProposals(t)
+	proposals := []*commonevents.AllocationProposal{
+		createBatchProposal("alloc-1", "app-1"),
+		createBatchProposal("alloc-2", "app-1"),
+		createBatchProposal("alloc-3", "app-1"),
+	}
+	clusterInfo.processAllocationProposalEvent(&cacheevent.AllocationProposalBundleEvent{
+		PartitionName:       "default",
+		AllocationProposals: proposals,
+	})
+
+	// all allocations are accepted in one event and sent to the RM in one message in order
+	assert.Equal(t, len(scheduler.events), 1, "expected one scheduler event")
+	update, ok := scheduler.events[0].(*schedulerevent.SchedulerAllocationUpdatesEvent)
+	assert.Assert(t, ok, "unexpected scheduler event type")
+	assert.DeepEqual(t, update.AcceptedAllocations, proposals)
+	assert.Equal(t, len(rm.events), 1, "expected one RM event")
+	allocations := rm.events[0].(*rmevent.RMNewAllocationsEvent).Allocations
+	assert.Equal(t, len(allocations), 3, "expected all allocations in one RM event")
+	for i, alloc := range allocations {
+		assert.Equal(t, alloc.AllocationKey, proposals[i].AllocationKey, "allocations not in proposal order")
+	}
+	assert.Equal(t, len(clusterInfo.GetPartition("default").GetNode("node-1").GetAllAllocations()), 3, "allocations not added to node")
+}
+
+func TestProcessAllocationBatchPartialFailure(t *testing.T) {
+	clusterInfo, scheduler, rm := createClusterForProposals(t)
+	// the proposal in the middle of the batch fails
+	proposals := []*commonevents.AllocationProposal{
+		createBatchProposal("alloc-1", "app-1"),
+		createBatchProposal("alloc-2", "unknown"),
+		createBatchProposal("alloc-3", "app-1"),
+	}
+	clusterInfo.processAllocationProposalEvent(&cacheevent.AllocationProposalBundleEvent{
+		PartitionName:       "default",
+		AllocationProposals: proposals,
+	})
+
+	// the failed proposal is rejected, the others are committed
+	assert.Equal(t, len(scheduler.events), 2, "expected a reject and an accept event")
+	rejected := scheduler.events[0].(*schedulerevent.SchedulerAllocationUpdatesEvent)
+	assert.DeepEqual(t, rejected.RejectedAllocations, proposals[1:2])
+	assert.Equal(t, len(rejected.AcceptedAllocations), 0, "reject event should not have accepted allocations")
+	accepted := scheduler.events[1].(*schedulerevent.SchedulerAllocationUpdatesEvent)
+	assert.DeepEqual(t, accepted.AcceptedAllocations, []*commonevents.AllocationProposal{proposals[0], proposals[2]})
+	assert.Equal(t, len(rm.events), 1, "expected one RM event")
+	allocations := rm.events[0].(*rmevent.RMNewAllocationsEvent).Allocations
+	assert.Equal(t, len(allocations), 2, "expected committed allocations only")
+	assert.Equal(t, allocations[0].AllocationKey, "alloc-1", "allocations not in proposal order")
+	assert.Equal(t, allocations[1].AllocationKey, "alloc-3", "allocations not in proposal order")
+
+	// nothing can be committed: no RM event
+	scheduler.events = nil
+	rm.events = nil
+	clusterInfo.processAllocationProposalEvent(&cacheevent.AllocationProposalBundleEvent{
+		PartitionName:       "default",
+		AllocationProposals: []*commonevents.AllocationProposal{createBatchProposal("alloc-4", "unknown")},
+	})
+	assert.Equal(t, len(scheduler.events), 1, "expected a reject event only")
+	assert.Equal(t, len(rm.events), 0, "no RM event expected without committed allocations")
+}
+
+func TestProcessAllocationEmptyReleases(t *testing.T) {
+	clusterInfo, scheduler, rm := createClusterForProposals(t)
+	// a bundle with releases only supports one allocation
+	proposals := []*commonevents.AllocationProposal{
+		createBatchProposal("alloc-1", "app-1"),
+		createBatchProposal("alloc-2", "app-1"),
+	}
+	clusterInfo.processAllocationProposalEvent(&cacheevent.AllocationProposalBundleEvent{
+		PartitionName:       "default",
+		AllocationProposals: proposals,
+		ReleaseProposals:    []*commonevents.ReleaseAllocation{},
+	})
+	// empty releases are not releases
+	assert.Equal(t, len(rm.events), 1, "expected one RM event")
+	assert.Equal(t, len(rm.events[0].(*rmevent.RMNewAllocationsEvent).Allocations), 2, "expected both allocations")
+	assert.Equal(t, len(scheduler.events), 1, "expected accept event only")
+}
+
+func TestNotifyRMQueueUpdates(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: a
+            resources:
+              max: {memory: 10}
+          - name: b
+          - name: c
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	// derive the guaranteed resources as a partition created from the config file would
+	err = checkResourceConfigurationsForQueue(partition.Root, nil)
+	assert.NilError(t, err, "partition resource check failed")
+	clusterInfo := NewClusterInfo()
+	clusterInfo.addPartition(partition.Name, partition)
+	rm := &eventRecorder{}
+	clusterInfo.EventHandlers = handler.EventHandlers{RMProxyEventHandler: rm}
+
+	// nothing changed: nothing sent
+	before := clusterInfo.getQueueUpdates("rm1")
+	assert.Equal(t, len(before["default"]), 4, "expected all queues in the updates")
+	clusterInfo.notifyRMQueueUpdates("rm1", before)
+	assert.Equal(t, len(rm.events), 0, "no queue updates expected without changes")
+	assert.Equal(t, len(clusterInfo.getQueueUpdates("other")), 0, "other RM should not have queue updates")
+
+	// change the limit of a, the sort policy of b, remove c and add d
+	conf := partition.GetEffectiveConfig()
+	queues := conf.Queues[0].Queues
+	queues[0].Resources.Max = map[string]string{"memory": "20"}
+	queues[1].Properties = map[string]string{QueueSortPolicy: "fairshare"}
+	queues[2].Name = "d"
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	clusterInfo.notifyRMQueueUpdates("rm1", before)
+	assert.Equal(t, len(rm.events), 1, "expected one queue update event")
+	event, ok := rm.events[0].(*rmevent.RMQueueUpdateEvent)
+	assert.Assert(t, ok, "unexpected event type: %T", rm.events[0])
+	assert.Equal(t, event.RmID, "rm1", "unexpected RM in event")
+	assert.Equal(t, len(event.Updates), 4, "unexpected number of queue updates")
+	for i, queueName := range []string{"root.a", "root.b", "root.c", "root.d"} {
+		assert.Equal(t, event.Updates[i].QueueName, queueName, "updates not sorted")
+		assert.Equal(t, event.Updates[i].PartitionName, "default", "unexpected partition")
+	}
+	assert.Equal(t, event.Updates[0].MaxResource.Resources[resources.MEMORY].Value, int64(20), "unexpected max resource")
+	assert.Equal(t, event.Updates[1].Properties[QueueSortPolicy], "fairshare", "unexpected queue properties")
+	assert.Equal(t, event.Updates[2].State, Draining.String(), "removed queue should be draining")
+	assert.Equal(t, event.Updates[3].State, Active.String(), "new queue should be active")
+}
+
+func TestKillApplication(t *testing.T) {
+	clusterInfo, scheduler, _ := createClusterForProposals(t)
+	assert.ErrorContains(t, clusterInfo.KillApplication("unknown", "app-1"), "partition unknown not found")
+	assert.ErrorContains(t, clusterInfo.KillApplication("default", "unknown"), "application unknown not found")
+	assert.Equal(t, len(scheduler.events), 0, "failed kill should not send events")
+
+	// the scheduler is asked to remove the app, the cache removes it when the scheduler confirms
+	err := clusterInfo.KillApplication("default", "app-1")
+	assert.NilError(t, err, "kill of application should not have failed")
+	app := clusterInfo.GetPartition("default").getApplication("app-1")
+	assert.Equal(t, app.GetApplicationState(), Killed.String(), "application should have been killed")
+	assert.Equal(t, len(scheduler.events), 1, "expected one event for the scheduler")
+	event, ok := scheduler.events[0].(*schedulerevent.SchedulerApplicationsUpdateEvent)
+	assert.Assert(t, ok, "unexpected event type: %T", scheduler.events[0])
+	assert.Equal(t, len(event.RemovedApplications), 1, "expected the app to be removed")
+	assert.Equal(t, event.RemovedApplications[0].ApplicationID, "app-1", "unexpected app removed")
+	assert.Equal(t, event.RemovedApplications[0].PartitionName, "default", "unexpected partition for removed app")
+
+	// an application that is not running cannot be killed
+	partition := clusterInfo.GetPartition("default")
+	rejected := newApplicationInfo("app-2", "default", "root.default")
+	err = partition.addNewApplication(rejected, true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+	err = rejected.HandleApplicationEvent(RejectApplication)
+	assert.NilError(t, err, "reject of application should not have failed")
+	err = clusterInfo.KillApplication("default", "app-2")
+	assert.Assert(t, api.IsError(err, api.ErrInvalidState), "kill of rejected application should fail with invalid state: %v", err)
+}
@@ -0,0 +1,155 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sort"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// The resource types of a partition that are tracked but not enforced in the node fit checks.
+// The same object is shared by the partition and all its nodes: a configuration reload changes the types in place.
+// For each ignored type the number of allocations that were only placed because the type was ignored is counted.
+type ignoredResources struct {
+	types   map[string]bool  // the ignored resource types
+	blocked map[string]int64 // the number of allocations the type would have blocked
+
+	locking.RWMutex
+}
+
+func newIgnoredResources() *ignoredResources {
+	return &ignoredResources{
+		types:   make(map[string]bool),
+		blocked: make(map[string]int64),
+	}
+}
+
+// Replace the ignored resource types, the counts of the types that are still ignored are kept.
+func (ir *ignoredResources) setTypes(types []string) {
+	ir.Lock()
+	defer ir.Unlock()
+	ir.types = make(map[string]bool, len(types))
+	for _, resType := range types {
+		ir.types[resType] = true
+	}
+	for resType := range ir.blocked {
+		if !ir.types[resType] {
+			delete(ir.blocked, resType)
+		}
+	}
+}
+
+// Get the ignored resource types sorted by name, nil if no types are ignored.
+func (ir *ignoredResources) getTypes() []string {
+	if ir == nil {
+		return nil
+	}
+	ir.RLock()
+	defer ir.RUnlock()
+	if len(ir.types) == 0 {
+		return nil
+	}
+	types := make([]string, 0, len(ir.types))
+	for resType := range ir.types {
+		types = append(types, resType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Get the number of allocations each ignored type would have blocked. The ignored types that never blocked an
+// allocation are included with a count of 0.
+func (ir *ignoredResources) getBlocked() map[string]int64 {
+	if ir == nil {
+		return nil
+	}
+	ir.RLock()
+	defer ir.RUnlock()
+	blocked := make(map[string]int64, len(ir.types))
+	for resType := range ir.types {
+		blocked[resType] = ir.blocked[resType]
+	}
+	return blocked
+}
+
+// Check if the smaller resource fits in the larger resource, see resources.FitIn, not checking the ignored types.
+// If record is set and the check passes only because of ignored types the blocked count of those types is increased.
+// A nil object ignores nothing.
+func (ir *ignoredResources) fitIn(larger, smaller *resources.Resource, record bool) bool {
+	if resources.FitIn(larger, smaller) {
+		return true
+	}
+	if ir == nil {
+		return false
+	}
+	ir.RLock()
+	if len(ir.types) == 0 {
+		ir.RUnlock()
+		return false
+	}
+	var blocking []string
+	for resType, value := range smaller.Resources {
+		largerValue := resources.Quantity(0)
+		if larger != nil && larger.Resources[resType] > 0 {
+			largerValue = larger.Resources[resType]
+		}
+		if value <= largerValue {
+			continue
+		}
+		if !ir.types[resType] {
+			ir.RUnlock()
+			return false
+		}
+		blocking = append(blocking, resType)
+	}
+	ir.RUnlock()
+	if !record {
+		return true
+	}
+	ir.Lock()
+	defer ir.Unlock()
+	for _, resType := range blocking {
+		ir.blocked[resType]++
+	}
+	return true
+}
+
+// Check if the smaller resource fits in the larger resource, not checking the resource types that are ignored in the
+// node fit checks of the partition. The blocked counts of the ignored types are not changed.
+func (pi *PartitionInfo) FitInIgnoringTypes(larger, smaller *resources.Resource) bool {
+	return pi.ignored.fitIn(larger, smaller, false)
+}
+
+// Get the resource types that are ignored in the node fit checks, sorted by name.
+func (pi *PartitionInfo) GetIgnoredResourceTypes() []string {
+	return pi.ignored.getTypes()
+}
+
+// Get the number of allocations each ignored resource type would have blocked.
+func (pi *PartitionInfo) GetIgnoredResourceBlocks() map[string]int64 {
+	return pi.ignored.getBlocked()
+}
+
+// Check if the request fits in the resource passed in, not checking the resource types ignored in the partition of
+// the node. Used for the fit checks that take the allocations in progress into account.
+func (ni *NodeInfo) FitIn(available, request *resources.Resource) bool {
+	return ni.ignored.fitIn(available, request, false)
+}
@@ -0,0 +1,112 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestIgnoredResourcesFitIn(t *testing.T) {
+	larger := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 10, "storage": 5})
+	fits := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 5, "storage": 5})
+	storage := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 5, "storage": 10})
+	both := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 20, "storage": 10})
+
+	// nothing ignored: same as a normal fit check
+	var ir *ignoredResources
+	assert.Assert(t, ir.fitIn(larger, fits, true), "resource should fit without ignored types")
+	assert.Assert(t, !ir.fitIn(larger, storage, true), "resource should not fit without ignored types")
+	assert.Assert(t, ir.getTypes() == nil, "nil object should not ignore types")
+
+	ir = newIgnoredResources()
+	ir.setTypes([]string{"storage"})
+	assert.DeepEqual(t, ir.getTypes(), []string{"storage"})
+	assert.Assert(t, ir.fitIn(larger, fits, true), "resource should fit")
+	assert.Assert(t, ir.fitIn(larger, storage, false), "ignored type should not block the fit")
+	assert.DeepEqual(t, ir.getBlocked(), map[string]int64{"storage": 0})
+	assert.Assert(t, ir.fitIn(larger, storage, true), "ignored type should not block the fit")
+	assert.DeepEqual(t, ir.getBlocked(), map[string]int64{"storage": 1})
+	// a type that is not ignored still blocks and nothing is counted
+	assert.Assert(t, !ir.fitIn(larger, both, true), "memory should still block the fit")
+	assert.DeepEqual(t, ir.getBlocked(), map[string]int64{"storage": 1})
+	// a type missing from the larger resource is ignored as well
+	missing := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 5, "storage": 5})
+	assert.Assert(t, ir.fitIn(resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 10}), missing, true), "missing ignored type should not block")
+	assert.DeepEqual(t, ir.getBlocked(), map[string]int64{"storage": 2})
+
+	// the counts of types that stay ignored are kept on update
+	ir.setTypes([]string{"storage", "gpu"})
+	assert.DeepEqual(t, ir.getTypes(), []string{"gpu", "storage"})
+	assert.DeepEqual(t, ir.getBlocked(), map[string]int64{"gpu": 0, "storage": 2})
+	ir.setTypes(nil)
+	assert.Assert(t, ir.getTypes() == nil, "no types should be ignored")
+	assert.Assert(t, !ir.fitIn(larger, storage, true), "resource should not fit without ignored types")
+	assert.Equal(t, len(ir.getBlocked()), 0, "counts should be removed with the types")
+}
+
+func TestIgnoredResourcesAllocation(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    ignoredresourcetypes:
+      - storage
+    queues:
+      - name: root
+        queues:
+          - name: default
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	assert.DeepEqual(t, partition.GetIgnoredResourceTypes(), []string{"storage"})
+	appInfo := newApplicationInfo("app-1", "default", "root.default")
+	err = partition.addNewApplication(appInfo, true)
+	assert.NilError(t, err, "add application to partition failed")
+	node := NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10, "storage": 5}))
+	err = partition.addNewNode(node, nil)
+	assert.NilError(t, err, "add node to partition failed")
+
+	// the storage does not fit on the node: the allocation is placed and counted
+	proposal := createAllocationProposal("root.default", "node-1", "alloc-1", "app-1")
+	proposal.AllocatedResource = resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 5, "storage": 10})
+	assert.Assert(t, node.FitInNode(proposal.AllocatedResource), "ignored type should not block the node fit")
+	_, err = partition.addNewAllocation(proposal)
+	assert.NilError(t, err, "allocation should not be blocked by the ignored type")
+	assert.DeepEqual(t, partition.GetIgnoredResourceBlocks(), map[string]int64{"storage": 1})
+	// the root queue reports the cluster size but does not limit the ignored type
+	assert.Equal(t, partition.Root.GetMaxResource().Resources["storage"], resources.Quantity(5), "root max should be the cluster size")
+	// the ignored type is still tracked
+	assert.Equal(t, node.GetAllocatedResource().Resources["storage"], resources.Quantity(10), "ignored type not tracked on the node")
+
+	// after a reload without the ignored type the storage blocks the allocation
+	conf := partition.GetEffectiveConfig()
+	assert.DeepEqual(t, conf.IgnoredResourceTypes, []string{"storage"})
+	conf.IgnoredResourceTypes = nil
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	proposal = createAllocationProposal("root.default", "node-1", "alloc-2", "app-1")
+	proposal.AllocatedResource = resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 1, "storage": 1})
+	_, err = partition.addNewAllocation(proposal)
+	assert.Assert(t, err != nil, "allocation should be blocked after the reload")
+	assert.Equal(t, len(partition.GetIgnoredResourceBlocks()), 0, "no counts expected without ignored types")
+	assert.DeepEqual(t, partition.GetEffectiveConfig().IgnoredResourceTypes, []string(nil))
+}
@@ -65,7 +65,24 @@ func SetClusterInfoFromConfigFile(clusterInfo *ClusterInfo, rmID string, policyG
 	if err != nil {
 		return []*PartitionInfo{}, err
 	}
+	return setClusterInfoFromConfig(clusterInfo, rmID, policyGroup, conf)
+}
+
+// Create the partitions of the cluster info from the configuration in the replicated state.
+// This function may only be called by the scheduler when a RM registers with a standby instance.
+func setClusterInfoFromReplicatedState(clusterInfo *ClusterInfo, state *ReplicatedState) ([]*PartitionInfo, error) {
+	if len(clusterInfo.partitions) > 0 {
+		return []*PartitionInfo{}, fmt.Errorf("RM %s has been registerd before, active partitions %d", state.RmID, len(clusterInfo.partitions))
+	}
+	conf, err := configs.LoadSchedulerConfigFromByteArray([]byte(state.Config))
+	if err != nil {
+		return []*PartitionInfo{}, err
+	}
+	return setClusterInfoFromConfig(clusterInfo, state.RmID, state.PolicyGroup, conf)
+}
 
+// Create the partitions of the cluster info from a validated configuration.
+func setClusterInfoFromConfig(clusterInfo *ClusterInfo, rmID string, policyGroup string, conf *configs.SchedulerConfig) ([]*PartitionInfo, error) {
 	// update global scheduler configs
 	configs.ConfigContext.Set(policyGroup, conf)
 
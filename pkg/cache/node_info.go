@@ -19,10 +19,15 @@
 package cache
 
 import (
-	"sync"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -34,6 +39,9 @@ type NodeInfo struct {
 	Hostname  string
 	Rackname  string
 	Partition string
+	Pool      string // the node pool in the partition, set by the partition when the node is added
+
+	ignored *ignoredResources // resource types not enforced in the fit checks, set by the partition when the node is added
 
 	// Private fields need protection
 	attributes        map[string]string
@@ -41,9 +49,13 @@ type NodeInfo struct {
 	allocatedResource *resources.Resource
 	availableResource *resources.Resource
 	allocations       map[string]*AllocationInfo
+	queueAllocations  map[string]int   // number of allocations on the node per queue
+	drift             *AllocationDrift // allocation digest mismatch with the RM, nil if the last digest matched
 	schedulable       bool
+	quarantineEnd     time.Time // the node is quarantined until this time, zero if never quarantined
+	readySince        time.Time // the node has been registered and schedulable since this time
 
-	lock sync.RWMutex
+	lock locking.RWMutex
 }
 
 // Create a new node from the protocol object.
@@ -58,6 +70,7 @@ func NewNodeInfo(proto *si.NewNodeInfo) *NodeInfo {
 		allocatedResource: resources.NewResource(),
 		allocations:       make(map[string]*AllocationInfo),
 		schedulable:       true,
+		readySince:        time.Now(),
 	}
 	m.availableResource = m.totalResource.Clone()
 
@@ -83,6 +96,14 @@ func (ni *NodeInfo) GetAttribute(key string) string {
 	return ni.attributes[key]
 }
 
+// Call the function for each attribute of the node.
+// This is a lock free call. All attributes are considered read only
+func (ni *NodeInfo) RangeAttributes(fn func(key, value string)) {
+	for key, value := range ni.attributes {
+		fn(key, value)
+	}
+}
+
 // Return the currently allocated resource for the node.
 // It returns a cloned object as we do not want to allow modifications to be made to the
 // value of the node.
@@ -118,17 +139,18 @@ func (ni *NodeInfo) GetAllocation(uuid string) *AllocationInfo {
 	return ni.allocations[uuid]
 }
 
-// Check if the allocation fits int the nodes resources.
+// Check if the allocation fits int the nodes resources, the resource types ignored in the partition are not checked.
 // unlocked call as the totalResource can not be changed
 func (ni *NodeInfo) FitInNode(resRequest *resources.Resource) bool {
-	return resources.FitIn(ni.totalResource, resRequest)
+	return ni.ignored.fitIn(ni.totalResource, resRequest, false)
 }
 
-// Check if the allocation fits in the currently available resources.
+// Check if the allocation fits in the currently available resources, the resource types ignored in the partition are
+// not checked. This is the check for the allocation itself: the types that would have blocked it are counted.
 func (ni *NodeInfo) canAllocate(resRequest *resources.Resource) bool {
 	ni.lock.RLock()
 	defer ni.lock.RUnlock()
-	return resources.FitIn(ni.availableResource, resRequest)
+	return ni.ignored.fitIn(ni.availableResource, resRequest, true)
 }
 
 // Add the allocation to the node.Used resources will increase available will decrease.
@@ -143,6 +165,10 @@ func (ni *NodeInfo) AddAllocation(alloc *AllocationInfo) {
 	ni.allocations[alloc.AllocationProto.UUID] = alloc
 	ni.allocatedResource.AddTo(alloc.AllocatedResource)
 	ni.availableResource.SubFrom(alloc.AllocatedResource)
+	if ni.queueAllocations == nil {
+		ni.queueAllocations = make(map[string]int)
+	}
+	ni.queueAllocations[alloc.AllocationProto.QueueName]++
 }
 
 // Remove the allocation to the node.
@@ -158,11 +184,76 @@ func (ni *NodeInfo) RemoveAllocation(uuid string) *AllocationInfo {
 		delete(ni.allocations, uuid)
 		ni.allocatedResource.SubFrom(info.AllocatedResource)
 		ni.availableResource.AddTo(info.AllocatedResource)
+		queueName := info.AllocationProto.QueueName
+		if ni.queueAllocations[queueName] <= 1 {
+			delete(ni.queueAllocations, queueName)
+		} else {
+			ni.queueAllocations[queueName]--
+		}
 	}
 
 	return info
 }
 
+// Check if the node has an allocation from one of the queues or their children.
+// The queue names must be fully qualified.
+func (ni *NodeInfo) HasQueueAllocations(queueNames []string) bool {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	for queueName := range ni.queueAllocations {
+		for _, name := range queueNames {
+			if queueName == name || strings.HasPrefix(queueName, name+DOT) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Compare the allocation digest reported by the RM with the allocations on the node and track the drift.
+// Returns true if the node went into drift with this digest: the digests did not match for driftThreshold
+// consecutive reports. A matching digest clears the drift.
+func (ni *NodeInfo) checkAllocationDigest(digest string, now time.Time) (bool, error) {
+	rmCount, err := api.ParseAllocationDigest(digest)
+	if err != nil {
+		return false, err
+	}
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	uuids := make([]string, 0, len(ni.allocations))
+	for uuid := range ni.allocations {
+		uuids = append(uuids, uuid)
+	}
+	if api.GetAllocationDigest(uuids) == digest {
+		if ni.drift != nil && ni.drift.Mismatches >= driftThreshold {
+			log.Logger().Info("node allocation drift resolved",
+				zap.String("nodeID", ni.NodeID),
+				zap.Time("since", ni.drift.Since))
+		}
+		ni.drift = nil
+		return false, nil
+	}
+	if ni.drift == nil {
+		ni.drift = &AllocationDrift{Since: now}
+	}
+	ni.drift.CoreAllocations = len(uuids)
+	ni.drift.RMAllocations = rmCount
+	ni.drift.Mismatches++
+	return ni.drift.Mismatches == driftThreshold, nil
+}
+
+// Get a copy of the allocation drift of the node.
+// Returns nil if the node is not in drift: the mismatch has not been reported often enough or the digests match.
+func (ni *NodeInfo) GetAllocationDrift() *AllocationDrift {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	if ni.drift == nil || ni.drift.Mismatches < driftThreshold {
+		return nil
+	}
+	drift := *ni.drift
+	return &drift
+}
+
 // Get a copy of the allocations on this node
 func (ni *NodeInfo) GetAllAllocations() []*AllocationInfo {
 	ni.lock.RLock()
@@ -182,12 +273,41 @@ func (ni *NodeInfo) GetAllAllocations() []*AllocationInfo {
 func (ni *NodeInfo) SetSchedulable(schedulable bool) {
 	ni.lock.Lock()
 	defer ni.lock.Unlock()
+	// a node that becomes schedulable again is ready from now on
+	if schedulable && !ni.schedulable {
+		ni.readySince = time.Now()
+	}
 	ni.schedulable = schedulable
 }
 
+// Get the time since which the node has been registered and schedulable.
+func (ni *NodeInfo) GetReadySince() time.Time {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	return ni.readySince
+}
+
 // Can this node be used in scheduling.
+// A quarantined node cannot be used until the quarantine ends.
 func (ni *NodeInfo) IsSchedulable() bool {
 	ni.lock.RLock()
 	defer ni.lock.RUnlock()
-	return ni.schedulable
+	return ni.schedulable && !time.Now().Before(ni.quarantineEnd)
+}
+
+// Quarantine the node until the end time: the node is not used in scheduling until then.
+func (ni *NodeInfo) setQuarantine(end time.Time) {
+	ni.lock.Lock()
+	defer ni.lock.Unlock()
+	ni.quarantineEnd = end
+}
+
+// Get the end of the node quarantine, zero if the node is not quarantined.
+func (ni *NodeInfo) GetQuarantineEnd() time.Time {
+	ni.lock.RLock()
+	defer ni.lock.RUnlock()
+	if time.Now().Before(ni.quarantineEnd) {
+		return ni.quarantineEnd
+	}
+	return time.Time{}
 }
@@ -20,6 +20,7 @@ package cache
 
 import (
 	"testing"
+	"time"
 
 	"gotest.tools/assert"
 
@@ -191,6 +192,91 @@ func TestRemoveAllocation(t *testing.T) {
 	}
 }
 
+func TestHasQueueAllocations(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	node := NewNodeForTest("node-1", resources.Multiply(res, 10))
+	if node.HasQueueAllocations([]string{"root"}) {
+		t.Error("empty node should not have queue allocations")
+	}
+	node.AddAllocation(CreateMockAllocationInfo("app1", res, "1", "root.batch.daily", "node-1"))
+	node.AddAllocation(CreateMockAllocationInfo("app1", res, "2", "root.batch.daily", "node-1"))
+	for _, queues := range [][]string{{"root.batch.daily"}, {"root.batch"}, {"root.etl", "root"}} {
+		if !node.HasQueueAllocations(queues) {
+			t.Errorf("node should have allocations for queues %v", queues)
+		}
+	}
+	for _, queues := range [][]string{nil, {"root.batch.d"}, {"root.batch.daily.child"}, {"root.bat"}} {
+		if node.HasQueueAllocations(queues) {
+			t.Errorf("node should not have allocations for queues %v", queues)
+		}
+	}
+	// the queue is tracked until the last allocation is removed
+	node.RemoveAllocation("1")
+	if !node.HasQueueAllocations([]string{"root.batch"}) {
+		t.Error("node should still have an allocation for the queue")
+	}
+	node.RemoveAllocation("2")
+	if node.HasQueueAllocations([]string{"root.batch"}) {
+		t.Error("node should not have allocations for the queue after removal")
+	}
+}
+
+func TestAllocationDigest(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	node := NewNodeForTest("node-1", resources.Multiply(res, 10))
+	node.AddAllocation(CreateMockAllocationInfo("app1", res, "1", "root.a", "node-1"))
+	node.AddAllocation(CreateMockAllocationInfo("app1", res, "2", "root.a", "node-1"))
+	now := time.Now()
+
+	if _, err := node.checkAllocationDigest("invalid", now); err == nil {
+		t.Error("invalid digest should have failed")
+	}
+	// the RM lost an allocation: only reported after the threshold
+	rmDigest := api.GetAllocationDigest([]string{"1"})
+	for i := 1; i <= driftThreshold+1; i++ {
+		newDrift, err := node.checkAllocationDigest(rmDigest, now.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("digest check failed: %v", err)
+		}
+		if newDrift != (i == driftThreshold) {
+			t.Errorf("unexpected new drift %t for report %d", newDrift, i)
+		}
+		if drift := node.GetAllocationDrift(); (drift != nil) != (i >= driftThreshold) {
+			t.Errorf("unexpected drift %v for report %d", drift, i)
+		}
+	}
+	drift := node.GetAllocationDrift()
+	if drift.GetType() != DriftRMLost || drift.CoreAllocations != 2 || drift.RMAllocations != 1 || !drift.Since.Equal(now.Add(time.Second)) {
+		t.Errorf("unexpected drift: %v", drift)
+	}
+
+	// a matching digest clears the drift
+	if newDrift, err := node.checkAllocationDigest(api.GetAllocationDigest([]string{"2", "1"}), now); err != nil || newDrift {
+		t.Errorf("matching digest should not drift: %t, %v", newDrift, err)
+	}
+	if node.GetAllocationDrift() != nil {
+		t.Error("matching digest should have cleared the drift")
+	}
+
+	// core lost an allocation, same count with other allocations is a mismatch
+	for _, test := range []struct {
+		uuids     []string
+		driftType string
+	}{
+		{[]string{"1", "2", "3"}, DriftCoreLost},
+		{[]string{"1", "3"}, DriftMismatch},
+	} {
+		for i := 0; i < driftThreshold; i++ {
+			if _, err := node.checkAllocationDigest(api.GetAllocationDigest(test.uuids), now); err != nil {
+				t.Fatalf("digest check failed: %v", err)
+			}
+		}
+		if drift = node.GetAllocationDrift(); drift == nil || drift.GetType() != test.driftType {
+			t.Errorf("expected drift type %s got %v", test.driftType, drift)
+		}
+	}
+}
+
 func TestCanAllocate(t *testing.T) {
 	total := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 20})
 	node := NewNodeForTest("node-123", total)
@@ -256,4 +342,16 @@ func TestSchedulingState(t *testing.T) {
 	if node.IsSchedulable() {
 		t.Error("failed to modify node state: schedulable")
 	}
+
+	// the node is ready again from the time it becomes schedulable
+	registered := time.Now().Add(-time.Hour)
+	SetNodeReadySince(node, registered)
+	node.SetSchedulable(false)
+	if !node.GetReadySince().Equal(registered) {
+		t.Errorf("unschedulable node should not change the ready time: %v", node.GetReadySince())
+	}
+	node.SetSchedulable(true)
+	if !node.GetReadySince().After(registered) {
+		t.Errorf("schedulable node should be ready from now: %v", node.GetReadySince())
+	}
 }
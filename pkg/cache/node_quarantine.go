@@ -0,0 +1,141 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
+)
+
+const (
+	// default window in which the changes of a node are counted
+	DefaultQuarantineWindow = 10 * time.Minute
+	// default time a quarantined node is excluded from allocations
+	DefaultQuarantineCoolDown = 10 * time.Minute
+)
+
+// Track the registrations and removals of nodes in the partition.
+// A node that registers and is removed more often than allowed within the window is quarantined: the node is not used
+// for new allocations until the cool down has passed. Allocations placed on an unstable node are lost each time the
+// node is removed.
+type nodeQuarantine struct {
+	changes     int                    // number of changes allowed in the window, 0 means disabled
+	window      time.Duration          // window in which the changes are counted
+	coolDown    time.Duration          // time a quarantined node is excluded
+	history     map[string][]time.Time // the changes of a node within the window
+	quarantined map[string]time.Time   // the end of the quarantine per node
+}
+
+// Parse a quarantine duration from the config. The config has been validated: a failure means the default is used.
+func parseQuarantineDuration(value string, defaultDuration time.Duration) time.Duration {
+	if value == "" {
+		return defaultDuration
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return defaultDuration
+	}
+	return duration
+}
+
+// Set the node quarantine from the config. The tracked changes and current quarantines are kept unless the quarantine
+// is turned off.
+// Lock free call this must be called holding the partition lock or during create only
+func (pi *PartitionInfo) setNodeQuarantine(conf configs.PartitionNodeQuarantineConfig) {
+	pi.quarantine.changes = conf.Changes
+	pi.quarantine.window = parseQuarantineDuration(conf.Window, DefaultQuarantineWindow)
+	pi.quarantine.coolDown = parseQuarantineDuration(conf.CoolDown, DefaultQuarantineCoolDown)
+	if conf.Changes <= 0 || pi.quarantine.history == nil {
+		pi.quarantine.history = make(map[string][]time.Time)
+		pi.quarantine.quarantined = make(map[string]time.Time)
+	}
+}
+
+// Get the node quarantine as configured, empty if the quarantine is turned off.
+// Lock free call this must be called holding the partition lock
+func (pi *PartitionInfo) getNodeQuarantineConfig() configs.PartitionNodeQuarantineConfig {
+	if pi.quarantine.changes <= 0 {
+		return configs.PartitionNodeQuarantineConfig{}
+	}
+	return configs.PartitionNodeQuarantineConfig{
+		Changes:  pi.quarantine.changes,
+		Window:   pi.quarantine.window.String(),
+		CoolDown: pi.quarantine.coolDown.String(),
+	}
+}
+
+// Record a registration or removal of the node at the time. Changes and quarantines that are no longer relevant are
+// dropped. The node is quarantined if it changed more often than allowed within the window.
+// Returns the end of the quarantine of the node, zero if the node is not quarantined.
+// Lock free call this must be called holding the partition lock
+func (pi *PartitionInfo) recordNodeChange(nodeID string, now time.Time) time.Time {
+	q := &pi.quarantine
+	if q.changes <= 0 {
+		return time.Time{}
+	}
+	start := now.Add(-q.window)
+	for id, changes := range q.history {
+		if !changes[len(changes)-1].After(start) {
+			delete(q.history, id)
+		}
+	}
+	for id, end := range q.quarantined {
+		if !now.Before(end) {
+			delete(q.quarantined, id)
+		}
+	}
+
+	var changes []time.Time
+	for _, change := range q.history[nodeID] {
+		if change.After(start) {
+			changes = append(changes, change)
+		}
+	}
+	changes = append(changes, now)
+	q.history[nodeID] = changes
+	if len(changes) > q.changes {
+		end := now.Add(q.coolDown)
+		log.Logger().Warn("node quarantined, registered and removed too often",
+			zap.String("partition", pi.Name),
+			zap.String("nodeID", nodeID),
+			zap.Int("changes", len(changes)),
+			zap.Duration("window", q.window),
+			zap.Time("quarantineEnd", end))
+		metrics.GetSchedulerMetrics().IncQuarantinedNodes()
+		q.quarantined[nodeID] = end
+		return end
+	}
+	return q.quarantined[nodeID]
+}
+
+// Get the end of the quarantine of the node, zero if the node is not quarantined.
+func (pi *PartitionInfo) GetNodeQuarantineEnd(nodeID string) time.Time {
+	pi.RLock()
+	defer pi.RUnlock()
+	end := pi.quarantine.quarantined[nodeID]
+	if time.Now().Before(end) {
+		return end
+	}
+	return time.Time{}
+}
@@ -0,0 +1,108 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestRecordNodeChange(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    nodequarantine:
+      changes: 2
+      window: 1m
+      cooldown: 5m
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+
+	now := time.Now()
+	assert.Assert(t, partition.recordNodeChange("node-1", now).IsZero(), "first change should not quarantine")
+	assert.Assert(t, partition.recordNodeChange("node-1", now.Add(30*time.Second)).IsZero(), "second change should not quarantine")
+	// changes outside the window do not count
+	assert.Assert(t, partition.recordNodeChange("node-1", now.Add(65*time.Second)).IsZero(), "change outside the window should not quarantine")
+	end := partition.recordNodeChange("node-1", now.Add(70*time.Second))
+	assert.Equal(t, end, now.Add(70*time.Second+5*time.Minute), "third change in the window should quarantine")
+	// other nodes are not affected
+	assert.Assert(t, partition.recordNodeChange("node-2", now.Add(70*time.Second)).IsZero(), "other node should not be quarantined")
+	// the quarantine is kept for a change that does not flap
+	assert.Equal(t, partition.recordNodeChange("node-1", now.Add(4*time.Minute)), end, "quarantine should be kept")
+	// and is dropped after the cool down
+	assert.Assert(t, partition.recordNodeChange("node-1", now.Add(10*time.Minute)).IsZero(), "quarantine should have ended")
+
+	conf := partition.GetEffectiveConfig()
+	assert.Equal(t, conf.NodeQuarantine.Changes, 2)
+	assert.Equal(t, conf.NodeQuarantine.Window, "1m0s")
+	assert.Equal(t, conf.NodeQuarantine.CoolDown, "5m0s")
+
+	// turning the quarantine off drops the tracked nodes
+	conf.NodeQuarantine.Changes = 0
+	err = UpdatePartitionInfo(partition, conf)
+	assert.NilError(t, err, "partition update failed")
+	assert.Equal(t, len(partition.quarantine.history), 0, "tracked changes should be dropped")
+	assert.Assert(t, partition.recordNodeChange("node-1", now).IsZero(), "disabled quarantine should not quarantine")
+	assert.Equal(t, partition.GetEffectiveConfig().NodeQuarantine.Changes, 0)
+}
+
+func TestQuarantineFlappingNode(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    nodequarantine:
+      changes: 2
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	total := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+
+	node := NewNodeForTest("node-1", total)
+	err = partition.addNewNode(node, nil)
+	assert.NilError(t, err, "node add failed")
+	assert.Assert(t, node.IsSchedulable(), "node should be schedulable after the first registration")
+	partition.RemoveNode("node-1")
+
+	// the third change in the window quarantines the node
+	node = NewNodeForTest("node-1", total)
+	err = partition.addNewNode(node, nil)
+	assert.NilError(t, err, "node add failed")
+	assert.Assert(t, !node.IsSchedulable(), "flapping node should be quarantined")
+	end := node.GetQuarantineEnd()
+	assert.Assert(t, !end.IsZero(), "quarantine end should be set on the node")
+	assert.Equal(t, partition.GetNodeQuarantineEnd("node-1"), end)
+	assert.Assert(t, end.After(time.Now().Add(DefaultQuarantineCoolDown-time.Minute)), "default cool down should be used: %v", end)
+
+	// a node that registers during the quarantine stays quarantined
+	partition.RemoveNode("node-1")
+	node = NewNodeForTest("node-1", total)
+	err = partition.addNewNode(node, nil)
+	assert.NilError(t, err, "node add failed")
+	assert.Assert(t, !node.IsSchedulable(), "node should still be quarantined")
+	assert.Assert(t, !node.GetQuarantineEnd().Before(end), "quarantine should not be shortened")
+}
@@ -0,0 +1,137 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+// Number of events buffered per subscriber before events are dropped
+const partitionEventSubscriberBuffer = 256
+
+// Types of the events published by a partition
+const (
+	AllocationAdded         = "AllocationAdded"
+	AllocationReleased      = "AllocationReleased"
+	ApplicationStateChanged = "ApplicationStateChanged"
+	NodeAdded               = "NodeAdded"
+	NodeRemoved             = "NodeRemoved"
+)
+
+// An event that changed the partition. Only the fields relevant for the type are set.
+type PartitionEvent struct {
+	Type          string
+	Time          time.Time
+	Partition     string
+	ApplicationID string
+	QueueName     string
+	NodeID        string
+	UUID          string
+	Resource      *resources.Resource
+	Event         string
+	State         string
+}
+
+// The subscribers to the events of one partition.
+// A nil value is valid and drops all events: partitions created outside newPartitionInfo do not publish.
+type partitionEvents struct {
+	channels map[int]chan *PartitionEvent
+	nextID   int
+
+	sync.Mutex
+}
+
+func newPartitionEvents() *partitionEvents {
+	return &partitionEvents{
+		channels: make(map[int]chan *PartitionEvent),
+	}
+}
+
+func (pe *partitionEvents) subscribe() (<-chan *PartitionEvent, func()) {
+	pe.Lock()
+	defer pe.Unlock()
+	id := pe.nextID
+	pe.nextID++
+	events := make(chan *PartitionEvent, partitionEventSubscriberBuffer)
+	pe.channels[id] = events
+	return events, func() {
+		pe.Lock()
+		defer pe.Unlock()
+		delete(pe.channels, id)
+	}
+}
+
+// Send the event to all subscribers without blocking the caller.
+func (pe *partitionEvents) publish(event *PartitionEvent) {
+	if pe == nil {
+		return
+	}
+	pe.Lock()
+	defer pe.Unlock()
+	if len(pe.channels) == 0 {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for id, channel := range pe.channels {
+		select {
+		case channel <- event:
+		default:
+			log.Logger().Warn("partition event subscriber not keeping up, event dropped",
+				zap.Int("subscriber", id),
+				zap.String("partition", event.Partition),
+				zap.String("type", event.Type))
+		}
+	}
+}
+
+// Publish the allocation change, the allocation is not modified after it is created.
+func (pe *partitionEvents) publishAllocation(eventType, partition string, alloc *AllocationInfo) {
+	if pe == nil || alloc == nil {
+		return
+	}
+	pe.publish(&PartitionEvent{
+		Type:          eventType,
+		Partition:     partition,
+		ApplicationID: alloc.ApplicationID,
+		QueueName:     alloc.AllocationProto.QueueName,
+		NodeID:        alloc.AllocationProto.NodeID,
+		UUID:          alloc.AllocationProto.UUID,
+		Resource:      alloc.AllocatedResource,
+	})
+}
+
+// Subscribe to the events of the partition: allocations, releases, application state and node changes.
+// The returned function must be called to unsubscribe, the channel is not closed.
+func (pi *PartitionInfo) SubscribeEvents() (<-chan *PartitionEvent, func()) {
+	pi.Lock()
+	if pi.events == nil {
+		pi.events = newPartitionEvents()
+	}
+	events := pi.events
+	pi.Unlock()
+	return events.subscribe()
+}
@@ -0,0 +1,90 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func nextPartitionEvent(t *testing.T, events <-chan *PartitionEvent, eventType string) *PartitionEvent {
+	select {
+	case event := <-events:
+		assert.Equal(t, event.Type, eventType, "unexpected event type")
+		assert.Assert(t, !event.Time.IsZero(), "event time not set")
+		return event
+	default:
+		t.Fatalf("expected %s event was not published", eventType)
+	}
+	return nil
+}
+
+func TestPartitionEvents(t *testing.T) {
+	partition, err := CreatePartitionInfo([]byte(configDefault))
+	assert.NilError(t, err, "partition create failed")
+	events, unsubscribe := partition.SubscribeEvents()
+
+	appID := "app-1"
+	app := newApplicationInfo(appID, "default", "root.default")
+	err = partition.addNewApplication(app, true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+	err = app.HandleApplicationEvent(AcceptApplication)
+	assert.NilError(t, err, "accepting the application should not have failed")
+	event := nextPartitionEvent(t, events, ApplicationStateChanged)
+	assert.Equal(t, event.ApplicationID, appID, "unexpected application")
+	assert.Equal(t, event.QueueName, "root.default", "unexpected queue")
+	assert.Equal(t, event.State, Accepted.String(), "unexpected state")
+
+	capacity := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10})
+	err = partition.addNewNode(NewNodeForTest("node-1", capacity), nil)
+	assert.NilError(t, err, "add node to partition should not have failed")
+	event = nextPartitionEvent(t, events, NodeAdded)
+	assert.Equal(t, event.NodeID, "node-1", "unexpected node")
+	assert.Assert(t, resources.Equals(event.Resource, capacity), "unexpected node capacity")
+
+	alloc, err := partition.addNewAllocation(createAllocationProposal("root.default", "node-1", "alloc-1", appID))
+	assert.NilError(t, err, "adding allocation should not have failed")
+	event = nextPartitionEvent(t, events, AllocationAdded)
+	assert.Equal(t, event.UUID, alloc.AllocationProto.UUID, "unexpected allocation")
+	assert.Equal(t, event.NodeID, "node-1", "unexpected node")
+
+	toRelease := commonevents.NewReleaseAllocation(alloc.AllocationProto.UUID, appID, partition.Name, "", si.AllocationReleaseResponse_TerminationType(0))
+	partition.releaseAllocationsForApplication(toRelease)
+	event = nextPartitionEvent(t, events, AllocationReleased)
+	assert.Equal(t, event.UUID, alloc.AllocationProto.UUID, "unexpected allocation")
+
+	partition.RemoveNode("node-1")
+	event = nextPartitionEvent(t, events, NodeRemoved)
+	assert.Equal(t, event.NodeID, "node-1", "unexpected node")
+
+	// no events after unsubscribing
+	unsubscribe()
+	err = partition.addNewNode(NewNodeForTest("node-2", capacity), nil)
+	assert.NilError(t, err, "add node to partition should not have failed")
+	select {
+	case event = <-events:
+		t.Fatalf("unexpected event after unsubscribe: %v", event)
+	default:
+	}
+}
@@ -21,25 +21,40 @@ package cache
 import (
 	"fmt"
 	"math"
+	"reflect"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/looplab/fsm"
 	uuid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/common"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
 	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-core/pkg/plugins"
 	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
+// The margin below the utilization trigger at which preemption is released if the partition does not configure it.
+const DefaultUtilizationReleaseMargin = 10
+
+// The age after which a reservation for a removed ask or node is cleaned up if the partition does not configure it.
+const DefaultStaleReservationAge = 10 * time.Minute
+
+// The time the pending resource of a queue must stay above the autoscale watermark if the partition does not configure it.
+const DefaultAutoscaleThreshold = time.Minute
+
+// The number of nodes sampled for an ask in throughput mode if the partition does not configure it.
+const DefaultThroughputSampleSize = 100
+
 /* Related to partitions */
 type PartitionInfo struct {
 	Name string
@@ -47,19 +62,54 @@ type PartitionInfo struct {
 	RmID string
 
 	// Private fields need protection
-	allocations            map[string]*AllocationInfo  // allocations
-	nodes                  map[string]*NodeInfo        // nodes registered
-	applications           map[string]*ApplicationInfo // the application list
-	stateMachine           *fsm.FSM                    // the state of the queue for scheduling
-	stateTime              time.Time                   // last time the state was updated (needed for cleanup)
-	isPreemptable          bool                        // can allocations be preempted
-	rules                  *[]configs.PlacementRule    // placement rules to be loaded by the scheduler
-	userGroupCache         *security.UserGroupCache    // user cache per partition
-	clusterInfo            *ClusterInfo                // link back to the cluster info
-	totalPartitionResource *resources.Resource         // Total node resources
-	nodeSortingPolicy      *common.NodeSortingPolicy   // Global Node Sorting Policies
-
-	sync.RWMutex
+	allocations            map[string]*AllocationInfo          // allocations
+	nodes                  map[string]*NodeInfo                // nodes registered
+	applications           map[string]*ApplicationInfo         // the application list
+	stateMachine           *fsm.FSM                            // the state of the queue for scheduling
+	stateTime              time.Time                           // last time the state was updated (needed for cleanup)
+	isPreemptable          bool                                // can allocations be preempted
+	preemptionGracePeriod  time.Duration                       // time between the notification and the release of a checkpointable allocation
+	utilizationTrigger     configs.PreemptionUtilizationConfig // utilization based preemption trigger, release is always set
+	preemptionMinRuntime   time.Duration                       // allocations younger than this are not preempted
+	preemptionConfirm      time.Duration                       // time the RM has to confirm a preemption intent, 0 means no confirmation
+	emergencyPriority      int32                               // ask priority that ignores the minimum runtime, 0 means none
+	preemptionSettle       time.Duration                       // time after a reload changed the limits of a queue in which it is not preempted
+	maxReservations        int                                 // maximum number of reservations outstanding, 0 means no limit
+	maxReservedResource    *resources.Resource                 // maximum resource of all reservations outstanding, nil means no limit
+	staleReservationAge    time.Duration                       // age after which a reservation for a removed ask or node is cleaned up
+	queueIdleTimeout       time.Duration                       // time an unmanaged queue must be idle before it is removed
+	queueDrainTimeout      time.Duration                       // time a managed queue can drain before its applications are forced out
+	queueDrainTarget       string                              // queue the applications of a queue are moved to after the drain timeout
+	appCompletionGrace     time.Duration                       // time a running application must be idle before it is completed, 0 means never
+	rules                  *[]configs.PlacementRule            // placement rules to be loaded by the scheduler
+	rulesVersion           uint64                              // version of the placement rules, changes with the queue hierarchy on reload
+	limits                 []configs.Limit                     // user and group limits as configured, not enforced
+	userGroupCache         *security.UserGroupCache            // user cache per partition
+	userGroupConf          configs.UserGroupResolverConfig     // user group resolver as configured
+	clusterInfo            *ClusterInfo                        // link back to the cluster info
+	totalPartitionResource *resources.Resource                 // Total node resources
+	nodeSortingPolicy      *common.NodeSortingPolicy           // Global Node Sorting Policies
+	nodeSortWeights        map[string]float64                  // resource weights for sorting the nodes, nil means no weights
+	nodePoolAttribute      string                              // node attribute with the node pool name, cannot be changed
+	nodePoolResources      map[string]*resources.Resource      // Total node resources per node pool
+	resourceAliases        map[string]string                   // resource type alias to canonical type for nodes and asks
+	resourceUnits          map[string]int64                    // scheduler units in one configured unit per resource type
+	rmResourceUnits        map[string]int64                    // RM units in one configured unit per resource type
+	rmUnitFactors          map[string]int64                    // scheduler units in one RM unit per resource type, nil means no conversion
+	autoscaleWatermark     *resources.Resource                 // pending resource of a queue that triggers autoscale events, nil means none
+	autoscaleThreshold     time.Duration                       // time a queue must stay above the watermark before an event is sent
+	askBudgetTime          time.Duration                       // maximum time evaluating nodes for one ask, 0 means no limit
+	askBudgetNodes         int                                 // maximum number of nodes evaluated for one ask, 0 means no limit
+	throughput             bool                                // throughput mode: sampled nodes and batched pending propagation
+	throughputSampleSize   int                                 // number of nodes sampled and sorted for an ask in throughput mode
+	ignored                *ignoredResources                   // resource types not enforced in the node fit checks, shared with the nodes
+	hierarchy              *hierarchyLimits                    // limits on the depth and fan-out of the queues, shared with the root queue
+	maintenance            []*maintenanceWindow                // scheduled capacity reductions
+	quarantine             nodeQuarantine                      // registrations and removals of nodes, quarantined nodes
+	replicatedUUIDs        map[string][]string                 // UUIDs of replicated allocations not yet reported by a node
+	events                 *partitionEvents                    // subscribers to the partition events
+
+	locking.RWMutex
 }
 
 // Create a new partition from scratch based on a validated configuration.
@@ -75,6 +125,13 @@ func newPartitionInfo(partition configs.PartitionConfig, rmID string, info *Clus
 	p.nodes = make(map[string]*NodeInfo)
 	p.applications = make(map[string]*ApplicationInfo)
 	p.totalPartitionResource = resources.NewResource()
+	p.nodePoolResources = make(map[string]*resources.Resource)
+	p.nodePoolAttribute = partition.NodePools.Attribute
+	p.ignored = newIgnoredResources()
+	p.ignored.setTypes(partition.IgnoredResourceTypes)
+	p.hierarchy = newHierarchyLimits()
+	p.hierarchy.setLimits(partition.Hierarchy)
+	p.events = newPartitionEvents()
 	log.Logger().Info("creating partition",
 		zap.String("partitionName", p.Name),
 		zap.String("rmID", p.RmID))
@@ -86,10 +143,14 @@ func newPartitionInfo(partition configs.PartitionConfig, rmID string, info *Clus
 	if err != nil {
 		return nil, err
 	}
+	// the limits must be set before the queues are added below the root
+	root.hierarchy = p.hierarchy
 	err = addQueueInfo(queueConf.Queues, root)
 	if err != nil {
 		return nil, err
 	}
+	root.updateDerivedGuarantee()
+	root.ignored = p.ignored
 	p.Root = root
 	log.Logger().Info("root queue added",
 		zap.String("partitionName", p.Name),
@@ -97,11 +158,33 @@ func newPartitionInfo(partition configs.PartitionConfig, rmID string, info *Clus
 
 	// set preemption needed flag
 	p.isPreemptable = partition.Preemption.Enabled
+	p.preemptionGracePeriod = parseGracePeriod(partition.Preemption)
+	p.utilizationTrigger = parseUtilizationTrigger(partition.Preemption)
+	p.preemptionMinRuntime = parseMinRuntime(partition.Preemption)
+	p.preemptionConfirm = parseConfirmTimeout(partition.Preemption)
+	p.emergencyPriority = partition.Preemption.EmergencyPriority
+	p.preemptionSettle = parseSettlePeriod(partition.Preemption)
+	p.queueIdleTimeout = parseQueueIdleTimeout(partition.QueueIdleTimeout)
+	p.queueDrainTimeout = parseQueueIdleTimeout(partition.QueueDrainTimeout)
+	p.queueDrainTarget = strings.ToLower(partition.QueueDrainTarget)
+	p.appCompletionGrace = parseAppCompletionGrace(partition.AppCompletionGracePeriod)
+	p.resourceAliases = partition.ResourceAliases
+	p.resourceUnits = partition.ResourceUnits
+	p.rmResourceUnits = partition.RMResourceUnits
+	p.rmUnitFactors = configs.RMUnitFactors(&partition)
+	p.setReservationLimits(partition.Reservations)
+	p.setAutoscale(partition.Autoscale)
+	p.setAskBudget(partition.AskBudget)
+	p.setThroughput(partition.Throughput)
+	p.setMaintenance(partition.Maintenance)
+	p.setNodeQuarantine(partition.NodeQuarantine)
+	p.nodeSortWeights = partition.NodeSortPolicy.ResourceWeights
 
 	p.rules = &partition.PlacementRules
+	p.limits = partition.Limits
 	// get the user group cache for the partition
-	// TODO get the resolver from the config
-	p.userGroupCache = security.GetUserGroupCache("")
+	p.userGroupConf = partition.UserGroups
+	p.userGroupCache = newUserGroupCache(partition.UserGroups)
 
 	// TODO Need some more cleaner interface here.
 	var configuredPolicy common.SortingPolicy
@@ -138,6 +221,8 @@ func addQueueInfo(conf []configs.QueueConfig, parent *QueueInfo) error {
 				return err
 			}
 		}
+		// the children are complete: a queue that derives its guarantee sums their guarantees
+		thisQueue.updateDerivedGuarantee()
 	}
 	return nil
 }
@@ -164,19 +249,553 @@ func (pi *PartitionInfo) GetTotalPartitionResource() *resources.Resource {
 	return pi.totalPartitionResource
 }
 
+// Return the total node resources of the node pool.
+func (pi *PartitionInfo) GetNodePoolResource(pool string) *resources.Resource {
+	pi.RLock()
+	defer pi.RUnlock()
+	if total := pi.nodePoolResources[pool]; total != nil {
+		return total.Clone()
+	}
+	return resources.NewResource()
+}
+
+// Get the node pool for the node based on the node pool attribute of the partition.
+// Nodes without the attribute, or all nodes if the partition has no attribute set, are part of the default pool.
+// Lock free call, the attribute cannot change
+func (pi *PartitionInfo) getNodePool(node *NodeInfo) string {
+	if pi.nodePoolAttribute != "" {
+		if pool := node.GetAttribute(pi.nodePoolAttribute); pool != "" {
+			return pool
+		}
+	}
+	return configs.DefaultNodePool
+}
+
 // Does the partition allow pre-emption?
 func (pi *PartitionInfo) NeedPreemption() bool {
 	return pi.isPreemptable
 }
 
+// Get the grace period between the preemption notification and the release of a checkpointable allocation.
+func (pi *PartitionInfo) GetPreemptionGracePeriod() time.Duration {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.preemptionGracePeriod
+}
+
+// Convert the grace period from the preemption config. The config has been validated: a failure means no grace period.
+func parseGracePeriod(preemption configs.PartitionPreemptionConfig) time.Duration {
+	if preemption.GracePeriod == "" {
+		return 0
+	}
+	gracePeriod, err := time.ParseDuration(preemption.GracePeriod)
+	if err != nil || gracePeriod < 0 {
+		return 0
+	}
+	return gracePeriod
+}
+
+// Get the time the RM has to respond to a preemption intent before the allocation is released.
+// A value of 0 means the partition does not use confirmation and preempted allocations are released directly.
+func (pi *PartitionInfo) GetPreemptionConfirmTimeout() time.Duration {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.preemptionConfirm
+}
+
+// Convert the confirm timeout from the preemption config. The config has been validated: a failure means no confirmation.
+func parseConfirmTimeout(preemption configs.PartitionPreemptionConfig) time.Duration {
+	if preemption.ConfirmTimeout == "" {
+		return 0
+	}
+	confirmTimeout, err := time.ParseDuration(preemption.ConfirmTimeout)
+	if err != nil || confirmTimeout < 0 {
+		return 0
+	}
+	return confirmTimeout
+}
+
+// Get the minimum runtime of an allocation before it can be preempted, 0 means no protection.
+func (pi *PartitionInfo) GetPreemptionMinRuntime() time.Duration {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.preemptionMinRuntime
+}
+
+// Get the ask priority at or above which the preemption minimum runtime is ignored, 0 means no emergency priority.
+func (pi *PartitionInfo) GetPreemptionEmergencyPriority() int32 {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.emergencyPriority
+}
+
+// Convert the minimum runtime from the preemption config. The config has been validated: a failure means no minimum.
+func parseMinRuntime(preemption configs.PartitionPreemptionConfig) time.Duration {
+	if preemption.MinRuntime == "" {
+		return 0
+	}
+	minRuntime, err := time.ParseDuration(preemption.MinRuntime)
+	if err != nil || minRuntime < 0 {
+		return 0
+	}
+	return minRuntime
+}
+
+// Get the settle period after a reload changed the guaranteed or max resource of a queue, 0 means no settle period.
+// The allocations of the queue and its children are not preempted during the settle period.
+func (pi *PartitionInfo) GetPreemptionSettlePeriod() time.Duration {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.preemptionSettle
+}
+
+// Convert the settle period from the preemption config. The config has been validated: a failure means no settle period.
+func parseSettlePeriod(preemption configs.PartitionPreemptionConfig) time.Duration {
+	if preemption.SettlePeriod == "" {
+		return 0
+	}
+	settlePeriod, err := time.ParseDuration(preemption.SettlePeriod)
+	if err != nil || settlePeriod < 0 {
+		return 0
+	}
+	return settlePeriod
+}
+
+// Create the user group cache for the partition. The config has been validated.
+// A partition without a resolver or cache times set uses the shared cache that does not resolve users.
+func newUserGroupCache(conf configs.UserGroupResolverConfig) *security.UserGroupCache {
+	if conf.Type == security.NoResolver && conf.CacheTTL == "" && conf.FailedTTL == "" {
+		return security.GetUserGroupCache("")
+	}
+	var ugCache *security.UserGroupCache
+	switch conf.Type {
+	case security.OSResolver:
+		ugCache = security.GetUserGroupCacheOS()
+	case security.StaticResolver:
+		ugCache = security.GetUserGroupCacheStatic(conf.Groups)
+	case security.PluginResolver:
+		ugCache = security.GetUserGroupCacheResolver(resolvePluginGroups)
+	default:
+		ugCache = security.GetUserGroupNoResolve()
+	}
+	ugCache.SetTTL(parseCacheTTL(conf.CacheTTL), parseCacheTTL(conf.FailedTTL))
+	ugCache.Start()
+	return ugCache
+}
+
+// Resolve the groups using the plugin registered by the RM.
+// The plugin is looked up on each call: the RM registers the plugin after the partitions are created.
+func resolvePluginGroups(user string) ([]string, error) {
+	plugin := plugins.GetGroupResolverPlugin()
+	if plugin == nil {
+		return nil, fmt.Errorf("no group resolver plugin registered")
+	}
+	return plugin.ResolveGroups(user)
+}
+
+// Convert the configured cache time, an unset or invalid time means the default.
+func parseCacheTTL(ttl string) time.Duration {
+	if ttl == "" {
+		return 0
+	}
+	duration, err := time.ParseDuration(ttl)
+	if err != nil || duration < 0 {
+		return 0
+	}
+	return duration
+}
+
+// Stop the user group cache of the partition when the partition is removed.
+func (pi *PartitionInfo) stopUserGroupCache() {
+	pi.RLock()
+	defer pi.RUnlock()
+	pi.userGroupCache.Stop()
+}
+
+// Get the time an unmanaged queue must be idle before it is removed, 0 means it is removed as soon as it is empty.
+func (pi *PartitionInfo) GetQueueIdleTimeout() time.Duration {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.queueIdleTimeout
+}
+
+// Kill the application, see ClusterInfo.KillApplication.
+// The removal is processed asynchronously by the scheduler, a partition not linked to a cluster only changes the
+// application state.
+func (pi *PartitionInfo) KillApplication(appID string) error {
+	if err := pi.killApplication(appID); err != nil {
+		return err
+	}
+	if pi.clusterInfo != nil {
+		pi.clusterInfo.removeKilledApplication(pi.Name, appID)
+	}
+	return nil
+}
+
+// Move the application to the killed state.
+func (pi *PartitionInfo) killApplication(appID string) error {
+	app := pi.getApplication(appID)
+	if app == nil {
+		return fmt.Errorf("application %s not found in partition %s", appID, pi.Name)
+	}
+	if err := app.HandleApplicationEvent(KillApplication); err != nil {
+		return api.NewError(api.ErrInvalidState, "application %s cannot be killed: %v", appID, err)
+	}
+	log.Logger().Info("killing application",
+		zap.String("applicationID", appID),
+		zap.String("partitionName", pi.Name))
+	return nil
+}
+
+// Get the time a managed queue can drain before its remaining applications are moved or killed, 0 means no timeout.
+func (pi *PartitionInfo) GetQueueDrainTimeout() time.Duration {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.queueDrainTimeout
+}
+
+// Get the queue the remaining applications of a queue are moved to after the drain timeout.
+// An empty string means the applications are killed.
+func (pi *PartitionInfo) GetQueueDrainTarget() string {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.queueDrainTarget
+}
+
+// Convert the configured queue idle or drain timeout. The config has been validated: a failure means no timeout.
+func parseQueueIdleTimeout(timeout string) time.Duration {
+	if timeout == "" {
+		return 0
+	}
+	idleTimeout, err := time.ParseDuration(timeout)
+	if err != nil || idleTimeout < 0 {
+		return 0
+	}
+	return idleTimeout
+}
+
+// Get the time a running application without allocations and pending asks is kept before it is completed.
+// 0 means the application is never completed by the scheduler, it is kept until the shim removes it.
+func (pi *PartitionInfo) GetAppCompletionGracePeriod() time.Duration {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.appCompletionGrace
+}
+
+// Convert the configured application completion grace period. The config has been validated: a failure means
+// applications are not completed by the scheduler.
+func parseAppCompletionGrace(grace string) time.Duration {
+	if grace == "" {
+		return 0
+	}
+	gracePeriod, err := time.ParseDuration(grace)
+	if err != nil || gracePeriod < 0 {
+		return 0
+	}
+	return gracePeriod
+}
+
+// Get the utilization based preemption trigger: the utilization percentage at which preemption is triggered and
+// the percentage below which it is released. A trigger of 0 means preemption is not triggered by the utilization.
+func (pi *PartitionInfo) GetUtilizationTrigger() (int, int) {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.utilizationTrigger.Trigger, pi.utilizationTrigger.Release
+}
+
+// Convert the utilization trigger from the preemption config, setting the default release if not configured.
+// The config has been validated.
+func parseUtilizationTrigger(preemption configs.PartitionPreemptionConfig) configs.PreemptionUtilizationConfig {
+	utilization := preemption.Utilization
+	if utilization.Trigger > 0 && utilization.Release == 0 {
+		utilization.Release = utilization.Trigger - DefaultUtilizationReleaseMargin
+		if utilization.Release < 0 {
+			utilization.Release = 0
+		}
+	}
+	return utilization
+}
+
+// Get the utilization of the partition: the largest percentage of the partition total resource allocated over all
+// resource types. A partition without resources is not utilized.
+func (pi *PartitionInfo) GetUtilization() int {
+	pi.RLock()
+	if pi.totalPartitionResource == nil {
+		pi.RUnlock()
+		return 0
+	}
+	total := pi.totalPartitionResource.Clone()
+	pi.RUnlock()
+	allocated := pi.Root.GetAllocatedResource()
+	utilization := 0
+	for name, quantity := range total.Resources {
+		if quantity <= 0 {
+			continue
+		}
+		if percentage := int(allocated.Resources[name] * 100 / quantity); percentage > utilization {
+			utilization = percentage
+		}
+	}
+	return utilization
+}
+
+// Get the limits for the reservations outstanding in the partition.
+// A count of 0 or a nil resource means the limit is not set.
+func (pi *PartitionInfo) GetReservationLimits() (int, *resources.Resource) {
+	pi.RLock()
+	defer pi.RUnlock()
+	if pi.maxReservedResource == nil {
+		return pi.maxReservations, nil
+	}
+	return pi.maxReservations, pi.maxReservedResource.Clone()
+}
+
+// Get the age after which a reservation for a removed ask or node is cleaned up.
+func (pi *PartitionInfo) GetStaleReservationAge() time.Duration {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.staleReservationAge
+}
+
+// Set the reservation limits from the config. The config has been validated: a failure means no limit.
+// The stale reservation age falls back to the default if not set or not valid.
+// Lock free call this must be called holding the partition lock or during create only
+func (pi *PartitionInfo) setReservationLimits(conf configs.PartitionReservationConfig) {
+	pi.maxReservations = conf.MaxReservations
+	pi.maxReservedResource = nil
+	if len(conf.MaxResource) != 0 {
+		maxResource, err := resources.NewResourceFromConf(conf.MaxResource)
+		if err == nil {
+			pi.maxReservedResource = maxResource
+		}
+	}
+	pi.staleReservationAge = DefaultStaleReservationAge
+	if conf.StaleAge != "" {
+		staleAge, err := time.ParseDuration(conf.StaleAge)
+		if err == nil && staleAge > 0 {
+			pi.staleReservationAge = staleAge
+		}
+	}
+}
+
+// Get the autoscale watermark and threshold of the partition.
+// A nil watermark means that no autoscale events are sent for the partition.
+func (pi *PartitionInfo) GetAutoscaleWatermark() (*resources.Resource, time.Duration) {
+	pi.RLock()
+	defer pi.RUnlock()
+	if pi.autoscaleWatermark == nil {
+		return nil, pi.autoscaleThreshold
+	}
+	return pi.autoscaleWatermark.Clone(), pi.autoscaleThreshold
+}
+
+// Set the autoscale watermark and threshold from the config. The config has been validated: a failure means no
+// watermark. The threshold falls back to the default if not set or not valid.
+// Lock free call this must be called holding the partition lock or during create only
+func (pi *PartitionInfo) setAutoscale(conf configs.PartitionAutoscaleConfig) {
+	pi.autoscaleWatermark = nil
+	if len(conf.Watermark) != 0 {
+		watermark, err := resources.NewResourceFromConf(conf.Watermark)
+		if err == nil {
+			pi.autoscaleWatermark = watermark
+		}
+	}
+	pi.autoscaleThreshold = DefaultAutoscaleThreshold
+	if conf.Threshold != "" {
+		threshold, err := time.ParseDuration(conf.Threshold)
+		if err == nil && threshold > 0 {
+			pi.autoscaleThreshold = threshold
+		}
+	}
+}
+
+// Get the budget for the node evaluation of one ask: the maximum time and number of nodes.
+// A value of 0 means that there is no limit.
+func (pi *PartitionInfo) GetAskBudget() (time.Duration, int) {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.askBudgetTime, pi.askBudgetNodes
+}
+
+// Set the ask budget from the config. The config has been validated: a failure means no limit.
+// Lock free call this must be called holding the partition lock or during create only
+func (pi *PartitionInfo) setAskBudget(conf configs.PartitionAskBudgetConfig) {
+	pi.askBudgetNodes = conf.MaxNodes
+	pi.askBudgetTime = 0
+	if conf.MaxTime != "" {
+		maxTime, err := time.ParseDuration(conf.MaxTime)
+		if err == nil && maxTime > 0 {
+			pi.askBudgetTime = maxTime
+		}
+	}
+}
+
+// Get the throughput mode of the partition: is the mode enabled and the number of nodes sampled for an ask.
+func (pi *PartitionInfo) GetThroughput() (bool, int) {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.throughput, pi.throughputSampleSize
+}
+
+// Set the throughput mode from the config. The config has been validated: a sample size that is not set means the
+// default is used.
+// Lock free call this must be called holding the partition lock or during create only
+func (pi *PartitionInfo) setThroughput(conf configs.PartitionThroughputConfig) {
+	pi.throughput = conf.Enabled
+	pi.throughputSampleSize = DefaultThroughputSampleSize
+	if conf.SampleSize > 0 {
+		pi.throughputSampleSize = conf.SampleSize
+	}
+}
+
 // Return the config element for the placement rules
 func (pi *PartitionInfo) GetRules() []configs.PlacementRule {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.getRules()
+}
+
+// Return the config element for the placement rules with the version of the rules.
+// The rules are replaced together with the queue hierarchy on a config reload: the rules of a version only
+// reference queues that exist in the hierarchy they were loaded with.
+func (pi *PartitionInfo) GetVersionedRules() ([]configs.PlacementRule, uint64) {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.getRules(), pi.rulesVersion
+}
+
+// Return the version of the placement rules.
+func (pi *PartitionInfo) GetRulesVersion() uint64 {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.rulesVersion
+}
+
+// Lock free call, must be called holding the partition lock.
+func (pi *PartitionInfo) getRules() []configs.PlacementRule {
 	if pi.rules == nil {
 		return []configs.PlacementRule{}
 	}
 	return *pi.rules
 }
 
+// Get the effective configuration of the partition.
+// Defaults are filled in: the node sorting policy, the stale reservation age and the root queue max are always set.
+// The name of the partition is the name as used in the configuration, without the cluster ID.
+func (pi *PartitionInfo) GetEffectiveConfig() configs.PartitionConfig {
+	pi.RLock()
+	conf := configs.PartitionConfig{
+		Name:           common.GetPartitionNameWithoutClusterID(pi.Name),
+		PlacementRules: pi.getRules(),
+		Limits:         pi.limits,
+		Preemption: configs.PartitionPreemptionConfig{
+			Enabled:           pi.isPreemptable,
+			Utilization:       pi.utilizationTrigger,
+			EmergencyPriority: pi.emergencyPriority,
+		},
+		NodeSortPolicy: configs.NodeSortingPolicy{
+			Type:            pi.GetNodeSortingPolicy().String(),
+			ResourceWeights: pi.nodeSortWeights,
+		},
+		Reservations: configs.PartitionReservationConfig{
+			MaxReservations: pi.maxReservations,
+			MaxResource:     pi.maxReservedResource.ToConf(),
+			StaleAge:        pi.staleReservationAge.String(),
+		},
+		NodePools: configs.PartitionNodePoolConfig{
+			Attribute: pi.nodePoolAttribute,
+		},
+		UserGroups:      pi.userGroupConf,
+		ResourceAliases: pi.resourceAliases,
+		ResourceUnits:   pi.resourceUnits,
+		RMResourceUnits: pi.rmResourceUnits,
+	}
+	if pi.autoscaleWatermark != nil {
+		conf.Autoscale = configs.PartitionAutoscaleConfig{
+			Watermark: pi.autoscaleWatermark.ToConf(),
+			Threshold: pi.autoscaleThreshold.String(),
+		}
+	}
+	conf.AskBudget.MaxNodes = pi.askBudgetNodes
+	conf.IgnoredResourceTypes = pi.ignored.getTypes()
+	conf.Hierarchy = pi.hierarchy.getConfig()
+	if pi.throughput {
+		conf.Throughput = configs.PartitionThroughputConfig{
+			Enabled:    true,
+			SampleSize: pi.throughputSampleSize,
+		}
+	}
+	conf.Maintenance = pi.getMaintenanceConfig()
+	conf.NodeQuarantine = pi.getNodeQuarantineConfig()
+	if pi.askBudgetTime > 0 {
+		conf.AskBudget.MaxTime = pi.askBudgetTime.String()
+	}
+	if pi.queueIdleTimeout > 0 {
+		conf.QueueIdleTimeout = pi.queueIdleTimeout.String()
+	}
+	if pi.queueDrainTimeout > 0 {
+		conf.QueueDrainTimeout = pi.queueDrainTimeout.String()
+		conf.QueueDrainTarget = pi.queueDrainTarget
+	}
+	if pi.appCompletionGrace > 0 {
+		conf.AppCompletionGracePeriod = pi.appCompletionGrace.String()
+	}
+	if pi.preemptionGracePeriod > 0 {
+		conf.Preemption.GracePeriod = pi.preemptionGracePeriod.String()
+	}
+	if pi.preemptionMinRuntime > 0 {
+		conf.Preemption.MinRuntime = pi.preemptionMinRuntime.String()
+	}
+	if pi.preemptionConfirm > 0 {
+		conf.Preemption.ConfirmTimeout = pi.preemptionConfirm.String()
+	}
+	if pi.preemptionSettle > 0 {
+		conf.Preemption.SettlePeriod = pi.preemptionSettle.String()
+	}
+	pi.RUnlock()
+	// the queues lock themselves
+	conf.Queues = []configs.QueueConfig{pi.Root.GetEffectiveConfig()}
+	return conf
+}
+
+// Get the queue updates for all queues in the partition keyed by the queue path.
+// Lock free call, the queues lock themselves.
+func (pi *PartitionInfo) getQueueUpdates() map[string]*api.QueueUpdate {
+	updates := make(map[string]*api.QueueUpdate)
+	addQueueUpdates(pi.Root, pi.Name, updates)
+	if factors := pi.GetRMUnitFactors(); len(factors) > 0 {
+		for _, update := range updates {
+			toRMQueueUpdate(update, factors)
+		}
+	}
+	return updates
+}
+
+// Convert the resources and the resource valued properties of the update in place into the units of the RM.
+func toRMQueueUpdate(update *api.QueueUpdate, factors map[string]int64) {
+	update.MaxResource = resources.ToRMUnitsProto(update.MaxResource, factors)
+	update.GuaranteedResource = resources.ToRMUnitsProto(update.GuaranteedResource, factors)
+	for key, value := range update.Properties {
+		if !configs.IsResourceProperty(key) {
+			continue
+		}
+		// a percentage is not a resource and is passed on as is
+		if res, err := resources.ParseResource(value); err == nil {
+			// the units of DAOString are the units of the scheduler: the RM gets the quantities without a unit
+			update.Properties[key] = strings.TrimPrefix(resources.ToRMUnits(res, factors).String(), "map")
+		}
+	}
+}
+
+// Add the update for the queue and all its children to the updates.
+func addQueueUpdates(queue *QueueInfo, partitionName string, updates map[string]*api.QueueUpdate) {
+	update := queue.getQueueUpdate(partitionName)
+	updates[update.QueueName] = update
+	for _, child := range queue.GetCopyOfChildren() {
+		addQueueUpdates(child, partitionName, updates)
+	}
+}
+
 // Is bin-packing scheduling enabled?
 // TODO: more finer enum based return model here is better instead of bool.
 func (pi *PartitionInfo) GetNodeSortingPolicy() common.SortingPolicy {
@@ -187,6 +806,14 @@ func (pi *PartitionInfo) GetNodeSortingPolicy() common.SortingPolicy {
 	return pi.nodeSortingPolicy.PolicyType
 }
 
+// Get the resource weights used to sort the nodes, nil if the partition does not set weights.
+// The returned map must not be modified.
+func (pi *PartitionInfo) GetNodeSortWeights() map[string]float64 {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.nodeSortWeights
+}
+
 // Add a new node to the partition.
 // If a partition is not active a new node can not be added as the partition is about to be removed.
 // A new node must be added to the partition before the existing allocations can be processed. This
@@ -201,16 +828,39 @@ func (pi *PartitionInfo) addNewNode(node *NodeInfo, existingAllocations []*si.Al
 		zap.String("partition", pi.Name))
 
 	if pi.isDraining() || pi.isStopped() {
-		return fmt.Errorf("partition %s is stopped cannot add a new node %s", pi.Name, node.NodeID)
+		return api.NewError(api.ErrInvalidState, "partition %s is stopped cannot add a new node %s", pi.Name, node.NodeID)
 	}
 
 	if pi.nodes[node.NodeID] != nil {
 		return fmt.Errorf("partition %s has an existing node %s, node name must be unique", pi.Name, node.NodeID)
 	}
 
-	// update the resources available in the cluster
+	// the shim can use different names for the resource types: convert before the node is used
+	if len(pi.resourceAliases) > 0 {
+		node.totalResource = resources.Canonicalize(node.totalResource, pi.resourceAliases)
+		node.availableResource = node.totalResource.Clone()
+		for _, alloc := range existingAllocations {
+			resources.CanonicalizeProto(alloc.ResourcePerAlloc, pi.resourceAliases)
+		}
+	}
+	// the shim can use different units for the resource types: convert into scheduler units
+	if len(pi.rmUnitFactors) > 0 {
+		node.totalResource = resources.ToSchedulerUnits(node.totalResource, pi.rmUnitFactors)
+		node.availableResource = node.totalResource.Clone()
+		for _, alloc := range existingAllocations {
+			resources.ToSchedulerUnitsProto(alloc.ResourcePerAlloc, pi.rmUnitFactors)
+		}
+	}
+
+	// update the resources available in the cluster and the pool
 	pi.totalPartitionResource.AddTo(node.totalResource)
 	pi.Root.setMaxResource(pi.totalPartitionResource)
+	node.Pool = pi.getNodePool(node)
+	node.ignored = pi.ignored
+	if pi.nodePoolResources[node.Pool] == nil {
+		pi.nodePoolResources[node.Pool] = resources.NewResource()
+	}
+	pi.nodePoolResources[node.Pool].AddTo(node.totalResource)
 
 	// Node is added to the system to allow processing of the allocations
 	pi.nodes[node.NodeID] = node
@@ -260,8 +910,19 @@ func (pi *PartitionInfo) addNewNode(node *NodeInfo, existingAllocations []*si.Al
 		}
 	}
 
+	// a node that flaps is not used for new allocations: quarantine after the existing allocations are recovered
+	if end := pi.recordNodeChange(node.NodeID, time.Now()); !end.IsZero() {
+		node.setQuarantine(end)
+	}
+
 	// Node is added update the metrics
 	metrics.GetSchedulerMetrics().IncActiveNodes()
+	pi.events.publish(&PartitionEvent{
+		Type:      NodeAdded,
+		Partition: pi.Name,
+		NodeID:    node.NodeID,
+		Resource:  node.GetCapacity(),
+	})
 	log.Logger().Info("added node to partition",
 		zap.String("nodeID", node.NodeID),
 		zap.String("partition", pi.Name))
@@ -283,6 +944,62 @@ func (pi *PartitionInfo) addNodeReportedAllocations(allocation *si.Allocation) (
 	}, true)
 }
 
+// Return the resource type aliases of the partition.
+func (pi *PartitionInfo) GetResourceAliases() map[string]string {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.resourceAliases
+}
+
+// Return the scheduler units in one configured unit per resource type of the partition.
+func (pi *PartitionInfo) GetResourceUnits() map[string]int64 {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.resourceUnits
+}
+
+// Return the scheduler units in one RM unit per resource type of the partition, nil if the RM uses scheduler units.
+func (pi *PartitionInfo) GetRMUnitFactors() map[string]int64 {
+	pi.RLock()
+	defer pi.RUnlock()
+	return pi.rmUnitFactors
+}
+
+// Return the resource with the quantities converted into the units of the RM, the resource passed in is not changed.
+func (pi *PartitionInfo) ToRMResource(res *si.Resource) *si.Resource {
+	return resources.ToRMUnitsProto(res, pi.GetRMUnitFactors())
+}
+
+// Return the allocation as it is sent to the RM: a copy with the resource in the units of the RM if the RM uses its
+// own units, the allocation itself otherwise.
+func (pi *PartitionInfo) toRMAllocation(alloc *si.Allocation) *si.Allocation {
+	factors := pi.GetRMUnitFactors()
+	if len(factors) == 0 {
+		return alloc
+	}
+	converted := *alloc
+	converted.ResourcePerAlloc = resources.ToRMUnitsProto(alloc.ResourcePerAlloc, factors)
+	return &converted
+}
+
+// Replace the resource types in the ask that are an alias with the canonical type and convert the quantities from
+// RM units into scheduler units. The ask is changed in place.
+func (pi *PartitionInfo) canonicalizeAsk(ask *si.AllocationAsk) {
+	pi.RLock()
+	defer pi.RUnlock()
+	resources.CanonicalizeProto(ask.ResourceAsk, pi.resourceAliases)
+	resources.ToSchedulerUnitsProto(ask.ResourceAsk, pi.rmUnitFactors)
+}
+
+// Replace the resource types in the node update that are an alias with the canonical type and convert the quantities
+// from RM units into scheduler units. The update is changed in place.
+func (pi *PartitionInfo) canonicalizeNodeUpdate(update *si.UpdateNodeInfo) {
+	pi.RLock()
+	defer pi.RUnlock()
+	resources.CanonicalizeProto(update.SchedulableResource, pi.resourceAliases)
+	resources.ToSchedulerUnitsProto(update.SchedulableResource, pi.rmUnitFactors)
+}
+
 // Remove a node from the partition.
 // This locks the partition and calls the internal unlocked version.
 func (pi *PartitionInfo) RemoveNode(nodeID string) []*AllocationInfo {
@@ -313,10 +1030,19 @@ func (pi *PartitionInfo) removeNodeInternal(nodeID string) []*AllocationInfo {
 	released := pi.removeNodeAllocations(node)
 	pi.totalPartitionResource.SubFrom(node.totalResource)
 	pi.Root.setMaxResource(pi.totalPartitionResource)
+	if total := pi.nodePoolResources[node.Pool]; total != nil {
+		total.SubFrom(node.totalResource)
+	}
 
 	// Remove node from list of tracked nodes
 	delete(pi.nodes, nodeID)
+	pi.recordNodeChange(nodeID, time.Now())
 	metrics.GetSchedulerMetrics().DecActiveNodes()
+	pi.events.publish(&PartitionEvent{
+		Type:      NodeRemoved,
+		Partition: pi.Name,
+		NodeID:    nodeID,
+	})
 
 	log.Logger().Info("node removed",
 		zap.String("partitionName", pi.Name),
@@ -332,6 +1058,7 @@ func (pi *PartitionInfo) removeNodeAllocations(node *NodeInfo) []*AllocationInfo
 	// walk over all allocations still registered for this node
 	for _, alloc := range node.GetAllAllocations() {
 		var queue *QueueInfo = nil
+		var user string
 		allocID := alloc.AllocationProto.UUID
 		// since we are not locking the node and or application we could have had an update while processing
 		// note that we do not return the allocation if the app or allocation is not found and assume that it
@@ -346,6 +1073,7 @@ func (pi *PartitionInfo) removeNodeAllocations(node *NodeInfo) []*AllocationInfo
 				continue
 			}
 			queue = app.leafQueue
+			user = app.GetUser().User
 		} else {
 			log.Logger().Info("app is not found, skipping while removing the node",
 				zap.String("appID", alloc.ApplicationID),
@@ -360,10 +1088,21 @@ func (pi *PartitionInfo) removeNodeAllocations(node *NodeInfo) []*AllocationInfo
 					zap.String("appID", alloc.ApplicationID),
 					zap.Error(err))
 			}
+			if err := queue.decNodePoolAllocatedResource(node.Pool, alloc.AllocatedResource); err != nil {
+				log.Logger().Warn("failed to release node pool resources from queue",
+					zap.String("appID", alloc.ApplicationID),
+					zap.Error(err))
+			}
+			if err := queue.decUserAllocatedResource(user, alloc.AllocatedResource); err != nil {
+				log.Logger().Warn("failed to release user resources from queue",
+					zap.String("appID", alloc.ApplicationID),
+					zap.Error(err))
+			}
 		}
 
 		// the allocation is removed so add it to the list that we return
 		released = append(released, alloc)
+		pi.events.publishAllocation(AllocationReleased, pi.Name, alloc)
 		log.Logger().Info("allocation removed",
 			zap.String("allocationId", allocID),
 			zap.String("nodeID", node.NodeID))
@@ -384,12 +1123,12 @@ func (pi *PartitionInfo) addNewApplication(info *ApplicationInfo, failIfExist bo
 		zap.String("queue", info.QueueName),
 		zap.String("partitionName", pi.Name))
 	if pi.isDraining() || pi.isStopped() {
-		return fmt.Errorf("partition %s is stopped cannot add a new application %s", pi.Name, info.ApplicationID)
+		return api.NewRejectionError(api.RejectionPartitionStopped, "partition %s is stopped cannot add a new application %s", pi.Name, info.ApplicationID)
 	}
 
 	if app := pi.applications[info.ApplicationID]; app != nil {
 		if failIfExist {
-			return fmt.Errorf("application %s already exists in partition %s", info.ApplicationID, pi.Name)
+			return api.NewRejectionError(api.RejectionApplicationExists, "application %s already exists in partition %s", info.ApplicationID, pi.Name)
 		}
 		log.Logger().Info("app already exists in partition",
 			zap.String("appID", info.ApplicationID),
@@ -400,6 +1139,7 @@ func (pi *PartitionInfo) addNewApplication(info *ApplicationInfo, failIfExist bo
 	// queue is checked later and overwritten based on placement rules
 	info.leafQueue = pi.getQueue(info.QueueName)
 	// Add app to the partition
+	info.events = pi.events
 	pi.applications[info.ApplicationID] = info
 
 	log.Logger().Info("app added to partition",
@@ -427,6 +1167,15 @@ func (pi *PartitionInfo) GetNode(nodeID string) *NodeInfo {
 	return pi.nodes[nodeID]
 }
 
+// Get the allocation for the uuid from the partition.
+// Returns nil if the allocation is not found.
+func (pi *PartitionInfo) GetAllocation(uuid string) *AllocationInfo {
+	pi.RLock()
+	defer pi.RUnlock()
+
+	return pi.allocations[uuid]
+}
+
 // Remove one or more allocations for an application.
 // Returns all removed allocations.
 // If no specific allocation is specified via a uuid all allocations are removed.
@@ -446,7 +1195,8 @@ func (pi *PartitionInfo) releaseAllocationsForApplication(toRelease *commonevent
 
 	// First delete from app
 	var queue *QueueInfo = nil
-	if app := pi.applications[toRelease.ApplicationID]; app != nil {
+	app := pi.applications[toRelease.ApplicationID]
+	if app != nil {
 		// when uuid not specified, remove all allocations from the app
 		if toRelease.UUID == "" {
 			log.Logger().Debug("remove all allocations",
@@ -484,6 +1234,17 @@ func (pi *PartitionInfo) releaseAllocationsForApplication(toRelease *commonevent
 		}
 		node.RemoveAllocation(alloc.AllocationProto.UUID)
 		totalReleasedResource.AddTo(alloc.AllocatedResource)
+		if queue != nil {
+			// the resources of a preempted allocation are freed for a different ask: do not prefer the node
+			if toRelease.ReleaseType != si.AllocationReleaseResponse_PREEMPTED_BY_SCHEDULER {
+				app.addReuseHint(alloc, queue.getReuseTTL())
+			}
+			if err := queue.decNodePoolAllocatedResource(node.Pool, alloc.AllocatedResource); err != nil {
+				log.Logger().Warn("failed to release node pool resources",
+					zap.String("appID", toRelease.ApplicationID),
+					zap.Error(err))
+			}
+		}
 	}
 
 	// this nil check is not really needed as we can only reach here with a queue set, IDE complains without this
@@ -494,11 +1255,17 @@ func (pi *PartitionInfo) releaseAllocationsForApplication(toRelease *commonevent
 				zap.Any("appID", toRelease.ApplicationID),
 				zap.Error(err))
 		}
+		if err := queue.decUserAllocatedResource(app.GetUser().User, totalReleasedResource); err != nil {
+			log.Logger().Warn("failed to release user resources",
+				zap.Any("appID", toRelease.ApplicationID),
+				zap.Error(err))
+		}
 	}
 
 	// Update global allocation list
 	for _, alloc := range allocationsToRelease {
 		delete(pi.allocations, alloc.AllocationProto.UUID)
+		pi.events.publishAllocation(AllocationReleased, pi.Name, alloc)
 	}
 
 	log.Logger().Info("allocation removed",
@@ -514,7 +1281,7 @@ func (pi *PartitionInfo) releaseAllocationsForApplication(toRelease *commonevent
 // If access outside is needed a locked version must used, see addNewAllocation
 func (pi *PartitionInfo) addNewAllocationInternal(alloc *commonevents.AllocationProposal, nodeReported bool) (*AllocationInfo, error) {
 	if pi.isStopped() {
-		return nil, fmt.Errorf("partition %s is stopped cannot add new allocation %s", pi.Name, alloc.AllocationKey)
+		return nil, api.NewError(api.ErrInvalidState, "partition %s is stopped cannot add new allocation %s", pi.Name, alloc.AllocationKey)
 	}
 
 	log.Logger().Debug("adding allocation",
@@ -543,13 +1310,13 @@ func (pi *PartitionInfo) addNewAllocationInternal(alloc *commonevents.Allocation
 	// allocation inherits the app queue as the source of truth
 	if queue = pi.getQueue(app.QueueName); queue == nil || !queue.IsLeafQueue() {
 		metrics.GetSchedulerMetrics().IncSchedulingError()
-		return nil, fmt.Errorf("queue does not exist or is not a leaf queue %s", app.QueueName)
+		return nil, api.NewError(api.ErrQueueNotFound, "queue does not exist or is not a leaf queue %s", app.QueueName)
 	}
 
 	// check the node status again
 	if !node.IsSchedulable() {
 		metrics.GetSchedulerMetrics().IncSchedulingError()
-		return nil, fmt.Errorf("node %s is not in schedulable state", node.NodeID)
+		return nil, api.NewError(api.ErrInvalidState, "node %s is not in schedulable state", node.NodeID)
 	}
 
 	// Does the new allocation exceed the node's available resource?
@@ -564,12 +1331,42 @@ func (pi *PartitionInfo) addNewAllocationInternal(alloc *commonevents.Allocation
 	// Only check if it is allocated not when it is node reported.
 	if err := queue.IncAllocatedResource(alloc.AllocatedResource, nodeReported); err != nil {
 		metrics.GetSchedulerMetrics().IncSchedulingError()
-		return nil, fmt.Errorf("cannot allocate resource from application %s: %v ",
-			alloc.ApplicationID, err)
+		return nil, api.WrapError(err, "cannot allocate resource from application %s", alloc.ApplicationID)
+	}
+	// same check for the max resource in the node pool (recursive), undo the queue change on failure
+	if err := queue.incNodePoolAllocatedResource(node.Pool, alloc.AllocatedResource, nodeReported); err != nil {
+		if decErr := queue.decAllocatedResource(alloc.AllocatedResource); decErr != nil {
+			log.Logger().Warn("failed to undo queue allocation",
+				zap.String("appID", alloc.ApplicationID),
+				zap.Error(decErr))
+		}
+		metrics.GetSchedulerMetrics().IncSchedulingError()
+		return nil, api.WrapError(err, "cannot allocate resource from application %s", alloc.ApplicationID)
+	}
+	// same check for the max resource of the user (recursive), undo the queue and node pool changes on failure
+	if err := queue.incUserAllocatedResource(app.GetUser().User, alloc.AllocatedResource, nodeReported); err != nil {
+		if decErr := queue.decAllocatedResource(alloc.AllocatedResource); decErr != nil {
+			log.Logger().Warn("failed to undo queue allocation",
+				zap.String("appID", alloc.ApplicationID),
+				zap.Error(decErr))
+		}
+		if decErr := queue.decNodePoolAllocatedResource(node.Pool, alloc.AllocatedResource); decErr != nil {
+			log.Logger().Warn("failed to undo node pool allocation",
+				zap.String("appID", alloc.ApplicationID),
+				zap.Error(decErr))
+		}
+		metrics.GetSchedulerMetrics().IncSchedulingError()
+		return nil, api.WrapError(err, "cannot allocate resource from application %s", alloc.ApplicationID)
 	}
 
-	// Start allocation
-	allocationUUID := pi.getNewAllocationUUID()
+	// Start allocation, an allocation reported by a node keeps the UUID it had on the active instance
+	allocationUUID := ""
+	if nodeReported {
+		allocationUUID = pi.takeReplicatedUUID(alloc.ApplicationID, alloc.AllocationKey, alloc.NodeID)
+	}
+	if allocationUUID == "" {
+		allocationUUID = pi.getNewAllocationUUID()
+	}
 	allocation := NewAllocationInfo(allocationUUID, alloc)
 
 	node.AddAllocation(allocation)
@@ -577,6 +1374,7 @@ func (pi *PartitionInfo) addNewAllocationInternal(alloc *commonevents.Allocation
 	app.addAllocation(allocation)
 
 	pi.allocations[allocation.AllocationProto.UUID] = allocation
+	pi.events.publishAllocation(AllocationAdded, pi.Name, allocation)
 
 	log.Logger().Debug("added allocation",
 		zap.String("partitionName", pi.Name),
@@ -655,6 +1453,7 @@ func (pi *PartitionInfo) RemoveApplication(appID string) (*ApplicationInfo, []*A
 				continue
 			} else {
 				delete(pi.allocations, currentUUID)
+				pi.events.publishAllocation(AllocationReleased, pi.Name, alloc)
 			}
 
 			// Remove from node
@@ -672,6 +1471,13 @@ func (pi *PartitionInfo) RemoveApplication(appID string) (*ApplicationInfo, []*A
 					zap.String("nodeID", alloc.AllocationProto.NodeID))
 				continue
 			}
+			if queue := app.leafQueue; queue != nil {
+				if err := queue.decNodePoolAllocatedResource(node.Pool, alloc.AllocatedResource); err != nil {
+					log.Logger().Error("failed to release node pool resources for app",
+						zap.String("appID", app.ApplicationID),
+						zap.Error(err))
+				}
+			}
 		}
 
 		// we should never have an error, cache is in an inconsistent state if this happens
@@ -682,6 +1488,11 @@ func (pi *PartitionInfo) RemoveApplication(appID string) (*ApplicationInfo, []*A
 					zap.String("appID", app.ApplicationID),
 					zap.Error(err))
 			}
+			if err := queue.decUserAllocatedResource(app.GetUser().User, totalAppAllocated); err != nil {
+				log.Logger().Error("failed to release user resources for app",
+					zap.String("appID", app.ApplicationID),
+					zap.Error(err))
+			}
 		}
 	}
 	// Remove app from cache now that everything is cleaned up
@@ -695,6 +1506,129 @@ func (pi *PartitionInfo) RemoveApplication(appID string) (*ApplicationInfo, []*A
 	return app, allocations
 }
 
+// Move the application to another leaf queue. The allocated resources of the application are released from the
+// current queue and charged to the new queue, including the node pool and user usage. The move fails if the
+// allocated resources do not fit in the maximum of the new queue, the usage of the current queue is restored.
+func (pi *PartitionInfo) MoveApplication(appID, queueName string) error {
+	pi.Lock()
+	defer pi.Unlock()
+
+	app := pi.applications[appID]
+	if app == nil {
+		return fmt.Errorf("application %s not found in partition %s", appID, pi.Name)
+	}
+	target := pi.getQueue(queueName)
+	if target == nil || !target.IsLeafQueue() {
+		return api.NewError(api.ErrQueueNotFound, "queue does not exist or is not a leaf queue %s", queueName)
+	}
+	if !target.IsRunning() {
+		return api.NewError(api.ErrInvalidState, "queue %s is not running, cannot move application %s", queueName, appID)
+	}
+	source := app.leafQueue
+	if source == target {
+		return nil
+	}
+	user := app.GetUser().User
+	allocated := app.GetAllocatedResource()
+	poolAllocated := pi.getPoolAllocated(app)
+	// release from the current queue first: both queues share at least the root and the maximum of the shared
+	// parents must not be checked with the usage counted twice
+	if source != nil {
+		if err := releaseQueueResources(source, user, allocated, poolAllocated); err != nil {
+			log.Logger().Error("failed to release resources for moved app",
+				zap.String("appID", appID),
+				zap.String("queue", source.GetQueuePath()),
+				zap.Error(err))
+		}
+	}
+	if err := chargeQueueResources(target, user, allocated, poolAllocated, false); err != nil {
+		if source != nil {
+			if undoErr := chargeQueueResources(source, user, allocated, poolAllocated, true); undoErr != nil {
+				log.Logger().Error("failed to restore resources for app after failed move",
+					zap.String("appID", appID),
+					zap.String("queue", source.GetQueuePath()),
+					zap.Error(undoErr))
+			}
+		}
+		return api.WrapError(err, "cannot move application %s to queue %s", appID, queueName)
+	}
+	app.SetQueue(target)
+	sourcePath := ""
+	if source != nil {
+		sourcePath = source.GetQueuePath()
+	}
+	moveQueueAppMetrics(app.GetApplicationState(), sourcePath, target.GetQueuePath())
+
+	log.Logger().Info("app moved to queue",
+		zap.String("appID", appID),
+		zap.String("partitionName", pi.Name),
+		zap.String("queue", queueName),
+		zap.Any("allocatedResource", allocated))
+	return nil
+}
+
+// Return the allocated resources of the application per node pool.
+// Lock free call this must be called holding the partition lock
+func (pi *PartitionInfo) getPoolAllocated(app *ApplicationInfo) map[string]*resources.Resource {
+	poolAllocated := make(map[string]*resources.Resource)
+	for _, alloc := range app.GetAllAllocations() {
+		node := pi.nodes[alloc.AllocationProto.NodeID]
+		if node == nil {
+			continue
+		}
+		poolAllocated[node.Pool] = resources.Add(poolAllocated[node.Pool], alloc.AllocatedResource)
+	}
+	return poolAllocated
+}
+
+// Charge the allocated resources to the queue, the node pools and the user (recursively). The maximum resources are
+// not checked if force is set. All changes are undone if one of the charges fails.
+func chargeQueueResources(queue *QueueInfo, user string, allocated *resources.Resource, poolAllocated map[string]*resources.Resource, force bool) error {
+	if err := queue.IncAllocatedResource(allocated, force); err != nil {
+		return err
+	}
+	charged := make(map[string]*resources.Resource)
+	var err error
+	for pool, alloc := range poolAllocated {
+		if err = queue.incNodePoolAllocatedResource(pool, alloc, force); err != nil {
+			break
+		}
+		charged[pool] = alloc
+	}
+	if err == nil {
+		err = queue.incUserAllocatedResource(user, allocated, force)
+	}
+	if err != nil {
+		// the undo can only fail if the queue is already inconsistent: the failure is returned by the release
+		_ = releaseQueueResources(queue, "", allocated, charged)
+		return err
+	}
+	return nil
+}
+
+// Release the allocated resources from the queue, the node pools and the user (recursively).
+// The user is not released if it is empty. The first failure is returned, all releases are attempted.
+func releaseQueueResources(queue *QueueInfo, user string, allocated *resources.Resource, poolAllocated map[string]*resources.Resource) error {
+	var errs []error
+	if err := queue.decAllocatedResource(allocated); err != nil {
+		errs = append(errs, err)
+	}
+	for pool, alloc := range poolAllocated {
+		if err := queue.decNodePoolAllocatedResource(pool, alloc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if user != "" {
+		if err := queue.decUserAllocatedResource(user, allocated); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
 // Return a copy of all the nodes registers to this partition
 func (pi *PartitionInfo) CopyNodeInfos() []*NodeInfo {
 	pi.RLock()
@@ -713,7 +1647,7 @@ func (pi *PartitionInfo) CopyNodeInfos() []*NodeInfo {
 
 func checkAndSetResource(resource *resources.Resource) string {
 	if resource != nil {
-		return strings.Trim(resource.String(), "map")
+		return resource.DAOString()
 	}
 	return ""
 }
@@ -731,9 +1665,13 @@ func (pi *PartitionInfo) GetQueueInfos() []dao.QueueDAOInfo {
 	info := dao.QueueDAOInfo{}
 	info.QueueName = pi.Root.Name
 	info.Status = pi.Root.stateMachine.Current()
+	info.StartDelayed = pi.Root.IsStartDelayed()
 	info.Capacities = dao.QueueCapacity{
 		Capacity:        checkAndSetResource(pi.Root.GetGuaranteedResource()),
 		MaxCapacity:     checkAndSetResource(pi.Root.GetMaxResource()),
+		SoftMaxCapacity: checkAndSetResource(pi.Root.GetSoftMaxResource()),
+		OverSoftMax:     pi.Root.IsOverSoftMax(),
+		OverMax:         pi.Root.IsOverMax(),
 		UsedCapacity:    checkAndSetResource(pi.Root.GetAllocatedResource()),
 		AbsUsedCapacity: "20",
 	}
@@ -753,9 +1691,13 @@ func GetChildQueueInfos(info *QueueInfo) []dao.QueueDAOInfo {
 		queue := dao.QueueDAOInfo{}
 		queue.QueueName = child.Name
 		queue.Status = child.stateMachine.Current()
+		queue.StartDelayed = child.IsStartDelayed()
 		queue.Capacities = dao.QueueCapacity{
 			Capacity:        checkAndSetResource(child.GetGuaranteedResource()),
 			MaxCapacity:     checkAndSetResource(child.GetMaxResource()),
+			SoftMaxCapacity: checkAndSetResource(child.GetSoftMaxResource()),
+			OverSoftMax:     child.IsOverSoftMax(),
+			OverMax:         child.IsOverMax(),
 			UsedCapacity:    checkAndSetResource(child.GetAllocatedResource()),
 			AbsUsedCapacity: "20",
 		}
@@ -833,26 +1775,89 @@ func (pi *PartitionInfo) getQueue(name string) *QueueInfo {
 
 // Update the queues in the partition based on the reloaded and checked config
 func (pi *PartitionInfo) updatePartitionDetails(partition configs.PartitionConfig) error {
-	pi.RLock()
-	defer pi.RUnlock()
+	pi.Lock()
+	defer pi.Unlock()
 	// update preemption needed flag
 	pi.isPreemptable = partition.Preemption.Enabled
+	pi.preemptionGracePeriod = parseGracePeriod(partition.Preemption)
+	pi.utilizationTrigger = parseUtilizationTrigger(partition.Preemption)
+	pi.preemptionMinRuntime = parseMinRuntime(partition.Preemption)
+	pi.preemptionConfirm = parseConfirmTimeout(partition.Preemption)
+	pi.emergencyPriority = partition.Preemption.EmergencyPriority
+	pi.preemptionSettle = parseSettlePeriod(partition.Preemption)
+	pi.queueIdleTimeout = parseQueueIdleTimeout(partition.QueueIdleTimeout)
+	pi.queueDrainTimeout = parseQueueIdleTimeout(partition.QueueDrainTimeout)
+	pi.queueDrainTarget = strings.ToLower(partition.QueueDrainTarget)
+	pi.appCompletionGrace = parseAppCompletionGrace(partition.AppCompletionGracePeriod)
+	// registered nodes and asks are not changed: aliases only apply to new nodes and asks
+	pi.resourceAliases = partition.ResourceAliases
+	pi.resourceUnits = partition.ResourceUnits
+	pi.rmResourceUnits = partition.RMResourceUnits
+	pi.rmUnitFactors = configs.RMUnitFactors(&partition)
+	// the ignored types are shared with the registered nodes: changed in place
+	pi.ignored.setTypes(partition.IgnoredResourceTypes)
+	// the limits are shared with the root queue: changed in place, existing queues are not checked again
+	pi.hierarchy.setLimits(partition.Hierarchy)
+	pi.setReservationLimits(partition.Reservations)
+	pi.setAutoscale(partition.Autoscale)
+	pi.setAskBudget(partition.AskBudget)
+	pi.setThroughput(partition.Throughput)
+	pi.setMaintenance(partition.Maintenance)
+	pi.setNodeQuarantine(partition.NodeQuarantine)
+	pi.nodeSortWeights = partition.NodeSortPolicy.ResourceWeights
+	pi.limits = partition.Limits
+	// replace the user group cache: cached users are resolved again using the new config
+	if !reflect.DeepEqual(pi.userGroupConf, partition.UserGroups) {
+		pi.userGroupCache.Stop()
+		pi.userGroupConf = partition.UserGroups
+		pi.userGroupCache = newUserGroupCache(partition.UserGroups)
+	}
+	// the node pool of registered nodes is fixed
+	if partition.NodePools.Attribute != pi.nodePoolAttribute {
+		log.Logger().Warn("node pool attribute cannot be changed, restart required",
+			zap.String("partitionName", pi.Name),
+			zap.String("currentAttribute", pi.nodePoolAttribute),
+			zap.String("newAttribute", partition.NodePools.Attribute))
+	}
 	// start at the root: there is only one queue
 	queueConf := partition.Queues[0]
 	root := pi.getQueue(queueConf.Name)
+	limits := root.getLimits(make(map[string]queueLimits))
 	err := root.updateQueueProps(queueConf)
 	if err != nil {
 		return err
 	}
-	return pi.updateQueues(queueConf.Queues, root)
+	err = pi.updateQueues(queueConf.Queues, root)
+	if err != nil {
+		return err
+	}
+	// queues without a configured guarantee get it derived from the updated hierarchy
+	root.deriveGuaranteedResource()
+	// compared after the guarantees are derived: a changed derived guarantee is a change of the queue
+	root.markChangedLimits(limits, time.Now())
+	// replace the placement rules under the same lock as the queues: a rule never sees a hierarchy it was not
+	// validated against
+	if !reflect.DeepEqual(pi.getRules(), partition.PlacementRules) {
+		pi.rules = &partition.PlacementRules
+		pi.rulesVersion++
+	}
+	return nil
 }
 
 // Update the passed in queues and then do this recursively for the children
 func (pi *PartitionInfo) updateQueues(config []configs.QueueConfig, parent *QueueInfo) error {
 	// get the name of the passed in queue
 	parentPath := parent.GetQueuePath() + DOT
-	// keep track of which children we have updated
-	visited := map[string]bool{}
+	// remove all children that are not in the config before adding new ones: they do not count towards the limits
+	configured := map[string]bool{}
+	for _, queueConfig := range config {
+		configured[queueConfig.Name] = true
+	}
+	for childName, childQueue := range parent.children {
+		if !configured[childName] {
+			childQueue.MarkQueueForRemoval()
+		}
+	}
 	// walk over the queues recursively
 	for _, queueConfig := range config {
 		pathName := parentPath + queueConfig.Name
@@ -870,13 +1875,6 @@ func (pi *PartitionInfo) updateQueues(config []configs.QueueConfig, parent *Queu
 		if err != nil {
 			return err
 		}
-		visited[queueConfig.Name] = true
-	}
-	// remove all children that were not visited
-	for childName, childQueue := range parent.children {
-		if !visited[childName] {
-			childQueue.MarkQueueForRemoval()
-		}
 	}
 	return nil
 }
@@ -919,6 +1917,24 @@ func (pi *PartitionInfo) isStopped() bool {
 	return pi.stateMachine.Current() == Stopped.String()
 }
 
+// Pause or resume the partition. A paused partition is stopped: no new applications, nodes or allocations are
+// accepted and the scheduler skips the partition. A partition that is marked for deletion cannot be paused or resumed.
+func (pi *PartitionInfo) SetPaused(paused bool) error {
+	if pi.isDraining() {
+		return api.NewError(api.ErrInvalidState, "partition %s is marked for deletion and cannot be paused or resumed", pi.Name)
+	}
+	event := Start
+	if paused {
+		event = Stop
+	}
+	return pi.handlePartitionEvent(event)
+}
+
+// Is the partition paused, see SetPaused.
+func (pi *PartitionInfo) IsPaused() bool {
+	return pi.isStopped()
+}
+
 // Create the new queue that is returned from a rule.
 // It creates a queue with all parents needed.
 func (pi *PartitionInfo) CreateQueues(queueName string) error {
@@ -945,15 +1961,22 @@ func (pi *PartitionInfo) CreateQueues(queueName string) error {
 			zap.String("leafQueue", current))
 		return fmt.Errorf("cannot create queue below leaf queue '%s'", current)
 	}
+	// check the hierarchy limits before any queue is created: a rule must not leave a partial hierarchy behind
+	if err := parent.CheckHierarchyLimits(toCreate[len(toCreate)-1], len(toCreate)); err != nil {
+		log.Logger().Debug("Cannot create queue outside the hierarchy limits",
+			zap.String("requestedQueue", queueName),
+			zap.Error(err))
+		return fmt.Errorf("cannot create queue '%s': %v", queueName, err)
+	}
 	log.Logger().Debug("Queue can be created, creating queue(s)")
 	for i := len(toCreate) - 1; i >= 0; i-- {
-		// everything is checked and there should be no errors
 		var err error
 		parent, err = NewUnmanagedQueue(toCreate[i], i == 0, parent)
 		if err != nil {
 			log.Logger().Warn("Queue auto create failed unexpected",
 				zap.String("queueName", queueName),
 				zap.Error(err))
+			return err
 		}
 	}
 	return nil
@@ -970,10 +1993,12 @@ func (pi *PartitionInfo) convertUGI(ugi *si.UserGroupInformation) (security.User
 // which is a slice with 10 elements,
 // each element represents a range of resource usage,
 // such as
-//   0: 0%->10%
-//   1: 10% -> 20%
-//   ...
-//   9: 90% -> 100%
+//
+//	0: 0%->10%
+//	1: 10% -> 20%
+//	...
+//	9: 90% -> 100%
+//
 // the element value represents number of nodes fall into this bucket.
 // if slice[9] = 3, this means there are 3 nodes resource usage is in the range 80% to 90%.
 func (pi *PartitionInfo) CalculateNodesResourceUsage() map[string][]int {
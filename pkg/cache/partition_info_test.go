@@ -25,8 +25,11 @@ import (
 
 	"gotest.tools/assert"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -448,6 +451,12 @@ func TestAddNewAllocation(t *testing.T) {
 	if partition.allocations[alloc.AllocationProto.UUID] == nil {
 		t.Errorf("add allocation to partition not found in the allocation list")
 	}
+	if found := partition.GetAllocation(alloc.AllocationProto.UUID); found == nil || found.AllocationProto.NodeID != nodeID {
+		t.Errorf("allocation lookup by uuid did not return the allocation on node %s: %v", nodeID, found)
+	}
+	if partition.GetAllocation("unknown-uuid") != nil {
+		t.Errorf("allocation lookup for unknown uuid should not return an allocation")
+	}
 	// check the leaf queue usage
 	qi := partition.getQueue(queueName)
 	if qi.allocatedResource.Resources[resources.MEMORY] != 1 {
@@ -501,6 +510,241 @@ func TestAddNewAllocation(t *testing.T) {
 	}
 }
 
+func TestNodePools(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    nodepools:
+      attribute: si.io/node-pool
+    queues:
+      - name: root
+        queues:
+          - name: spot
+            nodepools:
+              - name: spot
+                max:
+                  memory: 2
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	appID := "app-1"
+	queueName := "root.spot"
+	err = partition.addNewApplication(newApplicationInfo(appID, "default", queueName), true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+
+	nodeRes := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10})
+	node1 := NewNodeForTest("node-1", nodeRes)
+	node2 := NewNodeForTest("node-2", nodeRes)
+	node2.initializeAttribute(map[string]string{"si.io/node-pool": "spot"})
+	err = partition.addNewNode(node1, nil)
+	assert.NilError(t, err, "add node-1 to partition should not have failed")
+	err = partition.addNewNode(node2, nil)
+	assert.NilError(t, err, "add node-2 to partition should not have failed")
+	assert.Equal(t, node1.Pool, configs.DefaultNodePool, "node without attribute should be in the default pool")
+	assert.Equal(t, node2.Pool, "spot", "node pool not set from attribute")
+	assert.Assert(t, resources.Equals(partition.GetNodePoolResource("spot"), nodeRes), "unexpected spot pool total")
+	assert.Assert(t, resources.Equals(partition.GetNodePoolResource(configs.DefaultNodePool), nodeRes), "unexpected default pool total")
+
+	queue := partition.getQueue(queueName)
+	assert.Assert(t, queue.IsNodePoolAllowed("spot"), "queue should be allowed in the spot pool")
+	assert.Assert(t, !queue.IsNodePoolAllowed(configs.DefaultNodePool), "queue should not be allowed in the default pool")
+	assert.Assert(t, partition.Root.IsNodePoolAllowed(configs.DefaultNodePool), "root should not be restricted")
+
+	// allocations on the spot node are tracked up to the pool max
+	alloc, err := partition.addNewAllocation(createAllocationProposal(queueName, "node-2", "alloc-1", appID))
+	assert.NilError(t, err, "adding allocation should not have failed")
+	_, err = partition.addNewAllocation(createAllocationProposal(queueName, "node-2", "alloc-2", appID))
+	assert.NilError(t, err, "adding allocation should not have failed")
+	used := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 2})
+	assert.Assert(t, resources.Equals(queue.GetNodePoolAllocatedResource("spot"), used), "unexpected pool usage on leaf")
+	assert.Assert(t, resources.Equals(partition.Root.GetNodePoolAllocatedResource("spot"), used), "unexpected pool usage on root")
+	_, err = partition.addNewAllocation(createAllocationProposal(queueName, "node-2", "alloc-3", appID))
+	if err == nil {
+		t.Fatal("adding allocation over the pool max should have failed")
+	}
+	assert.Assert(t, resources.Equals(queue.GetAllocatedResource(), used), "failed allocation should not change the queue usage")
+
+	// release one allocation and then remove the node
+	toRelease := commonevents.NewReleaseAllocation(alloc.AllocationProto.UUID, appID, partition.Name, "", si.AllocationReleaseResponse_TerminationType(0))
+	allocs := partition.releaseAllocationsForApplication(toRelease)
+	assert.Equal(t, len(allocs), 1, "allocation should have been released")
+	assert.Equal(t, queue.GetNodePoolAllocatedResource("spot").Resources[resources.MEMORY], resources.Quantity(1), "pool usage not decreased on release")
+	allocs = partition.RemoveNode("node-2")
+	assert.Equal(t, len(allocs), 1, "allocation should have been removed with the node")
+	assert.Assert(t, resources.IsZero(queue.GetNodePoolAllocatedResource("spot")), "pool usage not decreased on node removal")
+	assert.Assert(t, resources.IsZero(partition.GetNodePoolResource("spot")), "pool total not decreased on node removal")
+}
+
+func TestUserMax(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: users
+            resources:
+              max:
+                memory: 4
+            properties:
+              queue.user.max: 50%
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	queueName := "root.users"
+	err = partition.addNewApplication(newApplicationInfo("app-1", "default", queueName), true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+	other := NewApplicationInfo("app-2", "default", queueName, security.UserGroup{User: "other"}, nil)
+	err = partition.addNewApplication(other, true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+	err = partition.addNewNode(NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10})), nil)
+	assert.NilError(t, err, "add node to partition should not have failed")
+
+	// allocations of a user are tracked up to the user max
+	queue := partition.getQueue(queueName)
+	alloc, err := partition.addNewAllocation(createAllocationProposal(queueName, "node-1", "alloc-1", "app-1"))
+	assert.NilError(t, err, "adding allocation should not have failed")
+	_, err = partition.addNewAllocation(createAllocationProposal(queueName, "node-1", "alloc-2", "app-1"))
+	assert.NilError(t, err, "adding allocation should not have failed")
+	used := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 2})
+	assert.Assert(t, resources.Equals(queue.GetUserAllocatedResource("testuser"), used), "unexpected user usage on leaf")
+	assert.Assert(t, resources.Equals(partition.Root.GetUserAllocatedResource("testuser"), used), "unexpected user usage on root")
+	_, err = partition.addNewAllocation(createAllocationProposal(queueName, "node-1", "alloc-3", "app-1"))
+	if err == nil {
+		t.Fatal("adding allocation over the user max should have failed")
+	}
+	assert.Assert(t, resources.Equals(queue.GetAllocatedResource(), used), "failed allocation should not change the queue usage")
+
+	// another user has its own limit
+	_, err = partition.addNewAllocation(createAllocationProposal(queueName, "node-1", "alloc-4", "app-2"))
+	assert.NilError(t, err, "adding allocation for another user should not have failed")
+	assert.Equal(t, queue.GetUserAllocatedResource("other").Resources[resources.MEMORY], resources.Quantity(1), "unexpected usage of other user")
+
+	// release one allocation, remove the application and then the node
+	toRelease := commonevents.NewReleaseAllocation(alloc.AllocationProto.UUID, "app-1", partition.Name, "", si.AllocationReleaseResponse_TerminationType(0))
+	allocs := partition.releaseAllocationsForApplication(toRelease)
+	assert.Equal(t, len(allocs), 1, "allocation should have been released")
+	assert.Equal(t, queue.GetUserAllocatedResource("testuser").Resources[resources.MEMORY], resources.Quantity(1), "user usage not decreased on release")
+	_, allocs = partition.RemoveApplication("app-1")
+	assert.Equal(t, len(allocs), 1, "allocation should have been removed with the application")
+	assert.Assert(t, resources.IsZero(queue.GetUserAllocatedResource("testuser")), "user usage not decreased on application removal")
+	allocs = partition.RemoveNode("node-1")
+	assert.Equal(t, len(allocs), 1, "allocation should have been removed with the node")
+	assert.Assert(t, resources.IsZero(partition.Root.GetUserAllocatedResource("other")), "user usage not decreased on node removal")
+}
+
+func TestMoveApplication(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: source
+          - name: target
+          - name: small
+            resources:
+              max:
+                memory: 1
+          - name: parent
+            parent: true
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	appID := "app-1"
+	err = partition.addNewApplication(newApplicationInfo(appID, "default", "root.source"), true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+	err = partition.addNewNode(NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10})), nil)
+	assert.NilError(t, err, "add node to partition should not have failed")
+	_, err = partition.addNewAllocation(createAllocationProposal("root.source", "node-1", "alloc-1", appID))
+	assert.NilError(t, err, "adding allocation should not have failed")
+	_, err = partition.addNewAllocation(createAllocationProposal("root.source", "node-1", "alloc-2", appID))
+	assert.NilError(t, err, "adding allocation should not have failed")
+	used := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 2})
+	source := partition.getQueue("root.source")
+
+	// unknown app, unknown queue and parent queue
+	err = partition.MoveApplication("unknown", "root.target")
+	assert.Assert(t, err != nil, "unknown application should not have been moved")
+	for _, name := range []string{"root.unknown", "root.parent"} {
+		err = partition.MoveApplication(appID, name)
+		assert.Equal(t, api.GetErrorKind(err), api.ErrQueueNotFound, "move to %s should have failed", name)
+	}
+
+	// the allocated resources do not fit in the target: nothing changes
+	err = partition.MoveApplication(appID, "root.small")
+	assert.Equal(t, api.GetErrorKind(err), api.ErrOverQueueMax, "move over the queue max should have failed")
+	assert.Assert(t, resources.Equals(source.GetAllocatedResource(), used), "failed move should not change the source usage")
+	assert.Assert(t, resources.Equals(source.GetUserAllocatedResource("testuser"), used), "failed move should not change the source user usage")
+	assert.Assert(t, resources.Equals(source.GetNodePoolAllocatedResource(configs.DefaultNodePool), used), "failed move should not change the source pool usage")
+	assert.Assert(t, resources.IsZero(partition.getQueue("root.small").GetAllocatedResource()), "failed move should not change the target usage")
+	assert.Equal(t, partition.getApplication(appID).QueueName, "root.source", "failed move should not change the application queue")
+
+	// the allocated resources move to the target, the root is not changed
+	err = partition.MoveApplication(appID, "root.target")
+	assert.NilError(t, err, "move should not have failed")
+	target := partition.getQueue("root.target")
+	assert.Assert(t, resources.IsZero(source.GetAllocatedResource()), "source usage not released")
+	assert.Assert(t, resources.IsZero(source.GetUserAllocatedResource("testuser")), "source user usage not released")
+	assert.Assert(t, resources.IsZero(source.GetNodePoolAllocatedResource(configs.DefaultNodePool)), "source pool usage not released")
+	assert.Assert(t, resources.Equals(target.GetAllocatedResource(), used), "target usage not charged")
+	assert.Assert(t, resources.Equals(target.GetUserAllocatedResource("testuser"), used), "target user usage not charged")
+	assert.Assert(t, resources.Equals(target.GetNodePoolAllocatedResource(configs.DefaultNodePool), used), "target pool usage not charged")
+	assert.Assert(t, resources.Equals(partition.Root.GetAllocatedResource(), used), "root usage should not have changed")
+	assert.Equal(t, partition.getApplication(appID).QueueName, "root.target", "application queue not updated")
+
+	// the application is released from the new queue
+	_, allocs := partition.RemoveApplication(appID)
+	assert.Equal(t, len(allocs), 2, "allocations should have been removed with the application")
+	assert.Assert(t, resources.IsZero(target.GetAllocatedResource()), "target usage not released on application removal")
+}
+
+func TestMoveApplicationMetrics(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: movesource
+          - name: movetarget
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	sourceGauge := "yunikorn_queue_root_movesource_app_current"
+	targetGauge := "yunikorn_queue_root_movetarget_app_current"
+	pending := newApplicationInfo("app-pending", "default", "root.movesource")
+	err = partition.addNewApplication(pending, true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+	err = pending.HandleApplicationEvent(AcceptApplication)
+	assert.NilError(t, err, "accept transition failed")
+	running := newApplicationInfo("app-running", "default", "root.movesource")
+	err = partition.addNewApplication(running, true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+	err = running.HandleApplicationEvent(AcceptApplication)
+	assert.NilError(t, err, "accept transition failed")
+	err = running.HandleApplicationEvent(RunApplication)
+	assert.NilError(t, err, "run transition failed")
+	assert.Equal(t, getQueueAppMetric(t, sourceGauge, "pending"), float64(1), "unexpected pending apps in source")
+	assert.Equal(t, getQueueAppMetric(t, sourceGauge, "running"), float64(1), "unexpected running apps in source")
+
+	// the gauges follow the applications to the target queue
+	err = partition.MoveApplication("app-pending", "root.movetarget")
+	assert.NilError(t, err, "move should not have failed")
+	err = partition.MoveApplication("app-running", "root.movetarget")
+	assert.NilError(t, err, "move should not have failed")
+	assert.Equal(t, getQueueAppMetric(t, sourceGauge, "pending"), float64(0), "pending app not moved from source")
+	assert.Equal(t, getQueueAppMetric(t, sourceGauge, "running"), float64(0), "running app not moved from source")
+	assert.Equal(t, getQueueAppMetric(t, targetGauge, "pending"), float64(1), "pending app not moved to target")
+	assert.Equal(t, getQueueAppMetric(t, targetGauge, "running"), float64(1), "running app not moved to target")
+
+	// later transitions update the target queue only
+	err = running.HandleApplicationEvent(CompleteApplication)
+	assert.NilError(t, err, "complete transition failed")
+	assert.Equal(t, getQueueAppMetric(t, targetGauge, "running"), float64(0), "completed app should not be running in target")
+	assert.Equal(t, getQueueAppMetric(t, sourceGauge, "running"), float64(0), "completed app should not change the source")
+}
+
 func TestRemoveApp(t *testing.T) {
 	partition, err := CreatePartitionInfo([]byte(configDefault))
 	if err != nil {
@@ -772,3 +1016,520 @@ partitions:
 	assert.Equal(t, len(m), 2)
 	assert.Assert(t, reflect.DeepEqual(m["memory"], []int{1, 1, 0, 0, 0, 0, 0, 0, 1, 0}))
 }
+
+func TestGetEffectiveConfig(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    preemption:
+      enabled: true
+      graceperiod: 30s
+      minruntime: 2m
+      emergencypriority: 100
+    nodepools:
+      attribute: si.io/node-pool
+    autoscale:
+      watermark:
+        memory: 100
+    askbudget:
+      maxtime: 50ms
+      maxnodes: 100
+    throughput:
+      enabled: true
+    limits:
+      - limit: partition limit
+        users:
+          - user1
+        maxapplications: 5
+    queues:
+      - name: root
+        submitacl: "*"
+        properties:
+          application.sort.policy: fifo
+        queues:
+          - name: parent
+            parent: true
+            adminacl: " admin,ops"
+            resources:
+              guaranteed:
+                memory: 100
+              max:
+                memory: 200
+                vcore: 10
+          - name: leaf
+            maxapplications: 3
+            properties:
+              queue.start.delay: 10s
+            nodepools:
+              - name: spot
+                max:
+                  memory: 50
+              - name: gpu
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	err = partition.CreateQueues("root.parent.dynamic")
+	assert.NilError(t, err, "dynamic queue create failed")
+
+	conf := partition.GetEffectiveConfig()
+	assert.Equal(t, conf.Name, "default", "partition name should not contain the cluster ID")
+	assert.Equal(t, conf.Preemption.GracePeriod, "30s", "unexpected grace period")
+	assert.Equal(t, conf.Preemption.MinRuntime, "2m0s", "unexpected minimum runtime")
+	assert.Equal(t, conf.Preemption.EmergencyPriority, int32(100), "unexpected emergency priority")
+	assert.Equal(t, conf.NodeSortPolicy.Type, "fair", "default node sort policy not set")
+	assert.Equal(t, conf.Reservations.StaleAge, DefaultStaleReservationAge.String(), "default stale age not set")
+	assert.DeepEqual(t, conf.Autoscale.Watermark, map[string]string{"memory": "100"})
+	assert.Equal(t, conf.Autoscale.Threshold, DefaultAutoscaleThreshold.String(), "default autoscale threshold not set")
+	assert.Equal(t, conf.AskBudget, configs.PartitionAskBudgetConfig{MaxTime: "50ms", MaxNodes: 100}, "unexpected ask budget")
+	assert.Equal(t, conf.Throughput, configs.PartitionThroughputConfig{Enabled: true, SampleSize: DefaultThroughputSampleSize}, "default throughput sample size not set")
+	assert.Equal(t, len(conf.Limits), 1, "partition limits not exported")
+	assert.Equal(t, len(conf.Queues), 1, "expected root queue only at the top level")
+	root := conf.Queues[0]
+	assert.Equal(t, root.SubmitACL, "*", "root submit ACL not exported")
+	assert.Equal(t, len(root.Queues), 2, "unexpected children of root")
+	// children are sorted by name
+	leaf := root.Queues[0]
+	parent := root.Queues[1]
+	assert.Equal(t, leaf.Name, "leaf")
+	assert.Equal(t, parent.Name, "parent")
+	assert.Equal(t, parent.AdminACL, " admin,ops", "parent admin ACL not exported")
+	assert.DeepEqual(t, parent.Resources.Max, map[string]string{"memory": "200", "vcore": "10"})
+	assert.DeepEqual(t, parent.Resources.Guaranteed, map[string]string{"memory": "100"})
+	// properties are merged with the parent
+	assert.DeepEqual(t, parent.Properties, map[string]string{"application.sort.policy": "fifo"})
+	assert.DeepEqual(t, leaf.Properties, map[string]string{"application.sort.policy": "fifo", "queue.start.delay": "10s"})
+	assert.Equal(t, leaf.MaxApplications, uint64(3), "max applications not exported")
+	assert.DeepEqual(t, leaf.NodePools, []configs.NodePoolConfig{{Name: "gpu"}, {Name: "spot", Max: map[string]string{"memory": "50"}}})
+	// dynamic queues are included
+	assert.Equal(t, len(parent.Queues), 1, "dynamic queue not exported")
+	assert.Equal(t, parent.Queues[0].Name, "dynamic")
+	assert.Assert(t, !parent.Queues[0].Parent, "dynamic queue should be a leaf")
+
+	// the export must be stable and load as a valid config
+	out, err := configs.ExportSchedulerConfig([]configs.PartitionConfig{conf})
+	assert.NilError(t, err, "export failed")
+	again, err := configs.ExportSchedulerConfig([]configs.PartitionConfig{partition.GetEffectiveConfig()})
+	assert.NilError(t, err, "second export failed")
+	assert.Equal(t, string(out), string(again), "export is not stable")
+	loaded, err := configs.LoadSchedulerConfigFromByteArray(out)
+	assert.NilError(t, err, "exported config did not load: %s", string(out))
+	assert.Equal(t, loaded.Partitions[0].Queues[0].Queues[1].Queues[0].Name, "dynamic", "dynamic queue not in loaded config")
+}
+
+func TestPartitionUserGroups(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    usergroups:
+      type: static
+      groups:
+        user1:
+          - group1
+    queues:
+      - name: root
+        queues:
+          - name: restricted
+            submitacl: " group1"
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	defer partition.stopUserGroupCache()
+	queue := partition.getQueue("root.restricted")
+	assert.Assert(t, queue != nil, "queue not found")
+
+	// the RM does not pass in groups: resolved from the static config
+	ugi, err := partition.convertUGI(&si.UserGroupInformation{User: "user1"})
+	assert.NilError(t, err, "user resolution should not have failed")
+	assert.DeepEqual(t, ugi.Groups, []string{"group1"})
+	assert.Assert(t, queue.CheckSubmitAccess(ugi), "user1 should have access via group1")
+	ugi, err = partition.convertUGI(&si.UserGroupInformation{User: "user2"})
+	assert.NilError(t, err, "unknown user should resolve without groups")
+	assert.Assert(t, !queue.CheckSubmitAccess(ugi), "user2 should not have access")
+	// groups passed in are used as is
+	ugi, err = partition.convertUGI(&si.UserGroupInformation{User: "user3", Groups: []string{"group1"}})
+	assert.NilError(t, err, "user conversion should not have failed")
+	assert.Assert(t, queue.CheckSubmitAccess(ugi), "user3 should have access via the passed in group")
+
+	// update the mapping: the cache is replaced
+	conf := partition.GetEffectiveConfig()
+	assert.Equal(t, conf.UserGroups.Type, "static", "resolver not part of the effective config")
+	conf.UserGroups.Groups = map[string][]string{"user2": {"group1"}}
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	ugi, err = partition.convertUGI(&si.UserGroupInformation{User: "user2"})
+	assert.NilError(t, err, "user resolution should not have failed")
+	assert.Assert(t, queue.CheckSubmitAccess(ugi), "user2 should have access after the update")
+
+	// no resolver configured uses the shared cache
+	assert.Equal(t, newUserGroupCache(configs.UserGroupResolverConfig{}), security.GetUserGroupCache(""), "expected the shared cache")
+}
+
+func TestResourceAliases(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    resourcealiases:
+      cpu: vcore
+      nvidia.com/gpu: gpu
+    queues:
+      - name: root
+        queues:
+          - name: default
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	appInfo := newApplicationInfo("app-1", "default", "root.default")
+	err = partition.addNewApplication(appInfo, true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+
+	// node and existing allocations reported with the aliases
+	node := NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{"cpu": 10, "nvidia.com/gpu": 2}))
+	alloc := createAllocation("root.default", "node-1", "alloc-1", "app-1")
+	alloc.ResourcePerAlloc = &si.Resource{Resources: map[string]*si.Quantity{"cpu": {Value: 1}}}
+	err = partition.addNewNode(node, []*si.Allocation{alloc})
+	assert.NilError(t, err, "add node to partition should not have failed")
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"vcore": 10, "gpu": 2})
+	assert.Assert(t, resources.Equals(node.GetCapacity(), expected), "node capacity not canonical: %v", node.GetCapacity())
+	assert.Assert(t, resources.Equals(partition.GetTotalPartitionResource(), expected), "partition total not canonical")
+	allocated := resources.NewResourceFromMap(map[string]resources.Quantity{"vcore": 1})
+	assert.Assert(t, resources.Equals(node.GetAllocatedResource(), allocated), "existing allocation not canonical: %v", node.GetAllocatedResource())
+
+	ask := &si.AllocationAsk{ResourceAsk: &si.Resource{Resources: map[string]*si.Quantity{"nvidia.com/gpu": {Value: 1}, "memory": {Value: 5}}}}
+	partition.canonicalizeAsk(ask)
+	assert.DeepEqual(t, resources.NewResourceFromProto(ask.ResourceAsk).Resources, map[string]resources.Quantity{"gpu": 1, "memory": 5})
+
+	// the mapping is part of the effective config
+	conf := partition.GetEffectiveConfig()
+	assert.DeepEqual(t, conf.ResourceAliases, map[string]string{"cpu": "vcore", "nvidia.com/gpu": "gpu"})
+	conf.ResourceAliases = nil
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	assert.Equal(t, len(partition.GetResourceAliases()), 0, "aliases not removed on update")
+}
+
+func TestRMResourceUnits(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    resourceunits:
+      vcore: 1000
+    rmresourceunits:
+      vcore: 1
+    queues:
+      - name: root
+        queues:
+          - name: default
+            resources:
+              max:
+                vcore: "2.5"
+            properties:
+              ask.default.resource: "[vcore:1.5]"
+              queue.user.max: "50%"
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	assert.DeepEqual(t, partition.GetRMUnitFactors(), map[string]int64{"vcore": 1000})
+	appInfo := newApplicationInfo("app-1", "default", "root.default")
+	err = partition.addNewApplication(appInfo, true)
+	assert.NilError(t, err, "add application to partition should not have failed")
+
+	// node and existing allocations reported in RM units
+	node := NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{"vcore": 10, "memory": 100}))
+	alloc := createAllocation("root.default", "node-1", "alloc-1", "app-1")
+	alloc.ResourcePerAlloc = &si.Resource{Resources: map[string]*si.Quantity{"vcore": {Value: 1}}}
+	err = partition.addNewNode(node, []*si.Allocation{alloc})
+	assert.NilError(t, err, "add node to partition should not have failed")
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"vcore": 10000, "memory": 100})
+	assert.Assert(t, resources.Equals(node.GetCapacity(), expected), "node capacity not converted: %v", node.GetCapacity())
+	allocated := resources.NewResourceFromMap(map[string]resources.Quantity{"vcore": 1000})
+	assert.Assert(t, resources.Equals(node.GetAllocatedResource(), allocated), "existing allocation not converted: %v", node.GetAllocatedResource())
+
+	ask := &si.AllocationAsk{ResourceAsk: &si.Resource{Resources: map[string]*si.Quantity{"vcore": {Value: 2}, "memory": {Value: 5}}}}
+	partition.canonicalizeAsk(ask)
+	assert.DeepEqual(t, resources.NewResourceFromProto(ask.ResourceAsk).Resources, map[string]resources.Quantity{"vcore": 2000, "memory": 5})
+
+	update := &si.UpdateNodeInfo{NodeID: "node-1", SchedulableResource: &si.Resource{Resources: map[string]*si.Quantity{"vcore": {Value: 8}}}}
+	partition.canonicalizeNodeUpdate(update)
+	assert.DeepEqual(t, resources.NewResourceFromProto(update.SchedulableResource).Resources, map[string]resources.Quantity{"vcore": 8000})
+
+	// resources sent to the RM are converted back, the scheduler side is not changed
+	proto := &si.Allocation{UUID: "uuid-1", ResourcePerAlloc: &si.Resource{Resources: map[string]*si.Quantity{"vcore": {Value: 2000}}}}
+	sent := partition.toRMAllocation(proto)
+	assert.Equal(t, sent.UUID, "uuid-1")
+	assert.DeepEqual(t, resources.NewResourceFromProto(sent.ResourcePerAlloc).Resources, map[string]resources.Quantity{"vcore": 2})
+	assert.Equal(t, proto.ResourcePerAlloc.Resources["vcore"].Value, int64(2000), "scheduler allocation should not be changed")
+
+	queueUpdate := partition.getQueueUpdates()["root.default"]
+	assert.Assert(t, queueUpdate != nil, "queue update not found")
+	assert.DeepEqual(t, resources.NewResourceFromProto(queueUpdate.MaxResource).Resources, map[string]resources.Quantity{"vcore": 2})
+	assert.Equal(t, queueUpdate.Properties[configs.AskDefaultResource], "[vcore:1]")
+	assert.Equal(t, queueUpdate.Properties[configs.QueueUserMax], "50%")
+	queue := partition.getQueue("root.default")
+	assert.Equal(t, queue.Properties[configs.AskDefaultResource], "[vcore:1500]", "queue property should not be changed")
+
+	// the units are part of the effective config, removing them stops the conversion
+	conf := partition.GetEffectiveConfig()
+	assert.DeepEqual(t, conf.RMResourceUnits, map[string]int64{"vcore": 1})
+	conf.RMResourceUnits = nil
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	assert.Assert(t, partition.GetRMUnitFactors() == nil, "RM units not removed on update")
+	assert.Equal(t, partition.toRMAllocation(proto), proto, "allocation should be sent as is without RM units")
+}
+
+func TestLimitsChangeTime(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    preemption:
+      enabled: true
+      settleperiod: 5m
+    queues:
+      - name: root
+        queues:
+          - name: parent
+            parent: true
+            resources:
+              guaranteed:
+                memory: 100
+            queues:
+              - name: leaf
+          - name: other
+            resources:
+              max:
+                memory: 100
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	assert.Equal(t, partition.GetPreemptionSettlePeriod(), 5*time.Minute, "settle period not set")
+	for _, path := range []string{"root", "root.parent", "root.parent.leaf", "root.other"} {
+		assert.Assert(t, partition.GetQueue(path).GetLimitsChangeTime().IsZero(), "queue %s should not be changed on create", path)
+	}
+
+	// an update without limit changes does not mark any queue
+	conf := partition.GetEffectiveConfig()
+	assert.Equal(t, conf.Preemption.SettlePeriod, "5m0s", "settle period not exported")
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	for _, path := range []string{"root", "root.parent", "root.parent.leaf", "root.other"} {
+		assert.Assert(t, partition.GetQueue(path).GetLimitsChangeTime().IsZero(), "queue %s should not be changed", path)
+	}
+
+	// a changed guarantee marks the queue and is inherited by the children, new queues are not marked
+	conf.Queues[0].Queues[1].Resources.Guaranteed = map[string]string{"memory": "50"}
+	conf.Queues[0].Queues = append(conf.Queues[0].Queues, configs.QueueConfig{Name: "new"})
+	before := time.Now()
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	changed := partition.GetQueue("root.parent").GetLimitsChangeTime()
+	assert.Assert(t, !changed.Before(before), "changed queue not marked")
+	assert.Equal(t, partition.GetQueue("root.parent.leaf").GetLimitsChangeTime(), changed, "change not inherited by the child")
+	for _, path := range []string{"root", "root.other", "root.new"} {
+		assert.Assert(t, partition.GetQueue(path).GetLimitsChangeTime().IsZero(), "queue %s should not be changed", path)
+	}
+}
+
+func TestPlacementRulesUpdate(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    placementrules:
+      - name: fixed
+        value: root.default
+    queues:
+      - name: root
+        queues:
+          - name: default
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	rules, version := partition.GetVersionedRules()
+	assert.Equal(t, len(rules), 1, "rules not set on create")
+
+	// an update without rule changes keeps the version
+	conf := partition.GetEffectiveConfig()
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	assert.Equal(t, partition.GetRulesVersion(), version, "version changed without rule change")
+
+	// the rules are replaced with the queues
+	conf.Queues[0].Queues = []configs.QueueConfig{{Name: "other"}}
+	conf.PlacementRules = []configs.PlacementRule{{Name: "fixed", Value: "root.other"}}
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	rules, newVersion := partition.GetVersionedRules()
+	assert.Assert(t, newVersion != version, "version not changed on rule change")
+	assert.DeepEqual(t, rules, conf.PlacementRules)
+	assert.Assert(t, partition.GetQueue("root.other") != nil, "queue not added with the rules")
+	assert.Assert(t, partition.GetQueue("root.default").IsDraining(), "removed queue should be draining")
+}
+
+func TestQueueResourcesUpdate(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: leaf
+            resources:
+              guaranteed:
+                memory: 5
+              max:
+                memory: 10
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	err = partition.addNewNode(NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 100})), nil)
+	assert.NilError(t, err, "add node to partition should not have failed")
+	leaf := partition.GetQueue("root.leaf")
+	rootMax := partition.Root.GetMaxResource()
+
+	// changed resources are replaced in place
+	conf := partition.GetEffectiveConfig()
+	conf.Queues[0].Queues[0].Resources = configs.Resources{
+		Guaranteed: map[string]string{resources.MEMORY: "8"},
+		Max:        map[string]string{resources.MEMORY: "20"},
+	}
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	assert.Equal(t, partition.GetQueue("root.leaf"), leaf, "queue should not have been replaced")
+	assert.Equal(t, leaf.GetGuaranteedResource().Resources[resources.MEMORY], resources.Quantity(8), "guaranteed resource not updated")
+	assert.Equal(t, leaf.GetMaxResource().Resources[resources.MEMORY], resources.Quantity(20), "max resource not updated")
+
+	assert.Equal(t, partition.Root.GetGuaranteedResource().Resources[resources.MEMORY], resources.Quantity(8), "root guaranteed resource not derived")
+
+	// resources that are no longer configured are removed, the root max stays the cluster size
+	conf.Queues[0].Queues[0].Resources = configs.Resources{}
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	assert.Assert(t, resources.IsZero(leaf.GetGuaranteedResource()), "guaranteed resource not removed")
+	assert.Assert(t, resources.IsZero(partition.Root.GetGuaranteedResource()), "root guaranteed resource not derived")
+	assert.Assert(t, leaf.GetMaxResource() == nil, "max resource not removed")
+	assert.Assert(t, resources.Equals(partition.Root.GetMaxResource(), rootMax), "root max should not change on update")
+}
+
+func TestDerivedGuarantee(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: parent
+            properties:
+              queue.guaranteed.derived: "true"
+            childtemplate:
+              resources:
+                guaranteed:
+                  memory: 2
+            queues:
+              - name: leaf1
+                resources:
+                  guaranteed:
+                    memory: 5
+              - name: leaf2
+                resources:
+                  guaranteed:
+                    memory: 3
+                    vcore: 1
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	parent := partition.GetQueue("root.parent")
+	assert.Assert(t, parent.IsGuaranteedDerived(), "parent should derive its guarantee")
+	assert.Assert(t, !partition.GetQueue("root.parent.leaf1").IsGuaranteedDerived(), "leaf should not derive its guarantee")
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 8, resources.VCORE: 1})
+	assert.Assert(t, resources.Equals(parent.GetGuaranteedResource(), expected), "guarantee not derived on create: %v", parent.GetGuaranteedResource())
+
+	// a queue created below the parent adds its guarantee, removing it takes it off again
+	var dynamic *QueueInfo
+	dynamic, err = NewUnmanagedQueue("dynamic", true, parent)
+	assert.NilError(t, err, "failed to create unmanaged queue")
+	assert.Equal(t, parent.GetGuaranteedResource().Resources[resources.MEMORY], resources.Quantity(10), "guarantee of the new queue not added")
+	assert.Assert(t, dynamic.RemoveQueue(), "unmanaged queue should have been removed")
+	assert.Equal(t, parent.GetGuaranteedResource().Resources[resources.MEMORY], resources.Quantity(8), "guarantee of the removed queue not taken off")
+
+	// a changed child guarantee is picked up on reload
+	conf := partition.GetEffectiveConfig()
+	conf.Queues[0].Queues[0].Resources = configs.Resources{}
+	conf.Queues[0].Queues[0].Queues[0].Resources = configs.Resources{Guaranteed: map[string]string{resources.MEMORY: "7"}}
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10, resources.VCORE: 1})
+	assert.Assert(t, resources.Equals(parent.GetGuaranteedResource(), expected), "guarantee not derived on update: %v", parent.GetGuaranteedResource())
+}
+
+func TestDerivedGuaranteeIdenticalReload(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: parent
+            queues:
+              - name: leaf
+                resources:
+                  guaranteed:
+                    vcore: 10
+          - name: derived
+            properties:
+              queue.guaranteed.derived: "true"
+            queues:
+              - name: leaf
+                resources:
+                  guaranteed:
+                    vcore: 5
+          - name: empty
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	// derive the guaranteed resources as a partition created from the config file would
+	err = checkResourceConfigurationsForQueue(partition.Root, nil)
+	assert.NilError(t, err, "partition resource check failed")
+	paths := []string{"root", "root.parent", "root.parent.leaf", "root.derived", "root.derived.leaf", "root.empty"}
+	before := make(map[string]*resources.Resource)
+	for _, path := range paths {
+		before[path] = partition.GetQueue(path).GetGuaranteedResource()
+	}
+	assert.Equal(t, before["root"].Resources[resources.VCORE], resources.Quantity(15), "root guarantee not derived on create")
+	assert.Equal(t, before["root.parent"].Resources[resources.VCORE], resources.Quantity(10), "parent guarantee not derived on create")
+	assert.Assert(t, before["root.empty"] != nil && resources.IsZero(before["root.empty"]), "leaf guarantee should be zero on create")
+
+	// reloading the same config does not change any guarantee
+	conf, err := configs.LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.NilError(t, err, "config load failed")
+	err = partition.updatePartitionDetails(conf.Partitions[0])
+	assert.NilError(t, err, "partition update failed")
+	for _, path := range paths {
+		guaranteed := partition.GetQueue(path).GetGuaranteedResource()
+		assert.Assert(t, guaranteed != nil && resources.Equals(before[path], guaranteed),
+			"guarantee of queue %s changed on reload: %v to %v", path, before[path], guaranteed)
+		assert.Assert(t, partition.GetQueue(path).GetLimitsChangeTime().IsZero(), "queue %s should not be marked as changed", path)
+	}
+}
+
+func TestPausePartition(t *testing.T) {
+	partition, err := CreatePartitionInfo([]byte(configDefault))
+	assert.NilError(t, err, "partition create failed")
+	assert.Assert(t, !partition.IsPaused(), "new partition should not be paused")
+
+	// a paused partition does not accept new applications, pausing twice is allowed
+	assert.NilError(t, partition.SetPaused(true), "pause should not have failed")
+	assert.NilError(t, partition.SetPaused(true), "pause of paused partition should not have failed")
+	assert.Assert(t, partition.IsPaused(), "partition should be paused")
+	err = partition.addNewApplication(newApplicationInfo("app-1", "default", "root.default"), true)
+	assert.Assert(t, err != nil, "paused partition should not accept applications")
+
+	assert.NilError(t, partition.SetPaused(false), "resume should not have failed")
+	assert.Assert(t, !partition.IsPaused(), "partition should have been resumed")
+	err = partition.addNewApplication(newApplicationInfo("app-1", "default", "root.default"), true)
+	assert.NilError(t, err, "resumed partition should accept applications")
+
+	// a partition marked for deletion cannot be paused or resumed
+	partition.markPartitionForRemoval()
+	err = partition.SetPaused(true)
+	assert.Assert(t, api.IsError(err, api.ErrInvalidState), "pause of removed partition should fail with invalid state: %v", err)
+	assert.Assert(t, !partition.IsPaused(), "removed partition should not be paused")
+}
@@ -0,0 +1,117 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// A scheduled reduction of the partition capacity.
+// The reserved capacity grows linearly from nothing at the start of the lead time to the full capacity at the start of
+// the maintenance, and stays reserved until the end of the maintenance.
+type maintenanceWindow struct {
+	start    time.Time
+	end      time.Time
+	capacity int           // percentage of the partition total resource
+	leadTime time.Duration // time before the start the reservation starts growing
+}
+
+// Get the percentage of the partition reserved by the window at the time.
+func (mw *maintenanceWindow) getReserved(now time.Time) float64 {
+	if !now.Before(mw.end) || now.Before(mw.start.Add(-mw.leadTime)) {
+		return 0
+	}
+	if !now.Before(mw.start) {
+		return float64(mw.capacity)
+	}
+	// in the lead time: the lead time is always positive here
+	return float64(mw.capacity) * float64(now.Sub(mw.start.Add(-mw.leadTime))) / float64(mw.leadTime)
+}
+
+// Set the maintenance windows from the config. The config has been validated: a failure means the window is skipped.
+// Lock free call this must be called holding the partition lock or during create only
+func (pi *PartitionInfo) setMaintenance(conf []configs.PartitionMaintenanceConfig) {
+	pi.maintenance = nil
+	for _, window := range conf {
+		start, err := time.Parse(time.RFC3339, window.Start)
+		if err != nil {
+			continue
+		}
+		var duration time.Duration
+		duration, err = time.ParseDuration(window.Duration)
+		if err != nil || duration <= 0 {
+			continue
+		}
+		var leadTime time.Duration
+		if window.LeadTime != "" {
+			leadTime, err = time.ParseDuration(window.LeadTime)
+			if err != nil || leadTime < 0 {
+				leadTime = 0
+			}
+		}
+		pi.maintenance = append(pi.maintenance, &maintenanceWindow{
+			start:    start,
+			end:      start.Add(duration),
+			capacity: window.Capacity,
+			leadTime: leadTime,
+		})
+	}
+}
+
+// Get the maintenance windows as configured.
+// Lock free call this must be called holding the partition lock
+func (pi *PartitionInfo) getMaintenanceConfig() []configs.PartitionMaintenanceConfig {
+	if len(pi.maintenance) == 0 {
+		return nil
+	}
+	conf := make([]configs.PartitionMaintenanceConfig, len(pi.maintenance))
+	for i, window := range pi.maintenance {
+		conf[i] = configs.PartitionMaintenanceConfig{
+			Start:    window.start.Format(time.RFC3339),
+			Duration: window.end.Sub(window.start).String(),
+			Capacity: window.capacity,
+		}
+		if window.leadTime > 0 {
+			conf[i].LeadTime = window.leadTime.String()
+		}
+	}
+	return conf
+}
+
+// Get the resource reserved for maintenance at the time, nil if no capacity is reserved.
+// Overlapping windows do not add up: the largest reservation is used. The reservation is a share of the partition
+// total resource: nodes that are added or removed change the reserved resource.
+func (pi *PartitionInfo) GetMaintenanceReservation(now time.Time) *resources.Resource {
+	pi.RLock()
+	defer pi.RUnlock()
+
+	reserved := 0.0
+	for _, window := range pi.maintenance {
+		if share := window.getReserved(now); share > reserved {
+			reserved = share
+		}
+	}
+	if reserved == 0 || pi.totalPartitionResource == nil {
+		return nil
+	}
+	return resources.MultiplyBy(pi.totalPartitionResource, reserved/100)
+}
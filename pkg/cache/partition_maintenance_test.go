@@ -0,0 +1,83 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestMaintenanceReservation(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    maintenance:
+      - start: 2026-10-18T02:00:00Z
+        duration: 2h
+        capacity: 20
+        leadtime: 1h
+      - start: 2026-10-18T03:00:00Z
+        duration: 30m
+        capacity: 50
+`
+	partition, err := CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	SetTotalPartitionResource(partition, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1000}))
+	start, err := time.Parse(time.RFC3339, "2026-10-18T02:00:00Z")
+	assert.NilError(t, err, "failed to parse start time")
+
+	tests := map[time.Duration]resources.Quantity{
+		-2 * time.Hour:    0,   // before the lead time
+		-time.Hour:        0,   // start of the lead time
+		-30 * time.Minute: 100, // half way the lead time
+		0:                 200, // start of the maintenance
+		time.Hour:         500, // overlapping window: the largest reservation is used
+		90 * time.Minute:  200, // end of the overlapping window
+		2 * time.Hour:     0,   // end of the maintenance
+	}
+	for offset, expected := range tests {
+		reserved := partition.GetMaintenanceReservation(start.Add(offset))
+		if expected == 0 {
+			assert.Assert(t, reserved == nil, "nothing should be reserved at %v: %v", offset, reserved)
+			continue
+		}
+		assert.Assert(t, reserved != nil, "capacity should be reserved at %v", offset)
+		assert.Equal(t, reserved.Resources["first"], expected, "unexpected reservation at %v", offset)
+	}
+
+	conf := partition.GetEffectiveConfig()
+	assert.Equal(t, len(conf.Maintenance), 2, "maintenance windows should be in the effective config")
+	assert.Equal(t, conf.Maintenance[0].Start, "2026-10-18T02:00:00Z")
+	assert.Equal(t, conf.Maintenance[0].Duration, "2h0m0s")
+	assert.Equal(t, conf.Maintenance[0].Capacity, 20)
+	assert.Equal(t, conf.Maintenance[0].LeadTime, "1h0m0s")
+	assert.Equal(t, conf.Maintenance[1].LeadTime, "", "lead time should not be set")
+
+	// the windows are replaced on update
+	conf.Maintenance = nil
+	err = UpdatePartitionInfo(partition, conf)
+	assert.NilError(t, err, "partition update failed")
+	assert.Assert(t, partition.GetMaintenanceReservation(start) == nil, "maintenance should have been removed")
+}
@@ -0,0 +1,119 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
+)
+
+// The limits on the shape of the queue hierarchy of a partition, see configs.PartitionHierarchyConfig.
+// The same object is shared by the partition and its root queue: a configuration reload changes the limits in place.
+// Lowered limits do not remove existing queues, they only stop new queues from being added.
+type hierarchyLimits struct {
+	maxDepth    int // maximum depth of a queue below the root, 0 means no limit
+	maxChildren int // maximum number of child queues of a parent queue, 0 means no limit
+
+	locking.RWMutex
+}
+
+func newHierarchyLimits() *hierarchyLimits {
+	return &hierarchyLimits{}
+}
+
+// Replace the limits with the configured limits.
+func (hl *hierarchyLimits) setLimits(conf configs.PartitionHierarchyConfig) {
+	hl.Lock()
+	defer hl.Unlock()
+	hl.maxDepth = conf.MaxDepth
+	hl.maxChildren = conf.MaxChildren
+}
+
+// Get the limits as configured, no limits if the hierarchy is not limited.
+func (hl *hierarchyLimits) getConfig() configs.PartitionHierarchyConfig {
+	if hl == nil {
+		return configs.PartitionHierarchyConfig{}
+	}
+	hl.RLock()
+	defer hl.RUnlock()
+	return configs.PartitionHierarchyConfig{
+		MaxDepth:    hl.maxDepth,
+		MaxChildren: hl.maxChildren,
+	}
+}
+
+// Get the depth of the queue below the root queue: the root queue has a depth of 0, root.a has a depth of 1.
+func (qi *QueueInfo) getDepth() int {
+	depth := 0
+	for parent := qi.Parent; parent != nil; parent = parent.Parent {
+		depth++
+	}
+	return depth
+}
+
+// Get the hierarchy limits of the partition from the root queue.
+// The limits are set on the root before any queue is added below it and never replaced.
+func (qi *QueueInfo) getHierarchyLimits() *hierarchyLimits {
+	root := qi
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return root.hierarchy
+}
+
+// Check if new queues can be added below the queue within the hierarchy limits of the partition. The queues are a
+// chain of the given number of levels that starts with the named child: only the queue gets a new child queue.
+// A child that already exists does not count against the maximum number of children.
+// A managed queue is only checked against the managed children that are not draining: the configuration validator
+// has checked the configured children and cannot see the unmanaged ones, a reload must not fail half way.
+// Lock free call, must be called holding the queue lock.
+func (qi *QueueInfo) checkHierarchyLimits(childName string, levels int, managed bool) error {
+	limits := qi.getHierarchyLimits().getConfig()
+	if depth := qi.getDepth() + levels; limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return fmt.Errorf("a depth of %d is over the maximum queue depth %d of the partition", depth, limits.MaxDepth)
+	}
+	if _, ok := qi.children[childName]; !ok && limits.MaxChildren > 0 && qi.countChildren(managed) >= limits.MaxChildren {
+		return fmt.Errorf("queue %s already has the maximum of %d child queues", qi.GetQueuePath(), limits.MaxChildren)
+	}
+	return nil
+}
+
+// Count the children of the queue, all children or only the managed children that are not draining.
+// Lock free call, must be called holding the queue lock.
+func (qi *QueueInfo) countChildren(managedOnly bool) int {
+	if !managedOnly {
+		return len(qi.children)
+	}
+	count := 0
+	for _, child := range qi.children {
+		if child.IsManaged() && !child.IsDraining() {
+			count++
+		}
+	}
+	return count
+}
+
+// Check if new unmanaged queues can be added below the queue, see checkHierarchyLimits.
+func (qi *QueueInfo) CheckHierarchyLimits(childName string, levels int) error {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.checkHierarchyLimits(childName, levels, false)
+}
@@ -0,0 +1,115 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+)
+
+const configHierarchy = `
+partitions:
+  - name: default
+    hierarchy:
+      maxdepth: 2
+      maxchildren: 2
+    queues:
+      - name: root
+        queues:
+          - name: users
+            parent: true
+`
+
+func TestHierarchyLimits(t *testing.T) {
+	partition, err := CreatePartitionInfo([]byte(configHierarchy))
+	assert.NilError(t, err, "partition create failed")
+	assert.Equal(t, partition.Root.getDepth(), 0, "root should have a depth of 0")
+	assert.DeepEqual(t, partition.GetEffectiveConfig().Hierarchy, configs.PartitionHierarchyConfig{MaxDepth: 2, MaxChildren: 2})
+
+	err = partition.CreateQueues("root.users.alice")
+	assert.NilError(t, err, "queue within the limits should be created")
+	assert.Equal(t, partition.GetQueue("root.users.alice").getDepth(), 2, "unexpected depth")
+
+	// too deep: no part of the hierarchy is created
+	err = partition.CreateQueues("root.team.dev.alice")
+	assert.ErrorContains(t, err, "maximum queue depth 2")
+	assert.Assert(t, partition.GetQueue("root.team") == nil, "partial hierarchy should not have been created")
+	err = partition.CreateQueues("root.users.alice.job")
+	assert.Assert(t, err != nil, "queue below a leaf should fail")
+
+	// too many children: existing children can still be used
+	err = partition.CreateQueues("root.users.bob")
+	assert.NilError(t, err, "second child should be created")
+	err = partition.CreateQueues("root.users.carol")
+	assert.ErrorContains(t, err, "root.users already has the maximum of 2 child queues")
+	assert.Assert(t, partition.GetQueue("root.users.carol") == nil, "queue over the fan-out should not have been created")
+	_, err = NewUnmanagedQueue("carol", true, partition.GetQueue("root.users"))
+	assert.ErrorContains(t, err, "maximum of 2 child queues")
+
+	// the reload changes the limits in place
+	conf := partition.GetEffectiveConfig()
+	conf.Hierarchy = configs.PartitionHierarchyConfig{}
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "partition update failed")
+	err = partition.CreateQueues("root.users.carol")
+	assert.NilError(t, err, "queue should be created without limits")
+	err = partition.CreateQueues("root.team.dev.alice")
+	assert.NilError(t, err, "queue should be created without limits")
+}
+
+func TestHierarchyLimitsNoPartition(t *testing.T) {
+	// queues without a partition are not limited
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create root queue")
+	assert.Assert(t, root.getHierarchyLimits() == nil, "root without partition should not have limits")
+	assert.NilError(t, root.CheckHierarchyLimits("child", 10), "queue without limits should not fail")
+}
+
+func TestHierarchyLimitsReload(t *testing.T) {
+	partition, err := CreatePartitionInfo([]byte(configHierarchy))
+	assert.NilError(t, err, "partition create failed")
+	// root has the maximum of children: one managed and one unmanaged
+	err = partition.CreateQueues("root.dynamic")
+	assert.NilError(t, err, "unmanaged queue within the limits should be created")
+	err = partition.CreateQueues("root.other")
+	assert.ErrorContains(t, err, "root already has the maximum of 2 child queues")
+
+	// the unmanaged child does not block new managed children
+	conf := partition.GetEffectiveConfig()
+	conf.Queues[0].Queues = []configs.QueueConfig{{Name: "users", Parent: true}, {Name: "team"}}
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "reload within the configured limits should not fail")
+	assert.Assert(t, partition.GetQueue("root.team") != nil, "managed queue should have been added")
+	assert.Assert(t, partition.GetQueue("root.dynamic") != nil, "unmanaged queue should not have been removed")
+
+	// the removed managed child is draining and does not block its replacement
+	conf.Queues[0].Queues = []configs.QueueConfig{{Name: "team"}, {Name: "dev"}}
+	err = partition.updatePartitionDetails(conf)
+	assert.NilError(t, err, "reload replacing a queue should not fail")
+	assert.Assert(t, partition.GetQueue("root.users").IsDraining(), "removed queue should be draining")
+	assert.Assert(t, partition.GetQueue("root.dev") != nil, "managed queue should have been added")
+	assert.Assert(t, partition.GetQueue("root.team").IsRunning(), "existing queue should not have been changed")
+
+	// unmanaged queues still count all children
+	err = partition.CreateQueues("root.other")
+	assert.ErrorContains(t, err, "root already has the maximum of 2 child queues")
+}
@@ -20,14 +20,19 @@ package cache
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/looplab/fsm"
 	"go.uber.org/zap"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/common"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
@@ -37,10 +42,61 @@ import (
 const (
 	DOT        = "."
 	DotReplace = "_dot_"
-	// How to sort applications, valid options are fair / fifo
-	ApplicationSortPolicy = "application.sort.policy"
+	// The queue properties known to the scheduler, see the configs for the values
+	ApplicationSortPolicy      = configs.ApplicationSortPolicy
+	QueueSortPolicy            = configs.QueueSortPolicy
+	ApplicationSortAging       = configs.ApplicationSortAging
+	QueueStartDelay            = configs.QueueStartDelay
+	QueueBorrowLimit           = configs.QueueBorrowLimit
+	AllocationReuseTTL         = configs.AllocationReuseTTL
+	ApplicationPriorityFloor   = configs.ApplicationPriorityFloor
+	ApplicationPriorityCeiling = configs.ApplicationPriorityCeiling
+	ApplicationGroupMax        = configs.ApplicationGroupMax
+	AskDefaultResource         = configs.AskDefaultResource
+	QueueUserMax               = configs.QueueUserMax
+	NodeSortResourceWeights    = configs.NodeSortResourceWeights
+	QueueAntiAffinity          = configs.QueueAntiAffinity
+	QueueAntiAffinityMode      = configs.QueueAntiAffinityMode
+	QueueMaxEnforcement        = configs.QueueMaxEnforcement
+	QueueMaxTolerance          = configs.QueueMaxTolerance
+	PreemptionScope            = configs.PreemptionScope
+	PreemptionDisabled         = configs.PreemptionDisabled
+	QueueCycleAllocations      = configs.QueueCycleAllocations
+	QueueWeight                = configs.QueueWeight
+	QueuePriority              = configs.QueuePriority
+	QueueGuaranteedDerived     = configs.QueueGuaranteedDerived
 )
 
+// The preemption scopes of a queue
+const (
+	PreemptionScopePartition = configs.PreemptionScopePartition
+	PreemptionScopeQueue     = configs.PreemptionScopeQueue
+)
+
+// The max resource enforcement modes of a queue
+const (
+	MaxEnforcementHard = configs.MaxEnforcementHard
+	MaxEnforcementSoft = configs.MaxEnforcementSoft
+)
+
+// The tolerance used for soft max enforcement if the queue does not set one
+const defaultMaxTolerance = 10
+
+// The weight of a queue that does not set one
+const defaultWeight = 1.0
+
+// The anti affinity modes of a queue
+const (
+	AntiAffinitySoft = configs.AntiAffinitySoft
+	AntiAffinityHard = configs.AntiAffinityHard
+)
+
+// The lowest and highest priority of the asks in a queue
+type priorityRange struct {
+	floor   int32
+	ceiling int32
+}
+
 // The queue structure as used throughout the scheduler
 type QueueInfo struct {
 	Name               string
@@ -51,18 +107,49 @@ type QueueInfo struct {
 	// of the queue or via a queue configuration update
 
 	// Private fields need protection
-	adminACL           security.ACL          // admin ACL
-	submitACL          security.ACL          // submit ACL
-	maxResource        *resources.Resource   // When not set, max = nil
-	guaranteedResource *resources.Resource   // When not set, Guaranteed == 0
-	allocatedResource  *resources.Resource   // set based on allocation
-	isLeaf             bool                  // this is a leaf queue or not (i.e. parent)
-	isManaged          bool                  // queue is part of the config, not auto created
-	stateMachine       *fsm.FSM              // the state of the queue for scheduling
-	stateTime          time.Time             // last time the state was updated (needed for cleanup)
-	children           map[string]*QueueInfo // list of direct children
+	adminACL           security.ACL                   // admin ACL
+	submitACL          security.ACL                   // submit ACL
+	maxResource        *resources.Resource            // When not set, max = nil
+	softMaxResource    *resources.Resource            // When not set, soft max = nil, allocations beyond are allowed but flagged
+	maxAllocation      *resources.Resource            // largest single allocation, nil means not limited
+	guaranteedResource *resources.Resource            // When not set, Guaranteed == 0
+	guaranteedDerived  bool                           // parent queue only: the guarantee is the sum of the guarantees of the children
+	allocatedResource  *resources.Resource            // set based on allocation
+	isLeaf             bool                           // this is a leaf queue or not (i.e. parent)
+	isManaged          bool                           // queue is part of the config, not auto created
+	stateMachine       *fsm.FSM                       // the state of the queue for scheduling
+	stateTime          time.Time                      // last time the state was updated (needed for cleanup)
+	startDelay         time.Duration                  // delay after becoming active before the queue gets allocations
+	appSortAging       time.Duration                  // period an application waits before it moves up in the queue, 0 means no aging
+	reuseTTL           time.Duration                  // time the node of a released allocation is preferred, 0 if disabled
+	priorityRange      *priorityRange                 // range of the ask priorities in the queue, nil if not limited
+	borrowMaxResource  *resources.Resource            // guarantee plus the borrow limit, nil means no borrow limit
+	groupMaxResource   *resources.Resource            // maximum resource of an application group, nil means no limit
+	askDefaultResource *resources.Resource            // resource merged into asks that do not request a type, nil if not set
+	userMaxResource    *resources.Resource            // maximum allocated resource of one user, nil means no absolute limit
+	userMaxShare       int64                          // maximum allocated resource of one user as a percentage of the max, 0 means none
+	userAllocated      map[string]*resources.Resource // allocated resources per user
+	nodeSortWeights    map[string]float64             // resource weights for sorting the nodes, nil means the partition weights
+	antiAffinity       []string                       // fully qualified queues to avoid sharing a node with, nil if not set
+	antiAffinityHard   bool                           // nodes with allocations of the anti affinity queues are never used
+	maxTolerance       int64                          // percentage allocations can exceed the max, 0 means hard enforcement
+	ignored            *ignoredResources              // root only: resource types of the partition not limited by the cluster size
+	hierarchy          *hierarchyLimits               // root only: limits on the depth and fan-out of the queue hierarchy
+	limitsChanged      time.Time                      // last time a reload changed the guaranteed or max resource, zero if never
+	preemptionScoped   bool                           // preemption is contained to the queues below the scope queue
+	preemptionDisabled bool                           // allocations of the queue are never preempted
+	cycleCap           int                            // maximum allocations in a scheduling cycle, 0 means no cap
+	cycleCapShare      bool                           // the cycle cap is a percentage of the allocations of the cycle
+	weight             float64                        // weight of the queue compared to its siblings
+	priority           int32                          // priority of the queue compared to its siblings
+	children           map[string]*QueueInfo          // list of direct children
+	nodePools          map[string]*resources.Resource // node pools the queue can use with the max per pool, nil means not restricted
+	poolAllocated      map[string]*resources.Resource // allocated resources per node pool
+	maxApplications    uint64                         // maximum number of applications as configured, not enforced
+	limits             []configs.Limit                // user and group limits as configured, not enforced
+	childTemplate      *configs.ChildTemplate         // template for the leaf queues created below the queue, nil if not set
 
-	sync.RWMutex // lock for updating the queue
+	locking.RWMutex // lock for updating the queue
 }
 
 // Create a new queue from the configuration object.
@@ -73,7 +160,10 @@ func NewManagedQueue(conf configs.QueueConfig, parent *QueueInfo) (*QueueInfo, e
 		isManaged:         true,
 		isLeaf:            !conf.Parent,
 		stateMachine:      newObjectState(),
+		stateTime:         time.Now(),
 		allocatedResource: resources.NewResource(),
+		poolAllocated:     make(map[string]*resources.Resource),
+		userAllocated:     make(map[string]*resources.Resource),
 	}
 
 	err := qi.updateQueueProps(conf)
@@ -108,15 +198,26 @@ func NewUnmanagedQueue(name string, leaf bool, parent *QueueInfo) (*QueueInfo, e
 		Parent:            parent,
 		isLeaf:            leaf,
 		stateMachine:      newObjectState(),
+		stateTime:         time.Now(),
 		allocatedResource: resources.NewResource(),
+		poolAllocated:     make(map[string]*resources.Resource),
+		userAllocated:     make(map[string]*resources.Resource),
+	}
+	// a leaf queue created below a parent with a template gets the resources and properties of the template
+	if leaf && parent != nil {
+		if template := parent.getChildTemplate(); template != nil {
+			if err := qi.applyChildTemplate(template, parent.Properties); err != nil {
+				return nil, fmt.Errorf("queue creation failed: %s", err)
+			}
+		}
 	}
-	// TODO set resources and properties on unmanaged queues
 	// add the queue in the structure
 	if parent != nil {
 		err := parent.addChildQueue(qi)
 		if err != nil {
 			return nil, fmt.Errorf("queue creation failed: %s", err)
 		}
+		parent.updateDerivedGuarantee()
 	}
 
 	return qi, nil
@@ -138,6 +239,13 @@ func (qi *QueueInfo) HandleQueueEvent(event SchedulingObjectEvent) error {
 	return err
 }
 
+// Return the time of the last state change of the queue, for a draining queue the time it started draining.
+func (qi *QueueInfo) GetStateTime() time.Time {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.stateTime
+}
+
 // Return the currently allocated resource for the queue.
 // It returns a cloned object as we do not want to allow modifications to be made to the
 // value of the queue.
@@ -165,6 +273,169 @@ func (qi *QueueInfo) GetMaxResource() *resources.Resource {
 	return qi.maxResource.Clone()
 }
 
+// Return the max resource enforced for the queue: the max resource increased by the tolerance with soft enforcement.
+// If not set the returned resource will be nil.
+func (qi *QueueInfo) GetEnforcedMaxResource() *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.getEnforcedMaxResource()
+}
+
+// Return a copy of the max resource enforced for the queue, nil if not set.
+// Lock free call, must be called holding the queue lock.
+func (qi *QueueInfo) getEnforcedMaxResource() *resources.Resource {
+	if qi.maxResource == nil {
+		return nil
+	}
+	enforced := qi.maxResource.Clone()
+	if qi.maxTolerance > 0 {
+		for key, value := range enforced.Resources {
+			enforced.Resources[key] = value + value*resources.Quantity(qi.maxTolerance)/100
+		}
+	}
+	// the ignored types are tracked but never limit the root queue: the cluster size is a node fit check
+	for _, resType := range qi.ignored.getTypes() {
+		enforced.Resources[resType] = math.MaxInt64
+	}
+	return enforced
+}
+
+// Return the soft max resource for the queue.
+// If not set the returned resource will be nil.
+func (qi *QueueInfo) GetSoftMaxResource() *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	if qi.softMaxResource == nil {
+		return nil
+	}
+	return qi.softMaxResource.Clone()
+}
+
+// Return the largest single allocation for the queue, asks that are larger are rejected.
+// If not set the returned resource will be nil.
+func (qi *QueueInfo) GetMaxAllocation() *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	if qi.maxAllocation == nil {
+		return nil
+	}
+	return qi.maxAllocation.Clone()
+}
+
+// Return the maximum resource the queue can use based on its guarantee and the borrow limit.
+// Only the resource types of the guarantee are limited by the borrow limit.
+// If the queue has no borrow limit or no guarantee the returned resource will be nil.
+func (qi *QueueInfo) GetBorrowMaxResource() *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	if qi.borrowMaxResource == nil {
+		return nil
+	}
+	return qi.borrowMaxResource.Clone()
+}
+
+// Return the resource weights used to sort the nodes for the applications in the queue.
+// Returns nil if the queue does not set weights. The returned map must not be modified.
+func (qi *QueueInfo) GetNodeSortWeights() map[string]float64 {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.nodeSortWeights
+}
+
+// Return the queues the allocations of the queue avoid sharing a node with and if the anti affinity is hard.
+// Returns nil if the queue has no anti affinity.
+func (qi *QueueInfo) GetAntiAffinity() ([]string, bool) {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.antiAffinity, qi.antiAffinityHard
+}
+
+// Is the preemption for the queue contained to the queues below the highest parent with the queue scope?
+// See PreemptionScope.
+func (qi *QueueInfo) IsPreemptionScoped() bool {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.preemptionScoped
+}
+
+// Are the allocations of the queue excluded from preemption?
+// See PreemptionDisabled.
+func (qi *QueueInfo) IsPreemptionDisabled() bool {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.preemptionDisabled
+}
+
+// Return the maximum number of allocations of the queue in a scheduling cycle with the number of allocations passed
+// in, 0 means the queue is not capped. A cap that is a percentage is at least one allocation.
+// See QueueCycleAllocations.
+func (qi *QueueInfo) GetCycleAllocationCap(cycleSize int) int {
+	qi.RLock()
+	defer qi.RUnlock()
+	if !qi.cycleCapShare {
+		return qi.cycleCap
+	}
+	if cycleCap := cycleSize * qi.cycleCap / 100; cycleCap > 0 {
+		return cycleCap
+	}
+	return 1
+}
+
+// Return the weight of the queue compared to its siblings, 1 if the queue does not set a weight.
+// See QueueWeight.
+func (qi *QueueInfo) GetWeight() float64 {
+	qi.RLock()
+	defer qi.RUnlock()
+	if qi.weight <= 0 {
+		return defaultWeight
+	}
+	return qi.weight
+}
+
+// Return the priority of the queue compared to its siblings, 0 if the queue does not set a priority.
+// See QueuePriority.
+func (qi *QueueInfo) GetPriority() int32 {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.priority
+}
+
+// Return a copy of the maximum combined resource of an application group in the queue.
+// Returns nil if the queue does not limit application groups.
+func (qi *QueueInfo) GetApplicationGroupMax() *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	if qi.groupMaxResource == nil {
+		return nil
+	}
+	return qi.groupMaxResource.Clone()
+}
+
+// Return a copy of the resource merged into the asks of the queue that do not request a resource type.
+// Returns nil if the queue does not set a default. See AskDefaultResource.
+func (qi *QueueInfo) GetAskDefaultResource() *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	if qi.askDefaultResource == nil {
+		return nil
+	}
+	return qi.askDefaultResource.Clone()
+}
+
+// Is the allocated resource of the queue over the max? Only possible with soft max enforcement.
+func (qi *QueueInfo) IsOverMax() bool {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.maxResource != nil && !resources.FitIn(qi.maxResource, qi.allocatedResource)
+}
+
+// Is the allocated resource of the queue over the soft max?
+func (qi *QueueInfo) IsOverSoftMax() bool {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.softMaxResource != nil && !resources.FitIn(qi.softMaxResource, qi.allocatedResource)
+}
+
 // Set the max resource for root the queue.
 // Should only happen on the root, all other queues get it from the config via properties.
 func (qi *QueueInfo) setMaxResource(max *resources.Resource) {
@@ -200,6 +471,7 @@ func (qi *QueueInfo) GetQueuePath() string {
 // Add a new child queue to this queue
 // - can only add to a non leaf queue
 // - cannot add when the queue is marked for deletion
+// - cannot add beyond the hierarchy limits of the partition
 // - if this is the first child initialise
 func (qi *QueueInfo) addChildQueue(child *QueueInfo) error {
 	qi.Lock()
@@ -208,7 +480,10 @@ func (qi *QueueInfo) addChildQueue(child *QueueInfo) error {
 		return fmt.Errorf("cannot add a child queue to a leaf queue: %s", qi.Name)
 	}
 	if qi.IsDraining() {
-		return fmt.Errorf("cannot add a child queue when queue is marked for deletion: %s", qi.Name)
+		return api.NewError(api.ErrInvalidState, "cannot add a child queue when queue is marked for deletion: %s", qi.Name)
+	}
+	if err := qi.checkHierarchyLimits(child.Name, 1, child.isManaged); err != nil {
+		return fmt.Errorf("cannot add child queue %s to queue %s: %v", child.Name, qi.GetQueuePath(), err)
 	}
 	// add the child (init if needed)
 	if qi.children == nil {
@@ -218,15 +493,6 @@ func (qi *QueueInfo) addChildQueue(child *QueueInfo) error {
 	return nil
 }
 
-func (qi *QueueInfo) updateUsedResourceMetrics() {
-	// update queue metrics when this is a leaf queue
-	if qi.isLeaf {
-		for k, v := range qi.allocatedResource.Resources {
-			metrics.GetQueueMetrics(qi.GetQueuePath()).SetQueueUsedResourceMetrics(k, float64(v))
-		}
-	}
-}
-
 // Increment the allocated resources for this queue (recursively)
 // Guard against going over max resources if set
 func (qi *QueueInfo) IncAllocatedResource(alloc *resources.Resource, nodeReported bool) error {
@@ -236,9 +502,9 @@ func (qi *QueueInfo) IncAllocatedResource(alloc *resources.Resource, nodeReporte
 	// check this queue: failure stops checks if the allocation is not part of a node addition
 	newAllocation := resources.Add(qi.allocatedResource, alloc)
 	if !nodeReported {
-		if qi.maxResource != nil && !resources.FitIn(qi.maxResource, newAllocation) {
-			return fmt.Errorf("allocation (%v) puts queue %s over maximum allocation (%v)",
-				alloc, qi.GetQueuePath(), qi.maxResource)
+		if maxResource := qi.getEnforcedMaxResource(); maxResource != nil && !resources.FitIn(maxResource, newAllocation) {
+			return api.NewError(api.ErrOverQueueMax, "allocation (%v) puts queue %s over maximum allocation (%v)",
+				alloc, qi.GetQueuePath(), maxResource)
 		}
 	}
 	// check the parent: need to pass before updating
@@ -252,11 +518,47 @@ func (qi *QueueInfo) IncAllocatedResource(alloc *resources.Resource, nodeReporte
 		}
 	}
 	// all OK update this queue
+	qi.checkOverMax(alloc, newAllocation)
+	qi.checkSoftMax(alloc, newAllocation)
 	qi.allocatedResource = newAllocation
-	qi.updateUsedResourceMetrics()
 	return nil
 }
 
+// Flag an allocation that puts the queue over the max, only allowed with soft max enforcement. A warning is logged
+// when the queue goes over the max and every allocation beyond the max is counted.
+// Lock free call this must be called holding the queue lock
+func (qi *QueueInfo) checkOverMax(alloc, newAllocation *resources.Resource) {
+	if qi.maxResource == nil || resources.FitIn(qi.maxResource, newAllocation) {
+		return
+	}
+	if resources.FitIn(qi.maxResource, qi.allocatedResource) {
+		log.Logger().Warn("queue allocation over max resource",
+			zap.String("queueName", qi.GetQueuePath()),
+			zap.Any("allocation", alloc),
+			zap.Any("allocatedResource", newAllocation),
+			zap.Any("maxResource", qi.maxResource),
+			zap.Int64("tolerance", qi.maxTolerance))
+	}
+	metrics.GetQueueMetrics(qi.GetQueuePath()).IncAllocationsOverMax()
+}
+
+// Flag an allocation that puts the queue over the soft max. The allocation is allowed, a warning is logged when the
+// queue goes over the soft max and every allocation beyond the soft max is counted.
+// Lock free call this must be called holding the queue lock
+func (qi *QueueInfo) checkSoftMax(alloc, newAllocation *resources.Resource) {
+	if qi.softMaxResource == nil || resources.FitIn(qi.softMaxResource, newAllocation) {
+		return
+	}
+	if resources.FitIn(qi.softMaxResource, qi.allocatedResource) {
+		log.Logger().Warn("queue allocation over soft max resource",
+			zap.String("queueName", qi.GetQueuePath()),
+			zap.Any("allocation", alloc),
+			zap.Any("allocatedResource", newAllocation),
+			zap.Any("softMaxResource", qi.softMaxResource))
+	}
+	metrics.GetQueueMetrics(qi.GetQueuePath()).IncAllocationsOverSoftMax()
+}
+
 // Decrement the allocated resources for this queue (recursively)
 // Guard against going below zero resources.
 func (qi *QueueInfo) decAllocatedResource(alloc *resources.Resource) error {
@@ -280,7 +582,179 @@ func (qi *QueueInfo) decAllocatedResource(alloc *resources.Resource) error {
 	}
 	// all OK update the queue
 	qi.allocatedResource = resources.Sub(qi.allocatedResource, alloc)
-	qi.updateUsedResourceMetrics()
+	return nil
+}
+
+// Can the queue use nodes from the node pool? The queue and all its parents must allow the pool.
+func (qi *QueueInfo) IsNodePoolAllowed(pool string) bool {
+	qi.RLock()
+	allowed := true
+	if qi.nodePools != nil {
+		_, allowed = qi.nodePools[pool]
+	}
+	qi.RUnlock()
+	if !allowed {
+		return false
+	}
+	if qi.Parent != nil {
+		return qi.Parent.IsNodePoolAllowed(pool)
+	}
+	return true
+}
+
+// Return the max resource for the queue in the node pool.
+// If not set the returned resource will be nil.
+func (qi *QueueInfo) GetNodePoolMaxResource(pool string) *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	if max := qi.nodePools[pool]; max != nil {
+		return max.Clone()
+	}
+	return nil
+}
+
+// Return the currently allocated resource for the queue in the node pool.
+func (qi *QueueInfo) GetNodePoolAllocatedResource(pool string) *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	if allocated := qi.poolAllocated[pool]; allocated != nil {
+		return allocated.Clone()
+	}
+	return resources.NewResource()
+}
+
+// Increment the allocated resources for this queue in the node pool (recursively)
+// Guard against going over the max resources for the pool if set
+func (qi *QueueInfo) incNodePoolAllocatedResource(pool string, alloc *resources.Resource, nodeReported bool) error {
+	qi.Lock()
+	defer qi.Unlock()
+
+	// check this queue: failure stops checks if the allocation is not part of a node addition
+	newAllocation := resources.Add(qi.poolAllocated[pool], alloc)
+	if !nodeReported {
+		if max := qi.nodePools[pool]; max != nil && !resources.FitIn(max, newAllocation) {
+			return api.NewError(api.ErrOverQueueMax, "allocation (%v) puts queue %s over maximum allocation (%v) in node pool %s",
+				alloc, qi.GetQueuePath(), max, pool)
+		}
+	}
+	// check the parent: need to pass before updating
+	if qi.Parent != nil {
+		if err := qi.Parent.incNodePoolAllocatedResource(pool, alloc, nodeReported); err != nil {
+			return err
+		}
+	}
+	// all OK update this queue
+	qi.poolAllocated[pool] = newAllocation
+	return nil
+}
+
+// Decrement the allocated resources for this queue in the node pool (recursively)
+// Guard against going below zero resources.
+func (qi *QueueInfo) decNodePoolAllocatedResource(pool string, alloc *resources.Resource) error {
+	qi.Lock()
+	defer qi.Unlock()
+
+	// check this queue: failure stops checks
+	allocated := qi.poolAllocated[pool]
+	if alloc != nil && !resources.FitIn(allocated, alloc) {
+		return fmt.Errorf("released allocation (%v) is larger than queue %s allocation (%v) in node pool %s",
+			alloc, qi.GetQueuePath(), allocated, pool)
+	}
+	// check the parent: need to pass before updating
+	if qi.Parent != nil {
+		if err := qi.Parent.decNodePoolAllocatedResource(pool, alloc); err != nil {
+			return err
+		}
+	}
+	// all OK update the queue
+	qi.poolAllocated[pool] = resources.Sub(allocated, alloc)
+	return nil
+}
+
+// Return the maximum allocated resource of one user in the queue: the configured resource, or the configured
+// percentage of the max resource of the queue.
+// Returns nil if the queue does not limit users, or if a percentage is set and the queue has no max resource.
+func (qi *QueueInfo) GetUserMaxResource() *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.getUserMaxResource()
+}
+
+// Lock free call this must be called holding the queue lock
+func (qi *QueueInfo) getUserMaxResource() *resources.Resource {
+	if qi.userMaxResource != nil {
+		return qi.userMaxResource.Clone()
+	}
+	if qi.userMaxShare == 0 || qi.maxResource == nil {
+		return nil
+	}
+	userMax := resources.NewResource()
+	for key, value := range qi.maxResource.Resources {
+		userMax.Resources[key] = value * resources.Quantity(qi.userMaxShare) / 100
+	}
+	return userMax
+}
+
+// Return the currently allocated resource of the user in the queue.
+func (qi *QueueInfo) GetUserAllocatedResource(user string) *resources.Resource {
+	qi.RLock()
+	defer qi.RUnlock()
+	if allocated := qi.userAllocated[user]; allocated != nil {
+		return allocated.Clone()
+	}
+	return resources.NewResource()
+}
+
+// Increment the allocated resources of the user in this queue (recursively)
+// Guard against going over the max resources for one user if set
+func (qi *QueueInfo) incUserAllocatedResource(user string, alloc *resources.Resource, nodeReported bool) error {
+	qi.Lock()
+	defer qi.Unlock()
+
+	// check this queue: failure stops checks if the allocation is not part of a node addition
+	newAllocation := resources.Add(qi.userAllocated[user], alloc)
+	if !nodeReported {
+		if userMax := qi.getUserMaxResource(); userMax != nil && !resources.FitIn(userMax, newAllocation) {
+			return api.NewError(api.ErrOverQueueMax, "allocation (%v) puts user %s over maximum allocation (%v) in queue %s",
+				alloc, user, userMax, qi.GetQueuePath())
+		}
+	}
+	// check the parent: need to pass before updating
+	if qi.Parent != nil {
+		if err := qi.Parent.incUserAllocatedResource(user, alloc, nodeReported); err != nil {
+			return err
+		}
+	}
+	// all OK update this queue
+	qi.userAllocated[user] = newAllocation
+	return nil
+}
+
+// Decrement the allocated resources of the user in this queue (recursively)
+// Guard against going below zero resources. The user is removed when nothing is allocated anymore.
+func (qi *QueueInfo) decUserAllocatedResource(user string, alloc *resources.Resource) error {
+	qi.Lock()
+	defer qi.Unlock()
+
+	// check this queue: failure stops checks
+	allocated := qi.userAllocated[user]
+	if alloc != nil && !resources.FitIn(allocated, alloc) {
+		return fmt.Errorf("released allocation (%v) is larger than user %s allocation (%v) in queue %s",
+			alloc, user, allocated, qi.GetQueuePath())
+	}
+	// check the parent: need to pass before updating
+	if qi.Parent != nil {
+		if err := qi.Parent.decUserAllocatedResource(user, alloc); err != nil {
+			return err
+		}
+	}
+	// all OK update the queue
+	allocated = resources.Sub(allocated, alloc)
+	if resources.IsZero(allocated) {
+		delete(qi.userAllocated, user)
+	} else {
+		qi.userAllocated[user] = allocated
+	}
 	return nil
 }
 
@@ -296,6 +770,82 @@ func (qi *QueueInfo) GetCopyOfChildren() map[string]*QueueInfo {
 	return children
 }
 
+// Get the effective configuration of the queue and all its children.
+// The configuration reflects the queue as it is used by the scheduler: properties are merged with the parent,
+// the root max is the size of the partition and queues created by placement rules are included.
+// Children and node pools are sorted by name to give a stable output.
+func (qi *QueueInfo) GetEffectiveConfig() configs.QueueConfig {
+	qi.RLock()
+	conf := configs.QueueConfig{
+		Name:   qi.Name,
+		Parent: !qi.isLeaf,
+		Resources: configs.Resources{
+			Guaranteed:    qi.guaranteedResource.ToConf(),
+			Max:           qi.maxResource.ToConf(),
+			SoftMax:       qi.softMaxResource.ToConf(),
+			MaxAllocation: qi.maxAllocation.ToConf(),
+		},
+		MaxApplications: qi.maxApplications,
+		AdminACL:        qi.adminACL.String(),
+		SubmitACL:       qi.submitACL.String(),
+		Limits:          qi.limits,
+	}
+	if len(qi.Properties) != 0 {
+		conf.Properties = make(map[string]string, len(qi.Properties))
+		for key, value := range qi.Properties {
+			conf.Properties[key] = value
+		}
+	}
+	for name, poolMax := range qi.nodePools {
+		conf.NodePools = append(conf.NodePools, configs.NodePoolConfig{Name: name, Max: poolMax.ToConf()})
+	}
+	if qi.childTemplate != nil {
+		conf.ChildTemplate = *qi.childTemplate
+	}
+	qi.RUnlock()
+	sort.Slice(conf.NodePools, func(i, j int) bool {
+		return conf.NodePools[i].Name < conf.NodePools[j].Name
+	})
+	// children lock themselves
+	children := qi.GetCopyOfChildren()
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		conf.Queues = append(conf.Queues, children[name].GetEffectiveConfig())
+	}
+	return conf
+}
+
+// Get the queue configuration as sent to the RM in a queue update.
+// The properties are the merged properties, resources that are not set are nil.
+func (qi *QueueInfo) getQueueUpdate(partitionName string) *api.QueueUpdate {
+	queuePath := qi.GetQueuePath()
+	qi.RLock()
+	defer qi.RUnlock()
+	update := &api.QueueUpdate{
+		PartitionName:   partitionName,
+		QueueName:       queuePath,
+		State:           qi.stateMachine.Current(),
+		MaxApplications: qi.maxApplications,
+	}
+	if qi.maxResource != nil {
+		update.MaxResource = qi.maxResource.ToProto()
+	}
+	if qi.guaranteedResource != nil {
+		update.GuaranteedResource = qi.guaranteedResource.ToProto()
+	}
+	if len(qi.Properties) != 0 {
+		update.Properties = make(map[string]string, len(qi.Properties))
+		for key, value := range qi.Properties {
+			update.Properties[key] = value
+		}
+	}
+	return update
+}
+
 // Remove a child from the list of children
 // No checks are performed: if the child has been removed already it is a noop.
 // This may only be called by the queue removal itself on the registered parent.
@@ -324,6 +874,7 @@ func (qi *QueueInfo) RemoveQueue() bool {
 	log.Logger().Info("removing queue", zap.String("queue", qi.Name))
 	// root is always managed and is the only queue with a nil parent: no need to guard
 	qi.Parent.removeChildQueue(qi.Name)
+	qi.Parent.updateDerivedGuarantee()
 	return true
 }
 
@@ -352,6 +903,100 @@ func (qi *QueueInfo) MarkQueueForRemoval() {
 	}
 }
 
+// Derive the guaranteed resource for the queue and its children if it is not configured, the same way as
+// is done when the partition is created: a leaf queue gets an empty guarantee, a parent queue the sum of the
+// guarantees of its children. Returns the guaranteed resource of the queue.
+// The configuration has been validated before the update so no checks are performed.
+func (qi *QueueInfo) deriveGuaranteedResource() *resources.Resource {
+	children := qi.GetCopyOfChildren()
+	sum := resources.NewResource()
+	for _, child := range children {
+		sum.AddTo(child.deriveGuaranteedResource())
+	}
+	qi.Lock()
+	defer qi.Unlock()
+	if qi.guaranteedResource == nil || qi.guaranteedDerived {
+		qi.guaranteedResource = sum
+		qi.setBorrowMaxResource()
+	}
+	return qi.guaranteedResource
+}
+
+// Return true if the guaranteed resource of the queue is derived from its children.
+// See QueueGuaranteedDerived.
+func (qi *QueueInfo) IsGuaranteedDerived() bool {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.guaranteedDerived
+}
+
+// Set the guaranteed resource of the queue to the sum of the guarantees of its children if the queue derives its
+// guarantee, and update the parents that derive their guarantee in turn.
+// Called when the children of the queue change, stops at the first queue that does not derive its guarantee.
+func (qi *QueueInfo) updateDerivedGuarantee() {
+	for queue := qi; queue != nil && queue.IsGuaranteedDerived(); queue = queue.Parent {
+		sum := resources.NewResource()
+		for _, child := range queue.GetCopyOfChildren() {
+			sum.AddTo(child.GetGuaranteedResource())
+		}
+		queue.Lock()
+		queue.guaranteedResource = sum
+		queue.setBorrowMaxResource()
+		queue.Unlock()
+	}
+}
+
+// The guaranteed and max resource of a queue before a reload, see markChangedLimits.
+type queueLimits struct {
+	guaranteed *resources.Resource
+	max        *resources.Resource
+}
+
+// Add the guaranteed and max resource of the queue and all its children to the limits by queue path.
+func (qi *QueueInfo) getLimits(limits map[string]queueLimits) map[string]queueLimits {
+	limits[qi.GetQueuePath()] = queueLimits{
+		guaranteed: qi.GetGuaranteedResource(),
+		max:        qi.GetMaxResource(),
+	}
+	for _, child := range qi.GetCopyOfChildren() {
+		child.getLimits(limits)
+	}
+	return limits
+}
+
+// Record the time of the change on the queues of which the guaranteed or max resource differs from the limits before
+// the reload, the queue and all its children are checked. The max of the root queue is the size of the cluster and
+// is not checked. Queues added by the reload are not marked: they have no allocations yet.
+// A guarantee that is not set and an empty guarantee are the same: both mean the queue has no guarantee.
+func (qi *QueueInfo) markChangedLimits(before map[string]queueLimits, now time.Time) {
+	if limits, ok := before[qi.GetQueuePath()]; ok && qi.Parent != nil {
+		guaranteed := qi.GetGuaranteedResource()
+		sameGuarantee := resources.Equals(limits.guaranteed, guaranteed) || (resources.IsZero(limits.guaranteed) && resources.IsZero(guaranteed))
+		if !sameGuarantee || !resources.Equals(limits.max, qi.GetMaxResource()) {
+			qi.Lock()
+			qi.limitsChanged = now
+			qi.Unlock()
+		}
+	}
+	for _, child := range qi.GetCopyOfChildren() {
+		child.markChangedLimits(before, now)
+	}
+}
+
+// Return the last time a reload changed the guaranteed or max resource of the queue or one of its parents, zero if
+// the limits never changed. See configs.PartitionPreemptionConfig for the preemption settle period.
+func (qi *QueueInfo) GetLimitsChangeTime() time.Time {
+	var changed time.Time
+	for queue := qi; queue != nil; queue = queue.Parent {
+		queue.RLock()
+		if queue.limitsChanged.After(changed) {
+			changed = queue.limitsChanged
+		}
+		queue.RUnlock()
+	}
+	return changed
+}
+
 // Update an existing managed queue based on the updated configuration
 func (qi *QueueInfo) updateQueueProps(conf configs.QueueConfig) error {
 	qi.Lock()
@@ -382,26 +1027,91 @@ func (qi *QueueInfo) updateQueueProps(conf configs.QueueConfig) error {
 		qi.isLeaf = false
 	}
 
-	// Load the max resources
+	// Load the max resources: replaced in place, a max that is no longer configured is removed.
+	// The max of the root queue is normally not configured, it is the size of the cluster and is kept.
 	maxResource, err := resources.NewResourceFromConf(conf.Resources.Max)
 	if err != nil {
 		log.Logger().Error("parsing failed on max resources this should not happen",
 			zap.Error(err))
 		return err
 	}
-	if len(maxResource.Resources) != 0 {
+	if len(maxResource.Resources) == 0 {
+		maxResource = nil
+	}
+	if (qi.Parent != nil || maxResource != nil) && !resources.Equals(qi.maxResource, maxResource) {
+		log.Logger().Info("queue max resource updated",
+			zap.String("queueName", qi.GetQueuePath()),
+			zap.Any("oldMaxResource", qi.maxResource),
+			zap.Any("newMaxResource", maxResource))
 		qi.maxResource = maxResource
 	}
 
-	// Load the guaranteed resources
+	// Load the soft max resources
+	qi.softMaxResource = nil
+	if len(conf.Resources.SoftMax) != 0 {
+		qi.softMaxResource, err = resources.NewResourceFromConf(conf.Resources.SoftMax)
+		if err != nil {
+			log.Logger().Error("parsing failed on soft max resources this should not happen",
+				zap.Error(err))
+			return err
+		}
+	}
+
+	// Load the max allocation resources
+	qi.maxAllocation = nil
+	if len(conf.Resources.MaxAllocation) != 0 {
+		qi.maxAllocation, err = resources.NewResourceFromConf(conf.Resources.MaxAllocation)
+		if err != nil {
+			log.Logger().Error("parsing failed on max allocation resources this should not happen",
+				zap.Error(err))
+			return err
+		}
+	}
+
+	// Load the guaranteed resources: replaced in place, a guarantee that is not configured is derived
+	// after the whole hierarchy is loaded (see deriveGuaranteedResource)
 	guaranteedResource, err := resources.NewResourceFromConf(conf.Resources.Guaranteed)
 	if err != nil {
 		log.Logger().Error("parsing failed on max resources this should not happen",
 			zap.Error(err))
 		return err
 	}
-	if len(guaranteedResource.Resources) != 0 {
-		qi.guaranteedResource = guaranteedResource
+	if len(guaranteedResource.Resources) == 0 {
+		guaranteedResource = nil
+	}
+	if guaranteedResource != nil && !resources.Equals(qi.guaranteedResource, guaranteedResource) {
+		log.Logger().Info("queue guaranteed resource updated",
+			zap.String("queueName", qi.GetQueuePath()),
+			zap.Any("oldGuaranteedResource", qi.guaranteedResource),
+			zap.Any("newGuaranteedResource", guaranteedResource))
+	}
+	qi.guaranteedResource = guaranteedResource
+
+	// Load the node pools, no pools means not restricted
+	qi.nodePools = nil
+	if len(conf.NodePools) != 0 {
+		qi.nodePools = make(map[string]*resources.Resource)
+		for _, pool := range conf.NodePools {
+			var poolMax *resources.Resource
+			if len(pool.Max) != 0 {
+				poolMax, err = resources.NewResourceFromConf(pool.Max)
+				if err != nil {
+					log.Logger().Error("parsing failed on node pool max resources this should not happen",
+						zap.String("nodePool", pool.Name),
+						zap.Error(err))
+					return err
+				}
+			}
+			qi.nodePools[pool.Name] = poolMax
+		}
+	}
+
+	qi.maxApplications = conf.MaxApplications
+	qi.limits = conf.Limits
+	qi.childTemplate = nil
+	if !conf.ChildTemplate.IsEmpty() {
+		template := conf.ChildTemplate
+		qi.childTemplate = &template
 	}
 
 	// Update Properties
@@ -409,10 +1119,457 @@ func (qi *QueueInfo) updateQueueProps(conf configs.QueueConfig) error {
 	if qi.Parent != nil && qi.Parent.Properties != nil {
 		qi.Properties = mergeProperties(qi.Parent.Properties, conf.Properties)
 	}
+	qi.setPropertyValues()
+	return nil
+}
 
+// Set the resources, properties and ACLs of a queue created by a placement rule from the template of the parent.
+// The template properties are merged with the properties of the parent.
+// The template is validated as part of the config: we should not see any errors.
+func (qi *QueueInfo) applyChildTemplate(template *configs.ChildTemplate, parentProps map[string]string) error {
+	qi.Lock()
+	defer qi.Unlock()
+	var err error
+	if len(template.Resources.Max) != 0 {
+		if qi.maxResource, err = resources.NewResourceFromConf(template.Resources.Max); err != nil {
+			return err
+		}
+	}
+	if len(template.Resources.SoftMax) != 0 {
+		if qi.softMaxResource, err = resources.NewResourceFromConf(template.Resources.SoftMax); err != nil {
+			return err
+		}
+	}
+	if len(template.Resources.MaxAllocation) != 0 {
+		if qi.maxAllocation, err = resources.NewResourceFromConf(template.Resources.MaxAllocation); err != nil {
+			return err
+		}
+	}
+	if len(template.Resources.Guaranteed) != 0 {
+		if qi.guaranteedResource, err = resources.NewResourceFromConf(template.Resources.Guaranteed); err != nil {
+			return err
+		}
+	}
+	if template.AdminACL != "" {
+		if qi.adminACL, err = security.NewACL(template.AdminACL); err != nil {
+			return err
+		}
+	}
+	if template.SubmitACL != "" {
+		if qi.submitACL, err = security.NewACL(template.SubmitACL); err != nil {
+			return err
+		}
+	}
+	qi.maxApplications = template.MaxApplications
+	qi.Properties = template.Properties
+	if parentProps != nil {
+		qi.Properties = mergeProperties(parentProps, template.Properties)
+	}
+	qi.setPropertyValues()
 	return nil
 }
 
+// Get the template for the leaf queues created below the queue, nil if not set.
+func (qi *QueueInfo) getChildTemplate() *configs.ChildTemplate {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.childTemplate
+}
+
+// Set the values that are derived from the queue properties.
+// Lock free call, must be called holding the queue lock.
+func (qi *QueueInfo) setPropertyValues() {
+	qi.startDelay = parseStartDelay(qi.Properties)
+	qi.appSortAging = parseAppSortAging(qi.Properties)
+	qi.reuseTTL = parseReuseTTL(qi.Properties)
+	qi.priorityRange = parsePriorityRange(qi.Properties)
+	qi.groupMaxResource = parseGroupMax(qi.Properties)
+	qi.askDefaultResource = parseAskDefault(qi.Properties)
+	qi.userMaxResource, qi.userMaxShare = parseUserMax(qi.Properties)
+	qi.nodeSortWeights = parseNodeSortWeights(qi.Properties)
+	qi.antiAffinity, qi.antiAffinityHard = parseAntiAffinity(qi.Properties)
+	qi.maxTolerance = parseMaxTolerance(qi.Properties)
+	qi.preemptionScoped = parsePreemptionScope(qi.Properties)
+	qi.preemptionDisabled = parsePreemptionDisabled(qi.Properties)
+	qi.cycleCap, qi.cycleCapShare = parseCycleCap(qi.Properties)
+	qi.weight = parseWeight(qi.Properties)
+	qi.priority = parseQueuePriority(qi.Properties)
+	qi.guaranteedDerived = !qi.isLeaf && parseGuaranteedDerived(qi.Properties)
+	qi.setBorrowMaxResource()
+}
+
+// Set the borrow max from the guarantee and the borrow limit of the queue.
+// Lock free call, must be called holding the queue lock.
+func (qi *QueueInfo) setBorrowMaxResource() {
+	qi.borrowMaxResource = nil
+	if borrowLimit, ok := parseBorrowLimit(qi.Properties); ok && qi.guaranteedResource != nil {
+		qi.borrowMaxResource = resources.NewResource()
+		for key, value := range qi.guaranteedResource.Resources {
+			qi.borrowMaxResource.Resources[key] = value + value*resources.Quantity(borrowLimit)/100
+		}
+	}
+}
+
+// Get the borrow limit percentage from the queue properties, the percent sign is optional.
+// An invalid or negative value is logged and ignored, the queue will not have a borrow limit.
+func parseBorrowLimit(props map[string]string) (int64, bool) {
+	value, ok := props[QueueBorrowLimit]
+	if !ok {
+		return 0, false
+	}
+	limit, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), "%"), 10, 64)
+	if err != nil || limit < 0 {
+		log.Logger().Warn("invalid queue borrow limit, ignoring property",
+			zap.String("property", QueueBorrowLimit),
+			zap.String("value", value))
+		return 0, false
+	}
+	return limit, true
+}
+
+// Get the allocation cap per scheduling cycle from the queue properties: a number of allocations, or a percentage
+// of the allocations of the cycle if the value ends with a percent sign. An invalid value, a value that is not
+// positive or a percentage over 100 is logged and ignored, the queue will not be capped.
+func parseCycleCap(props map[string]string) (int, bool) {
+	value, ok := props[QueueCycleAllocations]
+	if !ok {
+		return 0, false
+	}
+	trimmed := strings.TrimSpace(value)
+	share := strings.HasSuffix(trimmed, "%")
+	cycleCap, err := strconv.Atoi(strings.TrimSuffix(trimmed, "%"))
+	if err != nil || cycleCap <= 0 || (share && cycleCap > 100) {
+		log.Logger().Warn("invalid queue cycle allocations, ignoring property",
+			zap.String("property", QueueCycleAllocations),
+			zap.String("value", value))
+		return 0, false
+	}
+	return cycleCap, share
+}
+
+// Get the weight from the queue properties.
+// An invalid value or a value that is not positive is logged and ignored, the queue will use the default weight.
+func parseWeight(props map[string]string) float64 {
+	value, ok := props[QueueWeight]
+	if !ok {
+		return defaultWeight
+	}
+	weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || weight <= 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		log.Logger().Warn("invalid queue weight, using default weight",
+			zap.String("property", QueueWeight),
+			zap.String("value", value))
+		return defaultWeight
+	}
+	return weight
+}
+
+// Get the priority of the queue from the queue properties.
+// An invalid value is logged and ignored, the queue will use the default priority of 0.
+func parseQueuePriority(props map[string]string) int32 {
+	value, ok := props[QueuePriority]
+	if !ok {
+		return 0
+	}
+	priority, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		log.Logger().Warn("invalid queue priority, using default priority",
+			zap.String("property", QueuePriority),
+			zap.String("value", value))
+		return 0
+	}
+	return int32(priority)
+}
+
+// Get the preemption scope from the queue properties: true if preemption is contained to the queue.
+// An invalid scope is logged and ignored, the queue will use the partition scope.
+func parsePreemptionScope(props map[string]string) bool {
+	switch scope := strings.ToLower(strings.TrimSpace(props[PreemptionScope])); scope {
+	case PreemptionScopeQueue:
+		return true
+	case "", PreemptionScopePartition:
+		return false
+	default:
+		log.Logger().Warn("invalid preemption scope, using partition scope",
+			zap.String("property", PreemptionScope),
+			zap.String("value", scope))
+		return false
+	}
+}
+
+// Get the preemption opt out from the queue properties: true if the allocations of the queue are never preempted.
+// An invalid value is logged and ignored, the allocations of the queue can be preempted.
+func parsePreemptionDisabled(props map[string]string) bool {
+	switch disabled := strings.ToLower(strings.TrimSpace(props[PreemptionDisabled])); disabled {
+	case "true":
+		return true
+	case "", "false":
+		return false
+	default:
+		log.Logger().Warn("invalid preemption disabled value, preemption enabled",
+			zap.String("property", PreemptionDisabled),
+			zap.String("value", disabled))
+		return false
+	}
+}
+
+// Get the derived guarantee flag from the queue properties: true if the guarantee is the sum of the children.
+// An invalid value is logged and ignored, the queue will use its configured guarantee.
+func parseGuaranteedDerived(props map[string]string) bool {
+	switch derived := strings.ToLower(strings.TrimSpace(props[QueueGuaranteedDerived])); derived {
+	case "true":
+		return true
+	case "", "false":
+		return false
+	default:
+		log.Logger().Warn("invalid guaranteed derived value, using the configured guarantee",
+			zap.String("property", QueueGuaranteedDerived),
+			zap.String("value", derived))
+		return false
+	}
+}
+
+// Get the tolerance for the max resource from the queue properties, the percent sign is optional.
+// The tolerance is only used with soft enforcement, 0 means the max is enforced hard. An invalid mode or tolerance is
+// logged and ignored, the max will be enforced hard.
+func parseMaxTolerance(props map[string]string) int64 {
+	switch mode := strings.ToLower(strings.TrimSpace(props[QueueMaxEnforcement])); mode {
+	case MaxEnforcementSoft:
+	case "", MaxEnforcementHard:
+		return 0
+	default:
+		log.Logger().Warn("invalid max enforcement mode, using hard enforcement",
+			zap.String("property", QueueMaxEnforcement),
+			zap.String("value", mode))
+		return 0
+	}
+	value, ok := props[QueueMaxTolerance]
+	if !ok {
+		return defaultMaxTolerance
+	}
+	tolerance, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), "%"), 10, 64)
+	if err != nil || tolerance < 0 {
+		log.Logger().Warn("invalid max tolerance, using hard enforcement",
+			zap.String("property", QueueMaxTolerance),
+			zap.String("value", value))
+		return 0
+	}
+	return tolerance
+}
+
+// Get the maximum resource of an application group from the queue properties.
+// An invalid value is logged and ignored, the queue will not limit application groups.
+func parseGroupMax(props map[string]string) *resources.Resource {
+	value, ok := props[ApplicationGroupMax]
+	if !ok {
+		return nil
+	}
+	groupMax, err := resources.ParseResource(value)
+	if err != nil || len(groupMax.Resources) == 0 {
+		log.Logger().Warn("invalid application group maximum, ignoring property",
+			zap.String("property", ApplicationGroupMax),
+			zap.String("value", value))
+		return nil
+	}
+	return groupMax
+}
+
+// Get the default resource of the asks from the queue properties.
+// An invalid value is logged and ignored, the asks of the queue will not get defaults.
+func parseAskDefault(props map[string]string) *resources.Resource {
+	value, ok := props[AskDefaultResource]
+	if !ok {
+		return nil
+	}
+	askDefault, err := resources.ParseResource(value)
+	if err != nil || !resources.StrictlyGreaterThanZero(askDefault) {
+		log.Logger().Warn("invalid ask default resource, ignoring property",
+			zap.String("property", AskDefaultResource),
+			zap.String("value", value))
+		return nil
+	}
+	return askDefault
+}
+
+// Get the maximum allocated resource of one user from the queue properties: a resource, or a percentage of the max
+// resource of the queue if the value ends with a percent sign. An invalid value, or a percentage that is not between
+// 1 and 100, is logged and ignored, the queue will not limit users.
+func parseUserMax(props map[string]string) (*resources.Resource, int64) {
+	value, ok := props[QueueUserMax]
+	if !ok {
+		return nil, 0
+	}
+	value = strings.TrimSpace(value)
+	if strings.HasSuffix(value, "%") {
+		share, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 64)
+		if err == nil && share > 0 && share <= 100 {
+			return nil, share
+		}
+	} else if userMax, err := resources.ParseResource(value); err == nil && len(userMax.Resources) != 0 {
+		return userMax, 0
+	}
+	log.Logger().Warn("invalid user maximum, ignoring property",
+		zap.String("property", QueueUserMax),
+		zap.String("value", value))
+	return nil, 0
+}
+
+// Get the node sorting resource weights from the queue properties.
+// An invalid value is logged and ignored, the queue will use the weights of the partition.
+func parseNodeSortWeights(props map[string]string) map[string]float64 {
+	value, ok := props[NodeSortResourceWeights]
+	if !ok {
+		return nil
+	}
+	weights, err := common.ParseResourceWeights(value)
+	if err != nil {
+		log.Logger().Warn("invalid node sort resource weights, ignoring property",
+			zap.String("property", NodeSortResourceWeights),
+			zap.String("value", value),
+			zap.Error(err))
+		return nil
+	}
+	return weights
+}
+
+// Get the anti affinity queues and mode from the queue properties.
+// Invalid queue names and an invalid mode are logged and ignored, the queue will use soft anti affinity.
+func parseAntiAffinity(props map[string]string) ([]string, bool) {
+	value, ok := props[QueueAntiAffinity]
+	if !ok {
+		return nil, false
+	}
+	var queues []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name != configs.RootQueue && !strings.HasPrefix(name, configs.RootQueue+DOT) {
+			name = configs.RootQueue + DOT + name
+		}
+		if !isValidQueuePath(name) {
+			log.Logger().Warn("invalid anti affinity queue, ignoring queue",
+				zap.String("property", QueueAntiAffinity),
+				zap.String("queue", name))
+			continue
+		}
+		queues = append(queues, name)
+	}
+	if len(queues) == 0 {
+		return nil, false
+	}
+	hard := false
+	switch mode := strings.ToLower(strings.TrimSpace(props[QueueAntiAffinityMode])); mode {
+	case AntiAffinityHard:
+		hard = true
+	case "", AntiAffinitySoft:
+	default:
+		log.Logger().Warn("invalid anti affinity mode, using soft anti affinity",
+			zap.String("property", QueueAntiAffinityMode),
+			zap.String("value", mode))
+	}
+	return queues, hard
+}
+
+// Check that each part of the queue path is a valid queue name.
+func isValidQueuePath(path string) bool {
+	for _, name := range strings.Split(path, DOT) {
+		if !configs.QueueNameRegExp.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// Get the start delay from the queue properties.
+// An invalid or negative value is logged and ignored, the queue will not have a start delay.
+func parseStartDelay(props map[string]string) time.Duration {
+	value, ok := props[QueueStartDelay]
+	if !ok {
+		return 0
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil || delay < 0 {
+		log.Logger().Warn("invalid queue start delay, ignoring property",
+			zap.String("property", QueueStartDelay),
+			zap.String("value", value))
+		return 0
+	}
+	return delay
+}
+
+// Get the application sort aging period from the queue properties.
+// An invalid or negative value is logged and ignored, the applications in the queue do not age.
+func parseAppSortAging(props map[string]string) time.Duration {
+	value, ok := props[ApplicationSortAging]
+	if !ok {
+		return 0
+	}
+	aging, err := time.ParseDuration(value)
+	if err != nil || aging < 0 {
+		log.Logger().Warn("invalid application sort aging, ignoring property",
+			zap.String("property", ApplicationSortAging),
+			zap.String("value", value))
+		return 0
+	}
+	return aging
+}
+
+// Get the allocation reuse TTL from the queue properties.
+// An invalid or negative value is logged and ignored, the queue will not record reuse hints.
+func parseReuseTTL(props map[string]string) time.Duration {
+	value, ok := props[AllocationReuseTTL]
+	if !ok {
+		return 0
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		log.Logger().Warn("invalid allocation reuse ttl, ignoring property",
+			zap.String("property", AllocationReuseTTL),
+			zap.String("value", value))
+		return 0
+	}
+	return ttl
+}
+
+// Get the priority floor and ceiling from the queue properties.
+// An invalid value is logged and ignored, a floor above the ceiling ignores both. Returns nil if the queue does not
+// limit the priority.
+func parsePriorityRange(props map[string]string) *priorityRange {
+	limits := &priorityRange{floor: math.MinInt32, ceiling: math.MaxInt32}
+	set := false
+	for _, property := range []string{ApplicationPriorityFloor, ApplicationPriorityCeiling} {
+		value, ok := props[property]
+		if !ok {
+			continue
+		}
+		priority, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			log.Logger().Warn("invalid queue priority limit, ignoring property",
+				zap.String("property", property),
+				zap.String("value", value))
+			continue
+		}
+		if property == ApplicationPriorityFloor {
+			limits.floor = int32(priority)
+		} else {
+			limits.ceiling = int32(priority)
+		}
+		set = true
+	}
+	if !set {
+		return nil
+	}
+	if limits.floor > limits.ceiling {
+		log.Logger().Warn("queue priority floor is above the ceiling, ignoring properties",
+			zap.Int32("floor", limits.floor),
+			zap.Int32("ceiling", limits.ceiling))
+		return nil
+	}
+	return limits
+}
+
 // Merge the properties for the queue. This is only called when updating the queue from the configuration.
 func mergeProperties(parent map[string]string, child map[string]string) map[string]string {
 	merged := make(map[string]string)
@@ -450,6 +1607,53 @@ func (qi *QueueInfo) CurrentState() string {
 	return qi.stateMachine.Current()
 }
 
+// Is the queue active but still within the configured start delay.
+// Applications can be added to a queue in the start delay but no allocations are made in the queue or its children.
+func (qi *QueueInfo) IsStartDelayed() bool {
+	if !qi.IsRunning() {
+		return false
+	}
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.startDelay > 0 && time.Since(qi.stateTime) < qi.startDelay
+}
+
+// Return the period an application with pending asks waits without an allocation before it moves up one step in the
+// order of the queue. Zero means the applications do not age.
+func (qi *QueueInfo) GetApplicationSortAging() time.Duration {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.appSortAging
+}
+
+// Return the time the node of a released allocation is preferred for the applications in the queue.
+// Zero means no reuse hints are recorded.
+func (qi *QueueInfo) getReuseTTL() time.Duration {
+	qi.RLock()
+	defer qi.RUnlock()
+	return qi.reuseTTL
+}
+
+// Clamp the priority of an ask to the floor and ceiling of the queue.
+// The range of the parent queues is applied after the range of the queue: a queue cannot widen the range of its parent.
+func (qi *QueueInfo) ClampPriority(priority int32) int32 {
+	qi.RLock()
+	limits := qi.priorityRange
+	qi.RUnlock()
+	if limits != nil {
+		if priority < limits.floor {
+			priority = limits.floor
+		}
+		if priority > limits.ceiling {
+			priority = limits.ceiling
+		}
+	}
+	if qi.Parent != nil {
+		return qi.Parent.ClampPriority(priority)
+	}
+	return priority
+}
+
 // Check if the user has access to the queue to submit an application recursively.
 // This will check the submit ACL and the admin ACL.
 func (qi *QueueInfo) CheckSubmitAccess(user security.UserGroup) bool {
@@ -19,11 +19,18 @@
 package cache
 
 import (
+	"math"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 )
 
 // create the root queue, base for all testing
@@ -369,3 +376,610 @@ func TestMaxResource(t *testing.T) {
 		t.Errorf("root max setting not picked up by parent queue expected %v, got %v", res, parent.GetMaxResource())
 	}
 }
+
+func TestStartDelay(t *testing.T) {
+	root, err := createRootQueue()
+	if err != nil {
+		t.Fatalf("failed to create basic root queue: %v", err)
+	}
+	assert.Assert(t, !root.IsStartDelayed(), "queue without start delay should not be delayed")
+
+	conf := configs.QueueConfig{
+		Name:       "delayed",
+		Properties: map[string]string{QueueStartDelay: "1h"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	if err != nil {
+		t.Fatalf("failed to create leaf queue: %v", err)
+	}
+	assert.Equal(t, leaf.startDelay, time.Hour, "start delay not parsed")
+	assert.Assert(t, leaf.IsStartDelayed(), "new queue should be within start delay")
+	// move the state time back to simulate the passing of the delay
+	leaf.stateTime = time.Now().Add(-2 * time.Hour)
+	assert.Assert(t, !leaf.IsStartDelayed(), "queue should be out of start delay")
+	// a queue that is not running is never delayed
+	leaf.stateTime = time.Now()
+	leaf.MarkQueueForRemoval()
+	assert.Assert(t, leaf.IsDraining(), "queue should be draining")
+	assert.Assert(t, !leaf.IsStartDelayed(), "draining queue should not be delayed")
+
+	// invalid values are ignored
+	for _, value := range []string{"abc", "-1s", "10"} {
+		conf.Properties[QueueStartDelay] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid start delay should not fail the update")
+		assert.Equal(t, leaf.startDelay, time.Duration(0), "invalid start delay %s should have been ignored", value)
+	}
+}
+
+func TestApplicationSortAging(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	assert.Equal(t, root.GetApplicationSortAging(), time.Duration(0), "queue without property should not age")
+	conf := configs.QueueConfig{
+		Name:       "aging",
+		Properties: map[string]string{ApplicationSortAging: "5m"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Equal(t, leaf.GetApplicationSortAging(), 5*time.Minute, "aging not parsed")
+
+	// invalid values are ignored
+	for _, value := range []string{"abc", "-1s", "10"} {
+		conf.Properties[ApplicationSortAging] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid aging should not fail the update")
+		assert.Equal(t, leaf.GetApplicationSortAging(), time.Duration(0), "invalid aging %s should have been ignored", value)
+	}
+}
+
+func TestReuseTTL(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	assert.Equal(t, root.getReuseTTL(), time.Duration(0), "queue without property should not have a reuse ttl")
+	conf := configs.QueueConfig{
+		Name:       "reuse",
+		Properties: map[string]string{AllocationReuseTTL: "30s"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Equal(t, leaf.getReuseTTL(), 30*time.Second, "reuse ttl not parsed")
+
+	// invalid values are ignored
+	for _, value := range []string{"abc", "-1s", "10"} {
+		conf.Properties[AllocationReuseTTL] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid reuse ttl should not fail the update")
+		assert.Equal(t, leaf.getReuseTTL(), time.Duration(0), "invalid reuse ttl %s should have been ignored", value)
+	}
+}
+
+func TestPriorityRange(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	assert.Equal(t, root.ClampPriority(math.MaxInt32), int32(math.MaxInt32), "queue without range should not clamp")
+	conf := configs.QueueConfig{
+		Name:       "parent",
+		Parent:     true,
+		Properties: map[string]string{ApplicationPriorityCeiling: "100"},
+	}
+	var parent, leaf *QueueInfo
+	parent, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create parent queue")
+	assert.Equal(t, parent.ClampPriority(math.MinInt32), int32(math.MinInt32), "ceiling only should not set a floor")
+	assert.Equal(t, parent.ClampPriority(200), int32(100), "priority not clamped to ceiling")
+
+	// the leaf cannot raise the ceiling of the parent
+	conf = configs.QueueConfig{
+		Name:       "leaf",
+		Properties: map[string]string{ApplicationPriorityFloor: " 10 ", ApplicationPriorityCeiling: "500"},
+	}
+	leaf, err = NewManagedQueue(conf, parent)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Equal(t, leaf.ClampPriority(0), int32(10), "priority not clamped to floor")
+	assert.Equal(t, leaf.ClampPriority(50), int32(50), "priority in range should not change")
+	assert.Equal(t, leaf.ClampPriority(300), int32(100), "priority not clamped to parent ceiling")
+
+	// invalid values are ignored
+	for _, props := range []map[string]string{
+		{ApplicationPriorityFloor: "abc"},
+		{ApplicationPriorityFloor: "1.5"},
+		{ApplicationPriorityFloor: "3000000000"},
+		{ApplicationPriorityFloor: "20", ApplicationPriorityCeiling: "10"},
+	} {
+		assert.Assert(t, parsePriorityRange(props) == nil, "invalid range %v should have been ignored", props)
+	}
+	limits := parsePriorityRange(map[string]string{ApplicationPriorityFloor: "abc", ApplicationPriorityCeiling: "10"})
+	assert.Assert(t, limits != nil && limits.floor == math.MinInt32 && limits.ceiling == 10, "valid ceiling should have been used")
+}
+
+func TestBorrowLimit(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name: "borrow",
+		Resources: configs.Resources{
+			Guaranteed: map[string]string{"first": "10", "second": "5"},
+		},
+		Properties: map[string]string{QueueBorrowLimit: "50%"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 15, "second": 7})
+	assert.Assert(t, resources.Equals(leaf.GetBorrowMaxResource(), expected), "unexpected borrow max: %v", leaf.GetBorrowMaxResource())
+	assert.Assert(t, root.GetBorrowMaxResource() == nil, "root should not have a borrow max")
+
+	// the percent sign is optional, 0 means no borrowing
+	conf.Properties[QueueBorrowLimit] = "0"
+	err = leaf.updateQueueProps(conf)
+	assert.NilError(t, err, "queue update should not fail")
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 5})
+	assert.Assert(t, resources.Equals(leaf.GetBorrowMaxResource(), expected), "unexpected borrow max: %v", leaf.GetBorrowMaxResource())
+
+	// invalid values are ignored
+	for _, value := range []string{"abc", "-10%", "10.5%"} {
+		conf.Properties[QueueBorrowLimit] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid borrow limit should not fail the update")
+		assert.Assert(t, leaf.GetBorrowMaxResource() == nil, "invalid borrow limit %s should have been ignored", value)
+	}
+
+	// no guarantee means no borrow limit
+	conf = configs.QueueConfig{
+		Name:       "noguarantee",
+		Properties: map[string]string{QueueBorrowLimit: "50%"},
+	}
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Assert(t, leaf.GetBorrowMaxResource() == nil, "queue without guarantee should not have a borrow max")
+}
+
+func TestApplicationGroupMax(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name:       "groups",
+		Properties: map[string]string{ApplicationGroupMax: "[first:10 second:5]"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 5})
+	assert.Assert(t, resources.Equals(leaf.GetApplicationGroupMax(), expected), "unexpected group max: %v", leaf.GetApplicationGroupMax())
+	assert.Assert(t, root.GetApplicationGroupMax() == nil, "root should not have a group max")
+
+	// invalid values are ignored
+	for _, value := range []string{"first:abc", "[first:10", ""} {
+		conf.Properties[ApplicationGroupMax] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid group max should not fail the update")
+		assert.Assert(t, leaf.GetApplicationGroupMax() == nil, "invalid group max '%s' should have been ignored", value)
+	}
+}
+
+func TestAskDefaultResource(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name:       "defaults",
+		Parent:     true,
+		Properties: map[string]string{AskDefaultResource: "[memory:512 vcore:500]"},
+	}
+	var parent, leaf *QueueInfo
+	parent, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = NewManagedQueue(configs.QueueConfig{Name: "leaf"}, parent)
+	assert.NilError(t, err, "failed to create leaf queue")
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 512, "vcore": 500})
+	assert.Assert(t, resources.Equals(leaf.GetAskDefaultResource(), expected), "default should be inherited: %v", leaf.GetAskDefaultResource())
+	assert.Assert(t, root.GetAskDefaultResource() == nil, "root should not have a default")
+
+	// invalid values are ignored
+	for _, value := range []string{"[memory:-1]", "[memory:0]", "[memory:10"} {
+		conf.Properties[AskDefaultResource] = value
+		err = parent.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid default should not fail the update")
+		assert.Assert(t, parent.GetAskDefaultResource() == nil, "invalid default '%s' should have been ignored", value)
+	}
+}
+
+func TestUserMaxProperty(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name:       "users",
+		Properties: map[string]string{QueueUserMax: "[first:10 second:5]"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 5})
+	assert.Assert(t, resources.Equals(leaf.GetUserMaxResource(), expected), "unexpected user max: %v", leaf.GetUserMaxResource())
+	assert.Assert(t, root.GetUserMaxResource() == nil, "root should not have a user max")
+
+	// percentage of the queue max, nothing without a max
+	conf.Properties[QueueUserMax] = "25%"
+	err = leaf.updateQueueProps(conf)
+	assert.NilError(t, err, "user max update failed")
+	assert.Assert(t, leaf.GetUserMaxResource() == nil, "percentage without a queue max should not limit users")
+	conf.Resources = configs.Resources{Max: map[string]string{"first": "10", "second": "4"}}
+	err = leaf.updateQueueProps(conf)
+	assert.NilError(t, err, "user max update failed")
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2, "second": 1})
+	assert.Assert(t, resources.Equals(leaf.GetUserMaxResource(), expected), "unexpected user max: %v", leaf.GetUserMaxResource())
+
+	// invalid values are ignored
+	for _, value := range []string{"first:abc", "0%", "101%", ""} {
+		conf.Properties[QueueUserMax] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid user max should not fail the update")
+		assert.Assert(t, leaf.GetUserMaxResource() == nil, "invalid user max '%s' should have been ignored", value)
+	}
+}
+
+func TestNodeSortWeightsProperty(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name:       "gpu",
+		Parent:     true,
+		Properties: map[string]string{NodeSortResourceWeights: "gpu:10 vcore:1"},
+	}
+	var parent, leaf *QueueInfo
+	parent, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = NewManagedQueue(configs.QueueConfig{Name: "leaf"}, parent)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.DeepEqual(t, leaf.GetNodeSortWeights(), map[string]float64{"gpu": 10, "vcore": 1})
+	assert.Assert(t, root.GetNodeSortWeights() == nil, "root should not have weights")
+
+	// invalid values are ignored
+	for _, value := range []string{"gpu", "gpu:-1", "gpu:0"} {
+		conf.Properties[NodeSortResourceWeights] = value
+		err = parent.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid weights should not fail the update")
+		assert.Assert(t, parent.GetNodeSortWeights() == nil, "invalid weights '%s' should have been ignored", value)
+	}
+}
+
+func TestAntiAffinityProperty(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name:       "noisy",
+		Parent:     true,
+		Properties: map[string]string{QueueAntiAffinity: "root.Batch, etl.daily,,bad queue"},
+	}
+	var parent, leaf *QueueInfo
+	parent, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = NewManagedQueue(configs.QueueConfig{Name: "leaf"}, parent)
+	assert.NilError(t, err, "failed to create leaf queue")
+	queues, hard := leaf.GetAntiAffinity()
+	assert.DeepEqual(t, queues, []string{"root.batch", "root.etl.daily"})
+	assert.Assert(t, !hard, "anti affinity should be soft by default")
+	queues, _ = root.GetAntiAffinity()
+	assert.Assert(t, queues == nil, "root should not have anti affinity")
+
+	// mode is only used with queues, an invalid mode is soft
+	conf.Properties[QueueAntiAffinityMode] = "Hard"
+	err = parent.updateQueueProps(conf)
+	assert.NilError(t, err, "mode update should not fail")
+	_, hard = parent.GetAntiAffinity()
+	assert.Assert(t, hard, "anti affinity should be hard")
+	conf.Properties[QueueAntiAffinityMode] = "never"
+	err = parent.updateQueueProps(conf)
+	assert.NilError(t, err, "invalid mode should not fail the update")
+	_, hard = parent.GetAntiAffinity()
+	assert.Assert(t, !hard, "invalid mode should be soft")
+	conf.Properties[QueueAntiAffinity] = "bad queue"
+	conf.Properties[QueueAntiAffinityMode] = AntiAffinityHard
+	err = parent.updateQueueProps(conf)
+	assert.NilError(t, err, "invalid queues should not fail the update")
+	queues, hard = parent.GetAntiAffinity()
+	assert.Assert(t, queues == nil && !hard, "invalid queues should have been ignored")
+}
+
+func TestSoftMaxResource(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name: "softmax",
+		Resources: configs.Resources{
+			Max:     map[string]string{"first": "10"},
+			SoftMax: map[string]string{"first": "5"},
+		},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	softMax := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	assert.Assert(t, resources.Equals(leaf.GetSoftMaxResource(), softMax), "soft max not set from config")
+	assert.Assert(t, root.GetSoftMaxResource() == nil, "root should not have a soft max")
+	metric := "yunikorn_queue_root_softmax_allocations_over_soft_max"
+
+	// up to the soft max nothing is flagged
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	err = leaf.IncAllocatedResource(res, false)
+	assert.NilError(t, err, "allocation up to the soft max should not fail")
+	assert.Assert(t, !leaf.IsOverSoftMax(), "queue at the soft max should not be over")
+	assert.Equal(t, getCounterValue(t, metric), float64(0), "allocation within soft max should not be counted")
+
+	// beyond the soft max is allowed and counted
+	res = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2})
+	err = leaf.IncAllocatedResource(res, false)
+	assert.NilError(t, err, "allocation over the soft max should not fail")
+	err = leaf.IncAllocatedResource(res, false)
+	assert.NilError(t, err, "allocation over the soft max should not fail")
+	assert.Assert(t, leaf.IsOverSoftMax(), "queue should be over the soft max")
+	assert.Equal(t, getCounterValue(t, metric), float64(2), "allocations over soft max should be counted")
+
+	// the hard max still applies
+	err = leaf.IncAllocatedResource(res, false)
+	if err == nil {
+		t.Error("allocation over the max should have failed")
+	}
+	assert.Assert(t, api.IsError(err, api.ErrOverQueueMax), "allocation over the max should fail with over max error: %v", err)
+	assert.Equal(t, getCounterValue(t, metric), float64(2), "failed allocation should not be counted")
+
+	// releasing brings the queue back under the soft max, removing the soft max from the config clears it
+	err = leaf.decAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 4}))
+	assert.NilError(t, err, "release should not fail")
+	assert.Assert(t, !leaf.IsOverSoftMax(), "queue should be back under the soft max")
+	conf.Resources.SoftMax = nil
+	err = leaf.updateQueueProps(conf)
+	assert.NilError(t, err, "queue update should not fail")
+	assert.Assert(t, leaf.GetSoftMaxResource() == nil, "soft max should be removed")
+}
+
+func TestSoftMaxEnforcement(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name: "enforce",
+		Resources: configs.Resources{
+			Max: map[string]string{"first": "10"},
+		},
+		Properties: map[string]string{QueueMaxEnforcement: MaxEnforcementSoft},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	// the default tolerance is used without a tolerance property
+	enforced := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 11})
+	assert.Assert(t, resources.Equals(leaf.GetEnforcedMaxResource(), enforced), "unexpected enforced max: %v", leaf.GetEnforcedMaxResource())
+	conf.Properties[QueueMaxTolerance] = "20%"
+	err = leaf.updateQueueProps(conf)
+	assert.NilError(t, err, "queue update should not fail")
+	enforced = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 12})
+	assert.Assert(t, resources.Equals(leaf.GetEnforcedMaxResource(), enforced), "unexpected enforced max: %v", leaf.GetEnforcedMaxResource())
+	metric := "yunikorn_queue_root_enforce_allocations_over_max"
+
+	// beyond the max is allowed up to the tolerance and counted
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	err = leaf.IncAllocatedResource(res, false)
+	assert.NilError(t, err, "allocation up to the max should not fail")
+	assert.Assert(t, !leaf.IsOverMax(), "queue at the max should not be over")
+	res = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2})
+	err = leaf.IncAllocatedResource(res, false)
+	assert.NilError(t, err, "allocation within the tolerance should not fail")
+	assert.Assert(t, leaf.IsOverMax(), "queue should be over the max")
+	assert.Equal(t, getCounterValue(t, metric), float64(1), "allocation over max should be counted")
+	err = leaf.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1}), false)
+	assert.Assert(t, api.IsError(err, api.ErrOverQueueMax), "allocation over the tolerance should fail with over max error: %v", err)
+	assert.Equal(t, getCounterValue(t, metric), float64(1), "failed allocation should not be counted")
+
+	// an invalid mode or tolerance and the hard mode enforce the max
+	for _, props := range []map[string]string{
+		{QueueMaxEnforcement: MaxEnforcementHard, QueueMaxTolerance: "20"},
+		{QueueMaxEnforcement: "never"},
+		{QueueMaxEnforcement: MaxEnforcementSoft, QueueMaxTolerance: "-5%"},
+		{},
+	} {
+		conf.Properties = props
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "queue update should not fail")
+		assert.Assert(t, resources.Equals(leaf.GetEnforcedMaxResource(), leaf.GetMaxResource()), "max should be enforced hard for %v", props)
+	}
+}
+
+// Get the value of the registered counter metric, 0 if not found.
+func getCounterValue(t *testing.T, name string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NilError(t, err, "failed to gather metrics")
+	for _, family := range families {
+		if family.GetName() == name && len(family.GetMetric()) == 1 {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestChildTemplate(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name:       "users",
+		Parent:     true,
+		Properties: map[string]string{QueueStartDelay: "10s"},
+		ChildTemplate: configs.ChildTemplate{
+			Resources: configs.Resources{
+				Guaranteed: map[string]string{"first": "5"},
+				Max:        map[string]string{"first": "10"},
+			},
+			MaxApplications: 2,
+			Properties:      map[string]string{ApplicationSortPolicy: "fifo"},
+			SubmitACL:       "user1",
+		},
+	}
+	var parent, leaf *QueueInfo
+	parent, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create parent queue")
+	assert.DeepEqual(t, parent.GetEffectiveConfig().ChildTemplate, conf.ChildTemplate)
+
+	// leaf created below the parent gets the template
+	leaf, err = createUnManagedQueue(parent, "user1", false)
+	assert.NilError(t, err, "failed to create unmanaged leaf queue")
+	maxRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	assert.Assert(t, resources.Equals(leaf.GetMaxResource(), maxRes), "max not set from template: %v", leaf.GetMaxResource())
+	guaranteed := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	assert.Assert(t, resources.Equals(leaf.GetGuaranteedResource(), guaranteed), "guaranteed not set from template")
+	assert.Equal(t, leaf.GetEffectiveConfig().MaxApplications, uint64(2), "max applications not set from template")
+	assert.Equal(t, leaf.Properties[ApplicationSortPolicy], "fifo", "template property not set")
+	assert.Equal(t, leaf.Properties[QueueStartDelay], "10s", "parent property not merged")
+	assert.Assert(t, leaf.GetEffectiveConfig().ChildTemplate.IsEmpty(), "leaf should not have a template")
+	assert.Equal(t, leaf.GetEffectiveConfig().SubmitACL, "user1", "submit ACL not set from template")
+	assert.Assert(t, leaf.CheckSubmitAccess(security.UserGroup{User: "user1"}), "template ACL should allow user1")
+	assert.Assert(t, !leaf.CheckSubmitAccess(security.UserGroup{User: "user2"}), "template ACL should not allow user2")
+
+	// a parent created below the queue does not get the template, nor do its children
+	var sub *QueueInfo
+	sub, err = createUnManagedQueue(parent, "sub", true)
+	assert.NilError(t, err, "failed to create unmanaged parent queue")
+	assert.Assert(t, sub.GetMaxResource() == nil, "unmanaged parent should not get the template")
+	leaf, err = createUnManagedQueue(sub, "user2", false)
+	assert.NilError(t, err, "failed to create unmanaged leaf queue")
+	assert.Assert(t, leaf.GetMaxResource() == nil, "template should only apply to direct children")
+
+	// removing the template from the config
+	conf.ChildTemplate = configs.ChildTemplate{}
+	err = parent.updateQueueProps(conf)
+	assert.NilError(t, err, "failed to update parent queue")
+	leaf, err = createUnManagedQueue(parent, "user3", false)
+	assert.NilError(t, err, "failed to create unmanaged leaf queue")
+	assert.Assert(t, leaf.GetMaxResource() == nil, "removed template should not be applied")
+}
+
+func TestPreemptionScopeProperty(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name:       "team",
+		Parent:     true,
+		Properties: map[string]string{PreemptionScope: " Queue "},
+	}
+	var parent, leaf *QueueInfo
+	parent, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = NewManagedQueue(configs.QueueConfig{Name: "leaf"}, parent)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Assert(t, parent.IsPreemptionScoped(), "parent should be scoped")
+	assert.Assert(t, leaf.IsPreemptionScoped(), "leaf should inherit the scope")
+	assert.Assert(t, !root.IsPreemptionScoped(), "root should not be scoped")
+
+	// an invalid scope falls back to the partition scope
+	conf.Properties[PreemptionScope] = "subtree"
+	err = parent.updateQueueProps(conf)
+	assert.NilError(t, err, "invalid scope should not fail the update")
+	assert.Assert(t, !parent.IsPreemptionScoped(), "invalid scope should use the partition scope")
+	conf.Properties[PreemptionScope] = PreemptionScopePartition
+	err = parent.updateQueueProps(conf)
+	assert.NilError(t, err, "scope update should not fail")
+	assert.Assert(t, !parent.IsPreemptionScoped(), "parent should not be scoped")
+}
+
+func TestPreemptionDisabledProperty(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	conf := configs.QueueConfig{
+		Name:       "team",
+		Parent:     true,
+		Properties: map[string]string{PreemptionDisabled: " True "},
+	}
+	var parent, leaf *QueueInfo
+	parent, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = NewManagedQueue(configs.QueueConfig{Name: "leaf"}, parent)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Assert(t, parent.IsPreemptionDisabled(), "parent should have preemption disabled")
+	assert.Assert(t, leaf.IsPreemptionDisabled(), "leaf should inherit the disabled preemption")
+	assert.Assert(t, !root.IsPreemptionDisabled(), "root should not have preemption disabled")
+
+	// an invalid value leaves preemption enabled
+	conf.Properties[PreemptionDisabled] = "yes"
+	err = parent.updateQueueProps(conf)
+	assert.NilError(t, err, "invalid value should not fail the update")
+	assert.Assert(t, !parent.IsPreemptionDisabled(), "invalid value should leave preemption enabled")
+}
+
+func TestCycleAllocationCapProperty(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	assert.Equal(t, root.GetCycleAllocationCap(100), 0, "root should not be capped")
+	conf := configs.QueueConfig{
+		Name:       "leaf",
+		Properties: map[string]string{QueueCycleAllocations: "5"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Equal(t, leaf.GetCycleAllocationCap(100), 5, "absolute cap should not depend on the cycle")
+	assert.Equal(t, leaf.GetCycleAllocationCap(1), 5, "absolute cap should not depend on the cycle")
+
+	// share of the cycle: at least one allocation
+	conf.Properties[QueueCycleAllocations] = " 25% "
+	err = leaf.updateQueueProps(conf)
+	assert.NilError(t, err, "cap update should not fail")
+	assert.Equal(t, leaf.GetCycleAllocationCap(100), 25, "unexpected share based cap")
+	assert.Equal(t, leaf.GetCycleAllocationCap(2), 1, "share based cap should be at least one")
+
+	// invalid values are ignored
+	for _, value := range []string{"0", "-1", "150%", "many"} {
+		conf.Properties[QueueCycleAllocations] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid cap should not fail the update")
+		assert.Equal(t, leaf.GetCycleAllocationCap(100), 0, "invalid cap %s should be ignored", value)
+	}
+}
+
+func TestQueueWeightProperty(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	assert.Equal(t, root.GetWeight(), 1.0, "root should have the default weight")
+	conf := configs.QueueConfig{
+		Name:       "leaf",
+		Properties: map[string]string{QueueWeight: "2.5"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Equal(t, leaf.GetWeight(), 2.5, "unexpected weight")
+
+	// invalid values are ignored
+	for _, value := range []string{"0", "-1", "heavy", "NaN"} {
+		conf.Properties[QueueWeight] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid weight should not fail the update")
+		assert.Equal(t, leaf.GetWeight(), 1.0, "invalid weight %s should be ignored", value)
+	}
+}
+
+func TestQueuePriorityProperty(t *testing.T) {
+	root, err := createRootQueue()
+	assert.NilError(t, err, "failed to create basic root queue")
+	assert.Equal(t, root.GetPriority(), int32(0), "root should have the default priority")
+	conf := configs.QueueConfig{
+		Name:       "leaf",
+		Properties: map[string]string{QueuePriority: "10"},
+	}
+	var leaf *QueueInfo
+	leaf, err = NewManagedQueue(conf, root)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Equal(t, leaf.GetPriority(), int32(10), "unexpected priority")
+	conf.Properties[QueuePriority] = " -3 "
+	err = leaf.updateQueueProps(conf)
+	assert.NilError(t, err, "priority update should not fail")
+	assert.Equal(t, leaf.GetPriority(), int32(-3), "unexpected negative priority")
+
+	// invalid values are ignored
+	for _, value := range []string{"high", "1.5", "3000000000"} {
+		conf.Properties[QueuePriority] = value
+		err = leaf.updateQueueProps(conf)
+		assert.NilError(t, err, "invalid priority should not fail the update")
+		assert.Equal(t, leaf.GetPriority(), int32(0), "invalid priority %s should be ignored", value)
+	}
+}
@@ -0,0 +1,256 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/schedulerevent"
+)
+
+// The state of the active scheduler instance replicated to a standby instance.
+// The state is exchanged by the process that embeds the scheduler, the scheduler does not send or receive it.
+// A standby instance uses the last state received when the RM registers: the configuration of the active instance
+// is used, the applications are restored and the allocations the nodes report keep their UUID.
+type ReplicatedState struct {
+	RmID         string                  `json:"rmID"`
+	PolicyGroup  string                  `json:"policyGroup"`
+	Config       string                  `json:"config"` // the scheduler configuration as yaml
+	Applications []ReplicatedApplication `json:"applications"`
+	Allocations  []ReplicatedAllocation  `json:"allocations"`
+	Time         int64                   `json:"time"`
+}
+
+type ReplicatedApplication struct {
+	ApplicationID  string            `json:"applicationID"`
+	PartitionName  string            `json:"partition"`
+	QueueName      string            `json:"queueName"`
+	User           string            `json:"user"`
+	Groups         []string          `json:"groups"`
+	Tags           map[string]string `json:"tags"`
+	SubmissionTime int64             `json:"submissionTime"`
+}
+
+type ReplicatedAllocation struct {
+	UUID          string `json:"uuid"`
+	AllocationKey string `json:"allocationKey"`
+	ApplicationID string `json:"applicationID"`
+	PartitionName string `json:"partition"`
+	NodeID        string `json:"nodeID"`
+}
+
+// Return the state of this instance to replicate to a standby instance.
+// Returns an error if no RM is registered: a standby instance has no state to replicate.
+func (m *ClusterInfo) GetReplicatedState() (*ReplicatedState, error) {
+	m.RLock()
+	policyGroup := m.policyGroup
+	partitions := make([]*PartitionInfo, 0, len(m.partitions))
+	for _, partition := range m.partitions {
+		partitions = append(partitions, partition)
+	}
+	m.RUnlock()
+	conf := configs.ConfigContext.Get(policyGroup)
+	if len(partitions) == 0 || conf == nil {
+		return nil, fmt.Errorf("no RM registered, no state to replicate")
+	}
+	config, err := configs.ExportSchedulerConfig(conf.Partitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export the scheduler configuration: %v", err)
+	}
+	state := &ReplicatedState{
+		RmID:         partitions[0].RmID,
+		PolicyGroup:  policyGroup,
+		Config:       string(config),
+		Applications: make([]ReplicatedApplication, 0),
+		Allocations:  make([]ReplicatedAllocation, 0),
+		Time:         time.Now().UnixNano(),
+	}
+	for _, partition := range partitions {
+		for _, app := range partition.GetApplications() {
+			user := app.GetUser()
+			state.Applications = append(state.Applications, ReplicatedApplication{
+				ApplicationID:  app.ApplicationID,
+				PartitionName:  app.Partition,
+				QueueName:      app.QueueName,
+				User:           user.User,
+				Groups:         user.Groups,
+				Tags:           app.tags,
+				SubmissionTime: app.SubmissionTime,
+			})
+			for _, alloc := range app.GetAllAllocations() {
+				state.Allocations = append(state.Allocations, ReplicatedAllocation{
+					UUID:          alloc.AllocationProto.UUID,
+					AllocationKey: alloc.AllocationProto.AllocationKey,
+					ApplicationID: app.ApplicationID,
+					PartitionName: app.Partition,
+					NodeID:        alloc.AllocationProto.NodeID,
+				})
+			}
+		}
+	}
+	// stable output for the same state
+	sort.Slice(state.Applications, func(i, j int) bool {
+		return state.Applications[i].ApplicationID < state.Applications[j].ApplicationID
+	})
+	sort.Slice(state.Allocations, func(i, j int) bool {
+		return state.Allocations[i].UUID < state.Allocations[j].UUID
+	})
+	return state, nil
+}
+
+// Store the state replicated from the active instance, replacing the state received before.
+// Only a standby instance accepts the state: an error is returned if an RM is registered with this instance or the
+// configuration in the state is not valid.
+func (m *ClusterInfo) SetReplicatedState(state *ReplicatedState) error {
+	if state == nil || state.RmID == "" || state.PolicyGroup == "" {
+		return fmt.Errorf("replicated state must have the RM ID and policy group set")
+	}
+	if _, err := configs.LoadSchedulerConfigFromByteArray([]byte(state.Config)); err != nil {
+		return fmt.Errorf("replicated state has an invalid configuration: %v", err)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if len(m.partitions) != 0 {
+		return api.NewError(api.ErrInvalidState, "RM %s is registered, replicated state is only accepted by a standby instance", m.getRmID())
+	}
+	m.replicatedState = state
+	log.Logger().Info("replicated state received",
+		zap.String("rmID", state.RmID),
+		zap.Int("applications", len(state.Applications)),
+		zap.Int("allocations", len(state.Allocations)))
+	return nil
+}
+
+// Return the replicated state for the RM registering with this instance and remove it from the instance.
+// The state is only used once: it returns nil if no state was replicated or the state is for a different RM or
+// policy group.
+func (m *ClusterInfo) takeReplicatedState(rmID, policyGroup string) *ReplicatedState {
+	m.Lock()
+	defer m.Unlock()
+	state := m.replicatedState
+	m.replicatedState = nil
+	if state == nil {
+		return nil
+	}
+	if state.RmID != rmID || state.PolicyGroup != policyGroup {
+		log.Logger().Warn("replicated state does not match the registering RM, ignoring state",
+			zap.String("rmID", rmID),
+			zap.String("policyGroup", policyGroup),
+			zap.String("replicatedRmID", state.RmID),
+			zap.String("replicatedPolicyGroup", state.PolicyGroup))
+		return nil
+	}
+	return state
+}
+
+// Restore the replicated applications and allocation UUIDs in the partitions after the RM registered.
+// The applications are added to the scheduler like new applications, the RM will be notified of their acceptance.
+// Lock free call, all updates occur in the partitions which are locked.
+func (m *ClusterInfo) restoreReplicatedState(state *ReplicatedState) {
+	restored := make([]interface{}, 0)
+	for _, replicated := range state.Applications {
+		partition := m.GetPartition(replicated.PartitionName)
+		if partition == nil {
+			log.Logger().Warn("partition of replicated application not found, application not restored",
+				zap.String("appID", replicated.ApplicationID),
+				zap.String("partitionName", replicated.PartitionName))
+			continue
+		}
+		ugi := security.UserGroup{User: replicated.User, Groups: replicated.Groups}
+		app := NewApplicationInfo(replicated.ApplicationID, replicated.PartitionName, replicated.QueueName, ugi, replicated.Tags)
+		app.SubmissionTime = replicated.SubmissionTime
+		app.restored = true
+		if err := partition.addNewApplication(app, true); err != nil {
+			log.Logger().Warn("failed to restore replicated application",
+				zap.String("appID", replicated.ApplicationID),
+				zap.Error(err))
+			continue
+		}
+		restored = append(restored, app)
+	}
+	for _, alloc := range state.Allocations {
+		if partition := m.GetPartition(alloc.PartitionName); partition != nil {
+			partition.addReplicatedUUID(alloc)
+		}
+	}
+	log.Logger().Info("replicated state restored",
+		zap.String("rmID", state.RmID),
+		zap.Int("applications", len(restored)),
+		zap.Int("allocations", len(state.Allocations)))
+	if len(restored) > 0 {
+		m.EventHandlers.SchedulerEventHandler.HandleEvent(
+			&schedulerevent.SchedulerApplicationsUpdateEvent{
+				AddedApplications: restored,
+			})
+	}
+}
+
+// Return the RM ID of the registered RM.
+// Lock free call, must be called holding the cluster lock.
+func (m *ClusterInfo) getRmID() string {
+	for _, partition := range m.partitions {
+		return partition.RmID
+	}
+	return ""
+}
+
+// Key to match an allocation reported by a node to a replicated allocation.
+func replicatedAllocationKey(appID, allocKey, nodeID string) string {
+	return appID + "|" + allocKey + "|" + nodeID
+}
+
+// Record the UUID of a replicated allocation to be used when the node reports the allocation.
+func (pi *PartitionInfo) addReplicatedUUID(alloc ReplicatedAllocation) {
+	pi.Lock()
+	defer pi.Unlock()
+	if pi.replicatedUUIDs == nil {
+		pi.replicatedUUIDs = make(map[string][]string)
+	}
+	key := replicatedAllocationKey(alloc.ApplicationID, alloc.AllocationKey, alloc.NodeID)
+	pi.replicatedUUIDs[key] = append(pi.replicatedUUIDs[key], alloc.UUID)
+}
+
+// Return the UUID of a replicated allocation for an allocation reported by the node, each UUID is returned once.
+// Returns an empty string if there is no replicated allocation left or the UUID is in use.
+// Lock free call, must be called holding the partition lock.
+func (pi *PartitionInfo) takeReplicatedUUID(appID, allocKey, nodeID string) string {
+	key := replicatedAllocationKey(appID, allocKey, nodeID)
+	uuids := pi.replicatedUUIDs[key]
+	if len(uuids) == 0 {
+		return ""
+	}
+	uuid := uuids[0]
+	if len(uuids) == 1 {
+		delete(pi.replicatedUUIDs, key)
+	} else {
+		pi.replicatedUUIDs[key] = uuids[1:]
+	}
+	if pi.allocations[uuid] != nil {
+		return ""
+	}
+	return uuid
+}
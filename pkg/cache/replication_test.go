@@ -0,0 +1,189 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+	"github.com/apache/incubator-yunikorn-core/pkg/handler"
+	"github.com/apache/incubator-yunikorn-core/pkg/rmproxy/rmevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/schedulerevent"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+const configReplicated = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+        - name: replicated
+`
+
+// Register the RM with the cluster using the mocked configuration.
+func registerRM(t *testing.T, clusterInfo *ClusterInfo) {
+	clusterInfo.processRMRegistrationEvent(&commonevents.RegisterRMEvent{
+		RMRegistrationRequest: &si.RegisterResourceManagerRequest{RmID: "rm1", PolicyGroup: "default-policy-group"},
+		Channel:               make(chan *commonevents.Result, 1),
+	})
+	assert.Equal(t, len(clusterInfo.ListPartitions()), 1, "RM registration failed")
+}
+
+func newClusterWithRecorders() (*ClusterInfo, *eventRecorder, *eventRecorder) {
+	clusterInfo := NewClusterInfo()
+	scheduler := &eventRecorder{}
+	rm := &eventRecorder{}
+	clusterInfo.EventHandlers = handler.EventHandlers{
+		SchedulerEventHandler: scheduler,
+		RMProxyEventHandler:   rm,
+	}
+	return clusterInfo, scheduler, rm
+}
+
+func TestReplicatedStateStandby(t *testing.T) {
+	clusterInfo, _, _ := newClusterWithRecorders()
+	_, err := clusterInfo.GetReplicatedState()
+	assert.ErrorContains(t, err, "no RM registered")
+
+	// invalid states are rejected
+	assert.ErrorContains(t, clusterInfo.SetReplicatedState(nil), "must have the RM ID")
+	assert.ErrorContains(t, clusterInfo.SetReplicatedState(&ReplicatedState{RmID: "rm1"}), "must have the RM ID")
+	state := &ReplicatedState{RmID: "rm1", PolicyGroup: "default-policy-group", Config: "partitions: ["}
+	assert.ErrorContains(t, clusterInfo.SetReplicatedState(state), "invalid configuration")
+
+	// a state for a different RM is not used
+	state.Config = configReplicated
+	assert.NilError(t, clusterInfo.SetReplicatedState(state), "valid state should have been stored")
+	assert.Assert(t, clusterInfo.takeReplicatedState("rm2", "default-policy-group") == nil, "state for other RM returned")
+	assert.Assert(t, clusterInfo.takeReplicatedState("rm1", "default-policy-group") == nil, "state should only be used once")
+
+	// an instance with a registered RM is not a standby
+	configs.MockSchedulerConfigByData([]byte(configDefault))
+	registerRM(t, clusterInfo)
+	err = clusterInfo.SetReplicatedState(state)
+	assert.ErrorContains(t, err, "only accepted by a standby instance")
+	assert.Assert(t, api.IsError(err, api.ErrInvalidState), "registered instance should return an invalid state error")
+}
+
+func TestReplicatedStateTakeover(t *testing.T) {
+	// the active instance with an application and two allocations for the same ask on a node
+	configs.MockSchedulerConfigByData([]byte(configReplicated))
+	active, _, _ := newClusterWithRecorders()
+	registerRM(t, active)
+	partition := active.GetPartition("[rm1]default")
+	assert.Assert(t, partition != nil, "partition not created")
+	user := security.UserGroup{User: "testuser", Groups: []string{"testgroup"}}
+	app := NewApplicationInfo("app-1", partition.Name, "root.replicated", user, map[string]string{"tag": "value"})
+	err := partition.addNewApplication(app, true)
+	assert.NilError(t, err, "add application failed")
+	node := NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10}))
+	err = partition.addNewNode(node, nil)
+	assert.NilError(t, err, "add node failed")
+	uuids := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		var alloc *AllocationInfo
+		alloc, err = partition.addNewAllocation(createAllocationProposal("root.replicated", "node-1", "alloc-1", "app-1"))
+		assert.NilError(t, err, "add allocation failed")
+		uuids[alloc.AllocationProto.UUID] = true
+	}
+
+	state, err := active.GetReplicatedState()
+	assert.NilError(t, err, "active instance should have state")
+	assert.Equal(t, state.RmID, "rm1", "unexpected RM")
+	assert.Equal(t, len(state.Applications), 1, "application not replicated")
+	assert.Equal(t, len(state.Allocations), 2, "allocations not replicated")
+	// the state is exchanged as json
+	var data []byte
+	data, err = json.Marshal(state)
+	assert.NilError(t, err, "state marshal failed")
+	received := &ReplicatedState{}
+	err = json.Unmarshal(data, received)
+	assert.NilError(t, err, "state unmarshal failed")
+
+	// the standby uses the replicated configuration, not its own
+	configs.MockSchedulerConfigByData([]byte(configDefault))
+	standby, scheduler, rm := newClusterWithRecorders()
+	err = standby.SetReplicatedState(received)
+	assert.NilError(t, err, "standby should accept the state")
+	registerRM(t, standby)
+	partition = standby.GetPartition("[rm1]default")
+	assert.Assert(t, partition.getQueue("root.replicated") != nil, "replicated configuration not used")
+	assert.Assert(t, partition.getQueue("root.default") == nil, "configuration of the standby should not be used")
+
+	// the application is restored and sent to the scheduler after the partitions
+	assert.Equal(t, len(scheduler.events), 2, "expected partition and application events")
+	_, ok := scheduler.events[0].(*schedulerevent.SchedulerUpdatePartitionsConfigEvent)
+	assert.Assert(t, ok, "first event should update the partitions")
+	added := scheduler.events[1].(*schedulerevent.SchedulerApplicationsUpdateEvent).AddedApplications
+	assert.Equal(t, len(added), 1, "expected the restored application")
+	restored := partition.getApplication("app-1")
+	assert.Assert(t, restored != nil, "application not restored")
+	assert.Equal(t, restored.QueueName, "root.replicated", "unexpected queue")
+	assert.Equal(t, restored.SubmissionTime, app.SubmissionTime, "submission time not restored")
+	assert.Equal(t, restored.GetUser().User, user.User, "user not restored")
+	assert.DeepEqual(t, restored.GetUser().Groups, user.Groups)
+	assert.Equal(t, restored.GetTag("tag"), "value", "tags not restored")
+
+	// the RM sends the application again: accepted once without adding it
+	resend := &si.UpdateRequest{
+		RmID: "rm1",
+		NewApplications: []*si.AddApplicationRequest{{
+			ApplicationID: "app-1",
+			QueueName:     "root.replicated",
+			PartitionName: partition.Name,
+			Ugi:           &si.UserGroupInformation{User: "testuser"},
+		}},
+	}
+	standby.processApplicationUpdateFromRMUpdate(resend)
+	assert.Equal(t, len(rm.events), 1, "expected an RM event")
+	update := rm.events[0].(*rmevent.RMApplicationUpdateEvent)
+	assert.Equal(t, len(update.AcceptedApplications), 1, "restored application should have been accepted")
+	assert.Equal(t, len(update.RejectedApplications), 0, "restored application should not have been rejected")
+	// sending it again updates the tags of the application
+	standby.processApplicationUpdateFromRMUpdate(resend)
+	assert.Equal(t, len(rm.events), 1, "second add of the application should not be accepted or rejected")
+	updated := scheduler.events[len(scheduler.events)-1].(*schedulerevent.SchedulerApplicationsUpdateEvent).UpdatedApplications
+	assert.Equal(t, len(updated), 1, "second add of the application should update the application")
+	assert.Equal(t, restored.GetTag("tag"), "", "tags not updated")
+
+	// the allocations reported by the node keep their UUID, an unknown allocation gets a new UUID
+	reported := createAllocation("root.replicated", "node-1", "alloc-1", "app-1")
+	unknown := createAllocation("root.replicated", "node-1", "alloc-2", "app-1")
+	node = NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10}))
+	err = partition.addNewNode(node, []*si.Allocation{reported, reported, unknown})
+	assert.NilError(t, err, "add node with existing allocations failed")
+	allocs := partition.GetNode("node-1").GetAllAllocations()
+	assert.Equal(t, len(allocs), 3, "existing allocations not added")
+	kept := 0
+	for _, alloc := range allocs {
+		if uuids[alloc.AllocationProto.UUID] {
+			assert.Equal(t, alloc.AllocationProto.AllocationKey, "alloc-1", "replicated UUID used for wrong allocation")
+			kept++
+		}
+	}
+	assert.Equal(t, kept, 2, "reported allocations should have kept their UUID")
+}
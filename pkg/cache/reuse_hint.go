@@ -0,0 +1,84 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// Maximum number of reuse hints kept per application, the oldest hint is dropped first
+const maxReuseHints = 16
+
+// The node of a released allocation, preferred for a similar ask of the same application until it expires.
+type reuseHint struct {
+	nodeID   string
+	resource *resources.Resource
+	expires  time.Time
+}
+
+// Record the node of a released allocation as a reuse hint for the application.
+// Expired hints are removed, a ttl of zero does not record a hint.
+func (ai *ApplicationInfo) addReuseHint(alloc *AllocationInfo, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	ai.lock.Lock()
+	defer ai.lock.Unlock()
+
+	now := time.Now()
+	ai.removeExpiredHints(now)
+	if len(ai.reuseHints) >= maxReuseHints {
+		ai.reuseHints = ai.reuseHints[1:]
+	}
+	ai.reuseHints = append(ai.reuseHints, reuseHint{
+		nodeID:   alloc.AllocationProto.NodeID,
+		resource: alloc.AllocatedResource,
+		expires:  now.Add(ttl),
+	})
+}
+
+// Return the node of the most recently released allocation the requested resource fits in.
+// An empty string is returned if there is no hint that has not expired.
+func (ai *ApplicationInfo) GetReuseNode(request *resources.Resource) string {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
+	now := time.Now()
+	for i := len(ai.reuseHints) - 1; i >= 0; i-- {
+		hint := ai.reuseHints[i]
+		if now.Before(hint.expires) && resources.FitIn(hint.resource, request) {
+			return hint.nodeID
+		}
+	}
+	return ""
+}
+
+// Remove the expired hints keeping the order of the remaining hints.
+// Lock free call, must be called holding the application lock.
+func (ai *ApplicationInfo) removeExpiredHints(now time.Time) {
+	hints := ai.reuseHints[:0]
+	for _, hint := range ai.reuseHints {
+		if now.Before(hint.expires) {
+			hints = append(hints, hint)
+		}
+	}
+	ai.reuseHints = hints
+}
@@ -0,0 +1,108 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func newReleasedAllocation(nodeID string, mem resources.Quantity) *AllocationInfo {
+	return &AllocationInfo{
+		AllocationProto:   &si.Allocation{NodeID: nodeID},
+		AllocatedResource: resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: mem}),
+	}
+}
+
+func TestReuseHint(t *testing.T) {
+	app := NewApplicationInfo("app-1", "default", "root.default", security.UserGroup{}, nil)
+	small := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 5})
+	large := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 20})
+	assert.Equal(t, app.GetReuseNode(small), "", "app without released allocations should not have a hint")
+
+	// no ttl no hint
+	app.addReuseHint(newReleasedAllocation("node-0", 10), 0)
+	assert.Equal(t, len(app.reuseHints), 0, "hint recorded without ttl")
+
+	app.addReuseHint(newReleasedAllocation("node-1", 10), time.Hour)
+	app.addReuseHint(newReleasedAllocation("node-2", 10), time.Hour)
+	assert.Equal(t, app.GetReuseNode(small), "node-2", "most recent hint should be returned")
+	assert.Equal(t, app.GetReuseNode(large), "", "request larger than the released allocation should not have a hint")
+
+	// expired hints are skipped and removed on the next add
+	app.reuseHints[1].expires = time.Now().Add(-time.Second)
+	assert.Equal(t, app.GetReuseNode(small), "node-1", "expired hint should be skipped")
+	app.addReuseHint(newReleasedAllocation("node-3", 30), time.Hour)
+	assert.Equal(t, len(app.reuseHints), 2, "expired hint not removed")
+	assert.Equal(t, app.GetReuseNode(large), "node-3", "hint for the large request not found")
+
+	// oldest hint is dropped when the maximum is reached
+	for i := 0; i < maxReuseHints; i++ {
+		app.addReuseHint(newReleasedAllocation("node-"+strconv.Itoa(10+i), 1), time.Hour)
+	}
+	assert.Equal(t, len(app.reuseHints), maxReuseHints, "hints not limited")
+	assert.Equal(t, app.GetReuseNode(small), "", "oldest hints should have been dropped")
+}
+
+func TestReleaseReuseHint(t *testing.T) {
+	partition, err := CreatePartitionInfo([]byte(`
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+        - name: default
+        - name: reuse
+          properties:
+            allocation.reuse.ttl: 1h
+`))
+	assert.NilError(t, err, "partition create failed")
+	nodeID := "node-1"
+	node := NewNodeForTest(nodeID, resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 10}))
+	err = partition.addNewNode(node, nil)
+	assert.NilError(t, err, "add node to partition should not have failed")
+	request := resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 1})
+
+	release := func(appID, queueName string, releaseType si.AllocationReleaseResponse_TerminationType) *ApplicationInfo {
+		app := newApplicationInfo(appID, "default", queueName)
+		err = partition.addNewApplication(app, true)
+		assert.NilError(t, err, "add application to partition should not have failed")
+		var alloc *AllocationInfo
+		alloc, err = partition.addNewAllocation(createAllocationProposal(queueName, nodeID, "alloc-1", appID))
+		assert.NilError(t, err, "add allocation to partition should not have failed")
+		toRelease := commonevents.NewReleaseAllocation(alloc.AllocationProto.UUID, appID, partition.Name, "", releaseType)
+		allocs := partition.releaseAllocationsForApplication(toRelease)
+		assert.Equal(t, len(allocs), 1, "allocation not released")
+		return app
+	}
+	app := release("app-1", "root.reuse", si.AllocationReleaseResponse_STOPPED_BY_RM)
+	assert.Equal(t, app.GetReuseNode(request), nodeID, "released allocation should have been recorded")
+	app = release("app-2", "root.default", si.AllocationReleaseResponse_STOPPED_BY_RM)
+	assert.Equal(t, app.GetReuseNode(request), "", "queue without reuse ttl should not record hints")
+	app = release("app-3", "root.reuse", si.AllocationReleaseResponse_PREEMPTED_BY_SCHEDULER)
+	assert.Equal(t, app.GetReuseNode(request), "", "preempted allocation should not be recorded")
+}
@@ -0,0 +1,260 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"sync"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache/cacheevent"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// The number of objects an RM or an application can update per turn when the updates of multiple RMs or applications
+// are queued
+const updateQuantum = 100
+
+// Queue of the events received from the RMs: the update requests, the registrations and the configuration updates.
+// Between RMs the events are ordered using a deficit round robin weighted by the number of objects in the event.
+// An RM sending a storm of large requests cannot delay the events of the other RMs by more than one turn.
+// The events of one RM are processed in the order they were received, a request can depend on an earlier one like an
+// ask for an application added in a previous request. The only exception are the requests that update just one
+// application: the requests for different applications received between two other events of the RM are ordered
+// using the same deficit round robin. A request is never processed before an earlier request for the same
+// application or before an earlier event of the RM that is not for a single application.
+type updateQueue struct {
+	rms   map[string]*rmUpdates // queued events per RM
+	turns *roundRobin           // turns of the RMs with queued events
+
+	sync.Mutex
+}
+
+// The queued events of one RM in segments, in the order received. A segment is either a single event that orders
+// all events of the RM or the requests for single applications received between two such events.
+type rmUpdates struct {
+	segments []*updateSegment
+}
+
+type updateSegment struct {
+	barrier  *queuedUpdate              // the event that orders all events of the RM, nil for application requests
+	requests map[string][]*queuedUpdate // queued requests per application in order received
+	turns    *roundRobin                // turns of the applications with queued requests
+}
+
+type queuedUpdate struct {
+	event interface{}
+	appID string // the only application the request updates, empty if the event orders all events of the RM
+	cost  int    // the number of objects in the event
+}
+
+// Event queued on the RM event channel for each event added to the update queue.
+// The handler processes the next event from the update queue, which is not necessarily the one that was added.
+type updateQueued struct{}
+
+func newUpdateQueue() *updateQueue {
+	return &updateQueue{
+		rms:   make(map[string]*rmUpdates),
+		turns: newRoundRobin(),
+	}
+}
+
+// Add the event to the end of the queue of the RM.
+func (q *updateQueue) add(rmID string, event interface{}) {
+	item := &queuedUpdate{event: event, cost: 1}
+	if update, ok := event.(*cacheevent.RMUpdateRequestEvent); ok {
+		item.appID = requestApplication(update.Request)
+		item.cost = requestSize(update.Request)
+	}
+	q.Lock()
+	defer q.Unlock()
+	rm := q.rms[rmID]
+	if rm == nil {
+		rm = &rmUpdates{}
+		q.rms[rmID] = rm
+		q.turns.add(rmID)
+	}
+	rm.add(item)
+}
+
+// Remove and return the next event to process, nil if the queue is empty.
+func (q *updateQueue) next() interface{} {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.rms) == 0 {
+		return nil
+	}
+	rmID := q.turns.next(func(rmID string) int {
+		return q.rms[rmID].peek().cost
+	})
+	rm := q.rms[rmID]
+	item := rm.pop()
+	empty := len(rm.segments) == 0
+	q.turns.charge(rmID, item.cost, empty)
+	if empty {
+		delete(q.rms, rmID)
+	}
+	return item.event
+}
+
+// Add the event to the last segment if both are for single applications, otherwise start a new segment.
+func (rm *rmUpdates) add(item *queuedUpdate) {
+	if item.appID == "" {
+		rm.segments = append(rm.segments, &updateSegment{barrier: item})
+		return
+	}
+	last := len(rm.segments) - 1
+	if last < 0 || rm.segments[last].barrier != nil {
+		rm.segments = append(rm.segments, &updateSegment{
+			requests: make(map[string][]*queuedUpdate),
+			turns:    newRoundRobin(),
+		})
+		last++
+	}
+	segment := rm.segments[last]
+	if len(segment.requests[item.appID]) == 0 {
+		segment.turns.add(item.appID)
+	}
+	segment.requests[item.appID] = append(segment.requests[item.appID], item)
+}
+
+// Return the next event of the RM without removing it. The RM must have queued events.
+func (rm *rmUpdates) peek() *queuedUpdate {
+	segment := rm.segments[0]
+	if segment.barrier != nil {
+		return segment.barrier
+	}
+	return segment.requests[segment.nextApplication()][0]
+}
+
+// Remove and return the next event of the RM. The RM must have queued events.
+func (rm *rmUpdates) pop() *queuedUpdate {
+	segment := rm.segments[0]
+	if segment.barrier != nil {
+		rm.segments = rm.segments[1:]
+		return segment.barrier
+	}
+	appID := segment.nextApplication()
+	queued := segment.requests[appID]
+	empty := len(queued) == 1
+	segment.turns.charge(appID, queued[0].cost, empty)
+	if empty {
+		delete(segment.requests, appID)
+	} else {
+		segment.requests[appID] = queued[1:]
+	}
+	if len(segment.requests) == 0 {
+		rm.segments = rm.segments[1:]
+	}
+	return queued[0]
+}
+
+// Return the application that has the turn in the segment.
+func (s *updateSegment) nextApplication() string {
+	return s.turns.next(func(appID string) int {
+		return s.requests[appID][0].cost
+	})
+}
+
+// Deficit round robin between the RMs or the applications of an RM.
+// The one that has the turn keeps it until the objects in its next event exceed its deficit. The turn then passes
+// to the next one and the deficit is increased for its next turn.
+type roundRobin struct {
+	deficit map[string]int // number of objects that can still be updated in the turn
+	order   []string       // the ones with queued events, the first one has the turn
+}
+
+func newRoundRobin() *roundRobin {
+	return &roundRobin{
+		deficit: make(map[string]int),
+	}
+}
+
+// Add at the end of the order, must only be called if there were no queued events for the key.
+func (rr *roundRobin) add(key string) {
+	rr.order = append(rr.order, key)
+}
+
+// Return the key that has the turn for its next event, the cost function returns the cost of the next event of a key.
+// Calling it again without charging the event returns the same key.
+func (rr *roundRobin) next(cost func(key string) int) string {
+	for {
+		key := rr.order[0]
+		if rr.deficit[key] >= cost(key) {
+			return key
+		}
+		rr.deficit[key] += updateQuantum
+		rr.order = append(rr.order[1:], key)
+	}
+}
+
+// Charge the cost of the processed event to the key that has the turn.
+// A key without queued events left is removed and does not keep its deficit.
+func (rr *roundRobin) charge(key string, cost int, empty bool) {
+	rr.deficit[key] -= cost
+	if empty {
+		delete(rr.deficit, key)
+		rr.order = rr.order[1:]
+	}
+}
+
+// Return the number of objects in the request, a request without objects counts as one.
+func requestSize(request *si.UpdateRequest) int {
+	size := len(request.Asks) + len(request.NewSchedulableNodes) + len(request.UpdatedNodes) +
+		len(request.UtilizationReports) + len(request.NewApplications) + len(request.RemoveApplications)
+	if request.Releases != nil {
+		size += len(request.Releases.AllocationsToRelease) + len(request.Releases.AllocationAsksToRelease)
+	}
+	if size == 0 {
+		return 1
+	}
+	return size
+}
+
+// Return the only application the request updates. Returns an empty string if the request updates nodes, more than
+// one application or nothing.
+func requestApplication(request *si.UpdateRequest) string {
+	if len(request.NewSchedulableNodes) != 0 || len(request.UpdatedNodes) != 0 || len(request.UtilizationReports) != 0 {
+		return ""
+	}
+	var appIDs []string
+	for _, ask := range request.Asks {
+		appIDs = append(appIDs, ask.ApplicationID)
+	}
+	for _, app := range request.NewApplications {
+		appIDs = append(appIDs, app.ApplicationID)
+	}
+	for _, app := range request.RemoveApplications {
+		appIDs = append(appIDs, app.ApplicationID)
+	}
+	if request.Releases != nil {
+		for _, release := range request.Releases.AllocationsToRelease {
+			appIDs = append(appIDs, release.ApplicationID)
+		}
+		for _, release := range request.Releases.AllocationAsksToRelease {
+			appIDs = append(appIDs, release.ApplicationID)
+		}
+	}
+	appID := ""
+	for _, id := range appIDs {
+		if id == "" || (appID != "" && id != appID) {
+			return ""
+		}
+		appID = id
+	}
+	return appID
+}
@@ -0,0 +1,198 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package cache
+
+import (
+	"strconv"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache/cacheevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// create an update request with the number of asks for one application, the sequence is stored in the allocation key
+func newUpdateEvent(rmID string, sequence, asks int) *cacheevent.RMUpdateRequestEvent {
+	return newAppUpdateEvent(rmID, "app", sequence, asks)
+}
+
+func newAppUpdateEvent(rmID, appID string, sequence, asks int) *cacheevent.RMUpdateRequestEvent {
+	request := &si.UpdateRequest{RmID: rmID}
+	for i := 0; i < asks; i++ {
+		request.Asks = append(request.Asks, &si.AllocationAsk{ApplicationID: appID, AllocationKey: strconv.Itoa(sequence)})
+	}
+	return &cacheevent.RMUpdateRequestEvent{Request: request}
+}
+
+// get the next update request from the queue, nil if the queue is empty
+func nextRequest(t *testing.T, queue *updateQueue) *si.UpdateRequest {
+	event := queue.next()
+	if event == nil {
+		return nil
+	}
+	update, ok := event.(*cacheevent.RMUpdateRequestEvent)
+	assert.Assert(t, ok, "expected an update request, got %v", event)
+	return update.Request
+}
+
+func TestRequestSize(t *testing.T) {
+	assert.Equal(t, requestSize(&si.UpdateRequest{}), 1, "empty request should count as one")
+	request := &si.UpdateRequest{
+		Asks:            []*si.AllocationAsk{{}, {}},
+		NewApplications: []*si.AddApplicationRequest{{}},
+		Releases: &si.AllocationReleasesRequest{
+			AllocationsToRelease: []*si.AllocationReleaseRequest{{}},
+		},
+	}
+	assert.Equal(t, requestSize(request), 4, "unexpected request size")
+}
+
+func TestRequestApplication(t *testing.T) {
+	assert.Equal(t, requestApplication(&si.UpdateRequest{}), "", "empty request should not be for an application")
+	request := &si.UpdateRequest{
+		Asks:            []*si.AllocationAsk{{ApplicationID: "app-1"}},
+		NewApplications: []*si.AddApplicationRequest{{ApplicationID: "app-1"}},
+		Releases: &si.AllocationReleasesRequest{
+			AllocationAsksToRelease: []*si.AllocationAskReleaseRequest{{ApplicationID: "app-1"}},
+		},
+	}
+	assert.Equal(t, requestApplication(request), "app-1", "request for one application")
+	request.RemoveApplications = []*si.RemoveApplicationRequest{{ApplicationID: "app-2"}}
+	assert.Equal(t, requestApplication(request), "", "request for two applications")
+	request.RemoveApplications = nil
+	request.Releases.AllocationsToRelease = []*si.AllocationReleaseRequest{{}}
+	assert.Equal(t, requestApplication(request), "", "release without an application")
+	request.Releases = nil
+	request.UpdatedNodes = []*si.UpdateNodeInfo{{}}
+	assert.Equal(t, requestApplication(request), "", "request that updates a node")
+}
+
+func TestUpdateQueueOrder(t *testing.T) {
+	queue := newUpdateQueue()
+	assert.Assert(t, queue.next() == nil, "empty queue should not return a request")
+	for i := 0; i < 5; i++ {
+		queue.add("rm-1", newUpdateEvent("rm-1", i, 30))
+	}
+	// a single RM gets all turns and its requests are never reordered
+	for i := 0; i < 5; i++ {
+		request := nextRequest(t, queue)
+		assert.Equal(t, request.RmID, "rm-1", "unexpected RM")
+		assert.Equal(t, request.Asks[0].AllocationKey, strconv.Itoa(i), "requests of the RM out of order")
+	}
+	assert.Assert(t, queue.next() == nil, "queue should be empty")
+	assert.Equal(t, len(queue.rms), 0, "no RM should have queued events")
+	assert.Equal(t, len(queue.turns.order), 0, "no RM should have the turn")
+	assert.Equal(t, len(queue.turns.deficit), 0, "deficit should be removed for RMs without requests")
+}
+
+// An update storm from one RM must not delay the request of another RM until the storm is processed.
+func TestUpdateQueueLatencyFairness(t *testing.T) {
+	queue := newUpdateQueue()
+	for i := 0; i < 1000; i++ {
+		queue.add("storm", newUpdateEvent("storm", i, 50))
+	}
+	queue.add("quiet", newUpdateEvent("quiet", 0, 1))
+	position := -1
+	storm := 0
+	for i := 0; ; i++ {
+		request := nextRequest(t, queue)
+		if request == nil {
+			break
+		}
+		if request.RmID == "quiet" {
+			position = i
+			continue
+		}
+		assert.Equal(t, request.Asks[0].AllocationKey, strconv.Itoa(storm), "requests of the storm out of order")
+		storm++
+	}
+	assert.Equal(t, storm, 1000, "all storm requests should be processed")
+	// the storm processes at most one turn of updateQuantum objects before the quiet RM
+	assert.Assert(t, position >= 0 && position <= updateQuantum/50, "quiet request processed at position %d", position)
+}
+
+// The number of requests processed per turn depends on the size of the requests.
+func TestUpdateQueueWeighted(t *testing.T) {
+	queue := newUpdateQueue()
+	for i := 0; i < 100; i++ {
+		queue.add("small", newUpdateEvent("small", i, 10))
+		queue.add("large", newUpdateEvent("large", i, 100))
+	}
+	processed := make(map[string]int)
+	for i := 0; i < 55; i++ {
+		processed[nextRequest(t, queue).RmID]++
+	}
+	// per turn: ten small requests against one large request
+	assert.Equal(t, processed["small"], 50, "unexpected number of small requests processed")
+	assert.Equal(t, processed["large"], 5, "unexpected number of large requests processed")
+}
+
+// An update storm for one application must not delay the requests for other applications of the same RM.
+func TestUpdateQueueApplicationFairness(t *testing.T) {
+	queue := newUpdateQueue()
+	for i := 0; i < 1000; i++ {
+		queue.add("rm-1", newAppUpdateEvent("rm-1", "storm", i, 50))
+	}
+	queue.add("rm-1", newAppUpdateEvent("rm-1", "quiet", 0, 1))
+	position := -1
+	storm := 0
+	for i := 0; ; i++ {
+		request := nextRequest(t, queue)
+		if request == nil {
+			break
+		}
+		if request.Asks[0].ApplicationID == "quiet" {
+			position = i
+			continue
+		}
+		assert.Equal(t, request.Asks[0].AllocationKey, strconv.Itoa(storm), "requests of the storm out of order")
+		storm++
+	}
+	assert.Equal(t, storm, 1000, "all storm requests should be processed")
+	assert.Assert(t, position >= 0 && position <= updateQuantum/50, "quiet request processed at position %d", position)
+}
+
+// Events that are not for a single application keep their place in the order of the RM.
+func TestUpdateQueueBarrier(t *testing.T) {
+	queue := newUpdateQueue()
+	for i := 0; i < 3; i++ {
+		queue.add("rm-1", newAppUpdateEvent("rm-1", "app-1", i, 50))
+	}
+	nodes := &cacheevent.RMUpdateRequestEvent{Request: &si.UpdateRequest{RmID: "rm-1", NewSchedulableNodes: []*si.NewNodeInfo{{}}}}
+	queue.add("rm-1", nodes)
+	queue.add("rm-1", newAppUpdateEvent("rm-1", "app-2", 0, 1))
+	config := &commonevents.ConfigUpdateRMEvent{RmID: "rm-1"}
+	queue.add("rm-1", config)
+	queue.add("rm-1", newAppUpdateEvent("rm-1", "app-1", 3, 1))
+
+	// the request for app-2 cannot pass the node request, and the last app-1 request cannot pass the config update
+	for i := 0; i < 3; i++ {
+		request := nextRequest(t, queue)
+		assert.Equal(t, request.Asks[0].AllocationKey, strconv.Itoa(i), "app-1 requests out of order")
+	}
+	assert.Equal(t, queue.next(), nodes, "node request should keep its place")
+	assert.Equal(t, nextRequest(t, queue).Asks[0].ApplicationID, "app-2", "app-2 request should follow the node request")
+	assert.Equal(t, queue.next(), config, "config update should keep its place")
+	request := nextRequest(t, queue)
+	assert.Equal(t, request.Asks[0].ApplicationID, "app-1", "app-1 request should follow the config update")
+	assert.Equal(t, request.Asks[0].AllocationKey, "3", "unexpected app-1 request")
+	assert.Assert(t, queue.next() == nil, "queue should be empty")
+}
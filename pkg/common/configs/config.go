@@ -33,9 +33,29 @@ import (
 
 // The configuration can contain multiple partitions. Each partition contains the queue definition for a logical
 // set of scheduler resources.
+// Partition templates are resolved into partitions when the configuration is loaded.
 type SchedulerConfig struct {
 	Partitions []PartitionConfig
-	Checksum   []byte
+	Templates  []PartitionTemplate `yaml:",omitempty" json:",omitempty"`
+	Checksum   []byte              `yaml:",omitempty" json:",omitempty"`
+}
+
+// The partition template object:
+// - the name of the template
+// - the partition definition, any string value can contain placeholders in the form ${key}
+// - the list of partitions to create from the template
+type PartitionTemplate struct {
+	Name      string
+	Partition PartitionConfig
+	Instances []TemplateInstance
+}
+
+// A partition created from a template:
+// - the name of the partition, replaces the ${partition} placeholder and the name of the template partition
+// - the values for the placeholders in the template
+type TemplateInstance struct {
+	Name   string
+	Values map[string]string `yaml:",omitempty" json:",omitempty"`
 }
 
 // The partition object for each partition:
@@ -44,17 +64,165 @@ type SchedulerConfig struct {
 // - a list of placement rule definition objects
 // - a list of users specifying limits on the partition
 // - the preemption configuration for the partition
+// - the limits on the reservations outstanding in the partition
+// - the node pool configuration for the partition
+// - the user group resolver used for the ACL checks of the partition
+// - the time an unmanaged queue must be idle before it is removed (duration string), not set means the queue is
+// removed as soon as it is empty
+// - the resource type aliases: an alias used by a shim mapped to the canonical resource type used in the scheduler
+// - the resource type units: the number of scheduler units in one configured unit of a divisible resource type
+// - the RM resource type units: the number of RM units in one configured unit, not set means the RM uses scheduler units
+// - the autoscale event configuration for the partition
+// - the ask budget and the maintenance windows for the partition
+// - the configuration profile used as the base of the partition, not set means no profile
+// - the quarantine of flapping nodes for the partition
+// - the time a running application without allocations and pending asks is kept before it is completed (duration
+// string), not set means the application is kept until the shim removes it
+// - the throughput mode for very large partitions
+// - the resource types that are tracked but not enforced in the node fit checks
 type PartitionConfig struct {
-	Name           string
-	Queues         []QueueConfig
-	PlacementRules []PlacementRule           `yaml:",omitempty" json:",omitempty"`
-	Limits         []Limit                   `yaml:",omitempty" json:",omitempty"`
-	Preemption     PartitionPreemptionConfig `yaml:",omitempty" json:",omitempty"`
-	NodeSortPolicy NodeSortingPolicy         `yaml:",omitempty" json:",omitempty"`
+	Name                     string
+	Queues                   []QueueConfig
+	PlacementRules           []PlacementRule               `yaml:",omitempty" json:",omitempty"`
+	Limits                   []Limit                       `yaml:",omitempty" json:",omitempty"`
+	Preemption               PartitionPreemptionConfig     `yaml:",omitempty" json:",omitempty"`
+	NodeSortPolicy           NodeSortingPolicy             `yaml:",omitempty" json:",omitempty"`
+	Reservations             PartitionReservationConfig    `yaml:",omitempty" json:",omitempty"`
+	NodePools                PartitionNodePoolConfig       `yaml:",omitempty" json:",omitempty"`
+	UserGroups               UserGroupResolverConfig       `yaml:",omitempty" json:",omitempty"`
+	QueueIdleTimeout         string                        `yaml:",omitempty" json:",omitempty"`
+	QueueDrainTimeout        string                        `yaml:",omitempty" json:",omitempty"`
+	QueueDrainTarget         string                        `yaml:",omitempty" json:",omitempty"`
+	ResourceAliases          map[string]string             `yaml:",omitempty" json:",omitempty"`
+	ResourceUnits            map[string]int64              `yaml:",omitempty" json:",omitempty"`
+	RMResourceUnits          map[string]int64              `yaml:",omitempty" json:",omitempty"`
+	Autoscale                PartitionAutoscaleConfig      `yaml:",omitempty" json:",omitempty"`
+	AskBudget                PartitionAskBudgetConfig      `yaml:",omitempty" json:",omitempty"`
+	Maintenance              []PartitionMaintenanceConfig  `yaml:",omitempty" json:",omitempty"`
+	Profile                  string                        `yaml:",omitempty" json:",omitempty"`
+	NodeQuarantine           PartitionNodeQuarantineConfig `yaml:",omitempty" json:",omitempty"`
+	AppCompletionGracePeriod string                        `yaml:",omitempty" json:",omitempty"`
+	Throughput               PartitionThroughputConfig     `yaml:",omitempty" json:",omitempty"`
+	IgnoredResourceTypes     []string                      `yaml:",omitempty" json:",omitempty"`
+	Hierarchy                PartitionHierarchyConfig      `yaml:",omitempty" json:",omitempty"`
 }
 
+// The preemption configuration for the partition:
+// - enable or disable preemption
+// - the grace period between the notification and the release of a checkpointable allocation (duration string)
+// - the utilization trigger: preemption also runs while the partition is highly utilized and a queue is starving
+// - the minimum runtime of an allocation before it can be preempted (duration string), not set means no protection
+// - the ask priority at or above which the minimum runtime is ignored, 0 means the minimum runtime is always applied
+// - the settle period in which a queue is not preempted after a reload changed its limits (duration string), not set means none
 type PartitionPreemptionConfig struct {
-	Enabled bool
+	Enabled           bool
+	GracePeriod       string                      `yaml:",omitempty" json:",omitempty"`
+	Utilization       PreemptionUtilizationConfig `yaml:",omitempty" json:",omitempty"`
+	MinRuntime        string                      `yaml:",omitempty" json:",omitempty"`
+	EmergencyPriority int32                       `yaml:",omitempty" json:",omitempty"`
+	ConfirmTimeout    string                      `yaml:",omitempty" json:",omitempty"`
+	SettlePeriod      string                      `yaml:",omitempty" json:",omitempty"`
+}
+
+// The utilization based preemption trigger for the partition, as a percentage of the partition total resource:
+// - the utilization at which preemption is triggered if a queue is starving, 0 means no trigger
+// - the utilization below which the trigger is released, not set means 10 percent below the trigger
+type PreemptionUtilizationConfig struct {
+	Trigger int `yaml:",omitempty" json:",omitempty"`
+	Release int `yaml:",omitempty" json:",omitempty"`
+}
+
+// The reservation limits for the partition:
+// - the maximum number of node reservations outstanding at once, 0 means no limit
+// - the maximum total resource of all outstanding node reservations, not set means no limit
+// - the age after which a reservation for a removed ask or node is cleaned up (duration string), not set means the default
+type PartitionReservationConfig struct {
+	MaxReservations int               `yaml:",omitempty" json:",omitempty"`
+	MaxResource     map[string]string `yaml:",omitempty" json:",omitempty"`
+	StaleAge        string            `yaml:",omitempty" json:",omitempty"`
+}
+
+// The budget for the node evaluation of one ask in a scheduling cycle:
+// - the maximum time spent evaluating nodes for the ask (duration string), not set means no time limit
+// - the maximum number of nodes evaluated for the ask, 0 means no limit
+// When the budget is used up the node evaluation stops and the ask can reserve the best node evaluated so far.
+type PartitionAskBudgetConfig struct {
+	MaxTime  string `yaml:",omitempty" json:",omitempty"`
+	MaxNodes int    `yaml:",omitempty" json:",omitempty"`
+}
+
+// The limits on the shape of the queue hierarchy of the partition:
+// - the maximum depth of a queue below the root queue, root.a has a depth of 1, 0 means no limit
+// - the maximum number of child queues of a parent queue, 0 means no limit
+// The limits apply to the configured queues and to the queues created by the placement rules.
+type PartitionHierarchyConfig struct {
+	MaxDepth    int `yaml:",omitempty" json:",omitempty"`
+	MaxChildren int `yaml:",omitempty" json:",omitempty"`
+}
+
+// A scheduled reduction of the partition capacity, for instance for the maintenance of a part of the nodes:
+// - the start of the maintenance (RFC3339 time)
+// - the duration of the maintenance (duration string)
+// - the capacity removed as a percentage of the partition total resource
+// - the time before the start during which the reserved capacity grows to the full capacity (duration string), not
+// set means the capacity is reserved from the start only
+// The scheduler stops filling the reserved capacity, existing allocations are not preempted.
+type PartitionMaintenanceConfig struct {
+	Start    string
+	Duration string
+	Capacity int
+	LeadTime string `yaml:",omitempty" json:",omitempty"`
+}
+
+// The throughput mode of the partition trades the exact fair ordering of the allocations for scheduling speed:
+// - enable or disable the throughput mode
+// - the number of nodes sampled and sorted for an ask, 0 means the default. The nodes that are not in the sample are
+// tried unsorted after the sample.
+// In throughput mode the pending resources of a queue are passed on to its parents once per scheduling cycle.
+type PartitionThroughputConfig struct {
+	Enabled    bool
+	SampleSize int `yaml:",omitempty" json:",omitempty"`
+}
+
+// The quarantine of flapping nodes for the partition:
+// - the number of times a node can register or be removed within the window, a node that changes more often is
+// quarantined. 0 means nodes are never quarantined
+// - the window in which the changes of a node are counted (duration string), not set means the default
+// - the time a quarantined node is excluded from allocations (duration string), not set means the default
+type PartitionNodeQuarantineConfig struct {
+	Changes  int    `yaml:",omitempty" json:",omitempty"`
+	Window   string `yaml:",omitempty" json:",omitempty"`
+	CoolDown string `yaml:",omitempty" json:",omitempty"`
+}
+
+// The autoscale event configuration for the partition:
+// - the pending resource watermark of a leaf queue, not set means no autoscale events are sent
+// - the time the pending resource of a queue must stay above the watermark before an event is sent (duration
+// string), not set means the default. Events are repeated each threshold while the queue stays above the watermark.
+type PartitionAutoscaleConfig struct {
+	Watermark map[string]string `yaml:",omitempty" json:",omitempty"`
+	Threshold string            `yaml:",omitempty" json:",omitempty"`
+}
+
+// The node pool configuration for the partition:
+// - the node attribute that holds the name of the pool the node belongs to, nodes without the attribute
+// are part of the default pool
+type PartitionNodePoolConfig struct {
+	Attribute string `yaml:",omitempty" json:",omitempty"`
+}
+
+// The user group resolver configuration for the partition:
+// - the resolver type: not set means no resolution, "os", "static" or "plugin"
+// - the time a resolved user is cached (duration string), not set means the default
+// - the time a failed resolution is cached (duration string), not set means the default
+// - the groups per user for the static resolver
+// Groups passed in by the RM for an application are always used as is, the resolver is only used when the RM
+// does not pass in any groups.
+type UserGroupResolverConfig struct {
+	Type      string              `yaml:",omitempty" json:",omitempty"`
+	CacheTTL  string              `yaml:",omitempty" json:",omitempty"`
+	FailedTTL string              `yaml:",omitempty" json:",omitempty"`
+	Groups    map[string][]string `yaml:",omitempty" json:",omitempty"`
 }
 
 // The queue object for each queue:
@@ -65,6 +233,8 @@ type PartitionPreemptionConfig struct {
 // - ACL for submit and or admin access
 // - a list of sub or child queues
 // - a list of users specifying limits on a queue
+// - a list of node pools the queue is restricted to, not set means the pools of the parent
+// - a template applied to the leaf queues created below the queue by the placement rules
 type QueueConfig struct {
 	Name            string
 	Parent          bool              `yaml:",omitempty" json:",omitempty"`
@@ -75,15 +245,49 @@ type QueueConfig struct {
 	SubmitACL       string            `yaml:",omitempty" json:",omitempty"`
 	Queues          []QueueConfig     `yaml:",omitempty" json:",omitempty"`
 	Limits          []Limit           `yaml:",omitempty" json:",omitempty"`
+	NodePools       []NodePoolConfig  `yaml:",omitempty" json:",omitempty"`
+	ChildTemplate   ChildTemplate     `yaml:",omitempty" json:",omitempty"`
+}
+
+// The template for the leaf queues a placement rule creates below a parent queue, i.e. a per user quota.
+// Only applied to queues that are not defined in the config, queues in the config use their own settings.
+// - a resources object to specify resource limits on the created queue
+// - the maximum number of applications that can run in the created queue
+// - a set of properties, merged with the properties of the parent queue
+// - the admin and submit ACLs of the created queue, the ACLs of the parent queue still apply
+type ChildTemplate struct {
+	Resources       Resources         `yaml:",omitempty" json:",omitempty"`
+	MaxApplications uint64            `yaml:",omitempty" json:",omitempty"`
+	Properties      map[string]string `yaml:",omitempty" json:",omitempty"`
+	AdminACL        string            `yaml:",omitempty" json:",omitempty"`
+	SubmitACL       string            `yaml:",omitempty" json:",omitempty"`
+}
+
+// Check if the template has any value set.
+func (ct ChildTemplate) IsEmpty() bool {
+	return len(ct.Resources.Guaranteed) == 0 && len(ct.Resources.Max) == 0 && len(ct.Resources.SoftMax) == 0 &&
+		len(ct.Resources.MaxAllocation) == 0 && ct.MaxApplications == 0 && len(ct.Properties) == 0 && ct.AdminACL == "" && ct.SubmitACL == ""
+}
+
+// The node pool restriction for a queue:
+// - the name of the node pool the queue can use
+// - the max resources of the queue in the node pool, not set means no limit beside the queue max
+type NodePoolConfig struct {
+	Name string
+	Max  map[string]string `yaml:",omitempty" json:",omitempty"`
 }
 
 // The resource limits to set on the queue. The definition allows for an unlimited number of types to be used.
 // The mapping to "known" resources is not handled here.
 // - guaranteed resources
 // - max resources
+// - soft max resources: allocations beyond the soft max are allowed but flagged as overcommitted
+// - max allocation resources: the largest single allocation in the queue, larger asks are rejected
 type Resources struct {
-	Guaranteed map[string]string `yaml:",omitempty" json:",omitempty"`
-	Max        map[string]string `yaml:",omitempty" json:",omitempty"`
+	Guaranteed    map[string]string `yaml:",omitempty" json:",omitempty"`
+	Max           map[string]string `yaml:",omitempty" json:",omitempty"`
+	SoftMax       map[string]string `yaml:",omitempty" json:",omitempty"`
+	MaxAllocation map[string]string `yaml:",omitempty" json:",omitempty"`
 }
 
 // The queue placement rule definition
@@ -134,8 +338,11 @@ type Limit struct {
 
 // Global Node Sorting Policy section
 // - type: different type of policies supported (binpacking, fair etc)
+// - resource weights: sort the nodes on the weighted free fraction of the resource types instead of the dominant
+// share, not set means no weights. Queues can override the weights using a property.
 type NodeSortingPolicy struct {
-	Type string
+	Type            string
+	ResourceWeights map[string]float64 `yaml:",omitempty" json:",omitempty"`
 }
 
 type LoadSchedulerConfigFunc func(policyGroup string) (*SchedulerConfig, error)
@@ -149,6 +356,27 @@ func LoadSchedulerConfigFromByteArray(content []byte) (*SchedulerConfig, error)
 			zap.Error(err))
 		return nil, err
 	}
+	// resolve the templates before validating: the partitions created are validated like any other partition
+	err = resolveTemplates(conf)
+	if err != nil {
+		log.Logger().Error("partition template resolution failed",
+			zap.Error(err))
+		return nil, err
+	}
+	// apply the profiles after the templates: a template can set the profile of the partitions created
+	err = resolveProfiles(conf)
+	if err != nil {
+		log.Logger().Error("configuration profile resolution failed",
+			zap.Error(err))
+		return nil, err
+	}
+	// convert the resources before validating: the validation and the scheduler use the scheduler units
+	err = convertResourceUnits(conf)
+	if err != nil {
+		log.Logger().Error("resource unit conversion failed",
+			zap.Error(err))
+		return nil, err
+	}
 	// validate the config
 	err = Validate(conf)
 	if err != nil {
@@ -162,6 +390,13 @@ func LoadSchedulerConfigFromByteArray(content []byte) (*SchedulerConfig, error)
 	return conf, err
 }
 
+// Export the partition configurations as YAML.
+// The output is canonical for the same input: map keys are sorted and empty values are left out.
+// Resource quantities are exported in configuration units, see convertResourceUnits.
+func ExportSchedulerConfig(partitions []PartitionConfig) ([]byte, error) {
+	return exportWithResourceUnits(partitions)
+}
+
 func loadSchedulerConfigFromFile(policyGroup string) (*SchedulerConfig, error) {
 	filePath := resolveConfigurationFileFunc(policyGroup)
 	log.Logger().Debug("loading configuration",
@@ -589,6 +589,1057 @@ partitions:
 	}
 }
 
+func TestPartitionPreemptionGracePeriod(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      enabled: true
+      graceperiod: 30s
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].Preemption.GracePeriod != "30s" {
+		t.Errorf("grace period not parsed correctly: %s", conf.Partitions[0].Preemption.GracePeriod)
+	}
+
+	for _, gracePeriod := range []string{"thirty", "-30s"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      enabled: true
+      graceperiod: ` + gracePeriod + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid grace period '%s' should have failed: %v", gracePeriod, conf)
+		}
+	}
+}
+
+func TestPreemptionMinRuntime(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      enabled: true
+      minruntime: 2m
+      emergencypriority: 100
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	preemption := conf.Partitions[0].Preemption
+	if preemption.MinRuntime != "2m" || preemption.EmergencyPriority != 100 {
+		t.Errorf("minimum runtime not parsed correctly: %v", preemption)
+	}
+
+	for _, invalid := range []string{"minruntime: two", "minruntime: -2m", "emergencypriority: -1"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      ` + invalid + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid minimum runtime setting '%s' should have failed: %v", invalid, conf)
+		}
+	}
+}
+
+func TestPreemptionConfirmTimeout(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      enabled: true
+      confirmtimeout: 30s
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].Preemption.ConfirmTimeout != "30s" {
+		t.Errorf("confirm timeout not parsed correctly: %v", conf.Partitions[0].Preemption)
+	}
+
+	for _, confirmTimeout := range []string{"thirty", "-30s"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      confirmtimeout: ` + confirmTimeout + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid confirm timeout '%s' should have failed: %v", confirmTimeout, conf)
+		}
+	}
+}
+
+func TestPreemptionSettlePeriod(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      enabled: true
+      settleperiod: 5m
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].Preemption.SettlePeriod != "5m" {
+		t.Errorf("settle period not parsed correctly: %v", conf.Partitions[0].Preemption)
+	}
+
+	for _, settlePeriod := range []string{"five", "-5m"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      settleperiod: ` + settlePeriod + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid settle period '%s' should have failed: %v", settlePeriod, conf)
+		}
+	}
+}
+
+func TestPreemptionUtilization(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      utilization:
+        trigger: 90
+        release: 75
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	utilization := conf.Partitions[0].Preemption.Utilization
+	if utilization.Trigger != 90 || utilization.Release != 75 {
+		t.Errorf("utilization trigger not parsed correctly: %v", utilization)
+	}
+
+	for _, trigger := range []string{"trigger: 101", "trigger: -1", "trigger: 80\n        release: 80", "release: 50", "trigger: 80\n        release: -5"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    preemption:
+      utilization:
+        ` + trigger + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid utilization trigger '%s' should have failed: %v", trigger, conf)
+		}
+	}
+}
+
+func TestPartitionReservationLimits(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    reservations:
+      maxreservations: 10
+      maxresource:
+        memory: 1000
+      staleage: 5m
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].Reservations.MaxReservations != 10 || conf.Partitions[0].Reservations.MaxResource["memory"] != "1000" {
+		t.Errorf("reservation limits not parsed correctly: %v", conf.Partitions[0].Reservations)
+	}
+	if conf.Partitions[0].Reservations.StaleAge != "5m" {
+		t.Errorf("stale reservation age not parsed correctly: %v", conf.Partitions[0].Reservations)
+	}
+
+	for _, limit := range []string{"maxreservations: -1", "maxresource:\n        memory: lots", "staleage: 0s", "staleage: -1m", "staleage: soon"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    reservations:
+      ` + limit + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid reservation limit '%s' should have failed: %v", limit, conf)
+		}
+	}
+}
+
+func TestPartitionAskBudget(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    askbudget:
+      maxtime: 50ms
+      maxnodes: 500
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].AskBudget.MaxTime != "50ms" || conf.Partitions[0].AskBudget.MaxNodes != 500 {
+		t.Errorf("ask budget not parsed correctly: %v", conf.Partitions[0].AskBudget)
+	}
+
+	for _, budget := range []string{"maxnodes: -1", "maxtime: 0s", "maxtime: -1ms", "maxtime: fast"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    askbudget:
+      ` + budget + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid ask budget '%s' should have failed: %v", budget, conf)
+		}
+	}
+}
+
+func TestPartitionMaintenance(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    maintenance:
+      - start: 2026-10-18T02:00:00Z
+        duration: 2h
+        capacity: 20
+        leadtime: 1h
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if len(conf.Partitions[0].Maintenance) != 1 {
+		t.Fatalf("maintenance not parsed correctly: %v", conf.Partitions[0].Maintenance)
+	}
+	window := conf.Partitions[0].Maintenance[0]
+	if window.Start != "2026-10-18T02:00:00Z" || window.Duration != "2h" || window.Capacity != 20 || window.LeadTime != "1h" {
+		t.Errorf("maintenance not parsed correctly: %v", window)
+	}
+
+	for _, maintenance := range []string{
+		"{start: tomorrow, duration: 2h, capacity: 20}",
+		"{start: 2026-10-18T02:00:00Z, capacity: 20}",
+		"{start: 2026-10-18T02:00:00Z, duration: 0s, capacity: 20}",
+		"{start: 2026-10-18T02:00:00Z, duration: 2h, capacity: 0}",
+		"{start: 2026-10-18T02:00:00Z, duration: 2h, capacity: 120}",
+		"{start: 2026-10-18T02:00:00Z, duration: 2h, capacity: 20, leadtime: -1h}",
+	} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    maintenance:
+      - ` + maintenance + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid maintenance '%s' should have failed: %v", maintenance, conf)
+		}
+	}
+}
+
+func TestPartitionNodeQuarantine(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    nodequarantine:
+      changes: 3
+      window: 5m
+      cooldown: 30m
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	quarantine := conf.Partitions[0].NodeQuarantine
+	if quarantine.Changes != 3 || quarantine.Window != "5m" || quarantine.CoolDown != "30m" {
+		t.Errorf("node quarantine not parsed correctly: %v", quarantine)
+	}
+
+	for _, quarantine := range []string{
+		"{changes: -1}",
+		"{changes: 3, window: often}",
+		"{changes: 3, window: 0s}",
+		"{changes: 3, cooldown: -5m}",
+	} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    nodequarantine: ` + quarantine + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid node quarantine '%s' should have failed: %v", quarantine, conf)
+		}
+	}
+}
+
+func TestNodeSortResourceWeights(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    nodesortpolicy:
+      type: binpacking
+      resourceweights:
+        gpu: 10
+        vcore: 0.5
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	weights := conf.Partitions[0].NodeSortPolicy.ResourceWeights
+	if weights["gpu"] != 10 || weights["vcore"] != 0.5 {
+		t.Errorf("node sort resource weights not parsed correctly: %v", weights)
+	}
+
+	for _, weight := range []string{"gpu: -1", "gpu: 0", "gpu: many"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    nodesortpolicy:
+      resourceweights:
+        ` + weight + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid resource weight '%s' should have failed: %v", weight, conf)
+		}
+	}
+}
+
+func TestPartitionAutoscale(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    autoscale:
+      watermark:
+        vcore: 10
+      threshold: 30s
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].Autoscale.Watermark["vcore"] != "10" || conf.Partitions[0].Autoscale.Threshold != "30s" {
+		t.Errorf("autoscale config not parsed correctly: %v", conf.Partitions[0].Autoscale)
+	}
+
+	for _, autoscale := range []string{"watermark:\n        vcore: lots", "threshold: 0s", "threshold: -1m", "threshold: soon"} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    autoscale:
+      ` + autoscale + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid autoscale config '%s' should have failed: %v", autoscale, conf)
+		}
+	}
+}
+
+func TestPartitionNodePools(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    nodepools:
+      attribute: si.io/node-pool
+    queues:
+      - name: root
+        queues:
+          - name: batch
+            nodepools:
+              - name: spot
+                max:
+                  memory: 1000
+              - name: default
+            queues:
+              - name: low
+                nodepools:
+                  - name: spot
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].NodePools.Attribute != "si.io/node-pool" {
+		t.Errorf("node pool attribute not parsed correctly: %v", conf.Partitions[0].NodePools)
+	}
+	batch := conf.Partitions[0].Queues[0].Queues[0]
+	if len(batch.NodePools) != 2 || batch.NodePools[0].Name != "spot" || batch.NodePools[0].Max["memory"] != "1000" {
+		t.Errorf("queue node pools not parsed correctly: %v", batch.NodePools)
+	}
+
+	for _, pools := range []string{
+		"- name: ''",
+		"- name: spot\n                  - name: spot",
+		"- name: gpu",
+		"- name: spot\n                    max:\n                      memory: lots",
+	} {
+		data = `
+partitions:
+  - name: default
+    nodepools:
+      attribute: si.io/node-pool
+    queues:
+      - name: root
+        queues:
+          - name: batch
+            nodepools:
+              - name: spot
+              - name: default
+            queues:
+              - name: low
+                nodepools:
+                  ` + pools + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid node pools '%s' should have failed: %v", pools, conf)
+		}
+	}
+
+	// pools other than the default pool need the attribute
+	data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: batch
+            nodepools:
+              - name: spot
+`
+	conf, err = CreateConfig(data)
+	if err == nil {
+		t.Errorf("node pool without attribute should have failed: %v", conf)
+	}
+}
+
+func TestPartitionQueueIdleTimeout(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queueidletimeout: 10m
+    queues:
+      - name: root
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].QueueIdleTimeout != "10m" {
+		t.Errorf("queue idle timeout not parsed correctly: %s", conf.Partitions[0].QueueIdleTimeout)
+	}
+
+	for _, timeout := range []string{"idle", "-1m"} {
+		data = `
+partitions:
+  - name: default
+    queueidletimeout: ` + timeout + `
+    queues:
+      - name: root
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid queue idle timeout '%s' should have failed: %v", timeout, conf)
+		}
+	}
+}
+
+func TestPartitionQueueDrain(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queuedraintimeout: 30m
+    queuedraintarget: root.fallback
+    queues:
+      - name: root
+        queues:
+          - name: fallback
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].QueueDrainTimeout != "30m" || conf.Partitions[0].QueueDrainTarget != "root.fallback" {
+		t.Errorf("queue drain not parsed correctly: %v", conf.Partitions[0])
+	}
+
+	for _, drain := range []string{"queuedraintimeout: never", "queuedraintimeout: -1m", "queuedraintarget: root.fallback",
+		"queuedraintimeout: 1m\n    queuedraintarget: root.unknown", "queuedraintimeout: 1m\n    queuedraintarget: root",
+		"queuedraintimeout: 1m\n    queuedraintarget: root.parent"} {
+		data = `
+partitions:
+  - name: default
+    ` + drain + `
+    queues:
+      - name: root
+        queues:
+          - name: fallback
+          - name: parent
+            parent: true
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid queue drain setting '%s' should have failed: %v", drain, conf)
+		}
+	}
+}
+
+func TestPartitionAppCompletion(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    appcompletiongraceperiod: 5m
+    queues:
+      - name: root
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].AppCompletionGracePeriod != "5m" {
+		t.Errorf("application completion grace period not parsed correctly: %s", conf.Partitions[0].AppCompletionGracePeriod)
+	}
+
+	for _, grace := range []string{"never", "-1m"} {
+		data = `
+partitions:
+  - name: default
+    appcompletiongraceperiod: ` + grace + `
+    queues:
+      - name: root
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid application completion grace period '%s' should have failed: %v", grace, conf)
+		}
+	}
+}
+
+func TestPartitionThroughput(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    throughput:
+      enabled: true
+      samplesize: 50
+    queues:
+      - name: root
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	throughput := conf.Partitions[0].Throughput
+	if !throughput.Enabled || throughput.SampleSize != 50 {
+		t.Errorf("throughput mode not parsed correctly: %v", throughput)
+	}
+
+	data = `
+partitions:
+  - name: default
+    throughput:
+      enabled: true
+      samplesize: -1
+    queues:
+      - name: root
+`
+	conf, err = CreateConfig(data)
+	if err == nil {
+		t.Errorf("negative throughput sample size should have failed: %v", conf)
+	}
+}
+
+func TestIgnoredResourceTypes(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    ignoredresourcetypes:
+      - ephemeral-storage
+    queues:
+      - name: root
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	ignored := conf.Partitions[0].IgnoredResourceTypes
+	if len(ignored) != 1 || ignored[0] != "ephemeral-storage" {
+		t.Errorf("ignored resource types not parsed correctly: %v", ignored)
+	}
+
+	for _, types := range []string{
+		"- ''",
+		"- storage\n      - storage",
+		"- cpu",
+	} {
+		data = `
+partitions:
+  - name: default
+    resourcealiases:
+      cpu: vcore
+    ignoredresourcetypes:
+      ` + types + `
+    queues:
+      - name: root
+`
+		conf, err = LoadSchedulerConfigFromByteArray([]byte(data))
+		if err == nil {
+			t.Errorf("invalid ignored resource types '%s' should have failed: %v", types, conf)
+		}
+	}
+}
+
+func TestPartitionHierarchy(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    hierarchy:
+      maxdepth: 2
+      maxchildren: 2
+    queues:
+      - name: root
+        queues:
+          - name: a
+            queues:
+              - name: b
+          - name: c
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	hierarchy := conf.Partitions[0].Hierarchy
+	if hierarchy.MaxDepth != 2 || hierarchy.MaxChildren != 2 {
+		t.Errorf("hierarchy limits not parsed correctly: %v", hierarchy)
+	}
+
+	for _, limits := range []string{
+		"maxdepth: -1",
+		"maxchildren: -1",
+		"maxdepth: 1",
+		"maxchildren: 1",
+	} {
+		data = `
+partitions:
+  - name: default
+    hierarchy:
+      ` + limits + `
+    queues:
+      - name: root
+        queues:
+          - name: a
+            queues:
+              - name: b
+          - name: c
+`
+		conf, err = LoadSchedulerConfigFromByteArray([]byte(data))
+		if err == nil {
+			t.Errorf("invalid or exceeded hierarchy limits '%s' should have failed: %v", limits, conf)
+		}
+	}
+}
+
+func TestResourceAliases(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    resourcealiases:
+      cpu: vcore
+      nvidia.com/gpu: gpu
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	aliases := conf.Partitions[0].ResourceAliases
+	if len(aliases) != 2 || aliases["cpu"] != "vcore" || aliases["nvidia.com/gpu"] != "gpu" {
+		t.Errorf("resource aliases not parsed correctly: %v", aliases)
+	}
+
+	for _, alias := range []string{
+		"cpu: cpu",
+		"cpu: ''",
+		"cpu: vcore\n      vcore: core",
+	} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    resourcealiases:
+      ` + alias + `
+`
+		if _, err = LoadSchedulerConfigFromByteArray([]byte(data)); err == nil {
+			t.Errorf("resource aliases '%s' should have failed", alias)
+		}
+	}
+}
+
+func TestPartitionUserGroups(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    usergroups:
+      type: static
+      cachettl: 10m
+      failedttl: 1m
+      groups:
+        user1:
+          - group1
+          - group2
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	userGroups := conf.Partitions[0].UserGroups
+	if userGroups.Type != "static" || userGroups.CacheTTL != "10m" || userGroups.FailedTTL != "1m" {
+		t.Errorf("user group resolver not parsed correctly: %v", userGroups)
+	}
+	if len(userGroups.Groups["user1"]) != 2 || userGroups.Groups["user1"][1] != "group2" {
+		t.Errorf("static user groups not parsed correctly: %v", userGroups.Groups)
+	}
+
+	for _, resolver := range []string{
+		"type: ldap",
+		"type: os\n      cachettl: soon",
+		"type: plugin\n      failedttl: 10ms",
+		"type: os\n      groups:\n        user1:\n          - group1",
+		"type: static\n      groups:\n        user1:\n          - group.1",
+		"type: static\n      groups:\n        '1user':\n          - group1",
+	} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+    usergroups:
+      ` + resolver + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid user group resolver '%s' should have failed: %v", resolver, conf)
+		}
+	}
+}
+
+func TestQueueSoftMax(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: batch
+            resources:
+              max:
+                memory: 1000
+              softmax:
+                memory: 800
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].Queues[0].Queues[0].Resources.SoftMax["memory"] != "800" {
+		t.Errorf("soft max not parsed correctly: %v", conf.Partitions[0].Queues[0].Queues[0].Resources)
+	}
+
+	for _, res := range []string{
+		"softmax:\n                memory: lots",
+		"softmax:\n                memory: 1200",
+		"softmax:\n                vcore: 1",
+	} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: batch
+            resources:
+              max:
+                memory: 1000
+              ` + res + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid soft max '%s' should have failed: %v", res, conf)
+		}
+	}
+
+	// root must not have a soft max
+	data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        resources:
+          softmax:
+            memory: 800
+`
+	conf, err = CreateConfig(data)
+	if err == nil {
+		t.Errorf("soft max on root should have failed: %v", conf)
+	}
+}
+
+func TestQueueMaxAllocation(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: batch
+            resources:
+              max:
+                memory: 1000
+              maxallocation:
+                memory: 100
+                vcore: 10
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].Queues[0].Queues[0].Resources.MaxAllocation["memory"] != "100" {
+		t.Errorf("max allocation not parsed correctly: %v", conf.Partitions[0].Queues[0].Queues[0].Resources)
+	}
+
+	for _, res := range []string{
+		"maxallocation:\n                memory: lots",
+		"maxallocation:\n                memory: 1200",
+	} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: batch
+            resources:
+              max:
+                memory: 1000
+              ` + res + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid max allocation '%s' should have failed: %v", res, conf)
+		}
+	}
+
+	// root must not have a max allocation
+	data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        resources:
+          maxallocation:
+            memory: 100
+`
+	conf, err = CreateConfig(data)
+	if err == nil {
+		t.Errorf("max allocation on root should have failed: %v", conf)
+	}
+}
+
+func TestQueueChildTemplate(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    placementrules:
+      - name: user
+        create: true
+        value: root.users
+    queues:
+      - name: root
+        queues:
+          - name: users
+            parent: true
+            childtemplate:
+              maxapplications: 5
+              resources:
+                max:
+                  memory: 1000
+              properties:
+                application.sort.policy: fifo
+              submitacl: "user1 group1"
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	template := conf.Partitions[0].Queues[0].Queues[0].ChildTemplate
+	if template.IsEmpty() || template.MaxApplications != 5 || template.Resources.Max["memory"] != "1000" ||
+		template.Properties["application.sort.policy"] != "fifo" || template.SubmitACL != "user1 group1" {
+		t.Errorf("child template not parsed correctly: %v", template)
+	}
+
+	for _, queue := range []string{
+		// invalid resources
+		"name: users\n            parent: true\n            childtemplate:\n              resources:\n                max:\n                  memory: lots",
+		// template on a leaf queue
+		"name: users\n            childtemplate:\n              maxapplications: 5",
+		// invalid ACL
+		"name: users\n            parent: true\n            childtemplate:\n              adminacl: \"user1 group1 extra\"",
+	} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - ` + queue + `
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid child template '%s' should have failed: %v", queue, conf)
+		}
+	}
+}
+
+func TestQueueDerivedGuarantee(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: parent
+            properties:
+              queue.guaranteed.derived: "true"
+            resources:
+              max:
+                memory: 10
+            queues:
+              - name: leaf1
+                resources:
+                  guaranteed:
+                    memory: 5
+              - name: sub
+                properties:
+                  queue.guaranteed.derived: "false"
+                resources:
+                  guaranteed:
+                    memory: 5
+                queues:
+                  - name: leaf2
+                    resources:
+                      guaranteed:
+                        memory: 8
+`
+	conf, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("should expect no error %v", err)
+	}
+	if conf.Partitions[0].Queues[0].Queues[0].Properties[QueueGuaranteedDerived] != "true" {
+		t.Errorf("derived guarantee not parsed correctly: %v", conf.Partitions[0].Queues[0].Queues[0].Properties)
+	}
+
+	for _, queue := range []string{
+		// guarantee set on the deriving queue
+		"resources:\n              guaranteed:\n                memory: 5",
+		// derived guarantee over the max
+		"resources:\n              max:\n                memory: 8",
+		// derived guarantee type missing from the max
+		"resources:\n              max:\n                vcore: 10",
+	} {
+		data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: parent
+            properties:
+              queue.guaranteed.derived: "true"
+            ` + queue + `
+            queues:
+              - name: leaf1
+                resources:
+                  guaranteed:
+                    memory: 5
+              - name: leaf2
+                resources:
+                  guaranteed:
+                    memory: 4
+`
+		conf, err = CreateConfig(data)
+		if err == nil {
+			t.Errorf("invalid derived guarantee '%s' should have failed: %v", queue, conf)
+		}
+	}
+}
+
 func TestParseRule(t *testing.T) {
 	data := `
 partitions:
@@ -694,6 +1745,8 @@ partitions:
   - name: default
     queues:
       - name: root
+        queues:
+          - name: default
     placementrules:
       - name: fixed
         value: default
@@ -798,6 +1851,95 @@ partitions:
 	}
 }
 
+func TestPlacementRuleQueues(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: Parent
+            parent: true
+            queues:
+              - name: leaf
+    placementrules:
+      - name: fixed
+        value: root.parent.leaf
+      - name: fixed
+        value: Leaf
+        parent:
+          name: fixed
+          value: parent
+      - name: provided
+        parent:
+          name: fixed
+          value: root.parent
+      - name: fixed
+        value: dynamic
+        create: true
+      - name: user
+        parent:
+          name: tag
+          value: namespace
+`
+	_, err := CreateConfig(data)
+	if err != nil {
+		t.Fatalf("rules referencing defined queues should not have failed: %v", err)
+	}
+
+	// removing a queue that a rule references fails
+	var tests = []struct {
+		name  string
+		rules string
+		index int
+		rule  string
+		queue string
+	}{
+		{"qualified fixed", `
+      - name: fixed
+        value: root.parent.leaf
+      - name: fixed
+        value: root.parent.removed`, 1, "fixed", "root.parent.removed"},
+		{"fixed with fixed parent", `
+      - name: fixed
+        value: removed
+        parent:
+          name: fixed
+          value: parent`, 0, "fixed", "root.parent.removed"},
+		{"provided with fixed parent", `
+      - name: provided
+        create: true
+        parent:
+          name: fixed
+          value: root.removed`, 0, "fixed", "root.removed"},
+		{"unqualified fixed", `
+      - name: fixed
+        value: leaf`, 0, "fixed", "root.leaf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err = CreateConfig(`
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: parent
+            parent: true
+            queues:
+              - name: leaf
+    placementrules:` + tt.rules)
+			ruleErr, ok := err.(*PlacementRuleQueueError)
+			if !ok {
+				t.Fatalf("expected placement rule queue error, got: %v", err)
+			}
+			if ruleErr.Partition != "default" || ruleErr.Rule != tt.index || ruleErr.Name != tt.rule || ruleErr.Queue != tt.queue {
+				t.Errorf("unexpected placement rule queue error: %+v", ruleErr)
+			}
+		})
+	}
+}
+
 func TestRecurseParent(t *testing.T) {
 	data := `
 partitions:
@@ -23,6 +23,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -34,6 +35,7 @@ import (
 const (
 	RootQueue        = "root"
 	DefaultPartition = "default"
+	DefaultNodePool  = "default"
 )
 
 // A queue can be a username with the dot replaced. Most systems allow a 32 character user name.
@@ -100,6 +102,44 @@ func checkResources(resource Resources) error {
 			return fmt.Errorf("max resource total is '%d', or parsing failed: %v", total, err)
 		}
 	}
+	// check soft max resources: must not be larger than the max
+	if len(resource.SoftMax) != 0 {
+		total, err := checkResource(resource.SoftMax)
+		if err != nil || total == 0 {
+			return fmt.Errorf("soft max resource total is '%d', or parsing failed: %v", total, err)
+		}
+		if len(resource.Max) != 0 {
+			for name, val := range resource.SoftMax {
+				// both values are parsed already, a type missing from the max is a max of 0
+				soft, _ := strconv.ParseInt(val, 10, 64)
+				var max int64
+				if maxVal, ok := resource.Max[name]; ok {
+					max, _ = strconv.ParseInt(maxVal, 10, 64)
+				}
+				if soft > max {
+					return fmt.Errorf("soft max resource %s (%d) is larger than the max (%d)", name, soft, max)
+				}
+			}
+		}
+	}
+	// check max allocation resources: a type that is limited by the max must not be larger than the max
+	if len(resource.MaxAllocation) != 0 {
+		if _, err := checkResource(resource.MaxAllocation); err != nil {
+			return fmt.Errorf("max allocation resource parsing failed: %v", err)
+		}
+		for name, val := range resource.MaxAllocation {
+			maxVal, ok := resource.Max[name]
+			if !ok {
+				continue
+			}
+			// both values are parsed already
+			maxAlloc, _ := strconv.ParseInt(val, 10, 64)
+			max, _ := strconv.ParseInt(maxVal, 10, 64)
+			if maxAlloc > max {
+				return fmt.Errorf("max allocation resource %s (%d) is larger than the max (%d)", name, maxAlloc, max)
+			}
+		}
+	}
 	return nil
 }
 
@@ -121,6 +161,92 @@ func checkPlacementRules(partition *PartitionConfig) error {
 	return nil
 }
 
+// A placement rule that depends on a queue that is not defined in the queue hierarchy of the partition.
+// The rule cannot create the queue: a queue must not be removed from the config while a rule still references it.
+type PlacementRuleQueueError struct {
+	Partition string
+	Rule      int    // position of the top level rule in the placement rules of the partition
+	Name      string // name of the rule that references the queue, this can be a parent rule
+	Queue     string // fully qualified name of the queue
+}
+
+func (e *PlacementRuleQueueError) Error() string {
+	return fmt.Sprintf("placement rule %d (%s) in partition %s references queue %s which is not defined and cannot be created by the rule",
+		e.Rule, e.Name, e.Partition, e.Queue)
+}
+
+// Check that the queues the placement rules depend on exist in the queue hierarchy.
+// Only the queues that are known from the config can be checked: the queue of a fixed rule and the parent queue of a
+// rule that has a fixed parent rule. Queues the rule is allowed to create are not checked.
+func checkPlacementRuleQueues(partition *PartitionConfig) error {
+	if len(partition.PlacementRules) == 0 {
+		return nil
+	}
+	queues := make(map[string]bool)
+	for _, queue := range partition.Queues {
+		getQueuePaths(queue, "", queues)
+	}
+	for i, rule := range partition.PlacementRules {
+		if err := checkPlacementRuleQueue(partition.Name, i, rule, queues); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check the queue of the rule and its parent rules.
+func checkPlacementRuleQueue(partitionName string, index int, rule PlacementRule, queues map[string]bool) error {
+	if rule.Parent != nil {
+		if err := checkPlacementRuleQueue(partitionName, index, *rule.Parent, queues); err != nil {
+			return err
+		}
+	}
+	if rule.Create {
+		return nil
+	}
+	if queue := getRuleQueue(rule); queue != "" && !queues[queue] {
+		return &PlacementRuleQueueError{
+			Partition: partitionName,
+			Rule:      index,
+			Name:      rule.Name,
+			Queue:     queue,
+		}
+	}
+	return nil
+}
+
+// Get the fully qualified queue name for a rule if it only depends on the config.
+// Returns an empty string if the queue depends on the application: the rule or one of its parents is not a fixed rule.
+// This follows the placement of a fixed rule: a queue that is not qualified is added to the parent, or the root.
+func getRuleQueue(rule PlacementRule) string {
+	if !strings.EqualFold(rule.Name, "fixed") || rule.Value == "" {
+		return ""
+	}
+	queue := strings.ToLower(rule.Value)
+	if strings.HasPrefix(queue, RootQueue) {
+		return queue
+	}
+	parent := RootQueue
+	if rule.Parent != nil {
+		if parent = getRuleQueue(*rule.Parent); parent == "" {
+			return ""
+		}
+	}
+	return parent + "." + queue
+}
+
+// Add the fully qualified, lower case, names of the queue and its children to the set.
+func getQueuePaths(queue QueueConfig, parentPath string, paths map[string]bool) {
+	path := strings.ToLower(queue.Name)
+	if parentPath != "" {
+		path = parentPath + "." + path
+	}
+	paths[path] = true
+	for _, child := range queue.Queues {
+		getQueuePaths(child, path, paths)
+	}
+}
+
 // Check the specific rule for syntax.
 // The create flag is checked automatically by the config parser and is not checked.
 func checkPlacementRule(rule PlacementRule) error {
@@ -244,9 +370,503 @@ func checkNodeSortingPolicy(partition *PartitionConfig) error {
 	configuredNodeSortingPolicy, err := common.FromString(policy.Type)
 
 	log.Logger().Info("Node sorting policy:", zap.Any("policy name", policy.Type), zap.Any("value", configuredNodeSortingPolicy))
+	if err != nil {
+		return err
+	}
+	if err = common.CheckResourceWeights(policy.ResourceWeights); err != nil {
+		return fmt.Errorf("invalid node sorting resource weights for partition %s: %v", partition.Name, err)
+	}
+	return nil
+}
+
+// Check the preemption config of the partition:
+// - the grace period must be a valid, not negative, duration
+// - the utilization trigger must be a percentage and the release must be set below the trigger
+// - the minimum runtime must be a valid, not negative, duration and the emergency priority must not be negative
+// - the confirm timeout must be a valid, not negative, duration
+func checkPreemption(partition *PartitionConfig) error {
+	if partition.Preemption.GracePeriod != "" {
+		gracePeriod, err := time.ParseDuration(partition.Preemption.GracePeriod)
+		if err != nil {
+			return fmt.Errorf("invalid preemption grace period '%s' for partition %s: %v", partition.Preemption.GracePeriod, partition.Name, err)
+		}
+		if gracePeriod < 0 {
+			return fmt.Errorf("negative preemption grace period '%s' for partition %s", partition.Preemption.GracePeriod, partition.Name)
+		}
+	}
+	utilization := partition.Preemption.Utilization
+	if utilization.Trigger < 0 || utilization.Trigger > 100 {
+		return fmt.Errorf("preemption utilization trigger %d for partition %s must be a percentage", utilization.Trigger, partition.Name)
+	}
+	if utilization.Release < 0 {
+		return fmt.Errorf("negative preemption utilization release %d for partition %s", utilization.Release, partition.Name)
+	}
+	if utilization.Release != 0 && utilization.Release >= utilization.Trigger {
+		return fmt.Errorf("preemption utilization release %d for partition %s must be below the trigger %d", utilization.Release, partition.Name, utilization.Trigger)
+	}
+	if partition.Preemption.MinRuntime != "" {
+		minRuntime, err := time.ParseDuration(partition.Preemption.MinRuntime)
+		if err != nil {
+			return fmt.Errorf("invalid preemption minimum runtime '%s' for partition %s: %v", partition.Preemption.MinRuntime, partition.Name, err)
+		}
+		if minRuntime < 0 {
+			return fmt.Errorf("negative preemption minimum runtime '%s' for partition %s", partition.Preemption.MinRuntime, partition.Name)
+		}
+	}
+	if partition.Preemption.SettlePeriod != "" {
+		settlePeriod, err := time.ParseDuration(partition.Preemption.SettlePeriod)
+		if err != nil {
+			return fmt.Errorf("invalid preemption settle period '%s' for partition %s: %v", partition.Preemption.SettlePeriod, partition.Name, err)
+		}
+		if settlePeriod < 0 {
+			return fmt.Errorf("negative preemption settle period '%s' for partition %s", partition.Preemption.SettlePeriod, partition.Name)
+		}
+	}
+	if partition.Preemption.EmergencyPriority < 0 {
+		return fmt.Errorf("negative preemption emergency priority %d for partition %s", partition.Preemption.EmergencyPriority, partition.Name)
+	}
+	if partition.Preemption.ConfirmTimeout != "" {
+		confirmTimeout, err := time.ParseDuration(partition.Preemption.ConfirmTimeout)
+		if err != nil {
+			return fmt.Errorf("invalid preemption confirm timeout '%s' for partition %s: %v", partition.Preemption.ConfirmTimeout, partition.Name, err)
+		}
+		if confirmTimeout < 0 {
+			return fmt.Errorf("negative preemption confirm timeout '%s' for partition %s", partition.Preemption.ConfirmTimeout, partition.Name)
+		}
+	}
+	return nil
+}
+
+// Check the reservation limits of the partition: the count must not be negative, the resource must parse and
+// the stale age must be a valid, positive, duration
+func checkReservations(partition *PartitionConfig) error {
+	if partition.Reservations.MaxReservations < 0 {
+		return fmt.Errorf("negative maximum number of reservations %d for partition %s", partition.Reservations.MaxReservations, partition.Name)
+	}
+	if len(partition.Reservations.MaxResource) != 0 {
+		if _, err := checkResource(partition.Reservations.MaxResource); err != nil {
+			return fmt.Errorf("invalid maximum reservation resource for partition %s: %v", partition.Name, err)
+		}
+	}
+	if partition.Reservations.StaleAge != "" {
+		staleAge, err := time.ParseDuration(partition.Reservations.StaleAge)
+		if err != nil {
+			return fmt.Errorf("invalid stale reservation age '%s' for partition %s: %v", partition.Reservations.StaleAge, partition.Name, err)
+		}
+		if staleAge <= 0 {
+			return fmt.Errorf("stale reservation age '%s' for partition %s must be positive", partition.Reservations.StaleAge, partition.Name)
+		}
+	}
+	return nil
+}
+
+// Check the ask budget of the partition: the node count must not be negative and the time must be a valid, positive,
+// duration
+func checkAskBudget(partition *PartitionConfig) error {
+	if partition.AskBudget.MaxNodes < 0 {
+		return fmt.Errorf("negative ask budget node count %d for partition %s", partition.AskBudget.MaxNodes, partition.Name)
+	}
+	if partition.AskBudget.MaxTime != "" {
+		maxTime, err := time.ParseDuration(partition.AskBudget.MaxTime)
+		if err != nil {
+			return fmt.Errorf("invalid ask budget time '%s' for partition %s: %v", partition.AskBudget.MaxTime, partition.Name, err)
+		}
+		if maxTime <= 0 {
+			return fmt.Errorf("ask budget time '%s' for partition %s must be positive", partition.AskBudget.MaxTime, partition.Name)
+		}
+	}
+	return nil
+}
+
+// Check the hierarchy limits of the partition: the limits must not be negative and the configured queues must fit in
+// the limits.
+func checkHierarchy(partition *PartitionConfig) error {
+	limits := partition.Hierarchy
+	if limits.MaxDepth < 0 {
+		return fmt.Errorf("negative maximum queue depth %d for partition %s", limits.MaxDepth, partition.Name)
+	}
+	if limits.MaxChildren < 0 {
+		return fmt.Errorf("negative maximum child queues %d for partition %s", limits.MaxChildren, partition.Name)
+	}
+	return checkQueueHierarchy(&partition.Queues[0], strings.ToLower(partition.Queues[0].Name), 0, limits)
+}
+
+// Check the depth and the number of children of the queue and its children recursively against the limits.
+func checkQueueHierarchy(queue *QueueConfig, path string, depth int, limits PartitionHierarchyConfig) error {
+	if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+		return fmt.Errorf("queue %s has a depth of %d, the maximum depth is %d", path, depth, limits.MaxDepth)
+	}
+	if limits.MaxChildren > 0 && len(queue.Queues) > limits.MaxChildren {
+		return fmt.Errorf("queue %s has %d child queues, the maximum is %d", path, len(queue.Queues), limits.MaxChildren)
+	}
+	for i := range queue.Queues {
+		child := &queue.Queues[i]
+		if err := checkQueueHierarchy(child, path+"."+strings.ToLower(child.Name), depth+1, limits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check the node quarantine of the partition: the number of changes must not be negative and the window and cool
+// down must be valid, positive, durations
+func checkNodeQuarantine(partition *PartitionConfig) error {
+	quarantine := partition.NodeQuarantine
+	if quarantine.Changes < 0 {
+		return fmt.Errorf("negative node quarantine changes %d for partition %s", quarantine.Changes, partition.Name)
+	}
+	if err := checkQuarantineDuration("window", quarantine.Window, partition.Name); err != nil {
+		return err
+	}
+	return checkQuarantineDuration("cool down", quarantine.CoolDown, partition.Name)
+}
+
+// Check a node quarantine duration, an empty value means the default is used
+func checkQuarantineDuration(name, value, partitionName string) error {
+	if value == "" {
+		return nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid node quarantine %s '%s' for partition %s: %v", name, value, partitionName, err)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("node quarantine %s '%s' for partition %s must be positive", name, value, partitionName)
+	}
+	return nil
+}
+
+// Check the maintenance windows of the partition: the start must be a valid time, the duration must be a valid,
+// positive, duration and the capacity must be a percentage. The lead time is optional and cannot be negative.
+func checkMaintenance(partition *PartitionConfig) error {
+	for _, window := range partition.Maintenance {
+		if _, err := time.Parse(time.RFC3339, window.Start); err != nil {
+			return fmt.Errorf("invalid maintenance start '%s' for partition %s: %v", window.Start, partition.Name, err)
+		}
+		duration, err := time.ParseDuration(window.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid maintenance duration '%s' for partition %s: %v", window.Duration, partition.Name, err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("maintenance duration '%s' for partition %s must be positive", window.Duration, partition.Name)
+		}
+		if window.Capacity <= 0 || window.Capacity > 100 {
+			return fmt.Errorf("maintenance capacity %d for partition %s must be a percentage", window.Capacity, partition.Name)
+		}
+		if window.LeadTime != "" {
+			var leadTime time.Duration
+			leadTime, err = time.ParseDuration(window.LeadTime)
+			if err != nil {
+				return fmt.Errorf("invalid maintenance lead time '%s' for partition %s: %v", window.LeadTime, partition.Name, err)
+			}
+			if leadTime < 0 {
+				return fmt.Errorf("maintenance lead time '%s' for partition %s cannot be negative", window.LeadTime, partition.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Check the autoscale config of the partition: the watermark must be a valid resource and the threshold must be a
+// valid, positive, duration
+func checkAutoscale(partition *PartitionConfig) error {
+	if len(partition.Autoscale.Watermark) != 0 {
+		if _, err := checkResource(partition.Autoscale.Watermark); err != nil {
+			return fmt.Errorf("invalid autoscale watermark for partition %s: %v", partition.Name, err)
+		}
+	}
+	if partition.Autoscale.Threshold != "" {
+		threshold, err := time.ParseDuration(partition.Autoscale.Threshold)
+		if err != nil {
+			return fmt.Errorf("invalid autoscale threshold '%s' for partition %s: %v", partition.Autoscale.Threshold, partition.Name, err)
+		}
+		if threshold <= 0 {
+			return fmt.Errorf("autoscale threshold '%s' for partition %s must be positive", partition.Autoscale.Threshold, partition.Name)
+		}
+	}
+	return nil
+}
+
+// Check the idle timeout for unmanaged queues of the partition: must be a valid, not negative, duration
+func checkQueueIdleTimeout(partition *PartitionConfig) error {
+	if partition.QueueIdleTimeout == "" {
+		return nil
+	}
+	timeout, err := time.ParseDuration(partition.QueueIdleTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid queue idle timeout '%s' for partition %s: %v", partition.QueueIdleTimeout, partition.Name, err)
+	}
+	if timeout < 0 {
+		return fmt.Errorf("negative queue idle timeout '%s' for partition %s", partition.QueueIdleTimeout, partition.Name)
+	}
+	return nil
+}
+
+// Check the drain timeout for managed queues of the partition: must be a valid, not negative, duration.
+// The drain target must be a leaf queue in the queue hierarchy and is only allowed with a timeout.
+func checkQueueDrain(partition *PartitionConfig) error {
+	if partition.QueueDrainTimeout == "" {
+		if partition.QueueDrainTarget != "" {
+			return fmt.Errorf("queue drain target '%s' for partition %s requires a drain timeout", partition.QueueDrainTarget, partition.Name)
+		}
+		return nil
+	}
+	timeout, err := time.ParseDuration(partition.QueueDrainTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid queue drain timeout '%s' for partition %s: %v", partition.QueueDrainTimeout, partition.Name, err)
+	}
+	if timeout < 0 {
+		return fmt.Errorf("negative queue drain timeout '%s' for partition %s", partition.QueueDrainTimeout, partition.Name)
+	}
+	if partition.QueueDrainTarget == "" {
+		return nil
+	}
+	var target *QueueConfig
+	for i := range partition.Queues {
+		if target = findQueueConfig(&partition.Queues[i], "", strings.ToLower(partition.QueueDrainTarget)); target != nil {
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("queue drain target '%s' for partition %s is not a configured queue", partition.QueueDrainTarget, partition.Name)
+	}
+	if target.Parent || len(target.Queues) > 0 {
+		return fmt.Errorf("queue drain target '%s' for partition %s is not a leaf queue", partition.QueueDrainTarget, partition.Name)
+	}
+	return nil
+}
+
+// Find the queue with the lower case path in the queue hierarchy, nil if the queue is not found.
+func findQueueConfig(queue *QueueConfig, parentPath, path string) *QueueConfig {
+	queuePath := strings.ToLower(queue.Name)
+	if parentPath != "" {
+		queuePath = parentPath + "." + queuePath
+	}
+	if queuePath == path {
+		return queue
+	}
+	if !strings.HasPrefix(path, queuePath+".") {
+		return nil
+	}
+	for i := range queue.Queues {
+		if found := findQueueConfig(&queue.Queues[i], queuePath, path); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Check the grace period for the auto completion of applications of the partition: must be a valid, not negative, duration
+func checkAppCompletion(partition *PartitionConfig) error {
+	if partition.AppCompletionGracePeriod == "" {
+		return nil
+	}
+	grace, err := time.ParseDuration(partition.AppCompletionGracePeriod)
+	if err != nil {
+		return fmt.Errorf("invalid application completion grace period '%s' for partition %s: %v", partition.AppCompletionGracePeriod, partition.Name, err)
+	}
+	if grace < 0 {
+		return fmt.Errorf("negative application completion grace period '%s' for partition %s", partition.AppCompletionGracePeriod, partition.Name)
+	}
+	return nil
+}
+
+// Check the throughput mode of the partition: the sample size must not be negative
+func checkThroughput(partition *PartitionConfig) error {
+	if partition.Throughput.SampleSize < 0 {
+		return fmt.Errorf("negative throughput sample size %d for partition %s", partition.Throughput.SampleSize, partition.Name)
+	}
+	return nil
+}
+
+// Check the resource types ignored in the node fit checks of the partition: a type must be set, must not be listed
+// twice and must not be an alias, asks and nodes only use the canonical type
+func checkIgnoredResourceTypes(partition *PartitionConfig) error {
+	seen := make(map[string]bool)
+	for _, resType := range partition.IgnoredResourceTypes {
+		if resType == "" {
+			return fmt.Errorf("empty ignored resource type for partition %s", partition.Name)
+		}
+		if seen[resType] {
+			return fmt.Errorf("duplicate ignored resource type '%s' for partition %s", resType, partition.Name)
+		}
+		if canonical, ok := partition.ResourceAliases[resType]; ok {
+			return fmt.Errorf("ignored resource type '%s' for partition %s is an alias, use '%s'", resType, partition.Name, canonical)
+		}
+		seen[resType] = true
+	}
+	return nil
+}
+
+// Check the resource type aliases of the partition:
+// - alias and canonical type must be set and must be different
+// - aliases cannot be chained: a canonical type cannot be an alias itself
+func checkResourceAliases(partition *PartitionConfig) error {
+	for alias, canonical := range partition.ResourceAliases {
+		if alias == "" || canonical == "" {
+			return fmt.Errorf("empty resource alias '%s' or type '%s' for partition %s", alias, canonical, partition.Name)
+		}
+		if alias == canonical {
+			return fmt.Errorf("resource alias '%s' maps to itself for partition %s", alias, partition.Name)
+		}
+		if _, ok := partition.ResourceAliases[canonical]; ok {
+			return fmt.Errorf("resource alias '%s' maps to alias '%s' for partition %s", alias, canonical, partition.Name)
+		}
+	}
+	return nil
+}
+
+// Check the user group resolver of the partition:
+// - the type must be a known resolver
+// - the cache times must be valid, positive, durations
+// - groups can only be set for the static resolver and must use valid user and group names
+func checkUserGroupResolver(partition *PartitionConfig) error {
+	conf := partition.UserGroups
+	switch conf.Type {
+	case security.NoResolver, security.OSResolver, security.StaticResolver, security.PluginResolver:
+	default:
+		return fmt.Errorf("unknown user group resolver '%s' for partition %s", conf.Type, partition.Name)
+	}
+	for _, ttl := range []string{conf.CacheTTL, conf.FailedTTL} {
+		if ttl == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			return fmt.Errorf("invalid user group cache time '%s' for partition %s: %v", ttl, partition.Name, err)
+		}
+		if duration < time.Second {
+			return fmt.Errorf("user group cache time '%s' for partition %s must be at least one second", ttl, partition.Name)
+		}
+	}
+	if len(conf.Groups) != 0 && conf.Type != security.StaticResolver {
+		return fmt.Errorf("user groups set for partition %s without the static resolver", partition.Name)
+	}
+	for user, groups := range conf.Groups {
+		if !UserRegExp.MatchString(user) {
+			return fmt.Errorf("invalid user name '%s' in user groups for partition %s", user, partition.Name)
+		}
+		for _, group := range groups {
+			if !GroupRegExp.MatchString(group) {
+				return fmt.Errorf("invalid group name '%s' for user %s in partition %s", group, user, partition.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Check the node pools configured on the queues:
+// - pool names must be set and unique for the queue
+// - the max resources per pool must be parseable
+// - a queue can only use pools its parent can use
+// - pools other than the default pool need the node pool attribute set for the partition
+func checkNodePools(partition *PartitionConfig) error {
+	return checkQueueNodePools(&partition.Queues[0], nil, partition.NodePools.Attribute != "")
+}
+
+// Check the node pools of the queue and its children recursively.
+// The parentPools are the pools the parent can use, nil if the parent is not restricted.
+func checkQueueNodePools(queue *QueueConfig, parentPools map[string]bool, hasAttribute bool) error {
+	pools := parentPools
+	if len(queue.NodePools) != 0 {
+		pools = make(map[string]bool)
+		for _, pool := range queue.NodePools {
+			if pool.Name == "" {
+				return fmt.Errorf("node pool name not set for queue %s", queue.Name)
+			}
+			if pools[pool.Name] {
+				return fmt.Errorf("duplicate node pool %s for queue %s", pool.Name, queue.Name)
+			}
+			if parentPools != nil && !parentPools[pool.Name] {
+				return fmt.Errorf("node pool %s for queue %s is not available to the parent queue", pool.Name, queue.Name)
+			}
+			if pool.Name != DefaultNodePool && !hasAttribute {
+				return fmt.Errorf("node pool %s for queue %s requires the node pool attribute to be set for the partition", pool.Name, queue.Name)
+			}
+			if len(pool.Max) != 0 {
+				if _, err := checkResource(pool.Max); err != nil {
+					return fmt.Errorf("invalid max resource for node pool %s of queue %s: %v", pool.Name, queue.Name, err)
+				}
+			}
+			pools[pool.Name] = true
+		}
+	}
+	for i := range queue.Queues {
+		if err := checkQueueNodePools(&queue.Queues[i], pools, hasAttribute); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check the parent queues that derive their guaranteed resource from their children:
+// - the queue must not set a guaranteed resource itself
+// - the sum of the guarantees of the children must fit in the max of the queue
+// The property is inherited, a child that sets it to false uses its own guarantee again.
+func checkDerivedGuarantees(partition *PartitionConfig) error {
+	_, err := checkDerivedGuarantee(&partition.Queues[0], false)
 	return err
 }
 
+// Check the derived guarantee of the queue and its children recursively.
+// Returns the guaranteed resource of the queue: configured or derived.
+func checkDerivedGuarantee(queue *QueueConfig, inherited bool) (map[string]int64, error) {
+	derived := inherited
+	if value, ok := queue.Properties[QueueGuaranteedDerived]; ok {
+		derived = strings.EqualFold(strings.TrimSpace(value), "true")
+	}
+	guaranteed := make(map[string]int64)
+	for i := range queue.Queues {
+		childGuaranteed, err := checkDerivedGuarantee(&queue.Queues[i], derived)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range childGuaranteed {
+			guaranteed[name] += value
+		}
+	}
+	// a leaf queue has nothing to derive from and uses its own guarantee
+	if !derived || (!queue.Parent && len(queue.Queues) == 0) {
+		guaranteed = make(map[string]int64)
+		for name, value := range queue.Resources.Guaranteed {
+			// the resources are parsed already
+			guaranteed[name], _ = strconv.ParseInt(value, 10, 64)
+		}
+		return guaranteed, nil
+	}
+	if len(queue.Resources.Guaranteed) != 0 {
+		return nil, fmt.Errorf("queue %s derives its guaranteed resource from its children and must not set it", queue.Name)
+	}
+	if len(queue.Resources.Max) != 0 {
+		for name, value := range guaranteed {
+			// a type missing from the max is a max of 0
+			var max int64
+			if maxVal, ok := queue.Resources.Max[name]; ok {
+				max, _ = strconv.ParseInt(maxVal, 10, 64)
+			}
+			if value > max {
+				return nil, fmt.Errorf("derived guaranteed resource %s (%d) of queue %s is larger than the max (%d)", name, value, queue.Name, max)
+			}
+		}
+	}
+	return guaranteed, nil
+}
+
+// Check the properties of the queue: known properties must have a valid value.
+// Unknown properties are logged and passed through, they could be used by a plugin.
+func checkQueueProperties(props map[string]string, queueName string) error {
+	unknown, err := CheckQueueProperties(props)
+	if err != nil {
+		return fmt.Errorf("queue %s: %v", queueName, err)
+	}
+	for _, name := range unknown {
+		log.Logger().Warn("unknown queue property, passing through",
+			zap.String("queueName", queueName),
+			zap.String("property", name))
+	}
+	return nil
+}
+
 // Check the queue names configured for compliance and uniqueness
 // - no duplicate names at each branched level in the tree
 // - queue name is alphanumeric (case ignore) with - and _
@@ -274,6 +894,30 @@ func checkQueues(queue *QueueConfig, level int) error {
 		return err
 	}
 
+	// check the properties for this queue (if defined)
+	if err = checkQueueProperties(queue.Properties, queue.Name); err != nil {
+		return err
+	}
+
+	// check the child template (if defined): only a parent queue can have children created below it
+	if !queue.ChildTemplate.IsEmpty() {
+		if !queue.Parent && len(queue.Queues) == 0 {
+			return fmt.Errorf("child template set on leaf queue %s", queue.Name)
+		}
+		if err = checkResources(queue.ChildTemplate.Resources); err != nil {
+			return fmt.Errorf("invalid child template for queue %s: %v", queue.Name, err)
+		}
+		if err = checkQueueProperties(queue.ChildTemplate.Properties, queue.Name); err != nil {
+			return fmt.Errorf("invalid child template for queue %s: %v", queue.Name, err)
+		}
+		if err = checkACL(queue.ChildTemplate.AdminACL); err != nil {
+			return fmt.Errorf("invalid child template for queue %s: %v", queue.Name, err)
+		}
+		if err = checkACL(queue.ChildTemplate.SubmitACL); err != nil {
+			return fmt.Errorf("invalid child template for queue %s: %v", queue.Name, err)
+		}
+	}
+
 	// check this level for name compliance and uniqueness
 	queueMap := make(map[string]bool)
 	for _, child := range queue.Queues {
@@ -341,7 +985,8 @@ func checkQueuesStructure(partition *PartitionConfig) error {
 	// check name uniqueness: we have a root to start with directly
 	var rootQueue = partition.Queues[0]
 	// special check for root resources: must not be set
-	if rootQueue.Resources.Guaranteed != nil || rootQueue.Resources.Max != nil {
+	if rootQueue.Resources.Guaranteed != nil || rootQueue.Resources.Max != nil || rootQueue.Resources.SoftMax != nil ||
+		rootQueue.Resources.MaxAllocation != nil {
 		return fmt.Errorf("root queue must not have resource limits set")
 	}
 	return checkQueues(&rootQueue, 1)
@@ -356,6 +1001,7 @@ func checkQueuesStructure(partition *PartitionConfig) error {
 // For the sub components:
 // - The queue config is syntax checked
 // - The placement rules are syntax checked
+// - The queues the placement rules depend on are defined in the queue config
 // - The user objects are syntax checked
 func Validate(newConfig *SchedulerConfig) error {
 	if newConfig == nil {
@@ -383,6 +1029,10 @@ func Validate(newConfig *SchedulerConfig) error {
 		if err != nil {
 			return err
 		}
+		err = checkPlacementRuleQueues(&partition)
+		if err != nil {
+			return err
+		}
 		err = checkLimits(partition.Limits, partition.Name)
 		if err != nil {
 			return err
@@ -391,6 +1041,70 @@ func Validate(newConfig *SchedulerConfig) error {
 		if err != nil {
 			return err
 		}
+		err = checkPreemption(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkReservations(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkAutoscale(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkAskBudget(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkMaintenance(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkNodeQuarantine(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkNodePools(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkDerivedGuarantees(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkUserGroupResolver(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkQueueIdleTimeout(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkQueueDrain(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkAppCompletion(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkThroughput(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkResourceAliases(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkIgnoredResourceTypes(&partition)
+		if err != nil {
+			return err
+		}
+		err = checkHierarchy(&partition)
+		if err != nil {
+			return err
+		}
 		// write back the partition to keep changes
 		newConfig.Partitions[i] = partition
 	}
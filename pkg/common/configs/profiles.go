@@ -0,0 +1,160 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package configs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Names of the configuration profiles that can be used as the base of a partition
+const (
+	ProfileDevelopment = "development"
+	ProfileProduction  = "production"
+	ProfileBatch       = "batch"
+)
+
+// Get the partition configuration of the named profile, the second value is false if the profile does not exist.
+// A new configuration is returned on each call: the caller can modify it.
+// A profile only sets the scheduling behaviour, settings that depend on the cluster like node pools, resource
+// aliases or user group resolution are never part of a profile.
+func getProfile(name string) (PartitionConfig, bool) {
+	switch strings.ToLower(name) {
+	case ProfileDevelopment:
+		// single tenant: everyone can submit, queues are created on demand and nodes are filled up
+		return PartitionConfig{
+			Queues: []QueueConfig{{
+				Name:       RootQueue,
+				Parent:     true,
+				SubmitACL:  "*",
+				Properties: map[string]string{ApplicationSortPolicy: "fifo"},
+			}},
+			PlacementRules: []PlacementRule{
+				{Name: "provided", Create: true},
+				{Name: "fixed", Value: "default", Create: true},
+			},
+			NodeSortPolicy:   NodeSortingPolicy{Type: "binpacking"},
+			QueueIdleTimeout: "5m",
+		}, true
+	case ProfileProduction:
+		// multi tenant: fair sharing between applications and queues, preemption restores the guarantees
+		return PartitionConfig{
+			Queues: []QueueConfig{{
+				Name:       RootQueue,
+				Parent:     true,
+				Properties: map[string]string{ApplicationSortPolicy: "fair"},
+			}},
+			Preemption: PartitionPreemptionConfig{
+				Enabled:     true,
+				GracePeriod: "30s",
+				MinRuntime:  "1m",
+			},
+			NodeSortPolicy: NodeSortingPolicy{Type: "fair"},
+			Reservations: PartitionReservationConfig{
+				MaxReservations: 100,
+				StaleAge:        "10m",
+			},
+			QueueIdleTimeout: "30m",
+		}, true
+	case ProfileBatch:
+		// large numbers of short lived allocations: fill up nodes and limit the time spent on one ask
+		return PartitionConfig{
+			Queues: []QueueConfig{{
+				Name:       RootQueue,
+				Parent:     true,
+				Properties: map[string]string{ApplicationSortPolicy: "fifo"},
+			}},
+			NodeSortPolicy: NodeSortingPolicy{Type: "binpacking"},
+			AskBudget: PartitionAskBudgetConfig{
+				MaxTime:  "10ms",
+				MaxNodes: 500,
+			},
+			QueueIdleTimeout: "1h",
+		}, true
+	}
+	return PartitionConfig{}, false
+}
+
+// Apply the profiles set for the partitions: a value set in the partition overrides the value of the profile.
+// The profile name is kept in the partition, applying a profile more than once does not change the partition.
+func resolveProfiles(conf *SchedulerConfig) error {
+	for i := range conf.Partitions {
+		partition := &conf.Partitions[i]
+		if partition.Profile == "" {
+			continue
+		}
+		profile, ok := getProfile(partition.Profile)
+		if !ok {
+			return fmt.Errorf("unknown configuration profile %s for partition %s", partition.Profile, partition.Name)
+		}
+		applyProfile(partition, profile)
+	}
+	return nil
+}
+
+// Merge the profile into the partition. Lists are taken from the profile only if the partition does not set them,
+// maps are merged per key and other values are taken from the profile if the partition does not set them.
+// A boolean set in the profile cannot be switched off in the partition.
+func applyProfile(partition *PartitionConfig, profile PartitionConfig) {
+	applyProfileRoot(partition, profile.Queues[0])
+	if len(partition.PlacementRules) == 0 {
+		partition.PlacementRules = profile.PlacementRules
+	}
+	partition.Preemption.Enabled = partition.Preemption.Enabled || profile.Preemption.Enabled
+	partition.Preemption.GracePeriod = profileString(partition.Preemption.GracePeriod, profile.Preemption.GracePeriod)
+	partition.Preemption.MinRuntime = profileString(partition.Preemption.MinRuntime, profile.Preemption.MinRuntime)
+	partition.NodeSortPolicy.Type = profileString(partition.NodeSortPolicy.Type, profile.NodeSortPolicy.Type)
+	if partition.Reservations.MaxReservations == 0 {
+		partition.Reservations.MaxReservations = profile.Reservations.MaxReservations
+	}
+	partition.Reservations.StaleAge = profileString(partition.Reservations.StaleAge, profile.Reservations.StaleAge)
+	partition.AskBudget.MaxTime = profileString(partition.AskBudget.MaxTime, profile.AskBudget.MaxTime)
+	if partition.AskBudget.MaxNodes == 0 {
+		partition.AskBudget.MaxNodes = profile.AskBudget.MaxNodes
+	}
+	partition.QueueIdleTimeout = profileString(partition.QueueIdleTimeout, profile.QueueIdleTimeout)
+}
+
+// Merge the root queue of the profile into the root queue of the partition.
+// The root queue is inserted if the partition does not define it, the same as the validation does.
+func applyProfileRoot(partition *PartitionConfig, root QueueConfig) {
+	if len(partition.Queues) != 1 || strings.ToLower(partition.Queues[0].Name) != RootQueue {
+		root.Queues = partition.Queues
+		partition.Queues = []QueueConfig{root}
+		return
+	}
+	partitionRoot := &partition.Queues[0]
+	partitionRoot.SubmitACL = profileString(partitionRoot.SubmitACL, root.SubmitACL)
+	partitionRoot.AdminACL = profileString(partitionRoot.AdminACL, root.AdminACL)
+	if partitionRoot.Properties == nil {
+		partitionRoot.Properties = make(map[string]string)
+	}
+	for key, value := range root.Properties {
+		if _, ok := partitionRoot.Properties[key]; !ok {
+			partitionRoot.Properties[key] = value
+		}
+	}
+}
+
+func profileString(value, profile string) string {
+	if value == "" {
+		return profile
+	}
+	return value
+}
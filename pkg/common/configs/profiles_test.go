@@ -0,0 +1,103 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package configs
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestProfiles(t *testing.T) {
+	// all profiles must give a valid configuration without any other settings
+	for _, name := range []string{ProfileDevelopment, ProfileProduction, ProfileBatch} {
+		t.Run(name, func(t *testing.T) {
+			data := "partitions:\n  - name: default\n    profile: " + name + "\n"
+			conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+			assert.NilError(t, err, "profile config should load")
+			assert.Equal(t, len(conf.Partitions[0].Queues), 1, "expected the root queue from the profile")
+			assert.Equal(t, conf.Partitions[0].Queues[0].Name, RootQueue)
+		})
+	}
+	_, ok := getProfile("unknown")
+	assert.Assert(t, !ok, "unknown profile should not be found")
+}
+
+func TestProfileOverrides(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    profile: production
+    queues:
+      - name: root
+        properties:
+          application.sort.policy: fifo
+        queues:
+          - name: tenant1
+    nodesortpolicy:
+      type: binpacking
+    preemption:
+      graceperiod: 2m
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.NilError(t, err, "profile config should load")
+	partition := conf.Partitions[0]
+	// values set in the partition override the profile
+	assert.Equal(t, partition.Queues[0].Properties["application.sort.policy"], "fifo", "root property should not be replaced")
+	assert.Equal(t, partition.NodeSortPolicy.Type, "binpacking", "node sort policy should not be replaced")
+	assert.Equal(t, partition.Preemption.GracePeriod, "2m", "grace period should not be replaced")
+	assert.Equal(t, partition.Queues[0].Queues[0].Name, "tenant1", "queues should not be replaced")
+	// values not set are taken from the profile
+	assert.Assert(t, partition.Preemption.Enabled, "preemption should be enabled by the profile")
+	assert.Equal(t, partition.Preemption.MinRuntime, "1m", "minimum runtime should be set by the profile")
+	assert.Equal(t, partition.Reservations.MaxReservations, 100, "maximum reservations should be set by the profile")
+	assert.Equal(t, partition.QueueIdleTimeout, "30m", "idle timeout should be set by the profile")
+
+	// applying the profile again does not change the partition
+	applied := partition
+	profile, _ := getProfile(ProfileProduction)
+	applyProfile(&applied, profile)
+	assert.DeepEqual(t, applied, partition)
+}
+
+func TestProfileRootInserted(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    profile: development
+    queues:
+      - name: team1
+      - name: team2
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.NilError(t, err, "profile config should load")
+	root := conf.Partitions[0].Queues[0]
+	assert.Equal(t, root.Name, RootQueue, "root queue should be inserted")
+	assert.Equal(t, root.SubmitACL, "*", "root ACL should be set by the profile")
+	assert.Equal(t, len(root.Queues), 2, "queues should be moved below the root")
+	assert.Equal(t, len(conf.Partitions[0].PlacementRules), 2, "placement rules should be set by the profile")
+}
+
+func TestProfileUnknown(t *testing.T) {
+	data := "partitions:\n  - name: default\n    profile: unknown\n    queues:\n      - name: root\n"
+	_, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.Assert(t, err != nil, "unknown profile should fail")
+	assert.Assert(t, strings.Contains(err.Error(), "unknown configuration profile"), "unexpected error: %v", err)
+}
@@ -0,0 +1,321 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package configs
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// The queue properties known to the scheduler.
+const (
+	// How to sort applications, valid options are fifo / fair / sjf (shortest job first) / priority
+	ApplicationSortPolicy = "application.sort.policy"
+	// How a parent queue sorts its child queues, valid options are fair (usage against the guarantee) / fairshare
+	// (usage against the hierarchical fair share)
+	QueueSortPolicy = "queue.sort.policy"
+	// How long an application with pending asks waits without an allocation before it moves up in the order of the
+	// leaf queue, a duration like 5m. The application moves up one step for each period it waits.
+	ApplicationSortAging = "application.sort.aging"
+	// Delay after the queue becomes active before allocations are made, a duration like 30s
+	QueueStartDelay = "queue.start.delay"
+	// How far the queue can exceed its guarantee using unused capacity of its siblings, a percentage of the
+	// guarantee like 50%
+	QueueBorrowLimit = "queue.borrow.limit"
+	// How long the node of a released allocation is preferred for a similar ask of the same application, a duration
+	// like 30s
+	AllocationReuseTTL = "allocation.reuse.ttl"
+	// Lowest and highest priority of the asks of the applications in the queue, an integer. The priority of an ask
+	// outside the range is clamped to the range.
+	ApplicationPriorityFloor   = "application.priority.floor"
+	ApplicationPriorityCeiling = "application.priority.ceiling"
+	// Maximum combined resource of the applications of one application group in the queue, a resource like
+	// [memory:1000 vcore:10]. Applications join a group using an application tag.
+	ApplicationGroupMax = "application.group.max"
+	// Resource merged into the asks of the applications in the queue that do not request a resource type, a resource
+	// like [memory:512 vcore:500]. A type requested by the ask keeps the value of the ask.
+	AskDefaultResource = "ask.default.resource"
+	// Weights of the resource types used to sort the nodes for the applications in the queue, like gpu:10 vcore:1.
+	// Overrides the resource weights of the partition node sorting policy.
+	NodeSortResourceWeights = "node.sort.resource.weights"
+	// Queues the allocations of the queue avoid sharing a node with, a comma separated list of queue names like
+	// root.batch,root.etl. A queue covers its children, a name that is not fully qualified is placed under the root.
+	QueueAntiAffinity = "queue.anti.affinity"
+	// How the anti affinity is applied: soft prefers other nodes (default), hard never uses the node.
+	QueueAntiAffinityMode = "queue.anti.affinity.mode"
+	// How the max resource of the queue is enforced: hard blocks allocations over the max (default), soft allows
+	// allocations over the max up to the tolerance and flags them.
+	QueueMaxEnforcement = "queue.max.enforcement"
+	// How far the queue can exceed its max resource with soft enforcement, a percentage of the max like 10%
+	QueueMaxTolerance = "queue.max.tolerance"
+	// Where preemption for the queue can take victims from: partition allows all queues (default), queue contains
+	// preemption to the queues below the highest parent that sets the queue scope.
+	PreemptionScope = "preemption.scope"
+	// Allocations of the queue are never selected as preemption victims, true or false (default).
+	PreemptionDisabled = "preemption.disabled"
+	// Maximum number of allocations of a leaf queue in one scheduling cycle, a number like 10 or a percentage of the
+	// allocations of the cycle like 25%. A queue that reaches the cap is skipped for the rest of the cycle.
+	QueueCycleAllocations = "queue.cycle.allocations"
+	// Weight of the queue compared to its siblings, a positive number like 2 or 0.5 (default 1). Siblings share the
+	// capacity of the parent proportionally to their weight.
+	QueueWeight = "queue.weight"
+	// Maximum allocated resource of one user in the queue, a resource like [memory:1000 vcore:10] or a percentage of
+	// the max resource of the queue like 25%. Allocations of a user over the maximum are blocked.
+	QueueUserMax = "queue.user.max"
+	// Priority of the queue compared to its siblings, an integer (default 0). Higher priority queues are offered
+	// resources first, queues with the same priority are sorted by the sort policy of the parent.
+	QueuePriority = "queue.priority"
+	// The guaranteed resource of a parent queue is the sum of the guarantees of its children, true or false (default).
+	// The queue must not set a guaranteed resource itself, the derived guarantee must fit in the max of the queue.
+	QueueGuaranteedDerived = "queue.guaranteed.derived"
+)
+
+// The preemption scopes of a queue
+const (
+	PreemptionScopePartition = "partition"
+	PreemptionScopeQueue     = "queue"
+)
+
+// The max resource enforcement modes of a queue
+const (
+	MaxEnforcementHard = "hard"
+	MaxEnforcementSoft = "soft"
+)
+
+// The anti affinity modes of a queue
+const (
+	AntiAffinitySoft = "soft"
+	AntiAffinityHard = "hard"
+)
+
+// The check of the value of a known queue property, returns an error describing why the value is invalid.
+type propertyCheck func(value string) error
+
+// The registry of the queue properties known to the scheduler.
+// Properties that are not in the registry are passed through unchecked: plugins can use their own properties.
+var queueProperties = map[string]propertyCheck{
+	ApplicationSortPolicy:      checkPropertyOption(false, "fifo", "fair", "sjf", "priority"),
+	QueueSortPolicy:            checkPropertyOption(false, "fair", "fairshare"),
+	ApplicationSortAging:       checkPropertyDuration,
+	QueueStartDelay:            checkPropertyDuration,
+	QueueBorrowLimit:           checkPropertyPercentage,
+	AllocationReuseTTL:         checkPropertyDuration,
+	ApplicationPriorityFloor:   checkPropertyPriority,
+	ApplicationPriorityCeiling: checkPropertyPriority,
+	ApplicationGroupMax:        checkPropertyResource,
+	AskDefaultResource:         checkPropertyPositiveResource,
+	NodeSortResourceWeights:    checkPropertyWeights,
+	QueueAntiAffinity:          checkPropertyQueueList,
+	QueueAntiAffinityMode:      checkPropertyOption(true, AntiAffinitySoft, AntiAffinityHard),
+	QueueMaxEnforcement:        checkPropertyOption(true, MaxEnforcementHard, MaxEnforcementSoft),
+	QueueMaxTolerance:          checkPropertyPercentage,
+	PreemptionScope:            checkPropertyOption(true, PreemptionScopePartition, PreemptionScopeQueue),
+	PreemptionDisabled:         checkPropertyOption(true, "true", "false"),
+	QueueCycleAllocations:      checkPropertyCycleAllocations,
+	QueueWeight:                checkPropertyWeight,
+	QueueUserMax:               checkPropertyUserMax,
+	QueuePriority:              checkPropertyPriority,
+	QueueGuaranteedDerived:     checkPropertyOption(true, "true", "false"),
+}
+
+// Return the sorted names of the queue properties known to the scheduler.
+func KnownQueueProperties() []string {
+	names := make([]string, 0, len(queueProperties))
+	for name := range queueProperties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Return true if the queue property is known to the scheduler.
+func IsKnownQueueProperty(name string) bool {
+	_, ok := queueProperties[name]
+	return ok
+}
+
+// Check the queue properties: the value of each known property must be valid.
+// Returns the sorted names of the properties that are not known, these are passed through unchecked.
+func CheckQueueProperties(props map[string]string) ([]string, error) {
+	var unknown []string
+	for name, value := range props {
+		check, ok := queueProperties[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if err := check(value); err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for queue property %s: %v", value, name, err)
+		}
+	}
+	if floor, ok := props[ApplicationPriorityFloor]; ok {
+		if ceiling, ok := props[ApplicationPriorityCeiling]; ok {
+			// both values are valid integers here
+			floorValue, _ := strconv.ParseInt(strings.TrimSpace(floor), 10, 32)
+			ceilingValue, _ := strconv.ParseInt(strings.TrimSpace(ceiling), 10, 32)
+			if floorValue > ceilingValue {
+				return nil, fmt.Errorf("queue property %s %d is above %s %d",
+					ApplicationPriorityFloor, floorValue, ApplicationPriorityCeiling, ceilingValue)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// Check that the value is one of the options.
+func checkPropertyOption(ignoreCase bool, options ...string) propertyCheck {
+	return func(value string) error {
+		value = strings.TrimSpace(value)
+		if ignoreCase {
+			value = strings.ToLower(value)
+		}
+		for _, option := range options {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(options, ", "))
+	}
+}
+
+// Check that the value is a duration that is not negative.
+func checkPropertyDuration(value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if duration < 0 {
+		return fmt.Errorf("must not be negative")
+	}
+	return nil
+}
+
+// Check that the value is a percentage that is not negative, the percent sign is optional.
+func checkPropertyPercentage(value string) error {
+	percentage, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), "%"), 10, 64)
+	if err != nil {
+		return err
+	}
+	if percentage < 0 {
+		return fmt.Errorf("must not be negative")
+	}
+	return nil
+}
+
+// Check that the value is a priority.
+func checkPropertyPriority(value string) error {
+	_, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	return err
+}
+
+// Check that the value is a percentage between 1 and 100, or a resource with at least one resource type.
+func checkPropertyUserMax(value string) error {
+	value = strings.TrimSpace(value)
+	if !strings.HasSuffix(value, "%") {
+		return checkPropertyResource(value)
+	}
+	percentage, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 64)
+	if err != nil {
+		return err
+	}
+	if percentage <= 0 || percentage > 100 {
+		return fmt.Errorf("percentage must be between 1 and 100")
+	}
+	return nil
+}
+
+// Check that the value is a resource with at least one resource type.
+func checkPropertyResource(value string) error {
+	res, err := resources.ParseResource(value)
+	if err != nil {
+		return err
+	}
+	if len(res.Resources) == 0 {
+		return fmt.Errorf("must have at least one resource type")
+	}
+	return nil
+}
+
+// Check that the value is a resource with at least one resource type, all quantities must be zero or positive and
+// at least one quantity must be positive.
+func checkPropertyPositiveResource(value string) error {
+	res, err := resources.ParseResource(value)
+	if err != nil {
+		return err
+	}
+	if !resources.StrictlyGreaterThanZero(res) {
+		return fmt.Errorf("must have a positive quantity and no negative quantities")
+	}
+	return nil
+}
+
+// Check that the value is a list of resource weights.
+func checkPropertyWeights(value string) error {
+	_, err := common.ParseResourceWeights(value)
+	return err
+}
+
+// Check that the value is a comma separated list of queue names, each part of a name must be a valid queue name.
+func checkPropertyQueueList(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		for _, part := range strings.Split(name, ".") {
+			if !QueueNameRegExp.MatchString(part) {
+				return fmt.Errorf("invalid queue name %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Check that the value is a positive number of allocations or a percentage of the allocations of the cycle.
+func checkPropertyCycleAllocations(value string) error {
+	trimmed := strings.TrimSpace(value)
+	cycleCap, err := strconv.Atoi(strings.TrimSuffix(trimmed, "%"))
+	if err != nil {
+		return err
+	}
+	if cycleCap <= 0 || (strings.HasSuffix(trimmed, "%") && cycleCap > 100) {
+		return fmt.Errorf("must be a positive number or a percentage")
+	}
+	return nil
+}
+
+// Check that the value is a positive number.
+func checkPropertyWeight(value string) error {
+	weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return err
+	}
+	if weight <= 0 || math.IsInf(weight, 0) || math.IsNaN(weight) {
+		return fmt.Errorf("must be a positive number")
+	}
+	return nil
+}
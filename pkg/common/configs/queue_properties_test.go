@@ -0,0 +1,139 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package configs
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestCheckQueueProperties(t *testing.T) {
+	valid := map[string]string{
+		ApplicationSortPolicy:      "priority",
+		QueueSortPolicy:            "fairshare",
+		ApplicationSortAging:       "5m",
+		QueueStartDelay:            "30s",
+		QueueBorrowLimit:           "50%",
+		AllocationReuseTTL:         "1m",
+		ApplicationPriorityFloor:   "-10",
+		ApplicationPriorityCeiling: "100",
+		ApplicationGroupMax:        "[memory:1000 vcore:10]",
+		AskDefaultResource:         "[memory:512 vcore:0]",
+		NodeSortResourceWeights:    "gpu:10 vcore:1",
+		QueueAntiAffinity:          "root.batch, etl",
+		QueueAntiAffinityMode:      "Hard",
+		QueueMaxEnforcement:        "soft",
+		QueueMaxTolerance:          "10",
+		PreemptionScope:            "queue",
+		PreemptionDisabled:         "True",
+		QueueCycleAllocations:      "25%",
+		QueueWeight:                "0.5",
+		QueueUserMax:               "25%",
+		QueuePriority:              "-5",
+		QueueGuaranteedDerived:     "TRUE",
+		"plugin.custom":            "anything",
+	}
+	unknown, err := CheckQueueProperties(valid)
+	assert.NilError(t, err, "valid properties should pass")
+	assert.DeepEqual(t, unknown, []string{"plugin.custom"})
+	assert.Equal(t, len(KnownQueueProperties()), len(valid)-1, "all known properties should be checked")
+
+	invalid := map[string]string{
+		ApplicationSortPolicy:      "random",
+		QueueSortPolicy:            "Fair",
+		ApplicationSortAging:       "-5m",
+		QueueStartDelay:            "-1s",
+		QueueBorrowLimit:           "half",
+		AllocationReuseTTL:         "soon",
+		ApplicationPriorityFloor:   "low",
+		ApplicationPriorityCeiling: "3000000000",
+		ApplicationGroupMax:        "[]",
+		AskDefaultResource:         "[memory:-512 vcore:500]",
+		NodeSortResourceWeights:    "gpu",
+		QueueAntiAffinity:          "root.b@tch",
+		QueueAntiAffinityMode:      "sometimes",
+		QueueMaxEnforcement:        "strict",
+		QueueMaxTolerance:          "-10%",
+		PreemptionScope:            "cluster",
+		PreemptionDisabled:         "yes",
+		QueueCycleAllocations:      "150%",
+		QueueWeight:                "0",
+		QueueUserMax:               "0%",
+		QueuePriority:              "high",
+		QueueGuaranteedDerived:     "sum",
+	}
+	for name, value := range invalid {
+		_, err = CheckQueueProperties(map[string]string{name: value})
+		assert.Assert(t, err != nil, "invalid value '%s' for %s should fail", value, name)
+	}
+
+	_, err = CheckQueueProperties(map[string]string{ApplicationPriorityFloor: "10", ApplicationPriorityCeiling: "5"})
+	assert.Assert(t, err != nil, "priority floor above the ceiling should fail")
+	_, err = CheckQueueProperties(map[string]string{QueueUserMax: "[memory:1000 vcore:10]"})
+	assert.NilError(t, err, "user max resource should pass")
+	for _, value := range []string{"150%", "[]", "half"} {
+		_, err = CheckQueueProperties(map[string]string{QueueUserMax: value})
+		assert.Assert(t, err != nil, "invalid value '%s' for %s should fail", value, QueueUserMax)
+	}
+	assert.Assert(t, IsKnownQueueProperty(QueueBorrowLimit), "borrow limit should be known")
+	assert.Assert(t, !IsKnownQueueProperty("plugin.custom"), "plugin property should not be known")
+}
+
+func TestValidateQueueProperties(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        properties:
+          plugin.custom: value
+        queues:
+          - name: leaf
+            properties:
+              application.sort.policy: random
+`
+	_, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.ErrorContains(t, err, "application.sort.policy")
+
+	data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        childtemplate:
+          properties:
+            queue.borrow.limit: half
+`
+	_, err = LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.ErrorContains(t, err, "child template")
+
+	data = `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        properties:
+          plugin.custom: value
+          application.sort.policy: priority
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.NilError(t, err, "valid and unknown properties should pass")
+	assert.Equal(t, conf.Partitions[0].Queues[0].Properties["plugin.custom"], "value", "unknown property should be passed through")
+}
@@ -0,0 +1,161 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package configs
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The placeholder that is always replaced with the name of the partition created from a template.
+const PartitionPlaceholder = "partition"
+
+var placeholderRegExp = regexp.MustCompile(`\$\{([^${}]*)\}`)
+
+// Resolve the partition templates into partitions: one partition is added per template instance.
+// The templates are removed from the configuration after resolution.
+// Instance names must be unique over all templates and must not clash with a partition defined directly.
+// All placeholders must be resolved, a placeholder without a value is an error.
+func resolveTemplates(conf *SchedulerConfig) error {
+	if len(conf.Templates) == 0 {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, partition := range conf.Partitions {
+		names[partition.Name] = true
+	}
+	templates := make(map[string]bool)
+	for _, template := range conf.Templates {
+		if template.Name == "" {
+			return fmt.Errorf("partition template name is not set")
+		}
+		if templates[template.Name] {
+			return fmt.Errorf("duplicate partition template name found: %s", template.Name)
+		}
+		templates[template.Name] = true
+		if len(template.Instances) == 0 {
+			return fmt.Errorf("partition template %s has no instances", template.Name)
+		}
+		content, err := yaml.Marshal(template.Partition)
+		if err != nil {
+			return fmt.Errorf("partition template %s cannot be processed: %v", template.Name, err)
+		}
+		for _, instance := range template.Instances {
+			if instance.Name == "" {
+				return fmt.Errorf("partition template %s has an instance without a name", template.Name)
+			}
+			if names[instance.Name] {
+				return fmt.Errorf("partition template %s instance %s: duplicate partition name", template.Name, instance.Name)
+			}
+			names[instance.Name] = true
+			partition, err := newPartitionFromTemplate(content, instance)
+			if err != nil {
+				return fmt.Errorf("partition template %s instance %s: %v", template.Name, instance.Name, err)
+			}
+			conf.Partitions = append(conf.Partitions, *partition)
+		}
+	}
+	conf.Templates = nil
+	return nil
+}
+
+// Create a partition from the marshalled template: unmarshalling gives each partition its own copy of the
+// template before the placeholders are replaced.
+func newPartitionFromTemplate(content []byte, instance TemplateInstance) (*PartitionConfig, error) {
+	partition := &PartitionConfig{}
+	if err := yaml.Unmarshal(content, partition); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for key, value := range instance.Values {
+		values[key] = value
+	}
+	values[PartitionPlaceholder] = instance.Name
+	if err := replacePlaceholders(reflect.ValueOf(partition).Elem(), values); err != nil {
+		return nil, err
+	}
+	partition.Name = instance.Name
+	return partition, nil
+}
+
+// Replace the placeholders in all strings of the value, walking structs, slices, maps and pointers.
+// Map keys are replaced as well as map values.
+func replacePlaceholders(value reflect.Value, values map[string]string) error {
+	switch value.Kind() {
+	case reflect.String:
+		replaced, err := replaceString(value.String(), values)
+		if err != nil {
+			return err
+		}
+		value.SetString(replaced)
+	case reflect.Ptr:
+		if !value.IsNil() {
+			return replacePlaceholders(value.Elem(), values)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if err := replacePlaceholders(value.Field(i), values); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < value.Len(); i++ {
+			if err := replacePlaceholders(value.Index(i), values); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+		replaced := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			key := reflect.New(value.Type().Key()).Elem()
+			key.Set(iter.Key())
+			entry := reflect.New(value.Type().Elem()).Elem()
+			entry.Set(iter.Value())
+			if err := replacePlaceholders(key, values); err != nil {
+				return err
+			}
+			if err := replacePlaceholders(entry, values); err != nil {
+				return err
+			}
+			replaced.SetMapIndex(key, entry)
+		}
+		value.Set(replaced)
+	}
+	return nil
+}
+
+func replaceString(str string, values map[string]string) (string, error) {
+	var err error
+	replaced := placeholderRegExp.ReplaceAllStringFunc(str, func(placeholder string) string {
+		key := placeholder[2 : len(placeholder)-1]
+		value, ok := values[key]
+		if !ok && err == nil {
+			err = fmt.Errorf("no value for placeholder %s", placeholder)
+		}
+		return value
+	})
+	return replaced, err
+}
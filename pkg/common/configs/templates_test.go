@@ -0,0 +1,123 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package configs
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestPartitionTemplates(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+templates:
+  - name: region
+    partition:
+      name: ignored
+      queues:
+        - name: root
+          submitacl: "${team}"
+          queues:
+            - name: ${partition}-batch
+              resources:
+                max:
+                  ${resource}: ${size}
+      placementrules:
+        - name: fixed
+          value: root.${partition}-batch
+    instances:
+      - name: us-east
+        values:
+          team: east
+          resource: memory
+          size: "100"
+      - name: us-west
+        values:
+          team: west
+          resource: vcore
+          size: "10"
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.NilError(t, err, "template config should load")
+	assert.Equal(t, len(conf.Partitions), 3, "expected the partition and two template instances")
+	assert.Equal(t, len(conf.Templates), 0, "templates should be removed after resolution")
+	assert.Equal(t, conf.Partitions[0].Name, "default")
+
+	east := conf.Partitions[1]
+	assert.Equal(t, east.Name, "us-east", "template partition name not replaced by instance")
+	assert.Equal(t, east.Queues[0].SubmitACL, "east")
+	assert.Equal(t, east.Queues[0].Queues[0].Name, "us-east-batch")
+	assert.DeepEqual(t, east.Queues[0].Queues[0].Resources.Max, map[string]string{"memory": "100"})
+	assert.Equal(t, east.PlacementRules[0].Value, "root.us-east-batch")
+
+	west := conf.Partitions[2]
+	assert.Equal(t, west.Name, "us-west")
+	assert.Equal(t, west.Queues[0].SubmitACL, "west")
+	assert.Equal(t, west.Queues[0].Queues[0].Name, "us-west-batch")
+	assert.DeepEqual(t, west.Queues[0].Queues[0].Resources.Max, map[string]string{"vcore": "10"})
+	assert.Equal(t, west.PlacementRules[0].Value, "root.us-west-batch")
+}
+
+func TestPartitionTemplatesFail(t *testing.T) {
+	var tests = []struct {
+		name      string
+		templates string
+		errMsg    string
+	}{
+		{
+			name:      "missing value",
+			templates: "  - name: t1\n    partition:\n      queues:\n        - name: ${queue}\n    instances:\n      - name: p1\n",
+			errMsg:    "no value for placeholder ${queue}",
+		},
+		{
+			name:      "no instances",
+			templates: "  - name: t1\n    partition:\n      queues:\n        - name: root\n",
+			errMsg:    "has no instances",
+		},
+		{
+			name:      "instance clashes with partition",
+			templates: "  - name: t1\n    partition:\n      queues:\n        - name: root\n    instances:\n      - name: default\n",
+			errMsg:    "duplicate partition name",
+		},
+		{
+			name: "duplicate template",
+			templates: "  - name: t1\n    partition:\n      queues:\n        - name: root\n    instances:\n      - name: p1\n" +
+				"  - name: t1\n    partition:\n      queues:\n        - name: root\n    instances:\n      - name: p2\n",
+			errMsg: "duplicate partition template name",
+		},
+		{
+			name:      "invalid partition",
+			templates: "  - name: t1\n    partition:\n      queues:\n        - name: root\n          resources:\n            max:\n              memory: ${size}\n    instances:\n      - name: p1\n        values:\n          size: large\n",
+			errMsg:    "root queue must not have resource limits set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := "partitions:\n  - name: default\n    queues:\n      - name: root\ntemplates:\n" + tt.templates
+			_, err := LoadSchedulerConfigFromByteArray([]byte(data))
+			assert.Assert(t, err != nil, "template config should have failed")
+			assert.Assert(t, strings.Contains(err.Error(), tt.errMsg), "unexpected error: %v", err)
+		})
+	}
+}
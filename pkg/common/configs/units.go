@@ -0,0 +1,327 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package configs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Convert the resource quantities of all partitions from configuration units into scheduler units.
+// A partition can define units for divisible resource types: the number of scheduler units in one configured unit,
+// for example vcore: 1000 makes the scheduler track vcore in milli units. The configuration uses the whole unit and
+// can contain decimals, "0.5" is converted into 500. Resource types without a unit are not converted.
+// The RM can use its own units, see RMUnitFactors, the quantities are converted when they pass the RM boundary.
+func convertResourceUnits(conf *SchedulerConfig) error {
+	for i := range conf.Partitions {
+		partition := &conf.Partitions[i]
+		if len(partition.ResourceUnits) == 0 && len(partition.RMResourceUnits) == 0 {
+			continue
+		}
+		if err := checkResourceUnits(partition); err != nil {
+			return err
+		}
+		if len(partition.ResourceUnits) == 0 {
+			continue
+		}
+		if err := walkPartitionResources(partition, func(res map[string]string) error {
+			return toSchedulerUnits(res, partition.ResourceUnits)
+		}); err != nil {
+			return fmt.Errorf("partition %s: %v", partition.Name, err)
+		}
+	}
+	return nil
+}
+
+// The unit must be a positive power of 10 to allow an exact conversion of the decimal configuration values.
+func checkResourceUnits(partition *PartitionConfig) error {
+	for name, unit := range partition.ResourceUnits {
+		if unit <= 0 || !isPowerOfTen(unit) {
+			return fmt.Errorf("resource unit %d for type '%s' must be a positive power of 10 for partition %s", unit, name, partition.Name)
+		}
+	}
+	// the RM unit cannot be smaller than the scheduler unit: the scheduler cannot track the RM quantities otherwise
+	for name, unit := range partition.RMResourceUnits {
+		if unit <= 0 || !isPowerOfTen(unit) {
+			return fmt.Errorf("RM resource unit %d for type '%s' must be a positive power of 10 for partition %s", unit, name, partition.Name)
+		}
+		if unit > schedulerUnit(partition, name) {
+			return fmt.Errorf("RM resource unit %d for type '%s' is smaller than the scheduler unit for partition %s", unit, name, partition.Name)
+		}
+	}
+	return nil
+}
+
+func schedulerUnit(partition *PartitionConfig, name string) int64 {
+	if unit, ok := partition.ResourceUnits[name]; ok {
+		return unit
+	}
+	return 1
+}
+
+// Return the number of scheduler units in one RM unit for the resource types the RM uses its own unit for.
+// Returns nil if the RM uses the scheduler units for all types. The partition must have passed the validation.
+func RMUnitFactors(partition *PartitionConfig) map[string]int64 {
+	var factors map[string]int64
+	for name, unit := range partition.RMResourceUnits {
+		factor := schedulerUnit(partition, name) / unit
+		if factor <= 1 {
+			continue
+		}
+		if factors == nil {
+			factors = make(map[string]int64)
+		}
+		factors[name] = factor
+	}
+	return factors
+}
+
+func isPowerOfTen(value int64) bool {
+	for value%10 == 0 {
+		value /= 10
+	}
+	return value == 1
+}
+
+// Export the partitions with the resource quantities converted back into configuration units.
+// The partitions passed in are not changed: the conversion is done on a copy.
+func exportWithResourceUnits(partitions []PartitionConfig) ([]byte, error) {
+	out, err := yaml.Marshal(&SchedulerConfig{Partitions: partitions})
+	if err != nil {
+		return nil, err
+	}
+	converted := false
+	for _, partition := range partitions {
+		converted = converted || len(partition.ResourceUnits) != 0
+	}
+	if !converted {
+		return out, nil
+	}
+	conf := &SchedulerConfig{}
+	if err = yaml.Unmarshal(out, conf); err != nil {
+		return nil, err
+	}
+	for i := range conf.Partitions {
+		partition := &conf.Partitions[i]
+		if err = walkPartitionResources(partition, func(res map[string]string) error {
+			return fromSchedulerUnits(res, partition.ResourceUnits)
+		}); err != nil {
+			return nil, fmt.Errorf("partition %s: %v", partition.Name, err)
+		}
+	}
+	return yaml.Marshal(conf)
+}
+
+// The queue properties that have a resource as the value.
+var resourceProperties = []string{ApplicationGroupMax, AskDefaultResource, QueueUserMax}
+
+// Return true if the queue property has a resource as the value, the value could still be a percentage.
+func IsResourceProperty(key string) bool {
+	for _, name := range resourceProperties {
+		if key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Call the function for all resource definitions in the partition: queue and child template resources, the resource
+// valued queue properties, node pools, limits, the reservation maximum and the autoscale watermark.
+func walkPartitionResources(partition *PartitionConfig, convert func(res map[string]string) error) error {
+	var walkQueue func(queue *QueueConfig) error
+	walkResources := func(res Resources, props map[string]string) error {
+		for _, values := range []map[string]string{res.Guaranteed, res.Max, res.SoftMax, res.MaxAllocation} {
+			if err := convert(values); err != nil {
+				return err
+			}
+		}
+		for _, key := range resourceProperties {
+			if err := convertResourceProperty(props, key, convert); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	walkLimits := func(limits []Limit) error {
+		for i := range limits {
+			if err := convert(limits[i].MaxResources); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	walkQueue = func(queue *QueueConfig) error {
+		if err := walkResources(queue.Resources, queue.Properties); err != nil {
+			return fmt.Errorf("queue %s: %v", queue.Name, err)
+		}
+		if err := walkResources(queue.ChildTemplate.Resources, queue.ChildTemplate.Properties); err != nil {
+			return fmt.Errorf("queue %s child template: %v", queue.Name, err)
+		}
+		for i := range queue.NodePools {
+			if err := convert(queue.NodePools[i].Max); err != nil {
+				return fmt.Errorf("queue %s: %v", queue.Name, err)
+			}
+		}
+		if err := walkLimits(queue.Limits); err != nil {
+			return fmt.Errorf("queue %s: %v", queue.Name, err)
+		}
+		for i := range queue.Queues {
+			if err := walkQueue(&queue.Queues[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i := range partition.Queues {
+		if err := walkQueue(&partition.Queues[i]); err != nil {
+			return err
+		}
+	}
+	if err := walkLimits(partition.Limits); err != nil {
+		return err
+	}
+	if err := convert(partition.Reservations.MaxResource); err != nil {
+		return err
+	}
+	return convert(partition.Autoscale.Watermark)
+}
+
+// Call the function for the resource set as the value of the property. A percentage, like the percentage of the
+// queue.user.max property, or a value that is not a resource is left for the validation to report.
+// The property is only rewritten, in the sorted resource format, if the function changed a quantity.
+func convertResourceProperty(props map[string]string, key string, convert func(res map[string]string) error) error {
+	value, ok := props[key]
+	if !ok || strings.HasSuffix(strings.TrimSpace(value), "%") {
+		return nil
+	}
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(value), "map"), "["), "]")
+	res := make(map[string]string)
+	for _, part := range strings.Fields(trimmed) {
+		idx := strings.LastIndex(part, ":")
+		if idx <= 0 {
+			return nil
+		}
+		res[part[:idx]] = part[idx+1:]
+	}
+	names := make([]string, 0, len(res))
+	original := make(map[string]string, len(res))
+	for name, quantity := range res {
+		names = append(names, name)
+		original[name] = quantity
+	}
+	if err := convert(res); err != nil {
+		return fmt.Errorf("property %s: %v", key, err)
+	}
+	changed := false
+	for name, quantity := range res {
+		changed = changed || original[name] != quantity
+	}
+	if !changed {
+		return nil
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+":"+res[name])
+	}
+	props[key] = "[" + strings.Join(parts, " ") + "]"
+	return nil
+}
+
+// Convert the quantities in the resource map in place from configuration units into scheduler units.
+func toSchedulerUnits(res map[string]string, units map[string]int64) error {
+	for name, value := range res {
+		unit, ok := units[name]
+		if !ok || unit == 1 {
+			continue
+		}
+		quantity, err := ParseQuantity(value, unit)
+		if err != nil {
+			return fmt.Errorf("resource type '%s': %v", name, err)
+		}
+		res[name] = strconv.FormatInt(quantity, 10)
+	}
+	return nil
+}
+
+// Convert the quantities in the resource map in place from scheduler units into configuration units.
+func fromSchedulerUnits(res map[string]string, units map[string]int64) error {
+	for name, value := range res {
+		unit, ok := units[name]
+		if !ok || unit == 1 {
+			continue
+		}
+		quantity, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("resource type '%s': %v", name, err)
+		}
+		res[name] = FormatQuantity(quantity, unit)
+	}
+	return nil
+}
+
+// Parse a quantity in configuration units, an integer or a decimal like "0.5", into scheduler units.
+// The unit must be a power of 10, the value cannot have more decimals than the unit supports.
+func ParseQuantity(value string, unit int64) (int64, error) {
+	decimals := len(strconv.FormatInt(unit, 10)) - 1
+	whole, fraction := value, ""
+	if idx := strings.Index(value, "."); idx != -1 {
+		whole, fraction = value[:idx], value[idx+1:]
+	}
+	if strings.TrimLeft(whole, "+-")+fraction == "" {
+		return 0, fmt.Errorf("invalid value '%s': no digits", value)
+	}
+	if trimmed := strings.TrimRight(fraction, "0"); len(trimmed) > decimals {
+		return 0, fmt.Errorf("value %s is smaller than the resource unit 1/%d", value, unit)
+	}
+	if len(fraction) > decimals {
+		fraction = fraction[:decimals]
+	}
+	if whole == "" || whole == "-" || whole == "+" {
+		whole += "0"
+	}
+	quantity, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", decimals-len(fraction)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %s: %v", value, err)
+	}
+	return quantity, nil
+}
+
+// Format a quantity in scheduler units as a value in configuration units, the reverse of ParseQuantity.
+// Whole units are formatted as an integer, other values as a decimal without trailing zeros.
+func FormatQuantity(quantity, unit int64) string {
+	if unit <= 1 {
+		return strconv.FormatInt(quantity, 10)
+	}
+	if quantity%unit == 0 {
+		return strconv.FormatInt(quantity/unit, 10)
+	}
+	sign := ""
+	if quantity < 0 {
+		sign = "-"
+		quantity = -quantity
+	}
+	decimals := len(strconv.FormatInt(unit, 10)) - 1
+	fraction := fmt.Sprintf("%0*d", decimals, quantity%unit)
+	return fmt.Sprintf("%s%d.%s", sign, quantity/unit, strings.TrimRight(fraction, "0"))
+}
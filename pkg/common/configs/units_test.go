@@ -0,0 +1,190 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package configs
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		value    string
+		unit     int64
+		expected int64
+	}{
+		{"2", 1000, 2000},
+		{"0.5", 1000, 500},
+		{".25", 1000, 250},
+		{"1.125", 1000, 1125},
+		{"1.500000", 1000, 1500},
+		{"-0.5", 1000, -500},
+		{"7", 1, 7},
+	}
+	for _, test := range tests {
+		quantity, err := ParseQuantity(test.value, test.unit)
+		assert.NilError(t, err, "value %s unit %d", test.value, test.unit)
+		assert.Equal(t, quantity, test.expected, "value %s unit %d", test.value, test.unit)
+	}
+	for _, value := range []string{"0.0005", "1.5", "abc", "1.2.3", "", ".", "-"} {
+		unit := int64(1000)
+		if value == "1.5" {
+			unit = 1
+		}
+		_, err := ParseQuantity(value, unit)
+		assert.Assert(t, err != nil, "value %s should have failed for unit %d", value, unit)
+	}
+}
+
+func TestFormatQuantity(t *testing.T) {
+	assert.Equal(t, FormatQuantity(2000, 1000), "2")
+	assert.Equal(t, FormatQuantity(500, 1000), "0.5")
+	assert.Equal(t, FormatQuantity(1125, 1000), "1.125")
+	assert.Equal(t, FormatQuantity(5, 1000), "0.005")
+	assert.Equal(t, FormatQuantity(-1500, 1000), "-1.5")
+	assert.Equal(t, FormatQuantity(7, 1), "7")
+	assert.Equal(t, FormatQuantity(7, 0), "7")
+}
+
+func TestResourceUnits(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    resourceunits:
+      vcore: 1000
+    queues:
+      - name: root
+        childtemplate:
+          resources:
+            max:
+              vcore: "2.5"
+          properties:
+            queue.user.max: "[memory:10 vcore:0.5]"
+        queues:
+          - name: small
+            resources:
+              guaranteed:
+                vcore: "0.5"
+              max:
+                vcore: "1.5"
+                memory: "100"
+              maxallocation:
+                vcore: "0.25"
+            properties:
+              ask.default.resource: "[vcore:0.1 memory:10]"
+              application.group.max: "[memory:100]"
+              queue.user.max: "50%"
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.NilError(t, err, "config with resource units should load")
+	root := conf.Partitions[0].Queues[0]
+	assert.DeepEqual(t, root.ChildTemplate.Resources.Max, map[string]string{"vcore": "2500"})
+	assert.Equal(t, root.ChildTemplate.Properties[QueueUserMax], "[memory:10 vcore:500]")
+	small := root.Queues[0]
+	assert.DeepEqual(t, small.Resources.Guaranteed, map[string]string{"vcore": "500"})
+	assert.DeepEqual(t, small.Resources.Max, map[string]string{"vcore": "1500", "memory": "100"})
+	assert.DeepEqual(t, small.Resources.MaxAllocation, map[string]string{"vcore": "250"})
+	// resource valued properties are converted, properties without a converted type or a percentage are not
+	assert.Equal(t, small.Properties[AskDefaultResource], "[memory:10 vcore:100]")
+	assert.Equal(t, small.Properties[ApplicationGroupMax], "[memory:100]")
+	assert.Equal(t, small.Properties[QueueUserMax], "50%")
+
+	// export converts back to configuration units and loads into the same config
+	out, err := ExportSchedulerConfig(conf.Partitions)
+	assert.NilError(t, err, "export should not fail")
+	var exported *SchedulerConfig
+	exported, err = LoadSchedulerConfigFromByteArray(out)
+	assert.NilError(t, err, "exported config should load")
+	assert.DeepEqual(t, exported.Partitions, conf.Partitions)
+	// the original config must not be changed by the export
+	assert.DeepEqual(t, small.Resources.Max, map[string]string{"vcore": "1500", "memory": "100"})
+
+	// too precise for the unit
+	data = `
+partitions:
+  - name: default
+    resourceunits:
+      vcore: 1000
+    queues:
+      - name: root
+        resources:
+          max:
+            vcore: "0.0001"
+`
+	_, err = LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.Assert(t, err != nil, "value smaller than the unit should fail")
+
+	// unit must be a power of 10
+	data = `
+partitions:
+  - name: default
+    resourceunits:
+      vcore: 1024
+    queues:
+      - name: root
+`
+	_, err = LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.Assert(t, err != nil, "unit that is not a power of 10 should fail")
+}
+
+func TestRMResourceUnits(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    resourceunits:
+      vcore: 1000
+    rmresourceunits:
+      vcore: 1
+      memory: 1
+    queues:
+      - name: root
+`
+	conf, err := LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.NilError(t, err, "config with RM resource units should load")
+	// memory uses the same unit in the RM and the scheduler: no conversion
+	assert.DeepEqual(t, RMUnitFactors(&conf.Partitions[0]), map[string]int64{"vcore": 1000})
+	assert.Assert(t, RMUnitFactors(&PartitionConfig{}) == nil, "no RM units should not convert")
+
+	// RM unit smaller than the scheduler unit
+	data = `
+partitions:
+  - name: default
+    rmresourceunits:
+      vcore: 1000
+    queues:
+      - name: root
+`
+	_, err = LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.Assert(t, err != nil, "RM unit smaller than the scheduler unit should fail")
+
+	// RM unit must be a power of 10
+	data = `
+partitions:
+  - name: default
+    resourceunits:
+      vcore: 1000
+    rmresourceunits:
+      vcore: 2
+    queues:
+      - name: root
+`
+	_, err = LoadSchedulerConfigFromByteArray([]byte(data))
+	assert.Assert(t, err != nil, "RM unit that is not a power of 10 should fail")
+}
@@ -0,0 +1,57 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package locking provides the mutex used by the cache and scheduler objects.
+//
+// In a normal build the RWMutex is the standard library sync.RWMutex without any overhead.
+// Building or testing with the deadlock tag replaces it with a tracking mutex: each acquisition records the
+// lock order between the classes of objects (the type that owns the lock) per goroutine. An acquisition that
+// closes a cycle in the lock order, or that takes a lock the goroutine already holds, is recorded as a violation.
+// Violations are potential deadlocks even if the test that triggered them did not hang.
+//
+//	go test ./... -tags deadlock
+package locking
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violation kinds
+const (
+	OrderViolation     = "lock order cycle"
+	ReentrantViolation = "lock acquired twice"
+)
+
+// A lock ordering problem detected in deadlock tracking mode.
+// The cycle lists the lock classes in acquisition order, the first and last entry are the same class.
+// The stack is the stack of the goroutine that closed the cycle, the order stack is the stack that first recorded
+// the conflicting order between the classes (empty for a lock acquired twice).
+type Violation struct {
+	Kind       string
+	Cycle      []string
+	Stack      string
+	OrderStack string
+}
+
+func (v Violation) String() string {
+	if v.OrderStack == "" {
+		return fmt.Sprintf("%s: %s\n%s", v.Kind, strings.Join(v.Cycle, " -> "), v.Stack)
+	}
+	return fmt.Sprintf("%s: %s\n%s\nconflicting order recorded by:\n%s", v.Kind, strings.Join(v.Cycle, " -> "), v.Stack, v.OrderStack)
+}
@@ -0,0 +1,43 @@
+//go:build !deadlock
+// +build !deadlock
+
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package locking
+
+import (
+	"sync"
+)
+
+// Lock tracking is disabled: use the standard mutex.
+type RWMutex = sync.RWMutex
+
+// Returns true if lock tracking is compiled in.
+func Enabled() bool {
+	return false
+}
+
+// No violations are ever recorded without lock tracking.
+func Violations() []Violation {
+	return nil
+}
+
+// Reset the tracked lock order and violations, nothing to do without lock tracking.
+func Reset() {
+}
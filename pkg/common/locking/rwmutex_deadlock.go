@@ -0,0 +1,269 @@
+//go:build deadlock
+// +build deadlock
+
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package locking
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+const stackSize = 8192
+
+// The lock tracker shared by all tracking mutexes.
+var tracker = newLockTracker()
+
+// Tracking read write mutex: a drop in replacement for the sync.RWMutex.
+// The class of the mutex is the type of the object that owns it, derived from the method that takes the lock for
+// the first time. The zero value is an unlocked mutex, like the sync.RWMutex.
+type RWMutex struct {
+	mutex sync.RWMutex
+	class string
+}
+
+func (m *RWMutex) Lock() {
+	gid := tracker.acquire(m)
+	m.mutex.Lock()
+	tracker.acquired(m, gid)
+}
+
+func (m *RWMutex) Unlock() {
+	tracker.release(m)
+	m.mutex.Unlock()
+}
+
+func (m *RWMutex) RLock() {
+	gid := tracker.acquire(m)
+	m.mutex.RLock()
+	tracker.acquired(m, gid)
+}
+
+func (m *RWMutex) RUnlock() {
+	tracker.release(m)
+	m.mutex.RUnlock()
+}
+
+// Returns true if lock tracking is compiled in.
+func Enabled() bool {
+	return true
+}
+
+// Get a copy of the violations recorded since the start or the last reset.
+func Violations() []Violation {
+	tracker.Lock()
+	defer tracker.Unlock()
+	violations := make([]Violation, len(tracker.violations))
+	copy(violations, tracker.violations)
+	return violations
+}
+
+// Reset the tracked lock order and violations.
+func Reset() {
+	tracker.Lock()
+	defer tracker.Unlock()
+	tracker.order = make(map[string]map[string]string)
+	tracker.violations = nil
+}
+
+type lockTracker struct {
+	// locks held per goroutine, in acquisition order
+	held map[int64][]*RWMutex
+	// lock order graph between classes: order[a][b] is the stack that acquired b while holding a
+	order      map[string]map[string]string
+	violations []Violation
+
+	sync.Mutex
+}
+
+func newLockTracker() *lockTracker {
+	return &lockTracker{
+		held:  make(map[int64][]*RWMutex),
+		order: make(map[string]map[string]string),
+	}
+}
+
+// Check the lock order before blocking on the mutex: a real deadlock would otherwise never be reported.
+// Returns the goroutine ID to register the lock against once acquired.
+func (lt *lockTracker) acquire(m *RWMutex) int64 {
+	gid := goroutineID()
+	lt.Lock()
+	defer lt.Unlock()
+	if m.class == "" {
+		m.class = callerClass()
+	}
+	for _, held := range lt.held[gid] {
+		if held == m {
+			lt.addViolation(ReentrantViolation, []string{m.class, m.class}, currentStack(), "")
+			continue
+		}
+		// objects of the same class are locked in a hierarchy (i.e. queues): not tracked
+		if held.class == m.class || lt.order[held.class][m.class] != "" {
+			continue
+		}
+		stack := currentStack()
+		if path := lt.path(m.class, held.class); path != nil {
+			lt.addViolation(OrderViolation, append([]string{held.class}, path...), stack, lt.order[path[0]][path[1]])
+		}
+		if lt.order[held.class] == nil {
+			lt.order[held.class] = make(map[string]string)
+		}
+		lt.order[held.class][m.class] = stack
+	}
+	return gid
+}
+
+func (lt *lockTracker) acquired(m *RWMutex, gid int64) {
+	lt.Lock()
+	defer lt.Unlock()
+	lt.held[gid] = append(lt.held[gid], m)
+}
+
+// Unlocking is allowed from another goroutine than the one that took the lock: check all goroutines if the
+// current one does not hold the lock.
+func (lt *lockTracker) release(m *RWMutex) {
+	gid := goroutineID()
+	lt.Lock()
+	defer lt.Unlock()
+	if lt.remove(m, gid) {
+		return
+	}
+	for other := range lt.held {
+		if lt.remove(m, other) {
+			return
+		}
+	}
+}
+
+func (lt *lockTracker) remove(m *RWMutex, gid int64) bool {
+	held := lt.held[gid]
+	for i := len(held) - 1; i >= 0; i-- {
+		if held[i] == m {
+			held = append(held[:i], held[i+1:]...)
+			if len(held) == 0 {
+				delete(lt.held, gid)
+			} else {
+				lt.held[gid] = held
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// Find a path in the lock order graph between the two classes using a depth first search.
+// Returns nil if there is no path, or the classes on the path including both ends.
+func (lt *lockTracker) path(from, to string) []string {
+	visited := make(map[string]bool)
+	var search func(class string) []string
+	search = func(class string) []string {
+		if class == to {
+			return []string{class}
+		}
+		visited[class] = true
+		for next := range lt.order[class] {
+			if visited[next] {
+				continue
+			}
+			if path := search(next); path != nil {
+				return append([]string{class}, path...)
+			}
+		}
+		return nil
+	}
+	return search(from)
+}
+
+func (lt *lockTracker) addViolation(kind string, cycle []string, stack, orderStack string) {
+	lt.violations = append(lt.violations, Violation{
+		Kind:       kind,
+		Cycle:      cycle,
+		Stack:      stack,
+		OrderStack: orderStack,
+	})
+	log.Logger().Warn("potential deadlock detected",
+		zap.String("kind", kind),
+		zap.Strings("cycle", cycle),
+		zap.String("stack", stack),
+		zap.String("orderStack", orderStack))
+}
+
+func currentStack() string {
+	buf := make([]byte, stackSize)
+	return string(buf[:runtime.Stack(buf, false)])
+}
+
+// Get the ID of the current goroutine from the first line of the stack: "goroutine 123 [running]:"
+func goroutineID() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+	id, err := strconv.ParseInt(string(buf), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// Derive the lock class from the first caller outside the tracking mutex.
+// A method "github.com/org/repo/pkg/cache.(*PartitionInfo).GetQueue" gives the class "cache.PartitionInfo",
+// a function without a receiver is its own class.
+func callerClass() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(1, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !isTrackerFrame(frame) {
+			return className(frame.Function)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+func isTrackerFrame(frame runtime.Frame) bool {
+	if frame.File == "<autogenerated>" {
+		return true
+	}
+	name := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+	return strings.HasPrefix(name, "locking.(*RWMutex)") ||
+		strings.HasPrefix(name, "locking.(*lockTracker)") ||
+		strings.HasPrefix(name, "locking.callerClass")
+}
+
+func className(function string) string {
+	name := function[strings.LastIndex(function, "/")+1:]
+	parts := strings.SplitN(name, ".", 3)
+	if len(parts) == 3 && strings.HasPrefix(parts[1], "(*") {
+		return parts[0] + "." + strings.TrimSuffix(strings.TrimPrefix(parts[1], "(*"), ")")
+	}
+	return name
+}
@@ -0,0 +1,150 @@
+//go:build deadlock
+// +build deadlock
+
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package locking
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+type testQueue struct {
+	parent *testQueue
+	RWMutex
+}
+
+type testApp struct {
+	RWMutex
+}
+
+func (q *testQueue) lockApp(app *testApp) {
+	q.Lock()
+	defer q.Unlock()
+	app.lockSelf()
+}
+
+func (q *testQueue) lockParent() {
+	q.Lock()
+	defer q.Unlock()
+	if q.parent != nil {
+		q.parent.lockParent()
+	}
+}
+
+func (q *testQueue) lockSelf() {
+	q.RLock()
+	defer q.RUnlock()
+}
+
+func (app *testApp) lockSelf() {
+	app.RLock()
+	defer app.RUnlock()
+}
+
+func (app *testApp) lockQueue(q *testQueue) {
+	app.Lock()
+	defer app.Unlock()
+	q.lockSelf()
+}
+
+func (app *testApp) lockTwice() {
+	app.RLock()
+	defer app.RUnlock()
+	app.lockSelf()
+}
+
+func TestOrderViolation(t *testing.T) {
+	Reset()
+	defer Reset()
+	queue := &testQueue{}
+	app := &testApp{}
+	// the same order multiple times is not a problem
+	queue.lockApp(app)
+	queue.lockApp(app)
+	assert.Equal(t, len(Violations()), 0, "unexpected violation for consistent lock order")
+	// the reverse order closes the cycle
+	app.lockQueue(queue)
+	violations := Violations()
+	assert.Equal(t, len(violations), 1, "reverse lock order not detected")
+	assert.Equal(t, violations[0].Kind, OrderViolation)
+	assert.DeepEqual(t, violations[0].Cycle, []string{"locking.testApp", "locking.testQueue", "locking.testApp"})
+	assert.Assert(t, violations[0].Stack != "", "stack not recorded")
+	assert.Assert(t, violations[0].OrderStack != "", "conflicting order stack not recorded")
+	// reported once
+	app.lockQueue(queue)
+	assert.Equal(t, len(Violations()), 1, "violation reported twice")
+}
+
+func TestSameClass(t *testing.T) {
+	Reset()
+	defer Reset()
+	root := &testQueue{}
+	leaf := &testQueue{parent: &testQueue{parent: root}}
+	leaf.lockParent()
+	assert.Equal(t, len(Violations()), 0, "nested locks of the same class should not be tracked")
+}
+
+func TestReentrant(t *testing.T) {
+	Reset()
+	defer Reset()
+	app := &testApp{}
+	app.lockTwice()
+	violations := Violations()
+	assert.Equal(t, len(violations), 1, "lock acquired twice not detected")
+	assert.Equal(t, violations[0].Kind, ReentrantViolation)
+	// all locks are released
+	app.Lock()
+	app.Unlock()
+	assert.Equal(t, len(Violations()), 1, "released lock still tracked")
+}
+
+func TestUnlockOtherGoroutine(t *testing.T) {
+	Reset()
+	defer Reset()
+	app := &testApp{}
+	app.Lock()
+	done := make(chan bool)
+	go func() {
+		app.Unlock()
+		done <- true
+	}()
+	<-done
+	tracker.Lock()
+	held := len(tracker.held)
+	tracker.Unlock()
+	assert.Equal(t, held, 0, "lock released on other goroutine still tracked")
+}
+
+func TestClassName(t *testing.T) {
+	var tests = []struct {
+		function string
+		class    string
+	}{
+		{"github.com/apache/incubator-yunikorn-core/pkg/cache.(*PartitionInfo).GetQueue", "cache.PartitionInfo"},
+		{"github.com/apache/incubator-yunikorn-core/pkg/scheduler.(*SchedulingQueue).tryAllocate.func1", "scheduler.SchedulingQueue"},
+		{"github.com/apache/incubator-yunikorn-core/pkg/scheduler.newSchedulingQueue", "scheduler.newSchedulingQueue"},
+		{"main.main", "main.main"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, className(tt.function), tt.class)
+	}
+}
@@ -20,6 +20,8 @@ package common
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -68,3 +70,54 @@ func NewNodeSortingPolicy(policyType string) *NodeSortingPolicy {
 		zap.String("type", pType.String()))
 	return sp
 }
+
+// Check the resource weights of a node sorting policy: weights cannot be negative and at least one weight must be
+// larger than 0. An empty set of weights is valid, it means the nodes are not sorted using weights.
+func CheckResourceWeights(weights map[string]float64) error {
+	if len(weights) == 0 {
+		return nil
+	}
+	positive := false
+	for name, weight := range weights {
+		if weight < 0 {
+			return fmt.Errorf("negative weight %g for resource type '%s'", weight, name)
+		}
+		if weight > 0 {
+			positive = true
+		}
+	}
+	if !positive {
+		return fmt.Errorf("at least one resource weight must be larger than 0")
+	}
+	return nil
+}
+
+// Parse the resource weights of a node sorting policy from a string like "gpu:10 vcore:1".
+// The brackets used in the resource string representation are optional. The weights are checked using
+// CheckResourceWeights.
+func ParseResourceWeights(str string) (map[string]float64, error) {
+	trimmed := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(str), "["), "]")
+	weights := make(map[string]float64)
+	for _, part := range strings.Fields(trimmed) {
+		idx := strings.LastIndex(part, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid resource weight '%s' in '%s'", part, str)
+		}
+		name := part[:idx]
+		if _, ok := weights[name]; ok {
+			return nil, fmt.Errorf("duplicate resource type '%s' in '%s'", name, str)
+		}
+		weight, err := strconv.ParseFloat(part[idx+1:], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for resource type '%s' in '%s': %v", name, str, err)
+		}
+		weights[name] = weight
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no resource weights in '%s'", str)
+	}
+	if err := CheckResourceWeights(weights); err != nil {
+		return nil, err
+	}
+	return weights, nil
+}
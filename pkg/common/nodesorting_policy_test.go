@@ -19,6 +19,7 @@
 package common
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -83,3 +84,32 @@ func TestNewNodeSortingPolicy(t *testing.T) {
 		}
 	}
 }
+
+func TestParseResourceWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		arg     string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{"Single", "gpu:10", map[string]float64{"gpu": 10}, false},
+		{"Multiple", "gpu:10 vcore:0.5", map[string]float64{"gpu": 10, "vcore": 0.5}, false},
+		{"Brackets", "[gpu:1 memory:0]", map[string]float64{"gpu": 1, "memory": 0}, false},
+		{"Empty", "", nil, true},
+		{"NoValue", "gpu", nil, true},
+		{"NotNumber", "gpu:many", nil, true},
+		{"Duplicate", "gpu:1 gpu:2", nil, true},
+		{"Negative", "gpu:-1", nil, true},
+		{"AllZero", "gpu:0 vcore:0", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseResourceWeights(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s unexpected error returned, expected error: %t, got error '%v'", tt.name, tt.wantErr, err)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s unexpected weights returned, expected: %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package resources
+
+import (
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// Return a copy of the resource with the resource types replaced by the canonical type from the aliases.
+// The aliases map an alias to the canonical resource type. The quantities are added up if the resource contains both
+// the alias and the canonical type. Types without an alias are copied as is.
+func Canonicalize(res *Resource, aliases map[string]string) *Resource {
+	if res == nil {
+		return nil
+	}
+	out := NewResource()
+	for name, quantity := range res.Resources {
+		if canonical, ok := aliases[name]; ok {
+			name = canonical
+		}
+		out.Resources[name] = addVal(out.Resources[name], quantity)
+	}
+	return out
+}
+
+// Replace the resource types in the proto with the canonical type from the aliases.
+// The proto is changed in place, a nil proto or no aliases leave the proto unchanged. The aliases must not be chained:
+// a canonical type must not be an alias itself.
+func CanonicalizeProto(proto *si.Resource, aliases map[string]string) {
+	if proto == nil || len(aliases) == 0 {
+		return
+	}
+	for name, quantity := range proto.Resources {
+		canonical, ok := aliases[name]
+		if !ok {
+			continue
+		}
+		delete(proto.Resources, name)
+		if existing, found := proto.Resources[canonical]; found {
+			quantity = &si.Quantity{Value: int64(addVal(Quantity(existing.GetValue()), Quantity(quantity.GetValue())))}
+		}
+		proto.Resources[canonical] = quantity
+	}
+}
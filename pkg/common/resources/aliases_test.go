@@ -0,0 +1,57 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestCanonicalize(t *testing.T) {
+	aliases := map[string]string{"cpu": "vcore", "nvidia.com/gpu": "gpu"}
+	assert.Assert(t, Canonicalize(nil, aliases) == nil, "nil resource should stay nil")
+
+	res := NewResourceFromMap(map[string]Quantity{"cpu": 2, "nvidia.com/gpu": 1, "memory": 10})
+	out := Canonicalize(res, aliases)
+	assert.DeepEqual(t, out.Resources, map[string]Quantity{"vcore": 2, "gpu": 1, "memory": 10})
+	assert.Equal(t, res.Resources["cpu"], Quantity(2), "original resource should not be changed")
+
+	// alias and canonical type are added up
+	res = NewResourceFromMap(map[string]Quantity{"cpu": 2, "vcore": 3})
+	assert.DeepEqual(t, Canonicalize(res, aliases).Resources, map[string]Quantity{"vcore": 5})
+	// no aliases copies the resource
+	assert.DeepEqual(t, Canonicalize(res, nil).Resources, res.Resources)
+}
+
+func TestCanonicalizeProto(t *testing.T) {
+	aliases := map[string]string{"cpu": "vcore", "nvidia.com/gpu": "gpu"}
+	CanonicalizeProto(nil, aliases)
+
+	proto := &si.Resource{Resources: map[string]*si.Quantity{
+		"cpu":            {Value: 2},
+		"vcore":          {Value: 3},
+		"nvidia.com/gpu": {Value: 1},
+		"memory":         {Value: 10},
+	}}
+	CanonicalizeProto(proto, aliases)
+	assert.DeepEqual(t, NewResourceFromProto(proto).Resources, map[string]Quantity{"vcore": 5, "gpu": 1, "memory": 10})
+}
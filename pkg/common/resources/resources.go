@@ -23,6 +23,7 @@ import (
 	"math"
 	"sort"
 	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -51,10 +52,10 @@ func NewResource() *Resource {
 }
 
 func NewResourceFromProto(proto *si.Resource) *Resource {
-	out := NewResource()
 	if proto == nil {
-		return out
+		return NewResource()
 	}
+	out := &Resource{Resources: make(map[string]Quantity, len(proto.Resources))}
 	for k, v := range proto.Resources {
 		out.Resources[k] = Quantity(v.Value)
 	}
@@ -80,14 +81,134 @@ func NewResourceFromConf(configMap map[string]string) (*Resource, error) {
 	return res, nil
 }
 
+// Convert the resource into a config map, the reverse of NewResourceFromConf.
+// A nil or empty resource returns a nil map.
+func (r *Resource) ToConf() map[string]string {
+	if r == nil || len(r.Resources) == 0 {
+		return nil
+	}
+	configMap := make(map[string]string, len(r.Resources))
+	for key, value := range r.Resources {
+		configMap[key] = strconv.FormatInt(int64(value), 10)
+	}
+	return configMap
+}
+
+// Canonical string representation of the resource: the resource types are sorted by name and the values are the
+// quantities without a unit. The output is stable and can be converted back into a resource using ParseResource.
+// Format: map[name1:value1 name2:value2]
 func (r *Resource) String() string {
-	return fmt.Sprintf("%v", r.Resources)
+	return "map" + r.format(func(_ string, value Quantity) string {
+		return strconv.FormatInt(int64(value), 10)
+	})
+}
+
+// String representation of the resource as shown in the REST responses and events.
+// This is the canonical representation without the map prefix, the values of memory and vcore use a unit. Memory
+// is tracked in mebibytes: whole gibibytes are shown with the Gi suffix, other values with the Mi suffix. The vcore
+// is tracked in milli cores and shown with the m suffix. The output can be converted back using ParseResource.
+// Format: [gpu:1 memory:4Gi vcore:500m]
+func (r *Resource) DAOString() string {
+	return r.format(formatQuantity)
+}
+
+// Write the resource types sorted by name, the values are formatted by the function.
+func (r *Resource) format(formatValue func(name string, value Quantity) string) string {
+	if r == nil {
+		return "[]"
+	}
+	keys := make([]string, 0, len(r.Resources))
+	for k := range r.Resources {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(k)
+		sb.WriteString(":")
+		sb.WriteString(formatValue(k, r.Resources[k]))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+// The units of the resource types that are formatted with a unit, the largest unit first.
+// The factor is the number of tracked units in one unit.
+var quantityUnits = map[string][]struct {
+	suffix string
+	factor int64
+}{
+	MEMORY: {{"Gi", 1024}, {"Mi", 1}},
+	VCORE:  {{"m", 1}},
+}
+
+// Format the value of the resource type using the largest unit that shows it as a whole number.
+// Zero is shown in the smallest unit.
+func formatQuantity(name string, value Quantity) string {
+	for _, unit := range quantityUnits[name] {
+		if int64(value)%unit.factor == 0 && (value != 0 || unit.factor == 1) {
+			return strconv.FormatInt(int64(value)/unit.factor, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(value), 10)
+}
+
+// Parse the value of the resource type, the value has one of the units of the type or no unit.
+func parseQuantity(name, value string) (Quantity, error) {
+	for _, unit := range quantityUnits[name] {
+		if number := strings.TrimSuffix(value, unit.suffix); number != value {
+			parsed, err := strconv.ParseInt(number, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			if parsed > math.MaxInt64/unit.factor || parsed < math.MinInt64/unit.factor {
+				return 0, fmt.Errorf("value %s out of range", value)
+			}
+			return Quantity(parsed * unit.factor), nil
+		}
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	return Quantity(parsed), err
+}
+
+// Parse a resource from its string representation.
+// Accepts the output of String() and DAOString(): the map prefix and the brackets are optional.
+// Resource types must be unique and values must be integers, memory and vcore values can use the units of
+// DAOString().
+func ParseResource(str string) (*Resource, error) {
+	trimmed := strings.TrimSpace(str)
+	trimmed = strings.TrimPrefix(trimmed, "map")
+	if strings.HasPrefix(trimmed, "[") != strings.HasSuffix(trimmed, "]") {
+		return nil, fmt.Errorf("unbalanced brackets in resource string '%s'", str)
+	}
+	trimmed = strings.TrimSuffix(strings.TrimPrefix(trimmed, "["), "]")
+	res := NewResource()
+	for _, part := range strings.Fields(trimmed) {
+		idx := strings.LastIndex(part, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid resource entry '%s' in resource string '%s'", part, str)
+		}
+		name := part[:idx]
+		if _, ok := res.Resources[name]; ok {
+			return nil, fmt.Errorf("duplicate resource type '%s' in resource string '%s'", name, str)
+		}
+		value, err := parseQuantity(name, part[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for resource type '%s' in resource string '%s': %v", name, str, err)
+		}
+		res.Resources[name] = value
+	}
+	return res, nil
 }
 
 // Convert to a protobuf implementation
 func (r *Resource) ToProto() *si.Resource {
 	proto := &si.Resource{}
-	proto.Resources = make(map[string]*si.Quantity)
+	proto.Resources = make(map[string]*si.Quantity, len(r.Resources))
 	for k, v := range r.Resources {
 		proto.Resources[k] = &si.Quantity{Value: int64(v)}
 	}
@@ -98,7 +219,13 @@ func (r *Resource) ToProto() *si.Resource {
 // This provides a deep copy of the object with the exact same member set.
 // NOTE: this is a clone not a sparse copy of the original.
 func (r *Resource) Clone() *Resource {
-	ret := NewResource()
+	return r.cloneWithSize(len(r.Resources))
+}
+
+// Clone the resource into a map pre-sized for the number of resource types: the clone is often the start of a
+// calculation that adds types. Pre-sizing prevents the map from growing on the hot path.
+func (r *Resource) cloneWithSize(size int) *Resource {
+	ret := &Resource{Resources: make(map[string]Quantity, size)}
 	for k, v := range r.Resources {
 		ret.Resources[k] = v
 	}
@@ -184,27 +311,32 @@ func (r *Resource) FitInScore(fit *Resource) float64 {
 // Wrapping safe calculators for the quantities of resources.
 // They will always return a valid int64. Logging if the calculator wrapped the value.
 // Returning the appropriate MaxInt64 or MinInt64 value.
+// The add is kept small enough to be inlined: the wrapped case is handled separately.
 func addVal(valA, valB Quantity) Quantity {
 	result := valA + valB
 	// check if the sign wrapped
 	if (result < valA) != (valB < 0) {
-		if valA < 0 {
-			// return the minimum possible
-			log.Logger().Warn("Resource calculation wrapped: returned minimum value possible",
-				zap.Int64("valueA", int64(valA)),
-				zap.Int64("valueB", int64(valB)))
-			return math.MinInt64
-		}
-		// return the maximum possible
-		log.Logger().Warn("Resource calculation wrapped: returned maximum value possible",
-			zap.Int64("valueA", int64(valA)),
-			zap.Int64("valueB", int64(valB)))
-		return math.MaxInt64
+		return addValWrapped(valA, valB)
 	}
 	// not wrapped normal case
 	return result
 }
 
+func addValWrapped(valA, valB Quantity) Quantity {
+	if valA < 0 {
+		// return the minimum possible
+		log.Logger().Warn("Resource calculation wrapped: returned minimum value possible",
+			zap.Int64("valueA", int64(valA)),
+			zap.Int64("valueB", int64(valB)))
+		return math.MinInt64
+	}
+	// return the maximum possible
+	log.Logger().Warn("Resource calculation wrapped: returned maximum value possible",
+		zap.Int64("valueA", int64(valA)),
+		zap.Int64("valueB", int64(valB)))
+	return math.MaxInt64
+}
+
 func subVal(valA, valB Quantity) Quantity {
 	return addVal(valA, -valB)
 }
@@ -279,13 +411,21 @@ func Add(left, right *Resource) *Resource {
 	}
 
 	// neither are nil, clone one and add the other
-	out := left.Clone()
+	out := left.cloneWithSize(unionSize(left, right))
 	for k, v := range right.Resources {
 		out.Resources[k] = addVal(out.Resources[k], v)
 	}
 	return out
 }
 
+// The size hint for a resource that combines both resources: the types of the two resources mostly overlap.
+func unionSize(left, right *Resource) int {
+	if len(right.Resources) > len(left.Resources) {
+		return len(right.Resources)
+	}
+	return len(left.Resources)
+}
+
 // Subtract resource returning a new resource with the result
 // A nil resource is considered an empty resource
 // This might return negative values for specific quantities
@@ -299,7 +439,7 @@ func Sub(left, right *Resource) *Resource {
 	}
 
 	// neither are nil, clone one and sub the other
-	out := left.Clone()
+	out := left.cloneWithSize(unionSize(left, right))
 	for k, v := range right.Resources {
 		out.Resources[k] = subVal(out.Resources[k], v)
 	}
@@ -340,7 +480,7 @@ func subNonNegative(left, right *Resource) (*Resource, string) {
 	}
 
 	// neither are nil, clone one and sub the other
-	out := left.Clone()
+	out := left.cloneWithSize(unionSize(left, right))
 	for k, v := range right.Resources {
 		out.Resources[k] = subVal(out.Resources[k], v)
 		// make sure value is not negative
@@ -356,6 +496,24 @@ func subNonNegative(left, right *Resource) (*Resource, string) {
 	return out, message
 }
 
+// Check if smaller fits in larger for the resource types that are defined in larger only.
+// Resource types not defined in larger are not limited, negative values in larger will be treated as 0.
+// A nil larger resource does not limit anything.
+func FitInDefined(larger, smaller *Resource) bool {
+	if larger == nil || smaller == nil {
+		return true
+	}
+	for k, largerValue := range larger.Resources {
+		if largerValue < 0 {
+			largerValue = 0
+		}
+		if smaller.Resources[k] > largerValue {
+			return false
+		}
+	}
+	return true
+}
+
 // Check if smaller fitin larger, negative values will be treated as 0
 // A nil resource is treated as an empty resource (zero)
 func FitIn(larger, smaller *Resource) bool {
@@ -380,6 +538,10 @@ func FitIn(larger, smaller *Resource) bool {
 	return true
 }
 
+// The number of resource types the shares are calculated for without allocating memory.
+// The comparisons run for every pair of nodes or queues that is sorted: they use a buffer on the stack.
+const sharesBufferSize = 8
+
 // Get the share of each resource quantity when compared to the total
 // resources quantity
 // NOTE: shares can be negative and positive in the current assumptions
@@ -388,55 +550,102 @@ func getShares(res, total *Resource) []float64 {
 	if res == nil || len(res.Resources) == 0 {
 		return make([]float64, 0)
 	}
-	shares := make([]float64, len(res.Resources))
-	idx := 0
+	return appendShares(make([]float64, 0, len(res.Resources)), res, total)
+}
+
+// Append the shares of the resource to the slice, see getShares. The shares are sorted in increasing order.
+// The slice is only grown if it does not have the capacity for all resource types.
+func appendShares(shares []float64, res, total *Resource) []float64 {
+	if res == nil {
+		return shares
+	}
 	for k, v := range res.Resources {
 		// no usage then there is no share (skip prevents NaN)
-		// floats init to 0 in the array anyway
 		if v == 0 {
+			shares = append(shares, 0)
 			continue
 		}
 		// Share is usage if total is nil or zero for this resource
 		// Resources are integer so we could divide by 0. Handle it specifically here,
 		// similar to a nil total resource. The check is not to prevent the divide by 0 error.
 		// Compare against zero total resource fails without the check and some sorters use that.
-		if total == nil || total.Resources[k] == 0 {
+		var totalValue Quantity
+		if total != nil {
+			totalValue = total.Resources[k]
+		}
+		if totalValue == 0 {
 			// negative share is logged
 			if v < 0 {
 				log.Logger().Debug("usage is negative no total, share is also negative",
 					zap.String("resource key", k),
 					zap.Int64("resource quantity", int64(v)))
 			}
-			shares[idx] = float64(v)
-			idx++
+			shares = append(shares, float64(v))
 			continue
 		}
-		shares[idx] = float64(v) / float64(total.Resources[k])
+		share := float64(v) / float64(totalValue)
 		// negative share is logged
-		if shares[idx] < 0 {
+		if share < 0 {
 			log.Logger().Debug("share set is negative",
 				zap.String("resource key", k),
 				zap.Int64("resource quantity", int64(v)),
-				zap.Int64("total quantity", int64(total.Resources[k])))
+				zap.Int64("total quantity", int64(totalValue)))
 		}
-		idx++
+		shares = append(shares, share)
 	}
-
-	// sort in increasing order, NaN can not be part of the list
-	sort.Float64s(shares)
+	sortShares(shares)
 	return shares
 }
 
+// Sort the shares in increasing order, NaN can not be part of the list.
+// An insertion sort in place: the number of resource types is small and the sort package would move the slice to
+// the heap.
+func sortShares(shares []float64) {
+	for i := 1; i < len(shares); i++ {
+		for j := i; j > 0 && shares[j] < shares[j-1]; j-- {
+			shares[j], shares[j-1] = shares[j-1], shares[j]
+		}
+	}
+}
+
+// Compare the shares of left of leftTotal and right of rightTotal, see compareShares.
+func compareUsageShares(left, leftTotal, right, rightTotal *Resource) int {
+	var lbuf, rbuf [sharesBufferSize]float64
+	return compareShares(appendShares(lbuf[:0], left, leftTotal), appendShares(rbuf[:0], right, rightTotal))
+}
+
+// The shares of a resource sorted in increasing order.
+// Calculating the shares iterates over the resource: when the same resource is compared many times, for instance when
+// sorting, the shares should be calculated once.
+type Shares []float64
+
+// Get the shares of the resource compared to the total, a nil total means the share is the usage.
+func NewShares(res, total *Resource) Shares {
+	return getShares(res, total)
+}
+
+// Append the shares of the resource compared to the total to the slice, see NewShares.
+// Pass the slice truncated to zero length to reuse it: it is only grown if it does not have the capacity for all
+// resource types.
+func AppendShares(shares Shares, res, total *Resource) Shares {
+	return appendShares(shares, res, total)
+}
+
+// Compare the shares, returns the same value as compareShares:
+// 0 for equal shares
+// 1 if the left share is larger
+// -1 if the right share is larger
+func CompShares(left, right Shares) int {
+	return compareShares(left, right)
+}
+
 // Calculate share for left of total and right of total.
 // This returns the same value as compareShares does:
 // 0 for equal shares
 // 1 if the left share is larger
 // -1 if the right share is larger
 func CompUsageRatio(left, right, total *Resource) int {
-	lshares := getShares(left, total)
-	rshares := getShares(right, total)
-
-	return compareShares(lshares, rshares)
+	return compareUsageShares(left, total, right, total)
 }
 
 // Calculate share for left of total and right of total separately.
@@ -445,10 +654,7 @@ func CompUsageRatio(left, right, total *Resource) int {
 // 1 if the left share is larger
 // -1 if the right share is larger
 func CompUsageRatioSeparately(left, leftTotal, right, rightTotal *Resource) int {
-	lshares := getShares(left, leftTotal)
-	rshares := getShares(right, rightTotal)
-
-	return compareShares(lshares, rshares)
+	return compareUsageShares(left, leftTotal, right, rightTotal)
 }
 
 // Compare two resources usage shares and assumes a nil total resource.
@@ -458,10 +664,7 @@ func CompUsageRatioSeparately(left, leftTotal, right, rightTotal *Resource) int
 // 1 if the left share is larger
 // -1 if the right share is larger
 func CompUsageShares(left, right *Resource) int {
-	lshares := getShares(left, nil)
-	rshares := getShares(right, nil)
-
-	return compareShares(lshares, rshares)
+	return compareUsageShares(left, nil, right, nil)
 }
 
 // Get fairness ratio calculated by:
@@ -555,14 +758,28 @@ func Equals(left, right *Resource) bool {
 		return false
 	}
 
+	// count the types of right that are also defined in left
+	matched := 0
 	for k, v := range left.Resources {
-		if right.Resources[k] != v {
+		rightValue, ok := right.Resources[k]
+		if rightValue != v {
 			return false
 		}
+		if ok {
+			matched++
+		}
+	}
+	// shortcut: all types of right have been compared
+	if matched == len(right.Resources) {
+		return true
 	}
 
+	// the types defined in both have been compared: only the types missing from left must be zero
 	for k, v := range right.Resources {
-		if left.Resources[k] != v {
+		if v == 0 {
+			continue
+		}
+		if _, ok := left.Resources[k]; !ok {
 			return false
 		}
 	}
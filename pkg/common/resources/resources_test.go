@@ -21,6 +21,7 @@ package resources
 import (
 	"math"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"gotest.tools/assert"
@@ -133,6 +134,16 @@ func TestEquals(t *testing.T) {
 	if Equals(base, compare) {
 		t.Errorf("compared resources are equal, should not be: %v / %v", base, compare)
 	}
+	// zero values for types that are only defined on one side
+	base = NewResourceFromMap(map[string]Quantity{"first": 1, "second": 0})
+	compare = NewResourceFromMap(map[string]Quantity{"first": 1, "third": 0})
+	if !Equals(base, compare) {
+		t.Errorf("compared resources are not equal (zero types), should be: %v / %v", base, compare)
+	}
+	compare = NewResourceFromMap(map[string]Quantity{"first": 1, "third": 1})
+	if Equals(base, compare) {
+		t.Errorf("compared resources are equal (extra type), should not be: %v / %v", base, compare)
+	}
 }
 
 func TestIsZero(t *testing.T) {
@@ -495,6 +506,67 @@ func TestResourceProto(t *testing.T) {
 	}
 }
 
+func TestResourceString(t *testing.T) {
+	var nilRes *Resource
+	assert.Equal(t, nilRes.String(), "map[]", "nil resource string not correct")
+	assert.Equal(t, NewResource().String(), "map[]", "empty resource string not correct")
+	assert.Equal(t, NewResource().DAOString(), "[]", "empty resource dao string not correct")
+	// order must be stable independent of the insert order
+	res := NewResourceFromMap(map[string]Quantity{"vcore": 1, "memory": 10, "first": -5})
+	assert.Equal(t, res.String(), "map[first:-5 memory:10 vcore:1]", "resource string not correct")
+	assert.Equal(t, res.DAOString(), "[first:-5 memory:10Mi vcore:1m]", "resource dao string not correct")
+	// memory uses the largest whole unit, vcore is always in milli cores
+	res = NewResourceFromMap(map[string]Quantity{"vcore": 2000, "memory": 4096, "first": 1024})
+	assert.Equal(t, res.String(), "map[first:1024 memory:4096 vcore:2000]", "resource string not correct")
+	assert.Equal(t, res.DAOString(), "[first:1024 memory:4Gi vcore:2000m]", "resource dao string not correct")
+	res = NewResourceFromMap(map[string]Quantity{"vcore": 0, "memory": 0})
+	assert.Equal(t, res.DAOString(), "[memory:0Mi vcore:0m]", "zero resource dao string not correct")
+	res = NewResourceFromMap(map[string]Quantity{"memory": -1536})
+	assert.Equal(t, res.DAOString(), "[memory:-1536Mi]", "negative resource dao string not correct")
+}
+
+func TestParseResource(t *testing.T) {
+	// round trip for both representations
+	for _, res := range []*Resource{
+		NewResourceFromMap(map[string]Quantity{"vcore": 1, "memory": 10, "first": -5, "zero": 0}),
+		NewResourceFromMap(map[string]Quantity{"vcore": 2500, "memory": 2048, "first": 1024}),
+	} {
+		for _, str := range []string{res.String(), res.DAOString()} {
+			parsed, err := ParseResource(str)
+			assert.NilError(t, err, "parsing failed for %s", str)
+			assert.DeepEqual(t, res.Resources, parsed.Resources)
+			assert.Equal(t, parsed.String(), res.String(), "round trip changed the string")
+			assert.Equal(t, parsed.DAOString(), res.DAOString(), "round trip changed the dao string")
+		}
+	}
+	// units are converted, a value without a unit is the tracked quantity
+	res, err := ParseResource("[memory:2Gi vcore:500m]")
+	assert.NilError(t, err, "parsing failed for units")
+	assert.DeepEqual(t, res.Resources, map[string]Quantity{"memory": 2048, "vcore": 500})
+	res, err = ParseResource("[memory:512 vcore:500]")
+	assert.NilError(t, err, "parsing failed without units")
+	assert.DeepEqual(t, res.Resources, map[string]Quantity{"memory": 512, "vcore": 500})
+	// empty representations
+	for _, str := range []string{"", "[]", "map[]", " map[] "} {
+		parsed, err := ParseResource(str)
+		assert.NilError(t, err, "parsing failed for '%s'", str)
+		assert.Equal(t, len(parsed.Resources), 0, "parsed resource should have been empty for '%s'", str)
+	}
+	// resource names with a colon are split on the last colon
+	res, err = ParseResource("[nvidia.com/gpu:2 ns:key:3]")
+	assert.NilError(t, err, "parsing failed for names with a colon")
+	assert.Equal(t, res.Resources["nvidia.com/gpu"], Quantity(2), "gpu value not parsed")
+	assert.Equal(t, res.Resources["ns:key"], Quantity(3), "name with colon not parsed")
+	// failure cases
+	for _, str := range []string{"[memory:10", "memory:10]", "[memory]", "[:10]", "[memory:ten]", "[memory:1 memory:2]", "[memory:1.5]",
+		"[first:2Gi]", "[vcore:1Gi]", "[memory:Gi]", "[memory:9999999999999999Gi]"} {
+		_, err = ParseResource(str)
+		if err == nil {
+			t.Errorf("parsing should have failed for '%s'", str)
+		}
+	}
+}
+
 func TestMultiplyBy(t *testing.T) {
 	// simple case (nil checks)
 	result := MultiplyBy(nil, 0)
@@ -906,6 +978,28 @@ func TestFitIn(t *testing.T) {
 	}
 }
 
+func TestFitInDefined(t *testing.T) {
+	smaller := NewResourceFromMap(map[string]Quantity{"a": 5, "b": 5})
+	if !FitInDefined(nil, smaller) {
+		t.Error("fitin defined in nil resource should not limit")
+	}
+	if !FitInDefined(NewResource(), smaller) {
+		t.Error("fitin defined in empty resource should not limit")
+	}
+	larger := NewResourceFromMap(map[string]Quantity{"a": 5})
+	if !FitInDefined(larger, smaller) {
+		t.Errorf("fitin defined smaller %v should fit in larger %v: type b is not limited", smaller, larger)
+	}
+	larger = NewResourceFromMap(map[string]Quantity{"a": 4, "c": 10})
+	if FitInDefined(larger, smaller) {
+		t.Errorf("fitin defined smaller %v should not fit in larger %v", smaller, larger)
+	}
+	larger = NewResourceFromMap(map[string]Quantity{"a": -5})
+	if FitInDefined(larger, NewResourceFromMap(map[string]Quantity{"a": 1})) {
+		t.Error("fitin defined negative value should be treated as zero")
+	}
+}
+
 func TestGetShares(t *testing.T) {
 	// simple cases nil or empty resources
 	shares := getShares(nil, nil)
@@ -977,6 +1071,22 @@ func TestGetShares(t *testing.T) {
 	}
 }
 
+func TestAppendShares(t *testing.T) {
+	res := &Resource{Resources: map[string]Quantity{"first": 10, "second": 5}}
+	total := &Resource{Resources: map[string]Quantity{"first": 20, "second": 20}}
+	shares := AppendShares(nil, res, total)
+	expected := Shares{0.25, 0.5}
+	if !reflect.DeepEqual(shares, expected) {
+		t.Errorf("incorrect shares, expected %v got: %v", expected, shares)
+	}
+	// a reused slice is not grown and only holds the new shares
+	reused := AppendShares(shares[:0], &Resource{Resources: map[string]Quantity{"first": 20}}, total)
+	expected = Shares{1}
+	if !reflect.DeepEqual(reused, expected) || &reused[0] != &shares[0] {
+		t.Errorf("incorrect reused shares, expected %v got: %v", expected, reused)
+	}
+}
+
 func TestCompareShares(t *testing.T) {
 	// simple cases nil or empty shares
 	comp := compareShares(nil, nil)
@@ -1189,6 +1299,28 @@ func TestCompUsage(t *testing.T) {
 	}
 }
 
+func TestShares(t *testing.T) {
+	left := NewResourceFromMap(map[string]Quantity{"first": 50, "second": 10, "third": 0})
+	right := NewResourceFromMap(map[string]Quantity{"first": 10, "second": 60})
+	total := NewResourceFromMap(map[string]Quantity{"first": 100, "second": 100})
+	assert.DeepEqual(t, NewShares(left, total), Shares{0, 0.1, 0.5})
+	assert.Equal(t, len(NewShares(nil, total)), 0, "nil resource should not have shares")
+	// the precalculated shares compare the same as the resources
+	assert.Equal(t, CompShares(NewShares(left, total), NewShares(right, total)), CompUsageRatio(left, right, total))
+	assert.Equal(t, CompShares(NewShares(left, nil), NewShares(right, nil)), CompUsageShares(left, right))
+	assert.Equal(t, CompShares(NewShares(left, nil), NewShares(left.Clone(), nil)), 0)
+
+	// more resource types than fit in the buffer
+	large := NewResource()
+	for i := 0; i < 2*sharesBufferSize; i++ {
+		large.Resources[strconv.Itoa(i)] = Quantity(2*sharesBufferSize - i)
+	}
+	shares := NewShares(large, nil)
+	assert.Equal(t, len(shares), 2*sharesBufferSize, "unexpected number of shares")
+	assert.Equal(t, shares[0], 1.0, "shares not sorted")
+	assert.Equal(t, CompUsageShares(large, large.Clone()), 0, "large resources should compare equal")
+}
+
 func TestFitInScore(t *testing.T) {
 	// simple case (nil checks)
 	var empty *Resource
@@ -1245,3 +1377,74 @@ func TestFitInScore(t *testing.T) {
 	fit = NewResourceFromMap(map[string]Quantity{"first": 1, "second": 1})
 	assert.Equal(t, res.FitInScore(fit), 2.0, "FitInScore on resource with multiple negative quantities failed")
 }
+
+// Results of the benchmarks are stored to prevent the compiler from removing the calls.
+var (
+	benchResult  *Resource
+	benchCompare int
+	benchEquals  bool
+)
+
+// Resources as used by the scheduler: a few resource types with non zero values.
+func benchResources() (*Resource, *Resource) {
+	left := NewResourceFromMap(map[string]Quantity{MEMORY: 1024, VCORE: 1000, "gpu": 1, "ephemeral-storage": 2048})
+	right := NewResourceFromMap(map[string]Quantity{MEMORY: 2048, VCORE: 500, "gpu": 2, "ephemeral-storage": 1024})
+	return left, right
+}
+
+func BenchmarkEquals(b *testing.B) {
+	left, right := benchResources()
+	same := left.Clone()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchEquals = Equals(left, right) || Equals(left, same)
+	}
+}
+
+func BenchmarkAddTo(b *testing.B) {
+	left, right := benchResources()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		left.AddTo(right)
+		left.SubFrom(right)
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	left, right := benchResources()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchResult = Add(left, right)
+	}
+}
+
+func BenchmarkClone(b *testing.B) {
+	left, _ := benchResources()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchResult = left.Clone()
+	}
+}
+
+func BenchmarkCompUsageShares(b *testing.B) {
+	left, right := benchResources()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchCompare = CompUsageShares(left, right)
+	}
+}
+
+func BenchmarkCompUsageRatio(b *testing.B) {
+	left, right := benchResources()
+	total := Add(left, right)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchCompare = CompUsageRatio(left, right, total)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package resources
+
+import (
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// Return a copy of the resource with the quantities converted from RM units into scheduler units.
+// The factors map a resource type to the number of scheduler units in one RM unit, for example vcore: 1000 for an RM
+// that uses whole cores with a scheduler that uses milli cores. Types without a factor use the same unit in both.
+func ToSchedulerUnits(res *Resource, factors map[string]int64) *Resource {
+	if res == nil || len(factors) == 0 {
+		return res
+	}
+	out := res.Clone()
+	for name, quantity := range out.Resources {
+		if factor, ok := factors[name]; ok {
+			out.Resources[name] = mulVal(quantity, Quantity(factor))
+		}
+	}
+	return out
+}
+
+// Convert the quantities in the proto from RM units into scheduler units.
+// The proto is changed in place, a nil proto or no factors leave the proto unchanged.
+func ToSchedulerUnitsProto(proto *si.Resource, factors map[string]int64) {
+	if proto == nil || len(factors) == 0 {
+		return
+	}
+	for name, quantity := range proto.Resources {
+		if factor, ok := factors[name]; ok && quantity != nil {
+			proto.Resources[name] = &si.Quantity{Value: int64(mulVal(Quantity(quantity.Value), Quantity(factor)))}
+		}
+	}
+}
+
+// Return a copy of the resource with the quantities converted from scheduler units into RM units, rounded down.
+// A nil resource or no factors return the resource as is.
+func ToRMUnits(res *Resource, factors map[string]int64) *Resource {
+	if res == nil || len(factors) == 0 {
+		return res
+	}
+	out := res.Clone()
+	for name, quantity := range out.Resources {
+		if factor, ok := factors[name]; ok {
+			out.Resources[name] = quantity / Quantity(factor)
+		}
+	}
+	return out
+}
+
+// Return a copy of the resource with the quantities rounded down to a whole number of RM units.
+// A nil resource or no factors return the resource as is.
+func RoundDownToRMUnits(res *Resource, factors map[string]int64) *Resource {
+	if res == nil || len(factors) == 0 {
+		return res
+	}
+	out := res.Clone()
+	for name, quantity := range out.Resources {
+		if factor, ok := factors[name]; ok {
+			out.Resources[name] = quantity - quantity%Quantity(factor)
+		}
+	}
+	return out
+}
+
+// Check that all quantities of the resource are a whole number of RM units: only those can be sent to the RM
+// without losing a part of the quantity.
+func IsWholeRMUnits(res *Resource, factors map[string]int64) bool {
+	if res == nil {
+		return true
+	}
+	for name, quantity := range res.Resources {
+		if factor, ok := factors[name]; ok && quantity%Quantity(factor) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the proto with the quantities converted from scheduler units into RM units. A quantity that is not a whole
+// number of RM units is rounded down. The proto passed in is not changed: a nil proto or no factors return the proto
+// as is, otherwise a converted copy is returned.
+func ToRMUnitsProto(proto *si.Resource, factors map[string]int64) *si.Resource {
+	if proto == nil || len(factors) == 0 {
+		return proto
+	}
+	out := &si.Resource{Resources: make(map[string]*si.Quantity, len(proto.Resources))}
+	for name, quantity := range proto.Resources {
+		if factor, ok := factors[name]; ok && quantity != nil {
+			quantity = &si.Quantity{Value: quantity.Value / factor}
+		}
+		out.Resources[name] = quantity
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package resources
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestToSchedulerUnits(t *testing.T) {
+	factors := map[string]int64{"vcore": 1000}
+	assert.Assert(t, ToSchedulerUnits(nil, factors) == nil, "nil resource should stay nil")
+
+	res := NewResourceFromMap(map[string]Quantity{"vcore": 2, "memory": 10})
+	out := ToSchedulerUnits(res, factors)
+	assert.DeepEqual(t, out.Resources, map[string]Quantity{"vcore": 2000, "memory": 10})
+	assert.Equal(t, res.Resources["vcore"], Quantity(2), "original resource should not be changed")
+	// no factors returns the resource unchanged
+	assert.Equal(t, ToSchedulerUnits(res, nil), res)
+
+	proto := &si.Resource{Resources: map[string]*si.Quantity{"vcore": {Value: 2}, "memory": {Value: 10}}}
+	ToSchedulerUnitsProto(nil, factors)
+	ToSchedulerUnitsProto(proto, factors)
+	assert.DeepEqual(t, NewResourceFromProto(proto).Resources, map[string]Quantity{"vcore": 2000, "memory": 10})
+}
+
+func TestToRMUnits(t *testing.T) {
+	factors := map[string]int64{"vcore": 1000}
+	res := NewResourceFromMap(map[string]Quantity{"vcore": 1999, "memory": 10})
+	assert.DeepEqual(t, ToRMUnits(res, factors).Resources, map[string]Quantity{"vcore": 1, "memory": 10})
+	assert.Equal(t, res.Resources["vcore"], Quantity(1999), "original resource should not be changed")
+	assert.Assert(t, ToRMUnits(nil, factors) == nil, "nil resource should stay nil")
+
+	assert.Assert(t, ToRMUnitsProto(nil, factors) == nil, "nil proto should stay nil")
+
+	proto := &si.Resource{Resources: map[string]*si.Quantity{"vcore": {Value: 2500}, "memory": {Value: 10}}}
+	out := ToRMUnitsProto(proto, factors)
+	// partial RM units are rounded down
+	assert.DeepEqual(t, NewResourceFromProto(out).Resources, map[string]Quantity{"vcore": 2, "memory": 10})
+	assert.Equal(t, proto.Resources["vcore"].Value, int64(2500), "original proto should not be changed")
+	assert.Equal(t, ToRMUnitsProto(proto, nil), proto)
+}
+
+func TestRoundDownToRMUnits(t *testing.T) {
+	factors := map[string]int64{"vcore": 1000}
+	assert.Assert(t, RoundDownToRMUnits(nil, factors) == nil, "nil resource should stay nil")
+	res := NewResourceFromMap(map[string]Quantity{"vcore": 1999, "memory": 15})
+	assert.DeepEqual(t, RoundDownToRMUnits(res, factors).Resources, map[string]Quantity{"vcore": 1000, "memory": 15})
+	assert.Equal(t, res.Resources["vcore"], Quantity(1999), "original resource should not be changed")
+	assert.Equal(t, RoundDownToRMUnits(res, nil), res)
+}
+
+func TestIsWholeRMUnits(t *testing.T) {
+	factors := map[string]int64{"vcore": 1000}
+	assert.Assert(t, IsWholeRMUnits(nil, factors), "nil resource should be whole")
+	res := NewResourceFromMap(map[string]Quantity{"vcore": 2000, "memory": 15})
+	assert.Assert(t, IsWholeRMUnits(res, factors), "whole RM units not accepted")
+	res = NewResourceFromMap(map[string]Quantity{"vcore": 1500})
+	assert.Assert(t, !IsWholeRMUnits(res, factors), "partial RM unit should not be whole")
+	assert.Assert(t, IsWholeRMUnits(res, nil), "without factors all resources are whole")
+}
@@ -21,6 +21,7 @@ package security
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"go.uber.org/zap"
@@ -159,3 +160,28 @@ func (a ACL) CheckAccess(userObj UserGroup) bool {
 	}
 	return false
 }
+
+// Canonical string representation of the ACL as used in the configuration.
+// Users and groups are sorted, the output can be converted back into the same ACL using NewACL.
+func (a ACL) String() string {
+	if a.allAllowed {
+		return WildCard
+	}
+	users := sortedNames(a.users)
+	groups := sortedNames(a.groups)
+	if len(groups) == 0 {
+		return strings.Join(users, Separator)
+	}
+	return strings.Join(users, Separator) + Space + strings.Join(groups, Separator)
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name, allowed := range names {
+		if allowed {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -141,3 +141,34 @@ func TestACLAccess(t *testing.T) {
 	user = UserGroup{User: "user1", Groups: []string{"group1"}}
 	assert.Assert(t, !acl.CheckAccess(user), "user1/group1, empty ACL always deny")
 }
+
+func TestACLString(t *testing.T) {
+	var tests = []struct {
+		acl      string
+		expected string
+	}{
+		{"", ""},
+		{"*", "*"},
+		{"user1", "user1"},
+		{"user2,user1", "user1,user2"},
+		{" group2,group1", " group1,group2"},
+		{"user1 group1", "user1 group1"},
+		{"user1 *", "*"},
+		{"user1,invalid! group1", "user1 group1"},
+	}
+	for _, tt := range tests {
+		acl, err := NewACL(tt.acl)
+		if err != nil {
+			t.Fatalf("parsing failed for string: '%s': %v", tt.acl, err)
+		}
+		if acl.String() != tt.expected {
+			t.Errorf("unexpected string for ACL '%s': expected '%s' got '%s'", tt.acl, tt.expected, acl.String())
+		}
+		// the string must parse into the same ACL
+		var parsed ACL
+		parsed, err = NewACL(acl.String())
+		if err != nil || parsed.String() != tt.expected {
+			t.Errorf("string for ACL '%s' did not parse back: '%s' (err: %v)", tt.acl, parsed.String(), err)
+		}
+	}
+}
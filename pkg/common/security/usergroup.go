@@ -36,8 +36,15 @@ const (
 	cleanerInterval = 60  // default cleaner interval
 )
 
+// The resolvers that can be configured for a partition
+const (
+	NoResolver     = ""
+	OSResolver     = "os"
+	StaticResolver = "static"
+	PluginResolver = "plugin"
+)
+
 // global variables
-var now time.Time            // One clock to access
 var instance *UserGroupCache // The instance of the cache
 var once sync.Once           // Make sure we can only create the cache once
 
@@ -50,8 +57,16 @@ type UserGroupCache struct {
 	lookup        func(userName string) (*user.User, error)
 	lookupGroupID func(gid string) (*user.Group, error)
 	groupIds      func(osUser *user.User) ([]string, error)
+	// resolve the groups without the OS user lookup, replaces the lookup functions if set
+	resolveGroups GroupResolver
+	positiveTTL   time.Duration // time to cache a positive lookup, not set means the default
+	negativeTTL   time.Duration // time to cache a failed lookup, not set means the default
+	stop          chan struct{}
 }
 
+// Resolve the groups of a user outside the OS, the user is known if no error is returned.
+type GroupResolver func(userName string) ([]string, error)
+
 // The structure of the entry in the cache.
 type UserGroup struct {
 	User     string
@@ -80,45 +95,92 @@ func GetUserGroupCache(resolver string) *UserGroupCache {
 			instance = GetUserGroupNoResolve()
 		}
 		instance.ugs = make(map[string]*UserGroup)
-		log.Logger().Info("starting UserGroupCache cleaner",
-			zap.String("cleanerInterval", instance.interval.String()))
-		go instance.run()
+		instance.Start()
 	})
 	return instance
 }
 
+// Set the time resolved and failed lookups are cached. A zero duration means the default.
+// Must be called before the cache is used.
+func (c *UserGroupCache) SetTTL(positive, negative time.Duration) {
+	c.positiveTTL = positive
+	c.negativeTTL = negative
+}
+
+// Start the cleanup of the cache, runs until the cache is stopped.
+func (c *UserGroupCache) Start() {
+	log.Logger().Info("starting UserGroupCache cleaner",
+		zap.String("cleanerInterval", c.interval.String()))
+	c.stop = make(chan struct{})
+	go c.run(c.stop)
+}
+
+// Stop the cleanup of the cache. The cache can still be used but entries are only replaced when they expire.
+// The shared cache returned by GetUserGroupCache is never stopped.
+func (c *UserGroupCache) Stop() {
+	if c == instance {
+		log.Logger().Warn("shared UserGroupCache cannot be stopped")
+		return
+	}
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
+
 // Run the cleanup in a separate routine
-func (c *UserGroupCache) run() {
+func (c *UserGroupCache) run(stop chan struct{}) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
 	for {
-		time.Sleep(instance.interval)
-		runStart := time.Now()
-		c.cleanUpCache()
-		log.Logger().Debug("time consumed cleaning the UserGroupCache",
-			zap.String("duration", time.Since(runStart).String()))
+		select {
+		case <-stop:
+			log.Logger().Info("stopped UserGroupCache cleaner")
+			return
+		case <-ticker.C:
+			runStart := time.Now()
+			c.cleanUpCache()
+			log.Logger().Debug("time consumed cleaning the UserGroupCache",
+				zap.String("duration", time.Since(runStart).String()))
+		}
 	}
 }
 
 // Do the real work for the cache cleanup
 func (c *UserGroupCache) cleanUpCache() {
-	oldest := now.Unix() - poscache
-	oldestFailed := now.Unix() - negcache
+	now := time.Now().Unix()
 	// clean up the cache so we do not grow out of bounds
-	instance.lock.Lock()
-	defer instance.lock.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	// walk over the entries in the map and delete the expired ones, cleanup based on the resolved time.
 	// Negative cached entries will expire quicker
 	for key, val := range c.ugs {
-		if val.resolved < oldest || (val.failed && val.resolved < oldestFailed) {
+		if c.expired(val, now) {
 			delete(c.ugs, key)
 		}
 	}
 }
 
+// Check if the entry has been in the cache longer than the TTL for the entry, in seconds.
+func (c *UserGroupCache) expired(ug *UserGroup, now int64) bool {
+	ttl := int64(poscache)
+	if c.positiveTTL > 0 {
+		ttl = int64(c.positiveTTL.Seconds())
+	}
+	if ug.failed {
+		ttl = negcache
+		if c.negativeTTL > 0 {
+			ttl = int64(c.negativeTTL.Seconds())
+		}
+	}
+	return ug.resolved < now-ttl
+}
+
 // reset the cached content, test use only
 func (c *UserGroupCache) resetCache() {
 	log.Logger().Debug("UserGroupCache reset")
-	instance.lock.Lock()
-	defer instance.lock.Unlock()
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	c.ugs = make(map[string]*UserGroup)
 }
 
@@ -134,7 +196,7 @@ func (c *UserGroupCache) ConvertUGI(ugi *si.UserGroupInformation) (UserGroup, er
 	// If groups are already present we should just convert
 	newUG := UserGroup{User: ugi.User}
 	newUG.Groups = append(newUG.Groups, ugi.Groups...)
-	newUG.resolved = now.Unix()
+	newUG.resolved = time.Now().Unix()
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	c.ugs[ugi.User] = &newUG
@@ -143,7 +205,7 @@ func (c *UserGroupCache) ConvertUGI(ugi *si.UserGroupInformation) (UserGroup, er
 
 // Get the user group information. An error will still return a UserGroup.
 // The Failed flag in the object will be set to true for any failures.
-// The information is cached, negatively and positively.
+// The information is cached, negatively and positively, expired entries are resolved again.
 func (c *UserGroupCache) GetUserGroup(userName string) (UserGroup, error) {
 	// check if we have a user to resolve
 	if userName == "" {
@@ -153,21 +215,50 @@ func (c *UserGroupCache) GetUserGroup(userName string) (UserGroup, error) {
 	c.lock.RLock()
 	ug, ok := c.ugs[userName]
 	c.lock.RUnlock()
-	// return if this was not a negative cache that has not timed out
-	if ok && !ug.failed {
+	if ok && !c.expired(ug, time.Now().Unix()) {
+		// if we failed before we could get an object back, return the existing one with an error
+		if ug.failed {
+			return *ug, fmt.Errorf("user resolution failed, cached data returned: %v", time.Unix(ug.resolved, 0))
+		}
 		return *ug, nil
 	}
-	// nothing returned so create a new one
-	if ug == nil {
-		ug = &UserGroup{
-			User: userName,
-		}
+	// nothing returned or expired so create a new one
+	ug = &UserGroup{
+		User: userName,
 	}
-	// if we failed before we could get an object back, return the existing one with an error
-	if ug.failed {
-		return *ug, fmt.Errorf("user resolution failed, cached data returned: %v", time.Unix(ug.resolved, 0))
+	var err error
+	if c.resolveGroups != nil {
+		err = ug.resolveExternal(c.resolveGroups)
+	} else {
+		err = ug.resolveOS(c)
 	}
-	// resolve if we do not have it in the cache
+	// all resolved (or not) but use this time stamp
+	ug.resolved = time.Now().Unix()
+
+	// add it to the cache, even if we fail negative cache is also good to know
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ugs[userName] = ug
+	return *ug, err
+}
+
+// Resolve the user and groups using the group resolver.
+func (ug *UserGroup) resolveExternal(resolver GroupResolver) error {
+	groups, err := resolver(ug.User)
+	if err != nil {
+		log.Logger().Error("Error resolving groups for user",
+			zap.String("userName", ug.User),
+			zap.Error(err))
+		ug.failed = true
+		return err
+	}
+	ug.Groups = append(ug.Groups, groups...)
+	return nil
+}
+
+// Resolve the user and groups using the lookup functions of the cache.
+func (ug *UserGroup) resolveOS(c *UserGroupCache) error {
+	userName := ug.User
 	// find the user first, then resolve the groups
 	osUser, err := c.lookup(userName)
 	if err != nil {
@@ -188,14 +279,7 @@ func (c *UserGroupCache) GetUserGroup(userName string) (UserGroup, error) {
 			ug.failed = true
 		}
 	}
-	// all resolved (or not) but use this time stamp
-	ug.resolved = now.Unix()
-
-	// add it to the cache, even if we fail negative cache is also good to know
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	c.ugs[userName] = ug
-	return *ug, err
+	return err
 }
 
 // Resolve the groups for the user if the user exists
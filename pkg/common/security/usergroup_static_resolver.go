@@ -0,0 +1,40 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package security
+
+import (
+	"time"
+)
+
+// Get the cache with a static user to group mapping.
+// A user that is not in the mapping is not a member of any group, the lookup never fails.
+// The mapping is copied: later changes to the passed in map have no effect.
+func GetUserGroupCacheStatic(userGroups map[string][]string) *UserGroupCache {
+	mapping := make(map[string][]string, len(userGroups))
+	for userName, groups := range userGroups {
+		mapping[userName] = append([]string{}, groups...)
+	}
+	return &UserGroupCache{
+		ugs:      map[string]*UserGroup{},
+		interval: cleanerInterval * time.Second,
+		resolveGroups: func(userName string) ([]string, error) {
+			return mapping[userName], nil
+		},
+	}
+}
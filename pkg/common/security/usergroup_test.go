@@ -19,8 +19,10 @@
 package security
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
@@ -232,3 +234,80 @@ func TestConvertUGI(t *testing.T) {
 		t.Errorf("groups not initialised correctly on convert: expected '%s' got '%s'", group, ug.Groups[0])
 	}
 }
+
+func TestStaticResolver(t *testing.T) {
+	mapping := map[string][]string{"user1": {"group1", "group2"}}
+	testCache := GetUserGroupCacheStatic(mapping)
+	// later changes to the mapping must not leak into the cache
+	mapping["user1"][0] = "changed"
+	ug, err := testCache.GetUserGroup("user1")
+	if err != nil || len(ug.Groups) != 2 || ug.Groups[0] != "group1" || ug.failed {
+		t.Errorf("User 'user1' not resolved correctly: %v (err = %v)", ug, err)
+	}
+	// unknown users resolve without groups
+	ug, err = testCache.GetUserGroup("user2")
+	if err != nil || len(ug.Groups) != 0 || ug.failed {
+		t.Errorf("User 'user2' not resolved correctly: %v (err = %v)", ug, err)
+	}
+}
+
+func TestResolverTTL(t *testing.T) {
+	calls := 0
+	testCache := GetUserGroupCacheResolver(func(userName string) ([]string, error) {
+		calls++
+		if userName == "unknown" {
+			return nil, fmt.Errorf("unknown user")
+		}
+		return []string{"group" + userName}, nil
+	})
+	testCache.SetTTL(time.Minute, 10*time.Second)
+
+	// repeated lookups are served from the cache
+	for i := 0; i < 3; i++ {
+		ug, err := testCache.GetUserGroup("1")
+		if err != nil || len(ug.Groups) != 1 || ug.Groups[0] != "group1" {
+			t.Fatalf("User '1' not resolved correctly: %v (err = %v)", ug, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("resolver should have been called once: %d", calls)
+	}
+	// expire the entry: resolved again
+	testCache.ugs["1"].resolved -= 61
+	_, err := testCache.GetUserGroup("1")
+	if err != nil || calls != 2 {
+		t.Errorf("expired user should have been resolved again: calls %d (err = %v)", calls, err)
+	}
+
+	// failures are cached with the negative TTL
+	_, err = testCache.GetUserGroup("unknown")
+	if err == nil || calls != 3 {
+		t.Fatalf("unknown user should have failed: calls %d", calls)
+	}
+	_, err = testCache.GetUserGroup("unknown")
+	if err == nil || !strings.Contains(err.Error(), "cached data returned") || calls != 3 {
+		t.Errorf("failure should have been returned from the cache: calls %d (err = %v)", calls, err)
+	}
+	testCache.ugs["unknown"].resolved -= 11
+	testCache.cleanUpCache()
+	if len(testCache.ugs) != 1 {
+		t.Errorf("expired failure not cleaned up: %v", testCache.ugs)
+	}
+}
+
+func TestStopCache(t *testing.T) {
+	testCache := GetUserGroupCacheStatic(nil)
+	testCache.Start()
+	testCache.Stop()
+	if testCache.stop != nil {
+		t.Error("cleaner not stopped")
+	}
+	// stopping twice is safe
+	testCache.Stop()
+	// the shared cache is never stopped
+	shared := GetUserGroupCache("test")
+	shared.Stop()
+	if shared.stop == nil {
+		t.Error("shared cache should not have been stopped")
+	}
+}
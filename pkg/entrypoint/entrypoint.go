@@ -19,6 +19,8 @@
 package entrypoint
 
 import (
+	"time"
+
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 	"github.com/apache/incubator-yunikorn-core/pkg/handler"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
@@ -52,6 +54,17 @@ func StartAllServicesWithManualScheduler() *ServiceContext {
 		})
 }
 
+// Start only the web service as a read only mirror of a scheduler, see webservice.NewMirrorWebApp.
+// The mirror serves the state snapshots returned by the loader, no scheduling services are started.
+func StartMirrorServices(load webservice.SnapshotLoader, interval time.Duration) *ServiceContext {
+	log.Logger().Info("ServiceContext start read only web application mirror")
+	webapp := webservice.NewMirrorWebApp(load, interval)
+	webapp.StartWebApp()
+	return &ServiceContext{
+		WebApp: webapp,
+	}
+}
+
 func startAllServicesWithParameters(opts StartupOptions) *ServiceContext {
 	cache := cache.NewClusterInfo()
 	scheduler := scheduler.NewScheduler(cache)
@@ -77,7 +90,7 @@ func startAllServicesWithParameters(opts StartupOptions) *ServiceContext {
 
 	if opts.startWebAppFlag {
 		log.Logger().Info("ServiceContext start web application service")
-		webapp := webservice.NewWebApp(cache)
+		webapp := webservice.NewWebApp(cache, scheduler.GetClusterSchedulingContext())
 		webapp.StartWebApp()
 		context.WebApp = webapp
 	}
@@ -21,7 +21,7 @@ package examples
 import (
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/entrypoint"
-	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/tests"
+	"github.com/apache/incubator-yunikorn-core/pkg/testutils"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -62,7 +62,7 @@ partitions:
 	configs.MockSchedulerConfigByData([]byte(configData))
 
 	// Register RM
-	mockRM := tests.NewMockRMCallbackHandler() // [CHANGE THIS], should use your own implementation of api.ResourceManagerCallback
+	mockRM := testutils.NewMockRMCallbackHandler() // [CHANGE THIS], should use your own implementation of api.ResourceManagerCallback
 
 	_, err := proxy.RegisterResourceManager(
 		&si.RegisterResourceManagerRequest{
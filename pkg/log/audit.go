@@ -0,0 +1,41 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package log
+
+import (
+	"go.uber.org/zap"
+)
+
+// Audit events: changes made by the scheduler itself that are not directly requested by the RM or the configuration.
+const (
+	AuditQueueRemoved         = "QueueRemoved"
+	AuditApplicationCompleted = "ApplicationCompleted"
+	AuditApplicationMoved     = "ApplicationMoved"
+	AuditApplicationKilled    = "ApplicationKilled"
+)
+
+// The audit log is a named logger: audit events can be filtered from the scheduler log by the logger name.
+func AuditLogger() *zap.Logger {
+	return Logger().Named("audit")
+}
+
+// Log the audit event with the fields describing the event.
+func Audit(event string, fields ...zap.Field) {
+	AuditLogger().Info("audit event", append([]zap.Field{zap.String("event", event)}, fields...)...)
+}
@@ -0,0 +1,44 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAudit(t *testing.T) {
+	// make sure the logger is initialised before replacing it
+	current := Logger()
+	defer func() { logger = current }()
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger = zap.New(core)
+
+	Audit(AuditQueueRemoved, zap.String("queueName", "root.test"))
+	entries := logs.All()
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "audit", entries[0].LoggerName)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, AuditQueueRemoved, fields["event"])
+	assert.Equal(t, "root.test", fields["queueName"])
+}
@@ -0,0 +1,218 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Decision types: one JSON line is written to the decision log per decision.
+const (
+	DecisionAllocation = "Allocation"
+	DecisionRejection  = "Rejection"
+	DecisionPreemption = "Preemption"
+)
+
+// Defaults for the decision log rotation.
+const (
+	DefaultDecisionLogSize  = 100 * 1024 * 1024
+	DefaultDecisionLogFiles = 5
+)
+
+// A scheduler decision as written to the decision log.
+// The resource is the quantity per resource type of the allocation the decision is about.
+type Decision struct {
+	Time          int64            `json:"time"`
+	Type          string           `json:"type"`
+	Partition     string           `json:"partition"`
+	ApplicationID string           `json:"applicationID"`
+	QueueName     string           `json:"queueName,omitempty"`
+	AllocationKey string           `json:"allocationKey,omitempty"`
+	UUID          string           `json:"uuid,omitempty"`
+	NodeID        string           `json:"nodeID,omitempty"`
+	Resource      map[string]int64 `json:"resource,omitempty"`
+	Reason        string           `json:"reason,omitempty"`
+}
+
+// The decision log file with size based rotation: when the file would grow beyond the maximum size it is renamed to
+// path.1, an existing path.1 to path.2 etc. Only the configured number of rotated files is kept.
+type decisionLog struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+
+	sync.Mutex
+}
+
+var decisions *decisionLog
+var decisionLock sync.RWMutex
+
+// Start writing the decisions to the file at the path, appending to the file if it exists.
+// The embedding process calls this to turn the decision log on, it is off by default.
+// A zero or negative size or number of files uses the default.
+func StartDecisionLog(path string, maxSize int64, maxFiles int) error {
+	if path == "" {
+		return fmt.Errorf("decision log path is not set")
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultDecisionLogSize
+	}
+	if maxFiles <= 0 {
+		maxFiles = DefaultDecisionLogFiles
+	}
+	dl := &decisionLog{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := dl.open(); err != nil {
+		return err
+	}
+	decisionLock.Lock()
+	previous := decisions
+	decisions = dl
+	decisionLock.Unlock()
+	if previous != nil {
+		return previous.close()
+	}
+	return nil
+}
+
+// Stop writing the decisions and close the decision log file.
+func StopDecisionLog() error {
+	decisionLock.Lock()
+	previous := decisions
+	decisions = nil
+	decisionLock.Unlock()
+	if previous != nil {
+		return previous.close()
+	}
+	return nil
+}
+
+// Is the decision log turned on.
+func IsDecisionLogEnabled() bool {
+	decisionLock.RLock()
+	defer decisionLock.RUnlock()
+	return decisions != nil
+}
+
+// Write the decision to the decision log, the time is set if it is not.
+// Nothing is written if the decision log is not turned on. A failed write is logged and the decision is dropped:
+// the decision log must never block scheduling.
+func LogDecision(decision *Decision) {
+	decisionLock.RLock()
+	defer decisionLock.RUnlock()
+	if decisions == nil || decision == nil {
+		return
+	}
+	if decision.Time == 0 {
+		decision.Time = time.Now().UnixNano()
+	}
+	line, err := json.Marshal(decision)
+	if err == nil {
+		err = decisions.write(append(line, '\n'))
+	}
+	if err != nil {
+		Logger().Warn("failed to write scheduler decision",
+			zap.String("type", decision.Type),
+			zap.String("appID", decision.ApplicationID),
+			zap.Error(err))
+	}
+}
+
+func (dl *decisionLog) open() error {
+	file, err := os.OpenFile(dl.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot open decision log: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		if closeErr := file.Close(); closeErr != nil {
+			Logger().Debug("failed to close decision log", zap.Error(closeErr))
+		}
+		return fmt.Errorf("cannot open decision log: %v", err)
+	}
+	dl.file = file
+	dl.size = info.Size()
+	return nil
+}
+
+func (dl *decisionLog) close() error {
+	dl.Lock()
+	defer dl.Unlock()
+	if dl.file == nil {
+		return nil
+	}
+	err := dl.file.Close()
+	dl.file = nil
+	return err
+}
+
+// Write the line, rotate the file first if the line does not fit.
+// An empty file is never rotated: a line larger than the maximum size is still written.
+func (dl *decisionLog) write(line []byte) error {
+	dl.Lock()
+	defer dl.Unlock()
+	if dl.file == nil {
+		return fmt.Errorf("decision log is closed")
+	}
+	if dl.size > 0 && dl.size+int64(len(line)) > dl.maxSize {
+		if err := dl.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := dl.file.Write(line)
+	dl.size += int64(n)
+	return err
+}
+
+// Rotate the files: the oldest file is overwritten by the rename.
+// Lock free call, must be called holding the decision log lock.
+func (dl *decisionLog) rotate() error {
+	if err := dl.file.Close(); err != nil {
+		return err
+	}
+	dl.file = nil
+	var err error
+	for i := dl.maxFiles - 1; i > 0 && err == nil; i-- {
+		from := dl.path + "." + strconv.Itoa(i)
+		if _, statErr := os.Stat(from); statErr != nil {
+			continue
+		}
+		err = os.Rename(from, dl.path+"."+strconv.Itoa(i+1))
+	}
+	if err == nil {
+		err = os.Rename(dl.path, dl.path+".1")
+	}
+	// always reopen: a failed rename appends to the current file
+	if openErr := dl.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// read the decisions from the file, one per line
+func readDecisions(t *testing.T, path string) []*Decision {
+	content, err := ioutil.ReadFile(path)
+	assert.NilError(t, err, "failed to read decision log %s", path)
+	var result []*Decision
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		decision := &Decision{}
+		assert.NilError(t, json.Unmarshal([]byte(line), decision), "decision is not valid JSON: %s", line)
+		result = append(result, decision)
+	}
+	return result
+}
+
+// create a temporary directory for the decision log, removed when the test is done
+func createDecisionDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "decisions")
+	assert.NilError(t, err, "failed to create temporary directory")
+	return dir, func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Logf("failed to remove temporary directory: %v", err)
+		}
+	}
+}
+
+func TestDecisionLog(t *testing.T) {
+	dir, cleanup := createDecisionDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "decisions.log")
+
+	// not started: nothing is written
+	assert.Assert(t, !IsDecisionLogEnabled(), "decision log should not be enabled")
+	LogDecision(&Decision{Type: DecisionAllocation, ApplicationID: "app-0"})
+	_, err := os.Stat(path)
+	assert.Assert(t, os.IsNotExist(err), "decision log should not exist when not started")
+	assert.Assert(t, StartDecisionLog("", 0, 0) != nil, "start without a path should fail")
+
+	assert.NilError(t, StartDecisionLog(path, 0, 0))
+	defer func() {
+		assert.NilError(t, StopDecisionLog())
+	}()
+	assert.Assert(t, IsDecisionLogEnabled(), "decision log should be enabled")
+	LogDecision(&Decision{
+		Type:          DecisionAllocation,
+		Partition:     "default",
+		ApplicationID: "app-1",
+		QueueName:     "root.a",
+		AllocationKey: "ask-1",
+		UUID:          "uuid-1",
+		NodeID:        "node-1",
+		Resource:      map[string]int64{"memory": 10},
+	})
+	LogDecision(&Decision{Type: DecisionRejection, ApplicationID: "app-1", Reason: "node full", Time: 1})
+	decisions := readDecisions(t, path)
+	assert.Equal(t, len(decisions), 2)
+	assert.Equal(t, decisions[0].Type, DecisionAllocation)
+	assert.Equal(t, decisions[0].UUID, "uuid-1")
+	assert.Equal(t, decisions[0].Resource["memory"], int64(10))
+	assert.Assert(t, decisions[0].Time > 0, "time should be set on the decision")
+	assert.Equal(t, decisions[1].Reason, "node full")
+	assert.Equal(t, decisions[1].Time, int64(1))
+
+	// stop and restart appends to the existing file
+	assert.NilError(t, StopDecisionLog())
+	assert.Assert(t, !IsDecisionLogEnabled(), "decision log should not be enabled")
+	assert.NilError(t, StartDecisionLog(path, 0, 0))
+	LogDecision(&Decision{Type: DecisionPreemption, ApplicationID: "app-2"})
+	assert.Equal(t, len(readDecisions(t, path)), 3)
+}
+
+func TestDecisionLogRotation(t *testing.T) {
+	dir, cleanup := createDecisionDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "decisions.log")
+
+	decision := &Decision{Type: DecisionAllocation, ApplicationID: "app-1", Time: 1}
+	line, err := json.Marshal(decision)
+	assert.NilError(t, err, "failed to marshal decision")
+	// room for two lines per file, keep two rotated files
+	assert.NilError(t, StartDecisionLog(path, int64(2*(len(line)+1)), 2))
+	defer func() {
+		assert.NilError(t, StopDecisionLog())
+	}()
+	for i := 0; i < 7; i++ {
+		LogDecision(decision)
+	}
+	assert.Equal(t, len(readDecisions(t, path)), 1)
+	assert.Equal(t, len(readDecisions(t, path+".1")), 2)
+	assert.Equal(t, len(readDecisions(t, path+".2")), 2)
+	_, err = os.Stat(path + ".3")
+	assert.Assert(t, os.IsNotExist(err), "only the configured number of rotated files should be kept")
+}
@@ -42,11 +42,36 @@ type Metrics struct {
 }
 
 type CoreQueueMetrics interface {
+	// Metrics Ops related to the application lifecycle counters
+	IncApplicationsSubmitted()
 	IncApplicationsAccepted()
 	IncApplicationsRejected()
+	IncApplicationsRunning()
 	IncApplicationsCompleted()
+	IncApplicationsKilled()
+
+	// Metrics Ops related to the current number of applications
+	IncPendingApplications()
+	DecPendingApplications()
+	IncRunningApplications()
+	DecRunningApplications()
+
+	// Metrics Ops related to allocations beyond the soft max of the queue
+	IncAllocationsOverSoftMax()
+
+	// Metrics Ops related to allocations beyond the max of the queue with soft enforcement
+	IncAllocationsOverMax()
+
+	// Metrics Ops related to the allocation cap per scheduling cycle of the queue
+	IncCyclesCapped()
+
 	AddQueueUsedResourceMetrics(resourceName string, value float64)
 	SetQueueUsedResourceMetrics(resourceName string, value float64)
+
+	// Metrics Ops related to the fair share of the queue compared to the usage
+	SetQueueFairShare(value float64)
+	SetQueueUsageShare(value float64)
+	SetQueueFairnessDebt(value float64)
 }
 
 // Declare all core metrics ops in this interface
@@ -104,6 +129,30 @@ type CoreSchedulerMetrics interface {
 	SetFailedNodes(value int)
 	SetNodeResourceUsage(resourceName string, rangeIdx int, value float64)
 
+	// Metrics Ops related to reapedReservations
+	IncReapedReservations()
+	AddReapedReservations(value int)
+
+	// Metrics Ops related to preemption victims
+	AddPreemptedAllocations(value int)
+	IncPreemptionProtectedAllocations()
+
+	// Metrics Ops related to allocation rollbacks
+	AddCommitRollbacks(value int)
+	AddDeliveryRollbacks(value int)
+
+	// Metrics Ops related to asks that used up the node evaluation budget
+	IncAskBudgetExceeded()
+
+	// Metrics Ops related to nodes with allocations that drifted from the RM
+	IncAllocationDrifts()
+
+	// Metrics Ops related to nodes quarantined for flapping
+	IncQuarantinedNodes()
+
+	// Metrics Ops related to application runtime estimates
+	ObserveRuntimeEstimate(estimate, actual time.Duration)
+
 	//latency change
 	ObserveSchedulingLatency(start time.Time)
 	ObserveNodeSortingLatency(start time.Time)
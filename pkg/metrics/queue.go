@@ -30,12 +30,23 @@ import (
 
 type QueueMetrics struct {
 	// metrics related to app
-	appMetrics *prometheus.CounterVec
+	appMetrics        *prometheus.CounterVec
+	appCurrentMetrics *prometheus.GaugeVec
+
+	// metrics related to allocations
+	overSoftMaxMetrics prometheus.Counter
+	overMaxMetrics     prometheus.Counter
+	cycleCapMetrics    prometheus.Counter
 
 	// metrics related to resource
 	usedResourceMetrics      *prometheus.GaugeVec
 	pendingResourceMetrics   *prometheus.GaugeVec
 	availableResourceMetrics *prometheus.GaugeVec
+
+	// metrics related to fairness
+	fairShareMetrics    prometheus.Gauge
+	usageShareMetrics   prometheus.Gauge
+	fairnessDebtMetrics prometheus.Gauge
 }
 
 func forQueue(name string) CoreQueueMetrics {
@@ -50,6 +61,38 @@ func forQueue(name string) CoreQueueMetrics {
 			Help:      "Application Metrics",
 		}, []string{"state"})
 
+	q.appCurrentMetrics = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: substituteQueueName(name),
+			Name:      "app_current",
+			Help:      "Current number of applications",
+		}, []string{"state"})
+
+	q.overSoftMaxMetrics = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: substituteQueueName(name),
+			Name:      "allocations_over_soft_max",
+			Help:      "Number of allocations made while the queue is over its soft max resource",
+		})
+
+	q.overMaxMetrics = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: substituteQueueName(name),
+			Name:      "allocations_over_max",
+			Help:      "Number of allocations made while the queue is over its max resource with soft enforcement",
+		})
+
+	q.cycleCapMetrics = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: substituteQueueName(name),
+			Name:      "cycles_capped",
+			Help:      "Number of scheduling cycles in which the queue reached its allocation cap",
+		})
+
 	q.usedResourceMetrics = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: Namespace,
@@ -74,11 +117,42 @@ func forQueue(name string) CoreQueueMetrics {
 			Help:      "used resource metrics related to queues etc.",
 		}, []string{"resource"})
 
+	q.fairShareMetrics = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: substituteQueueName(name),
+			Name:      "fair_share",
+			Help:      "Normalized fair share of the queue, the entitlement as a share of the partition",
+		})
+
+	q.usageShareMetrics = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: substituteQueueName(name),
+			Name:      "usage_share",
+			Help:      "Dominant share of the partition used by the queue",
+		})
+
+	q.fairnessDebtMetrics = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: substituteQueueName(name),
+			Name:      "fairness_debt_seconds",
+			Help:      "Integral of the fair share minus the usage share over the fairness window, in share seconds",
+		})
+
 	var queueMetricsList = []prometheus.Collector{
 		q.appMetrics,
+		q.appCurrentMetrics,
+		q.overSoftMaxMetrics,
+		q.overMaxMetrics,
+		q.cycleCapMetrics,
 		q.usedResourceMetrics,
 		q.pendingResourceMetrics,
 		q.availableResourceMetrics,
+		q.fairShareMetrics,
+		q.usageShareMetrics,
+		q.fairnessDebtMetrics,
 	}
 
 	// Register the metrics.
@@ -100,6 +174,10 @@ func substituteQueueName(queueName string) string {
 	return strings.Replace(str, "-", "_", -1)
 }
 
+func (m *QueueMetrics) IncApplicationsSubmitted() {
+	m.appMetrics.With(prometheus.Labels{"state": "submitted"}).Inc()
+}
+
 func (m *QueueMetrics) IncApplicationsAccepted() {
 	m.appMetrics.With(prometheus.Labels{"state": "accepted"}).Inc()
 }
@@ -108,10 +186,46 @@ func (m *QueueMetrics) IncApplicationsRejected() {
 	m.appMetrics.With(prometheus.Labels{"state": "rejected"}).Inc()
 }
 
+func (m *QueueMetrics) IncApplicationsRunning() {
+	m.appMetrics.With(prometheus.Labels{"state": "running"}).Inc()
+}
+
 func (m *QueueMetrics) IncApplicationsCompleted() {
 	m.appMetrics.With(prometheus.Labels{"state": "completed"}).Inc()
 }
 
+func (m *QueueMetrics) IncApplicationsKilled() {
+	m.appMetrics.With(prometheus.Labels{"state": "killed"}).Inc()
+}
+
+func (m *QueueMetrics) IncPendingApplications() {
+	m.appCurrentMetrics.With(prometheus.Labels{"state": "pending"}).Inc()
+}
+
+func (m *QueueMetrics) DecPendingApplications() {
+	m.appCurrentMetrics.With(prometheus.Labels{"state": "pending"}).Dec()
+}
+
+func (m *QueueMetrics) IncRunningApplications() {
+	m.appCurrentMetrics.With(prometheus.Labels{"state": "running"}).Inc()
+}
+
+func (m *QueueMetrics) DecRunningApplications() {
+	m.appCurrentMetrics.With(prometheus.Labels{"state": "running"}).Dec()
+}
+
+func (m *QueueMetrics) IncAllocationsOverSoftMax() {
+	m.overSoftMaxMetrics.Inc()
+}
+
+func (m *QueueMetrics) IncAllocationsOverMax() {
+	m.overMaxMetrics.Inc()
+}
+
+func (m *QueueMetrics) IncCyclesCapped() {
+	m.cycleCapMetrics.Inc()
+}
+
 func (m *QueueMetrics) AddQueueUsedResourceMetrics(resourceName string, value float64) {
 	m.usedResourceMetrics.With(prometheus.Labels{"resource": resourceName}).Add(value)
 }
@@ -119,3 +233,15 @@ func (m *QueueMetrics) AddQueueUsedResourceMetrics(resourceName string, value fl
 func (m *QueueMetrics) SetQueueUsedResourceMetrics(resourceName string, value float64) {
 	m.usedResourceMetrics.With(prometheus.Labels{"resource": resourceName}).Set(value)
 }
+
+func (m *QueueMetrics) SetQueueFairShare(value float64) {
+	m.fairShareMetrics.Set(value)
+}
+
+func (m *QueueMetrics) SetQueueUsageShare(value float64) {
+	m.usageShareMetrics.Set(value)
+}
+
+func (m *QueueMetrics) SetQueueFairnessDebt(value float64) {
+	m.fairnessDebtMetrics.Set(value)
+}
@@ -56,6 +56,18 @@ type SchedulerMetrics struct {
 	totalApplicationsCompleted prometheus.Gauge
 	activeNodes                prometheus.Gauge
 	failedNodes                prometheus.Gauge
+	reapedReservations         prometheus.Counter
+	preemptionVictims          *prometheus.CounterVec
+	preemptedAllocations       prometheus.Counter
+	protectedAllocations       prometheus.Counter
+	allocationRollbacks        *prometheus.CounterVec
+	commitRollbacks            prometheus.Counter
+	deliveryRollbacks          prometheus.Counter
+	allocationDrifts           prometheus.Counter
+	quarantinedNodes           prometheus.Counter
+	askBudgetExceeded          prometheus.Counter
+	runtimeEstimates           *prometheus.CounterVec
+	runtimeEstimateRatio       prometheus.Histogram
 	nodesResourceUsages        map[string]*prometheus.GaugeVec
 	schedulingLatency          prometheus.Histogram
 	nodeSortingLatency         prometheus.Histogram
@@ -127,6 +139,55 @@ func initSchedulerMetrics() *SchedulerMetrics {
 			Help:      "failed nodes",
 		})
 
+	// Reservations
+	s.reapedReservations = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "reaped_reservations",
+			Help:      "stale reservations removed by the reservation reaper",
+		})
+
+	// Preemption
+	s.preemptionVictims = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "preemption_victims",
+			Help:      "Number of allocations selected as preemption victim, by the result. protected means the allocation was skipped as it has not run for the minimum runtime",
+		}, []string{"result"})
+	s.preemptedAllocations = s.preemptionVictims.With(prometheus.Labels{"result": "preempted"})
+	s.protectedAllocations = s.preemptionVictims.With(prometheus.Labels{"result": "protected"})
+
+	// Rollback of allocations that failed to commit in the cache or could not be delivered to the RM
+	s.allocationRollbacks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "allocation_rollbacks",
+			Help:      "Number of allocations rolled back and re-queued, by the stage that failed. commit means the cache rejected the allocation, delivery means the RM did not receive it",
+		}, []string{"stage"})
+	s.commitRollbacks = s.allocationRollbacks.With(prometheus.Labels{"stage": "commit"})
+	s.deliveryRollbacks = s.allocationRollbacks.With(prometheus.Labels{"stage": "delivery"})
+
+	// Application runtime estimates
+	s.runtimeEstimates = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "app_runtime_estimates",
+			Help:      "Number of finished applications with a runtime estimate, by the result. exceeded means the application ran longer than estimated",
+		}, []string{"result"})
+	s.runtimeEstimateRatio = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "app_runtime_estimate_ratio",
+			Help:      "ratio of the actual runtime to the estimated runtime of finished applications",
+			Buckets:   []float64{0.25, 0.5, 0.75, 0.9, 1, 1.1, 1.25, 1.5, 2, 4},
+		},
+	)
+
 	s.schedulingLatency = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: Namespace,
@@ -146,6 +207,33 @@ func initSchedulerMetrics() *SchedulerMetrics {
 			Buckets:   prometheus.ExponentialBuckets(0.0001, 10, 6), //start from 0.1ms
 		},
 	)
+	// Nodes with allocations that drifted from the allocations confirmed by the RM
+	s.allocationDrifts = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "allocation_drifts",
+			Help:      "Number of times the allocations of a node drifted from the allocations confirmed by the RM",
+		})
+
+	// Nodes that were quarantined because they registered and were removed too often
+	s.quarantinedNodes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "quarantined_nodes",
+			Help:      "Number of times a node was quarantined because it registered and was removed too often",
+		})
+
+	// Asks that stopped the node evaluation because the budget was used up
+	s.askBudgetExceeded = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: SchedulerSubsystem,
+			Name:      "ask_budget_exceeded",
+			Help:      "Number of times the node evaluation for an ask stopped because the ask budget was used up",
+		})
+
 	var metricsList = []prometheus.Collector{
 		s.allocations,
 		s.scheduleApplications,
@@ -155,6 +243,14 @@ func initSchedulerMetrics() *SchedulerMetrics {
 		s.totalApplicationsCompleted,
 		s.activeNodes,
 		s.failedNodes,
+		s.reapedReservations,
+		s.preemptionVictims,
+		s.allocationRollbacks,
+		s.allocationDrifts,
+		s.quarantinedNodes,
+		s.askBudgetExceeded,
+		s.runtimeEstimates,
+		s.runtimeEstimateRatio,
 	}
 
 	// Register the metrics.
@@ -350,3 +446,58 @@ func (m *SchedulerMetrics) SetNodeResourceUsage(resourceName string, rangeIdx in
 	}
 	resourceMetrics.With(prometheus.Labels{"range": resourceUsageRangeBuckets[rangeIdx]}).Set(value)
 }
+
+// Metrics Ops related to reapedReservations
+func (m *SchedulerMetrics) IncReapedReservations() {
+	m.reapedReservations.Inc()
+}
+
+func (m *SchedulerMetrics) AddReapedReservations(value int) {
+	m.reapedReservations.Add(float64(value))
+}
+
+// Metrics Ops related to preemption victims
+func (m *SchedulerMetrics) AddPreemptedAllocations(value int) {
+	m.preemptedAllocations.Add(float64(value))
+}
+
+func (m *SchedulerMetrics) IncPreemptionProtectedAllocations() {
+	m.protectedAllocations.Inc()
+}
+
+// Metrics Ops related to allocation rollbacks
+func (m *SchedulerMetrics) AddCommitRollbacks(value int) {
+	m.commitRollbacks.Add(float64(value))
+}
+
+func (m *SchedulerMetrics) AddDeliveryRollbacks(value int) {
+	m.deliveryRollbacks.Add(float64(value))
+}
+
+// Metrics Ops related to asks that used up the node evaluation budget
+func (m *SchedulerMetrics) IncAskBudgetExceeded() {
+	m.askBudgetExceeded.Inc()
+}
+
+// Metrics Ops related to nodes with allocations that drifted from the RM
+func (m *SchedulerMetrics) IncAllocationDrifts() {
+	m.allocationDrifts.Inc()
+}
+
+// Metrics Ops related to nodes quarantined for flapping
+func (m *SchedulerMetrics) IncQuarantinedNodes() {
+	m.quarantinedNodes.Inc()
+}
+
+// Metrics Ops related to application runtime estimates
+func (m *SchedulerMetrics) ObserveRuntimeEstimate(estimate, actual time.Duration) {
+	if estimate <= 0 {
+		return
+	}
+	result := "met"
+	if actual > estimate {
+		result = "exceeded"
+	}
+	m.runtimeEstimates.With(prometheus.Labels{"result": result}).Inc()
+	m.runtimeEstimateRatio.Observe(actual.Seconds() / estimate.Seconds())
+}
@@ -50,6 +50,12 @@ func RegisterSchedulerPlugin(plugin interface{}) {
 		plugins.reconcilePlugin = t
 		registered = true
 	}
+	if t, ok := plugin.(GroupResolverPlugin); ok {
+		log.Logger().Debug("register scheduler plugin",
+			zap.String("type", "GroupResolverPlugin"))
+		plugins.groupsPlugin = t
+		registered = true
+	}
 	if !registered {
 		log.Logger().Debug("no scheduler plugin implemented, none registered")
 	}
@@ -66,3 +72,7 @@ func GetVolumesPlugin() VolumesPlugin {
 func GetReconcilePlugin() ReconcilePlugin {
 	return plugins.reconcilePlugin
 }
+
+func GetGroupResolverPlugin() GroupResolverPlugin {
+	return plugins.groupsPlugin
+}
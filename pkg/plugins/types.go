@@ -24,6 +24,7 @@ type SchedulerPlugins struct {
 	predicatesPlugin PredicatesPlugin
 	volumesPlugin    VolumesPlugin
 	reconcilePlugin  ReconcilePlugin
+	groupsPlugin     GroupResolverPlugin
 }
 
 // RM side implements this API when it can provide plugin for predicates.
@@ -45,3 +46,10 @@ type ReconcilePlugin interface {
 	// to scheduler cache (shim-side), such as assumed allocations.
 	ReSyncSchedulerCache(args *si.ReSyncSchedulerCacheArgs) error
 }
+
+// RM side implements this API when it can resolve the groups of a user.
+// Used by partitions configured with the plugin user group resolver for the ACL checks.
+type GroupResolverPlugin interface {
+	// Return the groups the user is a member of, an error means the user is unknown.
+	ResolveGroups(user string) ([]string, error)
+}
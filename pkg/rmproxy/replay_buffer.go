@@ -0,0 +1,96 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rmproxy
+
+import (
+	"sync"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// The maximum number of update responses buffered per RM for replay
+const replayBufferSize = 1024
+
+// Buffer of the update responses sent to an RM with a sequenced callback.
+// The oldest responses are dropped when the buffer is full, the RM must register again if it needs them.
+type replayBuffer struct {
+	sequence  uint64               // sequence number of the last response sent
+	responses []*sequencedResponse // buffered responses ordered by sequence number
+	size      int                  // maximum number of buffered responses
+
+	sync.Mutex
+}
+
+type sequencedResponse struct {
+	sequence uint64
+	response *si.UpdateResponse
+}
+
+func newReplayBuffer(size int) *replayBuffer {
+	return &replayBuffer{
+		size: size,
+	}
+}
+
+// Send the response to the RM with the next sequence number and buffer it.
+// New allocations that are not delivered are rolled back by the caller: the response is not buffered and the
+// sequence number is reused for the next response.
+// The lock is held while the callback runs, responses are never sent out of order or during a replay.
+func (b *replayBuffer) send(callback api.SequencedUpdateCallback, response *si.UpdateResponse) error {
+	b.Lock()
+	defer b.Unlock()
+	sequence := b.sequence + 1
+	err := callback.RecvSequencedUpdateResponse(sequence, response)
+	if err != nil && len(response.NewAllocations) > 0 {
+		return err
+	}
+	b.sequence = sequence
+	b.responses = append(b.responses, &sequencedResponse{sequence: sequence, response: response})
+	if len(b.responses) > b.size {
+		b.responses[0] = nil
+		b.responses = b.responses[1:]
+	}
+	return err
+}
+
+// Resend the buffered responses after the last sequence to the RM in order.
+// The responses up to and including the last sequence are acknowledged and dropped. If the callback fails the
+// remaining responses stay buffered and the replay can be requested again.
+func (b *replayBuffer) replay(callback api.SequencedUpdateCallback, lastSequence uint64) error {
+	b.Lock()
+	defer b.Unlock()
+	if lastSequence > b.sequence {
+		return api.NewError(api.ErrReplayUnavailable, "sequence %d was never sent, last sent sequence is %d", lastSequence, b.sequence)
+	}
+	// drop the acknowledged responses
+	for len(b.responses) > 0 && b.responses[0].sequence <= lastSequence {
+		b.responses[0] = nil
+		b.responses = b.responses[1:]
+	}
+	if lastSequence < b.sequence && (len(b.responses) == 0 || b.responses[0].sequence > lastSequence+1) {
+		return api.NewError(api.ErrReplayUnavailable, "responses after sequence %d are no longer buffered", lastSequence)
+	}
+	for _, buffered := range b.responses {
+		if err := callback.RecvSequencedUpdateResponse(buffered.sequence, buffered.response); err != nil {
+			return api.WrapError(err, "replay failed at sequence %d", buffered.sequence)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package rmproxy
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// Sequenced callback that captures the sequence numbers received, fails while an error is set.
+type sequencedCallback struct {
+	sequences []uint64
+	err       error
+}
+
+func (c *sequencedCallback) RecvSequencedUpdateResponse(sequence uint64, response *si.UpdateResponse) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.sequences = append(c.sequences, sequence)
+	return nil
+}
+
+func TestReplayBufferSend(t *testing.T) {
+	buffer := newReplayBuffer(3)
+	callback := &sequencedCallback{}
+	for i := 0; i < 5; i++ {
+		assert.NilError(t, buffer.send(callback, &si.UpdateResponse{}), "send should not fail")
+	}
+	assert.DeepEqual(t, callback.sequences, []uint64{1, 2, 3, 4, 5})
+	assert.Equal(t, len(buffer.responses), 3, "buffer should be limited to its size")
+	assert.Equal(t, buffer.responses[0].sequence, uint64(3), "oldest responses should have been dropped")
+
+	// a failed response is buffered, failed new allocations are rolled back and not buffered
+	callback.err = fmt.Errorf("send failed")
+	assert.Assert(t, buffer.send(callback, &si.UpdateResponse{}) != nil, "send should return the callback error")
+	assert.Equal(t, buffer.sequence, uint64(6), "failed response should use a sequence")
+	allocs := &si.UpdateResponse{NewAllocations: []*si.Allocation{{UUID: "uuid-1"}}}
+	assert.Assert(t, buffer.send(callback, allocs) != nil, "send should return the callback error")
+	assert.Equal(t, buffer.sequence, uint64(6), "failed allocations should not use a sequence")
+}
+
+func TestReplayBufferReplay(t *testing.T) {
+	buffer := newReplayBuffer(3)
+	callback := &sequencedCallback{}
+	for i := 0; i < 5; i++ {
+		assert.NilError(t, buffer.send(callback, &si.UpdateResponse{}), "send should not fail")
+	}
+
+	// unknown or dropped sequences cannot be replayed
+	err := buffer.replay(callback, 6)
+	assert.Assert(t, api.IsError(err, api.ErrReplayUnavailable), "future sequence should not be replayed: %v", err)
+	err = buffer.replay(callback, 1)
+	assert.Assert(t, api.IsError(err, api.ErrReplayUnavailable), "dropped sequence should not be replayed: %v", err)
+
+	// replay after 3 sends 4 and 5 and drops the acknowledged response 3
+	callback.sequences = nil
+	assert.NilError(t, buffer.replay(callback, 3), "replay should not fail")
+	assert.DeepEqual(t, callback.sequences, []uint64{4, 5})
+	assert.Equal(t, len(buffer.responses), 2, "acknowledged response should have been dropped")
+
+	// a failed replay keeps the responses
+	callback.err = fmt.Errorf("replay failed")
+	err = buffer.replay(callback, 4)
+	assert.ErrorContains(t, err, "replay failed at sequence 5")
+	assert.Equal(t, len(buffer.responses), 1, "unacknowledged response should stay buffered")
+
+	// nothing to replay when all responses are acknowledged
+	callback.err = nil
+	callback.sequences = nil
+	assert.NilError(t, buffer.replay(callback, 5), "replay of all acknowledged should not fail")
+	assert.Equal(t, len(callback.sequences), 0, "nothing should have been replayed")
+	assert.Equal(t, len(buffer.responses), 0, "all responses should have been dropped")
+	err = buffer.replay(callback, 4)
+	assert.Assert(t, api.IsError(err, api.ErrReplayUnavailable), "acknowledged sequence should not be replayed: %v", err)
+}
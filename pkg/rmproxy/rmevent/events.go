@@ -18,7 +18,10 @@
 
 package rmevent
 
-import "github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+import (
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
 
 type RMNewAllocationsEvent struct {
 	RmID        string
@@ -46,3 +49,23 @@ type RMNodeUpdateEvent struct {
 	AcceptedNodes []*si.AcceptedNode
 	RejectedNodes []*si.RejectedNode
 }
+
+type RMPreemptionNotificationEvent struct {
+	RmID          string
+	Notifications []*api.PreemptionNotification
+}
+
+type RMPreemptionIntentEvent struct {
+	RmID    string
+	Intents []*api.PreemptionIntent
+}
+
+type RMQueueUpdateEvent struct {
+	RmID    string
+	Updates []*api.QueueUpdate
+}
+
+type RMAutoscaleEvent struct {
+	RmID   string
+	Events []*api.AutoscaleEvent
+}
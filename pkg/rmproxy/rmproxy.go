@@ -36,6 +36,7 @@ import (
 	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
 	"github.com/apache/incubator-yunikorn-core/pkg/plugins"
 	"github.com/apache/incubator-yunikorn-core/pkg/rmproxy/rmevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/schedulerevent"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -52,6 +53,9 @@ type RMProxy struct {
 	// it is used to determine if configs need to be reloaded
 	rmIDToConfigWatcher map[string]*configs.ConfigWatcher
 
+	// update responses sent to an RM with a sequenced callback, buffered for replay
+	rmIDToReplayBuffer map[string]*replayBuffer
+
 	lock sync.RWMutex
 }
 
@@ -79,6 +83,7 @@ func NewRMProxy() *RMProxy {
 	rm := &RMProxy{
 		rmIDToCallback:      make(map[string]api.ResourceManagerCallback),
 		rmIDToConfigWatcher: make(map[string]*configs.ConfigWatcher),
+		rmIDToReplayBuffer:  make(map[string]*replayBuffer),
 		pendingRMEvents:     make(chan interface{}, 1024*1024),
 	}
 	return rm
@@ -97,19 +102,30 @@ func (m *RMProxy) handleRMRecvUpdateResponseError(rmID string, err error) {
 }
 
 func (m *RMProxy) processUpdateResponse(rmID string, response *si.UpdateResponse) {
+	if err := m.sendUpdateResponse(rmID, response); err != nil {
+		m.handleRMRecvUpdateResponseError(rmID, err)
+	}
+}
+
+// Send the response to the RM, returns the error of the RM callback.
+// The response is sent with a sequence number and buffered for replay if the RM supports it.
+func (m *RMProxy) sendUpdateResponse(rmID string, response *si.UpdateResponse) error {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
 	if callback := m.rmIDToCallback[rmID]; callback != nil {
-		if err := callback.RecvUpdateResponse(response); err != nil {
-			m.handleRMRecvUpdateResponseError(rmID, err)
+		if sequenced, ok := callback.(api.SequencedUpdateCallback); ok {
+			return m.rmIDToReplayBuffer[rmID].send(sequenced, response)
 		}
-	} else {
-		log.Logger().DPanic("RM is not registered",
-			zap.String("rmID", rmID))
+		return callback.RecvUpdateResponse(response)
 	}
+	log.Logger().DPanic("RM is not registered",
+		zap.String("rmID", rmID))
+	return nil
 }
 
+// Deliver the new allocations to the RM.
+// If the RM does not accept the allocations they are rolled back in the cache and the asks are re-queued.
 func (m *RMProxy) processAllocationUpdateEvent(event *rmevent.RMNewAllocationsEvent) {
 	if len(event.Allocations) == 0 {
 		return
@@ -118,7 +134,18 @@ func (m *RMProxy) processAllocationUpdateEvent(event *rmevent.RMNewAllocationsEv
 		NewAllocations: event.Allocations,
 	}
 
-	m.processUpdateResponse(event.RmID, response)
+	if err := m.sendUpdateResponse(event.RmID, response); err != nil {
+		log.Logger().Warn("new allocations not delivered to the RM, rolling back",
+			zap.String("rmID", event.RmID),
+			zap.Int("allocations", len(event.Allocations)),
+			zap.Error(err))
+		m.EventHandlers.CacheEventHandler.HandleEvent(&cacheevent.RollbackAllocationsEvent{
+			RmID:        event.RmID,
+			Allocations: event.Allocations,
+			Reason:      err.Error(),
+		})
+		return
+	}
 	metrics.GetSchedulerMetrics().AddAllocatedContainers(len(event.Allocations))
 }
 
@@ -178,6 +205,104 @@ func (m *RMProxy) processRMNodeUpdateEvent(event *rmevent.RMNodeUpdateEvent) {
 	m.processUpdateResponse(event.RmID, response)
 }
 
+func (m *RMProxy) processRMPreemptionNotificationEvent(event *rmevent.RMPreemptionNotificationEvent) {
+	if len(event.Notifications) == 0 {
+		return
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	callback, ok := m.rmIDToCallback[event.RmID].(api.PreemptionNotificationCallback)
+	if !ok {
+		log.Logger().Debug("RM does not support preemption notifications",
+			zap.String("rmID", event.RmID),
+			zap.Int("notifications", len(event.Notifications)))
+		return
+	}
+	if err := callback.RecvPreemptionNotification(event.Notifications); err != nil {
+		log.Logger().Warn("failed to send preemption notifications to RM",
+			zap.String("rmID", event.RmID),
+			zap.Error(err))
+	}
+}
+
+func (m *RMProxy) processRMPreemptionIntentEvent(event *rmevent.RMPreemptionIntentEvent) {
+	if len(event.Intents) == 0 {
+		return
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	callback, ok := m.rmIDToCallback[event.RmID].(api.PreemptionIntentCallback)
+	if !ok {
+		// the RM cannot confirm: accept all intents so the releases are not delayed until the timeout
+		log.Logger().Debug("RM does not support preemption intents, accepting all",
+			zap.String("rmID", event.RmID),
+			zap.Int("intents", len(event.Intents)))
+		responses := make([]*api.PreemptionResponse, len(event.Intents))
+		for i, intent := range event.Intents {
+			responses[i] = &api.PreemptionResponse{
+				UUID:          intent.UUID,
+				PartitionName: intent.PartitionName,
+				Accepted:      true,
+			}
+		}
+		m.EventHandlers.SchedulerEventHandler.HandleEvent(&schedulerevent.SchedulerPreemptionResponseEvent{
+			RmID:      event.RmID,
+			Responses: responses,
+		})
+		return
+	}
+	// a failure is not retried: the allocations are released when the timeout passes
+	if err := callback.RecvPreemptionIntents(event.Intents); err != nil {
+		log.Logger().Warn("failed to send preemption intents to RM",
+			zap.String("rmID", event.RmID),
+			zap.Error(err))
+	}
+}
+
+func (m *RMProxy) processRMQueueUpdateEvent(event *rmevent.RMQueueUpdateEvent) {
+	if len(event.Updates) == 0 {
+		return
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	callback, ok := m.rmIDToCallback[event.RmID].(api.QueueUpdateCallback)
+	if !ok {
+		log.Logger().Debug("RM does not support queue updates",
+			zap.String("rmID", event.RmID),
+			zap.Int("updates", len(event.Updates)))
+		return
+	}
+	if err := callback.RecvQueueUpdate(event.Updates); err != nil {
+		log.Logger().Warn("failed to send queue updates to RM",
+			zap.String("rmID", event.RmID),
+			zap.Error(err))
+	}
+}
+
+func (m *RMProxy) processRMAutoscaleEvent(event *rmevent.RMAutoscaleEvent) {
+	if len(event.Events) == 0 {
+		return
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	callback, ok := m.rmIDToCallback[event.RmID].(api.AutoscaleEventCallback)
+	if !ok {
+		log.Logger().Debug("RM does not support autoscale events",
+			zap.String("rmID", event.RmID),
+			zap.Int("events", len(event.Events)))
+		return
+	}
+	if err := callback.RecvAutoscaleEvents(event.Events); err != nil {
+		log.Logger().Warn("failed to send autoscale events to RM",
+			zap.String("rmID", event.RmID),
+			zap.Error(err))
+	}
+}
+
 func (m *RMProxy) handleRMEvents() {
 	for {
 		ev := <-m.pendingRMEvents
@@ -192,6 +317,14 @@ func (m *RMProxy) handleRMEvents() {
 			m.processUpdatePartitionConfigsEvent(v)
 		case *rmevent.RMNodeUpdateEvent:
 			m.processRMNodeUpdateEvent(v)
+		case *rmevent.RMPreemptionNotificationEvent:
+			m.processRMPreemptionNotificationEvent(v)
+		case *rmevent.RMPreemptionIntentEvent:
+			m.processRMPreemptionIntentEvent(v)
+		case *rmevent.RMQueueUpdateEvent:
+			m.processRMQueueUpdateEvent(v)
+		case *rmevent.RMAutoscaleEvent:
+			m.processRMAutoscaleEvent(v)
 		default:
 			panic(fmt.Sprintf("%s is not an acceptable type for RM event.", reflect.TypeOf(v).String()))
 		}
@@ -244,6 +377,8 @@ func (m *RMProxy) RegisterResourceManager(request *si.RegisterResourceManagerReq
 		})
 		m.rmIDToConfigWatcher[request.RmID] = configWatcher
 		m.rmIDToCallback[request.RmID] = callback
+		// a registration is a full resync: the responses sent before are never replayed
+		m.rmIDToReplayBuffer[request.RmID] = newReplayBuffer(replayBufferSize)
 
 		// RM callback can optionally implement one or more scheduler plugin interfaces,
 		// register scheduler plugin if the callback implements any plugin interface
@@ -254,6 +389,43 @@ func (m *RMProxy) RegisterResourceManager(request *si.RegisterResourceManagerReq
 	return nil, fmt.Errorf("registration of RM failed: %v", result.Reason)
 }
 
+// Replay the update responses sent to the RM after the last sequence, see api.ReplayAPI.
+func (m *RMProxy) ReplayUpdateResponses(rmID string, lastSequence uint64) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	callback := m.rmIDToCallback[rmID]
+	if callback == nil {
+		return fmt.Errorf("failed to replay responses, RM %s is unknown to the scheduler", rmID)
+	}
+	sequenced, ok := callback.(api.SequencedUpdateCallback)
+	if !ok {
+		return api.NewError(api.ErrReplayUnavailable, "RM %s does not support sequenced responses", rmID)
+	}
+	log.Logger().Info("replaying update responses to RM",
+		zap.String("rmID", rmID),
+		zap.Uint64("lastSequence", lastSequence))
+	return m.rmIDToReplayBuffer[rmID].replay(sequenced, lastSequence)
+}
+
+// Pass the responses to the preemption intents to the scheduler, see api.PreemptionConfirmAPI.
+func (m *RMProxy) ConfirmPreemptions(rmID string, responses []*api.PreemptionResponse) error {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	if m.rmIDToCallback[rmID] == nil {
+		return fmt.Errorf("failed to confirm preemptions, RM %s is unknown to the scheduler", rmID)
+	}
+	if len(responses) == 0 {
+		return nil
+	}
+	m.EventHandlers.SchedulerEventHandler.HandleEvent(&schedulerevent.SchedulerPreemptionResponseEvent{
+		RmID:      rmID,
+		Responses: responses,
+	})
+	return nil
+}
+
 func (m *RMProxy) GetResourceManagerCallback(rmID string) api.ResourceManagerCallback {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
@@ -0,0 +1,76 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"github.com/apache/incubator-yunikorn-core/pkg/cache/cacheevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+)
+
+// The maximum number of allocations made in a partition in one scheduling cycle.
+const maxAllocationsPerCycle = 16
+
+// Allocations made in one scheduling cycle grouped per node.
+// All allocations on a node are committed in the cache and delivered to the RM as one batch, in the order they were
+// made. Nodes keep the order in which they got their first allocation in the cycle.
+// Not locked: only used by the scheduling cycle of one partition.
+type allocationBatch struct {
+	nodes  []string
+	allocs map[string][]*schedulingAllocation
+}
+
+func newAllocationBatch() *allocationBatch {
+	return &allocationBatch{
+		allocs: make(map[string][]*schedulingAllocation),
+	}
+}
+
+// Add the allocation to the batch of its node.
+func (ab *allocationBatch) add(alloc *schedulingAllocation) {
+	if _, ok := ab.allocs[alloc.nodeID]; !ok {
+		ab.nodes = append(ab.nodes, alloc.nodeID)
+	}
+	ab.allocs[alloc.nodeID] = append(ab.allocs[alloc.nodeID], alloc)
+}
+
+// Create the proposal events for the batch: one event per node.
+func (ab *allocationBatch) proposals() []*cacheevent.AllocationProposalBundleEvent {
+	events := make([]*cacheevent.AllocationProposalBundleEvent, 0, len(ab.nodes))
+	for _, nodeID := range ab.nodes {
+		allocs := ab.allocs[nodeID]
+		event := &cacheevent.AllocationProposalBundleEvent{
+			AllocationProposals: make([]*commonevents.AllocationProposal, 0, len(allocs)),
+			PartitionName:       allocs[0].schedulingAsk.PartitionName,
+		}
+		for _, alloc := range allocs {
+			event.AllocationProposals = append(event.AllocationProposals, newAllocationProposal(alloc))
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// Put all allocations of the batch back in the pool, must be called after the proposals are created.
+func (ab *allocationBatch) release() {
+	for _, allocs := range ab.allocs {
+		for _, alloc := range allocs {
+			alloc.release()
+		}
+	}
+}
@@ -0,0 +1,57 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestAllocationBatch(t *testing.T) {
+	batch := newAllocationBatch()
+	assert.Equal(t, len(batch.proposals()), 0, "empty batch should not create proposals")
+
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	allocs := []*schedulingAllocation{
+		newSchedulingAllocation(newAllocationAsk("alloc-1", "app-1", res), "node-2"),
+		newSchedulingAllocation(newAllocationAsk("alloc-2", "app-1", res), "node-1"),
+		newSchedulingAllocation(newAllocationAsk("alloc-3", "app-2", res), "node-2"),
+		newSchedulingAllocation(newAllocationAsk("alloc-4", "app-2", res), "node-1"),
+	}
+	for _, alloc := range allocs {
+		batch.add(alloc)
+	}
+	// one event per node, nodes and allocations in the order they were added
+	events := batch.proposals()
+	assert.Equal(t, len(events), 2, "expected one proposal event per node")
+	expected := map[int][]string{0: {"alloc-1", "alloc-3"}, 1: {"alloc-2", "alloc-4"}}
+	nodes := []string{"node-2", "node-1"}
+	for i, event := range events {
+		assert.Equal(t, event.PartitionName, "default", "unexpected partition name")
+		assert.Equal(t, len(event.ReleaseProposals), 0, "batch should not have releases")
+		assert.Equal(t, len(event.AllocationProposals), len(expected[i]), "unexpected number of proposals")
+		for j, proposal := range event.AllocationProposals {
+			assert.Equal(t, proposal.AllocationKey, expected[i][j], "proposal not in allocation order")
+			assert.Equal(t, proposal.NodeID, nodes[i], "proposal on wrong node")
+		}
+	}
+}
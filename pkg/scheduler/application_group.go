@@ -0,0 +1,142 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// Application tag with the name of the application group the application belongs to.
+// Frameworks that split a job into multiple applications, like a driver and its workers, use the same group for all
+// applications of the job. The applications of a group are accounted together: the maximum set for application groups
+// on the queue of an application limits the combined allocations of all applications in the group.
+// Groups are tracked per partition, an application without the tag is not part of a group.
+const ApplicationGroupTag = "application.group"
+
+// Add the application to its group, a noop for an application without a group.
+// Lock free call this must be called holding the partition lock
+func (psc *partitionSchedulingContext) addToApplicationGroup(app *SchedulingApplication) {
+	if app.group == "" {
+		return
+	}
+	members := psc.appGroups[app.group]
+	if members == nil {
+		members = make(map[string]*SchedulingApplication)
+		psc.appGroups[app.group] = members
+	}
+	members[app.ApplicationInfo.ApplicationID] = app
+}
+
+// Remove the application from its group, the group is removed with its last application.
+// Lock free call this must be called holding the partition lock
+func (psc *partitionSchedulingContext) removeFromApplicationGroup(app *SchedulingApplication) {
+	members := psc.appGroups[app.group]
+	if members == nil {
+		return
+	}
+	delete(members, app.ApplicationInfo.ApplicationID)
+	if len(members) == 0 {
+		delete(psc.appGroups, app.group)
+	}
+}
+
+// Get the name of the group of the application, the group can change when the application tags are updated.
+func (psc *partitionSchedulingContext) getApplicationGroupName(app *SchedulingApplication) string {
+	psc.RLock()
+	defer psc.RUnlock()
+	return app.group
+}
+
+// Get the applications in the group sorted by application ID, nil if the group does not exist.
+func (psc *partitionSchedulingContext) getApplicationGroup(group string) []*SchedulingApplication {
+	psc.RLock()
+	defer psc.RUnlock()
+	members := psc.appGroups[group]
+	if members == nil {
+		return nil
+	}
+	apps := make([]*SchedulingApplication, 0, len(members))
+	for _, app := range members {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].ApplicationInfo.ApplicationID < apps[j].ApplicationInfo.ApplicationID
+	})
+	return apps
+}
+
+// Limit the headroom for the application by the application group maximum of its queue.
+// The combined allocating and allocated resources of all applications in the group are subtracted from the maximum.
+// The headroom is returned as is for an application without a group or a queue without a group maximum.
+// The partition lock is not held while the applications are locked.
+func (psc *partitionSchedulingContext) getGroupHeadRoom(app *SchedulingApplication, headRoom *resources.Resource) *resources.Resource {
+	group := psc.getApplicationGroupName(app)
+	if group == "" {
+		return headRoom
+	}
+	groupMax := app.queue.QueueInfo.GetApplicationGroupMax()
+	if groupMax == nil {
+		return headRoom
+	}
+	for _, member := range psc.getApplicationGroup(group) {
+		groupMax.SubFrom(member.getAssumeAllocated())
+	}
+	if headRoom == nil {
+		return groupMax
+	}
+	return resources.ComponentWiseMin(headRoom, groupMax)
+}
+
+// Get the combined pending and allocated resources of all application groups in the partition, sorted by name.
+func (psc *partitionSchedulingContext) getApplicationGroupInfos() []*dao.ApplicationGroupDAOInfo {
+	psc.RLock()
+	groups := make([]string, 0, len(psc.appGroups))
+	for group := range psc.appGroups {
+		groups = append(groups, group)
+	}
+	psc.RUnlock()
+	sort.Strings(groups)
+	infos := make([]*dao.ApplicationGroupDAOInfo, 0, len(groups))
+	for _, group := range groups {
+		members := psc.getApplicationGroup(group)
+		// removed since the names were collected
+		if len(members) == 0 {
+			continue
+		}
+		info := &dao.ApplicationGroupDAOInfo{
+			Partition:    psc.Name,
+			GroupName:    group,
+			Applications: make([]string, 0, len(members)),
+		}
+		allocated := resources.NewResource()
+		pending := resources.NewResource()
+		for _, app := range members {
+			info.Applications = append(info.Applications, app.ApplicationInfo.ApplicationID)
+			allocated.AddTo(app.GetAllocatedResource())
+			pending.AddTo(app.GetPendingResource())
+		}
+		info.Allocated = allocated.DAOString()
+		info.Pending = pending.DAOString()
+		infos = append(infos, info)
+	}
+	return infos
+}
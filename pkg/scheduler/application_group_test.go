@@ -0,0 +1,98 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+// Add an application with the tags to the leaf queue and the partition, including the group membership.
+func addGroupApplication(partition *partitionSchedulingContext, leaf *SchedulingQueue, appID string, tags map[string]string) *SchedulingApplication {
+	app := newSchedulingApplication(cache.NewApplicationInfo(appID, "default", leaf.Name, security.UserGroup{}, tags))
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.Lock()
+	defer partition.Unlock()
+	partition.applications[appID] = app
+	partition.addToApplicationGroup(app)
+	return app
+}
+
+func TestApplicationGroupHeadRoom(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(partition.root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	partition.addSchedulingNode(cache.NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100})))
+	group := map[string]string{ApplicationGroupTag: "job-1"}
+	driver := addGroupApplication(partition, leaf, "app-driver", group)
+	worker := addGroupApplication(partition, leaf, "app-worker", group)
+	other := addGroupApplication(partition, leaf, "app-other", nil)
+	assert.Equal(t, len(partition.appGroups), 1, "expected one application group")
+	assert.Equal(t, len(partition.getApplicationGroup("job-1")), 2, "expected two applications in the group")
+
+	// no group max: headroom is not changed
+	assert.Assert(t, partition.getGroupHeadRoom(worker, nil) == nil, "headroom should not be limited without a group max")
+
+	// the group max is shared by the applications in the group
+	cache.SetApplicationGroupMax(leaf.QueueInfo, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10}))
+	used := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 6})
+	cache.AddAllocationToApp(driver.ApplicationInfo, cache.CreateMockAllocationInfo("app-driver", used, "uuid-1", leaf.Name, "node-1"))
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 4})
+	assert.Assert(t, resources.Equals(partition.getGroupHeadRoom(worker, nil), expected), "unexpected group headroom")
+	queueHeadRoom := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 3})
+	assert.Assert(t, resources.Equals(partition.getGroupHeadRoom(worker, queueHeadRoom), queueHeadRoom), "queue headroom should be the smallest")
+	assert.Assert(t, partition.getGroupHeadRoom(other, nil) == nil, "application without a group should not be limited")
+
+	// the worker cannot allocate above the group max
+	ask := newAllocationAsk("alloc-1", "app-worker", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}))
+	_, err = worker.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	assert.Assert(t, leaf.tryAllocate(partition) == nil, "allocation above the group max should not be made")
+	_, err = worker.updateAskRepeat("alloc-1", -1)
+	assert.NilError(t, err, "failed to remove ask from app")
+	ask = newAllocationAsk("alloc-2", "app-worker", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 4}))
+	_, err = worker.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	alloc := leaf.tryAllocate(partition)
+	assert.Assert(t, alloc != nil, "allocation within the group max should be made")
+	assert.Equal(t, alloc.schedulingAsk.ApplicationID, "app-worker")
+
+	// combined reporting
+	infos := partition.getApplicationGroupInfos()
+	assert.Equal(t, len(infos), 1, "expected one group")
+	assert.Equal(t, infos[0].GroupName, "job-1")
+	assert.DeepEqual(t, infos[0].Applications, []string{"app-driver", "app-worker"})
+	assert.Equal(t, infos[0].Allocated, "[first:6]")
+
+	// removing the applications removes the group
+	partition.Lock()
+	partition.removeFromApplicationGroup(driver)
+	partition.removeFromApplicationGroup(worker)
+	partition.removeFromApplicationGroup(other)
+	partition.Unlock()
+	assert.Equal(t, len(partition.appGroups), 0, "group should be removed with the last application")
+}
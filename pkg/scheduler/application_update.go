@@ -0,0 +1,120 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+// Application tag with the priority of the application, an integer.
+// Asks of the application that do not have a priority of their own use the application priority. The priority is
+// clamped by the priority range of the queue like the priority of an ask.
+const ApplicationPriorityTag = "application.priority"
+
+// Get the priority from the application tags, nil if the tag is not set.
+// An invalid value is logged and ignored, the application will not have a priority.
+func parseApplicationPriority(app *cache.ApplicationInfo) *int32 {
+	value := strings.TrimSpace(app.GetTag(ApplicationPriorityTag))
+	if value == "" {
+		return nil
+	}
+	priority, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		log.Logger().Warn("invalid application priority, ignoring tag",
+			zap.String("applicationID", app.ApplicationID),
+			zap.String("tag", ApplicationPriorityTag),
+			zap.String("value", value))
+		return nil
+	}
+	result := int32(priority)
+	return &result
+}
+
+// Set the effective priority of the ask: the application priority if the ask does not have a priority value, clamped
+// to the priority range of the queue. A priority value on the ask overrides the application priority.
+// Lock free call this must be called holding the application lock
+func (sa *SchedulingApplication) setAskPriority(ask *schedulingAllocationAsk) {
+	if !ask.hasPriorityValue() && sa.priority != nil {
+		ask.priority = *sa.priority
+	}
+	sa.clampAskPriority(ask)
+}
+
+// Apply the updated application tags: the priority of the application and its asks, the trace flag and the
+// application group are updated. The runtime estimate is only read when the application is submitted.
+// Takes the application lock. Called holding the partition lock: the application group is updated around the call.
+func (sa *SchedulingApplication) updateTags() {
+	sa.Lock()
+	defer sa.Unlock()
+
+	sa.traceEnabled = strings.EqualFold(sa.ApplicationInfo.GetTag(TraceApplicationTag), "true")
+	sa.group = strings.TrimSpace(sa.ApplicationInfo.GetTag(ApplicationGroupTag))
+	sa.priority = parseApplicationPriority(sa.ApplicationInfo)
+	sa.resetAskPriorities()
+}
+
+// Move the application to the queue. The asks are updated to the new queue: the priority range of the new queue is
+// applied to the requested priority of the asks.
+// Takes the application lock. Called holding the partition lock: the queues are updated around the call.
+func (sa *SchedulingApplication) setQueue(queue *SchedulingQueue) {
+	sa.Lock()
+	defer sa.Unlock()
+
+	sa.queue = queue
+	for _, ask := range sa.requests {
+		ask.QueueName = queue.Name
+	}
+	sa.resetAskPriorities()
+}
+
+// Recalculate the effective priority of all asks and restore the order of the asks.
+// Lock free call this must be called holding the application lock
+func (sa *SchedulingApplication) resetAskPriorities() {
+	for _, ask := range sa.requests {
+		ask.priority = ask.normalizePriority(ask.AskProto.Priority)
+		sa.setAskPriority(ask)
+	}
+	sa.askQueue.reorder()
+}
+
+// Apply the updated tags of the application in the partition. The application is moved to its new application group
+// when the group changed.
+func (psc *partitionSchedulingContext) updateSchedulingApplication(appID string) error {
+	psc.Lock()
+	defer psc.Unlock()
+
+	app := psc.applications[appID]
+	if app == nil {
+		return fmt.Errorf("updating application %s in partition %s, but application does not exist", appID, psc.Name)
+	}
+	psc.removeFromApplicationGroup(app)
+	app.updateTags()
+	psc.addToApplicationGroup(app)
+	log.Logger().Info("application updated in the scheduler",
+		zap.String("applicationID", appID),
+		zap.String("group", app.group))
+	return nil
+}
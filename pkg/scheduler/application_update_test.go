@@ -0,0 +1,142 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestParseApplicationPriority(t *testing.T) {
+	tests := map[string]*int32{
+		"":            nil,
+		" 10 ":        int32Ptr(10),
+		"-5":          int32Ptr(-5),
+		"high":        nil,
+		"1.5":         nil,
+		"99999999999": nil,
+	}
+	for value, expected := range tests {
+		app := cache.NewApplicationInfo("app-1", "default", "root.leaf", security.UserGroup{}, map[string]string{ApplicationPriorityTag: value})
+		priority := parseApplicationPriority(app)
+		if expected == nil {
+			assert.Assert(t, priority == nil, "priority should not be set for %q", value)
+		} else {
+			assert.Assert(t, priority != nil, "priority should be set for %q", value)
+			assert.Equal(t, *priority, *expected, "unexpected priority for %q", value)
+		}
+	}
+}
+
+func TestUpdateSchedulingApplication(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(partition.root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	app := addGroupApplication(partition, leaf, "app-1", map[string]string{ApplicationGroupTag: "job-1"})
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	noPriority := newAllocationAsk("alloc-1", "app-1", res)
+	_, err = app.addAllocationAsk(noPriority)
+	assert.NilError(t, err, "failed to add ask to app")
+	withPriority := newSchedulingAllocationAsk(&si.AllocationAsk{
+		AllocationKey:  "alloc-2",
+		ApplicationID:  "app-1",
+		PartitionName:  "default",
+		ResourceAsk:    res.ToProto(),
+		MaxAllocations: 1,
+		Priority:       &si.Priority{Priority: &si.Priority_PriorityValue{PriorityValue: 3}},
+	})
+	_, err = app.addAllocationAsk(withPriority)
+	assert.NilError(t, err, "failed to add ask to app")
+	assert.Equal(t, noPriority.priority, int32(0), "ask without priority should have the default priority")
+
+	// the tags change the application group and the priority of the asks without a priority
+	changed := app.ApplicationInfo.UpdateTags(map[string]string{ApplicationGroupTag: "job-2", ApplicationPriorityTag: "7"})
+	assert.Assert(t, changed, "tags should have changed")
+	err = partition.updateSchedulingApplication("app-1")
+	assert.NilError(t, err, "application update failed")
+	assert.Assert(t, partition.getApplicationGroup("job-1") == nil, "application should have left the old group")
+	assert.Equal(t, len(partition.getApplicationGroup("job-2")), 1, "application should have joined the new group")
+	assert.Equal(t, noPriority.priority, int32(7), "ask without priority should use the application priority")
+	assert.Equal(t, withPriority.priority, int32(3), "ask with priority should keep its priority")
+
+	// new asks use the application priority
+	ask := newAllocationAsk("alloc-3", "app-1", res)
+	_, err = app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	assert.Equal(t, ask.priority, int32(7), "new ask should use the application priority")
+
+	// removing the priority restores the ask priority
+	app.ApplicationInfo.UpdateTags(nil)
+	err = partition.updateSchedulingApplication("app-1")
+	assert.NilError(t, err, "application update failed")
+	assert.Equal(t, len(partition.appGroups), 0, "application should not be in a group")
+	assert.Equal(t, noPriority.priority, int32(0), "ask priority should have been restored")
+
+	err = partition.updateSchedulingApplication("unknown")
+	assert.Assert(t, err != nil, "update of an unknown application should fail")
+}
+
+func TestInheritApplicationPriority(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	app := newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", "root.leaf", security.UserGroup{}, map[string]string{ApplicationPriorityTag: "7"}))
+	app.queue = leaf
+
+	// asks without a priority value use the application priority, a value on the ask overrides it
+	tests := map[string]struct {
+		priority *si.Priority
+		expected int32
+	}{
+		"nil":        {nil, 7},
+		"empty":      {&si.Priority{}, 7},
+		"class name": {&si.Priority{Priority: &si.Priority_PriorityClassName{PriorityClassName: "high"}}, 7},
+		"value":      {&si.Priority{Priority: &si.Priority_PriorityValue{PriorityValue: 3}}, 3},
+		"zero value": {&si.Priority{Priority: &si.Priority_PriorityValue{PriorityValue: 0}}, 0},
+	}
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	for name, test := range tests {
+		ask := newSchedulingAllocationAsk(&si.AllocationAsk{
+			AllocationKey:  name,
+			ApplicationID:  "app-1",
+			PartitionName:  "default",
+			ResourceAsk:    res.ToProto(),
+			MaxAllocations: 1,
+			Priority:       test.priority,
+		})
+		_, err = app.addAllocationAsk(ask)
+		assert.NilError(t, err, "failed to add ask %s", name)
+		assert.Equal(t, ask.priority, test.expected, "unexpected priority for ask %s", name)
+		assert.Equal(t, ask.getAllocationPriority().GetPriorityValue(), test.expected, "unexpected allocation priority for ask %s", name)
+	}
+}
+
+func int32Ptr(value int32) *int32 {
+	return &value
+}
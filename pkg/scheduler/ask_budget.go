@@ -0,0 +1,67 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+)
+
+// The budget for the node evaluation of one ask in a scheduling cycle, see configs.PartitionAskBudgetConfig.
+// Bounds the time spent on one ask in very large clusters: when the budget is used up the node evaluation stops.
+// A nil budget is never used up.
+type askBudget struct {
+	maxTime  time.Duration // maximum time evaluating nodes, 0 means no limit
+	maxNodes int           // maximum number of nodes evaluated, 0 means no limit
+	start    time.Time     // start of the node evaluation, only set if the time is limited
+	nodes    int           // number of nodes evaluated
+}
+
+// Create a new budget for an ask, the time starts counting now.
+// Returns nil if the budget is not limited.
+func newAskBudget(maxTime time.Duration, maxNodes int) *askBudget {
+	if maxTime <= 0 && maxNodes <= 0 {
+		return nil
+	}
+	budget := &askBudget{
+		maxTime:  maxTime,
+		maxNodes: maxNodes,
+	}
+	if maxTime > 0 {
+		budget.start = time.Now()
+	}
+	return budget
+}
+
+// Record a node evaluation, safe to call on a nil budget.
+func (ab *askBudget) nodeEvaluated() {
+	if ab != nil {
+		ab.nodes++
+	}
+}
+
+// Check if the budget is used up: no more nodes should be evaluated. At least one node is always evaluated.
+func (ab *askBudget) isUsedUp() bool {
+	if ab == nil || ab.nodes == 0 {
+		return false
+	}
+	if ab.maxNodes > 0 && ab.nodes >= ab.maxNodes {
+		return true
+	}
+	return ab.maxTime > 0 && time.Since(ab.start) >= ab.maxTime
+}
@@ -0,0 +1,73 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+func TestAskBudget(t *testing.T) {
+	assert.Assert(t, newAskBudget(0, 0) == nil, "budget without limits should be nil")
+	var budget *askBudget
+	budget.nodeEvaluated()
+	assert.Assert(t, !budget.isUsedUp(), "nil budget should never be used up")
+
+	budget = newAskBudget(0, 2)
+	assert.Assert(t, !budget.isUsedUp(), "budget should not be used up before a node is evaluated")
+	budget.nodeEvaluated()
+	assert.Assert(t, !budget.isUsedUp(), "budget should not be used up after one node")
+	budget.nodeEvaluated()
+	assert.Assert(t, budget.isUsedUp(), "budget should be used up after two nodes")
+
+	budget = newAskBudget(time.Millisecond, 0)
+	assert.Assert(t, !budget.isUsedUp(), "at least one node should be evaluated")
+	budget.nodeEvaluated()
+	budget.start = time.Now().Add(-time.Second)
+	assert.Assert(t, budget.isUsedUp(), "budget should be used up after the time")
+}
+
+func TestAskBudgetAllocate(t *testing.T) {
+	partition := createQueuesNodes(t)
+	cache.SetAskBudget(partition.partition, 0, 1)
+	leaf := partition.getQueue("root.parent.leaf1")
+	tags := map[string]string{TraceApplicationTag: "true"}
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.parent.leaf1", security.UserGroup{}, tags)
+	app := newSchedulingApplication(appInfo)
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications["app-1"] = app
+	large := newAllocationAsk("alloc-large", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20}))
+	_, err := app.addAllocationAsk(large)
+	assert.NilError(t, err, "failed to add large ask to app")
+
+	// the budget stops the evaluation after the first node
+	alloc := partition.tryAllocate()
+	assert.Assert(t, alloc == nil, "large ask should not have been allocated")
+	traces := app.GetAskTraces()
+	assert.Equal(t, len(traces), 1, "expected a trace for the ask")
+	assert.Equal(t, traces[0].Result, traceAskBudget, "ask should have used up the budget")
+	assert.Equal(t, traces[0].NodesEvaluated, 1, "only one node should have been evaluated")
+}
@@ -0,0 +1,98 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+)
+
+// The pending asks of an application in the order the scheduler tries them: highest priority first, asks with the
+// same priority in the order they were added. The order is kept up to date on every change, finding the position of an
+// ask is a binary search. The priority of the asks must not be changed without calling reorder.
+// The queue is not locked: it must only be accessed while holding the application lock.
+type askQueue struct {
+	asks []*schedulingAllocationAsk // ordered list of the asks
+	seq  uint64                     // sequence number of the last ask added
+}
+
+// Return true if the ask l is tried before the ask r.
+func askBefore(l, r *schedulingAllocationAsk) bool {
+	if l.priority != r.priority {
+		return l.priority > r.priority
+	}
+	return l.seq < r.seq
+}
+
+// Add the ask in its place. The ask is added after all asks with the same priority unless it already has a sequence
+// number: an updated ask keeps the place of the ask it replaces.
+func (aq *askQueue) push(ask *schedulingAllocationAsk) {
+	if ask.seq == 0 {
+		aq.seq++
+		ask.seq = aq.seq
+	}
+	i := sort.Search(len(aq.asks), func(i int) bool {
+		return askBefore(ask, aq.asks[i])
+	})
+	aq.asks = append(aq.asks, nil)
+	copy(aq.asks[i+1:], aq.asks[i:])
+	aq.asks[i] = ask
+}
+
+// Remove the ask, a no-op if the ask is not in the queue.
+func (aq *askQueue) remove(ask *schedulingAllocationAsk) {
+	i := sort.Search(len(aq.asks), func(i int) bool {
+		return !askBefore(aq.asks[i], ask)
+	})
+	if i < len(aq.asks) && aq.asks[i] == ask {
+		aq.asks = append(aq.asks[:i], aq.asks[i+1:]...)
+	}
+}
+
+// Remove all asks.
+func (aq *askQueue) reset() {
+	aq.asks = nil
+}
+
+// Restore the order after the priority of one or more asks changed.
+func (aq *askQueue) reorder() {
+	sort.Slice(aq.asks, func(i, j int) bool {
+		return askBefore(aq.asks[i], aq.asks[j])
+	})
+}
+
+// Return the first ask that has an outstanding repeat, nil if there is none.
+func (aq *askQueue) first() *schedulingAllocationAsk {
+	for _, ask := range aq.asks {
+		if ask.getPendingAskRepeat() > 0 {
+			return ask
+		}
+	}
+	return nil
+}
+
+// Return the asks that have an outstanding repeat in order. The returned list is a copy.
+func (aq *askQueue) pending() []*schedulingAllocationAsk {
+	var asks []*schedulingAllocationAsk
+	for _, ask := range aq.asks {
+		if ask.getPendingAskRepeat() > 0 {
+			asks = append(asks, ask)
+		}
+	}
+	return asks
+}
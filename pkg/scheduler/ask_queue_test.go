@@ -0,0 +1,66 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"strconv"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestAskQueue(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{
+		"first": resources.Quantity(1)})
+	list := make([]*schedulingAllocationAsk, 4)
+	for i := 0; i < 4; i++ {
+		list[i] = newAllocationAsk("ask-"+strconv.Itoa(i), "app-1", res)
+	}
+	queue := &askQueue{}
+	assert.Assert(t, queue.first() == nil, "empty queue should not have a first ask")
+	// priorities: ask-0:1, ask-1:3, ask-2:1, ask-3:2 added in order: highest first, equal priorities in order added
+	for i, priority := range []int32{1, 3, 1, 2} {
+		list[i].priority = priority
+		queue.push(list[i])
+	}
+	assertAskList(t, queue.asks, []int{2, 0, 3, 1})
+	assert.Equal(t, queue.first(), list[1], "highest priority ask should be first")
+
+	// asks without a pending repeat are skipped
+	assert.Assert(t, list[1].updatePendingAskRepeat(-1), "repeat update should not have failed")
+	assert.Equal(t, queue.first(), list[3], "ask without a pending repeat should be skipped")
+	assert.Equal(t, len(queue.pending()), 3, "pending asks should not contain the ask without a pending repeat")
+
+	// an ask added again keeps its place, a removed ask is gone
+	queue.remove(list[0])
+	queue.remove(list[0])
+	assert.Equal(t, len(queue.asks), 3, "ask should have been removed once")
+	queue.push(list[0])
+	assertAskList(t, queue.asks, []int{2, 0, 3, 1})
+
+	// priority changes are applied on reorder
+	list[2].priority = 5
+	queue.reorder()
+	assertAskList(t, queue.asks, []int{3, 1, 0, 2})
+
+	queue.reset()
+	assert.Equal(t, len(queue.asks), 0, "reset should remove all asks")
+}
@@ -0,0 +1,201 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/rmproxy/rmevent"
+)
+
+// How often the pending resources of the queues are compared to the autoscale watermark
+var autoscaleInterval = 10 * time.Second
+
+// The number of events buffered per subscriber, events are dropped for a subscriber that does not keep up
+const autoscaleSubscriberBuffer = 64
+
+// The state of a leaf queue that has its pending resource above the watermark
+type autoscaleState struct {
+	aboveSince time.Time // first check that found the queue above the watermark
+	lastSent   time.Time // last time an event was sent, zero if no event was sent yet
+}
+
+// The subscribers to the autoscale events of all partitions, used by the REST stream.
+type autoscaleSubscribers struct {
+	channels map[int]chan *api.AutoscaleEvent
+	nextID   int
+
+	sync.Mutex
+}
+
+// Internal start of the autoscale watermark monitor
+func (s *Scheduler) internalAutoscaleMonitor() {
+	for {
+		time.Sleep(autoscaleInterval)
+		s.checkAutoscale(time.Now())
+	}
+}
+
+// Check all partitions against their watermark and send the events to the RM and the subscribers.
+func (s *Scheduler) checkAutoscale(now time.Time) {
+	for _, psc := range s.clusterSchedulingContext.getPartitionMapClone() {
+		events := psc.checkAutoscale(now)
+		if len(events) == 0 {
+			continue
+		}
+		s.eventHandlers.RMProxyEventHandler.HandleEvent(&rmevent.RMAutoscaleEvent{
+			RmID:   psc.RmID,
+			Events: toRMAutoscaleEvents(events, psc.partition.GetRMUnitFactors()),
+		})
+		s.clusterSchedulingContext.publishAutoscaleEvents(events)
+	}
+}
+
+// Return the events as they are sent to the RM: copies with the resources in the units of the RM if the RM uses its
+// own units, the events themselves otherwise. The subscribers get the events in scheduler units.
+func toRMAutoscaleEvents(events []*api.AutoscaleEvent, factors map[string]int64) []*api.AutoscaleEvent {
+	if len(factors) == 0 {
+		return events
+	}
+	converted := make([]*api.AutoscaleEvent, len(events))
+	for i, event := range events {
+		copied := *event
+		copied.Pending = resources.ToRMUnitsProto(event.Pending, factors)
+		copied.Unsatisfiable = resources.ToRMUnitsProto(event.Unsatisfiable, factors)
+		copied.Watermark = resources.ToRMUnitsProto(event.Watermark, factors)
+		converted[i] = &copied
+	}
+	return converted
+}
+
+// Compare the pending resource of the leaf queues with the watermark of the partition.
+// An event is returned for a queue that stayed above the watermark for the threshold, and again each threshold while
+// it stays above. A queue that drops below the watermark starts over.
+// The partition lock is only held to update the queue states, not while the queues are locked.
+func (psc *partitionSchedulingContext) checkAutoscale(now time.Time) []*api.AutoscaleEvent {
+	watermark, threshold := psc.partition.GetAutoscaleWatermark()
+	if watermark == nil {
+		psc.Lock()
+		psc.autoscale = make(map[string]*autoscaleState)
+		psc.Unlock()
+		return nil
+	}
+	// collect the queue details before locking the partition: the queues lock themselves
+	available := resources.Sub(psc.partition.GetTotalPartitionResource(), psc.root.GetAllocatedResource())
+	above := make(map[string]*api.AutoscaleEvent)
+	for _, leaf := range psc.root.getLeafQueues() {
+		pending := leaf.GetPendingResource()
+		if resources.FitInDefined(watermark, pending) {
+			continue
+		}
+		above[leaf.Name] = &api.AutoscaleEvent{
+			PartitionName: psc.Name,
+			QueueName:     leaf.Name,
+			Pending:       pending.ToProto(),
+			Unsatisfiable: getUnsatisfiable(pending, leaf.getHeadRoom(), available).ToProto(),
+			Watermark:     watermark.ToProto(),
+		}
+	}
+	psc.Lock()
+	defer psc.Unlock()
+	for name := range psc.autoscale {
+		if above[name] == nil {
+			delete(psc.autoscale, name)
+		}
+	}
+	var events []*api.AutoscaleEvent
+	for name, event := range above {
+		state := psc.autoscale[name]
+		if state == nil {
+			state = &autoscaleState{aboveSince: now}
+			psc.autoscale[name] = state
+		}
+		if now.Sub(state.aboveSince) < threshold || (!state.lastSent.IsZero() && now.Sub(state.lastSent) < threshold) {
+			continue
+		}
+		state.lastSent = now
+		event.AboveSince = state.aboveSince
+		events = append(events, event)
+		log.Logger().Info("queue pending resource above autoscale watermark",
+			zap.String("partitionName", psc.Name),
+			zap.String("queueName", name),
+			zap.String("pending", resources.NewResourceFromProto(event.Pending).String()),
+			zap.Time("aboveSince", state.aboveSince))
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].QueueName < events[j].QueueName
+	})
+	return events
+}
+
+// Get the part of the pending resource that cannot be scheduled with the available resources. The pending resource is
+// limited by the headroom first: a queue cannot use more than its headroom even if capacity is added.
+// Only the resource types that are pending are returned.
+func getUnsatisfiable(pending, headRoom, available *resources.Resource) *resources.Resource {
+	unsatisfiable := resources.NewResource()
+	for name, quantity := range pending.Resources {
+		if headRoom != nil {
+			quantity = resources.MinQuantity(quantity, headRoom.Resources[name])
+		}
+		unsatisfiable.Resources[name] = resources.MaxQuantity(quantity-available.Resources[name], 0)
+	}
+	return unsatisfiable
+}
+
+// Subscribe to the autoscale events of all partitions.
+// The returned function must be called to unsubscribe, the channel is not closed.
+func (csc *ClusterSchedulingContext) SubscribeAutoscaleEvents() (<-chan *api.AutoscaleEvent, func()) {
+	subscribers := csc.autoscaleSubscribers
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	id := subscribers.nextID
+	subscribers.nextID++
+	events := make(chan *api.AutoscaleEvent, autoscaleSubscriberBuffer)
+	subscribers.channels[id] = events
+	return events, func() {
+		subscribers.Lock()
+		defer subscribers.Unlock()
+		delete(subscribers.channels, id)
+	}
+}
+
+// Send the events to all subscribers without blocking the scheduler.
+func (csc *ClusterSchedulingContext) publishAutoscaleEvents(events []*api.AutoscaleEvent) {
+	subscribers := csc.autoscaleSubscribers
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	for id, channel := range subscribers.channels {
+		for _, event := range events {
+			select {
+			case channel <- event:
+			default:
+				log.Logger().Warn("autoscale event subscriber not keeping up, event dropped",
+					zap.Int("subscriber", id),
+					zap.String("queueName", event.QueueName))
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// partition for the autoscale tests: total 100 with 60 allocated, watermark 20 with a threshold of a minute.
+// The leaf queue has a max of 50 and nothing allocated, the other queue uses all allocated resources.
+func createAutoscalePartition(t *testing.T) (*partitionSchedulingContext, *SchedulingQueue) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	cache.SetTotalPartitionResource(partition.partition, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100}))
+	cache.SetAutoscaleWatermark(partition.partition, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20}), time.Minute)
+	var leaf, other *SchedulingQueue
+	leaf, err = createManagedQueue(partition.root, "leaf", false, map[string]string{"first": "50"})
+	assert.NilError(t, err, "failed to create leaf queue")
+	other, err = createManagedQueue(partition.root, "other", false, nil)
+	assert.NilError(t, err, "failed to create other queue")
+	err = other.QueueInfo.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 60}), false)
+	assert.NilError(t, err, "failed to set allocated resource on queue")
+	return partition, leaf
+}
+
+func TestCheckAutoscale(t *testing.T) {
+	partition, leaf := createAutoscalePartition(t)
+	now := time.Now()
+	// at the watermark: not above
+	leaf.incPendingResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20}))
+	assert.Equal(t, len(partition.checkAutoscale(now)), 0, "queue at the watermark should not send an event")
+	assert.Equal(t, len(partition.autoscale), 0, "queue at the watermark should not be tracked")
+
+	// above the watermark: tracked but no event before the threshold
+	leaf.incPendingResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 50}))
+	assert.Equal(t, len(partition.checkAutoscale(now)), 0, "no event expected before the threshold")
+	assert.Equal(t, len(partition.autoscale), 1, "queue above the watermark should be tracked")
+	assert.Equal(t, len(partition.checkAutoscale(now.Add(30*time.Second))), 0, "no event expected before the threshold")
+
+	// threshold passed: pending 70 limited by the headroom of 50, 40 available
+	events := partition.checkAutoscale(now.Add(time.Minute))
+	assert.Equal(t, len(events), 1, "expected an event after the threshold")
+	assert.Equal(t, events[0].QueueName, "root.leaf")
+	assert.Equal(t, events[0].PartitionName, "default")
+	assert.Equal(t, events[0].AboveSince, now)
+	assert.Assert(t, resources.Equals(resources.NewResourceFromProto(events[0].Pending), resources.NewResourceFromMap(map[string]resources.Quantity{"first": 70})))
+	assert.Assert(t, resources.Equals(resources.NewResourceFromProto(events[0].Unsatisfiable), resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})))
+
+	// repeated each threshold while above
+	assert.Equal(t, len(partition.checkAutoscale(now.Add(90*time.Second))), 0, "event should not repeat within the threshold")
+	assert.Equal(t, len(partition.checkAutoscale(now.Add(2*time.Minute))), 1, "event should repeat after the threshold")
+
+	// dropping below the watermark starts over
+	leaf.decPendingResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 60}))
+	assert.Equal(t, len(partition.checkAutoscale(now.Add(3*time.Minute))), 0, "queue below the watermark should not send an event")
+	assert.Equal(t, len(partition.autoscale), 0, "queue below the watermark should not be tracked")
+
+	// no watermark: nothing tracked
+	leaf.incPendingResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 60}))
+	assert.Equal(t, len(partition.checkAutoscale(now)), 0, "no event expected before the threshold")
+	cache.SetAutoscaleWatermark(partition.partition, nil, time.Minute)
+	assert.Equal(t, len(partition.checkAutoscale(now.Add(time.Hour))), 0, "no event expected without a watermark")
+	assert.Equal(t, len(partition.autoscale), 0, "nothing should be tracked without a watermark")
+}
+
+func TestUnsatisfiable(t *testing.T) {
+	pending := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 30, "second": 10})
+	available := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 20, "third": 5})
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20, "second": 0})
+	assert.Assert(t, resources.Equals(getUnsatisfiable(pending, nil, available), expected), "unexpected unsatisfiable without headroom")
+	headRoom := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 15, "second": 100})
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5, "second": 0})
+	assert.Assert(t, resources.Equals(getUnsatisfiable(pending, headRoom, available), expected), "unexpected unsatisfiable with headroom")
+}
+
+func TestToRMAutoscaleEvents(t *testing.T) {
+	event := &api.AutoscaleEvent{
+		QueueName:     "root.leaf",
+		Pending:       resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2500}).ToProto(),
+		Unsatisfiable: resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1000}).ToProto(),
+		Watermark:     resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2000, "second": 10}).ToProto(),
+	}
+	events := []*api.AutoscaleEvent{event}
+	assert.Equal(t, toRMAutoscaleEvents(events, nil)[0], event, "events without RM units should be sent as is")
+	converted := toRMAutoscaleEvents(events, map[string]int64{"first": 1000})
+	assert.Equal(t, converted[0].QueueName, "root.leaf")
+	assert.Assert(t, resources.Equals(resources.NewResourceFromProto(converted[0].Pending), resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2})))
+	assert.Assert(t, resources.Equals(resources.NewResourceFromProto(converted[0].Unsatisfiable), resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})))
+	assert.Assert(t, resources.Equals(resources.NewResourceFromProto(converted[0].Watermark), resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2, "second": 10})))
+	// the subscribers get the events in scheduler units
+	assert.Equal(t, event.Pending.Resources["first"].Value, int64(2500), "original event should not be changed")
+}
+
+func TestAutoscaleSubscribers(t *testing.T) {
+	csc := NewClusterSchedulingContext()
+	first, unsubscribeFirst := csc.SubscribeAutoscaleEvents()
+	second, unsubscribeSecond := csc.SubscribeAutoscaleEvents()
+	event := &api.AutoscaleEvent{PartitionName: "default", QueueName: "root.leaf"}
+	csc.publishAutoscaleEvents([]*api.AutoscaleEvent{event})
+	assert.Equal(t, <-first, event, "first subscriber should get the event")
+	assert.Equal(t, <-second, event, "second subscriber should get the event")
+
+	// unsubscribed: nothing received
+	unsubscribeSecond()
+	csc.publishAutoscaleEvents([]*api.AutoscaleEvent{event})
+	assert.Equal(t, len(first), 1, "first subscriber should get the event")
+	assert.Equal(t, len(second), 0, "unsubscribed subscriber should not get the event")
+
+	// full subscriber does not block
+	for i := 0; i < autoscaleSubscriberBuffer+10; i++ {
+		csc.publishAutoscaleEvents([]*api.AutoscaleEvent{event})
+	}
+	assert.Equal(t, len(first), autoscaleSubscriberBuffer, "subscriber buffer should be full")
+	unsubscribeFirst()
+}
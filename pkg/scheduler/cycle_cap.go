@@ -0,0 +1,76 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
+)
+
+// The allocations of the leaf queues in the current scheduling cycle of a partition.
+// A leaf queue with a cycle cap is skipped for the rest of the cycle after reaching the cap: a queue with a large
+// backlog cannot take all allocations of a cycle and delay the first allocation of the other queues.
+type cycleTracker struct {
+	size        int            // number of allocations of the current cycle
+	allocations map[string]int // allocations per leaf queue in the current cycle
+	capped      int            // number of leaf queues that reached their cap in the current cycle
+
+	locking.RWMutex
+}
+
+func newCycleTracker() *cycleTracker {
+	return &cycleTracker{
+		allocations: make(map[string]int),
+	}
+}
+
+// Start a new scheduling cycle with the number of allocations passed in.
+func (ct *cycleTracker) start(size int) {
+	ct.Lock()
+	defer ct.Unlock()
+	ct.size = size
+	ct.allocations = make(map[string]int)
+	ct.capped = 0
+}
+
+// Check if the leaf queue reached its allocation cap in the current cycle.
+func (ct *cycleTracker) isCapped(sq *SchedulingQueue) bool {
+	ct.RLock()
+	defer ct.RUnlock()
+	cycleCap := sq.QueueInfo.GetCycleAllocationCap(ct.size)
+	return cycleCap > 0 && ct.allocations[sq.Name] >= cycleCap
+}
+
+// Record an allocation of the leaf queue in the current cycle.
+func (ct *cycleTracker) allocated(sq *SchedulingQueue) {
+	ct.Lock()
+	defer ct.Unlock()
+	ct.allocations[sq.Name]++
+	if cycleCap := sq.QueueInfo.GetCycleAllocationCap(ct.size); cycleCap > 0 && ct.allocations[sq.Name] == cycleCap {
+		ct.capped++
+		metrics.GetQueueMetrics(sq.Name).IncCyclesCapped()
+	}
+}
+
+// Check if any leaf queue reached its allocation cap in the current cycle.
+func (ct *cycleTracker) anyCapped() bool {
+	ct.RLock()
+	defer ct.RUnlock()
+	return ct.capped > 0
+}
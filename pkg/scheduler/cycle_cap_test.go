@@ -0,0 +1,85 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"strconv"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+func TestCycleAllocationCap(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	partition.addSchedulingNode(cache.NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100})))
+	conf := configs.QueueConfig{
+		Name:       "backlog",
+		Resources:  configs.Resources{Max: map[string]string{"first": "100"}},
+		Properties: map[string]string{cache.QueueCycleAllocations: "2"},
+	}
+	var queueInfo *cache.QueueInfo
+	queueInfo, err = cache.NewManagedQueue(conf, partition.root.QueueInfo)
+	assert.NilError(t, err, "failed to create backlog queue")
+	backlog := newSchedulingQueueInfo(queueInfo, partition.root)
+	var interactive *SchedulingQueue
+	interactive, err = createManagedQueue(partition.root, "interactive", false, map[string]string{"first": "100"})
+	assert.NilError(t, err, "failed to create interactive queue")
+
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	addApp := func(queue *SchedulingQueue, appID string, asks int) {
+		app := newSchedulingApplication(cache.NewApplicationInfo(appID, "default", queue.Name, security.UserGroup{}, nil))
+		app.queue = queue
+		queue.addSchedulingApplication(app)
+		for i := 0; i < asks; i++ {
+			_, err = app.addAllocationAsk(newAllocationAsk("alloc-"+strconv.Itoa(i), appID, res))
+			assert.NilError(t, err, "failed to add ask to app")
+		}
+	}
+	addApp(backlog, "app-backlog", 10)
+
+	// the backlog queue is skipped after two allocations in the cycle
+	partition.cycle.start(10)
+	for i := 0; i < 2; i++ {
+		assert.Assert(t, !partition.cycle.isCapped(backlog), "queue should not be capped after %d allocations", i)
+		alloc := backlog.tryAllocate(partition)
+		assert.Assert(t, alloc != nil, "allocation %d should be made", i)
+		partition.cycle.allocated(backlog)
+	}
+	assert.Assert(t, partition.cycle.isCapped(backlog), "queue should be capped")
+	assert.Assert(t, partition.cycle.anyCapped(), "cycle should have a capped queue")
+	assert.Assert(t, backlog.tryAllocate(partition) == nil, "capped queue should be skipped")
+
+	// the other queue still gets its allocation in the same cycle
+	addApp(interactive, "app-interactive", 1)
+	alloc := partition.root.tryAllocate(partition)
+	assert.Assert(t, alloc != nil, "interactive allocation should be made")
+	assert.Equal(t, alloc.schedulingAsk.ApplicationID, "app-interactive", "capped queue should not get the allocation")
+
+	// a new cycle removes the cap
+	partition.cycle.start(10)
+	assert.Assert(t, !partition.cycle.isCapped(backlog), "queue should not be capped in a new cycle")
+	assert.Assert(t, !partition.cycle.anyCapped(), "new cycle should not have a capped queue")
+	assert.Assert(t, backlog.tryAllocate(partition) != nil, "allocation should be made in a new cycle")
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -123,6 +124,10 @@ type singleNodePreemptResult struct {
 // Do surgical preemption on node, if able to preempt, returns
 func trySurgicalPreemptionOnNode(preemptionPartitionCtx *preemptionPartitionContext, preemptorQueue *preemptionQueueContext, node *SchedulingNode, candidate *schedulingAllocationAsk,
 	headroomShortages map[string]*resources.Resource) *singleNodePreemptResult {
+	// never preempt on a node the candidate cannot be allocated on
+	if !candidate.toleratesNode(node) || !candidate.acceptsNodeAge(node) {
+		return nil
+	}
 	// If allocated resource can fit in the node, and no headroom shortage of preemptor queue, we can directly get it allocated. (lucky!)
 	if node.allocateResource(candidate.AllocatedResource, true) {
 		log.Logger().Debug("No preemption needed candidate fits on node",
@@ -153,6 +158,26 @@ func trySurgicalPreemptionOnNode(preemptionPartitionCtx *preemptionPartitionCont
 			continue
 		}
 
+		// Skip allocations of queues that opted out of preemption
+		if preemptQueue.resources.preemptionDisabled {
+			continue
+		}
+
+		// Skip allocations of queues that are settling after a change of their limits
+		if preemptQueue.settling {
+			continue
+		}
+
+		// Skip allocations outside the preemption scope of the preemptor
+		if preemptQueue.scope != preemptorQueue.scope {
+			continue
+		}
+
+		// Skip allocations that have not run long enough to be preempted
+		if preemptionPartitionCtx.protection.isProtected(alloc, candidate.priority) {
+			continue
+		}
+
 		// Skip when the queue has <= 0 preempt-able resource
 		if resources.CompUsageRatio(preemptQueue.resources.preemptable, resources.Zero, preemptionPartitionCtx.partitionTotalResource) <= 0 {
 			continue
@@ -253,7 +278,7 @@ func createPreemptionAndAllocationProposal(preemptionPartitionContext *preemptio
 	for _, pr := range preemptionResults {
 		for uuid, alloc := range pr.toReleaseAllocations {
 			allocation.releases = append(allocation.releases, commonevents.NewReleaseAllocation(uuid, alloc.ApplicationID, nodeToAllocate.nodeInfo.Partition,
-				fmt.Sprintf("Preempt allocation=%s for ask=%s", alloc, candidate.AskProto.AllocationKey), si.AllocationReleaseResponse_PREEMPTED_BY_SCHEDULER))
+				fmt.Sprintf("Preempt allocation=%s for ask=%s", uuid, candidate.AskProto.AllocationKey), si.AllocationReleaseResponse_PREEMPTED_BY_SCHEDULER))
 
 			// Update metrics of preempt queue
 			preemptQueue := preemptionPartitionContext.leafQueues[alloc.AllocationProto.QueueName]
@@ -261,6 +286,7 @@ func createPreemptionAndAllocationProposal(preemptionPartitionContext *preemptio
 			preemptQueue.resources.preemptable = resources.SubEliminateNegative(preemptQueue.resources.preemptable, alloc.AllocatedResource)
 		}
 		pr.node.incPreemptingResource(pr.totalReleasedResource)
+		metrics.GetSchedulerMetrics().AddPreemptedAllocations(len(pr.toReleaseAllocations))
 	}
 
 	// Update metrics
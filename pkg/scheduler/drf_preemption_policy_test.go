@@ -0,0 +1,330 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+type quantities = map[string]resources.Quantity
+
+// Golden tests for the DRF ideal and preemptable resource calculation.
+func TestDRFIdealAndPreemptable(t *testing.T) {
+	var tests = []struct {
+		name        string
+		total       quantities
+		queues      []queueFixture
+		ideal       map[string]quantities
+		preemptable map[string]quantities
+	}{
+		{
+			name:  "demand below total",
+			total: quantities{"memory": 100},
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 40}, used: quantities{"memory": 20}, pending: quantities{"memory": 10}},
+				{path: "root.b", guaranteed: quantities{"memory": 10}, used: quantities{"memory": 30}},
+			},
+			ideal: map[string]quantities{
+				"root":   {"memory": 100},
+				"root.a": {"memory": 30},
+				"root.b": {"memory": 30},
+			},
+			preemptable: map[string]quantities{
+				"root.a": {},
+				"root.b": {"memory": 20},
+			},
+		},
+		{
+			name:  "capped by max",
+			total: quantities{"memory": 100},
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 50}, pending: quantities{"memory": 100}, max: quantities{"memory": 30}},
+				{path: "root.b", guaranteed: quantities{"memory": 50}, pending: quantities{"memory": 100}},
+			},
+			ideal: map[string]quantities{
+				"root.a": {"memory": 30},
+				"root.b": {"memory": 70},
+			},
+			preemptable: map[string]quantities{
+				"root.a": {},
+				"root.b": {},
+			},
+		},
+		{
+			name:  "contended with dead zone",
+			total: quantities{"memory": 100},
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 50}, used: quantities{"memory": 54}},
+				{path: "root.b", guaranteed: quantities{"memory": 50}, used: quantities{"memory": 60}},
+			},
+			ideal: map[string]quantities{
+				"root.a": {"memory": 54},
+				"root.b": {"memory": 46},
+			},
+			preemptable: map[string]quantities{
+				"root.a": {},
+				"root.b": {"memory": 10},
+			},
+		},
+		{
+			name:  "nested hierarchy",
+			total: quantities{"memory": 100},
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.p", parent: true, guaranteed: quantities{"memory": 60}, used: quantities{"memory": 30}, pending: quantities{"memory": 20}},
+				{path: "root.p.x", guaranteed: quantities{"memory": 30}, used: quantities{"memory": 10}, pending: quantities{"memory": 10}},
+				{path: "root.p.y", guaranteed: quantities{"memory": 10}, used: quantities{"memory": 20}, pending: quantities{"memory": 10}},
+				{path: "root.c", guaranteed: quantities{"memory": 40}, used: quantities{"memory": 40}},
+			},
+			ideal: map[string]quantities{
+				"root.p":   {"memory": 50},
+				"root.p.x": {"memory": 20},
+				"root.p.y": {"memory": 30},
+				"root.c":   {"memory": 40},
+			},
+			preemptable: map[string]quantities{
+				"root.p":   {},
+				"root.p.x": {},
+				"root.p.y": {"memory": 10},
+				"root.c":   {},
+			},
+		},
+		{
+			name:  "no guarantees",
+			total: quantities{"memory": 100},
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", used: quantities{"memory": 10}, pending: quantities{"memory": 50}},
+				{path: "root.b", pending: quantities{"memory": 20}},
+			},
+			ideal: map[string]quantities{
+				"root.a": {"memory": 60},
+				"root.b": {"memory": 20},
+			},
+			preemptable: map[string]quantities{
+				"root.a": {"memory": 10},
+				"root.b": {},
+			},
+		},
+		{
+			name:  "guaranteed queues first",
+			total: quantities{"memory": 100},
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 50}, used: quantities{"memory": 50}, pending: quantities{"memory": 50}},
+				{path: "root.b", pending: quantities{"memory": 30}},
+			},
+			ideal: map[string]quantities{
+				"root.a": {"memory": 100},
+				"root.b": {},
+			},
+			preemptable: map[string]quantities{
+				"root.a": {},
+				"root.b": {},
+			},
+		},
+		{
+			name:  "multiple resource types",
+			total: quantities{"memory": 100, "vcore": 10},
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 50, "vcore": 5}, used: quantities{"memory": 20, "vcore": 8}, pending: quantities{"memory": 10}},
+				{path: "root.b", guaranteed: quantities{"memory": 50, "vcore": 5}, used: quantities{"memory": 40, "vcore": 1}, pending: quantities{"vcore": 1}},
+			},
+			ideal: map[string]quantities{
+				"root.a": {"memory": 30, "vcore": 8},
+				"root.b": {"memory": 40, "vcore": 2},
+			},
+			preemptable: map[string]quantities{
+				"root.a": {"vcore": 3},
+				"root.b": {},
+			},
+		},
+		{
+			name:  "preemption disabled",
+			total: quantities{"memory": 100},
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 40}, used: quantities{"memory": 20}, pending: quantities{"memory": 20}},
+				{path: "root.b", guaranteed: quantities{"memory": 10}, used: quantities{"memory": 40}, disabled: true},
+				{path: "root.c", guaranteed: quantities{"memory": 10}, used: quantities{"memory": 40}},
+			},
+			ideal: map[string]quantities{
+				"root.a": {"memory": 40},
+				"root.b": {"memory": 36},
+				"root.c": {"memory": 24},
+			},
+			preemptable: map[string]quantities{
+				"root.a": {},
+				"root.b": {},
+				"root.c": {"memory": 30},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newPreemptionHarness(t, tt.total, tt.queues)
+			h.calculate()
+			for queuePath, expected := range tt.ideal {
+				h.assertIdeal(queuePath, expected)
+			}
+			for queuePath, expected := range tt.preemptable {
+				h.assertPreemptable(queuePath, expected)
+			}
+		})
+	}
+}
+
+// Golden tests for the DRF victim selection on a single node.
+func TestDRFVictims(t *testing.T) {
+	var tests = []struct {
+		name    string
+		queues  []queueFixture
+		allocs  map[string]string // uuid to queue, all allocations are 10 memory
+		ask     quantities
+		victims []string
+		ok      bool
+	}{
+		{
+			name: "fits without preemption",
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 60}, used: quantities{"memory": 40}, pending: quantities{"memory": 20}},
+				{path: "root.b", guaranteed: quantities{"memory": 40}},
+			},
+			allocs:  map[string]string{"a-1": "root.a", "a-2": "root.a", "a-3": "root.a", "a-4": "root.a"},
+			ask:     quantities{"memory": 20},
+			victims: []string{},
+			ok:      true,
+		},
+		{
+			name: "preempt over guarantee",
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 60}, used: quantities{"memory": 40}, pending: quantities{"memory": 20}},
+				{path: "root.b", guaranteed: quantities{"memory": 40}, used: quantities{"memory": 60}},
+			},
+			allocs: map[string]string{"a-1": "root.a", "a-2": "root.a", "a-3": "root.a", "a-4": "root.a",
+				"b-1": "root.b", "b-2": "root.b", "b-3": "root.b", "b-4": "root.b", "b-5": "root.b", "b-6": "root.b"},
+			ask: quantities{"memory": 10},
+			ok:  true,
+		},
+		{
+			name: "preemption disabled",
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 60}, used: quantities{"memory": 40}, pending: quantities{"memory": 20}},
+				{path: "root.b", guaranteed: quantities{"memory": 40}, used: quantities{"memory": 60}, disabled: true},
+			},
+			allocs: map[string]string{"a-1": "root.a", "a-2": "root.a", "a-3": "root.a", "a-4": "root.a",
+				"b-1": "root.b", "b-2": "root.b", "b-3": "root.b", "b-4": "root.b", "b-5": "root.b", "b-6": "root.b"},
+			ask: quantities{"memory": 10},
+			ok:  false,
+		},
+		{
+			name: "nothing preemptable",
+			queues: []queueFixture{
+				{path: "root", parent: true},
+				{path: "root.a", guaranteed: quantities{"memory": 40}, used: quantities{"memory": 40}, pending: quantities{"memory": 20}},
+				{path: "root.b", guaranteed: quantities{"memory": 60}, used: quantities{"memory": 60}},
+			},
+			allocs: map[string]string{"a-1": "root.a", "a-2": "root.a", "a-3": "root.a", "a-4": "root.a",
+				"b-1": "root.b", "b-2": "root.b", "b-3": "root.b", "b-4": "root.b", "b-5": "root.b", "b-6": "root.b"},
+			ask: quantities{"memory": 10},
+			ok:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newPreemptionHarness(t, quantities{"memory": 100}, tt.queues)
+			h.addNode("node-1", quantities{"memory": 100})
+			for uuid, queuePath := range tt.allocs {
+				h.addAllocation(uuid, queuePath, "node-1", quantities{"memory": 10})
+			}
+			h.calculate()
+			victims, ok := h.victims("root.a", tt.ask)
+			assert.Equal(t, ok, tt.ok, "unexpected preemption result")
+			if !ok {
+				return
+			}
+			// which allocation of a queue is picked is not defined, only the queue and the number
+			if tt.victims != nil {
+				assert.DeepEqual(t, victims, tt.victims)
+				return
+			}
+			assert.Equal(t, len(victims), 1, "expected one victim: %v", victims)
+			assert.Equal(t, victims[0][:2], "b-", "victim should be from queue b: %v", victims)
+		})
+	}
+}
+
+// Victim selection with freshly started allocations protected by the minimum runtime.
+func TestDRFVictimsMinRuntime(t *testing.T) {
+	queues := []queueFixture{
+		{path: "root", parent: true},
+		{path: "root.a", guaranteed: quantities{"memory": 60}, used: quantities{"memory": 40}, pending: quantities{"memory": 20}},
+		{path: "root.b", guaranteed: quantities{"memory": 40}, used: quantities{"memory": 60}},
+	}
+	create := func() *preemptionHarness {
+		h := newPreemptionHarness(t, quantities{"memory": 100}, queues)
+		h.ctx.protection = &preemptionProtection{minRuntime: time.Minute, emergencyPriority: 100, now: time.Now()}
+		h.addNode("node-1", quantities{"memory": 100})
+		for _, uuid := range []string{"a-1", "a-2", "a-3", "a-4"} {
+			h.addAllocation(uuid, "root.a", "node-1", quantities{"memory": 10})
+		}
+		return h
+	}
+
+	// all allocations of b just started
+	h := create()
+	for _, uuid := range []string{"b-1", "b-2", "b-3", "b-4", "b-5", "b-6"} {
+		alloc := h.addAllocation(uuid, "root.b", "node-1", quantities{"memory": 10})
+		cache.SetAllocationCreateTime(alloc, time.Now())
+	}
+	h.calculate()
+	_, ok := h.victims("root.a", quantities{"memory": 10})
+	assert.Assert(t, !ok, "protected allocations should not be preempted")
+	victims, ok := h.victimsWithPriority("root.a", quantities{"memory": 10}, 100)
+	assert.Assert(t, ok, "emergency priority should preempt protected allocations")
+	assert.Equal(t, len(victims), 1, "expected one victim: %v", victims)
+
+	// only one allocation of b ran long enough
+	h = create()
+	for _, uuid := range []string{"b-1", "b-2", "b-3", "b-4", "b-5", "b-6"} {
+		alloc := h.addAllocation(uuid, "root.b", "node-1", quantities{"memory": 10})
+		createTime := time.Now()
+		if uuid == "b-4" {
+			createTime = createTime.Add(-2 * time.Minute)
+		}
+		cache.SetAllocationCreateTime(alloc, createTime)
+	}
+	h.calculate()
+	victims, ok = h.victims("root.a", quantities{"memory": 10})
+	assert.Assert(t, ok, "allocation past the minimum runtime should be preempted")
+	assert.DeepEqual(t, victims, []string{"b-4"})
+}
@@ -0,0 +1,369 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"math"
+	"sort"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// Hierarchical fair share calculation for a partition.
+// The partition total resource is divided over the queues top down, per resource type. Each level divides the share
+// of the parent over the children: queues with a guaranteed resource first, proportional to the guarantee multiplied
+// by the weight, then the queues without a guarantee proportional to the weight. A queue never gets more than its demand (used + pending) or its maximum.
+// The fair share of a queue is the ideal resource it should use. The normalized fair share is the dominant share of
+// the fair share compared to the partition total: a value between 0 and 1.
+// A calculator is not safe for concurrent modification: the queues are added and the shares calculated once, after
+// that it is read only.
+type FairShareCalculator struct {
+	total  *resources.Resource
+	root   *fairShareQueue
+	queues map[string]*fairShareQueue
+}
+
+// The input and the result of the calculation for one queue.
+type fairShareQueue struct {
+	queuePath  string
+	guaranteed *resources.Resource
+	used       *resources.Resource
+	pending    *resources.Resource
+	max        *resources.Resource
+	weight     float64
+	ideal      *resources.Resource
+	normalized float64
+	children   map[string]*fairShareQueue
+}
+
+// Temp object for better readability: the values of one resource type of a queue.
+type fairShareByType struct {
+	guaranteed          resources.Quantity
+	weight              float64
+	normalizedGuarantee float64
+	used                resources.Quantity
+	pending             resources.Quantity
+	max                 resources.Quantity
+	ideal               resources.Quantity
+	// Point to queue to be updated.
+	queue *fairShareQueue
+}
+
+// Create a calculator for the partition total resource, the queues must be added before calculating.
+func NewFairShareCalculator(total *resources.Resource) *FairShareCalculator {
+	if total == nil {
+		total = resources.NewResource()
+	}
+	return &FairShareCalculator{
+		total:  total,
+		queues: make(map[string]*fairShareQueue),
+	}
+}
+
+// Create a calculator for the scheduling queue hierarchy and calculate the fair shares.
+// The used resource of a queue includes the resources that are being allocated.
+// Lock free call all locks are taken when needed in called functions
+func newFairShareCalculatorForQueues(root *SchedulingQueue, total *resources.Resource) *FairShareCalculator {
+	calc := NewFairShareCalculator(total)
+	var add func(queue *SchedulingQueue, parent string)
+	add = func(queue *SchedulingQueue, parent string) {
+		calc.AddQueue(queue.Name, parent, queue.QueueInfo.GetGuaranteedResource(), queue.getAssumeAllocated(),
+			queue.GetPendingResource(), applyBorrowLimit(queue.QueueInfo.GetMaxResource(), queue.QueueInfo.GetBorrowMaxResource()))
+		calc.SetWeight(queue.Name, queue.QueueInfo.GetWeight())
+		for _, child := range queue.GetCopyOfChildren() {
+			add(child, queue.Name)
+		}
+	}
+	if root != nil {
+		add(root, "")
+	}
+	calc.Calculate()
+	return calc
+}
+
+// Add a queue to the calculator. The parent must be added before the children, an empty parent adds the root queue.
+// A nil maximum means the queue is not limited: the partition total is used. Nil resources are treated as zero.
+// Returns false if the parent is not found or a root queue is already set.
+func (fsc *FairShareCalculator) AddQueue(queuePath, parentPath string, guaranteed, used, pending, max *resources.Resource) bool {
+	queue := &fairShareQueue{
+		queuePath:  queuePath,
+		guaranteed: nonNilResource(guaranteed),
+		used:       nonNilResource(used),
+		pending:    nonNilResource(pending),
+		max:        max,
+		weight:     1,
+		ideal:      resources.NewResource(),
+		children:   make(map[string]*fairShareQueue),
+	}
+	if queue.max == nil {
+		queue.max = fsc.total.Clone()
+	}
+	if parentPath == "" {
+		if fsc.root != nil {
+			return false
+		}
+		fsc.root = queue
+	} else {
+		parent := fsc.queues[parentPath]
+		if parent == nil {
+			return false
+		}
+		parent.children[queuePath] = queue
+	}
+	fsc.queues[queuePath] = queue
+	return true
+}
+
+// Set the weight of the queue compared to its siblings, the default weight is 1. A weight that is not positive is
+// ignored. Returns false if the queue is not found.
+func (fsc *FairShareCalculator) SetWeight(queuePath string, weight float64) bool {
+	queue := fsc.queues[queuePath]
+	if queue == nil {
+		return false
+	}
+	if weight > 0 {
+		queue.weight = weight
+	}
+	return true
+}
+
+// Calculate the fair share for all queues added.
+func (fsc *FairShareCalculator) Calculate() {
+	if fsc.root == nil {
+		return
+	}
+	// the root queue gets the whole partition
+	fsc.root.ideal = fsc.total.Clone()
+	// for each resource type set the share of the children, recursively
+	for resourceType, quantity := range fsc.total.Resources {
+		setFairShareForChildren(resourceType, quantity, fsc.root.children)
+	}
+	for _, queue := range fsc.queues {
+		queue.normalized = dominantShare(queue.ideal, fsc.total)
+	}
+}
+
+// Get the fair share of the queue, nil if the queue is not known.
+func (fsc *FairShareCalculator) GetFairShare(queuePath string) *resources.Resource {
+	if queue := fsc.queues[queuePath]; queue != nil {
+		return queue.ideal.Clone()
+	}
+	return nil
+}
+
+// Get the normalized fair share of the queue, 0 if the queue is not known.
+func (fsc *FairShareCalculator) GetNormalizedFairShare(queuePath string) float64 {
+	if queue := fsc.queues[queuePath]; queue != nil {
+		return queue.normalized
+	}
+	return 0
+}
+
+// Get the dominant share of the partition used by the queue, 0 if the queue is not known.
+func (fsc *FairShareCalculator) GetUsageShare(queuePath string) float64 {
+	if queue := fsc.queues[queuePath]; queue != nil {
+		return dominantShare(queue.used, fsc.total)
+	}
+	return 0
+}
+
+// Get the fair share details of all queues, sorted by queue path.
+func (fsc *FairShareCalculator) GetFairShareInfos() []*dao.FairShareDAOInfo {
+	infos := make([]*dao.FairShareDAOInfo, 0, len(fsc.queues))
+	for _, queue := range fsc.queues {
+		infos = append(infos, &dao.FairShareDAOInfo{
+			QueueName:           queue.queuePath,
+			FairShare:           queue.ideal.DAOString(),
+			NormalizedFairShare: queue.normalized,
+			Used:                queue.used.DAOString(),
+			Pending:             queue.pending.DAOString(),
+			UsageShare:          dominantShare(queue.used, fsc.total),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].QueueName < infos[j].QueueName
+	})
+	return infos
+}
+
+func nonNilResource(res *resources.Resource) *resources.Resource {
+	if res == nil {
+		return resources.NewResource()
+	}
+	return res
+}
+
+// The largest share of any resource type of the resource in the total. Types not in the total are ignored.
+func dominantShare(res, total *resources.Resource) float64 {
+	share := 0.0
+	for resourceType, quantity := range total.Resources {
+		if quantity <= 0 {
+			continue
+		}
+		share = math.Max(share, float64(res.Resources[resourceType])/float64(quantity))
+	}
+	return share
+}
+
+func newFairShareByType(resourceType string, queue *fairShareQueue) *fairShareByType {
+	return &fairShareByType{
+		guaranteed: queue.guaranteed.Resources[resourceType],
+		weight:     queue.weight,
+		used:       queue.used.Resources[resourceType],
+		pending:    queue.pending.Resources[resourceType],
+		max:        queue.max.Resources[resourceType],
+		queue:      queue,
+	}
+}
+
+func setFairShareForChildren(resourceType string, parentTotal resources.Quantity, children map[string]*fairShareQueue) {
+	// Two iterates, first assign resources to non-empty guaranteed queues.
+	totalGuaranteed := 0.0
+	totalWeight := 0.0
+
+	nonZeroGuaranteedQueues := make(map[string]*fairShareByType)
+	zeroGuaranteedQueues := make(map[string]*fairShareByType)
+
+	for queueName, queue := range children {
+		byType := newFairShareByType(resourceType, queue)
+		if byType.guaranteed > 0 {
+			totalGuaranteed += float64(byType.guaranteed) * byType.weight
+			nonZeroGuaranteedQueues[queueName] = byType
+		} else {
+			totalWeight += byType.weight
+			zeroGuaranteedQueues[queueName] = byType
+		}
+	}
+
+	// calculate normalized guaranteed resources
+	// For queue which guaranteed resource > 0, it is proportion to its guaranteed multiplied by the weight
+	for _, queue := range nonZeroGuaranteedQueues {
+		queue.normalizedGuarantee = float64(queue.guaranteed) * queue.weight / totalGuaranteed
+	}
+	// For queue which guaranteed resource == 0, it is proportion to its weight
+	for _, queue := range zeroGuaranteedQueues {
+		queue.normalizedGuarantee = queue.weight / totalWeight
+	}
+
+	// Then calculate ideal allocation
+	totalAvailable := parentTotal
+	if totalAvailable > 0 {
+		totalAvailable -= calculateFairShare(resourceType, totalAvailable, nonZeroGuaranteedQueues)
+	}
+	if totalAvailable > 0 {
+		calculateFairShare(resourceType, totalAvailable, zeroGuaranteedQueues)
+	}
+
+	// For each children, set ideal for its children
+	for _, child := range children {
+		setFairShareForChildren(resourceType, child.ideal.Resources[resourceType], child.children)
+	}
+}
+
+// Returns allocated resources
+func calculateFairShare(resourceType string, totalAvailable resources.Quantity, queues map[string]*fairShareByType) resources.Quantity {
+	satisfiedQueues := make(map[string]bool)
+	totalAllocated := resources.Quantity(0)
+
+	// Reset ideal allocation to zero
+	for _, byType := range queues {
+		byType.ideal = 0
+		byType.queue.ideal.Resources[resourceType] = 0
+	}
+
+	// Iterate in a stable order: with contention the result depends on the order the queues are handled
+	queueNames := make([]string, 0, len(queues))
+	for queue := range queues {
+		queueNames = append(queueNames, queue)
+	}
+	sort.Strings(queueNames)
+
+	// With different weights the queues share what is available at the start of a round: a queue handled first in
+	// a round must not get more than its weighted share. Without weights the queues share what is left, as before.
+	weighted := hasDifferentWeights(queues)
+	for len(queues) > 0 && totalAvailable > 0 {
+		roundAvailable := totalAvailable
+		for _, queue := range queueNames {
+			byType := queues[queue]
+			// Ignore satisfied queues
+			if satisfiedQueues[queue] {
+				continue
+			}
+
+			// How much resource we can give to this queue this round, never more than what is left
+			if !weighted {
+				roundAvailable = totalAvailable
+			}
+			totalAvailableForQueue := resources.MinQuantity(totalAvailable,
+				resources.Quantity(math.Ceil(float64(roundAvailable)*byType.normalizedGuarantee)))
+
+			// How much queue will accept
+			// It equals min(total-available, min(pending + used, max) - ideal)
+			accepted := resources.MinQuantity(totalAvailableForQueue, resources.MinQuantity(byType.pending+byType.used, byType.max)-byType.ideal)
+
+			if accepted > 0 {
+				byType.ideal += accepted
+				totalAvailable -= accepted
+				totalAllocated += accepted
+			} else {
+				satisfiedQueues[queue] = true
+			}
+		}
+
+		// Stop when all queues are satisfied, the remaining resources cannot be assigned
+		if len(satisfiedQueues) == len(queues) {
+			break
+		}
+
+		// Recompute normalized guarantees
+		totalGuarantees := 0.0
+		for queue, byType := range queues {
+			if satisfiedQueues[queue] {
+				continue
+			}
+			totalGuarantees += byType.normalizedGuarantee
+		}
+		for queue, byType := range queues {
+			if satisfiedQueues[queue] {
+				continue
+			}
+			byType.normalizedGuarantee /= totalGuarantees
+		}
+	}
+
+	// Set the ideal resource for given resource type
+	for _, byType := range queues {
+		byType.queue.ideal.Resources[resourceType] = byType.ideal
+	}
+
+	return totalAllocated
+}
+
+// Return true if not all queues have the same weight.
+func hasDifferentWeights(queues map[string]*fairShareByType) bool {
+	weight := 0.0
+	for _, byType := range queues {
+		if weight != 0 && byType.weight != weight {
+			return true
+		}
+		weight = byType.weight
+	}
+	return false
+}
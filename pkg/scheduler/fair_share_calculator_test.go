@@ -0,0 +1,134 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestFairShareCalculator(t *testing.T) {
+	total := resources.NewResourceFromMap(quantities{"memory": 100, "vcore": 10})
+	calc := NewFairShareCalculator(total)
+	res := func(q quantities) *resources.Resource {
+		return resources.NewResourceFromMap(q)
+	}
+	assert.Assert(t, !calc.AddQueue("root.a", "root", nil, nil, nil, nil), "queue without parent should not be added")
+	assert.Assert(t, calc.AddQueue("root", "", nil, nil, nil, nil), "root queue should be added")
+	assert.Assert(t, !calc.AddQueue("other", "", nil, nil, nil, nil), "second root queue should not be added")
+	// a is below its guarantee, b uses the remainder: nothing is contended
+	assert.Assert(t, calc.AddQueue("root.a", "root", res(quantities{"memory": 60, "vcore": 5}), res(quantities{"memory": 10}), res(quantities{"memory": 20, "vcore": 1}), nil), "queue a should be added")
+	assert.Assert(t, calc.AddQueue("root.b", "root", res(quantities{"memory": 40, "vcore": 5}), res(quantities{"memory": 40, "vcore": 2}), res(quantities{"memory": 10, "vcore": 6}), nil), "queue b should be added")
+	calc.Calculate()
+
+	assert.Assert(t, resources.Equals(calc.GetFairShare("root"), total), "root fair share should be the total")
+	assert.Assert(t, resources.Equals(calc.GetFairShare("root.a"), res(quantities{"memory": 30, "vcore": 1})), "unexpected fair share a: %v", calc.GetFairShare("root.a"))
+	assert.Assert(t, resources.Equals(calc.GetFairShare("root.b"), res(quantities{"memory": 50, "vcore": 8})), "unexpected fair share b: %v", calc.GetFairShare("root.b"))
+	assert.Assert(t, calc.GetFairShare("root.unknown") == nil, "unknown queue should not have a fair share")
+
+	// normalized is the dominant share of the fair share
+	assert.Equal(t, calc.GetNormalizedFairShare("root"), 1.0, "unexpected normalized fair share root")
+	assert.Equal(t, calc.GetNormalizedFairShare("root.a"), 0.3, "unexpected normalized fair share a")
+	assert.Equal(t, calc.GetNormalizedFairShare("root.b"), 0.8, "unexpected normalized fair share b")
+	assert.Equal(t, calc.GetNormalizedFairShare("root.unknown"), 0.0, "unknown queue should not have a normalized fair share")
+
+	infos := calc.GetFairShareInfos()
+	assert.Equal(t, len(infos), 3, "expected all queues in the infos")
+	assert.Equal(t, infos[1].QueueName, "root.a", "infos should be sorted by queue")
+	assert.Equal(t, infos[1].FairShare, "[memory:30Mi vcore:1m]", "unexpected fair share in info")
+	assert.Equal(t, infos[1].Used, "[memory:10Mi]", "unexpected used in info")
+	assert.Equal(t, infos[1].UsageShare, 0.1, "unexpected usage share in info")
+	assert.Equal(t, calc.GetUsageShare("root.b"), 0.4, "unexpected usage share b")
+	assert.Equal(t, calc.GetUsageShare("root.unknown"), 0.0, "unknown queue should not have a usage share")
+}
+
+func TestFairShareCalculatorWeight(t *testing.T) {
+	total := resources.NewResourceFromMap(quantities{"memory": 100})
+	demand := resources.NewResourceFromMap(quantities{"memory": 100})
+	calc := NewFairShareCalculator(total)
+	assert.Assert(t, calc.AddQueue("root", "", nil, nil, nil, nil), "root queue should be added")
+	assert.Assert(t, calc.AddQueue("root.a", "root", nil, nil, demand, nil), "queue a should be added")
+	assert.Assert(t, calc.AddQueue("root.b", "root", nil, nil, demand, nil), "queue b should be added")
+	assert.Assert(t, calc.SetWeight("root.b", 3), "weight of b should be set")
+	assert.Assert(t, calc.SetWeight("root.a", 0), "weight that is not positive should be ignored")
+	assert.Assert(t, !calc.SetWeight("root.unknown", 2), "weight of unknown queue should not be set")
+	calc.Calculate()
+	// siblings without a guarantee share proportional to the weight
+	assert.Assert(t, resources.Equals(calc.GetFairShare("root.a"), resources.NewResourceFromMap(quantities{"memory": 25})), "unexpected fair share a: %v", calc.GetFairShare("root.a"))
+	assert.Assert(t, resources.Equals(calc.GetFairShare("root.b"), resources.NewResourceFromMap(quantities{"memory": 75})), "unexpected fair share b: %v", calc.GetFairShare("root.b"))
+
+	// siblings with a guarantee share proportional to the guarantee multiplied by the weight
+	calc = NewFairShareCalculator(total)
+	guaranteed := resources.NewResourceFromMap(quantities{"memory": 10})
+	assert.Assert(t, calc.AddQueue("root", "", nil, nil, nil, nil), "root queue should be added")
+	assert.Assert(t, calc.AddQueue("root.a", "root", guaranteed, nil, demand, nil), "queue a should be added")
+	assert.Assert(t, calc.AddQueue("root.b", "root", guaranteed, nil, demand, nil), "queue b should be added")
+	assert.Assert(t, calc.SetWeight("root.a", 4), "weight of a should be set")
+	calc.Calculate()
+	assert.Assert(t, resources.Equals(calc.GetFairShare("root.a"), resources.NewResourceFromMap(quantities{"memory": 80})), "unexpected fair share a: %v", calc.GetFairShare("root.a"))
+	assert.Assert(t, resources.Equals(calc.GetFairShare("root.b"), resources.NewResourceFromMap(quantities{"memory": 20})), "unexpected fair share b: %v", calc.GetFairShare("root.b"))
+}
+
+func TestFairShareCalculatorEmpty(t *testing.T) {
+	calc := NewFairShareCalculator(nil)
+	calc.Calculate()
+	assert.Equal(t, len(calc.GetFairShareInfos()), 0, "empty calculator should not have infos")
+
+	// no partition resources: all shares are zero
+	calc = NewFairShareCalculator(nil)
+	assert.Assert(t, calc.AddQueue("root", "", nil, nil, nil, nil), "root queue should be added")
+	assert.Assert(t, calc.AddQueue("root.a", "root", nil, nil, resources.NewResourceFromMap(quantities{"memory": 10}), nil), "queue a should be added")
+	calc.Calculate()
+	assert.Assert(t, resources.IsZero(calc.GetFairShare("root.a")), "fair share should be zero without resources")
+	assert.Equal(t, calc.GetNormalizedFairShare("root.a"), 0.0, "normalized fair share should be zero without resources")
+}
+
+func TestFairShareForQueues(t *testing.T) {
+	partition := createQueuesNodes(t)
+	total := resources.NewResourceFromMap(quantities{"first": 20})
+	cache.SetTotalPartitionResource(partition.partition, total)
+	leaf1 := partition.getQueue("root.parent.leaf1")
+	leaf2 := partition.getQueue("root.leaf2")
+	// leaf1 is using and asking for more than the partition, leaf2 asks for 5
+	leaf1.allocating = resources.NewResourceFromMap(quantities{"first": 10})
+	leaf1.incPendingResource(resources.NewResourceFromMap(quantities{"first": 20}))
+	leaf2.incPendingResource(resources.NewResourceFromMap(quantities{"first": 5}))
+
+	// not calculated yet: calculated on request and not cached
+	calc := partition.getFairShares()
+	assert.Assert(t, resources.Equals(calc.GetFairShare("root.leaf2"), resources.NewResourceFromMap(quantities{"first": 5})), "unexpected fair share leaf2")
+	assert.Assert(t, leaf1.getFairShare() == nil, "fair share should not be cached on the queue")
+
+	partition.updateFairShares()
+	assert.Assert(t, resources.Equals(leaf1.getFairShare(), resources.NewResourceFromMap(quantities{"first": 15})), "unexpected cached fair share leaf1: %v", leaf1.getFairShare())
+	assert.Assert(t, resources.Equals(leaf2.getFairShare(), resources.NewResourceFromMap(quantities{"first": 5})), "unexpected cached fair share leaf2: %v", leaf2.getFairShare())
+	assert.Equal(t, partition.getFairShares().GetNormalizedFairShare("root.parent.leaf1"), 0.75, "unexpected normalized fair share leaf1")
+
+	// sorting by fair share: leaf1 uses 10 of 15, leaf2 nothing of 5
+	queues := []*SchedulingQueue{leaf1, leaf2}
+	sortQueue(queues, FairSharePolicy)
+	assert.Equal(t, queues[0], leaf2, "queue furthest below the fair share should be first")
+	leaf2.allocating = resources.NewResourceFromMap(quantities{"first": 4})
+	sortQueue(queues, FairSharePolicy)
+	assert.Equal(t, queues[0], leaf1, "queue furthest below the fair share should be first")
+}
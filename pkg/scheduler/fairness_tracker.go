@@ -0,0 +1,109 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
+)
+
+// The sliding window over which the fairness debt of a queue is calculated.
+const fairnessWindow = 10 * time.Minute
+
+// One observation of a queue: the normalized fair share and the usage share at the start of a scheduling cycle.
+type fairnessSample struct {
+	time        time.Time
+	entitlement float64
+	usage       float64
+}
+
+// Track the fair share of the queues compared to the usage over time.
+// The fairness debt of a queue is the integral of the fair share minus the usage share over the window, in share
+// seconds. A positive debt means the queue used less than it was entitled to, a negative debt means it used more.
+// Each sample is assumed to hold until the next sample, the last sample until now.
+// The tracker is not locked: it is only used while holding the partition lock.
+type fairnessTracker struct {
+	window  time.Duration
+	samples map[string][]fairnessSample
+}
+
+func newFairnessTracker(window time.Duration) *fairnessTracker {
+	return &fairnessTracker{
+		window:  window,
+		samples: make(map[string][]fairnessSample),
+	}
+}
+
+// Record a sample for all queues in the calculator and update the queue metrics.
+// Queues that are no longer part of the calculator are removed from the tracker.
+func (ft *fairnessTracker) record(calc *FairShareCalculator, now time.Time) {
+	for queuePath := range ft.samples {
+		if calc.queues[queuePath] == nil {
+			delete(ft.samples, queuePath)
+		}
+	}
+	for queuePath := range calc.queues {
+		sample := fairnessSample{
+			time:        now,
+			entitlement: calc.GetNormalizedFairShare(queuePath),
+			usage:       calc.GetUsageShare(queuePath),
+		}
+		ft.samples[queuePath] = ft.removeExpired(append(ft.samples[queuePath], sample), now)
+		queueMetrics := metrics.GetQueueMetrics(queuePath)
+		queueMetrics.SetQueueFairShare(sample.entitlement)
+		queueMetrics.SetQueueUsageShare(sample.usage)
+		queueMetrics.SetQueueFairnessDebt(ft.getDebt(queuePath, now))
+	}
+}
+
+// Remove the samples that no longer cover any part of the window: the sample is followed by a sample that starts
+// before the window. Filters in place.
+func (ft *fairnessTracker) removeExpired(samples []fairnessSample, now time.Time) []fairnessSample {
+	start := now.Add(-ft.window)
+	expired := 0
+	for expired < len(samples)-1 && !samples[expired+1].time.After(start) {
+		expired++
+	}
+	if expired == 0 {
+		return samples
+	}
+	return append(samples[:0], samples[expired:]...)
+}
+
+// Get the fairness debt of the queue over the window ending at now, 0 if the queue is not tracked.
+func (ft *fairnessTracker) getDebt(queuePath string, now time.Time) float64 {
+	samples := ft.samples[queuePath]
+	start := now.Add(-ft.window)
+	debt := 0.0
+	for i, sample := range samples {
+		from := sample.time
+		if from.Before(start) {
+			from = start
+		}
+		to := now
+		if i+1 < len(samples) {
+			to = samples[i+1].time
+		}
+		if to.After(from) {
+			debt += (sample.entitlement - sample.usage) * to.Sub(from).Seconds()
+		}
+	}
+	return debt
+}
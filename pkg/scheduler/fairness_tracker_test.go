@@ -0,0 +1,68 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestFairnessTracker(t *testing.T) {
+	total := resources.NewResourceFromMap(quantities{"memory": 100})
+	half := resources.NewResourceFromMap(quantities{"memory": 50})
+	newCalc := func(usedA, usedB resources.Quantity) *FairShareCalculator {
+		calc := NewFairShareCalculator(total)
+		calc.AddQueue("root", "", nil, resources.NewResourceFromMap(quantities{"memory": usedA + usedB}), nil, nil)
+		calc.AddQueue("root.a", "root", nil, resources.NewResourceFromMap(quantities{"memory": usedA}), total, half)
+		calc.AddQueue("root.b", "root", nil, resources.NewResourceFromMap(quantities{"memory": usedB}), total, half)
+		calc.Calculate()
+		return calc
+	}
+	tracker := newFairnessTracker(time.Minute)
+	now := time.Now()
+	assert.Equal(t, tracker.getDebt("root.a", now), 0.0, "untracked queue should not have a debt")
+
+	// both queues are limited to and entitled to half: a uses 25% and b 75% for 30 seconds
+	tracker.record(newCalc(25, 75), now)
+	assert.Equal(t, tracker.getDebt("root.a", now), 0.0, "no time passed: no debt expected")
+	now = now.Add(30 * time.Second)
+	tracker.record(newCalc(50, 50), now)
+	assert.Equal(t, tracker.getDebt("root.a", now), 7.5, "unexpected debt for under served queue")
+	assert.Equal(t, tracker.getDebt("root.b", now), -7.5, "unexpected debt for over served queue")
+	assert.Equal(t, tracker.getDebt("root", now), 0.0, "root uses its whole entitlement")
+
+	// the first sample moves partially out of the window
+	now = now.Add(45 * time.Second)
+	assert.Equal(t, tracker.getDebt("root.a", now), 3.75, "unexpected debt after partial expiry")
+	// a new sample removes the expired samples
+	tracker.record(newCalc(50, 50), now.Add(time.Minute))
+	assert.Equal(t, len(tracker.samples["root.a"]), 2, "expired sample should have been removed")
+	assert.Equal(t, tracker.getDebt("root.a", now.Add(time.Minute)), 0.0, "debt should have left the window")
+
+	// removed queues are no longer tracked
+	calc := NewFairShareCalculator(total)
+	calc.AddQueue("root", "", nil, nil, nil, nil)
+	calc.Calculate()
+	tracker.record(calc, now.Add(2*time.Minute))
+	assert.Equal(t, len(tracker.samples), 1, "removed queues should not be tracked")
+}
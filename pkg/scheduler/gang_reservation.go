@@ -0,0 +1,161 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+// The ask tag that makes the repeats of the ask the members of a gang.
+// When a part of the gang is placed, headroom for the members that are not placed yet is held on the nodes the member
+// fits on. Other asks cannot use the held headroom. A hold is not a reservation: it does not block the node for other
+// asks that fit next to it and it only lasts a short time, it is refreshed each time a member is placed.
+// The value is "true" to hold the headroom for the default time, or the hold time as a duration, for example "30s".
+const GangAskTag = "gang.reservation"
+
+// The time the headroom for the members of a gang is held after a member is placed, if not set in the ask tag.
+const defaultGangHoldTime = 10 * time.Second
+
+// Headroom held on a node for the members of a gang that are not placed yet.
+type gangHold struct {
+	resource *resources.Resource // the resource of one member
+	members  int32               // the number of members the headroom is held for
+	expiry   time.Time           // the hold is ignored after the expiry
+}
+
+// Get the headroom held, nil if the hold has no members.
+func (gh *gangHold) getHeld() *resources.Resource {
+	return resources.Multiply(gh.resource, int64(gh.members))
+}
+
+// Parse the hold time from the gang ask tag value, returns 0 if the value is empty: the ask is not a gang.
+func parseGangHoldTime(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	if strings.EqualFold(value, "true") {
+		return defaultGangHoldTime, nil
+	}
+	holdTime, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid gang hold time '%s': %v", value, err)
+	}
+	if holdTime <= 0 {
+		return 0, fmt.Errorf("gang hold time must be positive: '%s'", value)
+	}
+	return holdTime, nil
+}
+
+// Get the headroom held on the node for gangs other than the one with the key, nil if nothing is held.
+// Expired holds are ignored.
+func (sn *SchedulingNode) getGangHeld(key string, now time.Time) *resources.Resource {
+	sn.RLock()
+	defer sn.RUnlock()
+	var held *resources.Resource
+	for holdKey, hold := range sn.gangHolds {
+		if holdKey == key || !now.Before(hold.expiry) {
+			continue
+		}
+		held = resources.Add(held, hold.getHeld())
+	}
+	return held
+}
+
+// Get the number of members the headroom is held for on the node for the gang with the key, 0 if the hold expired.
+func (sn *SchedulingNode) getGangHeldMembers(key string, now time.Time) int32 {
+	sn.RLock()
+	defer sn.RUnlock()
+	if hold, ok := sn.gangHolds[key]; ok && now.Before(hold.expiry) {
+		return hold.members
+	}
+	return 0
+}
+
+// Hold the headroom for the number of members of the gang with the key on the node, this replaces an existing hold.
+func (sn *SchedulingNode) holdGang(key string, member *resources.Resource, members int32, expiry time.Time) {
+	sn.Lock()
+	defer sn.Unlock()
+	if members <= 0 {
+		delete(sn.gangHolds, key)
+		return
+	}
+	sn.gangHolds[key] = &gangHold{
+		resource: member,
+		members:  members,
+		expiry:   expiry,
+	}
+}
+
+// Remove the holds that have expired from the node. Returns the number of holds removed.
+func (sn *SchedulingNode) removeExpiredGangHolds(now time.Time) int {
+	sn.Lock()
+	defer sn.Unlock()
+	removed := 0
+	for key, hold := range sn.gangHolds {
+		if !now.Before(hold.expiry) {
+			delete(sn.gangHolds, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Hold the headroom for the members of the gang that are not placed yet after a member of the gang is placed.
+// All holds of the gang are replaced: the members are held on the nodes the member fits on, in node order, on top of
+// the headroom held for other gangs. All holds are removed when the last member is placed.
+// Lock free call, must be called holding the partition lock.
+func (psc *partitionSchedulingContext) holdGangHeadroom(app *SchedulingApplication, ask *schedulingAllocationAsk, now time.Time) {
+	key := reservationKey(nil, app, ask)
+	remaining := ask.getPendingAskRepeat()
+	nodeIDs := make([]string, 0, len(psc.nodes))
+	for nodeID := range psc.nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	expiry := now.Add(ask.gangHoldTime)
+	held := int32(0)
+	for _, nodeID := range nodeIDs {
+		node := psc.nodes[nodeID]
+		members := int32(0)
+		if held < remaining && node.nodeInfo.IsSchedulable() && ask.toleratesNode(node) {
+			available := node.getAvailableResource().Clone()
+			available.SubFrom(node.getGangHeld(key, now))
+			for held+members < remaining && resources.FitIn(available, ask.AllocatedResource) {
+				available.SubFrom(ask.AllocatedResource)
+				members++
+			}
+		}
+		node.holdGang(key, ask.AllocatedResource, members, expiry)
+		held += members
+	}
+	log.Logger().Debug("gang headroom held",
+		zap.String("appID", app.ApplicationInfo.ApplicationID),
+		zap.String("allocationKey", ask.AskProto.AllocationKey),
+		zap.Int32("remaining", remaining),
+		zap.Int32("held", held))
+}
@@ -0,0 +1,114 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestParseGangHoldTime(t *testing.T) {
+	for value, expected := range map[string]time.Duration{"": 0, "true": defaultGangHoldTime, " TRUE ": defaultGangHoldTime, "30s": 30 * time.Second} {
+		holdTime, err := parseGangHoldTime(value)
+		assert.NilError(t, err, "unexpected error for '%s'", value)
+		assert.Equal(t, holdTime, expected, "unexpected hold time for '%s'", value)
+	}
+	for _, value := range []string{"false", "never", "0s", "-1m"} {
+		_, err := parseGangHoldTime(value)
+		assert.Assert(t, err != nil, "invalid hold time '%s' should have failed", value)
+	}
+}
+
+func TestNodeGangHolds(t *testing.T) {
+	node := newNode("node-1", map[string]resources.Quantity{"first": 10})
+	member := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 3})
+	now := time.Now()
+	node.holdGang("app-1|gang", member, 2, now.Add(time.Second))
+	assert.Equal(t, node.getGangHeldMembers("app-1|gang", now), int32(2), "unexpected held members")
+	assert.Assert(t, node.getGangHeld("app-1|gang", now) == nil, "the own hold should not be counted")
+	assert.Assert(t, resources.Equals(node.getGangHeld("app-2|other", now), resources.Multiply(member, 2)), "unexpected held headroom")
+
+	// the held headroom cannot be used by other asks, it can be used by the gang
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	assert.Assert(t, node.preAllocateCheck(res, "app-2|other", false) != nil, "held headroom should not be used")
+	assert.NilError(t, node.preAllocateCheck(res, "app-1|gang", false), "gang should use its held headroom")
+
+	// expired holds are ignored and removed
+	later := now.Add(2 * time.Second)
+	assert.Equal(t, node.getGangHeldMembers("app-1|gang", later), int32(0), "expired hold should be ignored")
+	assert.Assert(t, node.getGangHeld("app-2|other", later) == nil, "expired hold should be ignored")
+	assert.Equal(t, node.removeExpiredGangHolds(now), 0, "hold should not have expired")
+	assert.Equal(t, node.removeExpiredGangHolds(later), 1, "expired hold should have been removed")
+	node.holdGang("app-1|gang", member, 0, later)
+	assert.Equal(t, len(node.gangHolds), 0, "hold without members should not be stored")
+}
+
+func TestTryAllocateGang(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	gangApp := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: "app-1"})
+	gangApp.queue = leaf
+	leaf.addSchedulingApplication(gangApp)
+	partition.applications["app-1"] = gangApp
+	member := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 4})
+	gang := newAllocationAskRepeat("gang", "app-1", member, 3)
+	gang.AskProto.Tags = map[string]string{GangAskTag: "1m"}
+	assert.NilError(t, gang.parseGang(), "failed to parse gang")
+	_, err := gangApp.addAllocationAsk(gang)
+	assert.NilError(t, err, "failed to add ask to app")
+
+	// the first member is placed: headroom is held for the two other members
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("first gang member did not return any allocation")
+	}
+	assert.Assert(t, partition.allocate(alloc), "first gang member should have been allocated")
+	key := reservationKey(nil, gangApp, gang)
+	now := time.Now()
+	held := partition.nodes["node-1"].getGangHeldMembers(key, now) + partition.nodes["node-2"].getGangHeldMembers(key, now)
+	assert.Equal(t, held, int32(2), "headroom should be held for the members not placed")
+
+	// an unrelated ask cannot use the held headroom: 4 is held on each node
+	large := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 7})
+	for nodeID, node := range partition.nodes {
+		assert.Assert(t, node.preAllocateCheck(large, "app-2|other", false) != nil, "unrelated ask should not use the headroom held on %s", nodeID)
+	}
+	// the first member can be placed on either node: the other node is still empty
+	freeNode := "node-1"
+	if alloc.nodeID == freeNode {
+		freeNode = "node-2"
+	}
+	assert.Assert(t, resources.FitIn(partition.nodes[freeNode].getAvailableResource(), large), "ask should fit without the held headroom on %s", freeNode)
+
+	// the gang members use the held headroom, the holds are removed when the last member is placed
+	for i := 0; i < 2; i++ {
+		alloc = partition.tryAllocate()
+		if alloc == nil {
+			t.Fatalf("gang member %d did not return any allocation", i+2)
+		}
+		assert.Equal(t, alloc.schedulingAsk.AskProto.AllocationKey, "gang", "gang member should have been allocated")
+		assert.Assert(t, partition.allocate(alloc), "gang member should have been allocated")
+	}
+	assert.Equal(t, len(partition.nodes["node-1"].gangHolds)+len(partition.nodes["node-2"].gangHolds), 0, "holds should have been removed")
+}
@@ -0,0 +1,184 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"math"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+const (
+	// How often a new load sample is taken, the scheduling cycle takes the samples
+	loadSampleInterval = time.Second
+	// Weight of a new sample in the smoothed load index, the remainder is the weight of the history
+	loadSmoothing = 0.2
+	// Weights of the load components in a sample, adding up to 1
+	loadUtilizationWeight = 0.4
+	loadBacklogWeight     = 0.3
+	loadFailureWeight     = 0.3
+	// The reservation delay is reduced to this fraction of the configured delay at the maximum load
+	minReservationDelayFactor = 0.25
+)
+
+// A smoothed load index for a partition between 0 (idle) and 1 (overloaded).
+// Each sample combines the utilization of the partition, the pending backlog compared to the partition size and the
+// ratio of failed allocation attempts since the previous sample. The index is an exponential moving average of the
+// samples: a short spike does not change the scheduling behaviour, a sustained load does.
+// The scheduler uses the index to scale the number of allocations per cycle down and to reserve sooner when loaded.
+type loadTracker struct {
+	index       float64
+	utilization float64 // components of the last sample
+	backlog     float64
+	failure     float64
+	attempts    int // allocation attempts since the last sample
+	failures    int // failed allocation attempts since the last sample
+	lastSample  time.Time
+
+	locking.RWMutex
+}
+
+func newLoadTracker() *loadTracker {
+	return &loadTracker{}
+}
+
+// Record the result of an allocation attempt. A failure is an attempt that did not result in an allocation while
+// there was pending demand, or an allocation that was rolled back.
+func (lt *loadTracker) recordAttempt(failed bool) {
+	lt.Lock()
+	defer lt.Unlock()
+	lt.attempts++
+	if failed {
+		lt.failures++
+	}
+}
+
+// Record failures for allocations that were proposed and counted as an attempt already.
+func (lt *loadTracker) recordFailures(count int) {
+	lt.Lock()
+	defer lt.Unlock()
+	lt.failures += count
+}
+
+// Take a sample if the sample interval has passed and update the index.
+// The resources are the allocated and pending resources of the partition compared to the total.
+func (lt *loadTracker) sample(now time.Time, allocated, pending, total *resources.Resource) {
+	lt.Lock()
+	defer lt.Unlock()
+	if !lt.lastSample.IsZero() && now.Sub(lt.lastSample) < loadSampleInterval {
+		return
+	}
+	lt.utilization = getLoadShare(allocated, total)
+	lt.backlog = getLoadShare(pending, total)
+	lt.failure = 0
+	if lt.attempts > 0 {
+		lt.failure = math.Min(float64(lt.failures)/float64(lt.attempts), 1)
+	}
+	sample := loadUtilizationWeight*lt.utilization + loadBacklogWeight*lt.backlog + loadFailureWeight*lt.failure
+	if lt.lastSample.IsZero() {
+		lt.index = sample
+	} else {
+		lt.index = loadSmoothing*sample + (1-loadSmoothing)*lt.index
+	}
+	lt.attempts = 0
+	lt.failures = 0
+	lt.lastSample = now
+}
+
+// Get the smoothed load index.
+func (lt *loadTracker) getIndex() float64 {
+	lt.RLock()
+	defer lt.RUnlock()
+	return lt.index
+}
+
+// Get the number of allocations to make in a cycle for the current load: the maximum when idle scaled down to 1
+// when overloaded. Fewer allocations per cycle when loaded means the cycle reacts sooner to rejected allocations and
+// released resources.
+func (lt *loadTracker) getBatchSize(maxAllocs int) int {
+	size := int(math.Round(float64(maxAllocs) * (1 - lt.getIndex())))
+	if size < 1 {
+		return 1
+	}
+	return size
+}
+
+// Get the delay before an ask may reserve a node for the current load: the configured delay when idle down to a
+// quarter of the delay when overloaded. A loaded cluster has few nodes with room for large asks, reserving sooner
+// prevents the large asks from starving.
+func (lt *loadTracker) getReservationDelay(delay time.Duration) time.Duration {
+	factor := 1 - (1-minReservationDelayFactor)*lt.getIndex()
+	return time.Duration(float64(delay) * factor)
+}
+
+// Get the load index and the components of the last sample.
+func (lt *loadTracker) getLoadInfo() *dao.LoadIndexDAOInfo {
+	lt.RLock()
+	defer lt.RUnlock()
+	info := &dao.LoadIndexDAOInfo{
+		LoadIndex:   lt.index,
+		Utilization: lt.utilization,
+		Backlog:     lt.backlog,
+		FailureRate: lt.failure,
+	}
+	if !lt.lastSample.IsZero() {
+		info.LastSample = lt.lastSample.UnixNano()
+	}
+	return info
+}
+
+// Take a load sample for the partition if the sample interval has passed.
+// Lock free call, the queue and partition objects are locked when the resources are retrieved.
+func (psc *partitionSchedulingContext) sampleLoad(now time.Time) {
+	allocated := resources.Add(psc.root.GetAllocatedResource(), psc.root.getAllocatingResource())
+	psc.load.sample(now, allocated, psc.root.GetPendingResource(), psc.partition.GetTotalPartitionResource())
+}
+
+// Get the delay before an ask may reserve a node in the partition for the current load.
+func (psc *partitionSchedulingContext) getReservationDelay() time.Duration {
+	return psc.load.getReservationDelay(reservationDelay)
+}
+
+// Get the load index of the partition with the scheduling settings derived from it.
+func (psc *partitionSchedulingContext) getLoadInfo() *dao.LoadIndexDAOInfo {
+	info := psc.load.getLoadInfo()
+	info.Partition = psc.Name
+	info.BatchSize = psc.load.getBatchSize(maxAllocationsPerCycle)
+	info.ReservationDelay = psc.getReservationDelay().String()
+	return info
+}
+
+// Get the largest share of the resource compared to the total, capped at 1.
+// Only the resource types in the total are considered, 0 if the total is not set.
+func getLoadShare(res, total *resources.Resource) float64 {
+	if res == nil || total == nil {
+		return 0
+	}
+	share := 0.0
+	for name, quantity := range total.Resources {
+		if quantity <= 0 {
+			continue
+		}
+		share = math.Max(share, float64(res.Resources[name])/float64(quantity))
+	}
+	return math.Min(share, 1)
+}
@@ -0,0 +1,92 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestGetLoadShare(t *testing.T) {
+	total := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 100, "vcore": 10})
+	assert.Equal(t, getLoadShare(nil, total), 0.0, "nil resource should have no share")
+	assert.Equal(t, getLoadShare(total, nil), 0.0, "nil total should have no share")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 50, "vcore": 2})
+	assert.Equal(t, getLoadShare(res, total), 0.5, "largest share not returned")
+	res = resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 500, "gpu": 5})
+	assert.Equal(t, getLoadShare(res, total), 1.0, "share should be capped at 1")
+	res = resources.NewResourceFromMap(map[string]resources.Quantity{"gpu": 5})
+	assert.Equal(t, getLoadShare(res, total), 0.0, "types not in the total should be ignored")
+}
+
+func TestLoadTrackerSample(t *testing.T) {
+	lt := newLoadTracker()
+	assert.Equal(t, lt.getIndex(), 0.0, "new tracker should be idle")
+	assert.Equal(t, lt.getLoadInfo().LastSample, int64(0), "new tracker should not have a sample")
+	total := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 100})
+	allocated := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 100})
+	pending := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 50})
+
+	// first sample sets the index directly: half of the attempts failed
+	now := time.Now()
+	lt.recordAttempt(false)
+	lt.recordAttempt(true)
+	lt.sample(now, allocated, pending, total)
+	expected := loadUtilizationWeight + loadBacklogWeight*0.5 + loadFailureWeight*0.5
+	assert.Assert(t, math.Abs(lt.getIndex()-expected) < 1e-9, "unexpected first index %f", lt.getIndex())
+	info := lt.getLoadInfo()
+	assert.Equal(t, info.Utilization, 1.0, "unexpected utilization")
+	assert.Equal(t, info.Backlog, 0.5, "unexpected backlog")
+	assert.Equal(t, info.FailureRate, 0.5, "unexpected failure rate")
+
+	// sample within the interval is ignored
+	lt.sample(now.Add(loadSampleInterval/2), nil, nil, total)
+	assert.Assert(t, math.Abs(lt.getIndex()-expected) < 1e-9, "sample within interval changed index")
+
+	// idle sample only moves the index by the smoothing factor
+	lt.sample(now.Add(loadSampleInterval), nil, nil, total)
+	expected *= 1 - loadSmoothing
+	assert.Assert(t, math.Abs(lt.getIndex()-expected) < 1e-9, "unexpected smoothed index %f", lt.getIndex())
+	assert.Equal(t, lt.getLoadInfo().FailureRate, 0.0, "attempts should be reset after a sample")
+
+	// failures are capped at the number of attempts
+	lt.recordAttempt(false)
+	lt.recordFailures(3)
+	lt.sample(now.Add(2*loadSampleInterval), nil, nil, total)
+	assert.Equal(t, lt.getLoadInfo().FailureRate, 1.0, "failure rate should be capped at 1")
+}
+
+func TestLoadTrackerSettings(t *testing.T) {
+	lt := newLoadTracker()
+	assert.Equal(t, lt.getBatchSize(16), 16, "idle partition should use the full batch")
+	assert.Equal(t, lt.getReservationDelay(2*time.Second), 2*time.Second, "idle partition should use the full delay")
+
+	lt.index = 0.5
+	assert.Equal(t, lt.getBatchSize(16), 8, "half loaded partition should use half the batch")
+	assert.Equal(t, lt.getReservationDelay(2*time.Second), 1250*time.Millisecond, "unexpected delay at half load")
+
+	lt.index = 1
+	assert.Equal(t, lt.getBatchSize(16), 1, "overloaded partition should allocate at least one")
+	assert.Equal(t, lt.getReservationDelay(2*time.Second), 500*time.Millisecond, "unexpected minimum delay")
+}
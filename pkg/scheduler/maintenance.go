@@ -0,0 +1,43 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// Limit the headroom by the capacity reserved for maintenance of the partition. The partition total resource minus
+// the reserved capacity and the resources used by all queues is left for new allocations.
+// Allocations that already use the reserved capacity are not preempted: the headroom is negative until they finish.
+// The headroom is returned as is when no capacity is reserved.
+// Lock free call this all locks are taken when needed in called functions
+func (psc *partitionSchedulingContext) getMaintenanceHeadRoom(headRoom *resources.Resource) *resources.Resource {
+	reserved := psc.partition.GetMaintenanceReservation(time.Now())
+	if reserved == nil {
+		return headRoom
+	}
+	available := resources.Sub(psc.partition.GetTotalPartitionResource(), reserved)
+	available.SubFrom(psc.root.getAssumeAllocated())
+	if headRoom == nil {
+		return available
+	}
+	return resources.ComponentWiseMin(headRoom, available)
+}
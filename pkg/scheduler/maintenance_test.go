@@ -0,0 +1,66 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+func TestMaintenanceHeadRoom(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(partition.root, "leaf", false, map[string]string{"first": "100"})
+	assert.NilError(t, err, "failed to create leaf queue")
+	cache.SetTotalPartitionResource(partition.partition, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100}))
+	partition.addSchedulingNode(cache.NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100})))
+	used := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 50})
+	err = leaf.QueueInfo.IncAllocatedResource(used, false)
+	assert.NilError(t, err, "failed to allocate on the leaf queue")
+
+	// no maintenance: the headroom is not changed
+	assert.Assert(t, partition.getMaintenanceHeadRoom(nil) == nil, "headroom should not be limited without maintenance")
+
+	// 40% reserved: 100 - 40 - 50 used leaves 10
+	cache.SetMaintenance(partition.partition, time.Now().Add(-time.Minute), time.Hour, 40, 0)
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	assert.Assert(t, resources.Equals(partition.getMaintenanceHeadRoom(nil), expected), "unexpected maintenance headroom")
+	headRoom := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	assert.Assert(t, resources.Equals(partition.getMaintenanceHeadRoom(headRoom), headRoom), "queue headroom should be the smallest")
+
+	// allocations that do not fit in the capacity left are not made
+	app := newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", leaf.Name, security.UserGroup{}, nil))
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	_, err = app.addAllocationAsk(newAllocationAsk("alloc-1", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20})))
+	assert.NilError(t, err, "failed to add ask to app")
+	assert.Assert(t, leaf.tryAllocate(partition) == nil, "allocation in the reserved capacity should not be made")
+
+	// maintenance in the future outside the lead time: the allocation is made
+	cache.SetMaintenance(partition.partition, time.Now().Add(2*time.Hour), time.Hour, 40, time.Hour)
+	assert.Assert(t, partition.getMaintenanceHeadRoom(nil) == nil, "nothing should be reserved before the lead time")
+	assert.Assert(t, leaf.tryAllocate(partition) != nil, "allocation should be made before the lead time")
+}
@@ -0,0 +1,101 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+// Index of the nodes of a partition by attribute key and value. Matching a node selector against the index only
+// looks at the nodes that have the least common key value pair of the selector instead of at all nodes.
+// The attributes of a node do not change after the node is added: the index is updated when a node is added or
+// removed. The index is not locked, it is protected by the lock of the partition.
+type nodeAttributeIndex struct {
+	nodes map[string]map[string]map[string]*SchedulingNode // key -> value -> node ID -> node
+}
+
+func newNodeAttributeIndex() *nodeAttributeIndex {
+	return &nodeAttributeIndex{
+		nodes: make(map[string]map[string]map[string]*SchedulingNode),
+	}
+}
+
+// Add the node under each of its attributes.
+func (nai *nodeAttributeIndex) addNode(node *SchedulingNode) {
+	node.nodeInfo.RangeAttributes(func(key, value string) {
+		values := nai.nodes[key]
+		if values == nil {
+			values = make(map[string]map[string]*SchedulingNode)
+			nai.nodes[key] = values
+		}
+		nodes := values[value]
+		if nodes == nil {
+			nodes = make(map[string]*SchedulingNode)
+			values[value] = nodes
+		}
+		nodes[node.NodeID] = node
+	})
+}
+
+// Remove the node from the index, empty entries are cleaned up.
+func (nai *nodeAttributeIndex) removeNode(node *SchedulingNode) {
+	node.nodeInfo.RangeAttributes(func(key, value string) {
+		values := nai.nodes[key]
+		if values == nil {
+			return
+		}
+		delete(values[value], node.NodeID)
+		if len(values[value]) == 0 {
+			delete(values, value)
+		}
+		if len(values) == 0 {
+			delete(nai.nodes, key)
+		}
+	})
+}
+
+// Get the nodes that have all the key value pairs of the selector. The nodes of the smallest set in the index are
+// checked against the rest of the selector. Returns nil if no node matches, the selector must not be empty.
+func (nai *nodeAttributeIndex) match(selector map[string]string) []*SchedulingNode {
+	var smallest map[string]*SchedulingNode
+	var smallestKey string
+	for key, value := range selector {
+		nodes := nai.nodes[key][value]
+		if len(nodes) == 0 {
+			return nil
+		}
+		if smallest == nil || len(nodes) < len(smallest) {
+			smallest = nodes
+			smallestKey = key
+		}
+	}
+	var matched []*SchedulingNode
+	for _, node := range smallest {
+		if matchesSelector(node, selector, smallestKey) {
+			matched = append(matched, node)
+		}
+	}
+	return matched
+}
+
+// Check if the node has the key value pairs of the selector, the skipped key is known to match.
+func matchesSelector(node *SchedulingNode, selector map[string]string, skip string) bool {
+	for key, value := range selector {
+		if key != skip && node.nodeInfo.GetAttribute(key) != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func newAttributeNode(nodeID string, attributes map[string]string) *SchedulingNode {
+	return newSchedulingNode(cache.NewNodeInfo(&si.NewNodeInfo{
+		NodeID:     nodeID,
+		Attributes: attributes,
+	}))
+}
+
+func getNodeIDs(nodes []*SchedulingNode) []string {
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.NodeID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestNodeAttributeIndex(t *testing.T) {
+	index := newNodeAttributeIndex()
+	node1 := newAttributeNode("node-1", map[string]string{"zone": "a", "gpu": "true"})
+	node2 := newAttributeNode("node-2", map[string]string{"zone": "a"})
+	node3 := newAttributeNode("node-3", map[string]string{"zone": "b", "gpu": "true"})
+	index.addNode(node1)
+	index.addNode(node2)
+	index.addNode(node3)
+
+	assert.DeepEqual(t, getNodeIDs(index.match(map[string]string{"zone": "a"})), []string{"node-1", "node-2"})
+	assert.DeepEqual(t, getNodeIDs(index.match(map[string]string{"gpu": "true"})), []string{"node-1", "node-3"})
+	assert.DeepEqual(t, getNodeIDs(index.match(map[string]string{"zone": "a", "gpu": "true"})), []string{"node-1"})
+	assert.Equal(t, len(index.match(map[string]string{"zone": "c"})), 0, "unknown value should not match")
+	assert.Equal(t, len(index.match(map[string]string{"rack": "a"})), 0, "unknown key should not match")
+	assert.Equal(t, len(index.match(map[string]string{"zone": "b", "gpu": "false"})), 0, "partial match should not match")
+
+	// removal cleans up the empty entries
+	index.removeNode(node3)
+	assert.DeepEqual(t, getNodeIDs(index.match(map[string]string{"gpu": "true"})), []string{"node-1"})
+	_, ok := index.nodes["zone"]["b"]
+	assert.Assert(t, !ok, "empty value should have been removed")
+	index.removeNode(node1)
+	index.removeNode(node2)
+	assert.Equal(t, len(index.nodes), 0, "empty index expected")
+	// removing a node that is not indexed is a noop
+	index.removeNode(node1)
+}
+
+func TestGetSchedulingNodesWithSelector(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	partition.addSchedulingNode(cache.NewNodeInfo(&si.NewNodeInfo{NodeID: "node-1", Attributes: map[string]string{"zone": "a"}}))
+	partition.addSchedulingNode(cache.NewNodeInfo(&si.NewNodeInfo{NodeID: "node-2", Attributes: map[string]string{"zone": "a"}}))
+	partition.addSchedulingNode(cache.NewNodeInfo(&si.NewNodeInfo{NodeID: "node-3", Attributes: map[string]string{"zone": "b"}}))
+
+	nodes, unmatched := partition.getSchedulingNodesWithSelector(nil, false)
+	assert.Equal(t, len(nodes), 3, "empty selector should return all nodes")
+	assert.Equal(t, unmatched, 0, "empty selector should match all nodes")
+	nodes, unmatched = partition.getSchedulingNodesWithSelector(map[string]string{"zone": "a"}, false)
+	assert.DeepEqual(t, getNodeIDs(nodes), []string{"node-1", "node-2"})
+	assert.Equal(t, unmatched, 1, "node in zone b should not match")
+
+	// unschedulable nodes match but are not returned
+	partition.nodes["node-1"].nodeInfo.SetSchedulable(false)
+	nodes, unmatched = partition.getSchedulingNodesWithSelector(map[string]string{"zone": "a"}, false)
+	assert.DeepEqual(t, getNodeIDs(nodes), []string{"node-2"})
+	assert.Equal(t, unmatched, 1, "unschedulable node should not be counted as unmatched")
+
+	// removed and replaced nodes are updated in the index
+	partition.removeSchedulingNode("node-2")
+	partition.addSchedulingNode(cache.NewNodeInfo(&si.NewNodeInfo{NodeID: "node-3", Attributes: map[string]string{"zone": "a"}}))
+	nodes, unmatched = partition.getSchedulingNodesWithSelector(map[string]string{"zone": "a"}, false)
+	assert.DeepEqual(t, getNodeIDs(nodes), []string{"node-3"})
+	assert.Equal(t, unmatched, 0, "all nodes should match")
+	nodes, _ = partition.getSchedulingNodesWithSelector(map[string]string{"zone": "b"}, false)
+	assert.Equal(t, len(nodes), 0, "replaced node should not be in its old zone")
+}
+
+// 5000 nodes with 20 attributes each: 10 zones, 100 racks, an instance type and 17 labels shared by all nodes.
+func newBenchmarkIndex() (*nodeAttributeIndex, []*SchedulingNode) {
+	const numNodes = 5000
+	index := newNodeAttributeIndex()
+	nodes := make([]*SchedulingNode, numNodes)
+	for i := 0; i < numNodes; i++ {
+		attributes := map[string]string{
+			"zone":          "zone-" + strconv.Itoa(i%10),
+			"rack":          "rack-" + strconv.Itoa(i%100),
+			"instance-type": "type-" + strconv.Itoa(i%7),
+		}
+		for l := 0; l < 17; l++ {
+			attributes["label-"+strconv.Itoa(l)] = "value"
+		}
+		nodes[i] = newAttributeNode("node-"+strconv.Itoa(i), attributes)
+		index.addNode(nodes[i])
+	}
+	return index, nodes
+}
+
+var benchmarkSelector = map[string]string{"zone": "zone-3", "rack": "rack-13", "label-5": "value"}
+
+func BenchmarkNodeAttributeIndexMatch(b *testing.B) {
+	index, _ := newBenchmarkIndex()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(index.match(benchmarkSelector)) != 50 {
+			b.Fatal("unexpected number of matched nodes")
+		}
+	}
+}
+
+func BenchmarkNodeAttributeScan(b *testing.B) {
+	_, nodes := newBenchmarkIndex()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var matched []*SchedulingNode
+		for _, node := range nodes {
+			if matchesSelector(node, benchmarkSelector, "") {
+				matched = append(matched, node)
+			}
+		}
+		if len(matched) != 50 {
+			b.Fatal("unexpected number of matched nodes")
+		}
+	}
+}
@@ -119,3 +119,57 @@ func (ri *RoundRobinNodeIterator) Reset() {
 	ri.countIdx = 0
 	ri.startIdx = -1
 }
+
+// Preferred iterator, wraps another iterator.
+// Returns the preferred node first followed by the nodes of the wrapped iterator, the preferred node is not returned
+// a second time.
+type preferredNodeIterator struct {
+	NodeIterator
+	preferred *SchedulingNode
+	next      *SchedulingNode
+	done      bool
+}
+
+func newPreferredNodeIterator(preferred *SchedulingNode, iterator NodeIterator) *preferredNodeIterator {
+	return &preferredNodeIterator{
+		NodeIterator: iterator,
+		preferred:    preferred,
+	}
+}
+
+// HasNext returns true if the preferred node has not been returned or the wrapped iterator has a node left that is
+// not the preferred node.
+func (pi *preferredNodeIterator) HasNext() bool {
+	if !pi.done || pi.next != nil {
+		return true
+	}
+	for pi.NodeIterator.HasNext() {
+		if node := pi.NodeIterator.Next(); node != pi.preferred {
+			pi.next = node
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the preferred node on the first call and the next node of the wrapped iterator after that.
+// Returns nil at the end of iteration.
+func (pi *preferredNodeIterator) Next() *SchedulingNode {
+	if !pi.done {
+		pi.done = true
+		return pi.preferred
+	}
+	if !pi.HasNext() {
+		return nil
+	}
+	node := pi.next
+	pi.next = nil
+	return node
+}
+
+// Reset the iterator to return the preferred node first again.
+func (pi *preferredNodeIterator) Reset() {
+	pi.NodeIterator.Reset()
+	pi.done = false
+	pi.next = nil
+}
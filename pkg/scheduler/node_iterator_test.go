@@ -22,6 +22,8 @@ import (
 	"strconv"
 	"testing"
 
+	"gotest.tools/assert"
+
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 )
 
@@ -189,3 +191,27 @@ func newSchedNodeList(number int) []*SchedulingNode {
 	}
 	return list
 }
+
+func TestPreferredNodeIterator(t *testing.T) {
+	nodes := newSchedNodeList(3)
+	pni := newPreferredNodeIterator(nodes[1], NewDefaultNodeIterator(nodes))
+	expected := []*SchedulingNode{nodes[1], nodes[0], nodes[2]}
+	for i := 0; i < 2; i++ {
+		for _, node := range expected {
+			assert.Assert(t, pni.HasNext(), "iterator should have a next node")
+			assert.Equal(t, pni.Next(), node, "unexpected node returned")
+		}
+		assert.Assert(t, !pni.HasNext(), "preferred node should not be returned twice")
+		assert.Assert(t, pni.Next() == nil, "iterator at the end should return nil")
+		pni.Reset()
+	}
+
+	// preferred node not in the list
+	pni = newPreferredNodeIterator(nodes[0], NewDefaultNodeIterator(nodes[1:]))
+	count := 0
+	for pni.HasNext() {
+		pni.Next()
+		count++
+	}
+	assert.Equal(t, count, 3, "all nodes should have been returned")
+}
@@ -0,0 +1,146 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+// The ask tag with the node taints the ask tolerates.
+// The value is a comma separated list of tolerations: "key=value:effect", "key:effect", "key=value" or "key".
+// A toleration without a value tolerates the taint with any value, a toleration without an effect tolerates the taint
+// with any effect. The toleration "*" tolerates all taints.
+const TolerationsAskTag = "node.tolerations"
+
+// The effects of a node taint. A taint with the PreferNoSchedule effect does not filter nodes.
+const (
+	TaintEffectNoSchedule       = "NoSchedule"
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+	TaintEffectNoExecute        = "NoExecute"
+)
+
+const tolerateAll = "*"
+
+// A taint of a node, an ask can only be allocated on the node if it tolerates the taint.
+type nodeTaint struct {
+	key    string
+	value  string
+	effect string
+}
+
+// A toleration of an ask, an empty value or effect matches any value or effect of the taint.
+type taintToleration struct {
+	key    string
+	value  string
+	effect string
+}
+
+// Split an entry of the form "key=value:effect" into its parts, the value and effect are optional.
+func splitTaint(entry string) (key, value, effect string) {
+	if idx := strings.LastIndex(entry, ":"); idx != -1 {
+		effect = strings.TrimSpace(entry[idx+1:])
+		entry = entry[:idx]
+	}
+	if idx := strings.Index(entry, "="); idx != -1 {
+		value = strings.TrimSpace(entry[idx+1:])
+		entry = entry[:idx]
+	}
+	return strings.TrimSpace(entry), value, effect
+}
+
+func isTaintEffect(effect string) bool {
+	return effect == TaintEffectNoSchedule || effect == TaintEffectPreferNoSchedule || effect == TaintEffectNoExecute
+}
+
+// Parse the taints from the node attribute. Taints that do not filter nodes are not returned.
+// A taint without an effect is a NoSchedule taint. Invalid taints are logged and ignored.
+func parseNodeTaints(nodeID, value string) []nodeTaint {
+	var taints []nodeTaint
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, taintValue, effect := splitTaint(entry)
+		if effect == "" {
+			effect = TaintEffectNoSchedule
+		}
+		if key == "" || !isTaintEffect(effect) {
+			log.Logger().Warn("invalid node taint, ignoring taint",
+				zap.String("nodeID", nodeID),
+				zap.String("taint", entry))
+			continue
+		}
+		if effect == TaintEffectPreferNoSchedule {
+			continue
+		}
+		taints = append(taints, nodeTaint{key: key, value: taintValue, effect: effect})
+	}
+	return taints
+}
+
+// Parse the tolerations from the ask tag value.
+func parseAskTolerations(value string) ([]taintToleration, error) {
+	var tolerations []taintToleration
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, tolerationValue, effect := splitTaint(entry)
+		if key == "" {
+			return nil, fmt.Errorf("toleration %s has no key", entry)
+		}
+		if effect != "" && !isTaintEffect(effect) {
+			return nil, fmt.Errorf("toleration %s has an unknown effect %s", entry, effect)
+		}
+		if key == tolerateAll && (tolerationValue != "" || effect != "") {
+			return nil, fmt.Errorf("toleration %s for all taints cannot have a value or effect", entry)
+		}
+		tolerations = append(tolerations, taintToleration{key: key, value: tolerationValue, effect: effect})
+	}
+	return tolerations, nil
+}
+
+func (tt taintToleration) tolerates(taint nodeTaint) bool {
+	if tt.key == tolerateAll {
+		return true
+	}
+	return tt.key == taint.key && (tt.value == "" || tt.value == taint.value) && (tt.effect == "" || tt.effect == taint.effect)
+}
+
+// Check if all the taints are tolerated by at least one of the tolerations.
+func toleratesTaints(tolerations []taintToleration, taints []nodeTaint) bool {
+	for _, taint := range taints {
+		tolerated := false
+		for _, toleration := range tolerations {
+			if toleration.tolerates(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseNodeTaints(t *testing.T) {
+	taints := parseNodeTaints("node-1", "")
+	assert.Equal(t, len(taints), 0, "empty attribute should not have taints")
+	// invalid and preferred taints are dropped, a missing effect is NoSchedule
+	taints = parseNodeTaints("node-1", "gpu=true:NoSchedule, dedicated:NoExecute,spot,soft=1:PreferNoSchedule,bad:Unknown,=x:NoSchedule")
+	expected := []nodeTaint{
+		{key: "gpu", value: "true", effect: TaintEffectNoSchedule},
+		{key: "dedicated", effect: TaintEffectNoExecute},
+		{key: "spot", effect: TaintEffectNoSchedule},
+	}
+	assert.Assert(t, reflect.DeepEqual(taints, expected), "unexpected taints: %v", taints)
+}
+
+func TestParseAskTolerations(t *testing.T) {
+	tolerations, err := parseAskTolerations("gpu=true:NoSchedule,dedicated,spot:NoExecute,zone=a")
+	assert.NilError(t, err, "valid tolerations should not fail")
+	expected := []taintToleration{
+		{key: "gpu", value: "true", effect: TaintEffectNoSchedule},
+		{key: "dedicated"},
+		{key: "spot", effect: TaintEffectNoExecute},
+		{key: "zone", value: "a"},
+	}
+	assert.Assert(t, reflect.DeepEqual(tolerations, expected), "unexpected tolerations: %v", tolerations)
+	tolerations, err = parseAskTolerations("*")
+	assert.NilError(t, err, "tolerate all should not fail")
+	assert.Equal(t, len(tolerations), 1, "expected one toleration")
+
+	for _, value := range []string{"=true", "gpu:Unknown", "*:NoSchedule", "*=x"} {
+		_, err = parseAskTolerations(value)
+		assert.Assert(t, err != nil, "invalid toleration %s should fail", value)
+	}
+}
+
+func TestToleratesTaints(t *testing.T) {
+	taints := parseNodeTaints("node-1", "gpu=true:NoSchedule,dedicated=team1:NoExecute")
+	var tests = []struct {
+		name        string
+		tolerations string
+		expected    bool
+	}{
+		{"no tolerations", "", false},
+		{"one taint tolerated", "gpu", false},
+		{"all taints by key", "gpu,dedicated", true},
+		{"value match", "gpu=true,dedicated=team1", true},
+		{"value mismatch", "gpu=true,dedicated=team2", false},
+		{"effect match", "gpu:NoSchedule,dedicated=team1:NoExecute", true},
+		{"effect mismatch", "gpu:NoExecute,dedicated", false},
+		{"tolerate all", "*", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tolerations, err := parseAskTolerations(tt.tolerations)
+			assert.NilError(t, err, "tolerations should parse")
+			assert.Equal(t, toleratesTaints(tolerations, taints), tt.expected, "unexpected toleration result")
+		})
+	}
+	// a node without taints accepts all asks
+	assert.Assert(t, toleratesTaints(nil, nil), "node without taints should be tolerated")
+}
@@ -0,0 +1,82 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sync"
+)
+
+// Pools of the short lived objects of the scheduling loop. The objects are created for each allocation attempt, or
+// each preemption run, and are dropped when it is done: reusing them lowers the allocation rate of the loop and with
+// that the GC pressure. An object must not be used after it is put back in its pool.
+var (
+	nodeListPool               = sync.Pool{New: func() interface{} { return &nodeList{} }}
+	nodeSorterPool             = sync.Pool{New: func() interface{} { return &nodeSorter{} }}
+	preemptionQueueContextPool = sync.Pool{New: func() interface{} { return &preemptionQueueContext{} }}
+	schedulingAllocationPool   = sync.Pool{New: func() interface{} { return &schedulingAllocation{} }}
+)
+
+// A list of nodes that is reused between allocation attempts.
+type nodeList struct {
+	nodes []*SchedulingNode
+}
+
+// Get an empty node list from the pool.
+func getNodeList() *nodeList {
+	return nodeListPool.Get().(*nodeList)
+}
+
+// Put the list back in the pool. The nodes are cleared: a pooled list must not keep a removed node alive.
+func (nl *nodeList) release() {
+	for i := range nl.nodes {
+		nl.nodes[i] = nil
+	}
+	nl.nodes = nl.nodes[:0]
+	nodeListPool.Put(nl)
+}
+
+// Get a queue context from the pool, the children map is empty.
+func getPreemptionQueueContext() *preemptionQueueContext {
+	ctx := preemptionQueueContextPool.Get().(*preemptionQueueContext)
+	if ctx.children == nil {
+		ctx.children = make(map[string]*preemptionQueueContext)
+	}
+	return ctx
+}
+
+// Put the queue context and all its children back in the pool.
+func releasePreemptionQueueContext(ctx *preemptionQueueContext) {
+	if ctx == nil {
+		return
+	}
+	for name, child := range ctx.children {
+		releasePreemptionQueueContext(child)
+		delete(ctx.children, name)
+	}
+	children := ctx.children
+	*ctx = preemptionQueueContext{children: children}
+	preemptionQueueContextPool.Put(ctx)
+}
+
+// Put the allocation proposal back in the pool, called by the scheduling cycle after the proposal is passed to the
+// cache or dropped. The releases and the allocated resource are handed over to the cache event: they are not reused.
+func (sa *schedulingAllocation) release() {
+	*sa = schedulingAllocation{}
+	schedulingAllocationPool.Put(sa)
+}
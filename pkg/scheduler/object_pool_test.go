@@ -0,0 +1,96 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestNodeSorterReuse(t *testing.T) {
+	large := newNode("large", map[string]resources.Quantity{"first": 300})
+	medium := newNode("medium", map[string]resources.Quantity{"first": 200})
+	small := newNode("small", map[string]resources.Quantity{"first": 100})
+
+	// the shares of a released sorter must not leak into the next sort, shrinking or growing the list
+	list := []*SchedulingNode{small, large, medium}
+	sortNodes(list, MaxAvailableResources)
+	assert.DeepEqual(t, []string{list[0].NodeID, list[1].NodeID, list[2].NodeID}, []string{"large", "medium", "small"})
+	list = []*SchedulingNode{large, small}
+	sortNodes(list, MinAvailableResources)
+	assert.DeepEqual(t, []string{list[0].NodeID, list[1].NodeID}, []string{"small", "large"})
+	list = []*SchedulingNode{medium, small, large, newNode("empty", nil)}
+	sortNodes(list, MinAvailableResources)
+	assert.DeepEqual(t, []string{list[0].NodeID, list[1].NodeID, list[2].NodeID, list[3].NodeID}, []string{"empty", "small", "medium", "large"})
+
+	sorter := newNodeSorter(list, true)
+	sorter.release()
+	assert.Assert(t, sorter.nodes == nil, "released sorter should not keep the nodes")
+}
+
+func TestNodeListRelease(t *testing.T) {
+	list := getNodeList()
+	list.nodes = append(list.nodes, newSchedNode("node-1"), newSchedNode("node-2"))
+	nodes := list.nodes
+	list.release()
+	assert.Equal(t, len(list.nodes), 0, "released list should be empty")
+	assert.Assert(t, nodes[0] == nil && nodes[1] == nil, "released list should not keep the nodes")
+}
+
+func TestPreemptionQueueContextRelease(t *testing.T) {
+	root := getPreemptionQueueContext()
+	root.queuePath = "root"
+	root.resources = newQueuePreemptCalcResource()
+	child := getPreemptionQueueContext()
+	child.queuePath = "root.leaf"
+	child.parent = root
+	root.children["leaf"] = child
+
+	releasePreemptionQueueContext(root)
+	assert.Equal(t, len(root.children), 0, "released context should not have children")
+	assert.Assert(t, root.queuePath == "" && root.resources == nil, "released context should be cleared")
+	assert.Assert(t, child.queuePath == "" && child.parent == nil, "released child should be cleared")
+	// releasing nothing is a noop
+	releasePreemptionQueueContext(nil)
+}
+
+func TestSchedulingAllocationRelease(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	ask := newAllocationAsk("alloc-1", "app-1", res)
+	alloc := newSchedulingAllocation(ask, "node-1")
+	alloc.result = allocated
+	alloc.shape = 1
+	proposal := newAllocationProposal(alloc)
+	alloc.release()
+	assert.Assert(t, alloc.schedulingAsk == nil && alloc.nodeID == "" && alloc.allocatedResource == nil, "released allocation should be cleared")
+	assert.Equal(t, alloc.result, none, "released allocation should not have a result")
+	assert.Equal(t, alloc.shape, 0, "released allocation should not have a shape")
+	// the proposal does not depend on the released allocation
+	assert.Equal(t, proposal.NodeID, "node-1", "unexpected proposal node")
+	assert.Assert(t, resources.Equals(proposal.AllocatedResource, res), "unexpected proposal resource")
+
+	// a new allocation starts from a clean state
+	alloc = newSchedulingAllocation(ask, "node-2")
+	assert.Equal(t, alloc.repeats, int32(1), "new allocation should have one repeat")
+	assert.Equal(t, alloc.shape, 0, "new allocation should have the requested shape")
+	assert.Equal(t, alloc.nodeID, "node-2", "unexpected node on new allocation")
+}
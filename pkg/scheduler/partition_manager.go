@@ -39,7 +39,9 @@ type partitionManager struct {
 }
 
 // Run the manager for the partition.
-// The manager has two tasks:
+// The manager has four tasks:
+// - complete running applications that have been idle for the completion grace period
+// - force the applications out of managed queues that have been draining for longer than the drain timeout
 // - clean up the managed queues that are empty and removed from the configuration
 // - remove empty unmanaged queues
 // When the manager exits the partition is removed from the system and must be cleaned up
@@ -55,6 +57,7 @@ func (manager partitionManager) Run() {
 	for {
 		time.Sleep(manager.interval)
 		runStart := time.Now()
+		manager.completeApplications(runStart)
 		manager.cleanQueues(manager.psc.root)
 		if manager.stop {
 			break
@@ -71,7 +74,50 @@ func (manager partitionManager) Stop() {
 	manager.stop = true
 }
 
-// Remove drained managed and empty unmanaged queues. The logic is mostly hidden in the cached object(s).
+// Complete the running applications that have no allocations and no pending asks for longer than the application
+// completion grace period of the partition. The application is removed from the partition as if the shim removed it,
+// the completion is logged in the audit log. Without a grace period applications are kept until the shim removes them.
+// Only called internally, no locking
+func (manager partitionManager) completeApplications(now time.Time) {
+	gracePeriod := manager.psc.partition.GetAppCompletionGracePeriod()
+	if gracePeriod == 0 {
+		return
+	}
+	apps, _ := manager.psc.getApplicationsAndNodes()
+	for appID, app := range apps {
+		if app.ApplicationInfo.GetApplicationState() != cache.Running.String() {
+			continue
+		}
+		idleTime := app.updateIdleTime(now)
+		if idleTime < gracePeriod {
+			continue
+		}
+		if err := app.ApplicationInfo.HandleApplicationEvent(cache.CompleteApplication); err != nil {
+			log.Logger().Warn("failed to complete idle application",
+				zap.String("appID", appID),
+				zap.String("partitionName", manager.psc.Name),
+				zap.Error(err))
+			continue
+		}
+		if _, err := manager.psc.removeSchedulingApplication(appID); err != nil {
+			log.Logger().Warn("failed to remove completed application from the scheduler",
+				zap.String("appID", appID),
+				zap.String("partitionName", manager.psc.Name),
+				zap.Error(err))
+		}
+		manager.psc.partition.RemoveApplication(appID)
+		log.Audit(log.AuditApplicationCompleted,
+			zap.String("partitionName", manager.psc.Name),
+			zap.String("appID", appID),
+			zap.String("queueName", app.ApplicationInfo.QueueName),
+			zap.String("reason", "idle"),
+			zap.Duration("idleTime", idleTime))
+	}
+}
+
+// Remove drained managed and idle unmanaged queues. The logic is mostly hidden in the cached object(s).
+// Unmanaged queues are removed when they have been idle for the idle timeout of the partition, the removal is
+// logged in the audit log.
 // Perform the action recursively.
 // Only called internally and recursive, no locking
 func (manager partitionManager) cleanQueues(schedulingQueue *SchedulingQueue) {
@@ -84,6 +130,18 @@ func (manager partitionManager) cleanQueues(schedulingQueue *SchedulingQueue) {
 			manager.cleanQueues(child)
 		}
 	}
+	// unmanaged queues must be idle for the timeout before they can be removed
+	var idleTime time.Duration
+	if !schedulingQueue.isManaged() {
+		idleTime = schedulingQueue.updateIdleTime(time.Now())
+		if idleTime < manager.psc.partition.GetQueueIdleTimeout() {
+			return
+		}
+	}
+	// a managed queue that is draining for too long loses its applications
+	if schedulingQueue.isDraining() && schedulingQueue.isManaged() {
+		manager.drainQueue(schedulingQueue, time.Now())
+	}
 	// when we have done the children (or have none) this schedulingQueue might be removable
 	if schedulingQueue.isDraining() || !schedulingQueue.isManaged() {
 		log.Logger().Debug("removing scheduling queue",
@@ -98,6 +156,12 @@ func (manager partitionManager) cleanQueues(schedulingQueue *SchedulingQueue) {
 					log.Logger().Debug("unexpected failure removing the scheduling queue",
 						zap.String("partitionName", manager.psc.Name),
 						zap.String("schedulingQueue", schedulingQueue.Name))
+				} else if !schedulingQueue.isManaged() {
+					log.Audit(log.AuditQueueRemoved,
+						zap.String("partitionName", manager.psc.Name),
+						zap.String("queueName", schedulingQueue.Name),
+						zap.String("reason", "idle"),
+						zap.Duration("idleTime", idleTime))
 				}
 			} else {
 				log.Logger().Debug("failed to remove scheduling queue (cache)",
@@ -108,7 +172,6 @@ func (manager partitionManager) cleanQueues(schedulingQueue *SchedulingQueue) {
 					zap.String("partitionName", manager.psc.Name))
 			}
 		} else {
-			// TODO time out waiting for draining and removal
 			log.Logger().Debug("failed to remove scheduling queue due to existing assigned apps or leaf queues",
 				zap.String("schedulingQueue", schedulingQueue.Name),
 				zap.String("partitionName", manager.psc.Name))
@@ -116,6 +179,67 @@ func (manager partitionManager) cleanQueues(schedulingQueue *SchedulingQueue) {
 	}
 }
 
+// Force the applications out of a draining queue when the queue has been draining for longer than the drain timeout
+// of the partition. The applications are moved to the drain target of the partition, an application that cannot be
+// moved, or all applications if there is no target, is killed. The moves and kills are logged in the audit log.
+// The queue is removed by the cleanup as soon as the applications are gone.
+// Only called internally, no locking
+func (manager partitionManager) drainQueue(schedulingQueue *SchedulingQueue, now time.Time) {
+	timeout := manager.psc.partition.GetQueueDrainTimeout()
+	if timeout == 0 {
+		return
+	}
+	drainTime := now.Sub(schedulingQueue.QueueInfo.GetStateTime())
+	if drainTime < timeout {
+		return
+	}
+	// a target that is draining itself would only postpone the problem
+	target := manager.psc.partition.GetQueueDrainTarget()
+	if queue := manager.psc.GetQueue(target); target != "" && (queue == nil || queue.isDraining()) {
+		log.Logger().Warn("queue drain target is not available, killing the applications",
+			zap.String("partitionName", manager.psc.Name),
+			zap.String("queueName", schedulingQueue.Name),
+			zap.String("target", target))
+		target = ""
+	}
+	for appID, app := range schedulingQueue.getCopyOfApps() {
+		if app.ApplicationInfo.GetApplicationState() == cache.Killed.String() {
+			continue
+		}
+		if target != "" {
+			err := manager.psc.moveSchedulingApplication(appID, target)
+			if err == nil {
+				log.Audit(log.AuditApplicationMoved,
+					zap.String("partitionName", manager.psc.Name),
+					zap.String("appID", appID),
+					zap.String("queueName", schedulingQueue.Name),
+					zap.String("target", target),
+					zap.String("reason", "drain timeout"),
+					zap.Duration("drainTime", drainTime))
+				continue
+			}
+			log.Logger().Warn("failed to move application out of draining queue",
+				zap.String("appID", appID),
+				zap.String("queueName", schedulingQueue.Name),
+				zap.String("target", target),
+				zap.Error(err))
+		}
+		if err := manager.psc.partition.KillApplication(appID); err != nil {
+			log.Logger().Warn("failed to kill application in draining queue",
+				zap.String("appID", appID),
+				zap.String("queueName", schedulingQueue.Name),
+				zap.Error(err))
+			continue
+		}
+		log.Audit(log.AuditApplicationKilled,
+			zap.String("partitionName", manager.psc.Name),
+			zap.String("appID", appID),
+			zap.String("queueName", schedulingQueue.Name),
+			zap.String("reason", "drain timeout"),
+			zap.Duration("drainTime", drainTime))
+	}
+}
+
 // The partition has been removed from the configuration and must be removed.
 // Clean up all linked objects:
 // - queues
@@ -0,0 +1,192 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+func TestCleanIdleQueues(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	cache.SetQueueIdleTimeout(partition.partition, time.Hour)
+	root := partition.root
+	var parent, leaf *SchedulingQueue
+	parent, err = createUnManagedQueue(root, "users", true)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = createUnManagedQueue(parent, "alice", false)
+	assert.NilError(t, err, "failed to create leaf queue")
+	app := newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", leaf.Name, security.UserGroup{}, nil))
+	leaf.addSchedulingApplication(app)
+	manager := partitionManager{psc: partition}
+
+	// queue with an application is never removed
+	manager.cleanQueues(root)
+	assert.Equal(t, len(parent.GetCopyOfChildren()), 1, "queue with application should not be removed")
+
+	// idle but not for the timeout
+	leaf.removeSchedulingApplication(app)
+	manager.cleanQueues(root)
+	assert.Equal(t, len(parent.GetCopyOfChildren()), 1, "queue idle for less than the timeout should not be removed")
+
+	// an application added in between resets the idle time
+	leaf.addSchedulingApplication(app)
+	assert.Assert(t, leaf.idleSince.IsZero(), "idle time not reset on application add")
+	leaf.removeSchedulingApplication(app)
+
+	// idle for longer than the timeout: the leaf is removed, the parent just became idle
+	leaf.idleSince = time.Now().Add(-2 * time.Hour)
+	manager.cleanQueues(root)
+	assert.Equal(t, len(parent.GetCopyOfChildren()), 0, "idle leaf queue should have been removed")
+	assert.Equal(t, len(root.GetCopyOfChildren()), 1, "parent queue idle for less than the timeout should not be removed")
+	assert.Assert(t, !parent.idleSince.IsZero(), "parent queue should be marked idle")
+	parent.idleSince = time.Now().Add(-2 * time.Hour)
+	manager.cleanQueues(root)
+	assert.Equal(t, len(root.GetCopyOfChildren()), 0, "idle parent queue should have been removed")
+
+	// no timeout: removed as soon as the queue is empty
+	cache.SetQueueIdleTimeout(partition.partition, 0)
+	parent, err = createUnManagedQueue(root, "users", true)
+	assert.NilError(t, err, "failed to create parent queue")
+	_, err = createUnManagedQueue(parent, "bob", false)
+	assert.NilError(t, err, "failed to create leaf queue")
+	manager.cleanQueues(root)
+	assert.Equal(t, len(root.GetCopyOfChildren()), 0, "empty queues should have been removed without timeout")
+}
+
+func TestCompleteIdleApplications(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(partition.root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	addApp := func(appID string) *SchedulingApplication {
+		app := newSchedulingApplication(cache.NewApplicationInfo(appID, "default", leaf.Name, security.UserGroup{}, nil))
+		app.queue = leaf
+		leaf.addSchedulingApplication(app)
+		partition.applications[appID] = app
+		return app
+	}
+	// app-1 is running with a pending ask, app-2 is accepted but has not run yet
+	app1 := addApp("app-1")
+	startApplication(t, app1)
+	_, err = app1.addAllocationAsk(newAllocationAsk("alloc-1", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})))
+	assert.NilError(t, err, "failed to add ask")
+	app2 := addApp("app-2")
+	err = app2.ApplicationInfo.HandleApplicationEvent(cache.AcceptApplication)
+	assert.NilError(t, err, "failed to accept application")
+	manager := partitionManager{psc: partition}
+
+	// no grace period: nothing is ever completed
+	app1.removeAllocationAsk("")
+	manager.completeApplications(time.Now())
+	assert.Equal(t, len(partition.applications), 2, "applications should not be completed without grace period")
+	assert.Assert(t, app1.idleSince.IsZero(), "application should not be tracked without grace period")
+
+	// pending ask: not idle
+	cache.SetAppCompletionGracePeriod(partition.partition, time.Hour)
+	_, err = app1.addAllocationAsk(newAllocationAsk("alloc-1", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})))
+	assert.NilError(t, err, "failed to add ask")
+	manager.completeApplications(time.Now())
+	assert.Assert(t, app1.idleSince.IsZero(), "application with a pending ask should not be idle")
+
+	// idle but not for the grace period
+	app1.removeAllocationAsk("")
+	manager.completeApplications(time.Now())
+	assert.Equal(t, len(partition.applications), 2, "application idle for less than the grace period should not be completed")
+	assert.Assert(t, !app1.idleSince.IsZero(), "application should be marked idle")
+
+	// idle for longer than the grace period: only the running application is completed and removed
+	app1.idleSince = time.Now().Add(-2 * time.Hour)
+	app2.idleSince = time.Now().Add(-2 * time.Hour)
+	manager.completeApplications(time.Now())
+	assert.Equal(t, app1.ApplicationInfo.GetApplicationState(), cache.Completed.String(), "idle application should be completed")
+	assert.Assert(t, partition.getApplication("app-1") == nil, "completed application should have been removed")
+	assert.Equal(t, len(leaf.applications), 1, "completed application should have been removed from the queue")
+	assert.Equal(t, app2.ApplicationInfo.GetApplicationState(), cache.Accepted.String(), "application that is not running should not be completed")
+}
+
+func TestDrainQueues(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: moved
+            submitacl: "*"
+          - name: killed
+            submitacl: "*"
+          - name: fallback
+            submitacl: "*"
+`
+	info, err := cache.CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	partition := newPartitionSchedulingContext(info, newSchedulingQueueInfo(info.Root, nil))
+	addApp := func(appID, queueName string) *SchedulingApplication {
+		appInfo := cache.NewApplicationInfo(appID, "default", queueName, security.UserGroup{User: "user"}, nil)
+		err = cache.AddApplicationToPartition(info, appInfo)
+		assert.NilError(t, err, "failed to add app to cache partition")
+		app := newSchedulingApplication(appInfo)
+		err = partition.addSchedulingApplication(app)
+		assert.NilError(t, err, "failed to add app to partition")
+		return app
+	}
+	moved := addApp("app-1", "root.moved")
+	killed := addApp("app-2", "root.killed")
+	for _, name := range []string{"root.moved", "root.killed"} {
+		partition.GetQueue(name).QueueInfo.MarkQueueForRemoval()
+	}
+	manager := partitionManager{psc: partition}
+
+	// no timeout: draining queues with applications are kept
+	manager.cleanQueues(partition.root)
+	assert.Equal(t, len(partition.root.GetCopyOfChildren()), 3, "draining queues with applications should not be removed")
+
+	// draining for less than the timeout
+	cache.SetQueueDrain(info, time.Hour, "root.fallback")
+	manager.cleanQueues(partition.root)
+	assert.Equal(t, len(partition.root.GetCopyOfChildren()), 3, "queues draining for less than the timeout should not be removed")
+	assert.Equal(t, moved.queue.Name, "root.moved", "application should not be moved before the timeout")
+
+	// timeout passed: the application is moved and the empty queue removed
+	cache.SetQueueStateTime(partition.GetQueue("root.moved").QueueInfo, time.Now().Add(-2*time.Hour))
+	manager.cleanQueues(partition.root)
+	assert.Equal(t, moved.queue.Name, "root.fallback", "application should have been moved to the drain target")
+	assert.Assert(t, partition.GetQueue("root.moved") == nil, "drained queue should have been removed")
+	assert.Equal(t, killed.ApplicationInfo.GetApplicationState(), cache.New.String(), "application in queue draining for less than the timeout should not be killed")
+
+	// no target: the application is killed, the queue is removed when the application is gone
+	cache.SetQueueDrain(info, time.Hour, "")
+	cache.SetQueueStateTime(partition.GetQueue("root.killed").QueueInfo, time.Now().Add(-2*time.Hour))
+	manager.cleanQueues(partition.root)
+	assert.Equal(t, killed.ApplicationInfo.GetApplicationState(), cache.Killed.String(), "application should have been killed")
+	assert.Assert(t, partition.GetQueue("root.killed") != nil, "queue should not be removed before the application is gone")
+	_, err = partition.removeSchedulingApplication("app-2")
+	assert.NilError(t, err, "failed to remove killed app")
+	manager.cleanQueues(partition.root)
+	assert.Assert(t, partition.GetQueue("root.killed") == nil, "drained queue should have been removed")
+}
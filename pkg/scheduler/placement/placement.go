@@ -21,29 +21,31 @@ package placement
 import (
 	"fmt"
 	"strings"
-	"sync"
 
 	"go.uber.org/zap"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
 )
 
 type AppPlacementManager struct {
-	name        string
-	info        *cache.PartitionInfo
-	rules       []rule
-	initialised bool
-	lock        sync.RWMutex
+	name         string
+	info         *cache.PartitionInfo
+	rules        []rule
+	rulesVersion uint64 // version of the partition rules the rules were built from
+	initialised  bool
+	lock         locking.RWMutex
 }
 
 func NewPlacementManager(info *cache.PartitionInfo) *AppPlacementManager {
+	rules, version := info.GetVersionedRules()
 	m := &AppPlacementManager{
-		name: info.Name,
-		info: info,
+		name:         info.Name,
+		info:         info,
+		rulesVersion: version,
 	}
-	rules := info.GetRules()
 	if len(rules) > 0 {
 		if err := m.initialise(rules); err != nil {
 			log.Logger().Info("Placement manager created without rules: not active",
@@ -75,6 +77,28 @@ func (m *AppPlacementManager) UpdateRules(rules []configs.PlacementRule) error {
 	return nil
 }
 
+// Rebuild the rules if the rules of the partition changed since they were last built.
+// The partition replaces the rules together with the queue hierarchy on a config reload. Placing an application
+// with the rules of the old config could route the application to a queue that was removed.
+func (m *AppPlacementManager) RefreshRules() error {
+	rules, version := m.info.GetVersionedRules()
+	m.lock.RLock()
+	current := m.rulesVersion
+	m.lock.RUnlock()
+	if version == current {
+		return nil
+	}
+	log.Logger().Info("Placement rules changed in partition, reloading rules",
+		zap.String("partitionName", m.name),
+		zap.Uint64("rulesVersion", version))
+	// the version is updated even if the rules fail to build: the same rules would fail again
+	err := m.UpdateRules(rules)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.rulesVersion = version
+	return err
+}
+
 // Return the state of the placement manager
 func (m *AppPlacementManager) IsInitialised() bool {
 	m.lock.RLock()
@@ -126,6 +150,11 @@ func (m *AppPlacementManager) buildRules(rules []configs.PlacementRule) ([]rule,
 }
 
 func (m *AppPlacementManager) PlaceApplication(app *cache.ApplicationInfo) error {
+	// always place with the rules that match the current queue hierarchy
+	if err := m.RefreshRules(); err != nil {
+		log.Logger().Warn("Placement rules not reloaded, using previous rules",
+			zap.Error(err))
+	}
 	// Placement manager not initialised cannot place application, just return
 	m.lock.RLock()
 	defer m.lock.RUnlock()
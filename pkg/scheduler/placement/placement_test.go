@@ -121,6 +121,59 @@ func TestManagerUpdate(t *testing.T) {
 	}
 }
 
+func TestManagerRefreshRules(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    placementrules:
+      - name: fixed
+        value: root.first
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: first
+`
+	partInfo, err := CreatePartitionInfo([]byte(data))
+	if err != nil {
+		t.Fatalf("Partition create failed with error: %v", err)
+	}
+	man := NewPlacementManager(partInfo)
+	if !man.IsInitialised() {
+		t.Fatal("placement manager should have been initialised with the partition rules")
+	}
+	user := security.UserGroup{
+		User:   "test",
+		Groups: []string{},
+	}
+	appInfo := cache.NewApplicationInfo("app1", "default", "", user, nil)
+	err = man.PlaceApplication(appInfo)
+	if err != nil || appInfo.QueueName != "root.first" {
+		t.Errorf("app should have been placed in the fixed queue, queue: '%s', error: %v", appInfo.QueueName, err)
+	}
+
+	// reload replaces the queue and the rule: the manager must not place in the removed queue
+	conf := partInfo.GetEffectiveConfig()
+	conf.Queues[0].Queues = []configs.QueueConfig{{Name: "second"}}
+	conf.PlacementRules = []configs.PlacementRule{{Name: "fixed", Value: "root.second"}}
+	err = cache.UpdatePartitionInfo(partInfo, conf)
+	if err != nil {
+		t.Fatalf("Partition update failed with error: %v", err)
+	}
+	appInfo = cache.NewApplicationInfo("app2", "default", "", user, nil)
+	err = man.PlaceApplication(appInfo)
+	if err != nil || appInfo.QueueName != "root.second" {
+		t.Errorf("app should have been placed with the reloaded rule, queue: '%s', error: %v", appInfo.QueueName, err)
+	}
+	if man.rulesVersion != partInfo.GetRulesVersion() {
+		t.Errorf("rules version not updated, expected %d got %d", partInfo.GetRulesVersion(), man.rulesVersion)
+	}
+	// nothing changed: refresh is a noop
+	if err = man.RefreshRules(); err != nil {
+		t.Errorf("refresh without changes should not have failed: %v", err)
+	}
+}
+
 func TestManagerBuildRule(t *testing.T) {
 	pi := cache.PartitionInfo{
 		Name: "test",
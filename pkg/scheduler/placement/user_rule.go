@@ -30,8 +30,11 @@ import (
 )
 
 // A rule to place an application based on the user name of the submitting user.
+// The parent queue of the user queue is generated by the parent rule, or set as the rule value. A value like
+// root.users places each user in its own queue below root.users, the template of the parent queue sets the quota.
 type userRule struct {
 	basicRule
+	parentQueue string // fully qualified parent queue from the rule value, empty if not set
 }
 
 func (ur *userRule) getName() string {
@@ -43,8 +46,17 @@ func (ur *userRule) initialise(conf configs.PlacementRule) error {
 	ur.filter = newFilter(conf.Filter)
 	var err = error(nil)
 	if conf.Parent != nil {
+		if conf.Value != "" {
+			return fmt.Errorf("user rule cannot have both a parent rule and a parent queue value: %s", conf.Value)
+		}
 		ur.parent, err = newRule(*conf.Parent)
 	}
+	if conf.Value != "" {
+		ur.parentQueue = normalise(conf.Value)
+		if !strings.HasPrefix(ur.parentQueue, configs.RootQueue+cache.DOT) && ur.parentQueue != configs.RootQueue {
+			ur.parentQueue = configs.RootQueue + cache.DOT + ur.parentQueue
+		}
+	}
 	return err
 }
 
@@ -78,6 +90,13 @@ func (ur *userRule) placeApplication(app *cache.ApplicationInfo, info *cache.Par
 			return "", fmt.Errorf("parent rule returned a leaf queue: %s", parentName)
 		}
 	}
+	// the parent queue from the rule value must be a parent if it exists
+	if ur.parentQueue != "" {
+		parentName = ur.parentQueue
+		if queue := info.GetQueue(parentName); queue != nil && queue.IsLeafQueue() {
+			return "", fmt.Errorf("user rule parent queue is a leaf queue: %s", parentName)
+		}
+	}
 	// the parent is set from the rule otherwise set it to the root
 	if parentName == "" {
 		parentName = configs.RootQueue
@@ -126,3 +126,83 @@ partitions:
 		t.Errorf("user rule placed in to be created queue with create false '%s', err %v", queue, err)
 	}
 }
+
+func TestUserRuleParentQueue(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: users
+        parent: true
+        childtemplate:
+          resources:
+            max:
+              memory: 100
+      - name: leaf
+`
+	partInfo, err := CreatePartitionInfo([]byte(data))
+	if err != nil {
+		t.Fatalf("Partition create failed with error: %v", err)
+	}
+	user := security.UserGroup{
+		User:   "testuser",
+		Groups: []string{},
+	}
+	appInfo := cache.NewApplicationInfo("app1", "default", "ignored", user, make(map[string]string))
+
+	// parent rule and value cannot be combined
+	conf := configs.PlacementRule{
+		Name:   "user",
+		Value:  "users",
+		Parent: &configs.PlacementRule{Name: "fixed", Value: "users"},
+	}
+	if _, err = newRule(conf); err == nil {
+		t.Errorf("user rule create should have failed with both a parent rule and value")
+	}
+
+	// user queue does not exist and cannot be created
+	conf = configs.PlacementRule{
+		Name:  "user",
+		Value: "users",
+	}
+	var ur rule
+	ur, err = newRule(conf)
+	if err != nil || ur == nil {
+		t.Fatalf("user rule create failed with parent queue value, err %v", err)
+	}
+	var queue string
+	queue, err = ur.placeApplication(appInfo, partInfo)
+	if queue != "" || err != nil {
+		t.Errorf("user rule placed app in queue that does not exist '%s', err %v", queue, err)
+	}
+
+	// qualified parent and create set
+	conf = configs.PlacementRule{
+		Name:   "user",
+		Create: true,
+		Value:  "root.Users",
+	}
+	ur, err = newRule(conf)
+	if err != nil || ur == nil {
+		t.Fatalf("user rule create failed with qualified parent queue value, err %v", err)
+	}
+	queue, err = ur.placeApplication(appInfo, partInfo)
+	if queue != "root.users.testuser" || err != nil {
+		t.Errorf("user rule failed to place app in user queue '%s', err %v", queue, err)
+	}
+
+	// parent queue is a leaf
+	conf = configs.PlacementRule{
+		Name:   "user",
+		Create: true,
+		Value:  "leaf",
+	}
+	ur, err = newRule(conf)
+	if err != nil || ur == nil {
+		t.Fatalf("user rule create failed with leaf parent queue value, err %v", err)
+	}
+	queue, err = ur.placeApplication(appInfo, partInfo)
+	if queue != "" || err == nil {
+		t.Errorf("user rule placed app below leaf queue '%s'", queue)
+	}
+}
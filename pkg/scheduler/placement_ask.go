@@ -0,0 +1,168 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+// Is the ask a placement only ask? A placement only ask requests no resources: it is placed on a node that passes the
+// constraints of the ask, like the taints, node age, queue anti affinity and the predicates of the shim, without
+// consuming any headroom of the queues or resources of the node. Shims use them to place control pods.
+func (saa *schedulingAllocationAsk) isPlacementOnly() bool {
+	return resources.IsZero(saa.AllocatedResource)
+}
+
+// Return the pending repeat of the ask if it is a placement only ask, 0 for all other asks.
+func (saa *schedulingAllocationAsk) getPendingPlacements() int32 {
+	if !saa.isPlacementOnly() {
+		return 0
+	}
+	return saa.getPendingAskRepeat()
+}
+
+// Return the pending repeats of the placement only asks of the application.
+func (sa *SchedulingApplication) getPendingPlacements() int32 {
+	sa.RLock()
+	defer sa.RUnlock()
+	return sa.placements
+}
+
+// Update the pending placement only asks of the application and its queue with the delta.
+// Lock free call this must be called holding the application lock
+func (sa *SchedulingApplication) updatePendingPlacements(delta int32) {
+	if delta == 0 {
+		return
+	}
+	sa.placements += delta
+	sa.queue.incPendingPlacements(delta)
+}
+
+// Return the pending placement only asks for the apps in the queue and its children.
+func (sq *SchedulingQueue) getPendingPlacements() int32 {
+	sq.RLock()
+	defer sq.RUnlock()
+	return sq.placements
+}
+
+// Update the pending placement only asks of the queue and its parents with the delta.
+// The placement only asks are not batched in throughput mode: there is no resource to share.
+func (sq *SchedulingQueue) incPendingPlacements(delta int32) {
+	for queue := sq; queue != nil; queue = queue.parent {
+		queue.Lock()
+		queue.placements += delta
+		if queue.placements < 0 {
+			log.Logger().Warn("pending placement only asks went negative",
+				zap.String("queueName", queue.Name),
+				zap.Int32("placements", queue.placements))
+			queue.placements = 0
+		}
+		queue.Unlock()
+	}
+}
+
+// Try to place the pending placement only asks of the partition.
+// Lock free call this all locks are taken when needed in called functions
+func (psc *partitionSchedulingContext) tryPlacementAllocate() *schedulingAllocation {
+	if psc.root.getPendingPlacements() == 0 {
+		return nil
+	}
+	return psc.root.tryPlacementAllocate(psc)
+}
+
+// Try to place the pending placement only asks of the queue. This is a depth first walk over the queues with pending
+// placement only asks, the headroom of the queues is not checked. Child queues are tried in name order, applications
+// in the order of the sort policy of the queue. Queues that are within their start delay or reached the allocation cap
+// of the scheduling cycle are skipped, applications on hold are skipped.
+// Lock free call this all locks are taken when needed in called functions
+func (sq *SchedulingQueue) tryPlacementAllocate(ctx *partitionSchedulingContext) *schedulingAllocation {
+	if sq.getPendingPlacements() == 0 || sq.isStartDelayed() {
+		return nil
+	}
+	if sq.isLeafQueue() {
+		if ctx.cycle.isCapped(sq) {
+			return nil
+		}
+		for _, app := range sq.sortedApps.sorted(sq.getSortType(), sq.QueueInfo.GetApplicationSortAging(), sq.QueueInfo.GetGuaranteedResource()) {
+			if app.getPendingPlacements() == 0 || app.ApplicationInfo.IsHeld() {
+				continue
+			}
+			if alloc := app.tryPlacementAllocate(ctx); alloc != nil {
+				return alloc
+			}
+		}
+		return nil
+	}
+	children := sq.GetCopyOfChildren()
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if alloc := children[name].tryPlacementAllocate(ctx); alloc != nil {
+			return alloc
+		}
+	}
+	return nil
+}
+
+// Try to place the pending placement only asks of the application on a node.
+// Reserved nodes are not skipped: a placement only ask does not use the resources the node is reserved for.
+func (sa *SchedulingApplication) tryPlacementAllocate(ctx *partitionSchedulingContext) *schedulingAllocation {
+	// the partition must not be locked while holding the application lock: get the nodes first
+	nodes := ctx.getSchedulingNodes(false)
+	sa.Lock()
+	defer sa.Unlock()
+	sa.stats.schedulingAttempts++
+	avoid, hard := sa.queue.getAntiAffinity()
+	for _, request := range sa.askQueue.asks {
+		if request.getPendingPlacements() == 0 {
+			continue
+		}
+		nodeIterator := ctx.getNodeIterator(nodes, sa.queue)
+		if nodeIterator == nil {
+			return nil
+		}
+		for nodeIterator.HasNext() {
+			node := nodeIterator.Next()
+			sa.stats.nodesEvaluated++
+			if !request.toleratesNode(node) || !request.acceptsNodeAge(node) ||
+				(hard && len(avoid) != 0 && node.nodeInfo.HasQueueAllocations(avoid)) ||
+				!sa.queue.QueueInfo.IsNodePoolAllowed(node.nodeInfo.Pool) ||
+				!node.preAllocateConditions(request.AskProto.AllocationKey) {
+				continue
+			}
+			// mark this ask as allocating by lowering the repeat
+			if _, err := sa.updateAskRepeatInternal(request, -1); err != nil {
+				log.Logger().Debug("ask repeat update failed unexpectedly",
+					zap.Error(err))
+			}
+			alloc := newSchedulingAllocation(request, node.NodeID)
+			alloc.result = allocated
+			return alloc
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+// partition with an application in root.parent.leaf1 and no headroom left in the root queue
+func createPlacementPartition(t *testing.T) (*partitionSchedulingContext, *SchedulingApplication) {
+	partition := createQueuesNodes(t)
+	err := partition.root.QueueInfo.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100}), true)
+	assert.NilError(t, err, "failed to set allocated resource on root")
+	leaf := partition.getQueue("root.parent.leaf1")
+	app := newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", leaf.Name, security.UserGroup{}, nil))
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications["app-1"] = app
+	return partition, app
+}
+
+func TestPlacementOnlyAsk(t *testing.T) {
+	partition, app := createPlacementPartition(t)
+	regular := newAllocationAsk("alloc-1", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}))
+	_, err := app.addAllocationAsk(regular)
+	assert.NilError(t, err, "failed to add regular ask to app")
+	placement := newAllocationAskRepeat("placement-1", "app-1", resources.NewResource(), 2)
+	var delta *resources.Resource
+	delta, err = app.addAllocationAsk(placement)
+	assert.NilError(t, err, "failed to add placement only ask to app")
+	assert.Assert(t, resources.IsZero(delta), "placement only ask should not change the pending resource")
+	assert.Equal(t, app.getPendingPlacements(), int32(2), "unexpected pending placements on app")
+	assert.Equal(t, partition.root.getPendingPlacements(), int32(2), "unexpected pending placements on root")
+
+	// the regular ask is blocked by the headroom, the placement only ask is not
+	assert.Assert(t, partition.tryAllocate() == nil, "regular ask should not have been allocated")
+	alloc := partition.tryPlacementAllocate()
+	assert.Assert(t, alloc != nil, "placement only ask should have been allocated")
+	assert.Equal(t, alloc.result, allocated, "unexpected allocation result")
+	assert.Equal(t, alloc.schedulingAsk.AskProto.AllocationKey, "placement-1", "unexpected ask allocated")
+	assert.Assert(t, resources.IsZero(alloc.allocatedResource), "placement only allocation should not use resources")
+	assert.Equal(t, app.getPendingPlacements(), int32(1), "placement should have lowered the pending placements")
+	assert.Assert(t, partition.allocate(alloc), "placement should be passed on to the cache")
+
+	// a rejection adds the placement back
+	err = partition.confirmAllocation("app-1", alloc.nodeID, "placement-1", alloc.allocatedResource, false)
+	assert.NilError(t, err, "failed to reject the placement")
+	assert.Equal(t, partition.root.getPendingPlacements(), int32(2), "rejection should restore the pending placements")
+
+	// replacing the ask with a regular ask removes the placements
+	_, err = app.addAllocationAsk(newAllocationAsk("placement-1", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})))
+	assert.NilError(t, err, "failed to replace placement only ask")
+	assert.Equal(t, partition.root.getPendingPlacements(), int32(0), "regular ask should not count as a placement")
+	_, err = app.addAllocationAsk(placement)
+	assert.NilError(t, err, "failed to add placement only ask to app")
+	app.removeAllocationAsk("")
+	assert.Equal(t, app.getPendingPlacements(), int32(0), "removal should clear the pending placements on app")
+	assert.Equal(t, partition.root.getPendingPlacements(), int32(0), "removal should clear the pending placements on root")
+	assert.Assert(t, partition.tryPlacementAllocate() == nil, "nothing should be placed without pending placements")
+}
+
+func TestPlacementOnlyAskConstraints(t *testing.T) {
+	partition, app := createPlacementPartition(t)
+	placement := newAllocationAsk("placement-1", "app-1", resources.NewResource())
+	_, err := app.addAllocationAsk(placement)
+	assert.NilError(t, err, "failed to add placement only ask to app")
+
+	// the taints of the nodes still apply
+	for nodeID, node := range partition.nodes {
+		node.taints = parseNodeTaints(nodeID, "dedicated:NoSchedule")
+	}
+	assert.Assert(t, partition.tryPlacementAllocate() == nil, "placement only ask should not be placed on tainted nodes")
+	partition.nodes["node-2"].taints = nil
+	alloc := partition.tryPlacementAllocate()
+	assert.Assert(t, alloc != nil, "placement only ask should have been placed")
+	assert.Equal(t, alloc.nodeID, "node-2", "placement only ask should be placed on the untainted node")
+
+	// a placement only ask cannot have alternatives
+	placement = newAllocationAsk("placement-2", "app-1", resources.NewResource())
+	placement.AskProto.Tags = map[string]string{AlternativesAskTag: "[first:5]"}
+	assert.Assert(t, placement.parseAlternatives(nil, nil) != nil, "placement only ask with alternatives should fail")
+}
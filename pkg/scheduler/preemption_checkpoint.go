@@ -0,0 +1,124 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+// Application tag to declare that the allocations of the application can be checkpointed, set the tag to "true".
+// Checkpointable allocations are preferred as preemption victims. The RM is notified before the allocation is
+// released, giving the application the grace period configured on the partition to checkpoint its work.
+const CheckpointApplicationTag = "application.checkpoint"
+
+// An allocation selected for preemption that is waiting for the grace period to pass before it is released.
+// For an intent the deadline is the confirm timeout: the allocation is released if the RM has not responded.
+type pendingPreemption struct {
+	alloc       *cache.AllocationInfo
+	queue       *SchedulingQueue
+	release     *commonevents.ReleaseAllocation
+	deadline    time.Time
+	intent      bool
+	askPriority int32 // priority of the ask the intent was created for, the alternates are checked against it
+}
+
+// Check if the application has declared it can be checkpointed.
+func isCheckpointable(app *cache.ApplicationInfo) bool {
+	return strings.EqualFold(app.GetTag(CheckpointApplicationTag), "true")
+}
+
+// Add the allocation to the pending preemptions and create the notification for the RM.
+func (psc *partitionSchedulingContext) addPendingPreemption(alloc *cache.AllocationInfo, queue *SchedulingQueue,
+	release *commonevents.ReleaseAllocation, gracePeriod time.Duration) *api.PreemptionNotification {
+	psc.Lock()
+	defer psc.Unlock()
+
+	psc.pendingPreemptions[alloc.AllocationProto.UUID] = &pendingPreemption{
+		alloc:    alloc,
+		queue:    queue,
+		release:  release,
+		deadline: time.Now().Add(gracePeriod),
+	}
+	return &api.PreemptionNotification{
+		UUID:          alloc.AllocationProto.UUID,
+		ApplicationID: alloc.ApplicationID,
+		PartitionName: psc.Name,
+		GracePeriod:   gracePeriod,
+		Message:       release.Message,
+	}
+}
+
+// Return a copy of the pending preemptions.
+func (psc *partitionSchedulingContext) getPendingPreemptions() []*pendingPreemption {
+	psc.RLock()
+	defer psc.RUnlock()
+
+	pending := make([]*pendingPreemption, 0, len(psc.pendingPreemptions))
+	for _, preemption := range psc.pendingPreemptions {
+		pending = append(pending, preemption)
+	}
+	return pending
+}
+
+// Remove the pending preemptions for which the grace period has passed and return the releases to issue.
+func (psc *partitionSchedulingContext) getExpiredPreemptions(now time.Time) []*commonevents.ReleaseAllocation {
+	var releases []*commonevents.ReleaseAllocation
+	for _, preemption := range psc.getPendingPreemptions() {
+		if now.Before(preemption.deadline) {
+			continue
+		}
+		psc.Lock()
+		delete(psc.pendingPreemptions, preemption.alloc.AllocationProto.UUID)
+		psc.Unlock()
+		if release := psc.finalizePreemption(preemption); release != nil {
+			releases = append(releases, release)
+		}
+	}
+	return releases
+}
+
+// Return the release for a pending preemption that was removed from the pending preemptions.
+// An allocation that was released by the RM in the meantime is not released again, the preempting resource on the
+// node is reset directly and nil is returned.
+func (psc *partitionSchedulingContext) finalizePreemption(preemption *pendingPreemption) *commonevents.ReleaseAllocation {
+	app := psc.getApplication(preemption.alloc.ApplicationID)
+	if app == nil || app.ApplicationInfo.GetAllocation(preemption.alloc.AllocationProto.UUID) == nil {
+		log.Logger().Debug("pending preemption already released",
+			zap.String("appID", preemption.alloc.ApplicationID),
+			zap.String("allocationUUID", preemption.alloc.AllocationProto.UUID))
+		psc.resetPreemptingResource(preemption.alloc)
+		return nil
+	}
+	return preemption.release
+}
+
+// Remove the resources of an allocation that is no longer preempted from the preempting resource of its node.
+func (psc *partitionSchedulingContext) resetPreemptingResource(alloc *cache.AllocationInfo) {
+	if node := psc.getSchedulingNode(alloc.AllocationProto.NodeID); node != nil {
+		node.decPreemptingResource(alloc.AllocatedResource)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache/cacheevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/schedulerevent"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// Add the allocation to the pending preemptions as an intent and create the intent for the RM.
+// The allocation is released when the RM accepts the intent, or when the timeout passes without a response.
+func (psc *partitionSchedulingContext) addPreemptionIntent(alloc *cache.AllocationInfo, queue *SchedulingQueue,
+	release *commonevents.ReleaseAllocation, askPriority int32, timeout time.Duration) *api.PreemptionIntent {
+	// the intent is sent to the RM: the resource uses the units of the RM
+	resource := psc.partition.ToRMResource(alloc.AllocatedResource.ToProto())
+	psc.Lock()
+	defer psc.Unlock()
+
+	psc.pendingPreemptions[alloc.AllocationProto.UUID] = &pendingPreemption{
+		alloc:       alloc,
+		queue:       queue,
+		release:     release,
+		deadline:    time.Now().Add(timeout),
+		intent:      true,
+		askPriority: askPriority,
+	}
+	return &api.PreemptionIntent{
+		UUID:          alloc.AllocationProto.UUID,
+		ApplicationID: alloc.ApplicationID,
+		PartitionName: psc.Name,
+		Resource:      resource,
+		Timeout:       timeout,
+		Message:       release.Message,
+	}
+}
+
+// Process the response of the RM to a preemption intent and return the releases to issue.
+// A response for an allocation without a pending intent, like an intent that timed out, is ignored.
+func (psc *partitionSchedulingContext) confirmPreemption(response *api.PreemptionResponse) []*commonevents.ReleaseAllocation {
+	psc.Lock()
+	preemption := psc.pendingPreemptions[response.UUID]
+	if preemption == nil || !preemption.intent {
+		psc.Unlock()
+		log.Logger().Debug("preemption response without pending intent",
+			zap.String("partition", psc.Name),
+			zap.String("allocationUUID", response.UUID))
+		return nil
+	}
+	delete(psc.pendingPreemptions, response.UUID)
+	psc.Unlock()
+
+	if !response.Accepted && len(response.Alternates) == 0 {
+		log.Logger().Info("preemption declined by the RM, allocation is completing",
+			zap.String("appID", preemption.alloc.ApplicationID),
+			zap.String("allocationUUID", response.UUID))
+		psc.resetPreemptingResource(preemption.alloc)
+		return nil
+	}
+	if !response.Accepted {
+		alternates, err := psc.getPreemptionAlternates(preemption, response.Alternates)
+		if err == nil {
+			return psc.preemptAlternates(preemption, alternates)
+		}
+		log.Logger().Warn("preemption alternates rejected, releasing the allocation",
+			zap.String("appID", preemption.alloc.ApplicationID),
+			zap.String("allocationUUID", response.UUID),
+			zap.Error(err))
+	}
+	if release := psc.finalizePreemption(preemption); release != nil {
+		return []*commonevents.ReleaseAllocation{release}
+	}
+	return nil
+}
+
+// Find the alternate allocations proposed by the RM. The alternates must be allocations in the queue of the
+// preempted allocation that are not being preempted already, and they must use at least the same resources.
+// Using the same queue keeps the resources reclaimed for the priority inversion unchanged. The alternates must pass
+// the same checks as the victims selected by the scheduler: the queue opt out, the settle period, the priority of
+// the ask and the minimum runtime.
+func (psc *partitionSchedulingContext) getPreemptionAlternates(preemption *pendingPreemption, uuids []string) ([]*cache.AllocationInfo, error) {
+	pending := make(map[string]bool)
+	for _, other := range psc.getPendingPreemptions() {
+		pending[other.alloc.AllocationProto.UUID] = true
+	}
+	pending[preemption.alloc.AllocationProto.UUID] = true
+	protection := newPreemptionProtection(psc, time.Now())
+	if !isVictimQueue(preemption.queue, protection) {
+		return nil, fmt.Errorf("allocations of queue %s cannot be preempted", preemption.queue.Name)
+	}
+	apps := preemption.queue.getCopyOfApps()
+	total := resources.NewResource()
+	alternates := make([]*cache.AllocationInfo, 0, len(uuids))
+	for _, uuid := range uuids {
+		if pending[uuid] {
+			return nil, fmt.Errorf("allocation %s is already preempted", uuid)
+		}
+		var alloc *cache.AllocationInfo
+		for _, app := range apps {
+			if alloc = app.ApplicationInfo.GetAllocation(uuid); alloc != nil {
+				break
+			}
+		}
+		if alloc == nil {
+			return nil, fmt.Errorf("allocation %s not found in queue %s", uuid, preemption.queue.Name)
+		}
+		if !isVictimAllocation(alloc, preemption.askPriority, protection) {
+			return nil, fmt.Errorf("allocation %s cannot be preempted for an ask with priority %d", uuid, preemption.askPriority)
+		}
+		pending[uuid] = true
+		total.AddTo(alloc.AllocatedResource)
+		alternates = append(alternates, alloc)
+	}
+	if !resources.FitIn(total, preemption.alloc.AllocatedResource) {
+		return nil, fmt.Errorf("alternates use %s, less than the %s of allocation %s",
+			total.String(), preemption.alloc.AllocatedResource.String(), preemption.alloc.AllocationProto.UUID)
+	}
+	return alternates, nil
+}
+
+// Release the alternates instead of the allocation and move the preempting resource to the nodes of the alternates.
+func (psc *partitionSchedulingContext) preemptAlternates(preemption *pendingPreemption, alternates []*cache.AllocationInfo) []*commonevents.ReleaseAllocation {
+	psc.resetPreemptingResource(preemption.alloc)
+	releases := make([]*commonevents.ReleaseAllocation, 0, len(alternates))
+	for _, alloc := range alternates {
+		log.Logger().Info("preempting alternate allocation proposed by the RM",
+			zap.String("allocationUUID", preemption.alloc.AllocationProto.UUID),
+			zap.String("alternateAppID", alloc.ApplicationID),
+			zap.String("alternateUUID", alloc.AllocationProto.UUID))
+		releases = append(releases, commonevents.NewReleaseAllocation(alloc.AllocationProto.UUID, alloc.ApplicationID, psc.Name,
+			fmt.Sprintf("Preempt allocation=%s as alternate for allocation=%s", alloc.AllocationProto.UUID, preemption.alloc.AllocationProto.UUID),
+			si.AllocationReleaseResponse_PREEMPTED_BY_SCHEDULER))
+		if node := psc.getSchedulingNode(alloc.AllocationProto.NodeID); node != nil {
+			node.incPreemptingResource(alloc.AllocatedResource)
+		}
+	}
+	return releases
+}
+
+// Process the responses of the RM to the preemption intents and pass the releases to the cache.
+func (s *Scheduler) processPreemptionResponseEvent(event *schedulerevent.SchedulerPreemptionResponseEvent) {
+	var releases []*commonevents.ReleaseAllocation
+	for _, response := range event.Responses {
+		psc := s.clusterSchedulingContext.getPartition(response.PartitionName)
+		if psc == nil || psc.RmID != event.RmID {
+			log.Logger().Warn("preemption response for unknown partition",
+				zap.String("rmID", event.RmID),
+				zap.String("partition", response.PartitionName),
+				zap.String("allocationUUID", response.UUID))
+			continue
+		}
+		releases = append(releases, psc.confirmPreemption(response)...)
+	}
+	if len(releases) > 0 {
+		s.eventHandlers.CacheEventHandler.HandleEvent(&cacheevent.ReleaseAllocationsEvent{
+			AllocationsToRelease: releases,
+		})
+	}
+}
@@ -0,0 +1,187 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// Test harness for preemption policies.
+// The harness builds a synthetic queue hierarchy with the resource fixtures set directly on the preemption context,
+// bypassing the cache. Nodes and allocations can be added to test the victim selection.
+// A policy test describes the hierarchy, runs the calculation and asserts the outputs per queue.
+
+// The fixture for one queue in the hierarchy.
+// The path is the full queue path, a parent must be defined before its children. A nil max means unlimited:
+// the partition total resource is used as the maximum.
+type queueFixture struct {
+	path       string
+	parent     bool
+	guaranteed map[string]resources.Quantity
+	used       map[string]resources.Quantity
+	pending    map[string]resources.Quantity
+	max        map[string]resources.Quantity
+	disabled   bool
+}
+
+type preemptionHarness struct {
+	t      *testing.T
+	ctx    *preemptionPartitionContext
+	queues map[string]*preemptionQueueContext
+	nodes  []*SchedulingNode
+}
+
+// Create the harness for the partition total and queue fixtures. The first fixture must be the root queue.
+func newPreemptionHarness(t *testing.T, total map[string]resources.Quantity, fixtures []queueFixture) *preemptionHarness {
+	h := &preemptionHarness{
+		t: t,
+		ctx: &preemptionPartitionContext{
+			partitionTotalResource: resources.NewResourceFromMap(copyQuantities(total)),
+			leafQueues:             make(map[string]*preemptionQueueContext),
+		},
+		queues: make(map[string]*preemptionQueueContext),
+	}
+	for _, fixture := range fixtures {
+		var queue *SchedulingQueue
+		var parent *preemptionQueueContext
+		var err error
+		if fixture.path == "root" {
+			queue, err = createRootQueue(nil)
+		} else {
+			idx := strings.LastIndex(fixture.path, ".")
+			assert.Assert(t, idx > 0, "queue path %s has no parent", fixture.path)
+			parent = h.queues[fixture.path[:idx]]
+			assert.Assert(t, parent != nil, "parent of queue %s must be defined first", fixture.path)
+			queue, err = createManagedQueue(parent.schedulingQueue, fixture.path[idx+1:], fixture.parent, nil)
+		}
+		assert.NilError(t, err, "failed to create queue %s", fixture.path)
+		assert.Equal(t, queue.Name, fixture.path, "unexpected queue name")
+		calc := newQueuePreemptCalcResource()
+		calc.guaranteed = resources.NewResourceFromMap(copyQuantities(fixture.guaranteed))
+		calc.used = resources.NewResourceFromMap(copyQuantities(fixture.used))
+		calc.pending = resources.NewResourceFromMap(copyQuantities(fixture.pending))
+		calc.max = h.ctx.partitionTotalResource.Clone()
+		if fixture.max != nil {
+			calc.max = resources.NewResourceFromMap(copyQuantities(fixture.max))
+		}
+		calc.preemptionDisabled = fixture.disabled
+		ctx := &preemptionQueueContext{
+			queuePath:       fixture.path,
+			schedulingQueue: queue,
+			resources:       calc,
+			children:        make(map[string]*preemptionQueueContext),
+			parent:          parent,
+		}
+		if parent == nil {
+			assert.Assert(t, h.ctx.root == nil, "only one root queue can be defined")
+			h.ctx.root = ctx
+		} else {
+			parent.children[queue.Name] = ctx
+		}
+		if !fixture.parent {
+			h.ctx.leafQueues[fixture.path] = ctx
+		}
+		h.queues[fixture.path] = ctx
+	}
+	assert.Assert(t, h.ctx.root != nil, "root queue must be defined")
+	return h
+}
+
+// Add a node with the total resource to the harness.
+func (h *preemptionHarness) addNode(nodeID string, total map[string]resources.Quantity) {
+	h.nodes = append(h.nodes, newNode(nodeID, copyQuantities(total)))
+}
+
+// Add an allocation for the queue to the node. The fixture of the queue is not updated.
+func (h *preemptionHarness) addAllocation(uuid, queuePath, nodeID string, res map[string]resources.Quantity) *cache.AllocationInfo {
+	for _, node := range h.nodes {
+		if node.NodeID == nodeID {
+			alloc := cache.CreateMockAllocationInfo("app-"+uuid, resources.NewResourceFromMap(copyQuantities(res)), uuid, queuePath, nodeID)
+			node.nodeInfo.AddAllocation(alloc)
+			return alloc
+		}
+	}
+	h.t.Fatalf("node %s not found in harness", nodeID)
+	return nil
+}
+
+// Run the DRF ideal and preemptable resource calculation.
+func (h *preemptionHarness) calculate() {
+	calculateIdealAndPreemptableResources(h.ctx.partitionTotalResource, h.ctx.root)
+}
+
+// Find the victims for an ask of the leaf queue using the DRF policy on the nodes in order.
+// Returns false if the ask cannot be allocated, the victims are the sorted UUIDs of the allocations to release.
+// The ask has priority 0.
+func (h *preemptionHarness) victims(queuePath string, res map[string]resources.Quantity) ([]string, bool) {
+	return h.victimsWithPriority(queuePath, res, 0)
+}
+
+// Find the victims for an ask with the priority, see victims.
+func (h *preemptionHarness) victimsWithPriority(queuePath string, res map[string]resources.Quantity, priority int32) ([]string, bool) {
+	ask := newAllocationAsk("ask-preemptor", "app-preemptor", resources.NewResourceFromMap(copyQuantities(res)))
+	ask.QueueName = queuePath
+	ask.priority = priority
+	alloc := crossQueuePreemptionAllocate(h.ctx, NewDefaultNodeIterator(h.nodes), ask)
+	if alloc == nil {
+		return nil, false
+	}
+	victims := make([]string, 0)
+	for _, release := range alloc.releases {
+		victims = append(victims, release.UUID)
+	}
+	sort.Strings(victims)
+	return victims, true
+}
+
+func (h *preemptionHarness) assertIdeal(queuePath string, expected map[string]resources.Quantity) {
+	queue := h.getQueue(queuePath)
+	assert.Assert(h.t, resources.Equals(queue.resources.ideal, resources.NewResourceFromMap(expected)),
+		"queue %s ideal: expected %v got %v", queuePath, expected, queue.resources.ideal)
+}
+
+func (h *preemptionHarness) assertPreemptable(queuePath string, expected map[string]resources.Quantity) {
+	queue := h.getQueue(queuePath)
+	assert.Assert(h.t, resources.Equals(queue.resources.preemptable, resources.NewResourceFromMap(expected)),
+		"queue %s preemptable: expected %v got %v", queuePath, expected, queue.resources.preemptable)
+}
+
+func (h *preemptionHarness) getQueue(queuePath string) *preemptionQueueContext {
+	queue := h.queues[queuePath]
+	if queue == nil {
+		h.t.Fatalf("queue %s not found in harness", queuePath)
+	}
+	return queue
+}
+
+// Fixtures are shared between test cases: never hand out the fixture map itself.
+func copyQuantities(quantities map[string]resources.Quantity) map[string]resources.Quantity {
+	result := make(map[string]resources.Quantity)
+	for k, v := range quantities {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+// Get the queue the preemption for this queue is contained in: the highest queue in the unbroken chain of parents
+// with the queue preemption scope, see cache.PreemptionScope. The scope property is inherited: a parent queue that
+// sets the scope contains the preemption to its own subtree.
+// Returns nil if the preemption is not contained: the partition is the scope.
+func (sq *SchedulingQueue) getPreemptionScope() *SchedulingQueue {
+	var scope *SchedulingQueue
+	for queue := sq; queue != nil && queue.QueueInfo.IsPreemptionScoped(); queue = queue.parent {
+		scope = queue
+	}
+	return scope
+}
+
+// Check if preemption for an ask in the preemptor queue can take a victim from the victim queue.
+// Both queues must have the same preemption scope: a contained queue never preempts outside its scope, and the queues
+// outside the scope never preempt inside it.
+func canPreemptInScope(preemptor, victim *SchedulingQueue) bool {
+	return preemptor.getPreemptionScope() == victim.getPreemptionScope()
+}
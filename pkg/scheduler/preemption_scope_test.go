@@ -0,0 +1,81 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+)
+
+func TestPreemptionScope(t *testing.T) {
+	// root
+	// - team	scoped
+	//   - a
+	//   - b
+	// - other
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	conf := configs.QueueConfig{
+		Name:       "team",
+		Parent:     true,
+		Properties: map[string]string{cache.PreemptionScope: cache.PreemptionScopeQueue},
+	}
+	var info *cache.QueueInfo
+	info, err = cache.NewManagedQueue(conf, root.QueueInfo)
+	assert.NilError(t, err, "failed to create team queue")
+	team := newSchedulingQueueInfo(info, root)
+	var a, b, other *SchedulingQueue
+	a, err = createManagedQueue(team, "a", false, nil)
+	assert.NilError(t, err, "failed to create a queue")
+	b, err = createManagedQueue(team, "b", false, nil)
+	assert.NilError(t, err, "failed to create b queue")
+	other, err = createManagedQueue(root, "other", false, nil)
+	assert.NilError(t, err, "failed to create other queue")
+
+	// the scope property is inherited, the highest queue with the scope is the scope
+	assert.Equal(t, a.getPreemptionScope(), team, "leaf should be scoped to the team queue")
+	assert.Equal(t, team.getPreemptionScope(), team, "team queue should be its own scope")
+	assert.Assert(t, other.getPreemptionScope() == nil, "other queue should use the partition scope")
+	assert.Assert(t, root.getPreemptionScope() == nil, "root should use the partition scope")
+
+	assert.Assert(t, canPreemptInScope(a, b), "siblings in the scope should preempt each other")
+	assert.Assert(t, !canPreemptInScope(a, other), "scoped queue should not preempt outside the scope")
+	assert.Assert(t, !canPreemptInScope(other, a), "queue outside the scope should not preempt inside it")
+}
+
+func TestPriorityInversionScope(t *testing.T) {
+	// the high queue is contained to itself: the low queue is outside its scope
+	partition := createInversionPartition(t, 1, nil)
+	high := partition.getQueue("root.parent.high")
+	cache.SetPreemptionScoped(high.QueueInfo, true)
+	releases, _, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 0, "allocations outside the scope should not be preempted")
+
+	// both queues in the parent scope
+	parent := partition.getQueue("root.parent")
+	low := partition.getQueue("root.parent.low")
+	cache.SetPreemptionScoped(parent.QueueInfo, true)
+	cache.SetPreemptionScoped(low.QueueInfo, true)
+	releases, _, _ = resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 1, "allocation inside the scope should be preempted")
+}
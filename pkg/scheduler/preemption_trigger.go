@@ -0,0 +1,112 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+)
+
+// Preemption in a partition runs if it is enabled in the config, or if it is triggered by the utilization.
+// The utilization trigger is set when the partition utilization reaches the trigger level while a queue is starving.
+// It stays set until the utilization drops below the release level, or no queue is starving anymore. The gap between
+// the trigger and release level prevents flapping when the utilization hovers around the trigger level.
+
+// Check if preemption should run for the partition.
+func (psc *partitionSchedulingContext) needPreemption() bool {
+	if psc.partition.NeedPreemption() {
+		return true
+	}
+	psc.RLock()
+	defer psc.RUnlock()
+	return psc.utilizationTriggered
+}
+
+// Update the utilization trigger of the partition and return the new state.
+// Lock free call this all locks are taken when needed in called functions
+func (psc *partitionSchedulingContext) updateUtilizationTrigger() bool {
+	trigger, release := psc.partition.GetUtilizationTrigger()
+	psc.RLock()
+	triggered := psc.utilizationTriggered
+	psc.RUnlock()
+	newState := false
+	if trigger > 0 {
+		utilization := psc.partition.GetUtilization()
+		if triggered {
+			newState = utilization >= release
+		} else {
+			newState = utilization >= trigger
+		}
+		// only check the queues if the utilization would set the trigger
+		newState = newState && psc.hasStarvingQueue()
+		if newState != triggered {
+			log.Logger().Info("utilization based preemption trigger changed",
+				zap.String("partitionName", psc.Name),
+				zap.Bool("triggered", newState),
+				zap.Int("utilization", utilization),
+				zap.Int("trigger", trigger),
+				zap.Int("release", release))
+		}
+	}
+	psc.Lock()
+	defer psc.Unlock()
+	psc.utilizationTriggered = newState
+	return newState
+}
+
+// Check if any leaf queue in the partition is starving.
+// Lock free call this all locks are taken when needed in called functions
+func (psc *partitionSchedulingContext) hasStarvingQueue() bool {
+	for _, leaf := range psc.root.getLeafQueues() {
+		if leaf.isStarving() {
+			return true
+		}
+	}
+	return false
+}
+
+// A queue is starving if it has pending requests for a resource type while its usage of that type is below the
+// guaranteed resource of the queue. A queue without a guaranteed resource never starves.
+func (sq *SchedulingQueue) isStarving() bool {
+	guaranteed := sq.QueueInfo.GetGuaranteedResource()
+	if guaranteed == nil {
+		return false
+	}
+	pending := sq.GetPendingResource()
+	allocated := sq.QueueInfo.GetAllocatedResource()
+	for name, quantity := range pending.Resources {
+		if quantity > 0 && allocated.Resources[name] < guaranteed.Resources[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// Update the utilization triggers of all partitions, returns true if preemption was triggered in any partition.
+// Lock free call this all locks are taken when needed in called functions
+func (csc *ClusterSchedulingContext) updateUtilizationTriggers() bool {
+	triggered := false
+	for _, psc := range csc.getPartitionMapClone() {
+		if psc.updateUtilizationTrigger() {
+			triggered = true
+		}
+	}
+	return triggered
+}
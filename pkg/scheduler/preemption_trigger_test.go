@@ -0,0 +1,104 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// partition for the trigger tests: total 100, trigger at 80 and release at 60.
+// The leaf queue has a guarantee of 50 and uses 10, the other queue uses the rest of the resources passed in.
+func createTriggerPartition(t *testing.T, used resources.Quantity, pending bool) *partitionSchedulingContext {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	cache.SetTotalPartitionResource(partition.partition, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100}))
+	cache.SetUtilizationTrigger(partition.partition, 80, 60)
+	var leaf, other *SchedulingQueue
+	leaf, err = createManagedQueue(partition.root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	other, err = createManagedQueue(partition.root, "other", false, nil)
+	assert.NilError(t, err, "failed to create other queue")
+	cache.SetGuaranteedResource(leaf.QueueInfo, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 50}))
+	err = leaf.QueueInfo.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10}), false)
+	assert.NilError(t, err, "failed to set allocated resource on queue")
+	err = other.QueueInfo.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": used - 10}), false)
+	assert.NilError(t, err, "failed to set allocated resource on queue")
+	if pending {
+		leaf.incPendingResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}))
+	}
+	return partition
+}
+
+func TestUtilizationTrigger(t *testing.T) {
+	var tests = []struct {
+		name      string
+		used      resources.Quantity
+		pending   bool
+		triggered bool
+		expected  bool
+	}{
+		{"below trigger", 75, true, false, false},
+		{"at trigger", 80, true, false, true},
+		{"above trigger no starving queue", 90, false, false, false},
+		{"triggered above release", 65, true, true, true},
+		{"triggered at release", 60, true, true, true},
+		{"triggered below release", 55, true, true, false},
+		{"triggered no starving queue", 90, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partition := createTriggerPartition(t, tt.used, tt.pending)
+			assert.Equal(t, partition.partition.GetUtilization(), int(tt.used), "unexpected utilization")
+			partition.utilizationTriggered = tt.triggered
+			assert.Equal(t, partition.updateUtilizationTrigger(), tt.expected, "unexpected trigger state")
+			assert.Equal(t, partition.needPreemption(), tt.expected, "preemption need should follow the trigger")
+		})
+	}
+
+	// no trigger configured: never triggered
+	partition := createTriggerPartition(t, 100, true)
+	cache.SetUtilizationTrigger(partition.partition, 0, 0)
+	partition.utilizationTriggered = true
+	assert.Assert(t, !partition.updateUtilizationTrigger(), "trigger should be reset without config")
+}
+
+func TestQueueStarving(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	// no guarantee: never starving
+	leaf.incPendingResource(res)
+	assert.Assert(t, !leaf.isStarving(), "queue without guarantee should not starve")
+	// pending only for a type without a guarantee
+	cache.SetGuaranteedResource(leaf.QueueInfo, resources.NewResourceFromMap(map[string]resources.Quantity{"second": 5}))
+	assert.Assert(t, !leaf.isStarving(), "queue without guarantee for the pending type should not starve")
+	cache.SetGuaranteedResource(leaf.QueueInfo, res)
+	assert.Assert(t, leaf.isStarving(), "queue below guarantee with pending requests should starve")
+	err = leaf.QueueInfo.IncAllocatedResource(res, false)
+	assert.NilError(t, err, "failed to set allocated resource on queue")
+	assert.Assert(t, !leaf.isStarving(), "queue at guarantee should not starve")
+}
@@ -19,7 +19,11 @@
 package scheduler
 
 import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
 )
 
 // Below structures are intended to be used under single go routine, thus no
@@ -33,11 +37,14 @@ type preemptionPartitionContext struct {
 	partitionTotalResource *resources.Resource
 	root                   *preemptionQueueContext
 	leafQueues             map[string]*preemptionQueueContext
+	protection             *preemptionProtection
 }
 
 type preemptionQueueContext struct {
 	queuePath       string
 	schedulingQueue *SchedulingQueue
+	scope           *SchedulingQueue // queue the preemption is contained in, nil means the partition
+	settling        bool             // the limits of the queue changed less than the settle period ago, not preempted
 
 	// all resources-related for preemption decisions.
 	resources *queuePreemptCalcResource
@@ -56,13 +63,16 @@ type queuePreemptCalcResource struct {
 	markedPreemptedResource *resources.Resource
 	// How much resource can be preempted by other queues.
 	preemptable *resources.Resource
+	// The allocations of the queue are never preempted.
+	preemptionDisabled bool
 }
 
 func (m *queuePreemptCalcResource) initFromSchedulingQueue(queue *SchedulingQueue) {
 	m.guaranteed = queue.QueueInfo.GetGuaranteedResource()
 	m.used = queue.QueueInfo.GetAllocatedResource()
 	m.pending = queue.GetPendingResource()
-	m.max = queue.QueueInfo.GetMaxResource()
+	m.max = applyBorrowLimit(queue.QueueInfo.GetMaxResource(), queue.QueueInfo.GetBorrowMaxResource())
+	m.preemptionDisabled = queue.QueueInfo.IsPreemptionDisabled()
 }
 
 func newQueuePreemptCalcResource() *queuePreemptCalcResource {
@@ -73,6 +83,53 @@ func newQueuePreemptCalcResource() *queuePreemptCalcResource {
 	}
 }
 
+// The protection of freshly started allocations against preemption for a partition.
+// Allocations that have run for less than the minimum runtime are not preempted, unless the ask that triggers the
+// preemption has a priority at or above the emergency priority. A nil protection does not protect any allocation.
+// Queues of which a reload changed the guaranteed or max resource less than the settle period ago are not preempted:
+// the usage of the queue still reflects the old limits.
+type preemptionProtection struct {
+	minRuntime        time.Duration
+	emergencyPriority int32
+	settlePeriod      time.Duration
+	now               time.Time
+}
+
+func newPreemptionProtection(psc *partitionSchedulingContext, now time.Time) *preemptionProtection {
+	return &preemptionProtection{
+		minRuntime:        psc.partition.GetPreemptionMinRuntime(),
+		emergencyPriority: psc.partition.GetPreemptionEmergencyPriority(),
+		settlePeriod:      psc.partition.GetPreemptionSettlePeriod(),
+		now:               now,
+	}
+}
+
+// Check if the allocations of the queue must be skipped as victims: a reload changed the guaranteed or max resource
+// of the queue, or of one of its parents, less than the settle period ago.
+func (p *preemptionProtection) isSettling(queue *SchedulingQueue) bool {
+	if p == nil || p.settlePeriod <= 0 || queue == nil {
+		return false
+	}
+	changed := queue.QueueInfo.GetLimitsChangeTime()
+	return !changed.IsZero() && p.now.Sub(changed) < p.settlePeriod
+}
+
+// Check if the allocation must be skipped as a victim for an ask with the priority.
+// A protected allocation is counted in the preemption metrics.
+func (p *preemptionProtection) isProtected(alloc *cache.AllocationInfo, askPriority int32) bool {
+	if p == nil || p.minRuntime <= 0 {
+		return false
+	}
+	if p.emergencyPriority > 0 && askPriority >= p.emergencyPriority {
+		return false
+	}
+	if !alloc.IsPreemptionProtected(p.minRuntime, p.now) {
+		return false
+	}
+	metrics.GetSchedulerMetrics().IncPreemptionProtectedAllocations()
+	return true
+}
+
 type PreemptionPolicy interface {
 	DoPreemption(scheduler *Scheduler)
 }
@@ -80,13 +137,16 @@ type PreemptionPolicy interface {
 func getPreemptionPolicies() []PreemptionPolicy {
 	preemptionPolicies := make([]PreemptionPolicy, 0)
 	preemptionPolicies = append(preemptionPolicies, &DRFPreemptionPolicy{})
+	preemptionPolicies = append(preemptionPolicies, &PriorityInversionPreemptionPolicy{})
 	return preemptionPolicies
 }
 
 // Visible by tests
 func (s *Scheduler) SingleStepPreemption() {
+	// The utilization triggers are always updated: the trigger state must follow the partition utilization.
+	triggered := s.clusterSchedulingContext.updateUtilizationTriggers()
 	// Skip if no preemption needed.
-	if !s.clusterSchedulingContext.NeedPreemption() {
+	if !s.clusterSchedulingContext.NeedPreemption() && !triggered {
 		return
 	}
 
@@ -100,6 +160,12 @@ func (s *Scheduler) SingleStepPreemption() {
 
 // Copy & Reset PreemptionContext
 func (s *Scheduler) resetPreemptionContext() {
+	// The queue contexts of the previous run are reused
+	if s.preemptionContext != nil {
+		for _, partitionCtx := range s.preemptionContext.partitions {
+			releasePreemptionQueueContext(partitionCtx.root)
+		}
+	}
 	// Create a new preemption context
 	s.preemptionContext = &preemptionContext{
 		partitions: make(map[string]*preemptionPartitionContext),
@@ -109,6 +175,7 @@ func (s *Scheduler) resetPreemptionContext() {
 	for partition, partitionContext := range s.clusterSchedulingContext.getPartitionMapClone() {
 		preemptionPartitionCtx := &preemptionPartitionContext{
 			leafQueues: make(map[string]*preemptionQueueContext),
+			protection: newPreemptionProtection(partitionContext, time.Now()),
 		}
 		s.preemptionContext.partitions[partition] = preemptionPartitionCtx
 		preemptionPartitionCtx.root = s.recursiveInitPreemptionQueueContext(preemptionPartitionCtx, nil, partitionContext.root)
@@ -117,13 +184,13 @@ func (s *Scheduler) resetPreemptionContext() {
 
 func (s *Scheduler) recursiveInitPreemptionQueueContext(preemptionPartitionCtx *preemptionPartitionContext, parent *preemptionQueueContext,
 	queue *SchedulingQueue) *preemptionQueueContext {
-	preemptionQueue := &preemptionQueueContext{
-		queuePath:       queue.Name,
-		parent:          parent,
-		schedulingQueue: queue,
-		resources:       newQueuePreemptCalcResource(),
-		children:        make(map[string]*preemptionQueueContext),
-	}
+	preemptionQueue := getPreemptionQueueContext()
+	preemptionQueue.queuePath = queue.Name
+	preemptionQueue.parent = parent
+	preemptionQueue.schedulingQueue = queue
+	preemptionQueue.scope = queue.getPreemptionScope()
+	preemptionQueue.settling = preemptionPartitionCtx.protection.isSettling(queue)
+	preemptionQueue.resources = newQueuePreemptCalcResource()
 
 	if queue.isLeafQueue() {
 		preemptionPartitionCtx.leafQueues[queue.Name] = preemptionQueue
@@ -0,0 +1,278 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache/cacheevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-core/pkg/rmproxy/rmevent"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// Preemption policy that protects against priority inversion between sibling queues.
+// A pending ask can fit on a node and in the headroom of its own leaf queue but still be blocked by the headroom of
+// a parent queue. If that parent headroom is consumed by allocations with a lower priority in other queues below
+// the same parent the priority is inverted. The policy preempts the lowest priority allocations from those queues,
+// limited to the resources needed to remove the parent headroom shortage. Allocations of checkpointable applications
+// are preempted first: the RM is notified and the release follows after the grace period set for the partition.
+// If the partition sets a confirm timeout other allocations are only released after the RM confirmed the intent.
+type PriorityInversionPreemptionPolicy struct {
+}
+
+func (m *PriorityInversionPreemptionPolicy) DoPreemption(scheduler *Scheduler) {
+	for _, psc := range scheduler.clusterSchedulingContext.getPartitionMapClone() {
+		if !psc.needPreemption() {
+			continue
+		}
+		releases, notifications, intents := resolvePriorityInversion(psc)
+		releases = append(releases, psc.getExpiredPreemptions(time.Now())...)
+		if len(notifications) > 0 {
+			scheduler.eventHandlers.RMProxyEventHandler.HandleEvent(&rmevent.RMPreemptionNotificationEvent{
+				RmID:          psc.RmID,
+				Notifications: notifications,
+			})
+		}
+		if len(intents) > 0 {
+			scheduler.eventHandlers.RMProxyEventHandler.HandleEvent(&rmevent.RMPreemptionIntentEvent{
+				RmID:    psc.RmID,
+				Intents: intents,
+			})
+		}
+		if len(releases) > 0 {
+			scheduler.eventHandlers.CacheEventHandler.HandleEvent(&cacheevent.ReleaseAllocationsEvent{
+				AllocationsToRelease: releases,
+			})
+		}
+	}
+}
+
+// An allocation that could be preempted to resolve a priority inversion.
+type inversionVictim struct {
+	alloc          *cache.AllocationInfo
+	queue          *SchedulingQueue
+	priority       int32
+	checkpointable bool
+}
+
+// Find the priority inversions in the partition and select the allocations to preempt.
+// Only the highest priority pending ask of each leaf queue is checked in one run. The cache releases the
+// allocations asynchronously: the resources reclaimed in this run, and by the pending preemptions, are tracked per
+// queue to prevent preempting the same headroom twice.
+// Returns the allocations to release now, the notifications for checkpointable allocations that are released
+// after the grace period and the intents for allocations that are released after the RM confirmed them.
+// Lock free call this all locks are taken when needed in called functions
+func resolvePriorityInversion(psc *partitionSchedulingContext) ([]*commonevents.ReleaseAllocation, []*api.PreemptionNotification, []*api.PreemptionIntent) {
+	reclaimed := make(map[string]*resources.Resource)
+	preempted := make(map[string]bool)
+	addReclaimed := func(queue *SchedulingQueue, res *resources.Resource) {
+		for ; queue != nil; queue = queue.parent {
+			if reclaimed[queue.Name] == nil {
+				reclaimed[queue.Name] = resources.NewResource()
+			}
+			reclaimed[queue.Name].AddTo(res)
+		}
+	}
+	for _, pending := range psc.getPendingPreemptions() {
+		preempted[pending.alloc.AllocationProto.UUID] = true
+		addReclaimed(pending.queue, pending.alloc.AllocatedResource)
+	}
+	gracePeriod := psc.partition.GetPreemptionGracePeriod()
+	confirmTimeout := psc.partition.GetPreemptionConfirmTimeout()
+	protection := newPreemptionProtection(psc, time.Now())
+	var releases []*commonevents.ReleaseAllocation
+	var notifications []*api.PreemptionNotification
+	var intents []*api.PreemptionIntent
+	for _, leaf := range psc.root.getLeafQueues() {
+		ask := leaf.getHighestPriorityAsk()
+		if ask == nil {
+			continue
+		}
+		victims := findInversionVictims(psc, leaf, ask, reclaimed, preempted, protection)
+		metrics.GetSchedulerMetrics().AddPreemptedAllocations(len(victims))
+		for _, victim := range victims {
+			log.Logger().Info("preempting allocation to resolve priority inversion",
+				zap.String("preemptorQueue", leaf.Name),
+				zap.String("allocationKey", ask.AskProto.AllocationKey),
+				zap.Int32("askPriority", ask.priority),
+				zap.String("victimQueue", victim.queue.Name),
+				zap.String("victimUUID", victim.alloc.AllocationProto.UUID),
+				zap.Int32("victimPriority", victim.priority),
+				zap.Bool("checkpointable", victim.checkpointable))
+			release := commonevents.NewReleaseAllocation(victim.alloc.AllocationProto.UUID, victim.alloc.ApplicationID, psc.Name,
+				fmt.Sprintf("Preempt allocation=%s for ask=%s to resolve priority inversion", victim.alloc.AllocationProto.UUID, ask.AskProto.AllocationKey),
+				si.AllocationReleaseResponse_PREEMPTED_BY_SCHEDULER)
+			switch {
+			case victim.checkpointable && gracePeriod > 0:
+				notifications = append(notifications, psc.addPendingPreemption(victim.alloc, victim.queue, release, gracePeriod))
+			case confirmTimeout > 0:
+				intents = append(intents, psc.addPreemptionIntent(victim.alloc, victim.queue, release, ask.priority, confirmTimeout))
+			default:
+				releases = append(releases, release)
+			}
+			preempted[victim.alloc.AllocationProto.UUID] = true
+			addReclaimed(victim.queue, victim.alloc.AllocatedResource)
+			// the cache reports the release back to the node when done
+			if node := psc.getSchedulingNode(victim.alloc.AllocationProto.NodeID); node != nil {
+				node.incPreemptingResource(victim.alloc.AllocatedResource)
+			}
+		}
+	}
+	return releases, notifications, intents
+}
+
+// Check if the allocations of the queue can be victims: the queue did not opt out of preemption and is not settling
+// after a change of its limits.
+func isVictimQueue(queue *SchedulingQueue, protection *preemptionProtection) bool {
+	return !queue.QueueInfo.IsPreemptionDisabled() && !protection.isSettling(queue)
+}
+
+// Check if the allocation can be a victim for an ask with the priority: the allocation has a lower priority and is
+// not protected by the minimum runtime. A placement only allocation does not free any resources.
+func isVictimAllocation(alloc *cache.AllocationInfo, askPriority int32, protection *preemptionProtection) bool {
+	if alloc.AllocationProto.Priority.GetPriorityValue() >= askPriority {
+		return false
+	}
+	return !protection.isProtected(alloc, askPriority) && !alloc.IsPlacementOnly()
+}
+
+// Select the lowest priority allocations that remove the parent headroom shortage for the ask.
+// Allocations that are protected because they have not run for the minimum runtime are not considered.
+// Returns nil if the ask is not blocked by a parent headroom shortage only, or if the shortage cannot be removed
+// completely by preempting lower priority allocations: partial preemption would not help the ask.
+func findInversionVictims(psc *partitionSchedulingContext, leaf *SchedulingQueue, ask *schedulingAllocationAsk,
+	reclaimed map[string]*resources.Resource, preempted map[string]bool, protection *preemptionProtection) []*inversionVictim {
+	// the leaf itself must have the headroom
+	if getHeadRoomShortage(leaf, ask.AllocatedResource, reclaimed) != nil {
+		return nil
+	}
+	// collect the shortages of the parents, remember the highest queue that is short
+	shortages := make(map[string]*resources.Resource)
+	var top *SchedulingQueue
+	for queue := leaf.parent; queue != nil; queue = queue.parent {
+		if shortage := getHeadRoomShortage(queue, ask.AllocatedResource, reclaimed); shortage != nil {
+			shortages[queue.Name] = shortage
+			top = queue
+		}
+	}
+	if top == nil {
+		return nil
+	}
+	// there must be a node that can fit the ask otherwise it is not blocked by the headroom only
+	fits := false
+	for _, node := range psc.getSchedulableNodes() {
+		if ask.toleratesNode(node) && ask.acceptsNodeAge(node) && node.nodeInfo.FitIn(node.getAvailableResource(), ask.AllocatedResource) {
+			fits = true
+			break
+		}
+	}
+	if !fits {
+		return nil
+	}
+	// candidates are all lower priority allocations in the other leaf queues below the highest short queue that are
+	// in the preemption scope of the leaf, did not opt out of preemption and are not settling after a change of their
+	// limits
+	var candidates []*inversionVictim
+	for _, queue := range top.getLeafQueues() {
+		if queue == leaf || !canPreemptInScope(leaf, queue) || !isVictimQueue(queue, protection) {
+			continue
+		}
+		for _, app := range queue.getCopyOfApps() {
+			checkpointable := isCheckpointable(app.ApplicationInfo)
+			for _, alloc := range app.ApplicationInfo.GetAllAllocations() {
+				if preempted[alloc.AllocationProto.UUID] || !isVictimAllocation(alloc, ask.priority, protection) {
+					continue
+				}
+				priority := alloc.AllocationProto.Priority.GetPriorityValue()
+				candidates = append(candidates, &inversionVictim{
+					alloc:          alloc,
+					queue:          queue,
+					priority:       priority,
+					checkpointable: checkpointable,
+				})
+			}
+		}
+	}
+	// checkpointable first, then lowest priority first, stable order for the same priority
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].checkpointable != candidates[j].checkpointable {
+			return candidates[i].checkpointable
+		}
+		if candidates[i].priority == candidates[j].priority {
+			return candidates[i].alloc.AllocationProto.UUID < candidates[j].alloc.AllocationProto.UUID
+		}
+		return candidates[i].priority < candidates[j].priority
+	})
+	var victims []*inversionVictim
+	for _, candidate := range candidates {
+		// only pick the allocation if it reduces a shortage of one of its parents
+		contributed := false
+		for queue := candidate.queue; queue != nil; queue = queue.parent {
+			shortage := shortages[queue.Name]
+			if shortage == nil {
+				continue
+			}
+			remaining := resources.SubEliminateNegative(shortage, candidate.alloc.AllocatedResource)
+			if !resources.StrictlyGreaterThan(shortage, remaining) {
+				continue
+			}
+			contributed = true
+			if resources.StrictlyGreaterThanZero(remaining) {
+				shortages[queue.Name] = remaining
+			} else {
+				delete(shortages, queue.Name)
+			}
+		}
+		if contributed {
+			victims = append(victims, candidate)
+		}
+		if len(shortages) == 0 {
+			return victims
+		}
+	}
+	return nil
+}
+
+// Get the shortage of the headroom of the queue itself for the resource requested.
+// The resources already reclaimed by preemption for the queue are added to the headroom.
+// Returns nil if the queue has no maximum set or has no shortage.
+func getHeadRoomShortage(queue *SchedulingQueue, res *resources.Resource, reclaimed map[string]*resources.Resource) *resources.Resource {
+	headRoom := applyBorrowLimit(queue.QueueInfo.GetMaxResource(), queue.QueueInfo.GetBorrowMaxResource())
+	if headRoom == nil {
+		return nil
+	}
+	headRoom.SubFrom(queue.getAllocatingResource())
+	headRoom.SubFrom(queue.QueueInfo.GetAllocatedResource())
+	headRoom.AddTo(reclaimed[queue.Name])
+	shortage := resources.SubEliminateNegative(res, headRoom)
+	if !resources.StrictlyGreaterThanZero(shortage) {
+		return nil
+	}
+	return shortage
+}
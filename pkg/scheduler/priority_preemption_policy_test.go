@@ -0,0 +1,358 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// partition for the inversion tests, the structure is:
+// root				max 100
+// - parent			max 10
+//   - high			no max
+//   - low			no max
+//
+// node-1 with 10 used by 2 allocations of 5 in the low queue, node-2 empty.
+// The low queue app is created with the tags passed in.
+func createInversionPartition(t *testing.T, victimPriority int32, victimTags map[string]string) *partitionSchedulingContext {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	var root, parent, high, low *SchedulingQueue
+	root, err = createRootQueue(map[string]string{"first": "100"})
+	assert.NilError(t, err, "failed to create root queue")
+	partition.root = root
+	parent, err = createManagedQueue(root, "parent", true, map[string]string{"first": "10"})
+	assert.NilError(t, err, "failed to create parent queue")
+	high, err = createManagedQueue(parent, "high", false, nil)
+	assert.NilError(t, err, "failed to create high queue")
+	low, err = createManagedQueue(parent, "low", false, nil)
+	assert.NilError(t, err, "failed to create low queue")
+
+	nodeRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	partition.addSchedulingNode(cache.NewNodeForTest("node-1", nodeRes))
+	partition.addSchedulingNode(cache.NewNodeForTest("node-2", nodeRes))
+	node := partition.getSchedulingNode("node-1")
+	allocRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+
+	// low priority app using all the parent headroom
+	lowApp := newSchedulingApplication(cache.NewApplicationInfo("app-low", "default", low.Name, security.UserGroup{}, victimTags))
+	lowApp.queue = low
+	low.addSchedulingApplication(lowApp)
+	partition.applications["app-low"] = lowApp
+	for _, uuid := range []string{"uuid-1", "uuid-2"} {
+		alloc := cache.CreateMockAllocationInfo("app-low", allocRes, uuid, low.Name, "node-1")
+		alloc.AllocationProto.Priority = &si.Priority{Priority: &si.Priority_PriorityValue{PriorityValue: victimPriority}}
+		cache.AddAllocationToApp(lowApp.ApplicationInfo, alloc)
+		err = low.QueueInfo.IncAllocatedResource(allocRes, false)
+		assert.NilError(t, err, "failed to set allocated resource on queue")
+		node.nodeInfo.AddAllocation(alloc)
+	}
+
+	// high priority app with a pending ask
+	highApp := newSchedulingApplication(cache.NewApplicationInfo("app-high", "default", high.Name, security.UserGroup{}, nil))
+	highApp.queue = high
+	high.addSchedulingApplication(highApp)
+	partition.applications["app-high"] = highApp
+	ask := newAllocationAsk("alloc-high", "app-high", allocRes)
+	ask.priority = 10
+	_, err = highApp.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	return partition
+}
+
+func TestPriorityInversion(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	releases, _, _ := resolvePriorityInversion(partition)
+	// only one allocation should be preempted: exactly the parent shortage
+	assert.Equal(t, len(releases), 1, "expected one allocation to be preempted")
+	assert.Equal(t, releases[0].UUID, "uuid-1", "expected first allocation in order to be preempted")
+	assert.Equal(t, releases[0].ApplicationID, "app-low", "expected low priority app to be preempted")
+	assert.Equal(t, releases[0].ReleaseType, si.AllocationReleaseResponse_PREEMPTED_BY_SCHEDULER, "unexpected release type")
+	node := partition.getSchedulingNode("node-1")
+	assert.Assert(t, resources.Equals(node.getPreemptingResource(), resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})),
+		"preempting resource not set on node")
+}
+
+func TestPriorityInversionNoVictims(t *testing.T) {
+	// same priority is not an inversion
+	partition := createInversionPartition(t, 10, nil)
+	releases, _, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 0, "same priority allocations should not be preempted")
+
+	// no node fits the ask: blocked by more than the headroom
+	partition = createInversionPartition(t, 1, nil)
+	partition.removeSchedulingNode("node-2")
+	releases, _, _ = resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 0, "ask not fitting on a node should not preempt")
+}
+
+func TestPriorityInversionPreemptionDisabled(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	low := partition.getQueue("root.parent.low")
+	cache.SetPreemptionDisabled(low.QueueInfo, true)
+	releases, _, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 0, "allocations of a queue with preemption disabled should not be preempted")
+}
+
+func TestPriorityInversionLargerShortage(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	// high queue already uses 1: the parent shortage is 6 and needs both allocations
+	high := partition.getQueue("root.parent.high")
+	err := high.QueueInfo.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1}), true)
+	assert.NilError(t, err, "failed to set allocated resource on queue")
+	releases, _, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 2, "parent shortage of 6 should preempt both allocations")
+}
+
+func TestPriorityInversionPreferCheckpoint(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	// add a checkpointable app in the low queue: parent shortage becomes 10 and needs two allocations
+	low := partition.getQueue("root.parent.low")
+	tags := map[string]string{CheckpointApplicationTag: "true"}
+	ckptApp := newSchedulingApplication(cache.NewApplicationInfo("app-ckpt", "default", low.Name, security.UserGroup{}, tags))
+	ckptApp.queue = low
+	low.addSchedulingApplication(ckptApp)
+	partition.applications["app-ckpt"] = ckptApp
+	allocRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	alloc := cache.CreateMockAllocationInfo("app-ckpt", allocRes, "uuid-3", low.Name, "node-2")
+	alloc.AllocationProto.Priority = &si.Priority{Priority: &si.Priority_PriorityValue{PriorityValue: 1}}
+	cache.AddAllocationToApp(ckptApp.ApplicationInfo, alloc)
+	err := low.QueueInfo.IncAllocatedResource(allocRes, true)
+	assert.NilError(t, err, "failed to set allocated resource on queue")
+	partition.getSchedulingNode("node-2").nodeInfo.AddAllocation(alloc)
+
+	// no grace period: released directly, checkpointable allocation first
+	releases, notifications, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(notifications), 0, "no notifications expected without grace period")
+	assert.Equal(t, len(releases), 2, "parent shortage of 10 should preempt two allocations")
+	assert.Equal(t, releases[0].UUID, "uuid-3", "expected checkpointable allocation to be preempted first")
+	assert.Equal(t, releases[1].UUID, "uuid-1", "expected lowest UUID to be preempted second")
+}
+
+func TestPriorityInversionGracePeriod(t *testing.T) {
+	partition := createInversionPartition(t, 1, map[string]string{CheckpointApplicationTag: "true"})
+	cache.SetPreemptionGracePeriod(partition.partition, time.Minute)
+	releases, notifications, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 0, "checkpointable allocation should not be released before the grace period")
+	assert.Equal(t, len(notifications), 1, "expected one preemption notification")
+	assert.Equal(t, notifications[0].UUID, "uuid-1", "unexpected allocation in notification")
+	assert.Equal(t, notifications[0].GracePeriod, time.Minute, "unexpected grace period in notification")
+	assert.Equal(t, len(partition.getPendingPreemptions()), 1, "expected pending preemption")
+
+	// next run must not preempt again for the same ask
+	releases, notifications, _ = resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases)+len(notifications), 0, "pending preemption should cover the shortage")
+	assert.Equal(t, len(partition.getExpiredPreemptions(time.Now())), 0, "grace period has not passed yet")
+
+	// grace period passed: release is issued once
+	releases = partition.getExpiredPreemptions(time.Now().Add(time.Minute))
+	assert.Equal(t, len(releases), 1, "expected release after the grace period")
+	assert.Equal(t, releases[0].UUID, "uuid-1", "unexpected allocation released")
+	assert.Equal(t, len(partition.getPendingPreemptions()), 0, "pending preemption should be removed")
+}
+
+func TestPriorityInversionIntentRMUnits(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	cache.SetPreemptionConfirmTimeout(partition.partition, time.Minute)
+	// the RM uses a unit of 5 scheduler units: the intent is sent in RM units
+	cache.SetRMUnitFactors(partition.partition, map[string]int64{"first": 5})
+	_, _, intents := resolvePriorityInversion(partition)
+	assert.Equal(t, len(intents), 1, "expected one preemption intent")
+	assert.Assert(t, resources.Equals(resources.NewResourceFromProto(intents[0].Resource), resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})), "intent resource not in RM units")
+}
+
+func TestPriorityInversionGracePeriodReleased(t *testing.T) {
+	partition := createInversionPartition(t, 1, map[string]string{CheckpointApplicationTag: "true"})
+	cache.SetPreemptionGracePeriod(partition.partition, time.Minute)
+	_, notifications, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(notifications), 1, "expected one preemption notification")
+	node := partition.getSchedulingNode("node-1")
+	assert.Assert(t, !resources.IsZero(node.getPreemptingResource()), "preempting resource not set on node")
+
+	// application removed by the RM during the grace period: no release, preempting reset
+	delete(partition.applications, "app-low")
+	releases := partition.getExpiredPreemptions(time.Now().Add(time.Minute))
+	assert.Equal(t, len(releases), 0, "released allocation should not be released again")
+	assert.Assert(t, resources.IsZero(node.getPreemptingResource()), "preempting resource not reset on node")
+}
+
+func TestPriorityInversionConfirm(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	cache.SetPreemptionConfirmTimeout(partition.partition, time.Minute)
+	releases, notifications, intents := resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases)+len(notifications), 0, "allocation should not be released before the RM confirmed")
+	assert.Equal(t, len(intents), 1, "expected one preemption intent")
+	assert.Equal(t, intents[0].UUID, "uuid-1", "unexpected allocation in intent")
+	assert.Equal(t, intents[0].Timeout, time.Minute, "unexpected timeout in intent")
+	assert.Equal(t, intents[0].PartitionName, partition.Name, "unexpected partition in intent")
+	assert.Assert(t, resources.Equals(resources.NewResourceFromProto(intents[0].Resource), resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})), "unexpected resource in intent")
+
+	// next run must not preempt again for the same ask
+	releases, notifications, intents = resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases)+len(notifications)+len(intents), 0, "pending intent should cover the shortage")
+
+	// unknown allocation is ignored, accepted intent is released once
+	releases = partition.confirmPreemption(&api.PreemptionResponse{UUID: "unknown", Accepted: true})
+	assert.Equal(t, len(releases), 0, "response without intent should be ignored")
+	releases = partition.confirmPreemption(&api.PreemptionResponse{UUID: "uuid-1", Accepted: true})
+	assert.Equal(t, len(releases), 1, "expected release after the RM accepted")
+	assert.Equal(t, releases[0].UUID, "uuid-1", "unexpected allocation released")
+	assert.Equal(t, len(partition.getPendingPreemptions()), 0, "pending intent should be removed")
+	releases = partition.confirmPreemption(&api.PreemptionResponse{UUID: "uuid-1", Accepted: true})
+	assert.Equal(t, len(releases), 0, "second response should be ignored")
+}
+
+func TestPriorityInversionConfirmDeclined(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	cache.SetPreemptionConfirmTimeout(partition.partition, time.Minute)
+	_, _, intents := resolvePriorityInversion(partition)
+	assert.Equal(t, len(intents), 1, "expected one preemption intent")
+	node := partition.getSchedulingNode("node-1")
+	assert.Assert(t, !resources.IsZero(node.getPreemptingResource()), "preempting resource not set on node")
+
+	// declined without alternates: nothing is released, preempting reset
+	releases := partition.confirmPreemption(&api.PreemptionResponse{UUID: "uuid-1"})
+	assert.Equal(t, len(releases), 0, "declined intent should not be released")
+	assert.Assert(t, resources.IsZero(node.getPreemptingResource()), "preempting resource not reset on node")
+	assert.Equal(t, len(partition.getExpiredPreemptions(time.Now().Add(time.Minute))), 0, "declined intent should not time out")
+}
+
+func TestPriorityInversionConfirmAlternates(t *testing.T) {
+	// alternates that are valid are released instead, otherwise the allocation is released
+	tests := map[string]struct {
+		alternates []string
+		released   string
+	}{
+		"valid":      {[]string{"uuid-2"}, "uuid-2"},
+		"unknown":    {[]string{"unknown"}, "uuid-1"},
+		"preempted":  {[]string{"uuid-1"}, "uuid-1"},
+		"duplicates": {[]string{"uuid-2", "uuid-2"}, "uuid-1"},
+	}
+	for name, test := range tests {
+		partition := createInversionPartition(t, 1, nil)
+		cache.SetPreemptionConfirmTimeout(partition.partition, time.Minute)
+		_, _, intents := resolvePriorityInversion(partition)
+		assert.Equal(t, len(intents), 1, "%s: expected one preemption intent", name)
+		releases := partition.confirmPreemption(&api.PreemptionResponse{UUID: "uuid-1", Alternates: test.alternates})
+		assert.Equal(t, len(releases), 1, "%s: expected one release", name)
+		assert.Equal(t, releases[0].UUID, test.released, "%s: unexpected allocation released", name)
+		allocRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+		assert.Assert(t, resources.Equals(partition.getSchedulingNode("node-1").getPreemptingResource(), allocRes), "%s: unexpected preempting resource on node", name)
+	}
+}
+
+func TestPriorityInversionConfirmAlternatesFiltered(t *testing.T) {
+	// alternates that would not be selected as a victim are rejected: the allocation is released
+	tests := map[string]func(partition *partitionSchedulingContext){
+		"min runtime": func(partition *partitionSchedulingContext) {
+			cache.SetPreemptionMinRuntime(partition.partition, time.Minute, 0)
+			cache.SetAllocationCreateTime(partition.getApplication("app-low").ApplicationInfo.GetAllocation("uuid-2"), time.Now())
+		},
+		"priority": func(partition *partitionSchedulingContext) {
+			alloc := partition.getApplication("app-low").ApplicationInfo.GetAllocation("uuid-2")
+			alloc.AllocationProto.Priority = &si.Priority{Priority: &si.Priority_PriorityValue{PriorityValue: 10}}
+		},
+		"opted out": func(partition *partitionSchedulingContext) {
+			cache.SetPreemptionDisabled(partition.getQueue("root.parent.low").QueueInfo, true)
+		},
+	}
+	for name, update := range tests {
+		partition := createInversionPartition(t, 1, nil)
+		cache.SetPreemptionConfirmTimeout(partition.partition, time.Minute)
+		_, _, intents := resolvePriorityInversion(partition)
+		assert.Equal(t, len(intents), 1, "%s: expected one preemption intent", name)
+		assert.Equal(t, intents[0].UUID, "uuid-1", "%s: unexpected allocation in intent", name)
+		update(partition)
+		releases := partition.confirmPreemption(&api.PreemptionResponse{UUID: "uuid-1", Alternates: []string{"uuid-2"}})
+		assert.Equal(t, len(releases), 1, "%s: expected one release", name)
+		assert.Equal(t, releases[0].UUID, "uuid-1", "%s: alternate should not be released", name)
+	}
+}
+
+func TestPriorityInversionConfirmTimeout(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	cache.SetPreemptionConfirmTimeout(partition.partition, time.Minute)
+	_, _, intents := resolvePriorityInversion(partition)
+	assert.Equal(t, len(intents), 1, "expected one preemption intent")
+	assert.Equal(t, len(partition.getExpiredPreemptions(time.Now())), 0, "timeout has not passed yet")
+
+	// no response before the timeout: the allocation is released and a late response is ignored
+	releases := partition.getExpiredPreemptions(time.Now().Add(time.Minute))
+	assert.Equal(t, len(releases), 1, "expected release after the timeout")
+	assert.Equal(t, releases[0].UUID, "uuid-1", "unexpected allocation released")
+	releases = partition.confirmPreemption(&api.PreemptionResponse{UUID: "uuid-1"})
+	assert.Equal(t, len(releases), 0, "late response should be ignored")
+}
+
+func TestPriorityInversionMinRuntime(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	cache.SetPreemptionMinRuntime(partition.partition, time.Minute, 0)
+	// uuid-1 just started: protected, uuid-2 has run long enough
+	app := partition.getApplication("app-low")
+	cache.SetAllocationCreateTime(app.ApplicationInfo.GetAllocation("uuid-1"), time.Now())
+	cache.SetAllocationCreateTime(app.ApplicationInfo.GetAllocation("uuid-2"), time.Now().Add(-2*time.Minute))
+	releases, _, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 1, "expected one allocation to be preempted")
+	assert.Equal(t, releases[0].UUID, "uuid-2", "protected allocation should not be preempted")
+
+	// both allocations protected: nothing to preempt
+	partition = createInversionPartition(t, 1, nil)
+	cache.SetPreemptionMinRuntime(partition.partition, time.Minute, 0)
+	app = partition.getApplication("app-low")
+	for _, alloc := range app.ApplicationInfo.GetAllAllocations() {
+		cache.SetAllocationCreateTime(alloc, time.Now())
+	}
+	releases, _, _ = resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 0, "protected allocations should not be preempted")
+
+	// emergency priority of the ask overrides the protection
+	cache.SetPreemptionMinRuntime(partition.partition, time.Minute, 10)
+	releases, _, _ = resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 1, "emergency priority should preempt protected allocations")
+}
+
+func TestPriorityInversionSettlePeriod(t *testing.T) {
+	partition := createInversionPartition(t, 1, nil)
+	cache.SetPreemptionSettlePeriod(partition.partition, time.Minute)
+	// the limits of the parent just changed: the low queue below it is settling
+	parent := partition.getQueue("root.parent")
+	cache.SetLimitsChangeTime(parent.QueueInfo, time.Now())
+	releases, _, _ := resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 0, "allocations of a settling queue should not be preempted")
+
+	// the settle period has passed
+	cache.SetLimitsChangeTime(parent.QueueInfo, time.Now().Add(-2*time.Minute))
+	releases, _, _ = resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 1, "expected one allocation to be preempted after the settle period")
+
+	// no settle period configured: a change of the limits does not protect the queue
+	partition = createInversionPartition(t, 1, nil)
+	cache.SetLimitsChangeTime(partition.getQueue("root.parent.low").QueueInfo, time.Now())
+	releases, _, _ = resolvePriorityInversion(partition)
+	assert.Equal(t, len(releases), 1, "expected one allocation to be preempted without a settle period")
+}
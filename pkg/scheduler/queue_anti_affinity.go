@@ -0,0 +1,64 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"strings"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+)
+
+// Get the queues the allocations of the queue avoid sharing a node with and if the anti affinity is hard, see
+// cache.QueueAntiAffinity. Queues that cover this queue are ignored: a queue cannot avoid itself.
+// Returns nil if the queue has no anti affinity.
+func (sq *SchedulingQueue) getAntiAffinity() ([]string, bool) {
+	if sq == nil || sq.QueueInfo == nil {
+		return nil, false
+	}
+	queues, hard := sq.QueueInfo.GetAntiAffinity()
+	if len(queues) == 0 {
+		return nil, false
+	}
+	avoid := make([]string, 0, len(queues))
+	for _, name := range queues {
+		if sq.Name == name || strings.HasPrefix(sq.Name, name+cache.DOT) {
+			continue
+		}
+		avoid = append(avoid, name)
+	}
+	if len(avoid) == 0 {
+		return nil, false
+	}
+	return avoid, hard
+}
+
+// Order the nodes for soft anti affinity: the nodes without allocations of the avoided queues first followed by the
+// nodes with allocations of the avoided queues. The order of the iterator is kept within the two groups.
+func orderForAntiAffinity(nodeIterator NodeIterator, avoid []string) NodeIterator {
+	var preferred, shared []*SchedulingNode
+	for nodeIterator.HasNext() {
+		node := nodeIterator.Next()
+		if node.nodeInfo.HasQueueAllocations(avoid) {
+			shared = append(shared, node)
+		} else {
+			preferred = append(preferred, node)
+		}
+	}
+	return NewDefaultNodeIterator(append(preferred, shared...))
+}
@@ -0,0 +1,81 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestGetAntiAffinity(t *testing.T) {
+	var nilQueue *SchedulingQueue
+	avoid, hard := nilQueue.getAntiAffinity()
+	assert.Assert(t, avoid == nil && !hard, "nil queue should not have anti affinity")
+
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	conf := configs.QueueConfig{
+		Name: "noisy",
+		Properties: map[string]string{
+			cache.QueueAntiAffinity:     "root.batch,root.noisy,root",
+			cache.QueueAntiAffinityMode: cache.AntiAffinityHard,
+		},
+	}
+	var info *cache.QueueInfo
+	info, err = cache.NewManagedQueue(conf, root.QueueInfo)
+	assert.NilError(t, err, "failed to create leaf queue")
+	leaf := newSchedulingQueueInfo(info, root)
+	// the queue itself and the root cover the queue and are ignored
+	avoid, hard = leaf.getAntiAffinity()
+	assert.DeepEqual(t, avoid, []string{"root.batch"})
+	assert.Assert(t, hard, "anti affinity should be hard")
+
+	conf.Name = "self"
+	conf.Properties[cache.QueueAntiAffinity] = "root.self"
+	info, err = cache.NewManagedQueue(conf, root.QueueInfo)
+	assert.NilError(t, err, "failed to create leaf queue")
+	leaf = newSchedulingQueueInfo(info, root)
+	avoid, hard = leaf.getAntiAffinity()
+	assert.Assert(t, avoid == nil && !hard, "queue should not avoid itself")
+}
+
+func TestOrderForAntiAffinity(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	nodes := []*SchedulingNode{
+		newNode("node-1", map[string]resources.Quantity{"first": 10}),
+		newNode("node-2", map[string]resources.Quantity{"first": 10}),
+		newNode("node-3", map[string]resources.Quantity{"first": 10}),
+		newNode("node-4", map[string]resources.Quantity{"first": 10}),
+	}
+	nodes[0].nodeInfo.AddAllocation(cache.CreateMockAllocationInfo("app-1", res, "uuid-1", "root.batch.daily", "node-1"))
+	nodes[2].nodeInfo.AddAllocation(cache.CreateMockAllocationInfo("app-2", res, "uuid-2", "root.other", "node-3"))
+	nodes[3].nodeInfo.AddAllocation(cache.CreateMockAllocationInfo("app-3", res, "uuid-3", "root.batch", "node-4"))
+
+	iterator := orderForAntiAffinity(NewDefaultNodeIterator(nodes), []string{"root.batch"})
+	var order []string
+	for iterator.HasNext() {
+		order = append(order, iterator.Next().NodeID)
+	}
+	assert.DeepEqual(t, order, []string{"node-2", "node-3", "node-1", "node-4"})
+}
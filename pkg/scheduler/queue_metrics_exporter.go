@@ -0,0 +1,91 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
+)
+
+// Interval at which the queue metrics are exported.
+const queueMetricsInterval = time.Second
+
+// Export the queue metrics outside of the scheduling path. The used resources of the queues are read from the cache
+// on each tick and set in the metrics, instead of updating the metrics while the allocation holds the queue locks.
+// The exported values can lag the allocations by up to the interval.
+type queueMetricsExporter struct {
+	done      chan bool
+	ticker    *time.Ticker
+	scheduler *Scheduler
+}
+
+func newQueueMetricsExporter(scheduler *Scheduler, interval time.Duration) *queueMetricsExporter {
+	return &queueMetricsExporter{
+		done:      make(chan bool),
+		ticker:    time.NewTicker(interval),
+		scheduler: scheduler,
+	}
+}
+
+func (e *queueMetricsExporter) start() {
+	go func() {
+		for {
+			select {
+			case <-e.done:
+				e.ticker.Stop()
+				return
+			case <-e.ticker.C:
+				e.runOnce()
+			}
+		}
+	}()
+}
+
+func (e *queueMetricsExporter) runOnce() {
+	for _, p := range e.scheduler.GetClusterSchedulingContext().getPartitionMapClone() {
+		exportQueueMetrics(p.partition.Root)
+	}
+}
+
+// Export the metrics of the queue and all queues below it. Only leaf queues export the used resources.
+// Lock free call all locks are taken when needed in called functions
+func exportQueueMetrics(queue *cache.QueueInfo) {
+	if queue == nil {
+		return
+	}
+	if queue.IsLeafQueue() {
+		queueMetrics := metrics.GetQueueMetrics(queue.GetQueuePath())
+		for name, value := range queue.GetAllocatedResource().Resources {
+			queueMetrics.SetQueueUsedResourceMetrics(name, float64(value))
+		}
+		return
+	}
+	for _, child := range queue.GetCopyOfChildren() {
+		exportQueueMetrics(child)
+	}
+}
+
+// Stop the queue metrics exporter.
+//
+//nolint:unused
+func (e *queueMetricsExporter) stop() {
+	e.done <- true
+}
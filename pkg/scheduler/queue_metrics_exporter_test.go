@@ -0,0 +1,77 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestExportQueueMetrics(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create basic root queue")
+	var parent, leaf *SchedulingQueue
+	parent, err = createManagedQueue(root, "export", true, nil)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = createManagedQueue(parent, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	err = leaf.QueueInfo.IncAllocatedResource(res, false)
+	assert.NilError(t, err, "failed to allocate on the leaf queue")
+
+	leafMetric := "yunikorn_queue_root_export_leaf_used_resource"
+	exportQueueMetrics(root.QueueInfo)
+	value, found := getUsedResourceGauge(t, leafMetric, "first")
+	assert.Assert(t, found, "used resource of the leaf should have been exported")
+	assert.Equal(t, value, float64(5), "unexpected used resource exported")
+	_, found = getUsedResourceGauge(t, "yunikorn_queue_root_export_used_resource", "first")
+	assert.Assert(t, !found, "used resource of a parent should not be exported")
+
+	// nothing is exported inline with the allocation
+	err = leaf.QueueInfo.IncAllocatedResource(res, false)
+	assert.NilError(t, err, "failed to allocate on the leaf queue")
+	value, _ = getUsedResourceGauge(t, leafMetric, "first")
+	assert.Equal(t, value, float64(5), "used resource should not be exported before the exporter runs")
+	exportQueueMetrics(root.QueueInfo)
+	value, _ = getUsedResourceGauge(t, leafMetric, "first")
+	assert.Equal(t, value, float64(10), "used resource should have been updated")
+}
+
+// Get the value of the used resource gauge with the resource label, false if not found.
+func getUsedResourceGauge(t *testing.T, name, resource string) (float64, bool) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NilError(t, err, "failed to gather metrics")
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "resource" && label.GetValue() == resource {
+					return metric.GetGauge().GetValue(), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
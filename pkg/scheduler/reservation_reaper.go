@@ -0,0 +1,184 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// How often the partitions are checked for stale reservations
+var reservationReaperInterval = time.Minute
+
+// Internal start of the stale reservation cleanup service
+func (s *Scheduler) internalReservationReaper() {
+	for {
+		time.Sleep(reservationReaperInterval)
+		s.reapStaleReservations()
+	}
+}
+
+// Remove the stale reservations from all partitions.
+func (s *Scheduler) reapStaleReservations() {
+	for _, psc := range s.clusterSchedulingContext.getPartitionMapClone() {
+		if reaped := psc.reapStaleReservations(); reaped > 0 {
+			metrics.GetSchedulerMetrics().AddReapedReservations(reaped)
+		}
+	}
+}
+
+// Remove the reservations older than the stale age for an ask or node that has been removed.
+// Reservations are removed with the ask, node or application: a stale reservation means that cleanup failed. A leaked
+// reservation blocks the node forever and the queue keeps trying to allocate the reserved application.
+// The reservation counters of the partition and the queues are reconciled with the reservations left on the
+// applications. Returns the number of reservations removed.
+// The partition lock is only held to take a snapshot and to update the counters: the partition is locked while
+// holding the application lock in the scheduling cycle, taking them in the reverse order could deadlock.
+func (psc *partitionSchedulingContext) reapStaleReservations() int {
+	staleAge := psc.partition.GetStaleReservationAge()
+	applications, nodes := psc.getApplicationsAndNodes()
+	reaped := 0
+	// reservations tracked by the applications: this removes the node side too
+	for _, app := range applications {
+		reaped += app.reapStaleReservations(staleAge, nodes)
+	}
+	// reservations on the nodes without a matching reservation on an application in the partition
+	// the expired gang holds are removed from the nodes at the same time, they are not counted as reservations
+	now := time.Now()
+	for _, node := range nodes {
+		node.removeExpiredGangHolds(now)
+		for _, res := range node.getReservationsOlder(staleAge) {
+			if applications[res.appID] == res.app && res.app.hasReservation(node, res.ask) {
+				continue
+			}
+			log.Logger().Warn("removing stale node reservation",
+				zap.String("nodeID", node.NodeID),
+				zap.String("reservationKey", res.getKey()),
+				zap.Duration("age", time.Since(res.created)))
+			if err := node.unReserve(res.app, res.ask); err != nil {
+				log.Logger().Warn("removal of stale node reservation failed",
+					zap.String("nodeID", node.NodeID),
+					zap.String("reservationKey", res.getKey()),
+					zap.Error(err))
+				continue
+			}
+			reaped++
+		}
+	}
+	// reconcile the counters with the reservations left
+	counts := make(map[string]int)
+	for appID, app := range applications {
+		if num := app.getReservationCount(); num > 0 {
+			counts[appID] = num
+		}
+	}
+	psc.reconcileReservedApps(counts)
+	for _, leaf := range psc.root.getLeafQueues() {
+		if leaf.reconcileReservations(counts) {
+			log.Logger().Warn("queue reservation counters out of sync, corrected",
+				zap.String("queueName", leaf.Name))
+		}
+	}
+	if reaped > 0 {
+		log.Logger().Info("stale reservations removed",
+			zap.String("partitionName", psc.Name),
+			zap.Int("reaped", reaped))
+	}
+	return reaped
+}
+
+// Get a copy of the application and node maps of the partition.
+func (psc *partitionSchedulingContext) getApplicationsAndNodes() (map[string]*SchedulingApplication, map[string]*SchedulingNode) {
+	psc.RLock()
+	defer psc.RUnlock()
+	applications := make(map[string]*SchedulingApplication, len(psc.applications))
+	for appID, app := range psc.applications {
+		applications[appID] = app
+	}
+	nodes := make(map[string]*SchedulingNode, len(psc.nodes))
+	for nodeID, node := range psc.nodes {
+		nodes[nodeID] = node
+	}
+	return applications, nodes
+}
+
+// Replace the partition reservation counters with the counts from the applications.
+func (psc *partitionSchedulingContext) reconcileReservedApps(counts map[string]int) {
+	psc.Lock()
+	defer psc.Unlock()
+	if !reflect.DeepEqual(psc.reservedApps, counts) {
+		log.Logger().Warn("partition reservation counters out of sync, corrected",
+			zap.String("partitionName", psc.Name),
+			zap.Any("counted", psc.reservedApps),
+			zap.Any("reservations", counts))
+	}
+	psc.reservedApps = counts
+}
+
+// Get the details of all reservations in the partition, sorted by application, node and ask.
+func (psc *partitionSchedulingContext) getReservationInfos() []*dao.ReservationDAOInfo {
+	applications, _ := psc.getApplicationsAndNodes()
+	now := time.Now()
+	infos := make([]*dao.ReservationDAOInfo, 0)
+	for _, app := range applications {
+		for _, info := range app.getReservationInfos(now) {
+			info.Partition = psc.Name
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].ApplicationID != infos[j].ApplicationID {
+			return infos[i].ApplicationID < infos[j].ApplicationID
+		}
+		if infos[i].NodeID != infos[j].NodeID {
+			return infos[i].NodeID < infos[j].NodeID
+		}
+		return infos[i].AllocationKey < infos[j].AllocationKey
+	})
+	return infos
+}
+
+// Force the removal of a reservation of an application, used by operators to clean up a reservation that blocks
+// scheduling. The reservation is removed even if it is not stale. The queue and partition counters are updated.
+// The partition lock is not held while the application is locked, see reapStaleReservations.
+func (psc *partitionSchedulingContext) expireReservation(appID, nodeID, allocKey string) error {
+	app := psc.getApplication(appID)
+	if app == nil {
+		return fmt.Errorf("application %s not found in partition %s", appID, psc.Name)
+	}
+	if err := app.expireReservation(nodeID, allocKey); err != nil {
+		return err
+	}
+	app.queue.unReserve(appID)
+	psc.unReserveUpdate(appID, 1)
+	log.Logger().Info("reservation expired on request",
+		zap.String("partitionName", psc.Name),
+		zap.String("appID", appID),
+		zap.String("nodeID", nodeID),
+		zap.String("allocationKey", allocKey))
+	return nil
+}
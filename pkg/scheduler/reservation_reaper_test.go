@@ -0,0 +1,146 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+// Create the partition with an app in root.parent.leaf1 that has a reservation for ask alloc-1 on node-1.
+func createReservedPartition(t *testing.T) (*partitionSchedulingContext, *SchedulingApplication) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.parent.leaf1", security.UserGroup{}, nil)
+	app := newSchedulingApplication(appInfo)
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications["app-1"] = app
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	ask := newAllocationAsk("alloc-1", "app-1", res)
+	_, err := app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	node := partition.getSchedulingNode("node-1")
+	partition.reserve(app, node, ask)
+	assert.Equal(t, len(app.GetReservations()), 1, "app should have one reservation")
+	assert.Assert(t, node.isReserved(), "node should be reserved")
+	return partition, app
+}
+
+func assertNoReservations(t *testing.T, partition *partitionSchedulingContext, app *SchedulingApplication) {
+	assert.Equal(t, len(app.GetReservations()), 0, "app should not have reservations")
+	assert.Assert(t, !partition.getSchedulingNode("node-1").isReserved(), "node should not be reserved")
+	assert.Equal(t, len(partition.getReservations()), 0, "partition should not count reservations")
+	assert.Equal(t, len(partition.getQueue("root.parent.leaf1").reservedApps), 0, "queue should not count reservations")
+}
+
+func TestReapValidReservation(t *testing.T) {
+	partition, app := createReservedPartition(t)
+	cache.SetStaleReservationAge(partition.partition, 0)
+	assert.Equal(t, partition.reapStaleReservations(), 0, "valid reservation should not be reaped")
+	assert.Equal(t, len(app.GetReservations()), 1, "app should still have the reservation")
+	assert.Equal(t, partition.getReservations()["app-1"], 1, "partition should count the reservation")
+	assert.Equal(t, partition.getQueue("root.parent.leaf1").reservedApps["app-1"], 1, "queue should count the reservation")
+}
+
+func TestReapRemovedAsk(t *testing.T) {
+	partition, app := createReservedPartition(t)
+	// leak the reservation: remove the ask without the cleanup
+	delete(app.requests, "alloc-1")
+
+	// not old enough
+	cache.SetStaleReservationAge(partition.partition, time.Hour)
+	assert.Equal(t, partition.reapStaleReservations(), 0, "young reservation should not be reaped")
+	assert.Equal(t, len(app.GetReservations()), 1, "app should still have the reservation")
+
+	cache.SetStaleReservationAge(partition.partition, 0)
+	assert.Equal(t, partition.reapStaleReservations(), 1, "stale reservation should be reaped")
+	assertNoReservations(t, partition, app)
+}
+
+func TestReapRemovedNode(t *testing.T) {
+	partition, app := createReservedPartition(t)
+	// leak the reservation: remove the node without the cleanup
+	delete(partition.nodes, "node-1")
+	cache.SetStaleReservationAge(partition.partition, 0)
+	assert.Equal(t, partition.reapStaleReservations(), 1, "stale reservation should be reaped")
+	assert.Equal(t, len(app.GetReservations()), 0, "app should not have reservations")
+	assert.Equal(t, len(partition.getReservations()), 0, "partition should not count reservations")
+	assert.Equal(t, len(partition.getQueue("root.parent.leaf1").reservedApps), 0, "queue should not count reservations")
+}
+
+func TestReapRemovedApp(t *testing.T) {
+	partition, app := createReservedPartition(t)
+	// leak the reservation and the counters: remove the app without the cleanup
+	delete(partition.applications, "app-1")
+	leaf := partition.getQueue("root.parent.leaf1")
+	leaf.removeSchedulingApplication(app)
+	leaf.reservedApps["app-1"] = 1
+	cache.SetStaleReservationAge(partition.partition, 0)
+	assert.Equal(t, partition.reapStaleReservations(), 1, "stale node reservation should be reaped")
+	assert.Assert(t, !partition.getSchedulingNode("node-1").isReserved(), "node should not be reserved")
+	assert.Equal(t, len(partition.getReservations()), 0, "partition should not count reservations")
+	assert.Equal(t, len(leaf.reservedApps), 0, "queue should not count reservations")
+}
+
+func TestReapLeakedCounters(t *testing.T) {
+	partition, app := createReservedPartition(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	// counters out of sync without a stale reservation
+	leaf.reservedApps["app-1"] = 3
+	partition.reservedApps["app-2"] = 1
+	cache.SetStaleReservationAge(partition.partition, 0)
+	assert.Equal(t, partition.reapStaleReservations(), 0, "no reservation should be reaped")
+	assert.Equal(t, len(app.GetReservations()), 1, "app should still have the reservation")
+	assert.DeepEqual(t, partition.getReservations(), map[string]int{"app-1": 1})
+	assert.DeepEqual(t, leaf.reservedApps, map[string]int{"app-1": 1})
+}
+
+func TestGetReservationInfos(t *testing.T) {
+	partition, _ := createReservedPartition(t)
+	infos := partition.getReservationInfos()
+	assert.Equal(t, len(infos), 1, "expected one reservation")
+	assert.Equal(t, infos[0].Partition, partition.Name, "unexpected partition")
+	assert.Equal(t, infos[0].ApplicationID, "app-1", "unexpected application")
+	assert.Equal(t, infos[0].NodeID, "node-1", "unexpected node")
+	assert.Equal(t, infos[0].AllocationKey, "alloc-1", "unexpected ask")
+	assert.Assert(t, infos[0].CreateTime > 0 && infos[0].Age >= 0, "reservation time not set")
+}
+
+func TestExpireReservation(t *testing.T) {
+	partition, app := createReservedPartition(t)
+	err := partition.expireReservation("app-2", "node-1", "alloc-1")
+	assert.Assert(t, err != nil, "unknown app should fail")
+	err = partition.expireReservation("app-1", "node-2", "alloc-1")
+	assert.Assert(t, err != nil, "unknown reservation should fail")
+	assert.Equal(t, len(app.GetReservations()), 1, "app should still have the reservation")
+
+	// valid reservation is removed independent of the age
+	cache.SetStaleReservationAge(partition.partition, time.Hour)
+	err = partition.expireReservation("app-1", "node-1", "alloc-1")
+	assert.NilError(t, err, "expire of reservation failed")
+	assertNoReservations(t, partition, app)
+	assert.Equal(t, len(partition.getReservationInfos()), 0, "no reservations should be listed")
+}
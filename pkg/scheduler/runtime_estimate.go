@@ -0,0 +1,70 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
+)
+
+// Application tag with the estimated runtime of the application, a duration like 10m.
+// The estimate is used by the shortest job first application sorting of a queue. When the application is removed
+// the estimate is compared to the actual runtime to allow operators to evaluate the quality of the estimates.
+const RuntimeEstimateApplicationTag = "application.runtime.estimate"
+
+// Get the runtime estimate from the application tags.
+// An invalid or negative value is logged and ignored, the application will not have an estimate.
+func parseRuntimeEstimate(app *cache.ApplicationInfo) time.Duration {
+	value := app.GetTag(RuntimeEstimateApplicationTag)
+	if value == "" {
+		return 0
+	}
+	estimate, err := time.ParseDuration(value)
+	if err != nil || estimate <= 0 {
+		log.Logger().Warn("invalid application runtime estimate, ignoring tag",
+			zap.String("applicationID", app.ApplicationID),
+			zap.String("tag", RuntimeEstimateApplicationTag),
+			zap.String("value", value))
+		return 0
+	}
+	return estimate
+}
+
+// Compare the runtime estimate of the application with the actual runtime.
+// Applications without an estimate or that never started running are not recorded.
+func recordRuntimeEstimate(app *SchedulingApplication) {
+	if app.runtimeEstimate == 0 {
+		return
+	}
+	startTime := app.ApplicationInfo.GetStartTime()
+	if startTime.IsZero() {
+		return
+	}
+	actual := time.Since(startTime)
+	log.Logger().Debug("application runtime compared to estimate",
+		zap.String("applicationID", app.ApplicationInfo.ApplicationID),
+		zap.Duration("estimate", app.runtimeEstimate),
+		zap.Duration("actual", actual))
+	metrics.GetSchedulerMetrics().ObserveRuntimeEstimate(app.runtimeEstimate, actual)
+}
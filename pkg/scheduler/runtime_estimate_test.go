@@ -0,0 +1,103 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+func TestParseRuntimeEstimate(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"10m", 10 * time.Minute},
+		{"1h30m", 90 * time.Minute},
+		{"10", 0},
+		{"-1m", 0},
+		{"0s", 0},
+	}
+	for _, tt := range tests {
+		tags := map[string]string{RuntimeEstimateApplicationTag: tt.value}
+		app := cache.NewApplicationInfo("app-1", "default", "root.default", security.UserGroup{}, tags)
+		assert.Equal(t, parseRuntimeEstimate(app), tt.expected, "unexpected estimate for '%s'", tt.value)
+	}
+}
+
+func TestRecordRuntimeEstimate(t *testing.T) {
+	metric := "yunikorn_scheduler_app_runtime_estimates"
+	met := getRuntimeEstimateCount(t, metric, "met")
+	exceeded := getRuntimeEstimateCount(t, metric, "exceeded")
+
+	// no estimate or not started: nothing recorded
+	app := newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", "root.default", security.UserGroup{}, nil))
+	startApplication(t, app)
+	recordRuntimeEstimate(app)
+	tags := map[string]string{RuntimeEstimateApplicationTag: "1h"}
+	app = newSchedulingApplication(cache.NewApplicationInfo("app-2", "default", "root.default", security.UserGroup{}, tags))
+	recordRuntimeEstimate(app)
+	assert.Equal(t, getRuntimeEstimateCount(t, metric, "met"), met, "application without estimate or not started should not be recorded")
+
+	// started with a long estimate: met
+	startApplication(t, app)
+	recordRuntimeEstimate(app)
+	assert.Equal(t, getRuntimeEstimateCount(t, metric, "met"), met+1, "application within estimate not recorded")
+
+	// started with a short estimate: exceeded
+	tags = map[string]string{RuntimeEstimateApplicationTag: "1ns"}
+	app = newSchedulingApplication(cache.NewApplicationInfo("app-3", "default", "root.default", security.UserGroup{}, tags))
+	startApplication(t, app)
+	time.Sleep(time.Millisecond)
+	recordRuntimeEstimate(app)
+	assert.Equal(t, getRuntimeEstimateCount(t, metric, "exceeded"), exceeded+1, "application over estimate not recorded")
+}
+
+func startApplication(t *testing.T, app *SchedulingApplication) {
+	err := app.ApplicationInfo.HandleApplicationEvent(cache.AcceptApplication)
+	assert.NilError(t, err, "failed to accept application")
+	err = app.ApplicationInfo.HandleApplicationEvent(cache.RunApplication)
+	assert.NilError(t, err, "failed to run application")
+}
+
+// Get the value of the runtime estimate counter with the result label, 0 if not found.
+func getRuntimeEstimateCount(t *testing.T, name, result string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NilError(t, err, "failed to gather metrics")
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "result" && label.GetValue() == result {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -21,10 +21,12 @@ package scheduler
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 	"github.com/apache/incubator-yunikorn-core/pkg/cache/cacheevent"
 	"github.com/apache/incubator-yunikorn-core/pkg/common"
@@ -32,6 +34,7 @@ import (
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/handler"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
 	"github.com/apache/incubator-yunikorn-core/pkg/plugins"
 	"github.com/apache/incubator-yunikorn-core/pkg/rmproxy/rmevent"
 	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/schedulerevent"
@@ -70,35 +73,45 @@ func (s *Scheduler) StartService(handlers handler.EventHandlers, manualSchedule
 	monitor := newNodesResourceUsageMonitor(s)
 	monitor.start()
 
+	// Export the queue metrics outside of the scheduling path
+	exporter := newQueueMetricsExporter(s, queueMetricsInterval)
+	exporter.start()
+
 	if !manualSchedule {
 		go s.internalSchedule()
 		go s.internalPreemption()
+		go s.internalReservationReaper()
+		go s.internalAutoscaleMonitor()
 	}
 }
 
 // Create single allocation
 func newSingleAllocationProposal(alloc *schedulingAllocation) *cacheevent.AllocationProposalBundleEvent {
 	return &cacheevent.AllocationProposalBundleEvent{
-		AllocationProposals: []*commonevents.AllocationProposal{
-			{
-				NodeID:            alloc.nodeID,
-				ApplicationID:     alloc.schedulingAsk.ApplicationID,
-				QueueName:         alloc.schedulingAsk.QueueName,
-				AllocatedResource: alloc.schedulingAsk.AllocatedResource,
-				AllocationKey:     alloc.schedulingAsk.AskProto.AllocationKey,
-				Priority:          alloc.schedulingAsk.AskProto.Priority,
-				PartitionName:     alloc.schedulingAsk.PartitionName,
-			},
-		},
-		ReleaseProposals: alloc.releases,
-		PartitionName:    alloc.schedulingAsk.PartitionName,
+		AllocationProposals: []*commonevents.AllocationProposal{newAllocationProposal(alloc)},
+		ReleaseProposals:    alloc.releases,
+		PartitionName:       alloc.schedulingAsk.PartitionName,
+	}
+}
+
+// Create the proposal for the cache from the allocation
+func newAllocationProposal(alloc *schedulingAllocation) *commonevents.AllocationProposal {
+	return &commonevents.AllocationProposal{
+		NodeID:            alloc.nodeID,
+		ApplicationID:     alloc.schedulingAsk.ApplicationID,
+		QueueName:         alloc.schedulingAsk.QueueName,
+		AllocatedResource: alloc.allocatedResource,
+		AllocationKey:     alloc.schedulingAsk.AskProto.AllocationKey,
+		Tags:              alloc.getAllocationTags(),
+		Priority:          alloc.schedulingAsk.getAllocationPriority(),
+		PartitionName:     alloc.schedulingAsk.PartitionName,
 	}
 }
 
 // Internal start scheduling service
 func (s *Scheduler) internalSchedule() {
 	for {
-		s.schedule()
+		s.schedule(maxAllocationsPerCycle)
 	}
 }
 
@@ -114,7 +127,76 @@ func (s *Scheduler) updateSchedulingRequest(schedulingAsk *schedulingAllocationA
 	// Get SchedulingApplication
 	app := s.clusterSchedulingContext.GetSchedulingApplication(schedulingAsk.ApplicationID, schedulingAsk.PartitionName)
 	if app == nil {
-		return fmt.Errorf("cannot find scheduling application %s, for allocation %s", schedulingAsk.ApplicationID, schedulingAsk.AskProto.AllocationKey)
+		return api.NewRejectionError(api.RejectionApplicationNotFound, "cannot find scheduling application %s, for allocation %s", schedulingAsk.ApplicationID, schedulingAsk.AskProto.AllocationKey)
+	}
+	// a repeat delta only scales the existing ask, which was validated when it was added
+	if delta, ok, err := schedulingAsk.getRepeatDelta(); ok {
+		if err != nil {
+			return api.NewRejectionError(api.RejectionInvalidResource, "%v", err)
+		}
+		if _, err = app.changeAskRepeat(schedulingAsk.AskProto.AllocationKey, delta); err != nil {
+			return api.NewRejectionError(api.RejectionInvalidState, "repeat delta for application %s: %v", schedulingAsk.ApplicationID, err)
+		}
+		return nil
+	}
+	partition := s.clusterSchedulingContext.getPartition(schedulingAsk.PartitionName)
+	var aliases map[string]string
+	var factors map[string]int64
+	if partition != nil {
+		aliases = partition.partition.GetResourceAliases()
+		factors = partition.partition.GetRMUnitFactors()
+	}
+	// fill in the resource types the ask does not request before the ask is checked against the limits
+	if queue := app.queue; queue != nil && schedulingAsk.applyDefaultResource(resources.Canonicalize(queue.QueueInfo.GetAskDefaultResource(), aliases)) {
+		log.Logger().Debug("ask default resource applied",
+			zap.String("appID", schedulingAsk.ApplicationID),
+			zap.String("allocationKey", schedulingAsk.AskProto.AllocationKey),
+			zap.String("queueName", queue.Name),
+			zap.String("resource", schedulingAsk.AllocatedResource.String()))
+	}
+	// the RM cannot be sent a partial RM unit: the ask default resource can add one
+	if !resources.IsWholeRMUnits(schedulingAsk.AllocatedResource, factors) {
+		return api.NewRejectionError(api.RejectionInvalidResource, "allocation %s for application %s is not a whole number of RM units: %s",
+			schedulingAsk.AskProto.AllocationKey, schedulingAsk.ApplicationID, schedulingAsk.AllocatedResource.DAOString())
+	}
+	if err := schedulingAsk.parseAlternatives(aliases, factors); err != nil {
+		return api.NewRejectionError(api.RejectionInvalidResource, "%v", err)
+	}
+	if err := schedulingAsk.parseMinimum(aliases, factors); err != nil {
+		return api.NewRejectionError(api.RejectionInvalidResource, "%v", err)
+	}
+	if err := schedulingAsk.parseTolerations(); err != nil {
+		return api.NewRejectionError(api.RejectionInvalidTolerations, "%v", err)
+	}
+	if err := schedulingAsk.parseGang(); err != nil {
+		return api.NewRejectionError(api.RejectionInvalidGang, "%v", err)
+	}
+	if err := schedulingAsk.parseMinNodeAge(); err != nil {
+		return api.NewRejectionError(api.RejectionInvalidNodeAge, "%v", err)
+	}
+	// reject asks that can never be scheduled: they would be pending forever
+	// an ask with alternatives or a minimum is only rejected if none of the shapes can be scheduled
+	if partition != nil && !schedulingAsk.anyShape(partition.isSchedulable) {
+		return api.NewRejectionError(api.RejectionInvalidResource, "allocation %s for application %s can never be scheduled, requested resource %s is larger than the largest node: %s",
+			schedulingAsk.AskProto.AllocationKey, schedulingAsk.ApplicationID, schedulingAsk.AllocatedResource, partition.describeNodeLimit(schedulingAsk.AllocatedResource))
+	}
+	// reject asks that are larger than the configured maximum of the queue: they would be pending forever
+	if queue := app.queue; queue != nil {
+		if limit := queue.getConfiguredMaxResource(); limit != nil && !schedulingAsk.anyShape(func(res *resources.Resource) bool {
+			return resources.FitInDefined(limit, res)
+		}) {
+			return api.NewRejectionError(api.RejectionQuotaExceeded, "allocation %s for application %s can never be scheduled, requested resource %s is larger than the maximum %s of queue %s",
+				schedulingAsk.AskProto.AllocationKey, schedulingAsk.ApplicationID, schedulingAsk.AllocatedResource, limit, queue.Name)
+		}
+		// reject asks that are larger than the max allocation of the queue or its parents: one large ask must not
+		// block a small queue
+		if !schedulingAsk.anyShape(func(res *resources.Resource) bool {
+			return queue.getMaxAllocationLimit(res) == nil
+		}) {
+			limit := queue.getMaxAllocationLimit(schedulingAsk.AllocatedResource)
+			return api.NewRejectionError(api.RejectionMaxAllocation, "allocation %s for application %s is not allowed, requested resource %s is larger than the max allocation %s of queue %s",
+				schedulingAsk.AskProto.AllocationKey, schedulingAsk.ApplicationID, schedulingAsk.AllocatedResource, limit.QueueInfo.GetMaxAllocation(), limit.Name)
+		}
 	}
 
 	// found now update the pending requests for the queue that the app is running in
@@ -145,7 +227,25 @@ func (s *Scheduler) processAllocationProposal(allocProposal *commonevents.Alloca
 		return fmt.Errorf("cannot find scheduling partition %s, for allocation ID %s", allocProposal.PartitionName, allocProposal.AllocationKey)
 	}
 
-	return partition.confirmAllocation(allocProposal.ApplicationID, allocProposal.NodeID, allocProposal.AllocationKey, confirm)
+	return partition.confirmAllocation(allocProposal.ApplicationID, allocProposal.NodeID, allocProposal.AllocationKey, allocProposal.AllocatedResource, confirm)
+}
+
+// Re-queue the ask of an allocation that was rolled back in the cache.
+func (s *Scheduler) rollbackAllocation(alloc *commonevents.AllocationProposal) error {
+	partition := s.clusterSchedulingContext.getPartition(alloc.PartitionName)
+	if partition == nil {
+		return fmt.Errorf("cannot find scheduling partition %s, for allocation ID %s", alloc.PartitionName, alloc.AllocationKey)
+	}
+	return partition.rollbackAllocation(alloc.ApplicationID, alloc.AllocationKey)
+}
+
+// Count the failed allocations in the load of their partitions.
+func (s *Scheduler) recordAllocationFailures(allocs []*commonevents.AllocationProposal) {
+	for _, alloc := range allocs {
+		if partition := s.clusterSchedulingContext.getPartition(alloc.PartitionName); partition != nil {
+			partition.load.recordFailures(1)
+		}
+	}
 }
 
 // When a new app added, invoked by external
@@ -155,6 +255,15 @@ func (s *Scheduler) addNewApplication(info *cache.ApplicationInfo) error {
 	return s.clusterSchedulingContext.addSchedulingApplication(schedulingApp)
 }
 
+// Apply the updated tags of the application to the scheduling application.
+func (s *Scheduler) updateApplication(info *cache.ApplicationInfo) error {
+	partition := s.clusterSchedulingContext.getPartition(info.Partition)
+	if partition == nil {
+		return fmt.Errorf("failed to find partition=%s while updating app=%s", info.Partition, info.ApplicationID)
+	}
+	return partition.updateSchedulingApplication(info.ApplicationID)
+}
+
 func (s *Scheduler) removeApplication(request *si.RemoveApplicationRequest) error {
 	if _, err := s.clusterSchedulingContext.removeSchedulingApplication(request.ApplicationID, request.PartitionName); err != nil {
 		log.Logger().Error("failed to remove apps",
@@ -309,10 +418,9 @@ func (s *Scheduler) processAllocationUpdateEvent(ev *schedulerevent.SchedulerAll
 	}
 
 	// Allocations events cannot contain accepted and rejected allocations at the same time.
-	// There should never be more than one accepted allocation in the list
+	// A batch of allocations on a node can be accepted in one event, confirm them in order
 	// See cluster_info.processAllocationProposalEvent()
-	if len(ev.AcceptedAllocations) > 0 {
-		alloc := ev.AcceptedAllocations[0]
+	for _, alloc := range ev.AcceptedAllocations {
 		// Update pending resource
 		if err := s.confirmAllocationProposal(alloc); err != nil {
 			log.Logger().Error("failed to confirm allocation proposal",
@@ -330,6 +438,23 @@ func (s *Scheduler) processAllocationUpdateEvent(ev *schedulerevent.SchedulerAll
 					zap.Error(err))
 			}
 		}
+		metrics.GetSchedulerMetrics().AddCommitRollbacks(len(ev.RejectedAllocations))
+		s.recordAllocationFailures(ev.RejectedAllocations)
+	}
+
+	// Rolled back allocations were confirmed but never reached the RM, the cache has removed them already.
+	// The asks are re-queued to retry the allocation.
+	if len(ev.RolledBackAllocations) > 0 {
+		for _, alloc := range ev.RolledBackAllocations {
+			if err := s.rollbackAllocation(alloc); err != nil {
+				log.Logger().Warn("failed to re-queue ask of rolled back allocation",
+					zap.String("appID", alloc.ApplicationID),
+					zap.String("allocationKey", alloc.AllocationKey),
+					zap.Error(err))
+			}
+		}
+		metrics.GetSchedulerMetrics().AddDeliveryRollbacks(len(ev.RolledBackAllocations))
+		s.recordAllocationFailures(ev.RolledBackAllocations)
 	}
 
 	// When RM asks to remove some allocations, the event will be send to scheduler first, to release pending asks, etc.
@@ -351,7 +476,7 @@ func (s *Scheduler) processAllocationUpdateEvent(ev *schedulerevent.SchedulerAll
 				rejectedAsks = append(rejectedAsks, &si.RejectedAllocationAsk{
 					AllocationKey: schedulingAsk.AskProto.AllocationKey,
 					ApplicationID: schedulingAsk.ApplicationID,
-					Reason:        err.Error()})
+					Reason:        api.FormatRejectionReason(err)})
 			}
 		}
 
@@ -394,7 +519,7 @@ func (s *Scheduler) processApplicationUpdateEvent(ev *schedulerevent.SchedulerAp
 					})
 				rejectedApps = append(rejectedApps, &si.RejectedApplication{
 					ApplicationID: app.ApplicationID,
-					Reason:        err.Error(),
+					Reason:        api.FormatRejectionReason(err),
 				})
 				// app is rejected by the scheduler
 				err = app.HandleApplicationEvent(cache.RejectApplication)
@@ -412,6 +537,15 @@ func (s *Scheduler) processApplicationUpdateEvent(ev *schedulerevent.SchedulerAp
 					log.Logger().Debug("cache event handling error returned",
 						zap.Error(err))
 				}
+				// allocations recovered from a node before the app was accepted: the app is already running
+				if err == nil && len(app.GetAllAllocations()) > 0 {
+					log.Logger().Info("moving application with existing allocations to running state",
+						zap.String("appID", app.ApplicationID))
+					if err = app.HandleApplicationEvent(cache.RunApplication); err != nil {
+						log.Logger().Warn("unable to handle app event - RunApplication",
+							zap.Error(err))
+					}
+				}
 			}
 		}
 		// notify RM proxy about apps added and rejected
@@ -422,6 +556,21 @@ func (s *Scheduler) processApplicationUpdateEvent(ev *schedulerevent.SchedulerAp
 		})
 	}
 
+	for _, j := range ev.UpdatedApplications {
+		app, ok := j.(*cache.ApplicationInfo)
+		if !ok {
+			log.Logger().Debug("cast failed unexpected object in event",
+				zap.Any("ApplicationInfo", j))
+			continue
+		}
+		if err := s.updateApplication(app); err != nil {
+			log.Logger().Warn("failed to update app in partition",
+				zap.String("appID", app.ApplicationID),
+				zap.String("partitionName", app.Partition),
+				zap.Error(err))
+		}
+	}
+
 	if len(ev.RemovedApplications) > 0 {
 		for _, app := range ev.RemovedApplications {
 			err := s.removeApplication(app)
@@ -533,6 +682,8 @@ func (s *Scheduler) handleSchedulerEvent() {
 			s.processUpdatePartitionConfigsEvent(v)
 		case *schedulerevent.SchedulerDeletePartitionsConfigEvent:
 			s.processDeletePartitionConfigsEvent(v)
+		case *schedulerevent.SchedulerPreemptionResponseEvent:
+			s.processPreemptionResponseEvent(v)
 		default:
 			panic(fmt.Sprintf("%s is not an acceptable type for Scheduler event.", reflect.TypeOf(v).String()))
 		}
@@ -545,40 +696,101 @@ func (s *Scheduler) GetClusterSchedulingContext() *ClusterSchedulingContext {
 }
 
 // The scheduler for testing which runs nAlloc times the normal schedule routine.
+// Each run makes at most one allocation per partition.
 // Visible by tests
 func (s *Scheduler) MultiStepSchedule(nAlloc int) {
 	for i := 0; i < nAlloc; i++ {
-		s.schedule()
+		s.schedule(1)
 	}
 }
 
 // The main scheduling routine.
 // Process each partition in the scheduler, walk over each queue and app to check if anything can be scheduled.
-func (s *Scheduler) schedule() {
-	// schedule each partition defined in the cluster
-	for _, psc := range s.clusterSchedulingContext.getPartitionMapClone() {
-		// if there are no resources in the partition just skip
-		if psc.root.getMaxResource() == nil {
-			continue
+// Partitions are independent and are scheduled in parallel, each partition is protected by its own locks. The
+// cycle finishes when all partitions have been scheduled or the maximum number of allocations for the cycle has been
+// made in each partition.
+func (s *Scheduler) schedule(maxAllocs int) {
+	partitions := s.clusterSchedulingContext.getPartitionMapClone()
+	// a single partition does not need the overhead of a go routine
+	if len(partitions) == 1 {
+		for _, psc := range partitions {
+			s.schedulePartition(psc, maxAllocs)
 		}
+		return
+	}
+	var wg sync.WaitGroup
+	for _, psc := range partitions {
+		wg.Add(1)
+		go func(psc *partitionSchedulingContext) {
+			defer wg.Done()
+			s.schedulePartition(psc, maxAllocs)
+		}(psc)
+	}
+	wg.Wait()
+}
+
+// Try to make allocations in the partition, up to the maximum passed in.
+// The allocations made on the same node are passed to the cache as one batch after the cycle.
+// Lock free call this all locks are taken when needed in called functions
+func (s *Scheduler) schedulePartition(psc *partitionSchedulingContext, maxAllocs int) {
+	// if there are no resources in the partition or the partition is paused just skip
+	if psc.root.getMaxResource() == nil || psc.partition.IsPaused() {
+		return
+	}
+	// the fair shares use the pending resources batched in the last cycle
+	psc.flushPendingBatch()
+	psc.updateFairShares()
+	// the number of allocations in the cycle follows the load of the partition
+	psc.sampleLoad(time.Now())
+	maxAllocs = psc.load.getBatchSize(maxAllocs)
+	psc.cycle.start(maxAllocs)
+	batch := newAllocationBatch()
+	for i := 0; i < maxAllocs; i++ {
 		// try reservations first: gets back a node ID if the allocation occurs on a node
 		// that was not reserved by the app/ask
 		alloc := psc.tryReservedAllocate()
-		// nothing reserved that can be allocated try normal allocate
+		// nothing reserved that can be allocated try placement only asks, they do not need any headroom
+		if alloc == nil {
+			alloc = psc.tryPlacementAllocate()
+		}
+		// nothing reserved or placed that can be allocated try normal allocate
 		if alloc == nil {
 			alloc = psc.tryAllocate()
 		}
-		// there is an allocation that can be made do the real work in the partition
-		if alloc != nil {
-			// only pass back a real allocation, reservations are just scheduler side
-			// proposal this will return to the scheduler an SchedulerApplicationsUpdateEvent when the
-			// is processed by the cache (this can be a reject or accept)
-			// nodeID is an empty string in all but reserved alloc cases
-			if psc.allocate(alloc) {
-				s.eventHandlers.CacheEventHandler.HandleEvent(newSingleAllocationProposal(alloc))
+		// nothing can be allocated: the cycle is done, this is a failed attempt if there is demand
+		// demand of a queue that reached its cycle cap is not a failure: the queue is skipped on purpose
+		if alloc == nil {
+			if !resources.IsZero(psc.root.GetPendingResource()) && !psc.cycle.anyCapped() {
+				psc.load.recordAttempt(true)
 			}
+			break
+		}
+		// only pass back a real allocation, reservations are just scheduler side
+		// proposal this will return to the scheduler an SchedulerApplicationsUpdateEvent when the
+		// is processed by the cache (this can be a reject or accept)
+		// nodeID is an empty string in all but reserved alloc cases
+		if !psc.allocate(alloc) {
+			// a reservation means the ask did not fit on any node
+			psc.load.recordAttempt(alloc.result == reserved)
+			alloc.release()
+			continue
+		}
+		psc.load.recordAttempt(false)
+		if queue := psc.GetQueue(alloc.schedulingAsk.QueueName); queue != nil {
+			psc.cycle.allocated(queue)
 		}
+		// an allocation that releases other allocations is an all-or-none bundle: never batch it
+		if len(alloc.releases) > 0 {
+			s.eventHandlers.CacheEventHandler.HandleEvent(newSingleAllocationProposal(alloc))
+			alloc.release()
+			continue
+		}
+		batch.add(alloc)
+	}
+	for _, proposal := range batch.proposals() {
+		s.eventHandlers.CacheEventHandler.HandleEvent(proposal)
 	}
+	batch.release()
 }
 
 // Retrieve the app and node to set the allocating resources on when recovering allocations
@@ -19,6 +19,7 @@
 package schedulerevent
 
 import (
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
@@ -28,10 +29,12 @@ import (
 type SchedulerAllocationUpdatesEvent struct {
 	RejectedAllocations []*commonevents.AllocationProposal
 	AcceptedAllocations []*commonevents.AllocationProposal
-	NewAsks             []*si.AllocationAsk
-	ToReleases          *si.AllocationReleasesRequest
-	ExistingAllocations []*si.Allocation // optional, only required during recovery
-	RMId                string           // optional, only required during recovery
+	// allocations committed in the cache that could not be delivered to the RM, the cache has released them
+	RolledBackAllocations []*commonevents.AllocationProposal
+	NewAsks               []*si.AllocationAsk
+	ToReleases            *si.AllocationReleasesRequest
+	ExistingAllocations   []*si.Allocation // optional, only required during recovery
+	RMId                  string           // optional, only required during recovery
 }
 
 // From Cache, node updates.
@@ -54,10 +57,18 @@ type PreemptedNodeResource struct {
 // From Cache, update about apps.
 type SchedulerApplicationsUpdateEvent struct {
 	// Type is *cache.ApplicationInfo, avoid cyclic imports
-	AddedApplications   []interface{}
+	AddedApplications []interface{}
+	// Type is *cache.ApplicationInfo, applications with updated tags
+	UpdatedApplications []interface{}
 	RemovedApplications []*si.RemoveApplicationRequest
 }
 
+// From the RM, responses to the preemption intents.
+type SchedulerPreemptionResponseEvent struct {
+	RmID      string
+	Responses []*api.PreemptionResponse
+}
+
 type SchedulerUpdatePartitionsConfigEvent struct {
 	// Type is *cache.PartitionInfo, avoid cyclic imports
 	UpdatedPartitions []interface{}
@@ -20,8 +20,11 @@ package scheduler
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/common/commonevents"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 )
 
 type allocationResult int
@@ -39,23 +42,42 @@ func (ar allocationResult) String() string {
 }
 
 type schedulingAllocation struct {
-	schedulingAsk  *schedulingAllocationAsk
-	repeats        int32
-	nodeID         string
-	reservedNodeID string
-	releases       []*commonevents.ReleaseAllocation
-	result         allocationResult
+	schedulingAsk     *schedulingAllocationAsk
+	repeats           int32
+	nodeID            string
+	reservedNodeID    string
+	releases          []*commonevents.ReleaseAllocation
+	result            allocationResult
+	shape             int                 // shape of the ask allocated: 0 is the requested resource, 1 and up an alternative or the range
+	allocatedResource *resources.Resource // resource of the shape allocated
 }
 
+// Get a new allocation proposal from the pool, see release.
 func newSchedulingAllocation(ask *schedulingAllocationAsk, nodeID string) *schedulingAllocation {
-	return &schedulingAllocation{
-		schedulingAsk: ask,
-		nodeID:        nodeID,
-		repeats:       1,
-		result:        none,
-	}
+	alloc := schedulingAllocationPool.Get().(*schedulingAllocation)
+	alloc.schedulingAsk = ask
+	alloc.nodeID = nodeID
+	alloc.repeats = 1
+	alloc.result = none
+	alloc.allocatedResource = ask.AllocatedResource
+	return alloc
 }
 
 func (sa *schedulingAllocation) String() string {
 	return fmt.Sprintf("AllocatioKey=%s, repeats=%d, node=%s, result=%s", sa.schedulingAsk.AskProto.AllocationKey, sa.repeats, sa.nodeID, sa.result.String())
 }
+
+// Return the tags for the allocation: the alternative tag is only set if an alternative shape of the ask was allocated.
+// The granted tag is set if the ask was scaled down, it is not set if the desired resource was granted in the range.
+func (sa *schedulingAllocation) getAllocationTags() map[string]string {
+	if sa.shape == 0 {
+		return nil
+	}
+	if sa.schedulingAsk.isRangeShape(sa.shape) {
+		if resources.Equals(sa.allocatedResource, sa.schedulingAsk.AllocatedResource) {
+			return nil
+		}
+		return map[string]string{GrantedAllocationTag: strings.TrimPrefix(sa.allocatedResource.String(), "map")}
+	}
+	return map[string]string{AlternativeAllocationTag: strconv.Itoa(sa.shape)}
+}
@@ -19,14 +19,46 @@
 package scheduler
 
 import (
-	"sync"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/common"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
+// Ask tag with the alternative resource shapes of the ask, tried in order when the requested resource does not fit.
+// The value is a semicolon separated list of resources in the canonical resource string format,
+// for example: "[gpu:1 vcore:4000];[vcore:8000]".
+const AlternativesAskTag = "resource.alternatives"
+
+// Allocation tag set when an alternative shape of the ask was allocated. The value is the position of the alternative
+// in the list of the ask tag, starting at 1. The tag is not set when the requested resource was allocated.
+const AlternativeAllocationTag = "resource.alternative"
+
+// Ask tag with the minimum resource of the ask, the requested resource of the ask is the desired resource.
+// The ask can be allocated with any resource between the minimum and the desired resource, depending on the resources
+// available on the node and the headroom of the queue. The value uses the canonical resource string format, all types
+// must be part of the requested resource, for example: "[memory:512 vcore:500]".
+const MinimumAskTag = "resource.minimum"
+
+// Allocation tag set when the ask was scaled down below the desired resource. The value is the resource granted in the
+// canonical resource string format.
+const GrantedAllocationTag = "resource.granted"
+
+// Ask tag that turns the update of an existing ask into a change of its pending repeat, like the executor count of a
+// job. The value is the signed number of allocations to add to or remove from the pending repeat, for example "3" or
+// "-2". The pending repeat does not go below zero. The resource and repeat of the update itself are ignored.
+const RepeatDeltaAskTag = "repeat.delta"
+
+// Ask tag with the minimum time the node must have been registered and schedulable before the ask can be allocated on
+// it, a duration like "2m". Nodes that just joined the cluster may still be starting daemons the ask depends on.
+const MinNodeAgeAskTag = "node.min.age"
+
 type schedulingAllocationAsk struct {
 	// Original ask
 	AskProto *si.AllocationAsk
@@ -37,12 +69,28 @@ type schedulingAllocationAsk struct {
 	PartitionName     string
 	QueueName         string
 
+	// Alternative resource shapes in order of preference, parsed from the ask tags.
+	// Pending resources are always tracked using the requested resource.
+	alternatives []*resources.Resource
+	// Minimum resource of the ask parsed from the ask tags, nil if the ask cannot be scaled down.
+	// If set the ask has one extra shape after the alternatives: the range between the minimum and the requested resource.
+	minimum *resources.Resource
+	// Scheduler units in one RM unit per resource type: the range shape is scaled in whole RM units.
+	rmUnitFactors map[string]int64
+	// Node taints tolerated by the ask parsed from the ask tags.
+	tolerations []taintToleration
+	// Time the headroom is held for the members of the gang parsed from the ask tags, 0 if the ask is not a gang.
+	gangHoldTime time.Duration
+	// Minimum time the node must have been ready parsed from the ask tags, 0 means any node.
+	minNodeAge time.Duration
+
 	// Private fields need protection
 	createTime       time.Time // the time this ask was created (used in reservations)
 	priority         int32
 	pendingRepeatAsk int32
+	seq              uint64 // order in which the ask was added to the application, set by the askQueue
 
-	sync.RWMutex
+	locking.RWMutex
 }
 
 func newSchedulingAllocationAsk(ask *si.AllocationAsk) *schedulingAllocationAsk {
@@ -102,6 +150,27 @@ func (saa *schedulingAllocationAsk) getPendingAskRepeat() int32 {
 	return saa.pendingRepeatAsk
 }
 
+// Get the repeat delta from the ask tags. Returns false if the tag is not set.
+func (saa *schedulingAllocationAsk) getRepeatDelta() (int32, bool, error) {
+	value, ok := saa.AskProto.GetTags()[RepeatDeltaAskTag]
+	if !ok {
+		return 0, false, nil
+	}
+	delta, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, true, fmt.Errorf("invalid repeat delta '%s' for ask %s: %v", value, saa.AskProto.AllocationKey, err)
+	}
+	return int32(delta), true, nil
+}
+
+// Check if the ask only differs from the existing ask in the repeat: same resource, priority and tags.
+// An update that only changes the repeat is applied to the existing ask to keep its create time and reservations.
+func (saa *schedulingAllocationAsk) isRepeatUpdateOf(existing *schedulingAllocationAsk) bool {
+	return resources.Equals(saa.AllocatedResource, existing.AllocatedResource) &&
+		saa.priority == existing.priority &&
+		reflect.DeepEqual(saa.AskProto.GetTags(), existing.AskProto.GetTags())
+}
+
 // Return the time this ask was created
 // Should be treated as read only not te be modified
 func (saa *schedulingAllocationAsk) getCreateTime() time.Time {
@@ -114,3 +183,220 @@ func (saa *schedulingAllocationAsk) normalizePriority(priority *si.Priority) int
 	// TODO, really normalize priority from ask
 	return priority.GetPriorityValue()
 }
+
+// Check if the ask has a priority value of its own. An empty priority or a priority class name without a value is
+// not a priority: shims that only set the priority on the job send asks like that.
+func (saa *schedulingAllocationAsk) hasPriorityValue() bool {
+	_, ok := saa.AskProto.Priority.GetPriority().(*si.Priority_PriorityValue)
+	return ok
+}
+
+// Return the priority for the allocation of the ask: the priority of the ask unless it was clamped by the queue.
+func (saa *schedulingAllocationAsk) getAllocationPriority() *si.Priority {
+	if saa.priority == saa.AskProto.Priority.GetPriorityValue() {
+		return saa.AskProto.Priority
+	}
+	return &si.Priority{Priority: &si.Priority_PriorityValue{PriorityValue: saa.priority}}
+}
+
+// Merge the default resource of the queue into the requested resource: each type the ask does not request, or
+// requests as zero, gets the default value. Placement only asks are not changed, they request no resources on purpose.
+// Returns true if the requested resource was changed.
+func (saa *schedulingAllocationAsk) applyDefaultResource(defaults *resources.Resource) bool {
+	if defaults == nil || saa.isPlacementOnly() {
+		return false
+	}
+	changed := false
+	for name, value := range defaults.Resources {
+		if saa.AllocatedResource.Resources[name] == 0 && value > 0 {
+			saa.AllocatedResource.Resources[name] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Parse the alternative resource shapes from the ask tags. An ask without the tag has no alternatives.
+// Resource types in the alternatives that are an alias are replaced by the canonical type, the quantities are
+// converted from RM units into scheduler units.
+func (saa *schedulingAllocationAsk) parseAlternatives(aliases map[string]string, factors map[string]int64) error {
+	saa.alternatives = nil
+	value := saa.AskProto.GetTags()[AlternativesAskTag]
+	if value == "" {
+		return nil
+	}
+	if saa.isPlacementOnly() {
+		return fmt.Errorf("placement only ask %s cannot have alternatives", saa.AskProto.AllocationKey)
+	}
+	for i, shape := range strings.Split(value, ";") {
+		res, err := resources.ParseResource(shape)
+		if err != nil {
+			return fmt.Errorf("invalid alternative %d for ask %s: %v", i+1, saa.AskProto.AllocationKey, err)
+		}
+		if !resources.StrictlyGreaterThanZero(res) {
+			return fmt.Errorf("alternative %d for ask %s must be larger than zero: %s", i+1, saa.AskProto.AllocationKey, res.DAOString())
+		}
+		saa.alternatives = append(saa.alternatives, resources.ToSchedulerUnits(resources.Canonicalize(res, aliases), factors))
+	}
+	return nil
+}
+
+// Parse the minimum resource from the ask tags. An ask without the tag cannot be scaled down.
+// Resource types in the minimum that are an alias are replaced by the canonical type, the quantities are converted
+// from RM units into scheduler units.
+func (saa *schedulingAllocationAsk) parseMinimum(aliases map[string]string, factors map[string]int64) error {
+	saa.minimum = nil
+	saa.rmUnitFactors = factors
+	value := saa.AskProto.GetTags()[MinimumAskTag]
+	if value == "" {
+		return nil
+	}
+	res, err := resources.ParseResource(value)
+	if err != nil {
+		return fmt.Errorf("invalid minimum resource for ask %s: %v", saa.AskProto.AllocationKey, err)
+	}
+	res = resources.ToSchedulerUnits(resources.Canonicalize(res, aliases), factors)
+	if !resources.StrictlyGreaterThanZero(res) {
+		return fmt.Errorf("minimum resource for ask %s must be larger than zero: %s", saa.AskProto.AllocationKey, res.DAOString())
+	}
+	for name := range res.Resources {
+		if _, ok := saa.AllocatedResource.Resources[name]; !ok {
+			return fmt.Errorf("minimum resource for ask %s has type %s that is not requested", saa.AskProto.AllocationKey, name)
+		}
+	}
+	if !resources.FitIn(saa.AllocatedResource, res) {
+		return fmt.Errorf("minimum resource for ask %s is larger than the requested resource: %s", saa.AskProto.AllocationKey, res.DAOString())
+	}
+	saa.minimum = res
+	return nil
+}
+
+// Parse the node taint tolerations from the ask tags. An ask without the tag does not tolerate any taint.
+func (saa *schedulingAllocationAsk) parseTolerations() error {
+	saa.tolerations = nil
+	value := saa.AskProto.GetTags()[TolerationsAskTag]
+	if value == "" {
+		return nil
+	}
+	tolerations, err := parseAskTolerations(value)
+	if err != nil {
+		return fmt.Errorf("invalid tolerations for ask %s: %v", saa.AskProto.AllocationKey, err)
+	}
+	saa.tolerations = tolerations
+	return nil
+}
+
+// Parse the gang hold time from the ask tags. An ask without the tag is not a gang.
+func (saa *schedulingAllocationAsk) parseGang() error {
+	holdTime, err := parseGangHoldTime(saa.AskProto.GetTags()[GangAskTag])
+	if err != nil {
+		return fmt.Errorf("invalid gang for ask %s: %v", saa.AskProto.AllocationKey, err)
+	}
+	saa.gangHoldTime = holdTime
+	return nil
+}
+
+// Parse the minimum node age from the ask tags. An ask without the tag can be allocated on any node.
+func (saa *schedulingAllocationAsk) parseMinNodeAge() error {
+	saa.minNodeAge = 0
+	value := saa.AskProto.GetTags()[MinNodeAgeAskTag]
+	if value == "" {
+		return nil
+	}
+	age, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || age < 0 {
+		return fmt.Errorf("invalid minimum node age for ask %s: %s", saa.AskProto.AllocationKey, value)
+	}
+	saa.minNodeAge = age
+	return nil
+}
+
+// Can the ask be allocated on the node based on the time the node has been ready.
+func (saa *schedulingAllocationAsk) acceptsNodeAge(node *SchedulingNode) bool {
+	if saa.minNodeAge == 0 {
+		return true
+	}
+	return time.Since(node.nodeInfo.GetReadySince()) >= saa.minNodeAge
+}
+
+// Can the ask be allocated on the node based on the taints of the node.
+func (saa *schedulingAllocationAsk) toleratesNode(node *SchedulingNode) bool {
+	return toleratesTaints(saa.tolerations, node.taints)
+}
+
+// Return the resource of the shape: 0 is the requested resource, 1 and up are the alternatives in order.
+// For the range shape, the last shape if a minimum is set, the minimum is returned: the smallest resource the shape
+// can be allocated with.
+func (saa *schedulingAllocationAsk) getShape(shape int) *resources.Resource {
+	if shape == 0 {
+		return saa.AllocatedResource
+	}
+	if saa.isRangeShape(shape) {
+		return saa.minimum
+	}
+	return saa.alternatives[shape-1]
+}
+
+// Is the shape the range between the minimum and the requested resource?
+func (saa *schedulingAllocationAsk) isRangeShape(shape int) bool {
+	return saa.minimum != nil && shape == len(saa.alternatives)+1
+}
+
+// Return the resource to allocate for the range shape: the requested resource scaled down to fit in all limits.
+// A nil limit does not limit the resource, a type missing from a limit is treated as zero. The scaled down resource is
+// rounded down to whole RM units: a partial RM unit cannot be sent to the RM.
+// Returns nil if the scaled down resource is smaller than the minimum.
+func (saa *schedulingAllocationAsk) getScaledResource(limits ...*resources.Resource) *resources.Resource {
+	if saa.minimum == nil {
+		return nil
+	}
+	scaled := saa.AllocatedResource.Clone()
+	for _, limit := range limits {
+		if limit == nil {
+			continue
+		}
+		for name, quantity := range scaled.Resources {
+			scaled.Resources[name] = resources.MinQuantity(quantity, resources.MaxQuantity(limit.Resources[name], 0))
+		}
+	}
+	scaled = resources.RoundDownToRMUnits(scaled, saa.rmUnitFactors)
+	if !resources.FitIn(scaled, saa.minimum) {
+		return nil
+	}
+	return scaled
+}
+
+// Return all shapes of the ask in the order they must be tried.
+// The range shape, if the ask has a minimum, is always tried last.
+func (saa *schedulingAllocationAsk) getShapes() []int {
+	count := len(saa.alternatives) + 1
+	if saa.minimum != nil {
+		count++
+	}
+	shapes := make([]int, count)
+	for i := range shapes {
+		shapes[i] = i
+	}
+	return shapes
+}
+
+// Return the shapes of the ask that fit in the headroom in the order they must be tried.
+func (saa *schedulingAllocationAsk) getShapesFitIn(headRoom *resources.Resource) []int {
+	var shapes []int
+	for _, shape := range saa.getShapes() {
+		if resources.FitIn(headRoom, saa.getShape(shape)) {
+			shapes = append(shapes, shape)
+		}
+	}
+	return shapes
+}
+
+// Return true if the check passes for any of the shapes of the ask.
+func (saa *schedulingAllocationAsk) anyShape(check func(res *resources.Resource) bool) bool {
+	for _, shape := range saa.getShapes() {
+		if check(saa.getShape(shape)) {
+			return true
+		}
+	}
+	return false
+}
@@ -24,6 +24,7 @@ import (
 
 	"gotest.tools/assert"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
@@ -73,3 +74,137 @@ func TestGetCreateTime(t *testing.T) {
 		t.Fatal("create time stamp should have been modified")
 	}
 }
+
+func TestParseAlternatives(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	ask := newAllocationAsk("alloc-1", "app-1", res)
+	assert.NilError(t, ask.parseAlternatives(nil, nil), "ask without alternatives should not fail")
+	assert.DeepEqual(t, ask.getShapes(), []int{0})
+
+	ask.AskProto.Tags = map[string]string{AlternativesAskTag: "[first:5 second:1];[second:8]"}
+	assert.NilError(t, ask.parseAlternatives(nil, nil), "valid alternatives should not fail")
+	assert.DeepEqual(t, ask.getShapes(), []int{0, 1, 2})
+	assert.Assert(t, resources.Equals(ask.getShape(0), res), "shape 0 should be the requested resource")
+	assert.Equal(t, ask.getShape(1).DAOString(), "[first:5 second:1]", "unexpected first alternative")
+	assert.Equal(t, ask.getShape(2).DAOString(), "[second:8]", "unexpected second alternative")
+
+	// aliases are replaced in the alternatives
+	ask.AskProto.Tags = map[string]string{AlternativesAskTag: "[first:5 cpu:1]"}
+	assert.NilError(t, ask.parseAlternatives(map[string]string{"cpu": "second"}, nil), "alternatives with alias should not fail")
+	assert.Equal(t, ask.getShape(1).DAOString(), "[first:5 second:1]", "alias not replaced in alternative")
+	// RM units are converted into scheduler units in the alternatives
+	ask.AskProto.Tags = map[string]string{AlternativesAskTag: "[first:5 second:1]"}
+	assert.NilError(t, ask.parseAlternatives(nil, map[string]int64{"second": 1000}), "alternatives in RM units should not fail")
+	assert.Equal(t, ask.getShape(1).DAOString(), "[first:5 second:1000]", "RM units not converted in alternative")
+	ask.AskProto.Tags = map[string]string{AlternativesAskTag: "[first:5 second:1];[second:8]"}
+	assert.NilError(t, ask.parseAlternatives(nil, nil), "valid alternatives should not fail")
+
+	headRoom := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 6, "second": 10})
+	assert.DeepEqual(t, ask.getShapesFitIn(headRoom), []int{1, 2})
+	assert.Assert(t, ask.anyShape(func(shape *resources.Resource) bool { return resources.FitIn(headRoom, shape) }), "alternatives should fit")
+
+	for _, value := range []string{"[first:lots]", "[first:5];[]", "[first:-1]", "[first:5"} {
+		ask.AskProto.Tags = map[string]string{AlternativesAskTag: value}
+		if err := ask.parseAlternatives(nil, nil); err == nil {
+			t.Errorf("invalid alternatives '%s' should have failed", value)
+		}
+	}
+}
+
+func TestParseMinimum(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 4})
+	ask := newAllocationAsk("alloc-1", "app-1", res)
+	assert.NilError(t, ask.parseMinimum(nil, nil), "ask without minimum should not fail")
+	assert.DeepEqual(t, ask.getShapes(), []int{0})
+	assert.Assert(t, ask.getScaledResource(res) == nil, "ask without minimum cannot be scaled")
+
+	ask.AskProto.Tags = map[string]string{AlternativesAskTag: "[first:8 second:4]", MinimumAskTag: "[first:2 cpu:1]"}
+	assert.NilError(t, ask.parseAlternatives(nil, nil), "valid alternatives should not fail")
+	assert.NilError(t, ask.parseMinimum(map[string]string{"cpu": "second"}, nil), "valid minimum should not fail")
+	assert.DeepEqual(t, ask.getShapes(), []int{0, 1, 2})
+	assert.Assert(t, !ask.isRangeShape(1), "alternative should not be the range shape")
+	assert.Assert(t, ask.isRangeShape(2), "last shape should be the range shape")
+	assert.Equal(t, ask.getShape(2).DAOString(), "[first:2 second:1]", "range shape should return the minimum")
+
+	// scaled down to the smallest limit per type, nil limits are ignored
+	node := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 6, "second": 10})
+	headRoom := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 8, "second": 2})
+	assert.Equal(t, ask.getScaledResource(node, nil, headRoom).DAOString(), "[first:6 second:2]", "unexpected scaled resource")
+	assert.Assert(t, resources.Equals(ask.getScaledResource(nil), res), "unlimited should return the requested resource")
+	// below the minimum, a missing type is zero
+	headRoom = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 8})
+	assert.Assert(t, ask.getScaledResource(node, headRoom) == nil, "below the minimum should not be scaled")
+
+	// the RM uses whole units of 2 for the first type: the scaled resource is rounded down
+	assert.NilError(t, ask.parseMinimum(map[string]string{"cpu": "second"}, map[string]int64{"first": 2}), "valid minimum should not fail")
+	headRoom = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 7, "second": 3})
+	assert.Equal(t, ask.getScaledResource(headRoom).DAOString(), "[first:6 second:3]", "scaled resource not rounded to RM units")
+
+	for _, value := range []string{"[first:lots]", "[first:0]", "[third:1]", "[first:11]", "[first:5"} {
+		ask.AskProto.Tags = map[string]string{MinimumAskTag: value}
+		if err := ask.parseMinimum(nil, nil); err == nil {
+			t.Errorf("invalid minimum '%s' should have failed", value)
+		}
+	}
+}
+
+func TestApplyDefaultResource(t *testing.T) {
+	defaults := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 512, "vcore": 500})
+	ask := newAllocationAsk("alloc-1", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"vcore": 100}))
+	assert.Assert(t, !ask.applyDefaultResource(nil), "nil default should not change the ask")
+	assert.Assert(t, ask.applyDefaultResource(defaults), "missing type should have been set")
+	assert.Equal(t, ask.AllocatedResource.DAOString(), "[memory:512Mi vcore:100m]", "requested type should keep the value of the ask")
+	assert.Assert(t, !ask.applyDefaultResource(defaults), "complete ask should not change")
+
+	// a type requested as zero is missing
+	ask = newAllocationAsk("alloc-2", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 0, "gpu": 1}))
+	assert.Assert(t, ask.applyDefaultResource(defaults), "zero type should have been set")
+	assert.Equal(t, ask.AllocatedResource.DAOString(), "[gpu:1 memory:512Mi vcore:500m]", "unexpected resource")
+
+	// placement only asks request no resources on purpose
+	ask = newAllocationAsk("alloc-3", "app-1", resources.NewResource())
+	assert.Assert(t, !ask.applyDefaultResource(defaults), "placement only ask should not change")
+	assert.Assert(t, ask.isPlacementOnly(), "ask should still be placement only")
+}
+
+func TestParseMinNodeAge(t *testing.T) {
+	ask := newAllocationAsk("alloc-1", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1}))
+	assert.NilError(t, ask.parseMinNodeAge(), "ask without minimum node age should not fail")
+	node := newNode("node-1", map[string]resources.Quantity{"first": 10})
+	assert.Assert(t, ask.acceptsNodeAge(node), "ask without minimum node age should accept a new node")
+
+	ask.AskProto.Tags = map[string]string{MinNodeAgeAskTag: " 30s "}
+	assert.NilError(t, ask.parseMinNodeAge(), "valid minimum node age should not fail")
+	cache.SetNodeReadySince(node.nodeInfo, time.Now())
+	assert.Assert(t, !ask.acceptsNodeAge(node), "new node should not be accepted")
+	cache.SetNodeReadySince(node.nodeInfo, time.Now().Add(-time.Minute))
+	assert.Assert(t, ask.acceptsNodeAge(node), "ready node should be accepted")
+
+	for _, value := range []string{"30", "-1m", "soon"} {
+		ask.AskProto.Tags = map[string]string{MinNodeAgeAskTag: value}
+		if err := ask.parseMinNodeAge(); err == nil {
+			t.Errorf("invalid minimum node age '%s' should have failed", value)
+		}
+	}
+}
+
+func TestRepeatDelta(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	ask := newAllocationAsk("alloc-1", "app-1", res)
+	if _, ok, err := ask.getRepeatDelta(); ok || err != nil {
+		t.Errorf("ask without tag should not have a delta, err %v", err)
+	}
+	for value, expected := range map[string]int32{"3": 3, "-2": -2, " 0 ": 0} {
+		ask.AskProto.Tags = map[string]string{RepeatDeltaAskTag: value}
+		delta, ok, err := ask.getRepeatDelta()
+		if !ok || err != nil || delta != expected {
+			t.Errorf("delta '%s' not parsed, expected %d got %d, err %v", value, expected, delta, err)
+		}
+	}
+	for _, value := range []string{"", "one", "1.5", "3000000000"} {
+		ask.AskProto.Tags = map[string]string{RepeatDeltaAskTag: value}
+		if _, ok, err := ask.getRepeatDelta(); !ok || err == nil {
+			t.Errorf("invalid delta '%s' should have failed", value)
+		}
+	}
+}
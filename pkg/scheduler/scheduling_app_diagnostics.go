@@ -0,0 +1,96 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// Maximum number of diagnostic messages kept per application, the oldest message is dropped first
+const maxAppDiagnostics = 20
+
+// A message about a change the scheduler made to the requests of the application.
+type appDiagnostic struct {
+	time    time.Time
+	message string
+}
+
+// Record a diagnostic message for the application.
+// Lock free call this must be called holding the application lock
+func (sa *SchedulingApplication) addDiagnostic(format string, args ...interface{}) {
+	if len(sa.diagnostics) >= maxAppDiagnostics {
+		sa.diagnostics = sa.diagnostics[1:]
+	}
+	sa.diagnostics = append(sa.diagnostics, appDiagnostic{
+		time:    time.Now(),
+		message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Clamp the priority of the ask to the priority range of the queue of the application.
+// The clamping is recorded in the application diagnostics.
+// Lock free call this must be called holding the application lock
+func (sa *SchedulingApplication) clampAskPriority(ask *schedulingAllocationAsk) {
+	effective := sa.queue.QueueInfo.ClampPriority(ask.priority)
+	if effective == ask.priority {
+		return
+	}
+	sa.addDiagnostic("priority %d of ask %s clamped to %d by queue %s", ask.priority, ask.AskProto.AllocationKey, effective, sa.queue.Name)
+	ask.priority = effective
+}
+
+// Return the requested and effective priority of the asks of the application and the diagnostic messages.
+func (sa *SchedulingApplication) GetPriorityInfo() *dao.ApplicationPriorityDAOInfo {
+	sa.RLock()
+	defer sa.RUnlock()
+	info := &dao.ApplicationPriorityDAOInfo{
+		ApplicationID: sa.ApplicationInfo.ApplicationID,
+		Partition:     sa.ApplicationInfo.Partition,
+		QueueName:     sa.queue.Name,
+		Asks:          make([]dao.AskPriorityDAOInfo, 0, len(sa.requests)),
+		Diagnostics:   make([]dao.DiagnosticDAOInfo, len(sa.diagnostics)),
+	}
+	for _, ask := range sa.requests {
+		info.Asks = append(info.Asks, dao.AskPriorityDAOInfo{
+			AllocationKey:     ask.AskProto.AllocationKey,
+			Priority:          ask.AskProto.Priority.GetPriorityValue(),
+			EffectivePriority: ask.priority,
+		})
+	}
+	sort.Slice(info.Asks, func(i, j int) bool {
+		return info.Asks[i].AllocationKey < info.Asks[j].AllocationKey
+	})
+	// the pending asks in the order the scheduler tries them
+	pending := sa.askQueue.pending()
+	info.SelectionOrder = make([]string, len(pending))
+	for i, ask := range pending {
+		info.SelectionOrder[i] = ask.AskProto.AllocationKey
+	}
+	for i, diagnostic := range sa.diagnostics {
+		info.Diagnostics[i] = dao.DiagnosticDAOInfo{
+			Time:    diagnostic.time.UnixNano(),
+			Message: diagnostic.message,
+		}
+	}
+	return info
+}
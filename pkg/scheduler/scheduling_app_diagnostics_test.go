@@ -0,0 +1,110 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func newAllocationAskPriority(allocKey, appID string, priority int32) *schedulingAllocationAsk {
+	ask := newAllocationAsk(allocKey, appID, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1}))
+	ask.AskProto.Priority = &si.Priority{Priority: &si.Priority_PriorityValue{PriorityValue: priority}}
+	ask.priority = ask.normalizePriority(ask.AskProto.Priority)
+	return ask
+}
+
+func TestClampAskPriority(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	conf := configs.QueueConfig{
+		Name:   "tenant",
+		Parent: true,
+		Properties: map[string]string{
+			cache.ApplicationPriorityFloor:   "-10",
+			cache.ApplicationPriorityCeiling: "10",
+		},
+	}
+	var parentInfo *cache.QueueInfo
+	parentInfo, err = cache.NewManagedQueue(conf, root.QueueInfo)
+	assert.NilError(t, err, "failed to create parent queue")
+	parent := newSchedulingQueueInfo(parentInfo, root)
+	// the unmanaged leaf has no properties: the range of the parent applies
+	var leaf *SchedulingQueue
+	leaf, err = createUnManagedQueue(parent, "leaf", false)
+	assert.NilError(t, err, "failed to create leaf queue")
+
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.tenant.leaf", security.UserGroup{}, nil)
+	app := newSchedulingApplication(appInfo)
+	app.queue = leaf
+	info := app.GetPriorityInfo()
+	assert.Equal(t, len(info.Asks), 0, "new app should not have asks")
+	assert.Equal(t, len(info.Diagnostics), 0, "new app should not have diagnostics")
+
+	for _, ask := range []*schedulingAllocationAsk{
+		newAllocationAskPriority("alloc-high", "app-1", 100),
+		newAllocationAskPriority("alloc-low", "app-1", -100),
+		newAllocationAskPriority("alloc-mid", "app-1", 5),
+	} {
+		_, err = app.addAllocationAsk(ask)
+		assert.NilError(t, err, "failed to add ask %s to app", ask.AskProto.AllocationKey)
+	}
+	info = app.GetPriorityInfo()
+	assert.Equal(t, info.QueueName, "root.tenant.leaf", "unexpected queue")
+	expected := []struct {
+		key                 string
+		priority, effective int32
+	}{
+		{"alloc-high", 100, 10},
+		{"alloc-low", -100, -10},
+		{"alloc-mid", 5, 5},
+	}
+	assert.Equal(t, len(info.Asks), len(expected), "unexpected number of asks")
+	for i, ask := range info.Asks {
+		assert.Equal(t, ask.AllocationKey, expected[i].key, "asks not sorted")
+		assert.Equal(t, ask.Priority, expected[i].priority, "requested priority of %s", ask.AllocationKey)
+		assert.Equal(t, ask.EffectivePriority, expected[i].effective, "effective priority of %s", ask.AllocationKey)
+	}
+	// the selection order uses the effective priority
+	assert.DeepEqual(t, info.SelectionOrder, []string{"alloc-high", "alloc-mid", "alloc-low"})
+	assert.Equal(t, len(info.Diagnostics), 2, "clamped asks should have been recorded")
+	assert.Equal(t, info.Diagnostics[0].Message, "priority 100 of ask alloc-high clamped to 10 by queue root.tenant.leaf")
+	assert.Equal(t, info.Diagnostics[1].Message, "priority -100 of ask alloc-low clamped to -10 by queue root.tenant.leaf")
+
+	// the allocation uses the effective priority, an ask that is not clamped keeps its own priority
+	assert.Equal(t, app.requests["alloc-high"].getAllocationPriority().GetPriorityValue(), int32(10), "allocation priority not clamped")
+	mid := app.requests["alloc-mid"]
+	assert.Equal(t, mid.getAllocationPriority(), mid.AskProto.Priority, "allocation priority should be the ask priority")
+
+	// diagnostics are limited
+	for i := 0; i < maxAppDiagnostics; i++ {
+		_, err = app.addAllocationAsk(newAllocationAskPriority("alloc-high", "app-1", 100))
+		assert.NilError(t, err, "failed to update ask")
+	}
+	info = app.GetPriorityInfo()
+	assert.Equal(t, len(info.Diagnostics), maxAppDiagnostics, "diagnostics not limited")
+	assert.Equal(t, info.Diagnostics[0].Message, "priority 100 of ask alloc-high clamped to 10 by queue root.tenant.leaf")
+}
@@ -0,0 +1,107 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// The scheduling statistics of an application.
+// The statistics are only updated and read while holding the application lock.
+type appStatistics struct {
+	schedulingAttempts  int64     // number of times the application was tried in a scheduling cycle
+	nodesEvaluated      int64     // number of nodes evaluated for all asks of the application
+	reservationsCreated int64     // number of reservations made for the application
+	firstAllocation     time.Time // time the first allocation was confirmed, zero if nothing was allocated yet
+}
+
+// Return the time the application has been waiting in the queue: time since submission.
+// Lock free call this must be called holding the application lock
+func (sa *SchedulingApplication) getTimeInQueue(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, sa.ApplicationInfo.SubmissionTime))
+}
+
+// Return the time between the submission and the first confirmed allocation.
+// Returns 0 if nothing was allocated yet.
+// Lock free call this must be called holding the application lock
+func (sa *SchedulingApplication) getTimeToFirstAllocation() time.Duration {
+	if sa.stats.firstAllocation.IsZero() {
+		return 0
+	}
+	return sa.stats.firstAllocation.Sub(time.Unix(0, sa.ApplicationInfo.SubmissionTime))
+}
+
+// Record the confirmation of an allocation, only the first one is tracked.
+// An application that still has pending asks starts waiting again.
+func (sa *SchedulingApplication) allocationConfirmed() {
+	sa.Lock()
+	defer sa.Unlock()
+	now := time.Now()
+	if sa.stats.firstAllocation.IsZero() {
+		sa.stats.firstAllocation = now
+	}
+	if !sa.waitingSince.IsZero() {
+		sa.waitingSince = now
+	}
+}
+
+// Start or stop the waiting time after a change of the pending asks: an application waits from the time it gets a
+// pending ask until it has no pending asks left or an allocation is confirmed.
+// Lock free call this must be called holding the application lock
+func (sa *SchedulingApplication) updateWaiting(now time.Time) {
+	if resources.IsZero(sa.pending) && sa.placements == 0 {
+		sa.waitingSince = time.Time{}
+		return
+	}
+	if sa.waitingSince.IsZero() {
+		sa.waitingSince = now
+	}
+}
+
+// Return the time the application has been waiting for an allocation, 0 if nothing is pending.
+func (sa *SchedulingApplication) getWaitingTime(now time.Time) time.Duration {
+	sa.RLock()
+	defer sa.RUnlock()
+	if sa.waitingSince.IsZero() {
+		return 0
+	}
+	return now.Sub(sa.waitingSince)
+}
+
+// Return the scheduling statistics of the application.
+func (sa *SchedulingApplication) GetStatistics() *dao.ApplicationStatsDAOInfo {
+	sa.RLock()
+	defer sa.RUnlock()
+	info := &dao.ApplicationStatsDAOInfo{
+		ApplicationID:         sa.ApplicationInfo.ApplicationID,
+		Partition:             sa.ApplicationInfo.Partition,
+		SchedulingAttempts:    sa.stats.schedulingAttempts,
+		NodesEvaluated:        sa.stats.nodesEvaluated,
+		ReservationsCreated:   sa.stats.reservationsCreated,
+		TimeInQueue:           sa.getTimeInQueue(time.Now()).Nanoseconds(),
+		TimeToFirstAllocation: sa.getTimeToFirstAllocation().Nanoseconds(),
+	}
+	if !sa.stats.firstAllocation.IsZero() {
+		info.FirstAllocationTime = sa.stats.firstAllocation.UnixNano()
+	}
+	return info
+}
@@ -0,0 +1,116 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+func TestAppStatistics(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.parent.leaf1", security.UserGroup{}, nil)
+	app := newSchedulingApplication(appInfo)
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications["app-1"] = app
+
+	stats := app.GetStatistics()
+	assert.Equal(t, stats.ApplicationID, "app-1", "unexpected application in statistics")
+	assert.Equal(t, stats.SchedulingAttempts, int64(0), "new app should not have attempts")
+	assert.Equal(t, stats.FirstAllocationTime, int64(0), "new app should not have an allocation time")
+	assert.Equal(t, stats.TimeToFirstAllocation, int64(0), "new app should not have a time to first allocation")
+	assert.Assert(t, stats.TimeInQueue >= 0, "time in queue should not be negative")
+
+	// large ask is tried first (higher priority) and does not fit on any node
+	large := newAllocationAsk("alloc-large", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20}))
+	large.priority = 2
+	_, err := app.addAllocationAsk(large)
+	assert.NilError(t, err, "failed to add large ask to app")
+	small := newAllocationAsk("alloc-small", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}))
+	_, err = app.addAllocationAsk(small)
+	assert.NilError(t, err, "failed to add small ask to app")
+
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation")
+	}
+	stats = app.GetStatistics()
+	assert.Equal(t, stats.SchedulingAttempts, int64(1), "expected one scheduling attempt")
+	assert.Equal(t, stats.NodesEvaluated, int64(3), "expected both nodes for the large and one node for the small ask")
+	assert.Equal(t, stats.FirstAllocationTime, int64(0), "allocation is not confirmed")
+
+	err = partition.confirmAllocation("app-1", alloc.nodeID, "alloc-small", alloc.allocatedResource, true)
+	assert.NilError(t, err, "confirmation failed")
+	stats = app.GetStatistics()
+	assert.Assert(t, stats.FirstAllocationTime != 0, "first allocation time not set on confirmation")
+	assert.Assert(t, stats.TimeToFirstAllocation >= 0, "time to first allocation should not be negative")
+	first := stats.FirstAllocationTime
+	err = partition.confirmAllocation("app-1", alloc.nodeID, "alloc-small", resources.NewResource(), true)
+	assert.NilError(t, err, "second confirmation failed")
+	assert.Equal(t, app.GetStatistics().FirstAllocationTime, first, "only the first allocation should be tracked")
+
+	// reservations are counted when made
+	reserve := newAllocationAsk("alloc-reserve", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}))
+	_, err = app.addAllocationAsk(reserve)
+	assert.NilError(t, err, "failed to add reserve ask to app")
+	err = app.reserve(partition.getSchedulingNode("node-2"), reserve)
+	assert.NilError(t, err, "reservation failed")
+	assert.Equal(t, app.GetStatistics().ReservationsCreated, int64(1), "reservation not counted")
+}
+
+func TestAppWaitingTime(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.parent.leaf1", security.UserGroup{}, nil)
+	app := newSchedulingApplication(appInfo)
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications["app-1"] = app
+	assert.Equal(t, app.getWaitingTime(time.Now()), time.Duration(0), "app without asks should not be waiting")
+
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	_, err := app.addAllocationAsk(newAllocationAskRepeat("alloc-1", "app-1", res, 2))
+	assert.NilError(t, err, "failed to add ask to app")
+	assert.Assert(t, !app.waitingSince.IsZero(), "app with a pending ask should be waiting")
+	// move the start back to see it reset on confirmation
+	app.waitingSince = time.Now().Add(-time.Hour)
+	assert.Assert(t, app.getWaitingTime(time.Now()) >= time.Hour, "waiting time should include the hour")
+
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation")
+	}
+	assert.Assert(t, app.getWaitingTime(time.Now()) >= time.Hour, "unconfirmed allocation should not reset the wait")
+	err = partition.confirmAllocation("app-1", alloc.nodeID, "alloc-1", alloc.allocatedResource, true)
+	assert.NilError(t, err, "confirmation failed")
+	assert.Assert(t, app.getWaitingTime(time.Now()) < time.Hour, "confirmed allocation should reset the wait")
+	assert.Assert(t, !app.waitingSince.IsZero(), "app with a pending repeat should still be waiting")
+
+	// no pending asks left: not waiting
+	app.removeAllocationAsk("")
+	assert.Equal(t, app.getWaitingTime(time.Now()), time.Duration(0), "app without pending asks should not be waiting")
+}
@@ -21,16 +21,20 @@ package scheduler
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/metrics"
 	"github.com/apache/incubator-yunikorn-core/pkg/plugins"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -40,14 +44,24 @@ type SchedulingApplication struct {
 	ApplicationInfo *cache.ApplicationInfo
 
 	// Private fields need protection
-	queue          *SchedulingQueue                    // queue the application is running in
-	allocating     *resources.Resource                 // allocating resource set by the scheduler
-	pending        *resources.Resource                 // pending resources from asks for the app
-	reservations   map[string]*reservation             // a map of reservations
-	requests       map[string]*schedulingAllocationAsk // a map of asks
-	sortedRequests []*schedulingAllocationAsk
-
-	sync.RWMutex
+	queue           *SchedulingQueue                    // queue the application is running in
+	group           string                              // application group from the application tags, empty if none
+	priority        *int32                              // priority from the application tags, nil if none
+	allocating      *resources.Resource                 // allocating resource set by the scheduler
+	pending         *resources.Resource                 // pending resources from asks for the app
+	placements      int32                               // pending repeats of the placement only asks for the app
+	reservations    map[string]*reservation             // a map of reservations
+	requests        map[string]*schedulingAllocationAsk // a map of asks
+	askQueue        askQueue                            // the asks in the order they are tried
+	traceEnabled    bool                                // record the scheduling attempt trace for asks
+	traces          map[string]*askTrace                // last scheduling attempt trace per ask, only used if tracing is enabled
+	runtimeEstimate time.Duration                       // estimated runtime from the application tags, 0 means no estimate
+	stats           appStatistics                       // scheduling statistics
+	diagnostics     []appDiagnostic                     // changes made to the requests of the application, oldest first
+	idleSince       time.Time                           // time the application became idle, zero if the application is not idle
+	waitingSince    time.Time                           // time the application started waiting for an allocation, zero if nothing is pending
+
+	locking.RWMutex
 }
 
 func newSchedulingApplication(appInfo *cache.ApplicationInfo) *SchedulingApplication {
@@ -57,6 +71,11 @@ func newSchedulingApplication(appInfo *cache.ApplicationInfo) *SchedulingApplica
 		pending:         resources.NewResource(),
 		requests:        make(map[string]*schedulingAllocationAsk),
 		reservations:    make(map[string]*reservation),
+		traceEnabled:    strings.EqualFold(appInfo.GetTag(TraceApplicationTag), "true"),
+		traces:          make(map[string]*askTrace),
+		runtimeEstimate: parseRuntimeEstimate(appInfo),
+		group:           strings.TrimSpace(appInfo.GetTag(ApplicationGroupTag)),
+		priority:        parseApplicationPriority(appInfo),
 	}
 }
 
@@ -67,6 +86,39 @@ func OverrideReservationDelay(delay time.Duration) {
 	reservationDelay = delay
 }
 
+// Return the last scheduling attempt trace for all asks of the application.
+// The list is empty if tracing is not enabled for the application.
+func (sa *SchedulingApplication) GetAskTraces() []dao.AskTraceDAOInfo {
+	sa.RLock()
+	defer sa.RUnlock()
+	traces := make([]dao.AskTraceDAOInfo, 0, len(sa.traces))
+	for _, trace := range sa.traces {
+		traces = append(traces, trace.toDAO())
+	}
+	return traces
+}
+
+// Return the outstanding asks of the application with their pending repeat, sorted by allocation key.
+func (sa *SchedulingApplication) GetRequestInfos() []dao.AllocationAskDAOInfo {
+	sa.RLock()
+	defer sa.RUnlock()
+	infos := make([]dao.AllocationAskDAOInfo, 0, len(sa.requests))
+	for key, ask := range sa.requests {
+		infos = append(infos, dao.AllocationAskDAOInfo{
+			AllocationKey:   key,
+			ResourcePerAsk:  ask.AllocatedResource.DAOString(),
+			Priority:        ask.getAllocationPriority().String(),
+			RemainingRepeat: ask.getPendingAskRepeat(),
+			CreateTime:      ask.getCreateTime().UnixNano(),
+			PlacementOnly:   ask.isPlacementOnly(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].AllocationKey < infos[j].AllocationKey
+	})
+	return infos
+}
+
 // Return an array of all reservation keys for the app.
 // This will return an empty array if there are no reservations.
 // Visible for tests
@@ -92,11 +144,28 @@ func (sa *SchedulingApplication) GetAllocatedResource() *resources.Resource {
 	return sa.ApplicationInfo.GetAllocatedResource()
 }
 
-// Return the pending resources for this application
+// Return a copy of the pending resources for this application: the pending resource is changed in place.
 func (sa *SchedulingApplication) GetPendingResource() *resources.Resource {
 	sa.RLock()
 	defer sa.RUnlock()
-	return sa.pending
+	return sa.pending.Clone()
+}
+
+// Update the idle state of the application and return the time the application has been idle.
+// An application is idle when it has no allocations, nothing allocating, no pending resources and no pending placement
+// only asks. The idle time of an application that was not marked idle before is 0.
+func (sa *SchedulingApplication) updateIdleTime(now time.Time) time.Duration {
+	sa.Lock()
+	defer sa.Unlock()
+	idle := len(sa.ApplicationInfo.GetAllAllocations()) == 0 && resources.IsZero(sa.allocating) && resources.IsZero(sa.pending) && sa.placements == 0
+	if !idle {
+		sa.idleSince = time.Time{}
+		return 0
+	}
+	if sa.idleSince.IsZero() {
+		sa.idleSince = now
+	}
+	return now.Sub(sa.idleSince)
 }
 
 // Return the allocating and allocated resources for this application
@@ -162,7 +231,10 @@ func (sa *SchedulingApplication) removeAllocationAsk(allocKey string) int {
 		// Cleanup total pending resource
 		deltaPendingResource = sa.pending
 		sa.pending = resources.NewResource()
+		sa.updatePendingPlacements(-sa.placements)
 		sa.requests = make(map[string]*schedulingAllocationAsk)
+		sa.askQueue.reset()
+		sa.traces = make(map[string]*askTrace)
 	} else {
 		// cleanup the reservation for this allocation
 		for _, key := range sa.isAskReserved(allocKey) {
@@ -180,11 +252,15 @@ func (sa *SchedulingApplication) removeAllocationAsk(allocKey string) int {
 		if ask := sa.requests[allocKey]; ask != nil {
 			deltaPendingResource = resources.MultiplyBy(ask.AllocatedResource, float64(ask.getPendingAskRepeat()))
 			sa.pending.SubFrom(deltaPendingResource)
+			sa.updatePendingPlacements(-ask.getPendingPlacements())
 			delete(sa.requests, allocKey)
+			sa.askQueue.remove(ask)
+			delete(sa.traces, allocKey)
 		}
 	}
 	// clean up the queue pending resources
 	sa.queue.decPendingResource(deltaPendingResource)
+	sa.updateWaiting(time.Now())
 	return toRelease
 }
 
@@ -196,23 +272,36 @@ func (sa *SchedulingApplication) addAllocationAsk(ask *schedulingAllocationAsk)
 	if ask == nil {
 		return nil, fmt.Errorf("ask cannot be nil when added to app %s", sa.ApplicationInfo.ApplicationID)
 	}
-	if ask.getPendingAskRepeat() == 0 || resources.IsZero(ask.AllocatedResource) {
-		return nil, fmt.Errorf("invalid ask added to app %s: %v", sa.ApplicationInfo.ApplicationID, ask)
+	if ask.getPendingAskRepeat() == 0 {
+		return nil, api.NewRejectionError(api.RejectionInvalidResource, "invalid ask added to app %s: %v", sa.ApplicationInfo.ApplicationID, ask)
 	}
 	ask.QueueName = sa.queue.Name
+	sa.setAskPriority(ask)
 	delta := resources.Multiply(ask.AllocatedResource, int64(ask.getPendingAskRepeat()))
 
 	var oldAskResource *resources.Resource = nil
+	placements := ask.getPendingPlacements()
 	if oldAsk := sa.requests[ask.AskProto.AllocationKey]; oldAsk != nil {
+		// only the repeat changed: scale the existing ask, allocations in flight keep updating the same ask
+		if ask.isRepeatUpdateOf(oldAsk) {
+			return sa.updateAskRepeatInternal(oldAsk, ask.getPendingAskRepeat()-oldAsk.getPendingAskRepeat())
+		}
 		oldAskResource = resources.Multiply(oldAsk.AllocatedResource, int64(oldAsk.getPendingAskRepeat()))
+		placements -= oldAsk.getPendingPlacements()
+		// the update replaces the ask in its original place
+		sa.askQueue.remove(oldAsk)
+		ask.seq = oldAsk.seq
 	}
 
 	delta.SubFrom(oldAskResource)
 	sa.requests[ask.AskProto.AllocationKey] = ask
+	sa.askQueue.push(ask)
 
 	// Update total pending resource
 	sa.pending.AddTo(delta)
 	sa.queue.incPendingResource(delta)
+	sa.updatePendingPlacements(placements)
+	sa.updateWaiting(time.Now())
 
 	return delta, nil
 }
@@ -226,6 +315,21 @@ func (sa *SchedulingApplication) updateAskRepeat(allocKey string, delta int32) (
 	return nil, fmt.Errorf("failed to locate ask with key %s", allocKey)
 }
 
+// Change the pending repeat of the ask by the delta, the pending repeat does not go below zero.
+// Returns the change in the pending resource, an error if the ask is not found.
+func (sa *SchedulingApplication) changeAskRepeat(allocKey string, delta int32) (*resources.Resource, error) {
+	sa.Lock()
+	defer sa.Unlock()
+	ask := sa.requests[allocKey]
+	if ask == nil {
+		return nil, fmt.Errorf("failed to locate ask with key %s", allocKey)
+	}
+	if pending := ask.getPendingAskRepeat(); pending+delta < 0 {
+		delta = -pending
+	}
+	return sa.updateAskRepeatInternal(ask, delta)
+}
+
 func (sa *SchedulingApplication) updateAskRepeatInternal(ask *schedulingAllocationAsk, delta int32) (*resources.Resource, error) {
 	// updating with delta does error checking internally
 	if !ask.updatePendingAskRepeat(delta) {
@@ -236,6 +340,10 @@ func (sa *SchedulingApplication) updateAskRepeatInternal(ask *schedulingAllocati
 	sa.pending.AddTo(deltaPendingResource)
 	// update the pending of the queue with the same delta
 	sa.queue.incPendingResource(deltaPendingResource)
+	if ask.isPlacementOnly() {
+		sa.updatePendingPlacements(delta)
+	}
+	sa.updateWaiting(time.Now())
 
 	return deltaPendingResource, nil
 }
@@ -247,6 +355,17 @@ func (sa *SchedulingApplication) hasReserved() bool {
 	return len(sa.reservations) > 0
 }
 
+// Return the total resource of all asks reserved on a node by the application.
+func (sa *SchedulingApplication) getReservedResource() *resources.Resource {
+	sa.RLock()
+	defer sa.RUnlock()
+	reserved := resources.NewResource()
+	for _, reserve := range sa.reservations {
+		reserved.AddTo(reserve.ask.AllocatedResource)
+	}
+	return reserved
+}
+
 // Return if the application has the node reserved.
 // An empty nodeID is never reserved.
 func (sa *SchedulingApplication) isReservedOnNode(nodeID string) bool {
@@ -286,13 +405,14 @@ func (sa *SchedulingApplication) reserve(node *SchedulingNode, ask *schedulingAl
 		return fmt.Errorf("reservation creation failed ask %s not found on appID %s", allocKey, sa.ApplicationInfo.ApplicationID)
 	}
 	if !sa.canAskReserve(ask) {
-		return fmt.Errorf("reservation of ask exceeds pending repeat, pending ask repeat %d", ask.getPendingAskRepeat())
+		return api.NewError(api.ErrInvalidState, "reservation of ask exceeds pending repeat, pending ask repeat %d", ask.getPendingAskRepeat())
 	}
 	// check if we can reserve the node before reserving on the app
 	if err := node.reserve(sa, ask); err != nil {
 		return err
 	}
 	sa.reservations[nodeReservation.getKey()] = nodeReservation
+	sa.stats.reservationsCreated++
 	// reservation added successfully
 	return nil
 }
@@ -334,6 +454,77 @@ func (sa *SchedulingApplication) unReserveInternal(node *SchedulingNode, ask *sc
 	return nil
 }
 
+// Remove the reservations older than the stale age for an ask that is no longer registered with the app or a node that
+// is no longer part of the partition. The nodes passed in are the nodes of the partition.
+// Returns the number of reservations removed, the queue and partition counters are not updated.
+func (sa *SchedulingApplication) reapStaleReservations(staleAge time.Duration, nodes map[string]*SchedulingNode) int {
+	sa.Lock()
+	defer sa.Unlock()
+	reaped := 0
+	for key, res := range sa.reservations {
+		if !res.isOlder(staleAge) || (sa.requests[res.askKey] == res.ask && nodes[res.nodeID] == res.node) {
+			continue
+		}
+		log.Logger().Warn("removing stale reservation",
+			zap.String("appID", sa.ApplicationInfo.ApplicationID),
+			zap.String("reservationKey", key),
+			zap.Duration("age", time.Since(res.created)))
+		if err := sa.unReserveInternal(res.node, res.ask); err != nil {
+			log.Logger().Warn("removal of stale reservation failed",
+				zap.String("appID", sa.ApplicationInfo.ApplicationID),
+				zap.String("reservationKey", key),
+				zap.Error(err))
+			continue
+		}
+		reaped++
+	}
+	return reaped
+}
+
+// Return the details of all reservations for the app, the age is calculated at the time passed in.
+func (sa *SchedulingApplication) getReservationInfos(now time.Time) []*dao.ReservationDAOInfo {
+	sa.RLock()
+	defer sa.RUnlock()
+	infos := make([]*dao.ReservationDAOInfo, 0, len(sa.reservations))
+	for _, res := range sa.reservations {
+		infos = append(infos, &dao.ReservationDAOInfo{
+			ApplicationID: sa.ApplicationInfo.ApplicationID,
+			NodeID:        res.nodeID,
+			AllocationKey: res.askKey,
+			CreateTime:    res.created.UnixNano(),
+			Age:           now.Sub(res.created).Nanoseconds(),
+		})
+	}
+	return infos
+}
+
+// Remove the reservation for the ask on the node, independent of the state of the ask and node.
+// Returns an error if the app has no such reservation, the queue and partition counters are not updated.
+func (sa *SchedulingApplication) expireReservation(nodeID, allocKey string) error {
+	sa.Lock()
+	defer sa.Unlock()
+	res := sa.reservations[nodeID+"|"+allocKey]
+	if res == nil {
+		return fmt.Errorf("reservation for ask %s on node %s not found on appID %s", allocKey, nodeID, sa.ApplicationInfo.ApplicationID)
+	}
+	return sa.unReserveInternal(res.node, res.ask)
+}
+
+// Return the number of reservations for the app
+func (sa *SchedulingApplication) getReservationCount() int {
+	sa.RLock()
+	defer sa.RUnlock()
+	return len(sa.reservations)
+}
+
+// Is the node reserved for the ask by this app?
+func (sa *SchedulingApplication) hasReservation(node *SchedulingNode, ask *schedulingAllocationAsk) bool {
+	sa.RLock()
+	defer sa.RUnlock()
+	_, ok := sa.reservations[reservationKey(node, nil, ask)]
+	return ok
+}
+
 // Return the allocation reservations on any node.
 // The returned array is 0 or more keys into the reservations map.
 // No locking must be called while holding the lock
@@ -366,39 +557,59 @@ func (sa *SchedulingApplication) canAskReserve(ask *schedulingAllocationAsk) boo
 	return pending > len(resNumber)
 }
 
-// Sort the request for the app in order based on the priority of the request.
-// The sorted list only contains candidates that have an outstanding repeat.
-// No locking must be called while holding the lock
-func (sa *SchedulingApplication) sortRequests(ascending bool) {
-	sa.sortedRequests = nil
-	for _, request := range sa.requests {
-		if request.getPendingAskRepeat() == 0 {
-			continue
-		}
-		sa.sortedRequests = append(sa.sortedRequests, request)
-	}
-	// we might not have any requests
-	if len(sa.sortedRequests) > 0 {
-		sortAskByPriority(sa.sortedRequests, ascending)
-	}
+// Get the pending ask with the highest priority.
+// Returns nil if there are no pending asks.
+func (sa *SchedulingApplication) getHighestPriorityAsk() *schedulingAllocationAsk {
+	sa.RLock()
+	defer sa.RUnlock()
+	return sa.askQueue.first()
 }
 
 // Try a regular allocation of the pending requests
 func (sa *SchedulingApplication) tryAllocate(headRoom *resources.Resource, ctx *partitionSchedulingContext) *schedulingAllocation {
+	// the partition must not be locked while holding the application lock: get the nodes first
+	// the node lists are only used during this attempt and are reused by the next one
+	schedulable, sorted := getNodeList(), getNodeList()
+	defer schedulable.release()
+	defer sorted.release()
+	nodes := ctx.getSchedulableNodesInto(schedulable)
 	sa.Lock()
 	defer sa.Unlock()
-	// make sure the request are sorted
-	sa.sortRequests(false)
-	// get all the requests from the app sorted in order
-	for _, request := range sa.sortedRequests {
+	sa.stats.schedulingAttempts++
+	reserveDelay := ctx.getReservationDelay()
+	budgetTime, budgetNodes := ctx.partition.GetAskBudget()
+	// the requests are kept in the order they are tried, skip requests without an outstanding repeat
+	// placement only asks are tried separately, see tryPlacementAllocate
+	for _, request := range sa.askQueue.asks {
+		if request.getPendingAskRepeat() == 0 || request.isPlacementOnly() {
+			continue
+		}
+		var trace *askTrace
+		if sa.traceEnabled {
+			trace = newAskTrace(request, sa.queue)
+			sa.traces[request.AskProto.AllocationKey] = trace
+		}
 		// resource must fit in headroom otherwise skip the request
-		if !resources.FitIn(headRoom, request.AllocatedResource) {
+		shapes := request.getShapesFitIn(headRoom)
+		if len(shapes) == 0 {
+			trace.setResult(traceNoHeadRoom)
+			if trace != nil {
+				_, limit := sa.queue.getHeadRoomWithLimit(request.AllocatedResource)
+				trace.setHeadRoomLimit(limit)
+			}
 			continue
 		}
-		if nodeIterator := ctx.getNodeIterator(); nodeIterator != nil {
-			alloc := sa.tryNodes(request, nodeIterator)
+		trace.setResult(traceNoNode)
+		if nodeIterator := ctx.getNodeIteratorInto(sorted, nodes, sa.queue); nodeIterator != nil {
+			// try the node of a recently released allocation first if the ask is similar
+			if node := getReuseNode(sa.ApplicationInfo.GetReuseNode(request.AllocatedResource), nodes); node != nil {
+				nodeIterator = newPreferredNodeIterator(node, nodeIterator)
+			}
+			budget := newAskBudget(budgetTime, budgetNodes)
+			alloc := sa.tryNodes(request, shapes, headRoom, nodeIterator, reserveDelay, budget, trace)
 			// have a candidate return it
 			if alloc != nil {
+				trace.setResult(alloc.result.String())
 				return alloc
 			}
 		}
@@ -407,10 +618,31 @@ func (sa *SchedulingApplication) tryAllocate(headRoom *resources.Resource, ctx *
 	return nil
 }
 
+// Return the node with the ID from the list of schedulable nodes.
+// Returns nil if there is no reuse hint or the node is not schedulable.
+func getReuseNode(nodeID string, nodes []*SchedulingNode) *SchedulingNode {
+	if nodeID == "" {
+		return nil
+	}
+	for _, node := range nodes {
+		if node.NodeID == nodeID {
+			return node
+		}
+	}
+	return nil
+}
+
 // Try a reserved allocation of an outstanding reservation
 func (sa *SchedulingApplication) tryReservedAllocate(headRoom *resources.Resource, ctx *partitionSchedulingContext) *schedulingAllocation {
+	// the partition must not be locked while holding the application lock: get the nodes first
+	// the node lists are only used during this attempt and are reused by the next one
+	schedulable, sorted := getNodeList(), getNodeList()
+	defer schedulable.release()
+	defer sorted.release()
+	nodes := ctx.getSchedulableNodesInto(schedulable)
 	sa.Lock()
 	defer sa.Unlock()
+	sa.stats.schedulingAttempts++
 	// process all outstanding reservations and pick the first one that fits
 	for _, reserve := range sa.reservations {
 		ask := sa.requests[reserve.askKey]
@@ -433,11 +665,13 @@ func (sa *SchedulingApplication) tryReservedAllocate(headRoom *resources.Resourc
 			return alloc
 		}
 		// check if this fits in the queue's head room
-		if !resources.FitIn(headRoom, ask.AllocatedResource) {
+		shapes := ask.getShapesFitIn(headRoom)
+		if len(shapes) == 0 {
 			continue
 		}
 		// check allocation possibility
-		alloc := sa.tryNode(reserve.node, ask)
+		sa.stats.nodesEvaluated++
+		alloc := sa.tryNode(reserve.node, ask, shapes, headRoom, nil)
 		// allocation worked set the result and return
 		if alloc != nil {
 			alloc.result = allocatedReserved
@@ -446,8 +680,8 @@ func (sa *SchedulingApplication) tryReservedAllocate(headRoom *resources.Resourc
 	}
 	// lets try this on all other nodes
 	for _, reserve := range sa.reservations {
-		if nodeIterator := ctx.getNodeIterator(); nodeIterator != nil {
-			alloc := sa.tryNodesNoReserve(reserve.ask, nodeIterator, reserve.nodeID)
+		if nodeIterator := ctx.getNodeIteratorInto(sorted, nodes, sa.queue); nodeIterator != nil {
+			alloc := sa.tryNodesNoReserve(reserve.ask, reserve.ask.getShapes(), headRoom, nodeIterator, reserve.nodeID)
 			// have a candidate return it, including the node that was reserved
 			if alloc != nil {
 				return alloc
@@ -459,14 +693,16 @@ func (sa *SchedulingApplication) tryReservedAllocate(headRoom *resources.Resourc
 
 // Try all the nodes for a reserved request that have not been tried yet.
 // This should never result in a reservation as the ask is already reserved
-func (sa *SchedulingApplication) tryNodesNoReserve(ask *schedulingAllocationAsk, nodeIterator NodeIterator, reservedNode string) *schedulingAllocation {
+func (sa *SchedulingApplication) tryNodesNoReserve(ask *schedulingAllocationAsk, shapes []int, headRoom *resources.Resource, nodeIterator NodeIterator, reservedNode string) *schedulingAllocation {
 	for nodeIterator.HasNext() {
 		node := nodeIterator.Next()
-		// skip over the node if the resource does not fit the node or this is the reserved node.
-		if !node.nodeInfo.FitInNode(ask.AllocatedResource) || node.NodeID == reservedNode {
+		sa.stats.nodesEvaluated++
+		// skip over the node if the resource does not fit the node, the ask does not tolerate the taints of the node,
+		// the node has not been ready long enough or this is the reserved node.
+		if !fitInNode(node, ask, shapes) || !ask.toleratesNode(node) || !ask.acceptsNodeAge(node) || node.NodeID == reservedNode {
 			continue
 		}
-		alloc := sa.tryNode(node, ask)
+		alloc := sa.tryNode(node, ask, shapes, headRoom, nil)
 		// allocation worked so return
 		if alloc != nil {
 			alloc.reservedNodeID = reservedNode
@@ -479,20 +715,58 @@ func (sa *SchedulingApplication) tryNodesNoReserve(ask *schedulingAllocationAsk,
 }
 
 // Try all the nodes for a request. The result is an allocation or reservation of a node.
-// New allocations can only be reserved after a delay.
-func (sa *SchedulingApplication) tryNodes(ask *schedulingAllocationAsk, nodeIterator NodeIterator) *schedulingAllocation {
+// New allocations can only be reserved after a delay. A reservation is always for the requested resource of the ask,
+// it is only made if the requested resource is one of the shapes to try.
+// The node evaluation stops when the budget is used up, the best node to reserve found so far is used.
+// The node evaluations are recorded in the trace if it is not nil.
+func (sa *SchedulingApplication) tryNodes(ask *schedulingAllocationAsk, shapes []int, headRoom *resources.Resource, nodeIterator NodeIterator, reserveDelay time.Duration, budget *askBudget, trace *askTrace) *schedulingAllocation {
 	var nodeToReserve *SchedulingNode
 	scoreReserved := math.Inf(1)
+	canReserve := len(shapes) > 0 && shapes[0] == 0
 	// check if the ask is reserved or not
 	allocKey := ask.AskProto.AllocationKey
 	reservedAsks := sa.isAskReserved(allocKey)
+	// soft anti affinity tries the nodes shared with the avoided queues last, hard anti affinity skips them
+	avoid, hard := sa.queue.getAntiAffinity()
+	if len(avoid) != 0 && !hard {
+		nodeIterator = orderForAntiAffinity(nodeIterator, avoid)
+	}
 	for nodeIterator.HasNext() {
+		// stop if the budget is used up and there are nodes left to evaluate
+		if budget.isUsedUp() {
+			log.Logger().Debug("ask budget used up, stopping node evaluation",
+				zap.String("appID", sa.ApplicationInfo.ApplicationID),
+				zap.String("allocationKey", allocKey),
+				zap.Int("nodesEvaluated", budget.nodes))
+			metrics.GetSchedulerMetrics().IncAskBudgetExceeded()
+			trace.setResult(traceAskBudget)
+			break
+		}
 		node := nodeIterator.Next()
+		sa.stats.nodesEvaluated++
+		budget.nodeEvaluated()
+		trace.nodeEvaluated()
 		// skip over the node if the resource does not fit the node at all.
-		if !node.nodeInfo.FitInNode(ask.AllocatedResource) {
+		if !fitInNode(node, ask, shapes) {
+			trace.nodeFiltered(traceFitInNode)
+			continue
+		}
+		// skip over the node if the ask does not tolerate the taints of the node.
+		if !ask.toleratesNode(node) {
+			trace.nodeFiltered(traceNodeTaints)
 			continue
 		}
-		alloc := sa.tryNode(node, ask)
+		// skip over the node if it has not been ready long enough for the ask.
+		if !ask.acceptsNodeAge(node) {
+			trace.nodeFiltered(traceNodeAge)
+			continue
+		}
+		// skip over the node if it runs allocations of a queue the queue of the ask must not share a node with.
+		if hard && len(avoid) != 0 && node.nodeInfo.HasQueueAllocations(avoid) {
+			trace.nodeFiltered(traceQueueAntiAffinity)
+			continue
+		}
+		alloc := sa.tryNode(node, ask, shapes, headRoom, trace)
 		// allocation worked so return
 		if alloc != nil {
 			// check if the node was reserved for this ask: if it is set the result and return
@@ -523,8 +797,9 @@ func (sa *SchedulingApplication) tryNodes(ask *schedulingAllocationAsk, nodeIter
 			return alloc
 		}
 		// nothing allocated should we look at a reservation?
+		// a node in a pool the queue cannot allocate in is never reserved
 		// TODO make this smarter a hardcoded delay is not the right thing
-		if time.Since(ask.getCreateTime()) > reservationDelay {
+		if canReserve && time.Since(ask.getCreateTime()) > reserveDelay && sa.queue.canAllocateInPool(node.nodeInfo.Pool, ask.AllocatedResource) {
 			score := ask.AllocatedResource.FitInScore(node.getAvailableResource())
 			// Record the so-far best node to reserve
 			if score < scoreReserved {
@@ -549,53 +824,93 @@ func (sa *SchedulingApplication) tryNodes(ask *schedulingAllocationAsk, nodeIter
 	return nil
 }
 
+// Does any of the shapes of the ask fit in the total resource of the node?
+func fitInNode(node *SchedulingNode, ask *schedulingAllocationAsk, shapes []int) bool {
+	for _, shape := range shapes {
+		if node.nodeInfo.FitInNode(ask.getShape(shape)) {
+			return true
+		}
+	}
+	return false
+}
+
 // Try allocating on one specific node
-func (sa *SchedulingApplication) tryNode(node *SchedulingNode, ask *schedulingAllocationAsk) *schedulingAllocation {
+// The shapes of the ask are tried in order, the first shape that can be allocated on the node is used.
+// The range shape is scaled down to what is available on the node and in the queue and pool headroom.
+// The reason for skipping the node is recorded in the trace if it is not nil. If the ask has more than one shape the
+// reason the last shape was skipped is recorded.
+func (sa *SchedulingApplication) tryNode(node *SchedulingNode, ask *schedulingAllocationAsk, shapes []int, headRoom *resources.Resource, trace *askTrace) *schedulingAllocation {
 	allocKey := ask.AskProto.AllocationKey
-	toAllocate := ask.AllocatedResource
-	// create the key for the reservation
-	if err := node.preAllocateCheck(toAllocate, reservationKey(nil, sa, ask), false); err != nil {
-		// skip schedule onto node
-		log.Logger().Debug("skipping node for allocation: basic condition not satisfied",
-			zap.String("node", node.NodeID),
-			zap.Any("allocationKey", allocKey),
-			zap.Error(err))
-		return nil
-	}
-	// skip the node if conditions can not be satisfied
-	if !node.preAllocateConditions(allocKey) {
-		return nil
-	}
-	// everything OK really allocate
-	if node.allocateResource(toAllocate, false) {
-		// before deciding on an allocation, call the reconcile plugin to sync scheduler cache
-		// between core and shim if necessary. This is useful when running multiple allocations
-		// in parallel and need to handle inter container affinity and anti-affinity.
-		if rp := plugins.GetReconcilePlugin(); rp != nil {
-			if err := rp.ReSyncSchedulerCache(&si.ReSyncSchedulerCacheArgs{
-				AssumedAllocations: []*si.AssumedAllocation{
-					{
-						AllocationKey: allocKey,
-						NodeID:        node.NodeID,
-					},
-				},
-			}); err != nil {
-				log.Logger().Error("failed to sync shim cache",
-					zap.Error(err))
+	filtered := ""
+	for _, shape := range shapes {
+		toAllocate := ask.getShape(shape)
+		if ask.isRangeShape(shape) {
+			toAllocate = ask.getScaledResource(node.getAvailableResource(), headRoom, sa.queue.getPoolHeadRoom(node.nodeInfo.Pool))
+			if toAllocate == nil {
+				filtered = traceAllocateResource
+				continue
 			}
 		}
-		// update the allocating resources
-		sa.queue.incAllocatingResource(toAllocate)
-		sa.allocating.AddTo(toAllocate)
-		// mark this ask as allocating by lowering the repeat
-		_, err := sa.updateAskRepeatInternal(ask, -1)
-		if err != nil {
-			log.Logger().Debug("ask repeat update failed unexpectedly",
+		// skip the shape if the queue cannot use the node pool or has no headroom left in the pool
+		if !sa.queue.canAllocateInPool(node.nodeInfo.Pool, toAllocate) {
+			filtered = traceNodePool
+			continue
+		}
+		// create the key for the reservation
+		if err := node.preAllocateCheck(toAllocate, reservationKey(nil, sa, ask), false); err != nil {
+			// skip schedule onto node
+			log.Logger().Debug("skipping node for allocation: basic condition not satisfied",
+				zap.String("node", node.NodeID),
+				zap.Any("allocationKey", allocKey),
 				zap.Error(err))
+			filtered = tracePreAllocateCheck
+			continue
 		}
+		// skip the node if conditions can not be satisfied, the conditions do not depend on the shape
+		if !node.preAllocateConditions(allocKey) {
+			trace.nodeFiltered(tracePreAllocateConditions)
+			return nil
+		}
+		// everything OK really allocate
+		if node.allocateResource(toAllocate, false) {
+			// before deciding on an allocation, call the reconcile plugin to sync scheduler cache
+			// between core and shim if necessary. This is useful when running multiple allocations
+			// in parallel and need to handle inter container affinity and anti-affinity.
+			if rp := plugins.GetReconcilePlugin(); rp != nil {
+				if err := rp.ReSyncSchedulerCache(&si.ReSyncSchedulerCacheArgs{
+					AssumedAllocations: []*si.AssumedAllocation{
+						{
+							AllocationKey: allocKey,
+							NodeID:        node.NodeID,
+						},
+					},
+				}); err != nil {
+					log.Logger().Error("failed to sync shim cache",
+						zap.Error(err))
+				}
+			}
+			// update the allocating resources
+			sa.queue.incAllocatingResource(toAllocate)
+			sa.queue.incPoolAllocatingResource(node.nodeInfo.Pool, toAllocate)
+			sa.queue.incUserAllocatingResource(sa.ApplicationInfo.GetUser().User, toAllocate)
+			sa.allocating.AddTo(toAllocate)
+			// mark this ask as allocating by lowering the repeat
+			_, err := sa.updateAskRepeatInternal(ask, -1)
+			if err != nil {
+				log.Logger().Debug("ask repeat update failed unexpectedly",
+					zap.Error(err))
+			}
 
-		// return allocation
-		return newSchedulingAllocation(ask, node.NodeID)
+			// return allocation
+			alloc := newSchedulingAllocation(ask, node.NodeID)
+			alloc.shape = shape
+			alloc.allocatedResource = toAllocate
+			return alloc
+		}
+		filtered = traceAllocateResource
+	}
+	if filtered != "" {
+		trace.nodeFiltered(filtered)
 	}
 	return nil
 }
@@ -611,6 +926,8 @@ func (sa *SchedulingApplication) recoverOnNode(node *SchedulingNode, ask *schedu
 	// update the scheduling objects with the in progress resource
 	node.incAllocatingResource(toAllocate)
 	sa.queue.incAllocatingResource(toAllocate)
+	sa.queue.incPoolAllocatingResource(node.nodeInfo.Pool, toAllocate)
+	sa.queue.incUserAllocatingResource(sa.ApplicationInfo.GetUser().User, toAllocate)
 	sa.allocating.AddTo(toAllocate)
 	// mark this ask as allocating by lowering the repeat
 	if _, err := sa.updateAskRepeatInternal(ask, -1); err != nil {
@@ -337,14 +337,8 @@ func TestAddAllocAsk(t *testing.T) {
 		t.Errorf("nil ask should not have been added to app, returned delta: %v", delta)
 	}
 	allocKey := "alloc-1"
-	res := resources.NewResource()
-	ask := newAllocationAsk(allocKey, appID, res)
-	delta, err = app.addAllocationAsk(ask)
-	if err == nil {
-		t.Errorf("zero resource ask should not have been added to app, returned delta: %v", delta)
-	}
-	res = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
-	ask = newAllocationAskRepeat(allocKey, appID, res, 0)
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	ask := newAllocationAskRepeat(allocKey, appID, res, 0)
 	delta, err = app.addAllocationAsk(ask)
 	if err == nil {
 		t.Errorf("ask with zero repeat should not have been added to app, returned delta: %v", delta)
@@ -390,6 +384,77 @@ func TestAddAllocAsk(t *testing.T) {
 	}
 }
 
+// test scaling the repeat of an ask while allocations are in flight
+func TestAskRepeatScaling(t *testing.T) {
+	appID := "app-1"
+	appInfo := cache.NewApplicationInfo(appID, "default", "root.unknown", security.UserGroup{}, nil)
+	app := newSchedulingApplication(appInfo)
+	queue, err := createRootQueue(nil)
+	if err != nil {
+		t.Fatalf("queue create failed: %v", err)
+	}
+	app.queue = queue
+
+	allocKey := "alloc-1"
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	ask := newAllocationAskRepeat(allocKey, appID, res, 2)
+	if _, err = app.addAllocationAsk(ask); err != nil {
+		t.Fatalf("ask should have been added to app: %v", err)
+	}
+	// an allocation is in flight: the repeat is decreased on allocate
+	if _, err = app.updateAskRepeat(allocKey, -1); err != nil {
+		t.Fatalf("repeat update failed: %v", err)
+	}
+	// update that only changes the repeat keeps the existing ask
+	var delta *resources.Resource
+	delta, err = app.addAllocationAsk(newAllocationAskRepeat(allocKey, appID, res, 4))
+	if err != nil || !resources.Equals(resources.Multiply(res, 3), delta) {
+		t.Errorf("repeat update failed, err %v, expected delta %v but was: %v", err, resources.Multiply(res, 3), delta)
+	}
+	if app.GetSchedulingAllocationAsk(allocKey) != ask || ask.getPendingAskRepeat() != 4 {
+		t.Errorf("repeat update should have updated the existing ask, repeat %d", ask.getPendingAskRepeat())
+	}
+	// the in flight allocation is rejected: the repeat goes back up on the same ask
+	if _, err = app.updateAskRepeat(allocKey, 1); err != nil {
+		t.Fatalf("repeat update failed: %v", err)
+	}
+	if !resources.Equals(resources.Multiply(res, 5), app.GetPendingResource()) || !resources.Equals(app.GetPendingResource(), queue.GetPendingResource()) {
+		t.Errorf("pending resource not consistent with repeat 5, app %v, queue %v", app.GetPendingResource(), queue.GetPendingResource())
+	}
+
+	// change by delta: up, down and clamped at zero
+	delta, err = app.changeAskRepeat(allocKey, 2)
+	if err != nil || !resources.Equals(resources.Multiply(res, 2), delta) || ask.getPendingAskRepeat() != 7 {
+		t.Errorf("repeat increase failed, err %v, delta %v, repeat %d", err, delta, ask.getPendingAskRepeat())
+	}
+	delta, err = app.changeAskRepeat(allocKey, -10)
+	if err != nil || !resources.Equals(resources.Multiply(res, -7), delta) || ask.getPendingAskRepeat() != 0 {
+		t.Errorf("repeat decrease should be clamped at zero, err %v, delta %v, repeat %d", err, delta, ask.getPendingAskRepeat())
+	}
+	if !resources.IsZero(app.GetPendingResource()) || !resources.IsZero(queue.GetPendingResource()) {
+		t.Errorf("pending resource should be zero, app %v, queue %v", app.GetPendingResource(), queue.GetPendingResource())
+	}
+	if _, err = app.changeAskRepeat("unknown", 1); err == nil {
+		t.Error("repeat change of unknown ask should have failed")
+	}
+	if _, err = app.changeAskRepeat(allocKey, 3); err != nil {
+		t.Fatalf("repeat increase failed: %v", err)
+	}
+	infos := app.GetRequestInfos()
+	if len(infos) != 1 || infos[0].AllocationKey != allocKey || infos[0].RemainingRepeat != 3 {
+		t.Errorf("request info does not show the remaining repeat: %v", infos)
+	}
+
+	// a different resource replaces the ask
+	res = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 3})
+	if _, err = app.addAllocationAsk(newAllocationAskRepeat(allocKey, appID, res, 1)); err != nil {
+		t.Fatalf("ask should have been replaced: %v", err)
+	}
+	if app.GetSchedulingAllocationAsk(allocKey) == ask || !resources.Equals(res, app.GetPendingResource()) {
+		t.Errorf("ask with a new resource should have replaced the existing ask, pending %v", app.GetPendingResource())
+	}
+}
+
 // test reservations removal by allocation
 func TestRemoveReservedAllocAsk(t *testing.T) {
 	appID := "app-1"
@@ -575,8 +640,8 @@ func TestAssumedAppCalc(t *testing.T) {
 	}
 }
 
-// This test must not test the sorter that is underlying.
-// It tests the queue specific parts of the code only.
+// This test must not test the ask queue that is underlying.
+// It tests the application specific parts of the code only.
 func TestSortRequests(t *testing.T) {
 	appID := "app-1"
 	appInfo := cache.NewApplicationInfo(appID, "default", "root.unknown", security.UserGroup{}, nil)
@@ -584,29 +649,35 @@ func TestSortRequests(t *testing.T) {
 	if app == nil || app.ApplicationInfo.ApplicationID != appID {
 		t.Fatalf("app create failed which should not have %v", app)
 	}
-	if app.sortedRequests != nil {
-		t.Fatalf("new app create should not have sorted requests: %v", app)
-	}
-	app.sortRequests(true)
-	if app.sortedRequests != nil {
-		t.Fatalf("after sort call (no pending resources) list must be nil: %v", app.sortedRequests)
+	if app.getHighestPriorityAsk() != nil {
+		t.Fatalf("new app create should not have a pending ask: %v", app)
 	}
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	app.queue = root
 
 	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
 	for i := 1; i < 4; i++ {
 		num := strconv.Itoa(i)
-		ask := newAllocationAsk("ask-"+num, "app-1", res)
-		ask.priority = int32(i)
-		app.requests[ask.AskProto.AllocationKey] = ask
-	}
-	app.sortRequests(true)
-	if len(app.sortedRequests) != 3 {
-		t.Fatalf("app sorted requests not correct: %v", app.sortedRequests)
-	}
-	allocKey := app.sortedRequests[0].AskProto.AllocationKey
-	delete(app.requests, allocKey)
-	app.sortRequests(true)
-	if len(app.sortedRequests) != 2 {
-		t.Fatalf("app sorted requests not correct after removal: %v", app.sortedRequests)
+		ask := newAllocationAskPriority("ask-"+num, "app-1", int32(i))
+		_, err = app.addAllocationAsk(ask)
+		assert.NilError(t, err, "ask %s should have been added to app", num)
+	}
+	if len(app.askQueue.asks) != 3 || app.getHighestPriorityAsk().AskProto.AllocationKey != "ask-3" {
+		t.Fatalf("app sorted requests not correct: %v", app.askQueue.asks)
+	}
+	app.removeAllocationAsk("ask-3")
+	if len(app.askQueue.asks) != 2 || app.getHighestPriorityAsk().AskProto.AllocationKey != "ask-2" {
+		t.Fatalf("app sorted requests not correct after removal: %v", app.askQueue.asks)
+	}
+	// an update replaces the ask and uses the priority of the update
+	_, err = app.addAllocationAsk(newAllocationAsk("ask-2", "app-1", resources.Multiply(res, 2)))
+	assert.NilError(t, err, "ask update should not have failed")
+	if len(app.askQueue.asks) != 2 || app.getHighestPriorityAsk().AskProto.AllocationKey != "ask-1" {
+		t.Fatalf("app sorted requests not correct after update: %v", app.askQueue.asks)
+	}
+	app.removeAllocationAsk("")
+	if len(app.askQueue.asks) != 0 {
+		t.Fatalf("app sorted requests not empty after removing all asks: %v", app.askQueue.asks)
 	}
 }
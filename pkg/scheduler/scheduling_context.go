@@ -20,13 +20,15 @@ package scheduler
 
 import (
 	"fmt"
-	"sync"
 
 	"go.uber.org/zap"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
 	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/schedulerevent"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
 )
 
 type ClusterSchedulingContext struct {
@@ -34,12 +36,15 @@ type ClusterSchedulingContext struct {
 
 	needPreemption bool
 
-	lock sync.RWMutex
+	autoscaleSubscribers *autoscaleSubscribers // subscribers to the autoscale events, has its own lock
+
+	lock locking.RWMutex
 }
 
 func NewClusterSchedulingContext() *ClusterSchedulingContext {
 	return &ClusterSchedulingContext{
-		partitions: make(map[string]*partitionSchedulingContext),
+		partitions:           make(map[string]*partitionSchedulingContext),
+		autoscaleSubscribers: &autoscaleSubscribers{channels: make(map[int]chan *api.AutoscaleEvent)},
 	}
 }
 
@@ -103,6 +108,103 @@ func (csc *ClusterSchedulingContext) GetPartitionReservations(partitionName stri
 	return nil
 }
 
+// Return the details of all reservations for the partition.
+// Returns nil if the partition cannot be found.
+func (csc *ClusterSchedulingContext) GetReservationInfos(partitionName string) []*dao.ReservationDAOInfo {
+	csc.lock.RLock()
+	partition := csc.partitions[partitionName]
+	csc.lock.RUnlock()
+
+	if partition == nil {
+		return nil
+	}
+	return partition.getReservationInfos()
+}
+
+// Add the utilization of the scheduling queues to the queue infos of the partition, see SchedulingQueue.GetUtilization.
+// Queue infos without a matching scheduling queue are left unchanged, nothing is changed if the partition cannot be
+// found.
+func (csc *ClusterSchedulingContext) AddQueueUtilization(partitionName string, infos []dao.QueueDAOInfo) {
+	csc.lock.RLock()
+	partition := csc.partitions[partitionName]
+	csc.lock.RUnlock()
+
+	if partition == nil {
+		return
+	}
+	partition.addQueueUtilization(infos)
+}
+
+// Return the fair shares of all queues in the partition as calculated in the last scheduling cycle.
+// Returns nil if the partition cannot be found.
+func (csc *ClusterSchedulingContext) GetFairShareInfos(partitionName string) []*dao.FairShareDAOInfo {
+	csc.lock.RLock()
+	partition := csc.partitions[partitionName]
+	csc.lock.RUnlock()
+
+	if partition == nil {
+		return nil
+	}
+	infos := partition.getFairShares().GetFairShareInfos()
+	for _, info := range infos {
+		info.Partition = partitionName
+		info.FairnessDebt = partition.getFairnessDebt(info.QueueName)
+	}
+	return infos
+}
+
+// Return the combined pending and allocated resources of the application groups in the partition.
+// Returns nil if the partition cannot be found.
+func (csc *ClusterSchedulingContext) GetApplicationGroupInfos(partitionName string) []*dao.ApplicationGroupDAOInfo {
+	csc.lock.RLock()
+	partition := csc.partitions[partitionName]
+	csc.lock.RUnlock()
+
+	if partition == nil {
+		return nil
+	}
+	return partition.getApplicationGroupInfos()
+}
+
+// Return the smoothed load index of the partition and the scheduling settings derived from it.
+// Returns nil if the partition cannot be found.
+func (csc *ClusterSchedulingContext) GetLoadIndexInfo(partitionName string) *dao.LoadIndexDAOInfo {
+	csc.lock.RLock()
+	partition := csc.partitions[partitionName]
+	csc.lock.RUnlock()
+
+	if partition == nil {
+		return nil
+	}
+	return partition.getLoadInfo()
+}
+
+// Force the removal of the reservation of the application for the ask on the node.
+// Returns an error if the partition, application or reservation cannot be found.
+func (csc *ClusterSchedulingContext) ExpireReservation(partitionName, appID, nodeID, allocKey string) error {
+	csc.lock.RLock()
+	partition := csc.partitions[partitionName]
+	csc.lock.RUnlock()
+
+	if partition == nil {
+		return fmt.Errorf("partition %s not found", partitionName)
+	}
+	return partition.expireReservation(appID, nodeID, allocKey)
+}
+
+// Move a running application to another leaf queue in the partition, see partitionSchedulingContext.moveSchedulingApplication.
+// Returns an error if the partition, application or queue cannot be found or the application cannot be moved.
+func (csc *ClusterSchedulingContext) MoveApplication(partitionName, appID, queueName string) error {
+	csc.lock.RLock()
+	partition := csc.partitions[partitionName]
+	csc.lock.RUnlock()
+
+	if partition == nil {
+		return fmt.Errorf("partition %s not found", partitionName)
+	}
+	return partition.moveSchedulingApplication(appID, queueName)
+}
+
 func (csc *ClusterSchedulingContext) addSchedulingApplication(schedulingApp *SchedulingApplication) error {
 	partitionName := schedulingApp.ApplicationInfo.Partition
 	appID := schedulingApp.ApplicationInfo.ApplicationID
@@ -115,7 +217,7 @@ func (csc *ClusterSchedulingContext) addSchedulingApplication(schedulingApp *Sch
 			return err
 		}
 	} else {
-		return fmt.Errorf("failed to find partition=%s while adding app=%s", partitionName, appID)
+		return api.NewRejectionError(api.RejectionPartitionNotFound, "failed to find partition=%s while adding app=%s", partitionName, appID)
 	}
 
 	return nil
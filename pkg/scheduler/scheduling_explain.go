@@ -0,0 +1,195 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// Limits that block an explained ask which are not a rejection code.
+// An ask blocked by one of these limits would be accepted and stay pending.
+const (
+	explainQueueNotRunning = "QUEUE_NOT_RUNNING"
+	explainQueueStartDelay = "QUEUE_START_DELAY"
+	explainQueueHeadRoom   = "QUEUE_HEADROOM"
+	explainNoNode          = "NO_NODE"
+)
+
+// Name of the check that filters out a node not matching the node selector of the explained ask
+const explainNodeSelector = "nodeSelector"
+
+// A hypothetical ask to explain. The ask is never added to the scheduler.
+type ExplainAsk struct {
+	PartitionName string
+	QueueName     string
+	User          security.UserGroup
+	Resource      *resources.Resource
+	NodeSelector  map[string]string // node attributes a node must have to be considered
+	Tolerations   string            // node taints tolerated in the format of the ask tag
+}
+
+// Explain if the hypothetical ask could be scheduled right now in the partition.
+// The result shows the nodes the ask would fit on or the limit that blocks it.
+func (csc *ClusterSchedulingContext) ExplainAsk(ask *ExplainAsk) *dao.ExplainDAOInfo {
+	partition := csc.getPartition(ask.PartitionName)
+	if partition == nil {
+		return explainBlocked(newExplainInfo(ask), string(api.RejectionPartitionNotFound), "partition %s not found", ask.PartitionName)
+	}
+	return partition.explain(ask)
+}
+
+// Run the checks of the regular scheduling cycle for the ask in the order the scheduler runs them.
+// This does not change any state: nothing is allocated or reserved and the user is not resolved, the groups are
+// used as passed in. The predicate plugins are not called: a node listed could still be rejected by the shim.
+func (psc *partitionSchedulingContext) explain(ask *ExplainAsk) *dao.ExplainDAOInfo {
+	info := newExplainInfo(ask)
+	// a zero resource is a placement only ask, see isPlacementOnly
+	if !resources.IsZero(ask.Resource) && !resources.StrictlyGreaterThanZero(ask.Resource) {
+		return explainBlocked(info, string(api.RejectionInvalidResource), "requested resource %s must not be negative", info.Resource)
+	}
+	tolerations, err := parseAskTolerations(ask.Tolerations)
+	if err != nil {
+		return explainBlocked(info, string(api.RejectionInvalidTolerations), "invalid tolerations: %v", err)
+	}
+	queue := psc.GetQueue(ask.QueueName)
+	if queue == nil {
+		return explainBlocked(info, string(api.RejectionQueueNotFound), "queue %s not found", ask.QueueName)
+	}
+	if !queue.isLeafQueue() {
+		return explainBlocked(info, string(api.RejectionQueueNotFound), "queue %s is not a leaf queue", ask.QueueName)
+	}
+	if !queue.checkSubmitAccess(ask.User) {
+		return explainBlocked(info, string(api.RejectionACLDenied), "user %s has no submit access to queue %s", ask.User.User, ask.QueueName)
+	}
+	if !queue.isRunning() {
+		return explainBlocked(info, explainQueueNotRunning, "queue %s is %s", ask.QueueName, queue.QueueInfo.CurrentState())
+	}
+	// same checks as the ask rejection: the ask would never be scheduled
+	if !psc.isSchedulable(ask.Resource) {
+		return explainBlocked(info, string(api.RejectionInvalidResource), "requested resource %s is larger than the largest node: %s",
+			info.Resource, psc.describeNodeLimit(ask.Resource))
+	}
+	if limit := queue.getConfiguredMaxResource(); limit != nil && !resources.FitInDefined(limit, ask.Resource) {
+		return explainBlocked(info, string(api.RejectionQuotaExceeded), "requested resource %s is larger than the maximum %s of queue %s",
+			info.Resource, limit.DAOString(), queue.Name)
+	}
+	if limit := queue.getMaxAllocationLimit(ask.Resource); limit != nil {
+		return explainBlocked(info, string(api.RejectionMaxAllocation), "requested resource %s is larger than the max allocation %s of queue %s",
+			info.Resource, limit.QueueInfo.GetMaxAllocation().DAOString(), limit.Name)
+	}
+	if queue.isStartDelayed() {
+		return explainBlocked(info, explainQueueStartDelay, "queue %s is within its start delay", ask.QueueName)
+	}
+	// the headroom of the queues: leaf first root last
+	var blockingQueue string
+	for sq := queue; sq != nil; sq = sq.parent {
+		headRoom := sq.getHeadRoom()
+		display := "unlimited"
+		if headRoom != nil {
+			display = headRoom.DAOString()
+		}
+		info.Queues = append(info.Queues, dao.QueueHeadRoomDAOInfo{
+			QueueName: sq.Name,
+			HeadRoom:  display,
+		})
+		// the headroom of a queue is limited by its parents: the blocking queue is the one closest to the root
+		if !resources.FitIn(headRoom, ask.Resource) {
+			blockingQueue = sq.Name
+		}
+	}
+	if _, limit := queue.getHeadRoomWithLimit(ask.Resource); limit != nil {
+		info.LimitingQueue = limit.queuePath
+		info.LimitingResource = limit.resource
+		return explainBlocked(info, explainQueueHeadRoom, "insufficient headroom in queue %s for resource %s", limit.queuePath, limit.resource)
+	}
+	if blockingQueue != "" {
+		return explainBlocked(info, explainQueueHeadRoom, "insufficient headroom in queue %s", blockingQueue)
+	}
+	// check the nodes in the order the scheduler would try them, reserved nodes are included as they are rejected
+	// by the pre allocation check. The node selector is matched using the attribute index: the nodes that do not
+	// match are counted as filtered but not evaluated.
+	nodeList, unmatched := psc.getSchedulingNodesWithSelector(ask.NodeSelector, false)
+	if unmatched != 0 {
+		info.NodesFiltered[explainNodeSelector] = unmatched
+	}
+	if len(nodeList) != 0 {
+		nodeIterator := psc.getNodeIteratorForPolicy(nodeList, queue)
+		for nodeIterator.HasNext() {
+			node := nodeIterator.Next()
+			info.NodesEvaluated++
+			if check := explainNode(node, queue, ask, tolerations); check != "" {
+				info.NodesFiltered[check]++
+				continue
+			}
+			info.Nodes = append(info.Nodes, node.NodeID)
+		}
+	}
+	if len(info.Nodes) == 0 {
+		return explainBlocked(info, explainNoNode, "no node found for requested resource %s", info.Resource)
+	}
+	info.Schedulable = true
+	return info
+}
+
+// Return the check that filters out the node for the ask, an empty string if the ask fits on the node.
+// Lock free call all locks are taken when needed in called functions
+func explainNode(node *SchedulingNode, queue *SchedulingQueue, ask *ExplainAsk, tolerations []taintToleration) string {
+	if !node.nodeInfo.FitInNode(ask.Resource) {
+		return traceFitInNode
+	}
+	if !toleratesTaints(tolerations, node.taints) {
+		return traceNodeTaints
+	}
+	if !queue.canAllocateInPool(node.nodeInfo.Pool, ask.Resource) {
+		return traceNodePool
+	}
+	// a placement only ask does not use the resources of the node: the check does not apply
+	if resources.IsZero(ask.Resource) {
+		return ""
+	}
+	// the hypothetical ask has no reservation key: a reserved node always fails the check
+	if err := node.preAllocateCheck(ask.Resource, "", false); err != nil {
+		return tracePreAllocateCheck
+	}
+	return ""
+}
+
+func newExplainInfo(ask *ExplainAsk) *dao.ExplainDAOInfo {
+	return &dao.ExplainDAOInfo{
+		Partition:     ask.PartitionName,
+		QueueName:     ask.QueueName,
+		User:          ask.User.User,
+		Resource:      ask.Resource.DAOString(),
+		NodesFiltered: make(map[string]int),
+		Nodes:         make([]string, 0),
+	}
+}
+
+// Mark the ask as not schedulable and record the limit that blocks it.
+func explainBlocked(info *dao.ExplainDAOInfo, blockedBy string, format string, args ...interface{}) *dao.ExplainDAOInfo {
+	info.Schedulable = false
+	info.BlockedBy = blockedBy
+	info.Reason = fmt.Sprintf(format, args...)
+	return info
+}
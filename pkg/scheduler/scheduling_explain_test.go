@@ -0,0 +1,218 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// Partition with two nodes of 10 and the queues:
+// root (submit ACL user1, max 100) with leaf (no limit), limited (max 5), delayed (start delay), small (max allocation 3)
+// and parent.
+func createExplainPartition(t *testing.T) *partitionSchedulingContext {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	partition.addSchedulingNode(cache.NewNodeInfo(&si.NewNodeInfo{
+		NodeID:              "node-1",
+		Attributes:          map[string]string{"zone": "a"},
+		SchedulableResource: res.ToProto(),
+	}))
+	partition.addSchedulingNode(cache.NewNodeInfo(&si.NewNodeInfo{
+		NodeID:              "node-2",
+		Attributes:          map[string]string{"zone": "b"},
+		SchedulableResource: res.ToProto(),
+	}))
+
+	rootConf := configs.QueueConfig{
+		Name:      "root",
+		Parent:    true,
+		SubmitACL: "user1",
+		Resources: configs.Resources{Max: map[string]string{"first": "100"}},
+	}
+	var rootInfo *cache.QueueInfo
+	rootInfo, err = cache.NewManagedQueue(rootConf, nil)
+	assert.NilError(t, err, "failed to create root queue")
+	partition.root = newSchedulingQueueInfo(rootInfo, nil)
+	_, err = createManagedQueue(partition.root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	_, err = createManagedQueue(partition.root, "limited", false, map[string]string{"first": "5"})
+	assert.NilError(t, err, "failed to create limited queue")
+	_, err = createManagedQueue(partition.root, "parent", true, nil)
+	assert.NilError(t, err, "failed to create parent queue")
+	conf := configs.QueueConfig{
+		Name:       "delayed",
+		Properties: map[string]string{cache.QueueStartDelay: "1h"},
+	}
+	var delayedInfo *cache.QueueInfo
+	delayedInfo, err = cache.NewManagedQueue(conf, partition.root.QueueInfo)
+	assert.NilError(t, err, "failed to create delayed queue")
+	newSchedulingQueueInfo(delayedInfo, partition.root)
+	conf = configs.QueueConfig{
+		Name:      "small",
+		Resources: configs.Resources{MaxAllocation: map[string]string{"first": "3"}},
+	}
+	var smallInfo *cache.QueueInfo
+	smallInfo, err = cache.NewManagedQueue(conf, partition.root.QueueInfo)
+	assert.NilError(t, err, "failed to create small queue")
+	newSchedulingQueueInfo(smallInfo, partition.root)
+	return partition
+}
+
+func TestExplainAsk(t *testing.T) {
+	var tests = []struct {
+		name      string
+		queue     string
+		user      string
+		res       map[string]resources.Quantity
+		selector  map[string]string
+		blockedBy string
+		nodes     []string
+	}{
+		{"schedulable", "root.leaf", "user1", map[string]resources.Quantity{"first": 5}, nil, "", []string{"node-1", "node-2"}},
+		{"node selector", "root.leaf", "user1", map[string]resources.Quantity{"first": 5}, map[string]string{"zone": "b"}, "", []string{"node-2"}},
+		{"no selected node", "root.leaf", "user1", map[string]resources.Quantity{"first": 5}, map[string]string{"zone": "c"}, explainNoNode, nil},
+		{"placement only", "root.limited", "user1", map[string]resources.Quantity{"first": 0}, map[string]string{"zone": "b"}, "", []string{"node-2"}},
+		{"negative resource", "root.leaf", "user1", map[string]resources.Quantity{"first": -1}, nil, string(api.RejectionInvalidResource), nil},
+		{"unknown queue", "root.unknown", "user1", map[string]resources.Quantity{"first": 5}, nil, string(api.RejectionQueueNotFound), nil},
+		{"parent queue", "root.parent", "user1", map[string]resources.Quantity{"first": 5}, nil, string(api.RejectionQueueNotFound), nil},
+		{"acl denied", "root.leaf", "user2", map[string]resources.Quantity{"first": 5}, nil, string(api.RejectionACLDenied), nil},
+		{"larger than node", "root.leaf", "user1", map[string]resources.Quantity{"first": 20}, nil, string(api.RejectionInvalidResource), nil},
+		{"larger than max", "root.limited", "user1", map[string]resources.Quantity{"first": 6}, nil, string(api.RejectionQuotaExceeded), nil},
+		{"larger than max allocation", "root.small", "user1", map[string]resources.Quantity{"first": 4}, nil, string(api.RejectionMaxAllocation), nil},
+		{"start delay", "root.delayed", "user1", map[string]resources.Quantity{"first": 5}, nil, explainQueueStartDelay, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			partition := createExplainPartition(t)
+			info := partition.explain(&ExplainAsk{
+				PartitionName: "default",
+				QueueName:     tt.queue,
+				User:          security.UserGroup{User: tt.user},
+				Resource:      resources.NewResourceFromMap(tt.res),
+				NodeSelector:  tt.selector,
+			})
+			assert.Equal(t, info.BlockedBy, tt.blockedBy, "unexpected blocking limit: %s", info.Reason)
+			assert.Equal(t, info.Schedulable, tt.blockedBy == "", "unexpected schedulable flag")
+			if tt.nodes == nil {
+				assert.Equal(t, len(info.Nodes), 0, "no nodes expected: %v", info.Nodes)
+			} else {
+				// nodes with the same available resource are returned in any order
+				sort.Strings(info.Nodes)
+				assert.DeepEqual(t, info.Nodes, tt.nodes)
+			}
+		})
+	}
+}
+
+func TestExplainAskTolerations(t *testing.T) {
+	partition := createExplainPartition(t)
+	partition.nodes["node-2"].taints = parseNodeTaints("node-2", "dedicated:NoSchedule")
+	ask := &ExplainAsk{
+		PartitionName: "default",
+		QueueName:     "root.leaf",
+		User:          security.UserGroup{User: "user1"},
+		Resource:      resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}),
+	}
+	info := partition.explain(ask)
+	assert.DeepEqual(t, info.Nodes, []string{"node-1"})
+	assert.Equal(t, info.NodesFiltered[traceNodeTaints], 1, "tainted node should have been filtered")
+
+	// nodes with the same available resource are not returned in a fixed order
+	ask.Tolerations = "dedicated"
+	info = partition.explain(ask)
+	sort.Strings(info.Nodes)
+	assert.DeepEqual(t, info.Nodes, []string{"node-1", "node-2"})
+
+	ask.Tolerations = "dedicated:Unknown"
+	info = partition.explain(ask)
+	assert.Equal(t, info.BlockedBy, string(api.RejectionInvalidTolerations), "invalid tolerations should block the ask")
+}
+
+func TestExplainAskNodeSelector(t *testing.T) {
+	partition := createExplainPartition(t)
+	info := partition.explain(&ExplainAsk{
+		PartitionName: "default",
+		QueueName:     "root.leaf",
+		User:          security.UserGroup{User: "user1"},
+		Resource:      resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}),
+		NodeSelector:  map[string]string{"zone": "a"},
+	})
+	assert.DeepEqual(t, info.Nodes, []string{"node-1"})
+	assert.Equal(t, info.NodesEvaluated, 1, "only the selected node should be evaluated")
+	assert.Equal(t, info.NodesFiltered[explainNodeSelector], 1, "node in zone b should have been filtered")
+}
+
+func TestExplainAskNoState(t *testing.T) {
+	partition := createExplainPartition(t)
+	leaf := partition.getQueue("root.limited")
+	used := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 4})
+	leaf.incAllocatingResource(used)
+	ask := &ExplainAsk{
+		PartitionName: "default",
+		QueueName:     "root.limited",
+		User:          security.UserGroup{User: "user1"},
+		Resource:      resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2}),
+	}
+	info := partition.explain(ask)
+	assert.Assert(t, !info.Schedulable, "ask should not fit in the headroom")
+	assert.Equal(t, info.BlockedBy, explainQueueHeadRoom, "unexpected blocking limit: %s", info.Reason)
+	assert.Equal(t, len(info.Queues), 2, "expected leaf and root headroom")
+	assert.Equal(t, info.Queues[0].HeadRoom, "[first:1]", "leaf headroom not correct")
+	assert.Equal(t, info.Queues[1].HeadRoom, "[first:96]", "root headroom not correct")
+	assert.Equal(t, info.LimitingQueue, "root.limited", "unexpected limiting queue")
+	assert.Equal(t, info.LimitingResource, "first", "unexpected limiting resource")
+
+	// nothing is tracked for the explained asks
+	ask.Resource = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	info = partition.explain(ask)
+	assert.Assert(t, info.Schedulable, "ask should fit: %s", info.Reason)
+	assert.Equal(t, info.NodesEvaluated, 2, "all nodes should be evaluated")
+	assert.Assert(t, resources.Equals(leaf.getAllocatingResource(), used), "allocating changed on queue")
+	assert.Assert(t, resources.IsZero(partition.getSchedulingNode("node-1").getAllocatingResource()), "allocating changed on node")
+
+	// reserved nodes are not listed
+	node := partition.getSchedulingNode("node-1")
+	node.reservations["app-1|node-1|alloc-1"] = &reservation{}
+	info = partition.explain(ask)
+	assert.DeepEqual(t, info.Nodes, []string{"node-2"})
+	assert.Equal(t, info.NodesFiltered[tracePreAllocateCheck], 1, "reserved node should be filtered")
+}
+
+func TestExplainAskPartitionNotFound(t *testing.T) {
+	csc := NewClusterSchedulingContext()
+	info := csc.ExplainAsk(&ExplainAsk{
+		PartitionName: "unknown",
+		QueueName:     "root.leaf",
+		User:          security.UserGroup{User: "user1"},
+		Resource:      resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1}),
+	})
+	assert.Assert(t, !info.Schedulable, "ask should not be schedulable")
+	assert.Equal(t, info.BlockedBy, string(api.RejectionPartitionNotFound), "unexpected blocking limit")
+}
@@ -21,11 +21,13 @@ package scheduler
 import (
 	"fmt"
 	"strings"
-	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
 	"github.com/apache/incubator-yunikorn-core/pkg/plugins"
@@ -42,8 +44,10 @@ type SchedulingNode struct {
 	cachedAvailable             *resources.Resource     // calculated available resources
 	cachedAvailableUpdateNeeded bool                    // is the calculated available resource up to date?
 	reservations                map[string]*reservation // a map of reservations
+	taints                      []nodeTaint             // the taints that filter asks, read only
+	gangHolds                   map[string]*gangHold    // headroom held for the members of gangs by reservation key
 
-	sync.RWMutex
+	locking.RWMutex
 }
 
 func newSchedulingNode(info *cache.NodeInfo) *SchedulingNode {
@@ -58,6 +62,8 @@ func newSchedulingNode(info *cache.NodeInfo) *SchedulingNode {
 		preempting:                  resources.NewResource(),
 		cachedAvailableUpdateNeeded: true,
 		reservations:                make(map[string]*reservation),
+		taints:                      parseNodeTaints(info.NodeID, info.GetAttribute(api.NodeTaints)),
+		gangHolds:                   make(map[string]*gangHold),
 	}
 }
 
@@ -171,7 +177,7 @@ func (sn *SchedulingNode) allocateResource(res *resources.Resource, preemptionPh
 		available.AddTo(sn.preempting)
 	}
 	// check if this still fits: it might have changed since pre check
-	if resources.FitIn(available, newAllocating) {
+	if sn.nodeInfo.FitIn(available, newAllocating) {
 		log.Logger().Debug("allocations in progress updated",
 			zap.String("nodeID", sn.NodeID),
 			zap.Any("total unconfirmed", newAllocating))
@@ -219,7 +225,7 @@ func (sn *SchedulingNode) preAllocateCheck(res *resources.Resource, resKey strin
 	if !sn.nodeInfo.IsSchedulable() {
 		log.Logger().Debug("node is unschedulable",
 			zap.String("nodeID", sn.NodeID))
-		return fmt.Errorf("pre alloc check, node is unschedulable: %s", sn.NodeID)
+		return api.NewError(api.ErrInvalidState, "pre alloc check, node is unschedulable: %s", sn.NodeID)
 	}
 	// cannot allocate zero or negative resource
 	if !resources.StrictlyGreaterThanZero(res) {
@@ -237,13 +243,14 @@ func (sn *SchedulingNode) preAllocateCheck(res *resources.Resource, resKey strin
 		}
 	}
 
-	// check if resources are available
+	// check if resources are available: the headroom held for the members of other gangs cannot be used
 	available := sn.nodeInfo.GetAvailableResource()
 	if preemptionPhase {
 		available.AddTo(sn.preempting)
 	}
+	available.SubFrom(sn.getGangHeld(resKey, time.Now()))
 	newAllocating := resources.Add(res, sn.getAllocatingResource())
-	if !resources.FitIn(available, newAllocating) {
+	if !sn.nodeInfo.FitIn(available, newAllocating) {
 		log.Logger().Debug("requested resource is larger than available node resources",
 			zap.String("nodeID", sn.NodeID),
 			zap.Any("available", available),
@@ -287,7 +294,7 @@ func (sn *SchedulingNode) reserve(app *SchedulingApplication, ask *schedulingAll
 	sn.Lock()
 	defer sn.Unlock()
 	if len(sn.reservations) > 0 {
-		return fmt.Errorf("node is already reserved, nodeID %s", sn.NodeID)
+		return api.NewError(api.ErrInvalidState, "node is already reserved, nodeID %s", sn.NodeID)
 	}
 	appReservation := newReservation(sn, app, ask, false)
 	// this should really not happen just guard against panic
@@ -339,6 +346,19 @@ func (sn *SchedulingNode) unReserve(app *SchedulingApplication, ask *schedulingA
 	return nil
 }
 
+// Return a copy of the reservations on the node older than the age passed in.
+func (sn *SchedulingNode) getReservationsOlder(age time.Duration) []*reservation {
+	sn.RLock()
+	defer sn.RUnlock()
+	var reservations []*reservation
+	for _, res := range sn.reservations {
+		if res.isOlder(age) {
+			reservations = append(reservations, res)
+		}
+	}
+	return reservations
+}
+
 // Remove all reservation made on this node from the app.
 // This is an unlocked function, it does not use a copy of the map when calling unReserve. That call will via the app call
 // unReserve on the node which is locked and modifies the original map. However deleting an entry from a map while iterating
@@ -183,6 +183,19 @@ func TestCheckAllocate(t *testing.T) {
 	}
 }
 
+func TestIgnoredResourceTypes(t *testing.T) {
+	node := newNode("node-1", map[string]resources.Quantity{"first": 10, "second": 1})
+	cache.SetNodeIgnoredResourceTypes(node.nodeInfo, []string{"second"})
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5, "second": 2})
+	assert.NilError(t, node.preAllocateCheck(res, "", false), "ignored type should not block the pre allocation check")
+	assert.Assert(t, node.allocateResource(res, false), "ignored type should not block the allocation")
+	assert.Equal(t, node.getAllocatingResource().Resources["second"], resources.Quantity(2), "ignored type should be tracked")
+	// the other types are still checked
+	res = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 6})
+	assert.Assert(t, node.preAllocateCheck(res, "", false) != nil, "type that is not ignored should block the check")
+	assert.Assert(t, !node.allocateResource(res, false), "type that is not ignored should block the allocation")
+}
+
 func TestAllocatingResources(t *testing.T) {
 	node := newNode("node-1", map[string]resources.Quantity{"first": 100})
 	if node == nil || node.NodeID != "node-1" {
@@ -20,17 +20,22 @@ package scheduler
 
 import (
 	"fmt"
+	"sort"
 	"strings"
-	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 	"github.com/apache/incubator-yunikorn-core/pkg/common"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
 	"github.com/apache/incubator-yunikorn-core/pkg/scheduler/placement"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
 )
 
 type partitionSchedulingContext struct {
@@ -38,15 +43,25 @@ type partitionSchedulingContext struct {
 	Name string // name of the partition (logging mainly)
 
 	// Private fields need protection
-	partition        *cache.PartitionInfo              // link back to the partition in the cache
-	root             *SchedulingQueue                  // start of the scheduling queue hierarchy
-	applications     map[string]*SchedulingApplication // applications assigned to this partition
-	reservedApps     map[string]int                    // applications reserved within this partition, with reservation count
-	nodes            map[string]*SchedulingNode        // nodes assigned to this partition
-	placementManager *placement.AppPlacementManager    // placement manager for this partition
-	partitionManager *partitionManager                 // manager for this partition
-
-	sync.RWMutex
+	partition            *cache.PartitionInfo                         // link back to the partition in the cache
+	root                 *SchedulingQueue                             // start of the scheduling queue hierarchy
+	applications         map[string]*SchedulingApplication            // applications assigned to this partition
+	reservedApps         map[string]int                               // applications reserved within this partition, with reservation count
+	nodes                map[string]*SchedulingNode                   // nodes assigned to this partition
+	nodeAttributes       *nodeAttributeIndex                          // nodes assigned to this partition by attribute
+	maxNodeResource      *resources.Resource                          // component wise maximum of the capacity of all nodes
+	pendingPreemptions   map[string]*pendingPreemption                // checkpointable allocations waiting for the grace period to pass
+	utilizationTriggered bool                                         // preemption triggered by the partition utilization
+	placementManager     *placement.AppPlacementManager               // placement manager for this partition
+	partitionManager     *partitionManager                            // manager for this partition
+	fairShares           *FairShareCalculator                         // fair shares calculated at the start of the last scheduling cycle
+	fairness             *fairnessTracker                             // fair shares compared to the usage over time
+	autoscale            map[string]*autoscaleState                   // leaf queues with the pending resource above the autoscale watermark
+	appGroups            map[string]map[string]*SchedulingApplication // applications per application group
+	load                 *loadTracker                                 // smoothed load of the partition
+	cycle                *cycleTracker                                // allocations of the leaf queues in the current scheduling cycle
+
+	locking.RWMutex
 }
 
 // Create a new partitioning scheduling context.
@@ -56,15 +71,24 @@ func newPartitionSchedulingContext(info *cache.PartitionInfo, root *SchedulingQu
 		return nil
 	}
 	psc := &partitionSchedulingContext{
-		applications: make(map[string]*SchedulingApplication),
-		reservedApps: make(map[string]int),
-		nodes:        make(map[string]*SchedulingNode),
-		root:         root,
-		Name:         info.Name,
-		RmID:         info.RmID,
-		partition:    info,
+		applications:       make(map[string]*SchedulingApplication),
+		reservedApps:       make(map[string]int),
+		nodes:              make(map[string]*SchedulingNode),
+		nodeAttributes:     newNodeAttributeIndex(),
+		maxNodeResource:    resources.NewResource(),
+		pendingPreemptions: make(map[string]*pendingPreemption),
+		fairness:           newFairnessTracker(fairnessWindow),
+		autoscale:          make(map[string]*autoscaleState),
+		appGroups:          make(map[string]map[string]*SchedulingApplication),
+		load:               newLoadTracker(),
+		cycle:              newCycleTracker(),
+		root:               root,
+		Name:               info.Name,
+		RmID:               info.RmID,
+		partition:          info,
 	}
 	psc.placementManager = placement.NewPlacementManager(info)
+	psc.updatePendingBatching()
 	return psc
 }
 
@@ -75,7 +99,7 @@ func (psc *partitionSchedulingContext) updatePartitionSchedulingContext(info *ca
 
 	if psc.placementManager.IsInitialised() {
 		log.Logger().Info("Updating placement manager rules on config reload")
-		err := psc.placementManager.UpdateRules(info.GetRules())
+		err := psc.placementManager.RefreshRules()
 		if err != nil {
 			log.Logger().Info("New placement rules not activated, config reload failed", zap.Error(err))
 		}
@@ -88,6 +112,11 @@ func (psc *partitionSchedulingContext) updatePartitionSchedulingContext(info *ca
 	root.updateSchedulingQueueProperties(info.Root.Properties)
 	// update the rest of the queues recursively
 	root.updateSchedulingQueueInfo(info.Root.GetCopyOfChildren(), root)
+	// the max resources are used directly in the headroom, the fair shares follow the new guarantees before the
+	// next scheduling cycle
+	psc.fairShares = psc.calculateFairShares()
+	// the throughput mode can be switched on or off
+	psc.updatePendingBatching()
 }
 
 // Add a new application to the scheduling partition.
@@ -98,7 +127,7 @@ func (psc *partitionSchedulingContext) addSchedulingApplication(schedulingApp *S
 	// Add to applications
 	appID := schedulingApp.ApplicationInfo.ApplicationID
 	if psc.applications[appID] != nil {
-		return fmt.Errorf("adding application %s to partition %s, but application already existed", appID, psc.Name)
+		return api.NewRejectionError(api.RejectionApplicationExists, "adding application %s to partition %s, but application already existed", appID, psc.Name)
 	}
 
 	// Put app under the scheduling queue, the app has already been placed in the partition cache
@@ -106,7 +135,7 @@ func (psc *partitionSchedulingContext) addSchedulingApplication(schedulingApp *S
 	if psc.placementManager.IsInitialised() {
 		err := psc.placementManager.PlaceApplication(schedulingApp.ApplicationInfo)
 		if err != nil {
-			return fmt.Errorf("failed to place app in requested queue '%s' for application %s: %v", queueName, appID, err)
+			return api.NewRejectionError(api.RejectionQueueNotFound, "failed to place app in requested queue '%s' for application %s: %v", queueName, appID, err)
 		}
 		// pull out the queue name from the placement
 		queueName = schedulingApp.ApplicationInfo.QueueName
@@ -114,9 +143,11 @@ func (psc *partitionSchedulingContext) addSchedulingApplication(schedulingApp *S
 	// we have a queue name either from placement or direct
 	schedulingQueue := psc.getQueue(queueName)
 	// check if the queue already exist and what we have is a leaf queue with submit access
-	if schedulingQueue != nil &&
-		(!schedulingQueue.isLeafQueue() || !schedulingQueue.checkSubmitAccess(schedulingApp.ApplicationInfo.GetUser())) {
-		return fmt.Errorf("failed to find queue %s for application %s", schedulingApp.ApplicationInfo.QueueName, appID)
+	if schedulingQueue != nil && !schedulingQueue.isLeafQueue() {
+		return api.NewRejectionError(api.RejectionQueueNotFound, "failed to find queue %s for application %s", schedulingApp.ApplicationInfo.QueueName, appID)
+	}
+	if schedulingQueue != nil && !schedulingQueue.checkSubmitAccess(schedulingApp.ApplicationInfo.GetUser()) {
+		return api.NewRejectionError(api.RejectionACLDenied, "submit access denied on queue %s for application %s", schedulingApp.ApplicationInfo.QueueName, appID)
 	}
 	// with placement rules the hierarchy might not exist so try and create it
 	if schedulingQueue == nil {
@@ -124,7 +155,7 @@ func (psc *partitionSchedulingContext) addSchedulingApplication(schedulingApp *S
 		// find the scheduling queue: if it still does not exist we fail the app
 		schedulingQueue = psc.getQueue(queueName)
 		if schedulingQueue == nil {
-			return fmt.Errorf("failed to find queue %s for application %s", schedulingApp.ApplicationInfo.QueueName, appID)
+			return api.NewRejectionError(api.RejectionQueueNotFound, "failed to find queue %s for application %s", schedulingApp.ApplicationInfo.QueueName, appID)
 		}
 	}
 
@@ -132,6 +163,7 @@ func (psc *partitionSchedulingContext) addSchedulingApplication(schedulingApp *S
 	schedulingApp.queue = schedulingQueue
 	schedulingQueue.addSchedulingApplication(schedulingApp)
 	psc.applications[appID] = schedulingApp
+	psc.addToApplicationGroup(schedulingApp)
 
 	return nil
 }
@@ -148,6 +180,7 @@ func (psc *partitionSchedulingContext) removeSchedulingApplication(appID string)
 	schedulingApp := psc.applications[appID]
 	delete(psc.applications, appID)
 	delete(psc.reservedApps, appID)
+	psc.removeFromApplicationGroup(schedulingApp)
 
 	// Remove all asks and thus all reservations and pending resources (queue included)
 	queueName := schedulingApp.ApplicationInfo.QueueName
@@ -163,13 +196,63 @@ func (psc *partitionSchedulingContext) removeSchedulingApplication(appID string)
 		log.Logger().Warn("failed to find assigned queue while removing application",
 			zap.String("queue", queueName),
 			zap.String("applicationID", appID))
-		return nil, fmt.Errorf("failed to find queue %s while removing application %s", queueName, appID)
+		return nil, api.NewError(api.ErrQueueNotFound, "failed to find queue %s while removing application %s", queueName, appID)
 	}
 	schedulingQueue.removeSchedulingApplication(schedulingApp)
+	recordRuntimeEstimate(schedulingApp)
 
 	return schedulingApp, nil
 }
 
+// Move the application to another leaf queue in the partition. The pending resources and reservations of the
+// application move with it, the allocated resources are moved in the cache. The user of the application must have
+// submit access to the new queue. An application with allocations in flight cannot be moved.
+func (psc *partitionSchedulingContext) moveSchedulingApplication(appID, queueName string) error {
+	psc.Lock()
+	defer psc.Unlock()
+
+	app := psc.applications[appID]
+	if app == nil {
+		return fmt.Errorf("application %s not found in partition %s", appID, psc.Name)
+	}
+	target := psc.getQueue(queueName)
+	if target == nil || !target.isLeafQueue() {
+		return api.NewError(api.ErrQueueNotFound, "failed to find leaf queue %s for application %s", queueName, appID)
+	}
+	source := app.queue
+	if source == target {
+		return nil
+	}
+	if !target.checkSubmitAccess(app.ApplicationInfo.GetUser()) {
+		return api.NewRejectionError(api.RejectionACLDenied, "submit access denied on queue %s for application %s", queueName, appID)
+	}
+	if !resources.IsZero(app.getAllocatingResource()) {
+		return api.NewError(api.ErrInvalidState, "application %s has allocations in flight and cannot be moved", appID)
+	}
+	// the cache is the source of truth for the allocated resources: move there first
+	if err := psc.partition.MoveApplication(appID, target.Name); err != nil {
+		return err
+	}
+	reserved := app.getReservationCount()
+	source.removeSchedulingApplication(app)
+	for i := 0; i < reserved; i++ {
+		source.unReserve(appID)
+	}
+	app.setQueue(target)
+	target.addSchedulingApplication(app)
+	target.incPendingResource(app.GetPendingResource())
+	target.incPendingPlacements(app.getPendingPlacements())
+	for i := 0; i < reserved; i++ {
+		target.reserve(appID)
+	}
+	log.Logger().Info("application moved in the scheduler",
+		zap.String("applicationID", appID),
+		zap.String("fromQueue", source.Name),
+		zap.String("toQueue", target.Name),
+		zap.Int("reservations", reserved))
+	return nil
+}
+
 // Return a copy of the map of all reservations for the partition.
 // This will return an empty map if there are no reservations.
 // Visible for tests
@@ -192,6 +275,23 @@ func (psc *partitionSchedulingContext) GetQueue(name string) *SchedulingQueue {
 	return psc.getQueue(name)
 }
 
+// Add the utilization of the queues to the queue infos and their children.
+func (psc *partitionSchedulingContext) addQueueUtilization(infos []dao.QueueDAOInfo) {
+	for i := range infos {
+		if queue := psc.GetQueue(infos[i].QueueName); queue != nil {
+			capacities := &infos[i].Capacities
+			capacities.MaxUtilization, capacities.GuaranteedUtilization = queue.GetUtilization()
+			for _, ratio := range capacities.GuaranteedUtilization {
+				if ratio > 1 {
+					capacities.OverGuaranteed = true
+					break
+				}
+			}
+		}
+		psc.addQueueUtilization(infos[i].ChildQueues)
+	}
+}
+
 // Get the queue from the structure based on the fully qualified name.
 // The name is not syntax checked and must be valid.
 // Returns nil if the queue is not found otherwise the queue object.
@@ -272,13 +372,23 @@ func (psc *partitionSchedulingContext) getSchedulableNodes() []*SchedulingNode {
 	return psc.getSchedulingNodes(true)
 }
 
+// Get the schedulable nodes from the partition in the reusable list, see getSchedulableNodes.
+func (psc *partitionSchedulingContext) getSchedulableNodesInto(list *nodeList) []*SchedulingNode {
+	list.nodes = psc.appendSchedulingNodes(list.nodes[:0], true)
+	return list.nodes
+}
+
 // Get a copy of the scheduling nodes from the partition.
 // Excludes unschedulable nodes only, reserved node inclusion depends on the parameter passed in.
 func (psc *partitionSchedulingContext) getSchedulingNodes(excludeReserved bool) []*SchedulingNode {
+	return psc.appendSchedulingNodes(make([]*SchedulingNode, 0), excludeReserved)
+}
+
+// Append the scheduling nodes from the partition to the list, see getSchedulingNodes.
+func (psc *partitionSchedulingContext) appendSchedulingNodes(schedulingNodes []*SchedulingNode, excludeReserved bool) []*SchedulingNode {
 	psc.RLock()
 	defer psc.RUnlock()
 
-	schedulingNodes := make([]*SchedulingNode, 0)
 	for _, node := range psc.nodes {
 		// filter out the nodes that are not scheduling
 		if !node.nodeInfo.IsSchedulable() || (excludeReserved && node.isReserved()) {
@@ -289,6 +399,26 @@ func (psc *partitionSchedulingContext) getSchedulingNodes(excludeReserved bool)
 	return schedulingNodes
 }
 
+// Get the scheduling nodes from the partition that have all the attributes of the selector, see getSchedulingNodes.
+// The nodes are looked up in the attribute index: the nodes that do not match the selector are not looked at, only
+// their number is returned with the nodes. An empty selector returns all nodes.
+func (psc *partitionSchedulingContext) getSchedulingNodesWithSelector(selector map[string]string, excludeReserved bool) ([]*SchedulingNode, int) {
+	if len(selector) == 0 {
+		return psc.getSchedulingNodes(excludeReserved), 0
+	}
+	psc.RLock()
+	defer psc.RUnlock()
+	matched := psc.nodeAttributes.match(selector)
+	schedulingNodes := make([]*SchedulingNode, 0, len(matched))
+	for _, node := range matched {
+		if !node.nodeInfo.IsSchedulable() || (excludeReserved && node.isReserved()) {
+			continue
+		}
+		schedulingNodes = append(schedulingNodes, node)
+	}
+	return schedulingNodes, len(psc.nodes) - len(matched)
+}
+
 // Add a new scheduling node triggered on the addition of the cache node.
 // This will log if the scheduler is out of sync with the cache.
 // As a side effect it will bring the cache and scheduler back into sync.
@@ -300,12 +430,16 @@ func (psc *partitionSchedulingContext) addSchedulingNode(info *cache.NodeInfo) {
 	psc.Lock()
 	defer psc.Unlock()
 	// check consistency and reset to make sure it is consistent again
-	if _, ok := psc.nodes[info.NodeID]; ok {
+	if existing, ok := psc.nodes[info.NodeID]; ok {
 		log.Logger().Debug("new node already existed: cache out of sync with scheduler",
 			zap.String("nodeID", info.NodeID))
+		psc.nodeAttributes.removeNode(existing)
 	}
 	// add the node, this will also get the sync back between the two lists
-	psc.nodes[info.NodeID] = newSchedulingNode(info)
+	node := newSchedulingNode(info)
+	psc.nodes[info.NodeID] = node
+	psc.nodeAttributes.addNode(node)
+	psc.maxNodeResource = resources.ComponentWiseMax(psc.maxNodeResource, info.GetCapacity())
 }
 
 // Remove a scheduling node triggered by the removal of the cache node.
@@ -327,6 +461,8 @@ func (psc *partitionSchedulingContext) removeSchedulingNode(nodeID string) {
 	}
 	// remove the node, this will also get the sync back between the two lists
 	delete(psc.nodes, nodeID)
+	psc.nodeAttributes.removeNode(node)
+	psc.updateMaxNodeResource()
 	// unreserve all the apps that were reserved on the node
 	var reservedKeys []string
 	reservedKeys, ok = node.unReserveApps()
@@ -340,6 +476,64 @@ func (psc *partitionSchedulingContext) removeSchedulingNode(nodeID string) {
 	}
 }
 
+// Recalculate the largest node resource from all nodes in the partition.
+// Lock free call this must be called holding the partition lock
+func (psc *partitionSchedulingContext) updateMaxNodeResource() {
+	maxRes := resources.NewResource()
+	for _, node := range psc.nodes {
+		maxRes = resources.ComponentWiseMax(maxRes, node.nodeInfo.GetCapacity())
+	}
+	psc.maxNodeResource = maxRes
+}
+
+// Get the largest schedulable ask size in the partition.
+// This is the component wise maximum of the capacity of all nodes in the partition. Any ask that does not fit in this
+// resource can never be scheduled on any of the current nodes.
+func (psc *partitionSchedulingContext) getMaxNodeResource() *resources.Resource {
+	psc.RLock()
+	defer psc.RUnlock()
+
+	return psc.maxNodeResource.Clone()
+}
+
+// Check if the requested resource could ever be satisfied by a node in the partition.
+// If the partition has no nodes registered yet we cannot decide and the request is assumed to fit.
+// The resource types ignored in the node fit checks are not checked, as on the nodes.
+func (psc *partitionSchedulingContext) isSchedulable(res *resources.Resource) bool {
+	psc.RLock()
+	defer psc.RUnlock()
+
+	if len(psc.nodes) == 0 {
+		return true
+	}
+	return psc.partition.FitInIgnoringTypes(psc.maxNodeResource, res)
+}
+
+// Describe why the requested resource can never be satisfied by a node in the partition: the first resource type,
+// sorted by name and not ignored in the node fit checks, that is larger than on the largest node. The quantities are
+// shown in the configured units. Returns an empty string if the requested resource fits on the largest node.
+func (psc *partitionSchedulingContext) describeNodeLimit(res *resources.Resource) string {
+	maxRes := psc.getMaxNodeResource()
+	if res == nil || psc.partition.FitInIgnoringTypes(maxRes, res) {
+		return ""
+	}
+	ignored := make(map[string]bool)
+	for _, key := range psc.partition.GetIgnoredResourceTypes() {
+		ignored[key] = true
+	}
+	keys := make([]string, 0, len(res.Resources))
+	for key, value := range res.Resources {
+		if value > maxRes.Resources[key] && !ignored[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	key := keys[0]
+	unit := psc.partition.GetResourceUnits()[key]
+	return fmt.Sprintf("%s %s exceeds largest node %s", key,
+		configs.FormatQuantity(int64(res.Resources[key]), unit), configs.FormatQuantity(int64(maxRes.Resources[key]), unit))
+}
+
 // Try regular allocation for the partition
 // Lock free call this all locks are taken when needed in called functions
 func (psc *partitionSchedulingContext) tryAllocate() *schedulingAllocation {
@@ -409,6 +603,10 @@ func (psc *partitionSchedulingContext) allocate(alloc *schedulingAllocation) boo
 			return false
 		}
 	}
+	// a member of a gang is placed: hold the headroom for the members that are not placed yet
+	if alloc.schedulingAsk.gangHoldTime > 0 {
+		psc.holdGangHeadroom(app, alloc.schedulingAsk, time.Now())
+	}
 	return true
 }
 
@@ -416,20 +614,22 @@ func (psc *partitionSchedulingContext) allocate(alloc *schedulingAllocation) boo
 // This updates the allocating resources for app, queue and node in the scheduler
 // Called for both allocations from reserved as well as for direct allocations.
 // The unreserve is already handled before we get here so there is no difference in handling.
+// The allocated resource is the resource of the proposal: this is not the requested resource of the ask if an
+// alternative shape was allocated.
 // Lock free call this must be called holding the context lock
-func (psc *partitionSchedulingContext) confirmAllocation(appID, nodeID, allocKey string, confirm bool) error {
+func (psc *partitionSchedulingContext) confirmAllocation(appID, nodeID, allocKey string, allocated *resources.Resource, confirm bool) error {
+	// only lock the partition for the lookup: the application is locked before the partition while allocating
 	psc.RLock()
-	defer psc.RUnlock()
-	// partition is locked nothing can change from now on
-	// find the app make sure it still exists
 	app := psc.applications[appID]
+	node := psc.nodes[nodeID]
+	psc.RUnlock()
+	// make sure the app still exists
 	if app == nil {
-		return fmt.Errorf("application was removed while allocating: %s", appID)
+		return api.NewError(api.ErrInvalidState, "application was removed while allocating: %s", appID)
 	}
-	// find the node make sure it still exists
-	node := psc.nodes[nodeID]
+	// make sure the node still exists
 	if node == nil {
-		return fmt.Errorf("node was removed while allocating app %s: %s", appID, nodeID)
+		return api.NewError(api.ErrInvalidState, "node was removed while allocating app %s: %s", appID, nodeID)
 	}
 	log.Logger().Debug("allocation confirmation on partition",
 		zap.String("partition", psc.Name),
@@ -437,23 +637,19 @@ func (psc *partitionSchedulingContext) confirmAllocation(appID, nodeID, allocKey
 		zap.String("nodeID", nodeID),
 		zap.String("allocKey", allocKey),
 		zap.Bool("confirmation", confirm))
-	// The repeat gets "added back" when rejected, it was removed during the try
-	var delta *resources.Resource
-	if !confirm {
-		var err error
-		delta, err = app.updateAskRepeat(allocKey, 1)
-		if err != nil {
-			return err
-		}
-	} else {
-		delta = app.GetSchedulingAllocationAsk(allocKey).AllocatedResource
+	if confirm {
+		app.allocationConfirmed()
 	}
+	delta := allocated
 
 	// this is a confirmation or rejection update all objects of inflight allocating resources
+	// a rejection must always roll back the allocating resources, even if the ask cannot be re-queued
 	if !resources.IsZero(delta) {
 		// update the allocating values with the delta
 		app.decAllocatingResource(delta)
 		app.queue.decAllocatingResource(delta)
+		app.queue.decPoolAllocatingResource(node.nodeInfo.Pool, delta)
+		app.queue.decUserAllocatingResource(app.ApplicationInfo.GetUser().User, delta)
 		node.decAllocatingResource(delta)
 		log.Logger().Debug("confirm allocation updating allocating",
 			zap.String("partition", psc.Name),
@@ -462,10 +658,34 @@ func (psc *partitionSchedulingContext) confirmAllocation(appID, nodeID, allocKey
 			zap.String("allocKey", allocKey),
 			zap.String("delta", delta.String()))
 	}
+	// The repeat gets "added back" when rejected, it was removed during the try
+	if !confirm {
+		if _, err := app.updateAskRepeat(allocKey, 1); err != nil {
+			return err
+		}
+	}
 	// all is ok when we are here
 	return nil
 }
 
+// Re-queue the ask of an allocation that was committed in the cache but could not be delivered to the RM.
+// The cache has already removed the allocation: only the repeat of the ask needs to be added back.
+// The ask could have been removed by the RM in the meantime, an error is returned in that case.
+func (psc *partitionSchedulingContext) rollbackAllocation(appID, allocKey string) error {
+	psc.RLock()
+	app := psc.applications[appID]
+	psc.RUnlock()
+	if app == nil {
+		return api.NewError(api.ErrInvalidState, "application was removed before rollback: %s", appID)
+	}
+	log.Logger().Info("rolling back allocation, ask re-queued",
+		zap.String("partition", psc.Name),
+		zap.String("appID", appID),
+		zap.String("allocKey", allocKey))
+	_, err := app.updateAskRepeat(allocKey, 1)
+	return err
+}
+
 // Process the reservation in the scheduler
 // Lock free call this must be called holding the context lock
 func (psc *partitionSchedulingContext) reserve(app *SchedulingApplication, node *SchedulingNode, ask *schedulingAllocationAsk) {
@@ -477,6 +697,15 @@ func (psc *partitionSchedulingContext) reserve(app *SchedulingApplication, node
 			zap.String("nodeID", node.NodeID))
 		return
 	}
+	// the partition limits the reservations outstanding
+	if !psc.canReserve(ask) {
+		log.Logger().Debug("Reservation limit of partition reached",
+			zap.String("partition", psc.Name),
+			zap.String("appID", appID),
+			zap.String("nodeID", node.NodeID),
+			zap.String("allocationKey", ask.AskProto.AllocationKey))
+		return
+	}
 	// all ok, add the reservation to the app, this will also reserve the node
 	if err := app.reserve(node, ask); err != nil {
 		log.Logger().Info("Failed to handle reservation, error during update of app",
@@ -490,6 +719,34 @@ func (psc *partitionSchedulingContext) reserve(app *SchedulingApplication, node
 	psc.reservedApps[appID]++
 }
 
+// Check if a new reservation for the ask stays within the reservation limits of the partition.
+// The resource limit only applies to the resource types that are set in the limit.
+// Lock free call this must be called holding the context lock
+func (psc *partitionSchedulingContext) canReserve(ask *schedulingAllocationAsk) bool {
+	maxReservations, maxResource := psc.partition.GetReservationLimits()
+	if maxReservations > 0 {
+		count := 0
+		for _, num := range psc.reservedApps {
+			count += num
+		}
+		if count >= maxReservations {
+			return false
+		}
+	}
+	if maxResource != nil {
+		reserved := ask.AllocatedResource.Clone()
+		for appID := range psc.reservedApps {
+			if app := psc.applications[appID]; app != nil {
+				reserved.AddTo(app.getReservedResource())
+			}
+		}
+		if !resources.FitInDefined(maxResource, reserved) {
+			return false
+		}
+	}
+	return true
+}
+
 // Process the unreservation in the scheduler
 // Lock free call this must be called holding the context lock
 func (psc *partitionSchedulingContext) unReserve(app *SchedulingApplication, node *SchedulingNode, ask *schedulingAllocationAsk) {
@@ -512,27 +769,70 @@ func (psc *partitionSchedulingContext) unReserve(app *SchedulingApplication, nod
 }
 
 // Get the iterator for the sorted nodes list from the partition.
-func (psc *partitionSchedulingContext) getNodeIteratorForPolicy(nodes []*SchedulingNode) NodeIterator {
-	// Sort Nodes based on the policy configured.
+// The nodes are sorted on the weighted free fraction if resource weights are set for the queue or the partition.
+func (psc *partitionSchedulingContext) getNodeIteratorForPolicy(nodes []*SchedulingNode, queue *SchedulingQueue) NodeIterator {
+	if !psc.sortNodesForPolicy(nodes, queue) {
+		return nil
+	}
+	return NewDefaultNodeIterator(nodes)
+}
+
+// Sort the nodes based on the policy configured for the partition.
+// Returns false if the policy is unknown, the nodes are not sorted in that case.
+func (psc *partitionSchedulingContext) sortNodesForPolicy(nodes []*SchedulingNode, queue *SchedulingQueue) bool {
+	var sortType SortType
 	configuredPolicy := psc.partition.GetNodeSortingPolicy()
 	switch configuredPolicy {
 	case common.BinPackingPolicy:
-		sortNodes(nodes, MinAvailableResources)
-		return NewDefaultNodeIterator(nodes)
+		sortType = MinAvailableResources
 	case common.FairnessPolicy:
-		sortNodes(nodes, MaxAvailableResources)
-		return NewDefaultNodeIterator(nodes)
+		sortType = MaxAvailableResources
+	default:
+		return false
 	}
-	return nil
+	if weights := psc.getNodeSortWeights(queue); weights != nil {
+		sortNodesWeighted(nodes, sortType, weights)
+	} else {
+		sortNodes(nodes, sortType)
+	}
+	return true
 }
 
-// Create a node iterator for the schedulable nodes based on the policy set for this partition.
-// The iterator is nil if there are no schedulable nodes available.
-func (psc *partitionSchedulingContext) getNodeIterator() NodeIterator {
-	if nodeList := psc.getSchedulableNodes(); len(nodeList) != 0 {
-		return psc.getNodeIteratorForPolicy(nodeList)
+// Get the resource weights to sort the nodes for the applications in the queue: the weights set on the queue, or
+// the weights of the partition if the queue does not set them. Returns nil if no weights are set.
+func (psc *partitionSchedulingContext) getNodeSortWeights(queue *SchedulingQueue) map[string]float64 {
+	if queue != nil {
+		if weights := queue.QueueInfo.GetNodeSortWeights(); weights != nil {
+			return weights
+		}
 	}
-	return nil
+	return psc.partition.GetNodeSortWeights()
+}
+
+// Create a node iterator for the nodes based on the policy set for this partition and the queue.
+// The list of nodes is copied before sorting: the same list can be used for multiple iterators.
+// In throughput mode only a random sample of the nodes is sorted, the nodes outside the sample follow the sample.
+// The iterator is nil if there are no nodes in the list.
+func (psc *partitionSchedulingContext) getNodeIterator(nodes []*SchedulingNode, queue *SchedulingQueue) NodeIterator {
+	return psc.getNodeIteratorInto(&nodeList{}, nodes, queue)
+}
+
+// Create a node iterator for the nodes like getNodeIterator, the nodes are copied into the reusable list.
+// The iterator uses the list: it must not be used after the list is reused or released.
+func (psc *partitionSchedulingContext) getNodeIteratorInto(list *nodeList, nodes []*SchedulingNode, queue *SchedulingQueue) NodeIterator {
+	if len(nodes) == 0 {
+		return nil
+	}
+	list.nodes = append(list.nodes[:0], nodes...)
+	nodeList := list.nodes
+	if enabled, sampleSize := psc.partition.GetThroughput(); enabled && len(nodeList) > sampleSize {
+		sampleNodes(nodeList, sampleSize)
+		if !psc.sortNodesForPolicy(nodeList[:sampleSize], queue) {
+			return nil
+		}
+		return NewDefaultNodeIterator(nodeList)
+	}
+	return psc.getNodeIteratorForPolicy(nodeList, queue)
 }
 
 // Locked version of the reservation counter update
@@ -555,3 +855,48 @@ func (psc *partitionSchedulingContext) unReserveCount(appID string, asks int) {
 		}
 	}
 }
+
+// Calculate the fair shares of all queues in the partition and cache them on the queues and the partition.
+// This is called once at the start of each scheduling cycle.
+// Lock free call all locks are taken when needed in called functions
+func (psc *partitionSchedulingContext) updateFairShares() {
+	calc := psc.calculateFairShares()
+	psc.Lock()
+	defer psc.Unlock()
+	psc.fairShares = calc
+	psc.fairness.record(calc, time.Now())
+}
+
+// Calculate the fair shares of all queues in the partition and cache them on the queues.
+// Lock free call, the partition lock is not taken: the queues lock themselves
+func (psc *partitionSchedulingContext) calculateFairShares() *FairShareCalculator {
+	calc := newFairShareCalculatorForQueues(psc.root, psc.partition.GetTotalPartitionResource())
+	var update func(queue *SchedulingQueue)
+	update = func(queue *SchedulingQueue) {
+		queue.setFairShare(calc.GetFairShare(queue.Name))
+		for _, child := range queue.GetCopyOfChildren() {
+			update(child)
+		}
+	}
+	update(psc.root)
+	return calc
+}
+
+// Get the fair shares calculated in the last scheduling cycle.
+// If no cycle has run yet the fair shares are calculated, but not cached.
+func (psc *partitionSchedulingContext) getFairShares() *FairShareCalculator {
+	psc.RLock()
+	calc := psc.fairShares
+	psc.RUnlock()
+	if calc == nil {
+		calc = newFairShareCalculatorForQueues(psc.root, psc.partition.GetTotalPartitionResource())
+	}
+	return calc
+}
+
+// Get the fairness debt of the queue over the fairness window, 0 if the queue is not tracked.
+func (psc *partitionSchedulingContext) getFairnessDebt(queuePath string) float64 {
+	psc.RLock()
+	defer psc.RUnlock()
+	return psc.fairness.getDebt(queuePath, time.Now())
+}
@@ -24,8 +24,11 @@ import (
 
 	"gotest.tools/assert"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 )
 
 func newTestPartition() (*partitionSchedulingContext, error) {
@@ -95,6 +98,52 @@ func TestRemoveNode(t *testing.T) {
 	assert.Equal(t, 0, len(partition.nodes), "node was not removed")
 }
 
+func TestMaxNodeResource(t *testing.T) {
+	partition, err := newTestPartition()
+	if err != nil {
+		t.Fatalf("test partition create failed with error: %v ", err)
+	}
+	large := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100, "second": 100})
+	// no nodes: everything is assumed to fit
+	assert.Assert(t, resources.IsZero(partition.getMaxNodeResource()), "max node resource should be empty")
+	assert.Assert(t, partition.isSchedulable(large), "ask should be schedulable without nodes")
+
+	partition.addSchedulingNode(cache.NewNodeForTest("node-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100, "second": 10})))
+	partition.addSchedulingNode(cache.NewNodeForTest("node-2", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 50})))
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100, "second": 50})
+	assert.Assert(t, resources.Equals(expected, partition.getMaxNodeResource()), "max node resource not correct: %v", partition.getMaxNodeResource())
+	assert.Assert(t, !partition.isSchedulable(large), "ask larger than all nodes should not be schedulable")
+	assert.Assert(t, partition.isSchedulable(expected), "ask equal to max node resource should be schedulable")
+	unknown := resources.NewResourceFromMap(map[string]resources.Quantity{"unknown": 1})
+	assert.Assert(t, !partition.isSchedulable(unknown), "ask for resource type not on any node should not be schedulable")
+
+	// the first resource type that does not fit is described in configured units
+	assert.Equal(t, partition.describeNodeLimit(expected), "", "ask equal to max node resource should not be described")
+	assert.Equal(t, partition.describeNodeLimit(large), "second 100 exceeds largest node 50")
+	assert.Equal(t, partition.describeNodeLimit(unknown), "unknown 1 exceeds largest node 0")
+	cache.SetResourceUnits(partition.partition, map[string]int64{"second": 10})
+	assert.Equal(t, partition.describeNodeLimit(large), "second 10 exceeds largest node 5")
+	cache.SetResourceUnits(partition.partition, nil)
+
+	// ignored types are not checked, the first type that is not ignored is described
+	cache.SetIgnoredResourceTypes(partition.partition, []string{"unknown", "second"})
+	assert.Assert(t, partition.isSchedulable(unknown), "ask for an ignored resource type should be schedulable")
+	assert.Equal(t, partition.describeNodeLimit(unknown), "", "ask for an ignored resource type should not be described")
+	assert.Assert(t, partition.isSchedulable(large), "ask larger than all nodes for an ignored type only should be schedulable")
+	larger := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 200, "second": 100})
+	assert.Assert(t, !partition.isSchedulable(larger), "ask larger than all nodes for a type that is not ignored should not be schedulable")
+	assert.Equal(t, partition.describeNodeLimit(larger), "first 200 exceeds largest node 100")
+	assert.DeepEqual(t, partition.partition.GetIgnoredResourceBlocks(), map[string]int64{"second": 0, "unknown": 0})
+	cache.SetIgnoredResourceTypes(partition.partition, nil)
+
+	// removing a node must recalculate the maximum
+	partition.removeSchedulingNode("node-1")
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 50})
+	assert.Assert(t, resources.Equals(expected, partition.getMaxNodeResource()), "max node resource not correct after removal: %v", partition.getMaxNodeResource())
+	partition.removeSchedulingNode("node-2")
+	assert.Assert(t, resources.IsZero(partition.getMaxNodeResource()), "max node resource should be empty after removing all nodes")
+}
+
 func TestGetNodes(t *testing.T) {
 	partition, err := newTestPartition()
 	if err != nil {
@@ -134,7 +183,7 @@ func TestGetNodes(t *testing.T) {
 		t.Error("failed to retrieve existing reserved node")
 	}
 	if schedNode != nil {
-		schedNode.reservations["app-1|alloc-1"] = &reservation{"", "app-1", "alloc-1", nil, nil, nil}
+		schedNode.reservations["app-1|alloc-1"] = &reservation{appID: "app-1", askKey: "alloc-1"}
 	}
 
 	assert.Equal(t, 4, len(partition.nodes), "node list not correct")
@@ -158,6 +207,35 @@ func TestGetNodes(t *testing.T) {
 	}
 }
 
+func TestNodeSortWeights(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	var leaf, gpu *SchedulingQueue
+	leaf, err = createManagedQueue(partition.root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	gpu, err = createManagedQueue(partition.root, "gpu", false, nil)
+	assert.NilError(t, err, "failed to create gpu queue")
+	// node-1 is the largest node, node-2 is the only node with a gpu
+	nodes := []*SchedulingNode{
+		newNode("node-1", map[string]resources.Quantity{"first": 100}),
+		newNode("node-2", map[string]resources.Quantity{"first": 10, "gpu": 1}),
+	}
+	assert.Assert(t, partition.getNodeSortWeights(leaf) == nil, "no weights expected")
+	iterator := partition.getNodeIterator(nodes, gpu)
+	assert.Equal(t, iterator.Next().NodeID, "node-1", "largest node should be first without weights")
+
+	// the queue weights override the partition weights
+	cache.SetNodeSortWeights(partition.partition, map[string]float64{"first": 1})
+	cache.SetQueueNodeSortWeights(gpu.QueueInfo, map[string]float64{"gpu": 1})
+	assert.DeepEqual(t, partition.getNodeSortWeights(leaf), map[string]float64{"first": 1})
+	assert.DeepEqual(t, partition.getNodeSortWeights(gpu), map[string]float64{"gpu": 1})
+	iterator = partition.getNodeIterator(nodes, gpu)
+	assert.Equal(t, iterator.Next().NodeID, "node-2", "gpu node should be first for the gpu queue")
+	// both nodes are completely free: the order does not change for the partition weights
+	iterator = partition.getNodeIterator(nodes, leaf)
+	assert.Equal(t, iterator.Next().NodeID, "node-1", "order should not change with equal scores")
+}
+
 func TestGetQueue(t *testing.T) {
 	// get the
 	partition, err := newTestPartition()
@@ -227,6 +305,27 @@ func createQueuesNodes(t *testing.T) *partitionSchedulingContext {
 	return partition
 }
 
+func TestAddApplicationRejection(t *testing.T) {
+	partition := createQueuesNodes(t)
+	if partition == nil {
+		t.Fatal("partition create failed")
+	}
+	// not a leaf queue
+	app := newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", "root.parent", security.UserGroup{}, nil))
+	err := partition.addSchedulingApplication(app)
+	assert.Equal(t, api.GetRejectionCode(err), api.RejectionQueueNotFound, "parent queue should be rejected as not found: %v", err)
+
+	// no submit ACL set on the test queues
+	app = newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", "root.parent.leaf1", security.UserGroup{}, nil))
+	err = partition.addSchedulingApplication(app)
+	assert.Equal(t, api.GetRejectionCode(err), api.RejectionACLDenied, "app should be rejected as denied: %v", err)
+
+	// fake adding the app to the partition
+	partition.applications["app-1"] = app
+	err = partition.addSchedulingApplication(app)
+	assert.Equal(t, api.GetRejectionCode(err), api.RejectionApplicationExists, "duplicate app should be rejected as existing: %v", err)
+}
+
 func TestTryAllocate(t *testing.T) {
 	partition := createQueuesNodes(t)
 	if partition == nil {
@@ -344,6 +443,335 @@ func TestTryAllocate(t *testing.T) {
 	}
 }
 
+func TestTryAllocateNodePool(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	// node-1 in the default pool, node-2 in the spot pool
+	partition.addSchedulingNode(cache.NewNodeForTest("node-1", res))
+	spotNode := cache.NewNodeForTest("node-2", res)
+	spotNode.Pool = "spot"
+	partition.addSchedulingNode(spotNode)
+
+	var root *SchedulingQueue
+	root, err = createRootQueue(map[string]string{"first": "100"})
+	assert.NilError(t, err, "failed to create root queue")
+	partition.root = root
+	// leaf can only use the spot pool with a max of 2
+	conf := configs.QueueConfig{
+		Name:      "leaf",
+		NodePools: []configs.NodePoolConfig{{Name: "spot", Max: map[string]string{"first": "2"}}},
+	}
+	var leafInfo *cache.QueueInfo
+	leafInfo, err = cache.NewManagedQueue(conf, root.QueueInfo)
+	assert.NilError(t, err, "failed to create leaf queue")
+	leaf := newSchedulingQueueInfo(leafInfo, root)
+
+	appID := "app-1"
+	app := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: appID})
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	askRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	_, err = app.addAllocationAsk(newAllocationAskRepeat("alloc-1", appID, askRes, 3))
+	assert.NilError(t, err, "failed to add ask to app")
+
+	// both allocations must be on the spot node
+	for i := 0; i < 2; i++ {
+		alloc := partition.tryAllocate()
+		if alloc == nil {
+			t.Fatalf("allocation %d did not return any allocation", i)
+		}
+		assert.Equal(t, alloc.nodeID, "node-2", "allocation should be in the spot pool")
+	}
+	assert.Assert(t, resources.Equals(leaf.poolAllocating["spot"], resources.Multiply(askRes, 2)), "unexpected allocating in pool: %v", leaf.poolAllocating)
+	assert.Assert(t, resources.IsZero(leaf.poolAllocating[configs.DefaultNodePool]), "nothing should be allocating in the default pool")
+	// the pool max is reached, the default pool is not allowed
+	if alloc := partition.tryAllocate(); alloc != nil {
+		t.Fatalf("pool max reached allocation should not be returned: %v", alloc)
+	}
+	// confirming the allocation frees up the allocating resources in the pool
+	err = partition.confirmAllocation(appID, "node-2", "alloc-1", askRes, true)
+	assert.NilError(t, err, "failed to confirm allocation")
+	assert.Assert(t, resources.Equals(leaf.poolAllocating["spot"], askRes), "unexpected allocating in pool after confirm: %v", leaf.poolAllocating)
+}
+
+func TestTryAllocateAlternatives(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	appID := "app-1"
+	app := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: appID})
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	// the requested resource does not fit on any node, the first alternative does not fit in the headroom
+	askRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20})
+	ask := newAllocationAsk("alloc-1", appID, askRes)
+	ask.AskProto.Tags = map[string]string{AlternativesAskTag: "[first:200];[first:5]"}
+	assert.NilError(t, ask.parseAlternatives(nil, nil), "failed to parse alternatives")
+	_, err := app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation")
+	}
+	allocRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	assert.Equal(t, alloc.result, allocated, "unexpected allocation result")
+	assert.Equal(t, alloc.shape, 2, "second alternative should have been allocated")
+	assert.Assert(t, resources.Equals(alloc.allocatedResource, allocRes), "unexpected allocated resource: %v", alloc.allocatedResource)
+	assert.DeepEqual(t, alloc.getAllocationTags(), map[string]string{AlternativeAllocationTag: "2"})
+	assert.Assert(t, resources.Equals(leaf.getAllocatingResource(), allocRes), "unexpected allocating on queue")
+	assert.Assert(t, resources.Equals(app.getAllocatingResource(), allocRes), "unexpected allocating on app")
+	assert.Equal(t, ask.getPendingAskRepeat(), int32(0), "ask repeat should have been used")
+
+	// confirming uses the allocated resource not the requested resource
+	err = partition.confirmAllocation(appID, alloc.nodeID, "alloc-1", alloc.allocatedResource, true)
+	assert.NilError(t, err, "failed to confirm allocation")
+	assert.Assert(t, resources.IsZero(leaf.getAllocatingResource()), "queue allocating should be zero after confirm")
+	assert.Assert(t, resources.IsZero(app.getAllocatingResource()), "app allocating should be zero after confirm")
+	assert.Assert(t, resources.IsZero(partition.getSchedulingNode(alloc.nodeID).getAllocatingResource()), "node allocating should be zero after confirm")
+}
+
+func TestRejectAllocationRollback(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	appID := "app-1"
+	app := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: appID})
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	askRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	ask := newAllocationAskRepeat("alloc-1", appID, askRes, 2)
+	_, err := app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+
+	// a rejected allocation puts the ask back and removes the allocating resources
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation")
+	}
+	assert.Equal(t, ask.getPendingAskRepeat(), int32(1), "ask repeat should have been used")
+	err = partition.confirmAllocation(appID, alloc.nodeID, "alloc-1", alloc.allocatedResource, false)
+	assert.NilError(t, err, "failed to reject allocation")
+	assert.Equal(t, ask.getPendingAskRepeat(), int32(2), "ask repeat should have been added back")
+	assert.Assert(t, resources.Equals(app.GetPendingResource(), resources.Multiply(askRes, 2)), "unexpected pending on app")
+	assert.Assert(t, resources.IsZero(leaf.getAllocatingResource()), "queue allocating should be zero after reject")
+	assert.Assert(t, resources.IsZero(app.getAllocatingResource()), "app allocating should be zero after reject")
+
+	// the ask is removed while allocating: the reject fails but must not leak allocating resources
+	alloc = partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation")
+	}
+	app.removeAllocationAsk("alloc-1")
+	err = partition.confirmAllocation(appID, alloc.nodeID, "alloc-1", alloc.allocatedResource, false)
+	if err == nil {
+		t.Error("reject of a removed ask should have failed")
+	}
+	assert.Assert(t, resources.IsZero(leaf.getAllocatingResource()), "queue allocating should be zero after failed reject")
+	assert.Assert(t, resources.IsZero(app.getAllocatingResource()), "app allocating should be zero after failed reject")
+	assert.Assert(t, resources.IsZero(partition.getSchedulingNode(alloc.nodeID).getAllocatingResource()), "node allocating should be zero after failed reject")
+
+	// rolling back a delivered allocation only re-queues the ask
+	ask = newAllocationAsk("alloc-2", appID, askRes)
+	_, err = app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	alloc = partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation")
+	}
+	err = partition.confirmAllocation(appID, alloc.nodeID, "alloc-2", alloc.allocatedResource, true)
+	assert.NilError(t, err, "failed to confirm allocation")
+	err = partition.rollbackAllocation(appID, "alloc-2")
+	assert.NilError(t, err, "failed to roll back allocation")
+	assert.Equal(t, ask.getPendingAskRepeat(), int32(1), "ask repeat should have been added back")
+	assert.Assert(t, resources.Equals(app.GetPendingResource(), askRes), "unexpected pending on app")
+	if err = partition.rollbackAllocation("unknown", "alloc-2"); err == nil {
+		t.Error("roll back for an unknown application should have failed")
+	}
+	if err = partition.rollbackAllocation(appID, "unknown"); err == nil {
+		t.Error("roll back for an unknown ask should have failed")
+	}
+}
+
+func TestTryAllocateRange(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	appID := "app-1"
+	app := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: appID})
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	// the desired resource does not fit on any node, the ask is scaled down to the node
+	askRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20})
+	ask := newAllocationAskRepeat("alloc-1", appID, askRes, 3)
+	ask.AskProto.Tags = map[string]string{MinimumAskTag: "[first:4]"}
+	assert.NilError(t, ask.parseMinimum(nil, nil), "failed to parse minimum")
+	_, err := app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+
+	allocRes := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	for i := 0; i < 2; i++ {
+		alloc := partition.tryAllocate()
+		if alloc == nil {
+			t.Fatalf("allocation %d did not return any allocation", i)
+		}
+		assert.Equal(t, alloc.result, allocated, "unexpected allocation result")
+		assert.Assert(t, ask.isRangeShape(alloc.shape), "range shape should have been allocated")
+		assert.Assert(t, resources.Equals(alloc.allocatedResource, allocRes), "unexpected allocated resource: %v", alloc.allocatedResource)
+		assert.DeepEqual(t, alloc.getAllocationTags(), map[string]string{GrantedAllocationTag: "[first:10]"})
+	}
+	// both nodes are fully allocating: nothing left above the minimum, the desired resource can still be reserved
+	alloc := partition.tryAllocate()
+	assert.Assert(t, alloc == nil || alloc.result == reserved, "allocation below the minimum should not be made: %v", alloc)
+	assert.Equal(t, ask.getPendingAskRepeat(), int32(1), "one ask repeat should be pending")
+	assert.Assert(t, resources.Equals(leaf.getAllocatingResource(), resources.Multiply(allocRes, 2)), "unexpected allocating on queue")
+}
+
+func TestTryAllocateTaints(t *testing.T) {
+	partition := createQueuesNodes(t)
+	partition.nodes["node-1"].taints = parseNodeTaints("node-1", "gpu=true:NoSchedule")
+	leaf := partition.getQueue("root.parent.leaf1")
+	appID := "app-1"
+	app := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: appID})
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	_, err := app.addAllocationAsk(newAllocationAskRepeat("alloc-1", appID, res, 2))
+	assert.NilError(t, err, "failed to add ask to app")
+
+	// the ask does not tolerate the taint: only the untainted node is used
+	for i := 0; i < 2; i++ {
+		alloc := partition.tryAllocate()
+		if alloc == nil {
+			t.Fatalf("allocation %d did not return any allocation", i)
+		}
+		assert.Equal(t, alloc.result, allocated, "unexpected allocation result")
+		assert.Equal(t, alloc.nodeID, "node-2", "tainted node should not have been used")
+	}
+	// the untainted node is full: the ask with the toleration goes to the tainted node
+	ask := newAllocationAsk("alloc-2", appID, res)
+	ask.AskProto.Tags = map[string]string{TolerationsAskTag: "gpu"}
+	assert.NilError(t, ask.parseTolerations(), "failed to parse tolerations")
+	_, err = app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation on the tainted node did not return any allocation")
+	}
+	assert.Equal(t, alloc.nodeID, "node-1", "ask should have been allocated on the tainted node")
+}
+
+func TestTryAllocateMinNodeAge(t *testing.T) {
+	partition := createQueuesNodes(t)
+	cache.SetNodeReadySince(partition.nodes["node-1"].nodeInfo, time.Now())
+	cache.SetNodeReadySince(partition.nodes["node-2"].nodeInfo, time.Now().Add(-time.Hour))
+	leaf := partition.getQueue("root.parent.leaf1")
+	appID := "app-1"
+	app := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: appID})
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+
+	// the ask requires a node that has been ready for 10 minutes: the new node is not used
+	ask := newAllocationAskRepeat("alloc-1", appID, res, 3)
+	ask.AskProto.Tags = map[string]string{MinNodeAgeAskTag: "10m"}
+	assert.NilError(t, ask.parseMinNodeAge(), "failed to parse minimum node age")
+	_, err := app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	for i := 0; i < 2; i++ {
+		alloc := partition.tryAllocate()
+		if alloc == nil {
+			t.Fatalf("allocation %d did not return any allocation", i)
+		}
+		assert.Equal(t, alloc.result, allocated, "unexpected allocation result")
+		assert.Equal(t, alloc.nodeID, "node-2", "new node should not have been used")
+	}
+	// the ready node is full: the new node is not used for the last repeat
+	alloc := partition.tryAllocate()
+	assert.Assert(t, alloc == nil || alloc.result == reserved && alloc.nodeID == "node-2", "new node should not have been used: %v", alloc)
+}
+
+func TestTryAllocateReuseHint(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	appID := "app-1"
+	appInfo := &cache.ApplicationInfo{ApplicationID: appID}
+	app := newSchedulingApplication(appInfo)
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1})
+	released := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 2})
+
+	// the node of the released allocation is tried first independent of the node order
+	for _, nodeID := range []string{"node-1", "node-2"} {
+		cache.AddReuseHintToApp(appInfo, cache.CreateMockAllocationInfo(appID, released, "uuid", leaf.Name, nodeID), time.Hour)
+		_, err := app.addAllocationAsk(newAllocationAsk("alloc-"+nodeID, appID, res))
+		assert.NilError(t, err, "failed to add ask to app")
+		alloc := partition.tryAllocate()
+		if alloc == nil {
+			t.Fatalf("allocation with reuse hint for %s did not return any allocation", nodeID)
+		}
+		assert.Equal(t, alloc.result, allocated, "unexpected allocation result")
+		assert.Equal(t, alloc.nodeID, nodeID, "node of the released allocation should have been used")
+	}
+	// an ask larger than the released allocation is not placed using the hint: the ask must fit on either node
+	larger := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	_, err := app.addAllocationAsk(newAllocationAsk("alloc-large", appID, larger))
+	assert.NilError(t, err, "failed to add ask to app")
+	assert.Equal(t, appInfo.GetReuseNode(larger), "", "larger ask should not have a reuse hint")
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation without reuse hint did not return any allocation")
+	}
+}
+
+func TestTryAllocateStartDelay(t *testing.T) {
+	partition := createQueuesNodes(t)
+	if partition == nil {
+		t.Fatal("partition create failed")
+	}
+	// add a leaf queue with a short start delay
+	conf := configs.QueueConfig{
+		Name:       "delayed",
+		Properties: map[string]string{cache.QueueStartDelay: "50ms"},
+	}
+	leafInfo, err := cache.NewManagedQueue(conf, partition.root.QueueInfo)
+	if err != nil {
+		t.Fatalf("failed to create delayed leaf queue: %v", err)
+	}
+	leaf := newSchedulingQueueInfo(leafInfo, partition.root)
+	appID := "app-1"
+	var res *resources.Resource
+	res, err = resources.NewResourceFromConf(map[string]string{"first": "1"})
+	assert.NilError(t, err, "failed to create resource")
+	app := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: appID})
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	_, err = app.addAllocationAsk(newAllocationAsk("alloc-1", appID, res))
+	assert.NilError(t, err, "failed to add ask to app")
+
+	// the ask must stay pending while the queue is in the start delay
+	assert.Assert(t, leaf.isStartDelayed(), "queue should be within start delay")
+	if alloc := partition.tryAllocate(); alloc != nil {
+		t.Fatalf("allocation returned for queue within start delay: %v", alloc.String())
+	}
+	assert.Assert(t, resources.Equals(res, leaf.GetPendingResource()), "pending resource should not have changed")
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Assert(t, !leaf.isStartDelayed(), "queue should be out of start delay")
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation after the start delay")
+	}
+	assert.Equal(t, alloc.schedulingAsk.ApplicationID, appID, "expected application app-1 to be allocated")
+}
+
 func TestTryAllocateLarge(t *testing.T) {
 	partition := createQueuesNodes(t)
 	if partition == nil {
@@ -542,6 +970,50 @@ func TestTryAllocateReserve(t *testing.T) {
 	}
 }
 
+func TestReserveLimits(t *testing.T) {
+	partition := createQueuesNodes(t)
+	if partition == nil {
+		t.Fatal("partition create failed")
+	}
+	leaf := partition.getQueue("root.parent.leaf1")
+	if leaf == nil {
+		t.Fatal("leaf queue create failed")
+	}
+	appID := "app-1"
+	res, err := resources.NewResourceFromConf(map[string]string{"first": "1"})
+	assert.NilError(t, err, "failed to create resource")
+	app := newSchedulingApplication(&cache.ApplicationInfo{ApplicationID: appID})
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications[appID] = app
+	ask1 := newAllocationAsk("alloc-1", appID, res)
+	_, err = app.addAllocationAsk(ask1)
+	assert.NilError(t, err, "failed to add ask alloc-1 to app")
+	ask2 := newAllocationAsk("alloc-2", appID, res)
+	_, err = app.addAllocationAsk(ask2)
+	assert.NilError(t, err, "failed to add ask alloc-2 to app")
+	node1 := partition.getSchedulingNode("node-1")
+	node2 := partition.getSchedulingNode("node-2")
+
+	// count limit
+	cache.SetReservationLimits(partition.partition, 1, nil)
+	partition.reserve(app, node1, ask1)
+	assert.Assert(t, app.isReservedOnNode(node1.NodeID), "first reservation should be within the count limit")
+	partition.reserve(app, node2, ask2)
+	assert.Assert(t, !app.isReservedOnNode(node2.NodeID), "second reservation should be over the count limit")
+
+	// resource limit on the reserved type
+	cache.SetReservationLimits(partition.partition, 0, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 1}))
+	partition.reserve(app, node2, ask2)
+	assert.Assert(t, !app.isReservedOnNode(node2.NodeID), "second reservation should be over the resource limit")
+
+	// resource limit on another type does not apply
+	cache.SetReservationLimits(partition.partition, 0, resources.NewResourceFromMap(map[string]resources.Quantity{"second": 1}))
+	partition.reserve(app, node2, ask2)
+	assert.Assert(t, app.isReservedOnNode(node2.NodeID), "second reservation should not be limited by other type")
+	assert.Equal(t, partition.reservedApps[appID], 2, "expected two reservations for the app")
+}
+
 func TestTryAllocateWithReserved(t *testing.T) {
 	partition := createQueuesNodes(t)
 	if partition == nil {
@@ -602,3 +1074,99 @@ func TestTryAllocateWithReserved(t *testing.T) {
 	assert.Equal(t, allocated, alloc.result, "expected allocated allocation to be returned")
 	assert.Equal(t, node2.NodeID, alloc.nodeID, "expected allocation on node2 to be returned")
 }
+
+func TestUpdateQueueResources(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: leaf
+            resources:
+              max:
+                memory: 10
+`
+	info, err := cache.CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	cache.SetTotalPartitionResource(info, resources.NewResourceFromMap(map[string]resources.Quantity{resources.MEMORY: 100}))
+	partition := newPartitionSchedulingContext(info, newSchedulingQueueInfo(info.Root, nil))
+	leaf := partition.GetQueue("root.leaf")
+	assert.Equal(t, leaf.getMaxResource().Resources[resources.MEMORY], resources.Quantity(10), "unexpected max before the update")
+
+	// the scheduling queue is kept and uses the new max right away
+	conf := info.GetEffectiveConfig()
+	conf.Queues[0].Queues[0].Resources = configs.Resources{
+		Guaranteed: map[string]string{resources.MEMORY: "5"},
+		Max:        map[string]string{resources.MEMORY: "20"},
+	}
+	err = cache.UpdatePartitionInfo(info, conf)
+	assert.NilError(t, err, "partition update failed")
+	partition.updatePartitionSchedulingContext(info)
+	assert.Equal(t, partition.GetQueue("root.leaf"), leaf, "scheduling queue should not have been replaced")
+	assert.Equal(t, leaf.getMaxResource().Resources[resources.MEMORY], resources.Quantity(20), "max not updated")
+	assert.Assert(t, partition.fairShares != nil, "fair shares not calculated on update")
+}
+
+func TestMoveSchedulingApplication(t *testing.T) {
+	data := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        queues:
+          - name: source
+            submitacl: "*"
+          - name: target
+            submitacl: "*"
+          - name: closed
+`
+	info, err := cache.CreatePartitionInfo([]byte(data))
+	assert.NilError(t, err, "partition create failed")
+	partition := newPartitionSchedulingContext(info, newSchedulingQueueInfo(info.Root, nil))
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.source", security.UserGroup{User: "user"}, nil)
+	err = cache.AddApplicationToPartition(info, appInfo)
+	assert.NilError(t, err, "failed to add app to cache partition")
+	app := newSchedulingApplication(appInfo)
+	err = partition.addSchedulingApplication(app)
+	assert.NilError(t, err, "failed to add app to partition")
+	source := partition.GetQueue("root.source")
+	target := partition.GetQueue("root.target")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	ask := newAllocationAsk("alloc-1", "app-1", res)
+	_, err = app.addAllocationAsk(ask)
+	assert.NilError(t, err, "failed to add ask to app")
+	node := newNode("node-1", map[string]resources.Quantity{"first": 10})
+	partition.nodes[node.NodeID] = node
+	partition.reserve(app, node, ask)
+
+	// unknown app or queue, no submit access on the target
+	err = partition.moveSchedulingApplication("unknown", "root.target")
+	assert.Assert(t, err != nil, "unknown application should not have been moved")
+	err = partition.moveSchedulingApplication("app-1", "root")
+	assert.Equal(t, api.GetErrorKind(err), api.ErrQueueNotFound, "move to a parent queue should have failed")
+	err = partition.moveSchedulingApplication("app-1", "root.closed")
+	assert.Equal(t, api.GetRejectionCode(err), api.RejectionACLDenied, "move without submit access should have failed")
+
+	// no move while an allocation is in flight
+	app.incAllocatingResource(res)
+	err = partition.moveSchedulingApplication("app-1", "root.target")
+	assert.Equal(t, api.GetErrorKind(err), api.ErrInvalidState, "move with allocations in flight should have failed")
+	app.decAllocatingResource(res)
+	assert.Equal(t, app.queue, source, "failed move should not change the queue")
+
+	// pending resources and reservations move with the application
+	err = partition.moveSchedulingApplication("app-1", "root.target")
+	assert.NilError(t, err, "move should not have failed")
+	assert.Equal(t, app.queue, target, "app queue not updated")
+	assert.Equal(t, appInfo.QueueName, "root.target", "cache app queue not updated")
+	assert.Equal(t, ask.QueueName, "root.target", "ask queue not updated")
+	assert.Assert(t, source.getApplication("app-1") == nil, "app not removed from the source queue")
+	assert.Equal(t, target.getApplication("app-1"), app, "app not added to the target queue")
+	assert.Assert(t, resources.IsZero(source.GetPendingResource()), "pending not removed from the source queue")
+	assert.Assert(t, resources.Equals(target.GetPendingResource(), res), "pending not added to the target queue")
+	assert.Assert(t, resources.Equals(partition.root.GetPendingResource(), res), "root pending should not have changed")
+	assert.Equal(t, len(source.reservedApps), 0, "reservation not removed from the source queue")
+	assert.Equal(t, target.reservedApps["app-1"], 1, "reservation not added to the target queue")
+	assert.Equal(t, partition.getReservations()["app-1"], 1, "partition reservations should not have changed")
+}
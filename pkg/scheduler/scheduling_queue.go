@@ -19,12 +19,14 @@
 package scheduler
 
 import (
+	"sort"
 	"strings"
-	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
@@ -39,13 +41,21 @@ type SchedulingQueue struct {
 	sortType       SortType                          // How applications (leaf) or queues (parents) are sorted
 	childrenQueues map[string]*SchedulingQueue       // Only for direct children, parent queue only
 	applications   map[string]*SchedulingApplication // only for leaf queue
+	sortedApps     *sortedApplications               // applications in sort order, only for leaf queue
 	reservedApps   map[string]int                    // applications reserved within this queue, with reservation count
 	parent         *SchedulingQueue                  // link back to the parent in the scheduler
 	allocating     *resources.Resource               // resource being allocated in the queue but not confirmed
 	preempting     *resources.Resource               // resource considered for preemption in the queue
 	pending        *resources.Resource               // pending resource for the apps in the queue
+	pendingDelta   *resources.Resource               // pending resource change not yet passed on to the parent
+	batchPending   bool                              // pass pending changes on to the parent in batches (throughput mode)
+	placements     int32                             // pending placement only asks for the apps in the queue
+	poolAllocating map[string]*resources.Resource    // resource being allocated per node pool but not confirmed
+	userAllocating map[string]*resources.Resource    // resource being allocated per user but not confirmed
+	idleSince      time.Time                         // time the queue became idle, zero if the queue is not idle
+	fairShare      *resources.Resource               // fair share calculated at the start of the scheduling cycle
 
-	sync.RWMutex
+	locking.RWMutex
 }
 
 func newSchedulingQueueInfo(cacheQueueInfo *cache.QueueInfo, parent *SchedulingQueue) *SchedulingQueue {
@@ -55,10 +65,19 @@ func newSchedulingQueueInfo(cacheQueueInfo *cache.QueueInfo, parent *SchedulingQ
 		parent:         parent,
 		childrenQueues: make(map[string]*SchedulingQueue),
 		applications:   make(map[string]*SchedulingApplication),
+		sortedApps:     newSortedApplications(FifoSortPolicy),
 		reservedApps:   make(map[string]int),
 		allocating:     resources.NewResource(),
 		preempting:     resources.NewResource(),
 		pending:        resources.NewResource(),
+		pendingDelta:   resources.NewResource(),
+		poolAllocating: make(map[string]*resources.Resource),
+		userAllocating: make(map[string]*resources.Resource),
+	}
+
+	// a new queue follows the throughput mode of the partition
+	if parent != nil {
+		sq.batchPending = parent.isBatchingPending()
 	}
 
 	// update the properties
@@ -88,8 +107,21 @@ func (sq *SchedulingQueue) updateSchedulingQueueProperties(prop map[string]strin
 		sq.sortType = FifoSortPolicy
 		// walk over all properties and process
 		for key, value := range prop {
-			if key == cache.ApplicationSortPolicy && value == "fair" {
-				sq.sortType = FairSortPolicy
+			if key == cache.ApplicationSortPolicy {
+				switch value {
+				case "fifo":
+					sq.sortType = FifoSortPolicy
+				case "fair":
+					sq.sortType = FairSortPolicy
+				case "sjf":
+					sq.sortType = SjfSortPolicy
+				case "priority":
+					sq.sortType = PrioritySortPolicy
+				default:
+					log.Logger().Warn("unknown application sort policy, using fifo",
+						zap.String("queueName", sq.Name),
+						zap.String("policy", value))
+				}
 			}
 			// for now skip the rest just log them
 			log.Logger().Debug("queue property skipped",
@@ -100,6 +132,9 @@ func (sq *SchedulingQueue) updateSchedulingQueueProperties(prop map[string]strin
 	}
 	// set the sorting type for parent queues
 	sq.sortType = FairSortPolicy
+	if prop[cache.QueueSortPolicy] == "fairshare" {
+		sq.sortType = FairSharePolicy
+	}
 }
 
 // Update the queue properties and the child queues for the queue after a configuration update.
@@ -132,33 +167,90 @@ func (sq *SchedulingQueue) GetPendingResource() *resources.Resource {
 	return sq.pending
 }
 
-// Update pending resource of this queue
+// Update pending resource of this queue.
+// In throughput mode the change is passed on to the parent when the pending batch is flushed.
 func (sq *SchedulingQueue) incPendingResource(delta *resources.Resource) {
-	// update the parent
-	if sq.parent != nil {
+	if sq.updatePending(delta, false) && sq.parent != nil {
 		sq.parent.incPendingResource(delta)
 	}
-	// update this queue
-	sq.Lock()
-	defer sq.Unlock()
-	sq.pending = resources.Add(sq.pending, delta)
 }
 
-// Remove pending resource of this queue
+// Remove pending resource of this queue.
+// In throughput mode the change is passed on to the parent when the pending batch is flushed.
 func (sq *SchedulingQueue) decPendingResource(delta *resources.Resource) {
-	// update the parent
-	if sq.parent != nil {
+	if sq.updatePending(delta, true) && sq.parent != nil {
 		sq.parent.decPendingResource(delta)
 	}
-	// update this queue
+}
+
+// Update the pending resource of this queue with the delta, the delta is removed if remove is set.
+// Returns true if the change must be passed on to the parent directly, false if it was added to the pending batch.
+func (sq *SchedulingQueue) updatePending(delta *resources.Resource, remove bool) bool {
 	sq.Lock()
 	defer sq.Unlock()
-	var err error
-	sq.pending, err = resources.SubErrorNegative(sq.pending, delta)
-	if err != nil {
-		log.Logger().Warn("Pending resources went negative",
-			zap.String("queueName", sq.QueueInfo.Name),
-			zap.Error(err))
+	if !remove {
+		sq.pending = resources.Add(sq.pending, delta)
+	} else {
+		var err error
+		sq.pending, err = resources.SubErrorNegative(sq.pending, delta)
+		if err != nil {
+			log.Logger().Warn("Pending resources went negative",
+				zap.String("queueName", sq.QueueInfo.Name),
+				zap.Error(err))
+		}
+	}
+	if !sq.batchPending || sq.parent == nil {
+		return true
+	}
+	if remove {
+		sq.pendingDelta = resources.Sub(sq.pendingDelta, delta)
+	} else {
+		sq.pendingDelta = resources.Add(sq.pendingDelta, delta)
+	}
+	return false
+}
+
+// Is the pending resource passed on to the parent in batches?
+func (sq *SchedulingQueue) isBatchingPending() bool {
+	sq.RLock()
+	defer sq.RUnlock()
+	return sq.batchPending
+}
+
+// Set the batching of the pending resource for this queue and all queues below it.
+// Outstanding batches are not flushed, see flushPendingBatch.
+func (sq *SchedulingQueue) setBatchPending(batch bool) {
+	sq.Lock()
+	sq.batchPending = batch
+	sq.Unlock()
+	for _, child := range sq.GetCopyOfChildren() {
+		child.setBatchPending(batch)
+	}
+}
+
+// Pass the pending batches of the queues below this queue on to their parents, bottom up.
+// Returns the pending batch of this queue, which is reset, for the caller to pass on to the parent.
+func (sq *SchedulingQueue) flushPendingBatch() *resources.Resource {
+	for _, child := range sq.GetCopyOfChildren() {
+		if delta := child.flushPendingBatch(); !resources.IsZero(delta) {
+			sq.applyPendingBatch(delta)
+		}
+	}
+	sq.Lock()
+	defer sq.Unlock()
+	delta := sq.pendingDelta
+	sq.pendingDelta = resources.NewResource()
+	return delta
+}
+
+// Apply the pending batch of a child to this queue, the batch becomes part of the batch of this queue.
+// The root queue does not have a batch.
+func (sq *SchedulingQueue) applyPendingBatch(delta *resources.Resource) {
+	sq.Lock()
+	defer sq.Unlock()
+	sq.pending = resources.Add(sq.pending, delta)
+	if sq.parent != nil {
+		sq.pendingDelta = resources.Add(sq.pendingDelta, delta)
 	}
 }
 
@@ -169,6 +261,8 @@ func (sq *SchedulingQueue) addSchedulingApplication(app *SchedulingApplication)
 	sq.Lock()
 	defer sq.Unlock()
 	sq.applications[app.ApplicationInfo.ApplicationID] = app
+	sq.sortedApps.add(app)
+	sq.idleSince = time.Time{}
 }
 
 // Remove the scheduling app from the list of tracked applications. Make sure that the app
@@ -186,10 +280,17 @@ func (sq *SchedulingQueue) removeSchedulingApplication(app *SchedulingApplicatio
 	if appPending := app.GetPendingResource(); !resources.IsZero(appPending) {
 		sq.decPendingResource(appPending)
 	}
+	if placements := app.getPendingPlacements(); placements != 0 {
+		sq.incPendingPlacements(-placements)
+	}
 	sq.Lock()
 	defer sq.Unlock()
 
 	delete(sq.applications, appID)
+	sq.sortedApps.remove(appID)
+	if len(sq.applications) == 0 {
+		sq.idleSince = time.Now()
+	}
 }
 
 // Get a copy of all apps holding the lock
@@ -229,6 +330,23 @@ func (sq *SchedulingQueue) isEmpty() bool {
 	return len(sq.childrenQueues) == 0
 }
 
+// Update the idle state of the queue and return the time the queue has been idle.
+// A queue is idle when it is empty and has no pending resources. The idle time of a queue that was not marked idle
+// before starts now.
+func (sq *SchedulingQueue) updateIdleTime(now time.Time) time.Duration {
+	idle := sq.isEmpty() && resources.IsZero(sq.GetPendingResource())
+	sq.Lock()
+	defer sq.Unlock()
+	if !idle {
+		sq.idleSince = time.Time{}
+		return 0
+	}
+	if sq.idleSince.IsZero() {
+		sq.idleSince = now
+	}
+	return now.Sub(sq.idleSince)
+}
+
 // Remove a child queue from this queue.
 // No checks are performed: if the child has been removed already it is a noop.
 // This may only be called by the queue removal itself on the registered parent.
@@ -273,10 +391,40 @@ func (sq *SchedulingQueue) removeQueue() bool {
 		return false
 	}
 	// root is always managed and is the only queue with a nil parent: no need to guard
-	sq.parent.removeChildQueue(sq.Name)
+	// the pending batch is not lost with the queue
+	if !resources.IsZero(sq.pendingDelta) {
+		sq.parent.applyPendingBatch(sq.pendingDelta)
+	}
+	// children are tracked by their short name in the parent
+	sq.parent.removeChildQueue(sq.Name[strings.LastIndex(sq.Name, cache.DOT)+1:])
 	return true
 }
 
+// Get all the leaf queues in the hierarchy below this queue, or the queue itself if it is a leaf.
+func (sq *SchedulingQueue) getLeafQueues() []*SchedulingQueue {
+	if sq.isLeafQueue() {
+		return []*SchedulingQueue{sq}
+	}
+	var leaves []*SchedulingQueue
+	for _, child := range sq.GetCopyOfChildren() {
+		leaves = append(leaves, child.getLeafQueues()...)
+	}
+	return leaves
+}
+
+// Get the pending ask with the highest priority from all applications in the queue.
+// Returns nil if there are no pending asks.
+func (sq *SchedulingQueue) getHighestPriorityAsk() *schedulingAllocationAsk {
+	var highest *schedulingAllocationAsk
+	for _, app := range sq.getCopyOfApps() {
+		ask := app.getHighestPriorityAsk()
+		if ask != nil && (highest == nil || ask.priority > highest.priority) {
+			highest = ask
+		}
+	}
+	return highest
+}
+
 // Is this queue a leaf or not (i.e parent)
 // link back to the underlying queue object to prevent out of sync types
 func (sq *SchedulingQueue) isLeafQueue() bool {
@@ -297,6 +445,10 @@ func (sq *SchedulingQueue) isStopped() bool {
 	return sq.QueueInfo.IsStopped()
 }
 
+func (sq *SchedulingQueue) isStartDelayed() bool {
+	return sq.QueueInfo.IsStartDelayed()
+}
+
 // Is this queue managed or not.
 // link back to the underlying queue object to prevent out of sync types
 func (sq *SchedulingQueue) isManaged() bool {
@@ -362,6 +514,49 @@ func (sq *SchedulingQueue) getAssumeAllocated() *resources.Resource {
 	return resources.Add(sq.allocating, sq.QueueInfo.GetAllocatedResource())
 }
 
+// Return the fair share of the queue as calculated at the start of the last scheduling cycle.
+func (sq *SchedulingQueue) getFairShare() *resources.Resource {
+	sq.RLock()
+	defer sq.RUnlock()
+	return sq.fairShare
+}
+
+// Set the fair share of the queue.
+func (sq *SchedulingQueue) setFairShare(fairShare *resources.Resource) {
+	sq.Lock()
+	defer sq.Unlock()
+	sq.fairShare = fairShare
+}
+
+// Return the utilization of the queue per resource type: the used resource of the queue as a ratio of the max resource
+// and as a ratio of the guaranteed resource. The max resource includes the limits of the parents.
+// Only resource types with a limit above zero have a ratio, a ratio above 1 means the queue uses more than the limit.
+// A ratio map is nil if the queue has no limit of that kind.
+func (sq *SchedulingQueue) GetUtilization() (maxRatios, guaranteedRatios map[string]float64) {
+	used := sq.QueueInfo.GetAllocatedResource()
+	return getUtilizationRatios(used, sq.getMaxResource()), getUtilizationRatios(used, sq.QueueInfo.GetGuaranteedResource())
+}
+
+// Return the used resource as a ratio of the limit for each resource type of the limit that is above zero.
+// Returns nil if the limit is not set.
+func getUtilizationRatios(used, limit *resources.Resource) map[string]float64 {
+	if limit == nil {
+		return nil
+	}
+	ratios := make(map[string]float64)
+	for name, quantity := range limit.Resources {
+		if quantity <= 0 {
+			continue
+		}
+		var value resources.Quantity
+		if used != nil {
+			value = used.Resources[name]
+		}
+		ratios[name] = float64(value) / float64(quantity)
+	}
+	return ratios
+}
+
 // Return the allocating resources for this queue
 func (sq *SchedulingQueue) getAllocatingResource() *resources.Resource {
 	sq.RLock()
@@ -401,25 +596,23 @@ func (sq *SchedulingQueue) decAllocatingResource(delta *resources.Resource) {
 }
 
 // Return a sorted copy of the applications in the queue. Applications are sorted using the
-// sorting type of the queue.
-// Only applications with a pending resource request are considered.
+// sorting type of the queue. The order is maintained incrementally, see sortedApplications.
+// Only applications with a pending resource request are considered, applications on hold are skipped.
 // Lock free call all locks are taken when needed in called functions
 func (sq *SchedulingQueue) sortApplications() []*SchedulingApplication {
 	if !sq.isLeafQueue() {
 		return nil
 	}
-	// Create a copy of the applications with pending resources
-	sortedApps := make([]*SchedulingApplication, 0)
-	for _, app := range sq.getCopyOfApps() {
-		// Only look at app when pending-res > 0
-		if resources.StrictlyGreaterThanZero(app.GetPendingResource()) {
-			sortedApps = append(sortedApps, app)
+	// Filter the sorted applications on pending resources
+	sortedApps := sq.sortedApps.sorted(sq.getSortType(), sq.QueueInfo.GetApplicationSortAging(), sq.QueueInfo.GetGuaranteedResource())
+	filtered := sortedApps[:0]
+	for _, app := range sortedApps {
+		// Only look at app when pending-res > 0 and it is not held
+		if resources.StrictlyGreaterThanZero(app.GetPendingResource()) && !app.ApplicationInfo.IsHeld() {
+			filtered = append(filtered, app)
 		}
 	}
-	// Sort the applications
-	sortApplications(sortedApps, sq.getSortType(), sq.QueueInfo.GetGuaranteedResource())
-
-	return sortedApps
+	return filtered
 }
 
 // Return a sorted copy of the queues for this parent queue.
@@ -454,34 +647,288 @@ func (sq *SchedulingQueue) sortQueues() []*SchedulingQueue {
 // will return nil.
 // NOTE: if a resource quantity is missing and a limit is defined the missing quantity will be seen as a limit of 0.
 // When defining a limit you therefore should define all resource quantities.
+// The borrow limit of a queue only limits the resource types of its guarantee, it does not follow this rule.
+// With soft max enforcement the max of a queue includes the tolerance.
 func (sq *SchedulingQueue) getHeadRoom() *resources.Resource {
 	var parentHeadRoom *resources.Resource
 	if sq.parent != nil {
-		parentHeadRoom = sq.parent.getHeadRoom()
+		parentHeadRoom = sq.parent.getChildHeadRoom(sq)
 	}
 	sq.RLock()
 	defer sq.RUnlock()
-	headRoom := sq.QueueInfo.GetMaxResource()
+	headRoom := sq.QueueInfo.GetEnforcedMaxResource()
+	borrowMax := sq.QueueInfo.GetBorrowMaxResource()
+	// if we have no max and no borrow limit set headroom is always the same as the parent
+	if headRoom == nil && borrowMax == nil {
+		return parentHeadRoom
+	}
+	used := resources.Add(sq.allocating, sq.QueueInfo.GetAllocatedResource())
+	// only the borrow limit is set: limit the guaranteed types in the parent headroom
+	if headRoom == nil {
+		for key := range borrowMax.Resources {
+			borrowMax.Resources[key] -= used.Resources[key]
+		}
+		return applyBorrowLimit(parentHeadRoom, borrowMax)
+	}
+	// calculate unused
+	headRoom = applyBorrowLimit(headRoom, borrowMax)
+	headRoom.SubFrom(used)
+	// check the minimum of the two: parentHeadRoom is nil for root
+	if parentHeadRoom == nil {
+		return headRoom
+	}
+	return resources.ComponentWiseMin(headRoom, parentHeadRoom)
+}
+
+// Get the part of the headroom of the queue that the child can use.
+// The headroom plus the usage of the child and the siblings with pending resources is shared proportional to the
+// weight. A child below its weighted share can use the whole headroom, a child at or above its share cannot use more.
+// There is always a child below its share while there is headroom left. Children with the same weight all use the
+// whole headroom, as without weights.
+func (sq *SchedulingQueue) getChildHeadRoom(child *SchedulingQueue) *resources.Resource {
+	headRoom := sq.getHeadRoom()
+	if headRoom == nil {
+		return nil
+	}
+	weight := child.QueueInfo.GetWeight()
+	totalWeight := weight
+	weighted := false
+	used := child.getAssumeAllocated()
+	shared := resources.Add(headRoom, used)
+	for _, sibling := range sq.GetCopyOfChildren() {
+		if sibling == child || !resources.StrictlyGreaterThanZero(sibling.GetPendingResource()) {
+			continue
+		}
+		siblingWeight := sibling.QueueInfo.GetWeight()
+		weighted = weighted || siblingWeight != weight
+		totalWeight += siblingWeight
+		shared.AddTo(sibling.getAssumeAllocated())
+	}
+	if !weighted {
+		return headRoom
+	}
+	share := resources.MultiplyBy(shared, weight/totalWeight)
+	for key := range headRoom.Resources {
+		if used.Resources[key] >= share.Resources[key] {
+			headRoom.Resources[key] = share.Resources[key] - used.Resources[key]
+		}
+	}
+	return headRoom
+}
+
+// Limit the resource types set in the borrow limit in the resource, other resource types are not changed.
+// The resource passed in is modified. A nil resource is returned as is: there is no limit to apply it to.
+func applyBorrowLimit(res, borrowLimit *resources.Resource) *resources.Resource {
+	if res == nil || borrowLimit == nil {
+		return res
+	}
+	for key, limit := range borrowLimit.Resources {
+		if value, ok := res.Resources[key]; ok {
+			res.Resources[key] = resources.MinQuantity(value, limit)
+		}
+	}
+	return res
+}
+
+// The queue and the resource type that limit the headroom for a request.
+type headRoomLimit struct {
+	queuePath string
+	resource  string
+}
+
+// Get the headroom for the queue as returned by getHeadRoom and the limit that blocks the request.
+// The limit is the queue closest to the root that sets the headroom for the first resource type, sorted by name,
+// of the request that does not fit in the headroom. The limit is nil if the request fits.
+func (sq *SchedulingQueue) getHeadRoomWithLimit(res *resources.Resource) (*resources.Resource, *headRoomLimit) {
+	headRoom := sq.getHeadRoom()
+	if headRoom == nil || resources.FitIn(headRoom, res) {
+		return headRoom, nil
+	}
+	keys := make([]string, 0, len(res.Resources))
+	for key, value := range res.Resources {
+		if value > headRoom.Resources[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var queuePath string
+		for queue := sq; queue != nil; queue = queue.parent {
+			if value, ok := queue.getQueueHeadRoom(key); ok && value == headRoom.Resources[key] {
+				queuePath = queue.Name
+			}
+		}
+		if queuePath != "" {
+			return headRoom, &headRoomLimit{queuePath: queuePath, resource: key}
+		}
+	}
+	return headRoom, nil
+}
+
+// Get the headroom of the queue for the resource type without looking at the parents.
+// The second value is false if the queue does not limit the resource type.
+func (sq *SchedulingQueue) getQueueHeadRoom(key string) (resources.Quantity, bool) {
+	sq.RLock()
+	defer sq.RUnlock()
+	maxResource := sq.QueueInfo.GetEnforcedMaxResource()
+	borrowMax := sq.QueueInfo.GetBorrowMaxResource()
+	if maxResource == nil && borrowMax == nil {
+		return 0, false
+	}
+	used := resources.Add(sq.allocating, sq.QueueInfo.GetAllocatedResource())
+	// the borrow limit only limits the types it sets, a max limits all types
+	if maxResource == nil {
+		limit, ok := borrowMax.Resources[key]
+		return limit - used.Resources[key], ok
+	}
+	headRoom := applyBorrowLimit(maxResource, borrowMax)
+	return headRoom.Resources[key] - used.Resources[key], true
+}
+
+// Increment the resource proposed for allocation in the node pool for the queue.
+// Decrement will be triggered when the allocation is confirmed in the cache.
+func (sq *SchedulingQueue) incPoolAllocatingResource(pool string, delta *resources.Resource) {
+	if sq.parent != nil {
+		sq.parent.incPoolAllocatingResource(pool, delta)
+	}
+	// update this queue
+	sq.Lock()
+	defer sq.Unlock()
+	sq.poolAllocating[pool] = resources.Add(sq.poolAllocating[pool], delta)
+}
+
+// Decrement the resource proposed for allocation in the node pool for the queue.
+// This is triggered when the cache queue is updated and the allocation is confirmed.
+func (sq *SchedulingQueue) decPoolAllocatingResource(pool string, delta *resources.Resource) {
+	// update the parent
+	if sq.parent != nil {
+		sq.parent.decPoolAllocatingResource(pool, delta)
+	}
+	// update this queue
+	sq.Lock()
+	defer sq.Unlock()
+	var err error
+	sq.poolAllocating[pool], err = resources.SubErrorNegative(sq.poolAllocating[pool], delta)
+	if err != nil {
+		log.Logger().Warn("allocating resources in node pool went negative on queue",
+			zap.String("queueName", sq.QueueInfo.Name),
+			zap.String("nodePool", pool),
+			zap.Error(err))
+	}
+}
+
+// Return the headroom for the queue in the node pool. This takes the max set for the pool of the queue and
+// its parents into account. Returns nil if no queue in the hierarchy has a max set for the pool.
+func (sq *SchedulingQueue) getPoolHeadRoom(pool string) *resources.Resource {
+	var parentHeadRoom *resources.Resource
+	if sq.parent != nil {
+		parentHeadRoom = sq.parent.getPoolHeadRoom(pool)
+	}
+	sq.RLock()
+	defer sq.RUnlock()
+	headRoom := sq.QueueInfo.GetNodePoolMaxResource(pool)
 	// if we have no max set headroom is always the same as the parent
 	if headRoom == nil {
 		return parentHeadRoom
 	}
 	// calculate unused
-	headRoom.SubFrom(sq.allocating)
-	headRoom.SubFrom(sq.QueueInfo.GetAllocatedResource())
-	// check the minimum of the two: parentHeadRoom is nil for root
+	headRoom.SubFrom(sq.poolAllocating[pool])
+	headRoom.SubFrom(sq.QueueInfo.GetNodePoolAllocatedResource(pool))
 	if parentHeadRoom == nil {
 		return headRoom
 	}
 	return resources.ComponentWiseMin(headRoom, parentHeadRoom)
 }
 
+// Increment the resource proposed for allocation for the user in the queue.
+// Decrement will be triggered when the allocation is confirmed in the cache.
+func (sq *SchedulingQueue) incUserAllocatingResource(user string, delta *resources.Resource) {
+	if sq.parent != nil {
+		sq.parent.incUserAllocatingResource(user, delta)
+	}
+	// update this queue
+	sq.Lock()
+	defer sq.Unlock()
+	sq.userAllocating[user] = resources.Add(sq.userAllocating[user], delta)
+}
+
+// Decrement the resource proposed for allocation for the user in the queue.
+// This is triggered when the cache queue is updated and the allocation is confirmed.
+func (sq *SchedulingQueue) decUserAllocatingResource(user string, delta *resources.Resource) {
+	// update the parent
+	if sq.parent != nil {
+		sq.parent.decUserAllocatingResource(user, delta)
+	}
+	// update this queue
+	sq.Lock()
+	defer sq.Unlock()
+	allocating, err := resources.SubErrorNegative(sq.userAllocating[user], delta)
+	if err != nil {
+		log.Logger().Warn("allocating resources of user went negative on queue",
+			zap.String("queueName", sq.QueueInfo.Name),
+			zap.String("user", user),
+			zap.Error(err))
+	}
+	if resources.IsZero(allocating) {
+		delete(sq.userAllocating, user)
+	} else {
+		sq.userAllocating[user] = allocating
+	}
+}
+
+// Return the headroom for the user in the queue. This takes the max set for one user in the queue and its parents
+// into account. Returns nil if no queue in the hierarchy limits users.
+func (sq *SchedulingQueue) getUserHeadRoom(user string) *resources.Resource {
+	var parentHeadRoom *resources.Resource
+	if sq.parent != nil {
+		parentHeadRoom = sq.parent.getUserHeadRoom(user)
+	}
+	sq.RLock()
+	defer sq.RUnlock()
+	headRoom := sq.QueueInfo.GetUserMaxResource()
+	// if we have no max set headroom is always the same as the parent
+	if headRoom == nil {
+		return parentHeadRoom
+	}
+	// calculate unused
+	headRoom.SubFrom(sq.userAllocating[user])
+	headRoom.SubFrom(sq.QueueInfo.GetUserAllocatedResource(user))
+	if parentHeadRoom == nil {
+		return headRoom
+	}
+	return resources.ComponentWiseMin(headRoom, parentHeadRoom)
+}
+
+// Limit the headroom for the application by the headroom of the user of the application in the queue.
+// The headroom is returned as is if no queue in the hierarchy limits users.
+func (sq *SchedulingQueue) getAppHeadRoom(app *SchedulingApplication, headRoom *resources.Resource) *resources.Resource {
+	userHeadRoom := sq.getUserHeadRoom(app.ApplicationInfo.GetUser().User)
+	if userHeadRoom == nil {
+		return headRoom
+	}
+	if headRoom == nil {
+		return userHeadRoom
+	}
+	return resources.ComponentWiseMin(headRoom, userHeadRoom)
+}
+
+// Can the resource be allocated on a node in the node pool for this queue?
+// The queue must be allowed to use the pool and the resource must fit in the headroom of the pool.
+func (sq *SchedulingQueue) canAllocateInPool(pool string, res *resources.Resource) bool {
+	if !sq.QueueInfo.IsNodePoolAllowed(pool) {
+		return false
+	}
+	headRoom := sq.getPoolHeadRoom(pool)
+	return headRoom == nil || resources.FitIn(headRoom, res)
+}
+
 // Get the max resource for the queue this should never be more than the max for the parent.
 // The root queue always has its limit set to the total cluster size (dynamic based on node registration)
 // In case there are no nodes in a newly started cluster and no queues have a limit configured this call
 // will return nil.
 // NOTE: if a resource quantity is missing and a limit is defined the missing quantity will be seen as a limit of 0.
 // When defining a limit you therefore should define all resource quantities.
+// The borrow limit of a queue only limits the resource types of its guarantee, it does not follow this rule.
 func (sq *SchedulingQueue) getMaxResource() *resources.Resource {
 	// get the limit for the parent first and check against the queues own
 	var limit *resources.Resource
@@ -490,31 +937,92 @@ func (sq *SchedulingQueue) getMaxResource() *resources.Resource {
 	}
 	sq.RLock()
 	defer sq.RUnlock()
-	max := sq.QueueInfo.GetMaxResource()
+	max := sq.QueueInfo.GetEnforcedMaxResource()
+	borrowMax := sq.QueueInfo.GetBorrowMaxResource()
 	// no queue limit set, not even for root
 	if limit == nil {
-		return max
+		return applyBorrowLimit(max, borrowMax)
 	}
 	// parent limit set no queue limit return parent
 	if max == nil {
-		return limit
+		return applyBorrowLimit(limit, borrowMax)
 	}
 	// calculate the smallest value for each type
-	return resources.ComponentWiseMin(limit, max)
+	return applyBorrowLimit(resources.ComponentWiseMin(limit, max), borrowMax)
+}
+
+// Get the smallest configured max resource of the queue and its parents.
+// The root queue is not included as its limit is not configured but set to the cluster size. Returns nil if no
+// queue in the hierarchy has a limit configured.
+func (sq *SchedulingQueue) getConfiguredMaxResource() *resources.Resource {
+	if sq.parent == nil {
+		return nil
+	}
+	limit := sq.parent.getConfiguredMaxResource()
+	sq.RLock()
+	defer sq.RUnlock()
+	max := sq.QueueInfo.GetEnforcedMaxResource()
+	borrowMax := sq.QueueInfo.GetBorrowMaxResource()
+	if limit == nil {
+		return applyBorrowLimit(max, borrowMax)
+	}
+	if max == nil {
+		return applyBorrowLimit(limit, borrowMax)
+	}
+	return applyBorrowLimit(mergeConfiguredMax(limit, max), borrowMax)
+}
+
+// Merge two configured max resources: a resource type set in both gets the smallest value, a resource type set in
+// only one of them is not limited by the other and is kept as is. Both resources must be set.
+func mergeConfiguredMax(left, right *resources.Resource) *resources.Resource {
+	merged := left.Clone()
+	for key, value := range right.Resources {
+		if current, ok := merged.Resources[key]; ok {
+			merged.Resources[key] = resources.MinQuantity(current, value)
+		} else {
+			merged.Resources[key] = value
+		}
+	}
+	return merged
+}
+
+// Get the queue with a max allocation the resource does not fit in: the queue itself or one of its parents.
+// Returns nil if the resource fits in the max allocation of all queues in the hierarchy.
+func (sq *SchedulingQueue) getMaxAllocationLimit(res *resources.Resource) *SchedulingQueue {
+	for queue := sq; queue != nil; queue = queue.parent {
+		if !resources.FitInDefined(queue.QueueInfo.GetMaxAllocation(), res) {
+			return queue
+		}
+	}
+	return nil
 }
 
 // Try allocate pending requests. This only gets called if there is a pending request on this queue or its children.
 // This is a depth first algorithm: descend into the depth of the queue tree first. Child queues are sorted based on
 // the configured queue sortType. Queues without pending resources are skipped.
 // Applications are sorted based on the application sortType. Applications without pending resources are skipped.
+// The headroom of an application in an application group is limited by the group maximum of the queue.
+// The headroom of an application is limited by the user maximum of the queue and its parents.
+// Queues that are within their start delay are skipped, asks stay pending until the delay has passed.
+// Leaf queues that reached the allocation cap of the scheduling cycle are skipped until the next cycle.
 // Lock free call this all locks are taken when needed in called functions
 func (sq *SchedulingQueue) tryAllocate(ctx *partitionSchedulingContext) *schedulingAllocation {
+	if sq.isStartDelayed() {
+		log.Logger().Debug("queue skipped, within start delay",
+			zap.String("queueName", sq.Name))
+		return nil
+	}
 	if sq.isLeafQueue() {
-		// get the headroom
-		headRoom := sq.getHeadRoom()
+		if ctx.cycle.isCapped(sq) {
+			log.Logger().Debug("queue skipped, allocation cap of the cycle reached",
+				zap.String("queueName", sq.Name))
+			return nil
+		}
+		// get the headroom, capacity reserved for maintenance is not available
+		headRoom := ctx.getMaintenanceHeadRoom(sq.getHeadRoom())
 		// process the apps (filters out app without pending requests)
 		for _, app := range sq.sortApplications() {
-			alloc := app.tryAllocate(headRoom, ctx)
+			alloc := app.tryAllocate(sq.getAppHeadRoom(app, ctx.getGroupHeadRoom(app, headRoom)), ctx)
 			if alloc != nil {
 				log.Logger().Debug("allocation found on queue",
 					zap.String("queueName", sq.Name),
@@ -538,14 +1046,18 @@ func (sq *SchedulingQueue) tryAllocate(ctx *partitionSchedulingContext) *schedul
 // Try allocate reserved requests. This only gets called if there is a pending request on this queue or its children.
 // This is a depth first algorithm: descend into the depth of the queue tree first. Child queues are sorted based on
 // the configured queue sortType. Queues without pending resources are skipped.
-// Applications are currently NOT sorted and are iterated over in a random order.
+// Applications are currently NOT sorted and are iterated over in a random order, applications on hold are skipped.
+// Queues that are within their start delay or reached the allocation cap of the scheduling cycle are skipped.
 // Lock free call this all locks are taken when needed in called functions
 func (sq *SchedulingQueue) tryReservedAllocate(ctx *partitionSchedulingContext) *schedulingAllocation {
+	if sq.isStartDelayed() {
+		return nil
+	}
 	if sq.isLeafQueue() {
-		// skip if it has no reservations
-		if len(sq.reservedApps) != 0 {
-			// get the headroom
-			headRoom := sq.getHeadRoom()
+		// skip if it has no reservations or reached the allocation cap of the cycle
+		if len(sq.reservedApps) != 0 && !ctx.cycle.isCapped(sq) {
+			// get the headroom, capacity reserved for maintenance is not available
+			headRoom := ctx.getMaintenanceHeadRoom(sq.getHeadRoom())
 			// process the apps
 			for appID, numRes := range sq.reservedApps {
 				if numRes > 1 {
@@ -554,7 +1066,10 @@ func (sq *SchedulingQueue) tryReservedAllocate(ctx *partitionSchedulingContext)
 						zap.Int("reservations", numRes))
 				}
 				app := sq.getApplication(appID)
-				alloc := app.tryReservedAllocate(headRoom, ctx)
+				if app.ApplicationInfo.IsHeld() {
+					continue
+				}
+				alloc := app.tryReservedAllocate(sq.getAppHeadRoom(app, ctx.getGroupHeadRoom(app, headRoom)), ctx)
 				if alloc != nil {
 					log.Logger().Debug("reservation found for allocation found on queue",
 						zap.String("queueName", sq.Name),
@@ -601,6 +1116,27 @@ func (sq *SchedulingQueue) unReserve(appID string) {
 	}
 }
 
+// Set the reservation count for the apps in the queue to the counts passed in, apps not in the list have no reservations.
+// Returns true if the counters were out of sync and have been corrected.
+func (sq *SchedulingQueue) reconcileReservations(counts map[string]int) bool {
+	sq.Lock()
+	defer sq.Unlock()
+	corrected := false
+	for appID, num := range sq.reservedApps {
+		if sq.applications[appID] == nil || counts[appID] != num {
+			delete(sq.reservedApps, appID)
+			corrected = true
+		}
+	}
+	for appID := range sq.applications {
+		if num := counts[appID]; num > 0 && sq.reservedApps[appID] != num {
+			sq.reservedApps[appID] = num
+			corrected = true
+		}
+	}
+	return corrected
+}
+
 // Get the app based on the ID.
 func (sq *SchedulingQueue) getApplication(appID string) *SchedulingApplication {
 	sq.RLock()
@@ -27,6 +27,7 @@ import (
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 )
 
 // create the root queue, base for all testing
@@ -98,6 +99,30 @@ func TestQueueBasics(t *testing.T) {
 	}
 }
 
+func TestApplicationSortPolicy(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create basic root queue")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	assert.Equal(t, leaf.getSortType(), SortType(FifoSortPolicy), "leaf queue should default to fifo")
+
+	tests := map[string]SortType{
+		"fifo":     FifoSortPolicy,
+		"fair":     FairSortPolicy,
+		"sjf":      SjfSortPolicy,
+		"priority": PrioritySortPolicy,
+		"unknown":  FifoSortPolicy,
+	}
+	for policy, expected := range tests {
+		leaf.updateSchedulingQueueProperties(map[string]string{cache.ApplicationSortPolicy: policy})
+		assert.Equal(t, leaf.getSortType(), expected, "unexpected sort type for policy %s", policy)
+	}
+	// the application sort policy does not change the sorting of a parent queue
+	root.updateSchedulingQueueProperties(map[string]string{cache.ApplicationSortPolicy: "priority"})
+	assert.Equal(t, root.getSortType(), SortType(FairSortPolicy), "parent queue should sort fair")
+}
+
 func TestManagedSubQueues(t *testing.T) {
 	// create the root
 	root, err := createRootQueue(nil)
@@ -581,6 +606,32 @@ func TestSortApplications(t *testing.T) {
 	}
 }
 
+func TestSortApplicationsHeld(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create basic root queue")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	app := newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", leaf.Name, security.UserGroup{}, nil))
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	var res *resources.Resource
+	res, err = resources.NewResourceFromConf(map[string]string{"first": "1"})
+	assert.NilError(t, err, "failed to create basic resource")
+	_, err = app.addAllocationAsk(newAllocationAsk("alloc-1", "app-1", res))
+	assert.NilError(t, err, "ask should have been added to app")
+	assert.Equal(t, len(leaf.sortApplications()), 1, "app with pending ask should be in sorted apps")
+
+	// a held app keeps the ask pending but is not returned
+	err = app.ApplicationInfo.SetHeld(true)
+	assert.NilError(t, err, "app should have been put on hold")
+	assert.Equal(t, len(leaf.sortApplications()), 0, "held app should not be in sorted apps")
+	assert.Assert(t, resources.Equals(app.GetPendingResource(), res), "held app should keep the pending resource")
+	err = app.ApplicationInfo.SetHeld(false)
+	assert.NilError(t, err, "app should have been released")
+	assert.Equal(t, len(leaf.sortApplications()), 1, "released app should be in sorted apps")
+}
+
 // This test must not test the sorter that is underlying.
 // It tests the queue specific parts of the code only.
 func TestSortQueue(t *testing.T) {
@@ -733,6 +784,52 @@ func TestHeadroom(t *testing.T) {
 	}
 }
 
+func TestWeightedHeadroom(t *testing.T) {
+	// structure is:
+	// root		max resource 100
+	// - a		weight 3;	alloc 80
+	// - b		weight 1;	alloc 0
+	root, err := createRootQueue(map[string]string{"first": "100"})
+	assert.NilError(t, err, "failed to create root queue with limit")
+	var leafA, leafB *SchedulingQueue
+	leafA, err = createManagedQueue(root, "a", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue a")
+	leafB, err = createManagedQueue(root, "b", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue b")
+	cache.SetQueueWeight(leafA.QueueInfo, 3)
+	pending := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	leafA.incPendingResource(pending)
+	leafB.incPendingResource(pending)
+
+	// both queues are below their share: the whole headroom can be used
+	total := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100})
+	headRoom := leafA.getHeadRoom()
+	assert.Assert(t, resources.Equals(headRoom, total), "unexpected headroom for a: %v", headRoom)
+	headRoom = leafB.getHeadRoom()
+	assert.Assert(t, resources.Equals(headRoom, total), "unexpected headroom for b: %v", headRoom)
+
+	// a is above its share of 75: the headroom left is for b
+	err = leafA.QueueInfo.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 80}), true)
+	assert.NilError(t, err, "failed to set allocated resource on a")
+	headRoom = leafA.getHeadRoom()
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": -5})
+	assert.Assert(t, resources.Equals(headRoom, expected), "unexpected headroom for a: %v", headRoom)
+	headRoom = leafB.getHeadRoom()
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20})
+	assert.Assert(t, resources.Equals(headRoom, expected), "unexpected headroom for b: %v", headRoom)
+
+	// without pending resources the sibling does not take a share
+	leafB.decPendingResource(pending)
+	headRoom = leafA.getHeadRoom()
+	assert.Assert(t, resources.Equals(headRoom, expected), "unexpected headroom for a: %v", headRoom)
+
+	// siblings with the same weight use the whole headroom
+	leafB.incPendingResource(pending)
+	cache.SetQueueWeight(leafB.QueueInfo, 3)
+	headRoom = leafA.getHeadRoom()
+	assert.Assert(t, resources.Equals(headRoom, expected), "unexpected headroom for a: %v", headRoom)
+}
+
 func TestGetMaxUsage(t *testing.T) {
 	// create the root
 	root, err := createRootQueue(nil)
@@ -818,6 +915,228 @@ func TestGetMaxUsage(t *testing.T) {
 	}
 }
 
+func TestGetConfiguredMaxResource(t *testing.T) {
+	// the root limit is never configured
+	root, err := createRootQueue(map[string]string{"first": "10"})
+	assert.NilError(t, err, "failed to create root queue")
+	assert.Assert(t, root.getConfiguredMaxResource() == nil, "root queue should not return a configured max")
+	var parent, leaf *SchedulingQueue
+	parent, err = createManagedQueue(root, "parent", true, nil)
+	assert.NilError(t, err, "failed to create parent queue")
+	assert.Assert(t, parent.getConfiguredMaxResource() == nil, "parent queue without limit should not return a configured max")
+	leaf, err = createManagedQueue(parent, "leaf", false, map[string]string{"first": "5"})
+	assert.NilError(t, err, "failed to create leaf queue")
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	assert.Assert(t, resources.Equals(leaf.getConfiguredMaxResource(), expected), "leaf queue should return own limit")
+
+	// limits on parent and leaf are merged
+	parent, err = createManagedQueue(root, "parent2", true, map[string]string{"first": "3"})
+	assert.NilError(t, err, "failed to create parent2 queue")
+	leaf, err = createManagedQueue(parent, "leaf", false, map[string]string{"first": "5"})
+	assert.NilError(t, err, "failed to create leaf queue")
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 3})
+	assert.Assert(t, resources.Equals(leaf.getConfiguredMaxResource(), expected), "leaf queue should return merged limit")
+
+	// limits using different resource types do not limit each other
+	parent, err = createManagedQueue(root, "parent3", true, map[string]string{"vcore": "10"})
+	assert.NilError(t, err, "failed to create parent3 queue")
+	leaf, err = createManagedQueue(parent, "leaf", false, map[string]string{"memory": "100", "vcore": "20"})
+	assert.NilError(t, err, "failed to create leaf queue")
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 100, "vcore": 10})
+	assert.Assert(t, resources.Equals(leaf.getConfiguredMaxResource(), expected), "unexpected merged limit: %v", leaf.getConfiguredMaxResource())
+	leaf, err = createManagedQueue(parent, "other", false, map[string]string{"memory": "100"})
+	assert.NilError(t, err, "failed to create other leaf queue")
+	limit := leaf.getConfiguredMaxResource()
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 100, "vcore": 10})
+	assert.Assert(t, resources.Equals(limit, expected), "unexpected merged limit: %v", limit)
+	ask := resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 50, "vcore": 5})
+	assert.Assert(t, resources.FitInDefined(limit, ask), "ask within both limits should fit in the merged limit")
+}
+
+func TestSoftMaxEnforcementHeadroom(t *testing.T) {
+	root, err := createRootQueue(map[string]string{"first": "100"})
+	assert.NilError(t, err, "failed to create root queue")
+	conf := configs.QueueConfig{
+		Name: "leaf",
+		Resources: configs.Resources{
+			Max: map[string]string{"first": "20"},
+		},
+		Properties: map[string]string{cache.QueueMaxEnforcement: cache.MaxEnforcementSoft, cache.QueueMaxTolerance: "10"},
+	}
+	var queueInfo *cache.QueueInfo
+	queueInfo, err = cache.NewManagedQueue(conf, root.QueueInfo)
+	assert.NilError(t, err, "failed to create leaf queue")
+	leaf := newSchedulingQueueInfo(queueInfo, root)
+
+	// the tolerance is part of the limits used for scheduling
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 22})
+	assert.Assert(t, resources.Equals(leaf.getMaxResource(), expected), "unexpected max: %v", leaf.getMaxResource())
+	assert.Assert(t, resources.Equals(leaf.getHeadRoom(), expected), "unexpected headroom: %v", leaf.getHeadRoom())
+	assert.Assert(t, resources.Equals(leaf.getConfiguredMaxResource(), expected), "unexpected configured max: %v", leaf.getConfiguredMaxResource())
+}
+
+func TestBorrowLimit(t *testing.T) {
+	root, err := createRootQueue(map[string]string{"first": "100", "second": "100"})
+	assert.NilError(t, err, "failed to create root queue")
+	var parent *SchedulingQueue
+	parent, err = createManagedQueue(root, "parent", true, nil)
+	assert.NilError(t, err, "failed to create parent queue")
+	// guarantee on first only, can borrow 50% of the guarantee
+	conf := configs.QueueConfig{
+		Name: "leaf",
+		Resources: configs.Resources{
+			Guaranteed: map[string]string{"first": "20"},
+		},
+		Properties: map[string]string{cache.QueueBorrowLimit: "50%"},
+	}
+	var queueInfo *cache.QueueInfo
+	queueInfo, err = cache.NewManagedQueue(conf, parent.QueueInfo)
+	assert.NilError(t, err, "failed to create leaf queue")
+	leaf := newSchedulingQueueInfo(queueInfo, parent)
+
+	// only the guaranteed type is limited, the others follow the parent
+	expected := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 30, "second": 100})
+	assert.Assert(t, resources.Equals(leaf.getMaxResource(), expected), "unexpected max: %v", leaf.getMaxResource())
+	assert.Assert(t, resources.Equals(leaf.getHeadRoom(), expected), "unexpected headroom: %v", leaf.getHeadRoom())
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100, "second": 100})
+	assert.Assert(t, resources.Equals(parent.getHeadRoom(), expected), "parent headroom should not be limited: %v", parent.getHeadRoom())
+	assert.Assert(t, leaf.getConfiguredMaxResource() == nil, "configured max should be nil without queue max")
+
+	// usage of the leaf counts against the borrow limit and the parent
+	leaf.incAllocatingResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 10}))
+	err = leaf.QueueInfo.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 15}), true)
+	assert.NilError(t, err, "failed to set allocated resource on leaf")
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5, "second": 90})
+	assert.Assert(t, resources.Equals(leaf.getHeadRoom(), expected), "unexpected headroom after usage: %v", leaf.getHeadRoom())
+
+	// a queue max lower than the borrow limit wins
+	conf.Name = "leaf2"
+	conf.Resources.Max = map[string]string{"first": "25", "second": "50"}
+	queueInfo, err = cache.NewManagedQueue(conf, parent.QueueInfo)
+	assert.NilError(t, err, "failed to create leaf2 queue")
+	leaf = newSchedulingQueueInfo(queueInfo, parent)
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 25, "second": 50})
+	assert.Assert(t, resources.Equals(leaf.getConfiguredMaxResource(), expected), "unexpected configured max: %v", leaf.getConfiguredMaxResource())
+
+	// a borrow limit lower than the queue max wins
+	conf.Name = "leaf3"
+	conf.Properties[cache.QueueBorrowLimit] = "10%"
+	queueInfo, err = cache.NewManagedQueue(conf, parent.QueueInfo)
+	assert.NilError(t, err, "failed to create leaf3 queue")
+	leaf = newSchedulingQueueInfo(queueInfo, parent)
+	expected = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 22, "second": 50})
+	assert.Assert(t, resources.Equals(leaf.getConfiguredMaxResource(), expected), "unexpected configured max: %v", leaf.getConfiguredMaxResource())
+	assert.Assert(t, resources.Equals(leaf.getHeadRoom(), expected), "unexpected headroom: %v", leaf.getHeadRoom())
+}
+
+func TestGetUtilization(t *testing.T) {
+	root, err := createRootQueue(map[string]string{"first": "100", "second": "10"})
+	assert.NilError(t, err, "failed to create root queue")
+	maxRatios, guaranteedRatios := root.GetUtilization()
+	assert.DeepEqual(t, maxRatios, map[string]float64{"first": 0, "second": 0})
+	assert.Assert(t, guaranteedRatios == nil, "root without guarantee should not have a guaranteed utilization")
+
+	// the max and the guarantee of the leaf only cover first
+	conf := configs.QueueConfig{
+		Name: "leaf",
+		Resources: configs.Resources{
+			Guaranteed: map[string]string{"first": "20", "second": "0"},
+			Max:        map[string]string{"first": "50"},
+		},
+	}
+	var queueInfo *cache.QueueInfo
+	queueInfo, err = cache.NewManagedQueue(conf, root.QueueInfo)
+	assert.NilError(t, err, "failed to create leaf queue")
+	leaf := newSchedulingQueueInfo(queueInfo, root)
+	err = leaf.QueueInfo.IncAllocatedResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 30, "second": 5}), true)
+	assert.NilError(t, err, "failed to set allocated resource on leaf")
+	maxRatios, guaranteedRatios = leaf.GetUtilization()
+	assert.DeepEqual(t, maxRatios, map[string]float64{"first": 0.6})
+	assert.DeepEqual(t, guaranteedRatios, map[string]float64{"first": 1.5})
+	maxRatios, _ = root.GetUtilization()
+	assert.DeepEqual(t, maxRatios, map[string]float64{"first": 0.3, "second": 0.5})
+}
+
+func TestGetHeadRoomWithLimit(t *testing.T) {
+	root, err := createRootQueue(map[string]string{"first": "100", "second": "20"})
+	assert.NilError(t, err, "failed to create root queue")
+	var parent, leaf *SchedulingQueue
+	parent, err = createManagedQueue(root, "parent", true, map[string]string{"first": "50", "second": "20"})
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = createManagedQueue(parent, "leaf", false, map[string]string{"first": "40", "second": "20"})
+	assert.NilError(t, err, "failed to create leaf queue")
+
+	// fits: no limit returned
+	ask := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10, "second": 5})
+	headRoom, limit := leaf.getHeadRoomWithLimit(ask)
+	assert.Assert(t, resources.Equals(headRoom, leaf.getHeadRoom()), "unexpected headroom: %v", headRoom)
+	assert.Assert(t, limit == nil, "ask that fits should not return a limit")
+
+	// the leaf has the lowest limit on first
+	ask = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 45})
+	_, limit = leaf.getHeadRoomWithLimit(ask)
+	assert.Assert(t, limit != nil, "ask that does not fit should return a limit")
+	assert.Equal(t, limit.queuePath, "root.parent.leaf", "unexpected limiting queue")
+	assert.Equal(t, limit.resource, "first", "unexpected limiting resource")
+
+	// all queues have the same limit on second: the queue closest to the root is returned
+	ask = resources.NewResourceFromMap(map[string]resources.Quantity{"second": 25})
+	_, limit = leaf.getHeadRoomWithLimit(ask)
+	assert.Equal(t, limit.queuePath, "root", "unexpected limiting queue")
+	assert.Equal(t, limit.resource, "second", "unexpected limiting resource")
+
+	// usage in the queues is taken into account
+	leaf.incAllocatingResource(resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10}))
+	ask = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 35})
+	_, limit = leaf.getHeadRoomWithLimit(ask)
+	assert.Equal(t, limit.queuePath, "root.parent.leaf", "unexpected limiting queue")
+	assert.Equal(t, limit.resource, "first", "unexpected limiting resource")
+
+	// the first resource type sorted by name is returned
+	ask = resources.NewResourceFromMap(map[string]resources.Quantity{"first": 35, "second": 25})
+	_, limit = leaf.getHeadRoomWithLimit(ask)
+	assert.Equal(t, limit.resource, "first", "unexpected limiting resource")
+
+	// a type not set in the max is limited to zero
+	ask = resources.NewResourceFromMap(map[string]resources.Quantity{"third": 1})
+	_, limit = leaf.getHeadRoomWithLimit(ask)
+	assert.Equal(t, limit.queuePath, "root", "unexpected limiting queue")
+	assert.Equal(t, limit.resource, "third", "unexpected limiting resource")
+}
+
+func TestGetUserHeadRoom(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	var parent, leaf *SchedulingQueue
+	parent, err = createManagedQueue(root, "parent", true, nil)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = createManagedQueue(parent, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	app := newSchedulingApplication(cache.NewApplicationInfo("app-1", "default", leaf.Name, security.UserGroup{User: "alice"}, nil))
+	queueHeadRoom := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 3})
+
+	// no queue limits users
+	assert.Assert(t, leaf.getUserHeadRoom("alice") == nil, "headroom should not be limited without a user max")
+	assert.Assert(t, resources.Equals(leaf.getAppHeadRoom(app, queueHeadRoom), queueHeadRoom), "queue headroom should be returned as is")
+
+	// the allocating resources of the user are subtracted, other users are not affected
+	cache.SetQueueUserMax(leaf.QueueInfo, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10}))
+	leaf.incUserAllocatingResource("alice", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 4}))
+	assert.Equal(t, leaf.getUserHeadRoom("alice").Resources["first"], resources.Quantity(6), "unexpected user headroom")
+	assert.Equal(t, leaf.getUserHeadRoom("bob").Resources["first"], resources.Quantity(10), "unexpected headroom for other user")
+
+	// the lowest headroom in the hierarchy is used
+	cache.SetQueueUserMax(parent.QueueInfo, resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}))
+	assert.Equal(t, leaf.getUserHeadRoom("alice").Resources["first"], resources.Quantity(1), "parent user headroom should be the smallest")
+	assert.Equal(t, leaf.getAppHeadRoom(app, queueHeadRoom).Resources["first"], resources.Quantity(1), "user headroom should limit the queue headroom")
+	assert.Equal(t, leaf.getAppHeadRoom(app, nil).Resources["first"], resources.Quantity(1), "user headroom should be returned without queue headroom")
+
+	// the user is removed when nothing is allocating
+	leaf.decUserAllocatingResource("alice", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 4}))
+	assert.Equal(t, len(leaf.userAllocating), 0, "user should have been removed from the leaf")
+	assert.Equal(t, len(root.userAllocating), 0, "user should have been removed from the root")
+}
+
 func TestReserveApp(t *testing.T) {
 	// create the root
 	root, err := createRootQueue(nil)
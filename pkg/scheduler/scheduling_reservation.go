@@ -19,15 +19,18 @@
 package scheduler
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
 )
 
 type reservation struct {
-	nodeID string
-	appID  string
-	askKey string
+	nodeID  string
+	appID   string
+	askKey  string
+	created time.Time
 	// these references must ONLY be used for ask, node and application removal otherwise
 	// the reservations cannot be removed and scheduling might be impacted.
 	app  *SchedulingApplication
@@ -47,10 +50,11 @@ func newReservation(node *SchedulingNode, app *SchedulingApplication, ask *sched
 		return nil
 	}
 	res := &reservation{
-		askKey: ask.AskProto.AllocationKey,
-		ask:    ask,
-		app:    app,
-		node:   node,
+		askKey:  ask.AskProto.AllocationKey,
+		created: time.Now(),
+		ask:     ask,
+		app:     app,
+		node:    node,
 	}
 	if appBased {
 		res.nodeID = node.NodeID
@@ -91,6 +95,11 @@ func (r *reservation) unReserve() (string, error) {
 	return r.appID, err
 }
 
+// Is the reservation older than the age passed in?
+func (r *reservation) isOlder(age time.Duration) bool {
+	return time.Since(r.created) > age
+}
+
 func (r *reservation) String() string {
 	if r.nodeID == "" {
 		return r.node.NodeID + " -> " + r.appID + "|" + r.askKey
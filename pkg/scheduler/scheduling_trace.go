@@ -0,0 +1,138 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// Application tag to turn on the scheduling attempt trace for all asks of the application.
+// Tracing is expensive and should only be used to debug a single stuck application, set the tag to "true" to enable.
+const TraceApplicationTag = "debug.trace"
+
+// Names of the checks that can filter out a node as shown in the trace
+const (
+	traceFitInNode             = "fitInNode"
+	traceNodePool              = "nodePool"
+	traceNodeTaints            = "nodeTaints"
+	traceNodeAge               = "nodeAge"
+	traceQueueAntiAffinity     = "queueAntiAffinity"
+	tracePreAllocateCheck      = "preAllocateCheck"
+	tracePreAllocateConditions = "preAllocateConditions"
+	traceAllocateResource      = "allocateResource"
+)
+
+// Results of the scheduling attempt that are not an allocation result
+const (
+	traceNoHeadRoom = "insufficient headroom"
+	traceNoNode     = "no node found"
+	traceAskBudget  = "ask budget used up"
+)
+
+// The trace of the last regular scheduling attempt for an ask.
+// A trace is only created and updated while holding the application lock.
+type askTrace struct {
+	allocKey string
+	time     time.Time
+	queues   []queueHeadRoom // the queues traversed: leaf first, root last
+	nodes    int             // number of nodes evaluated
+	filtered map[string]int  // number of nodes filtered out per check
+	limit    *headRoomLimit  // the limit that blocks the ask if the result is traceNoHeadRoom
+	result   string
+}
+
+type queueHeadRoom struct {
+	name     string
+	headRoom *resources.Resource
+}
+
+// Create a new trace for the ask recording the headroom at each level of the queue hierarchy.
+func newAskTrace(ask *schedulingAllocationAsk, leaf *SchedulingQueue) *askTrace {
+	trace := &askTrace{
+		allocKey: ask.AskProto.AllocationKey,
+		time:     time.Now(),
+		filtered: make(map[string]int),
+	}
+	for queue := leaf; queue != nil; queue = queue.parent {
+		trace.queues = append(trace.queues, queueHeadRoom{
+			name:     queue.Name,
+			headRoom: queue.getHeadRoom(),
+		})
+	}
+	return trace
+}
+
+// Record a node evaluation, safe to call on a nil trace.
+func (at *askTrace) nodeEvaluated() {
+	if at != nil {
+		at.nodes++
+	}
+}
+
+// Record a node filtered by the check, safe to call on a nil trace.
+func (at *askTrace) nodeFiltered(check string) {
+	if at != nil {
+		at.filtered[check]++
+	}
+}
+
+// Record the result of the scheduling attempt, safe to call on a nil trace.
+func (at *askTrace) setResult(result string) {
+	if at != nil {
+		at.result = result
+	}
+}
+
+// Record the queue and resource type that limit the headroom, safe to call on a nil trace.
+func (at *askTrace) setHeadRoomLimit(limit *headRoomLimit) {
+	if at != nil {
+		at.limit = limit
+	}
+}
+
+func (at *askTrace) toDAO() dao.AskTraceDAOInfo {
+	info := dao.AskTraceDAOInfo{
+		AllocationKey:  at.allocKey,
+		Time:           at.time.UnixNano(),
+		NodesEvaluated: at.nodes,
+		NodesFiltered:  make(map[string]int),
+		Result:         at.result,
+	}
+	if at.limit != nil {
+		info.LimitingQueue = at.limit.queuePath
+		info.LimitingResource = at.limit.resource
+	}
+	for _, queue := range at.queues {
+		headRoom := "unlimited"
+		if queue.headRoom != nil {
+			headRoom = queue.headRoom.DAOString()
+		}
+		info.Queues = append(info.Queues, dao.QueueHeadRoomDAOInfo{
+			QueueName: queue.name,
+			HeadRoom:  headRoom,
+		})
+	}
+	for check, count := range at.filtered {
+		info.NodesFiltered[check] = count
+	}
+	return info
+}
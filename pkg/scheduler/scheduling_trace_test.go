@@ -0,0 +1,125 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+func TestTraceDisabled(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.parent.leaf1", security.UserGroup{}, nil)
+	app := newSchedulingApplication(appInfo)
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications["app-1"] = app
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+	_, err := app.addAllocationAsk(newAllocationAsk("alloc-1", "app-1", res))
+	assert.NilError(t, err, "failed to add ask to app")
+
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation")
+	}
+	assert.Equal(t, len(app.GetAskTraces()), 0, "no traces expected without the trace tag")
+}
+
+func TestTraceAllocate(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	tags := map[string]string{TraceApplicationTag: "True"}
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.parent.leaf1", security.UserGroup{}, tags)
+	app := newSchedulingApplication(appInfo)
+	assert.Assert(t, app.traceEnabled, "tracing should be enabled by the tag")
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications["app-1"] = app
+
+	// large ask is tried first (higher priority) and does not fit on any node
+	large := newAllocationAsk("alloc-large", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 20}))
+	large.priority = 2
+	_, err := app.addAllocationAsk(large)
+	assert.NilError(t, err, "failed to add large ask to app")
+	small := newAllocationAsk("alloc-small", "app-1", resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5}))
+	_, err = app.addAllocationAsk(small)
+	assert.NilError(t, err, "failed to add small ask to app")
+
+	alloc := partition.tryAllocate()
+	if alloc == nil {
+		t.Fatal("allocation did not return any allocation")
+	}
+	assert.Equal(t, alloc.schedulingAsk.AskProto.AllocationKey, "alloc-small", "expected small ask to be allocated")
+
+	traces := app.GetAskTraces()
+	assert.Equal(t, len(traces), 2, "expected a trace for each ask")
+	for _, trace := range traces {
+		// leaf first root last, no limits on leaf or parent
+		assert.Equal(t, len(trace.Queues), 3, "expected all queues up to the root in the trace")
+		assert.Equal(t, trace.Queues[0].QueueName, "root.parent.leaf1", "leaf queue should be first")
+		assert.Equal(t, trace.Queues[2].QueueName, "root", "root queue should be last")
+		assert.Equal(t, trace.Queues[2].HeadRoom, "[first:100]", "root headroom not correct")
+		switch trace.AllocationKey {
+		case "alloc-large":
+			assert.Equal(t, trace.Result, traceNoNode, "large ask should not have found a node")
+			assert.Equal(t, trace.NodesEvaluated, 2, "large ask should have evaluated all nodes")
+			assert.Equal(t, trace.NodesFiltered[traceFitInNode], 2, "large ask should not fit on any node")
+		case "alloc-small":
+			assert.Equal(t, trace.Result, allocated.String(), "small ask should have been allocated")
+			assert.Equal(t, trace.NodesEvaluated, 1, "small ask should have been allocated on the first node")
+			assert.Equal(t, len(trace.NodesFiltered), 0, "small ask should not have filtered nodes")
+		default:
+			t.Errorf("unexpected trace for ask %s", trace.AllocationKey)
+		}
+	}
+
+	// removing the ask removes the trace
+	app.removeAllocationAsk("alloc-large")
+	traces = app.GetAskTraces()
+	assert.Equal(t, len(traces), 1, "trace not removed with the ask")
+	assert.Equal(t, traces[0].AllocationKey, "alloc-small", "wrong trace removed")
+}
+
+func TestTraceNoHeadRoom(t *testing.T) {
+	partition := createQueuesNodes(t)
+	leaf := partition.getQueue("root.parent.leaf1")
+	tags := map[string]string{TraceApplicationTag: "true"}
+	appInfo := cache.NewApplicationInfo("app-1", "default", "root.parent.leaf1", security.UserGroup{}, tags)
+	app := newSchedulingApplication(appInfo)
+	app.queue = leaf
+	leaf.addSchedulingApplication(app)
+	partition.applications["app-1"] = app
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 150})
+	_, err := app.addAllocationAsk(newAllocationAsk("alloc-1", "app-1", res))
+	assert.NilError(t, err, "failed to add ask to app")
+
+	alloc := partition.tryAllocate()
+	assert.Assert(t, alloc == nil, "ask larger than the headroom should not be allocated")
+	traces := app.GetAskTraces()
+	assert.Equal(t, len(traces), 1, "expected a trace for the ask")
+	assert.Equal(t, traces[0].Result, traceNoHeadRoom, "ask should not fit in the headroom")
+	assert.Equal(t, traces[0].LimitingQueue, "root", "unexpected limiting queue")
+	assert.Equal(t, traces[0].LimitingResource, "first", "unexpected limiting resource")
+}
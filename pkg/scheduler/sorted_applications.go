@@ -0,0 +1,256 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/locking"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+// The applications of a leaf queue kept in the order of the sort policy of the queue. The order is maintained
+// incrementally: an application is inserted in its place when it is added to the queue and taken out when it is
+// removed, the applications are not sorted again for each allocation attempt.
+// The fifo and sjf policies sort on values that do not change while the application is in the queue. The fair and
+// priority policies sort on the usage and the asks of the application: the sort keys are refreshed when the sorted
+// applications are retrieved and only the applications of which the key changed are moved.
+// With aging an application moves up one step for each aging period it waits for an allocation. The boost is bounded
+// by the order of the policy: the fifo and sjf policies move the submission time back one aging period for each
+// step, the priority policy adds one to the priority for each step and the fair policy only uses the steps to order
+// applications with the same usage. The wait changes over time: with aging the sort keys of all policies are
+// refreshed.
+// No other lock is taken while the lock is held: the sort keys are retrieved, which takes the app lock, without it.
+type sortedApplications struct {
+	sortType SortType
+	aging    time.Duration         // aging period, 0 means the applications do not age
+	order    []*sortedApp          // applications in sort order
+	apps     map[string]*sortedApp // applications by application ID
+
+	locking.RWMutex
+}
+
+// An application with the sort key of the dynamic policies.
+type sortedApp struct {
+	app      *SchedulingApplication
+	shares   resources.Shares // fair: usage share of the guaranteed resource of the queue
+	priority int32            // priority: the priority of the highest pending ask
+	aged     int64            // number of aging periods the application waited
+}
+
+func newSortedApplications(sortType SortType) *sortedApplications {
+	return &sortedApplications{
+		sortType: sortType,
+		apps:     make(map[string]*sortedApp),
+	}
+}
+
+// Check if the sort policy sorts on values that change while the application is in the queue.
+func isDynamicSortType(sortType SortType, aging time.Duration) bool {
+	return sortType == FairSortPolicy || sortType == PrioritySortPolicy || aging > 0
+}
+
+// Add the application in its place. The application of a dynamic policy is added with an empty sort key: the key
+// is set when the sorted applications are retrieved. Adding an application that is already tracked is a noop.
+func (sa *sortedApplications) add(app *SchedulingApplication) {
+	sa.Lock()
+	defer sa.Unlock()
+	appID := app.ApplicationInfo.ApplicationID
+	if _, ok := sa.apps[appID]; ok {
+		return
+	}
+	entry := &sortedApp{app: app}
+	sa.apps[appID] = entry
+	idx := sort.Search(len(sa.order), func(i int) bool {
+		return sa.less(entry, sa.order[i])
+	})
+	sa.order = append(sa.order, nil)
+	copy(sa.order[idx+1:], sa.order[idx:])
+	sa.order[idx] = entry
+}
+
+// Remove the application, removing an application that is not tracked is a noop.
+func (sa *sortedApplications) remove(appID string) {
+	sa.Lock()
+	defer sa.Unlock()
+	entry, ok := sa.apps[appID]
+	if !ok {
+		return
+	}
+	delete(sa.apps, appID)
+	for i, e := range sa.order {
+		if e == entry {
+			copy(sa.order[i:], sa.order[i+1:])
+			sa.order[len(sa.order)-1] = nil
+			sa.order = sa.order[:len(sa.order)-1]
+			break
+		}
+	}
+}
+
+// Get a copy of the applications in sort order. A change of the sort policy or the aging period sorts all
+// applications again. For the dynamic policies the sort key of all applications is retrieved and the applications of
+// which the key changed are moved to their new place. The total is the resource the usage share is calculated
+// against for the fair policy.
+func (sa *sortedApplications) sorted(sortType SortType, aging time.Duration, total *resources.Resource) []*SchedulingApplication {
+	sa.RLock()
+	resort := sa.sortType != sortType || sa.aging != aging
+	apps := sa.getApps()
+	sa.RUnlock()
+
+	dynamic := isDynamicSortType(sortType, aging)
+	if !resort && !dynamic {
+		return apps
+	}
+	// retrieve the keys without the lock
+	keys := make([]sortedApp, len(apps))
+	if dynamic {
+		now := time.Now()
+		for i, app := range apps {
+			keys[i] = newSortedAppKey(app, sortType, aging, total, now)
+		}
+	}
+
+	sa.Lock()
+	defer sa.Unlock()
+	sa.sortType = sortType
+	sa.aging = aging
+	var changed []*sortedApp
+	for i, app := range apps {
+		entry, ok := sa.apps[app.ApplicationInfo.ApplicationID]
+		// removed while the keys were retrieved
+		if !ok || entry.app != app {
+			continue
+		}
+		if resort || entry.priority != keys[i].priority || entry.aged != keys[i].aged || resources.CompShares(entry.shares, keys[i].shares) != 0 {
+			entry.shares = keys[i].shares
+			entry.priority = keys[i].priority
+			entry.aged = keys[i].aged
+			changed = append(changed, entry)
+		}
+	}
+	if resort {
+		sort.SliceStable(sa.order, func(i, j int) bool {
+			return sa.less(sa.order[i], sa.order[j])
+		})
+	} else if len(changed) != 0 {
+		sa.reposition(changed)
+	}
+	return sa.getApps()
+}
+
+// Move the entries of which the key changed to their new place: the unchanged entries are still in sort order, the
+// changed entries are sorted and merged with them.
+// Should be called with the lock held.
+func (sa *sortedApplications) reposition(changed []*sortedApp) {
+	moved := make(map[*sortedApp]bool, len(changed))
+	for _, entry := range changed {
+		moved[entry] = true
+	}
+	sort.SliceStable(changed, func(i, j int) bool {
+		return sa.less(changed[i], changed[j])
+	})
+	merged := make([]*sortedApp, 0, len(sa.order))
+	c := 0
+	for _, entry := range sa.order {
+		if moved[entry] {
+			continue
+		}
+		for c < len(changed) && sa.less(changed[c], entry) {
+			merged = append(merged, changed[c])
+			c++
+		}
+		merged = append(merged, entry)
+	}
+	sa.order = append(merged, changed[c:]...)
+}
+
+// Get a copy of the applications in the current order.
+// Should be called with the lock held.
+func (sa *sortedApplications) getApps() []*SchedulingApplication {
+	apps := make([]*SchedulingApplication, len(sa.order))
+	for i, entry := range sa.order {
+		apps[i] = entry.app
+	}
+	return apps
+}
+
+// Get the sort key of the application for a dynamic policy, takes the app lock.
+func newSortedAppKey(app *SchedulingApplication, sortType SortType, aging time.Duration, total *resources.Resource, now time.Time) sortedApp {
+	key := sortedApp{}
+	if aging > 0 {
+		key.aged = int64(app.getWaitingTime(now) / aging)
+	}
+	switch sortType {
+	case FairSortPolicy:
+		key.shares = resources.NewShares(app.getAssumeAllocated(), total)
+	case PrioritySortPolicy:
+		if ask := app.getHighestPriorityAsk(); ask != nil {
+			key.priority = ask.priority
+		}
+	}
+	return key
+}
+
+// Compare the entries using the sort policy of the queue. Entries that are equal for the policy are sorted
+// on the application ID: the order does not depend on the order in which the applications were added.
+// Should be called with the lock held.
+func (sa *sortedApplications) less(l, r *sortedApp) bool {
+	la := l.app
+	ra := r.app
+	switch sa.sortType {
+	case FairSortPolicy:
+		if comp := resources.CompShares(l.shares, r.shares); comp != 0 {
+			return comp < 0
+		}
+		// the steps only order the applications with the same usage
+		if l.aged != r.aged {
+			return l.aged > r.aged
+		}
+	case FifoSortPolicy:
+		if lt, rt := sa.agedSubmissionTime(l), sa.agedSubmissionTime(r); lt != rt {
+			return lt < rt
+		}
+	case SjfSortPolicy:
+		if la.runtimeEstimate != ra.runtimeEstimate {
+			if la.runtimeEstimate == 0 || ra.runtimeEstimate == 0 {
+				return ra.runtimeEstimate == 0
+			}
+			return la.runtimeEstimate < ra.runtimeEstimate
+		}
+		if lt, rt := sa.agedSubmissionTime(l), sa.agedSubmissionTime(r); lt != rt {
+			return lt < rt
+		}
+	case PrioritySortPolicy:
+		if lp, rp := int64(l.priority)+l.aged, int64(r.priority)+r.aged; lp != rp {
+			return lp > rp
+		}
+		if la.ApplicationInfo.SubmissionTime != ra.ApplicationInfo.SubmissionTime {
+			return la.ApplicationInfo.SubmissionTime < ra.ApplicationInfo.SubmissionTime
+		}
+	}
+	return la.ApplicationInfo.ApplicationID < ra.ApplicationInfo.ApplicationID
+}
+
+// Get the submission time of the application moved back one aging period for each step.
+// Should be called with the lock held.
+func (sa *sortedApplications) agedSubmissionTime(entry *sortedApp) int64 {
+	return entry.app.ApplicationInfo.SubmissionTime - entry.aged*int64(sa.aging)
+}
@@ -0,0 +1,248 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
+)
+
+// sort the applications as the queue does, returns the applications in sort order
+func sortAppList(apps []*SchedulingApplication, sortType SortType, total *resources.Resource) []*SchedulingApplication {
+	sorted := newSortedApplications(sortType)
+	for _, app := range apps {
+		sorted.add(app)
+	}
+	return sorted.sorted(sortType, 0, total)
+}
+
+func newSortedTestApps(estimates []string) []*SchedulingApplication {
+	list := make([]*SchedulingApplication, len(estimates))
+	for i := range estimates {
+		tags := map[string]string{RuntimeEstimateApplicationTag: estimates[i]}
+		list[i] = newSchedulingApplication(
+			cache.NewApplicationInfo("app-"+strconv.Itoa(i), "partition", "queue",
+				security.UserGroup{}, tags))
+		// make sure the time stamps differ at least a bit (tracking in nano seconds)
+		time.Sleep(time.Nanosecond * 5)
+	}
+	return list
+}
+
+func TestSortedAppsAddRemove(t *testing.T) {
+	list := newSortedTestApps([]string{"", "", "", ""})
+	sorted := newSortedApplications(FifoSortPolicy)
+	// add out of order: fifo keeps the submission order
+	for _, i := range []int{2, 0, 3, 1} {
+		sorted.add(list[i])
+	}
+	// adding twice is a noop
+	sorted.add(list[1])
+	assertAppList(t, sorted.sorted(FifoSortPolicy, 0, nil), []int{0, 1, 2, 3})
+
+	sorted.remove("app-2")
+	apps := sorted.sorted(FifoSortPolicy, 0, nil)
+	assert.Equal(t, len(apps), 3, "removed app should not be returned")
+	assert.Equal(t, apps[2].ApplicationInfo.ApplicationID, "app-3")
+	// removing an unknown app is a noop
+	sorted.remove("unknown")
+	assert.Equal(t, len(sorted.sorted(FifoSortPolicy, 0, nil)), 3, "remove of unknown app changed the apps")
+	sorted.add(list[2])
+	assertAppList(t, sorted.sorted(FifoSortPolicy, 0, nil), []int{0, 1, 2, 3})
+}
+
+func TestSortedAppsChangePolicy(t *testing.T) {
+	// same setup as TestSortAppsSjf
+	list := newSortedTestApps([]string{"", "10m", "1m", "10m"})
+	sorted := newSortedApplications(FifoSortPolicy)
+	for _, app := range list {
+		sorted.add(app)
+	}
+	assertAppList(t, sorted.sorted(FifoSortPolicy, 0, nil), []int{0, 1, 2, 3})
+	// a new policy sorts all apps again
+	assertAppList(t, sorted.sorted(SjfSortPolicy, 0, nil), []int{3, 1, 0, 2})
+	// new apps are added using the new policy
+	sorted.remove("app-1")
+	sorted.add(list[1])
+	assertAppList(t, sorted.sorted(SjfSortPolicy, 0, nil), []int{3, 1, 0, 2})
+	assertAppList(t, sorted.sorted(FifoSortPolicy, 0, nil), []int{0, 1, 2, 3})
+}
+
+func TestSortedAppsFair(t *testing.T) {
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{
+		"first": resources.Quantity(100)})
+	list := newSortedTestApps([]string{"", "", "", ""})
+	sorted := newSortedApplications(FairSortPolicy)
+	for i := 3; i >= 0; i-- {
+		list[i].allocating = resources.Multiply(res, int64(i+1))
+		sorted.add(list[i])
+	}
+	// the keys are set on retrieval: lowest usage first
+	assertAppList(t, sorted.sorted(FairSortPolicy, 0, resources.Multiply(res, 5)), []int{0, 1, 2, 3})
+
+	// a change of the usage moves the app
+	list[1].allocating = resources.Multiply(res, 10)
+	assertAppList(t, sorted.sorted(FairSortPolicy, 0, resources.Multiply(res, 5)), []int{0, 3, 1, 2})
+	list[2].allocating = resources.Multiply(res, -10)
+	list[0].allocating = resources.Multiply(res, 20)
+	assertAppList(t, sorted.sorted(FairSortPolicy, 0, resources.Multiply(res, 5)), []int{3, 2, 0, 1})
+
+	// the result matches a new sort of the applications added in a different order
+	apps := sortAppList([]*SchedulingApplication{list[3], list[1], list[0], list[2]}, FairSortPolicy, resources.Multiply(res, 5))
+	for i, app := range sorted.sorted(FairSortPolicy, 0, resources.Multiply(res, 5)) {
+		assert.Equal(t, app, apps[i], "incremental order differs from new sort at %d", i)
+	}
+}
+
+func TestSortedAppsPriority(t *testing.T) {
+	list := newSortedTestApps([]string{"", "", "", ""})
+	sorted := newSortedApplications(PrioritySortPolicy)
+	for _, app := range list {
+		sorted.add(app)
+	}
+	// no asks: submission order
+	assertAppList(t, sorted.sorted(PrioritySortPolicy, 0, nil), []int{0, 1, 2, 3})
+	// same setup as TestSortAppsPriority
+	priorities := [][]int32{nil, {1, 5}, {3}, {5}}
+	for i, app := range list {
+		for j, priority := range priorities[i] {
+			ask := newAllocationAskPriority("alloc-"+strconv.Itoa(j), app.ApplicationInfo.ApplicationID, priority)
+			app.requests[ask.AskProto.AllocationKey] = ask
+			app.askQueue.push(ask)
+		}
+	}
+	assertAppList(t, sorted.sorted(PrioritySortPolicy, 0, nil), []int{3, 0, 2, 1})
+}
+
+func TestSortedAppsAging(t *testing.T) {
+	list := newSortedTestApps([]string{"", "", "", ""})
+	sorted := newSortedApplications(FifoSortPolicy)
+	for _, app := range list {
+		sorted.add(app)
+	}
+	now := time.Now()
+	for _, app := range list {
+		app.waitingSince = now
+	}
+	// app-3 waited two periods, app-2 one period
+	list[3].waitingSince = now.Add(-25 * time.Minute)
+	list[2].waitingSince = now.Add(-15 * time.Minute)
+	assertAppList(t, sorted.sorted(FifoSortPolicy, 0, nil), []int{0, 1, 2, 3})
+	assertAppList(t, sorted.sorted(FifoSortPolicy, 10*time.Minute, nil), []int{2, 3, 1, 0})
+	// an allocation resets the wait
+	list[3].waitingSince = now
+	assertAppList(t, sorted.sorted(FifoSortPolicy, 10*time.Minute, nil), []int{1, 2, 0, 3})
+
+	// the priority policy adds a step to the priority: app-1 has priority 2, app-3 waited three periods
+	for i, priority := range []int32{0, 2, 0, 0} {
+		ask := newAllocationAskPriority("alloc-1", list[i].ApplicationInfo.ApplicationID, priority)
+		list[i].requests[ask.AskProto.AllocationKey] = ask
+		list[i].askQueue.push(ask)
+	}
+	list[2].waitingSince = now
+	list[3].waitingSince = now.Add(-35 * time.Minute)
+	assertAppList(t, sorted.sorted(PrioritySortPolicy, 10*time.Minute, nil), []int{2, 1, 3, 0})
+	list[3].waitingSince = now.Add(-15 * time.Minute)
+	assertAppList(t, sorted.sorted(PrioritySortPolicy, 10*time.Minute, nil), []int{2, 0, 3, 1})
+}
+
+func TestSortedAppsAgingBounded(t *testing.T) {
+	list := newSortedTestApps([]string{"", "", "", ""})
+	sorted := newSortedApplications(FairSortPolicy)
+	now := time.Now()
+	for i, used := range []resources.Quantity{30, 10, 20, 20} {
+		list[i].allocating = resources.NewResourceFromMap(map[string]resources.Quantity{"first": used})
+		list[i].waitingSince = now
+		sorted.add(list[i])
+	}
+	assertAppList(t, sorted.sorted(FairSortPolicy, 10*time.Minute, nil), []int{3, 0, 1, 2})
+	// app-0 waited five periods but has the highest usage: it stays last, the apps that did not age keep the fair
+	// order and app-3 only passes app-2 with the same usage
+	list[0].waitingSince = now.Add(-55 * time.Minute)
+	list[3].waitingSince = now.Add(-15 * time.Minute)
+	assertAppList(t, sorted.sorted(FairSortPolicy, 10*time.Minute, nil), []int{3, 0, 2, 1})
+
+	// fifo: app-3 waited two periods, it only passes the apps submitted less than two periods before it
+	for _, app := range list {
+		app.waitingSince = now
+	}
+	list[1].ApplicationInfo.SubmissionTime = list[0].ApplicationInfo.SubmissionTime + int64(time.Hour)
+	list[2].ApplicationInfo.SubmissionTime = list[0].ApplicationInfo.SubmissionTime + int64(2*time.Hour)
+	list[3].ApplicationInfo.SubmissionTime = list[0].ApplicationInfo.SubmissionTime + int64(2*time.Hour+10*time.Minute)
+	list[3].waitingSince = now.Add(-25 * time.Minute)
+	assertAppList(t, sorted.sorted(FifoSortPolicy, 10*time.Minute, nil), []int{0, 1, 3, 2})
+}
+
+func TestSortApplicationsIncremental(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 10})
+	list := newSortedTestApps([]string{"", "", "", ""})
+	for i := 3; i >= 0; i-- {
+		app := list[i]
+		app.queue = leaf
+		leaf.addSchedulingApplication(app)
+		ask := newAllocationAsk("alloc-1", app.ApplicationInfo.ApplicationID, res)
+		_, err = app.addAllocationAsk(ask)
+		assert.NilError(t, err, "failed to add ask")
+	}
+	assertAppList(t, leaf.sortApplications(), []int{0, 1, 2, 3})
+	// removed apps are not sorted anymore
+	leaf.removeSchedulingApplication(list[0])
+	apps := leaf.sortApplications()
+	assert.Equal(t, len(apps), 3, "removed app should not be returned")
+	assert.Equal(t, apps[0].ApplicationInfo.ApplicationID, "app-1")
+}
+
+func BenchmarkSortApplications(b *testing.B) {
+	const numApps = 5000
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{
+		resources.MEMORY: 100,
+		resources.VCORE:  10,
+	})
+	sorted := newSortedApplications(FairSortPolicy)
+	apps := make([]*SchedulingApplication, numApps)
+	for i := 0; i < numApps; i++ {
+		apps[i] = newSchedulingApplication(
+			cache.NewApplicationInfo("app-"+strconv.Itoa(i), "partition", "queue",
+				security.UserGroup{}, nil))
+		apps[i].allocating = resources.Multiply(res, int64(i%97))
+		sorted.add(apps[i])
+	}
+	total := resources.Multiply(res, 1000)
+	sorted.sorted(FairSortPolicy, 0, total)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// one allocation per attempt changes the usage of one app
+		app := apps[i%numApps]
+		app.allocating = resources.Add(app.allocating, res)
+		sorted.sorted(FairSortPolicy, 0, total)
+	}
+}
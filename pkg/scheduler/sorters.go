@@ -34,70 +34,159 @@ const (
 	FifoSortPolicy        = 1
 	MaxAvailableResources = 2 // node sorting, descending on available resources
 	MinAvailableResources = 3 // node sorting, ascending on available resources
+	SjfSortPolicy         = 4 // application sorting, shortest estimated runtime first
+	FairSharePolicy       = 5 // queue sorting, lowest usage compared to the fair share first
+	PrioritySortPolicy    = 6 // application sorting, highest pending ask priority first
 )
 
 func sortQueue(queues []*SchedulingQueue, sortType SortType) {
 	// TODO add latency metric
-	if sortType == FairSortPolicy {
+	// queues with a higher priority are always sorted before their siblings, the sort policy only decides the
+	// order of queues with the same priority. The priorities are retrieved once before sorting.
+	priorities := make(map[*SchedulingQueue]int32, len(queues))
+	for _, queue := range queues {
+		priorities[queue] = queue.QueueInfo.GetPriority()
+	}
+	switch sortType {
+	case FairSortPolicy:
+		// the usage of a queue is divided by its weight: a queue with a higher weight can use more before it is
+		// sorted behind its siblings. The weighted usage is calculated once before sorting.
+		usage := make(map[*SchedulingQueue]*resources.Resource, len(queues))
+		for _, queue := range queues {
+			usage[queue] = getWeightedUsage(queue.getAssumeAllocated(), queue.QueueInfo.GetWeight())
+		}
+		sort.SliceStable(queues, func(i, j int) bool {
+			l := queues[i]
+			r := queues[j]
+			if priorities[l] != priorities[r] {
+				return priorities[l] > priorities[r]
+			}
+			comp := resources.CompUsageRatioSeparately(usage[l], l.QueueInfo.GetGuaranteedResource(),
+				usage[r], r.QueueInfo.GetGuaranteedResource())
+			return comp < 0
+		})
+	case FairSharePolicy:
+		// the fair share is calculated at the start of the scheduling cycle
 		sort.SliceStable(queues, func(i, j int) bool {
 			l := queues[i]
 			r := queues[j]
-			comp := resources.CompUsageRatioSeparately(l.getAssumeAllocated(), l.QueueInfo.GetGuaranteedResource(),
-				r.getAssumeAllocated(), r.QueueInfo.GetGuaranteedResource())
+			if priorities[l] != priorities[r] {
+				return priorities[l] > priorities[r]
+			}
+			comp := resources.CompUsageRatioSeparately(l.getAssumeAllocated(), l.getFairShare(),
+				r.getAssumeAllocated(), r.getFairShare())
 			return comp < 0
 		})
 	}
 }
 
-func sortApplications(apps []*SchedulingApplication, sortType SortType, globalResource *resources.Resource) {
-	// TODO add latency metric
-	switch sortType {
-	case FairSortPolicy:
-		// Sort by usage
-		sort.SliceStable(apps, func(i, j int) bool {
-			l := apps[i]
-			r := apps[j]
-			return resources.CompUsageRatio(l.getAssumeAllocated(), r.getAssumeAllocated(), globalResource) < 0
-		})
-	case FifoSortPolicy:
-		// Sort by submission time oldest first
-		sort.SliceStable(apps, func(i, j int) bool {
-			l := apps[i]
-			r := apps[j]
-			return l.ApplicationInfo.SubmissionTime < r.ApplicationInfo.SubmissionTime
-		})
+// Get the usage of a queue divided by its weight, the usage is returned as is for the default weight.
+func getWeightedUsage(usage *resources.Resource, weight float64) *resources.Resource {
+	if usage == nil || weight <= 0 || weight == 1 {
+		return usage
 	}
+	return resources.MultiplyBy(usage, 1/weight)
 }
 
 func sortNodes(nodes []*SchedulingNode, sortType SortType) {
 	sortingStart := time.Now()
+	// a single node does not need sorting: the available resource is only retrieved when there is something to sort
+	if len(nodes) < 2 {
+		metrics.GetSchedulerMetrics().ObserveNodeSortingLatency(sortingStart)
+		return
+	}
 	switch sortType {
 	case MaxAvailableResources:
 		// Sort by available resource, descending order
-		sort.SliceStable(nodes, func(i, j int) bool {
-			l := nodes[i]
-			r := nodes[j]
-			return resources.CompUsageShares(l.getAvailableResource(), r.getAvailableResource()) > 0
-		})
+		sorter := newNodeSorter(nodes, true)
+		sort.Stable(sorter)
+		sorter.release()
 	case MinAvailableResources:
 		// Sort by available resource, ascending order
-		sort.SliceStable(nodes, func(i, j int) bool {
-			l := nodes[i]
-			r := nodes[j]
-			return resources.CompUsageShares(r.getAvailableResource(), l.getAvailableResource()) > 0
-		})
+		sorter := newNodeSorter(nodes, false)
+		sort.Stable(sorter)
+		sorter.release()
 	}
 	metrics.GetSchedulerMetrics().ObserveNodeSortingLatency(sortingStart)
 }
 
-func sortAskByPriority(requests []*schedulingAllocationAsk, ascending bool) {
-	sort.SliceStable(requests, func(i, j int) bool {
-		l := requests[i]
-		r := requests[j]
+// Sort the nodes based on the available resource. The shares of the available resource of each node are calculated
+// once before sorting: retrieving the available resource takes the node lock and the sort compares each node
+// multiple times. The sorter is taken from a pool and must be released after sorting: the shares are reused.
+type nodeSorter struct {
+	nodes      []*SchedulingNode
+	shares     []resources.Shares
+	descending bool
+}
 
-		if ascending {
-			return l.priority < r.priority
+func newNodeSorter(nodes []*SchedulingNode, descending bool) *nodeSorter {
+	ns := nodeSorterPool.Get().(*nodeSorter)
+	if cap(ns.shares) < len(nodes) {
+		ns.shares = make([]resources.Shares, len(nodes))
+	}
+	ns.shares = ns.shares[:len(nodes)]
+	for i, node := range nodes {
+		ns.shares[i] = resources.AppendShares(ns.shares[i][:0], node.getAvailableResource(), nil)
+	}
+	ns.nodes = nodes
+	ns.descending = descending
+	return ns
+}
+
+// Put the sorter back in the pool, the nodes are not kept.
+func (ns *nodeSorter) release() {
+	ns.nodes = nil
+	nodeSorterPool.Put(ns)
+}
+
+func (ns *nodeSorter) Len() int {
+	return len(ns.nodes)
+}
+
+func (ns *nodeSorter) Less(i, j int) bool {
+	if ns.descending {
+		return resources.CompShares(ns.shares[i], ns.shares[j]) > 0
+	}
+	return resources.CompShares(ns.shares[j], ns.shares[i]) > 0
+}
+
+func (ns *nodeSorter) Swap(i, j int) {
+	ns.nodes[i], ns.nodes[j] = ns.nodes[j], ns.nodes[i]
+	ns.shares[i], ns.shares[j] = ns.shares[j], ns.shares[i]
+}
+
+// Sort the nodes on the weighted free fraction of their capacity: the sum of the free fraction of each resource type
+// multiplied by the weight of the type. Resource types without a weight are ignored. The score of each node is
+// calculated once before sorting, like the shares in the nodeSorter.
+func sortNodesWeighted(nodes []*SchedulingNode, sortType SortType, weights map[string]float64) {
+	sortingStart := time.Now()
+	if len(nodes) < 2 {
+		metrics.GetSchedulerMetrics().ObserveNodeSortingLatency(sortingStart)
+		return
+	}
+	scores := make(map[string]float64, len(nodes))
+	for _, node := range nodes {
+		scores[node.NodeID] = getWeightedFreeFraction(node.getAvailableResource(), node.nodeInfo.GetCapacity(), weights)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if sortType == MaxAvailableResources {
+			return scores[nodes[i].NodeID] > scores[nodes[j].NodeID]
 		}
-		return l.priority > r.priority
+		return scores[nodes[i].NodeID] < scores[nodes[j].NodeID]
 	})
+	metrics.GetSchedulerMetrics().ObserveNodeSortingLatency(sortingStart)
+}
+
+// Get the sum of the weighted free fraction of the capacity for the resource types with a weight.
+// A resource type the node does not have does not add to the score.
+func getWeightedFreeFraction(available, capacity *resources.Resource, weights map[string]float64) float64 {
+	var score float64
+	for name, weight := range weights {
+		total := capacity.Resources[name]
+		if weight == 0 || total <= 0 {
+			continue
+		}
+		score += weight * float64(available.Resources[name]) / float64(total)
+	}
+	return score
 }
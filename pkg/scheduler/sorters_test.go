@@ -92,6 +92,52 @@ func TestSortQueues(t *testing.T) {
 	sortQueue(queues, FairSortPolicy)
 	assert.Equal(t, len(queues), 3)
 	assertQueueList(t, queues, []int{0, 1, 2})
+
+	// the usage is divided by the weight: q2:100/2/200=0.25
+	cache.SetQueueWeight(q2.QueueInfo, 2)
+	sortQueue(queues, FairSortPolicy)
+	assert.Equal(t, len(queues), 3)
+	assertQueueList(t, queues, []int{1, 2, 0})
+}
+
+// verify the queue priority is used before the sort policy
+func TestSortQueuesPriority(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create basic root queue")
+	cache.SetGuaranteedResource(root.QueueInfo,
+		resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 1000}))
+	queues := make([]*SchedulingQueue, 3)
+	for i := range queues {
+		queues[i], err = createManagedQueue(root, "q"+strconv.Itoa(i), false, nil)
+		assert.NilError(t, err, "failed to create leaf queue")
+		cache.SetGuaranteedResource(queues[i].QueueInfo,
+			resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 100}))
+		queues[i].allocating = resources.NewResourceFromMap(map[string]resources.Quantity{
+			"memory": resources.Quantity(10 * (i + 1))})
+		queues[i].fairShare = resources.NewResourceFromMap(map[string]resources.Quantity{"memory": 100})
+	}
+	q0, q2 := queues[0], queues[2]
+
+	// same priority: the policy decides the order
+	for _, policy := range []SortType{FairSortPolicy, FairSharePolicy} {
+		sortQueue(queues, policy)
+		assertQueueList(t, queues, []int{0, 1, 2})
+	}
+
+	// the most used queue has the highest priority, the lowest priority is sorted last
+	cache.SetQueuePriority(q2.QueueInfo, 5)
+	cache.SetQueuePriority(q0.QueueInfo, -1)
+	for _, policy := range []SortType{FairSortPolicy, FairSharePolicy} {
+		sortQueue(queues, policy)
+		assertQueueList(t, queues, []int{2, 1, 0})
+	}
+
+	// ties are broken by the policy
+	cache.SetQueuePriority(q0.QueueInfo, 5)
+	for _, policy := range []SortType{FairSortPolicy, FairSharePolicy} {
+		sortQueue(queues, policy)
+		assertQueueList(t, queues, []int{0, 2, 1})
+	}
 }
 
 // queue guaranteed resource is 0
@@ -286,7 +332,6 @@ func TestSortNodesMax(t *testing.T) {
 }
 
 func TestSortAppsFifo(t *testing.T) {
-	// stable sort is used so equal values stay were they were
 	res := resources.NewResourceFromMap(map[string]resources.Quantity{
 		"first": resources.Quantity(100)})
 	// setup to sort descending
@@ -306,12 +351,52 @@ func TestSortAppsFifo(t *testing.T) {
 	list[1], list[3] = list[3], list[1]
 	assertAppList(t, list, []int{2, 3, 0, 1})
 	// apps should come back in order created 0, 1, 2, 3
-	sortApplications(list, FifoSortPolicy, nil)
+	list = sortAppList(list, FifoSortPolicy, nil)
 	assertAppList(t, list, []int{0, 1, 2, 3})
 }
 
+func TestSortAppsSjf(t *testing.T) {
+	// app-0 has no estimate, app-1 and app-3 have the same estimate
+	estimates := []string{"", "10m", "1m", "10m"}
+	list := make([]*SchedulingApplication, 4)
+	for i := 0; i < 4; i++ {
+		num := strconv.Itoa(i)
+		tags := map[string]string{RuntimeEstimateApplicationTag: estimates[i]}
+		list[i] = newSchedulingApplication(
+			cache.NewApplicationInfo("app-"+num, "partition", "queue",
+				security.UserGroup{}, tags))
+		// make sure the time stamps differ at least a bit (tracking in nano seconds)
+		time.Sleep(time.Nanosecond * 5)
+	}
+	// shortest first, equal estimates oldest first, no estimate last
+	list = sortAppList(list, SjfSortPolicy, nil)
+	assertAppList(t, list, []int{3, 1, 0, 2})
+}
+
+func TestSortAppsPriority(t *testing.T) {
+	// app-0 has no pending asks, app-1 and app-3 have the same highest priority
+	priorities := [][]int32{nil, {1, 5}, {3}, {5}}
+	list := make([]*SchedulingApplication, 4)
+	for i := 0; i < 4; i++ {
+		num := strconv.Itoa(i)
+		app := newSchedulingApplication(
+			cache.NewApplicationInfo("app-"+num, "partition", "queue",
+				security.UserGroup{}, nil))
+		for j, priority := range priorities[i] {
+			ask := newAllocationAskPriority("alloc-"+strconv.Itoa(j), app.ApplicationInfo.ApplicationID, priority)
+			app.requests[ask.AskProto.AllocationKey] = ask
+			app.askQueue.push(ask)
+		}
+		list[i] = app
+		// make sure the time stamps differ at least a bit (tracking in nano seconds)
+		time.Sleep(time.Nanosecond * 5)
+	}
+	// highest priority first, equal priorities oldest first, no pending asks last
+	list = sortAppList(list, PrioritySortPolicy, nil)
+	assertAppList(t, list, []int{3, 0, 2, 1})
+}
+
 func TestSortAppsFair(t *testing.T) {
-	// stable sort is used so equal values stay were they were
 	res := resources.NewResourceFromMap(map[string]resources.Quantity{
 		"first": resources.Quantity(100)})
 	// setup to sort descending
@@ -330,7 +415,7 @@ func TestSortAppsFair(t *testing.T) {
 	list[1], list[3] = list[3], list[1]
 	assertAppList(t, list, []int{2, 3, 0, 1})
 	// apps should come back in order: 0, 1, 2, 3
-	sortApplications(list, FairSortPolicy, nil)
+	list = sortAppList(list, FairSortPolicy, nil)
 	assertAppList(t, list, []int{0, 1, 2, 3})
 
 	// move things around
@@ -338,7 +423,7 @@ func TestSortAppsFair(t *testing.T) {
 	list[1], list[3] = list[3], list[1]
 	assertAppList(t, list, []int{2, 3, 0, 1})
 	// apps should come back in order: 0, 1, 2, 3
-	sortApplications(list, FairSortPolicy, resources.Multiply(res, 0))
+	list = sortAppList(list, FairSortPolicy, resources.Multiply(res, 0))
 	assertAppList(t, list, []int{0, 1, 2, 3})
 
 	// move things around
@@ -346,19 +431,19 @@ func TestSortAppsFair(t *testing.T) {
 	list[1], list[3] = list[3], list[1]
 	assertAppList(t, list, []int{2, 3, 0, 1})
 	// apps should come back in order: 0, 1, 2, 3
-	sortApplications(list, FairSortPolicy, resources.Multiply(res, 5))
+	list = sortAppList(list, FairSortPolicy, resources.Multiply(res, 5))
 	assertAppList(t, list, []int{0, 1, 2, 3})
 
 	// update allocated resource for app-1
 	list[1].allocating = resources.Multiply(res, 10)
 	// apps should come back in order: 0, 2, 3, 1
-	sortApplications(list, FairSortPolicy, resources.Multiply(res, 5))
+	list = sortAppList(list, FairSortPolicy, resources.Multiply(res, 5))
 	assertAppList(t, list, []int{0, 3, 1, 2})
 
 	// update allocated resource for app-3 to negative (move to head of the list)
 	list[2].allocating = resources.Multiply(res, -10)
 	// apps should come back in order: 3, 0, 2, 1
-	sortApplications(list, FairSortPolicy, resources.Multiply(res, 5))
+	list = sortAppList(list, FairSortPolicy, resources.Multiply(res, 5))
 	for i := 0; i < 4; i++ {
 		log.Logger().Info("allocated res",
 			zap.Int("order", i),
@@ -368,43 +453,6 @@ func TestSortAppsFair(t *testing.T) {
 	assertAppList(t, list, []int{1, 3, 2, 0})
 }
 
-func TestSortAsks(t *testing.T) {
-	// stable sort is used so equal values stay were they were
-	res := resources.NewResourceFromMap(map[string]resources.Quantity{
-		"first": resources.Quantity(1)})
-	list := make([]*schedulingAllocationAsk, 4)
-	for i := 0; i < 4; i++ {
-		num := strconv.Itoa(i)
-		ask := newAllocationAsk("ask-"+num, "app-1", res)
-		ask.priority = int32(i)
-		list[i] = ask
-	}
-	// move things around
-	list[0], list[2] = list[2], list[0]
-	list[1], list[3] = list[3], list[1]
-	assertAskList(t, list, []int{2, 3, 0, 1})
-	sortAskByPriority(list, true)
-	// asks should come back in order: 0, 1, 2, 3
-	assertAskList(t, list, []int{0, 1, 2, 3})
-	// move things around
-	list[0], list[2] = list[2], list[0]
-	list[1], list[3] = list[3], list[1]
-	assertAskList(t, list, []int{2, 3, 0, 1})
-	sortAskByPriority(list, false)
-	// asks should come back in order: 3, 2, 1, 0
-	assertAskList(t, list, []int{3, 2, 1, 0})
-	// make asks with same priority
-	// ask-3 and ask-1 both with prio 1 do not change order
-	// ask-3 must always be earlier in the list
-	list[0].priority = 1
-	sortAskByPriority(list, true)
-	// asks should come back in order: 0, 2, 3, 1
-	assertAskList(t, list, []int{0, 2, 3, 1})
-	sortAskByPriority(list, false)
-	// asks should come back in order: 3, 2, 0, 1
-	assertAskList(t, list, []int{3, 2, 0, 1})
-}
-
 // list of queues and the location of the named queue inside that list
 // place[0] defines the location of the root.q0 in the list of queues
 func assertQueueList(t *testing.T, list []*SchedulingQueue, place []int) {
@@ -415,6 +463,38 @@ func assertQueueList(t *testing.T, list []*SchedulingQueue, place []int) {
 
 // list of nodes and the location of the named nodes inside that list
 // place[0] defines the location of the node-0 in the list of nodes
+func TestSortNodesWeighted(t *testing.T) {
+	// nil or empty list cannot panic
+	weights := map[string]float64{"gpu": 10, "first": 1}
+	sortNodesWeighted(nil, MaxAvailableResources, weights)
+	sortNodesWeighted(make([]*SchedulingNode, 0), MaxAvailableResources, weights)
+
+	// node-0 has no free gpu, node-1 has little free first, node-2 has no gpu at all
+	total := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 100, "gpu": 4})
+	used := []*resources.Resource{
+		resources.NewResourceFromMap(map[string]resources.Quantity{"gpu": 4}),
+		resources.NewResourceFromMap(map[string]resources.Quantity{"first": 90}),
+	}
+	list := make([]*SchedulingNode, 3)
+	for i := 0; i < 2; i++ {
+		num := strconv.Itoa(i)
+		nodeInfo := cache.NewNodeForTest("node-"+num, total)
+		nodeInfo.AddAllocation(cache.CreateMockAllocationInfo("app-1", used[i], "uuid-"+num, "root.leaf", "node-"+num))
+		list[i] = newSchedulingNode(nodeInfo)
+	}
+	list[2] = newNode("node-2", map[string]resources.Quantity{"first": 100})
+
+	// scores: node-0 1, node-1 10.1, node-2 1
+	sortNodesWeighted(list, MaxAvailableResources, weights)
+	assertNodeList(t, list, []int{1, 0, 2})
+	sortNodesWeighted(list, MinAvailableResources, weights)
+	assertNodeList(t, list, []int{0, 2, 1})
+
+	// weights only for first: node-0 and node-2 are completely free
+	sortNodesWeighted(list, MaxAvailableResources, map[string]float64{"first": 1, "gpu": 0})
+	assertNodeList(t, list, []int{0, 2, 1})
+}
+
 func assertNodeList(t *testing.T, list []*SchedulingNode, place []int) {
 	assert.Equal(t, "node-0", list[place[0]].NodeID)
 	assert.Equal(t, "node-1", list[place[1]].NodeID)
@@ -438,3 +518,22 @@ func assertAskList(t *testing.T, list []*schedulingAllocationAsk, place []int) {
 	assert.Equal(t, "ask-2", list[place[2]].AskProto.AllocationKey)
 	assert.Equal(t, "ask-3", list[place[3]].AskProto.AllocationKey)
 }
+
+func BenchmarkSortNodes(b *testing.B) {
+	const numNodes = 1000
+	nodes := make([]*SchedulingNode, numNodes)
+	for i := 0; i < numNodes; i++ {
+		res := resources.NewResourceFromMap(map[string]resources.Quantity{
+			resources.MEMORY: resources.Quantity(1000 + i%97*10),
+			resources.VCORE:  resources.Quantity(100 + i%13),
+		})
+		nodes[i] = newSchedulingNode(cache.NewNodeForSort("node-"+strconv.Itoa(i), res))
+	}
+	list := make([]*SchedulingNode, numNodes)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(list, nodes)
+		sortNodes(list, MaxAvailableResources)
+	}
+}
@@ -25,13 +25,14 @@ import (
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/entrypoint"
 	"github.com/apache/incubator-yunikorn-core/pkg/scheduler"
+	"github.com/apache/incubator-yunikorn-core/pkg/testutils"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
 type mockScheduler struct {
 	proxy          api.SchedulerAPI
 	scheduler      *scheduler.Scheduler
-	mockRM         *mockRMCallback
+	mockRM         *testutils.MockRMCallback
 	serviceContext *entrypoint.ServiceContext
 	clusterInfo    *cache.ClusterInfo
 	rmID           string
@@ -58,7 +59,7 @@ func (m *mockScheduler) Init(config string, autoSchedule bool) error {
 	m.scheduler = m.serviceContext.Scheduler
 
 	configs.MockSchedulerConfigByData([]byte(config))
-	m.mockRM = NewMockRMCallbackHandler()
+	m.mockRM = testutils.NewMockRMCallbackHandler()
 
 	_, err := m.proxy.RegisterResourceManager(
 		&si.RegisterResourceManagerRequest{
@@ -0,0 +1,299 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package tests
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// The allowed difference between the expected and the actual share of a queue or application. The scenarios make
+// 10 or 20 allocations: the tolerance allows a tie between two queues to go either way once.
+const fairnessTolerance = 0.05
+
+// A golden fairness scenario: a fixed workload is scheduled on a fixed cluster until the cluster is full or all
+// asks are allocated. The share of each queue and application in the allocated memory must be within the tolerance
+// of the expected share. A change that fails a scenario changes the fairness of the scheduler: the expected shares
+// must only be updated when that change is intended.
+type fairnessScenario struct {
+	name      string
+	config    string
+	nodes     int                // number of nodes, each with 100 memory and 100 vcore
+	apps      []fairnessApp      // applications in submission order
+	allocs    int                // number of allocations before the cluster is full or all asks are allocated
+	queues    map[string]float64 // expected share of the allocated memory per queue
+	appShares map[string]float64 // expected share of the allocated memory per application
+}
+
+// An application with asks of 10 memory and 10 vcore.
+type fairnessApp struct {
+	id    string
+	queue string
+	asks  int32
+}
+
+var fairnessScenarios = []fairnessScenario{
+	{
+		name: "equal guarantees",
+		config: `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+          - name: b
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+`,
+		nodes:  2,
+		apps:   []fairnessApp{{"app-1", "root.a", 20}, {"app-2", "root.b", 20}},
+		allocs: 20,
+		queues: map[string]float64{"root.a": 0.5, "root.b": 0.5},
+	},
+	{
+		name: "unequal guarantees",
+		config: `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+            resources:
+              guaranteed: {memory: 150, vcore: 150}
+          - name: b
+            resources:
+              guaranteed: {memory: 50, vcore: 50}
+`,
+		nodes:  2,
+		apps:   []fairnessApp{{"app-1", "root.a", 20}, {"app-2", "root.b", 20}},
+		allocs: 20,
+		queues: map[string]float64{"root.a": 0.75, "root.b": 0.25},
+	},
+	{
+		name: "queue weight",
+		config: `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+            properties:
+              queue.weight: 3
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+          - name: b
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+`,
+		nodes:  2,
+		apps:   []fairnessApp{{"app-1", "root.a", 20}, {"app-2", "root.b", 20}},
+		allocs: 20,
+		queues: map[string]float64{"root.a": 0.75, "root.b": 0.25},
+	},
+	{
+		name: "queue max",
+		config: `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+            resources:
+              guaranteed: {memory: 50, vcore: 50}
+              max: {memory: 50, vcore: 50}
+          - name: b
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+`,
+		nodes:  2,
+		apps:   []fairnessApp{{"app-1", "root.a", 20}, {"app-2", "root.b", 20}},
+		allocs: 20,
+		queues: map[string]float64{"root.a": 0.25, "root.b": 0.75},
+	},
+	{
+		name: "unused guarantee",
+		config: `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+          - name: b
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+`,
+		nodes:  2,
+		apps:   []fairnessApp{{"app-1", "root.a", 5}, {"app-2", "root.b", 20}},
+		allocs: 20,
+		queues: map[string]float64{"root.a": 0.25, "root.b": 0.75},
+	},
+	{
+		name: "hierarchy",
+		config: `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: p
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+            queues:
+              - name: x
+              - name: y
+          - name: c
+            resources:
+              guaranteed: {memory: 100, vcore: 100}
+`,
+		nodes:  2,
+		apps:   []fairnessApp{{"app-1", "root.p.x", 20}, {"app-2", "root.p.y", 20}, {"app-3", "root.c", 20}},
+		allocs: 20,
+		queues: map[string]float64{"root.p": 0.5, "root.p.x": 0.25, "root.p.y": 0.25, "root.c": 0.5},
+	},
+	{
+		name: "fair applications",
+		config: `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+            properties:
+              application.sort.policy: fair
+`,
+		nodes:     1,
+		apps:      []fairnessApp{{"app-1", "root.a", 20}, {"app-2", "root.a", 20}},
+		allocs:    10,
+		queues:    map[string]float64{"root.a": 1},
+		appShares: map[string]float64{"app-1": 0.5, "app-2": 0.5},
+	},
+	{
+		name: "fifo applications",
+		config: `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+            properties:
+              application.sort.policy: fifo
+`,
+		nodes:     1,
+		apps:      []fairnessApp{{"app-1", "root.a", 20}, {"app-2", "root.a", 20}},
+		allocs:    10,
+		queues:    map[string]float64{"root.a": 1},
+		appShares: map[string]float64{"app-1": 1, "app-2": 0},
+	},
+}
+
+func TestFairnessScenarios(t *testing.T) {
+	for _, scenario := range fairnessScenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			runFairnessScenario(t, scenario)
+		})
+	}
+}
+
+func runFairnessScenario(t *testing.T, scenario fairnessScenario) {
+	ms := &mockScheduler{}
+	defer ms.Stop()
+	err := ms.Init(scenario.config, false)
+	if err != nil {
+		t.Fatalf("RegisterResourceManager failed: %v", err)
+	}
+	for i := 0; i < scenario.nodes; i++ {
+		nodeID := "node-" + strconv.Itoa(i)
+		err = ms.addNode(nodeID, &si.Resource{
+			Resources: map[string]*si.Quantity{
+				"memory": {Value: 100},
+				"vcore":  {Value: 100},
+			},
+		})
+		if err != nil {
+			t.Fatalf("node creation failed: %v", err)
+		}
+		ms.mockRM.WaitForAcceptedNode(t, nodeID, 1000)
+	}
+	// the apps are added one by one: the submission order is part of the scenario
+	ask := &si.Resource{Resources: map[string]*si.Quantity{"memory": {Value: 10}, "vcore": {Value: 10}}}
+	var pending resources.Quantity
+	for _, app := range scenario.apps {
+		err = ms.addApp(app.id, app.queue, "default")
+		if err != nil {
+			t.Fatalf("adding app %s failed: %v", app.id, err)
+		}
+		ms.mockRM.WaitForAcceptedApplication(t, app.id, 1000)
+		err = ms.addAppRequest(app.id, "alloc-1", ask, app.asks)
+		if err != nil {
+			t.Fatalf("adding request to app %s failed: %v", app.id, err)
+		}
+		pending += resources.Quantity(app.asks * 10)
+	}
+	waitForPendingQueueResource(t, ms.getSchedulingQueue("root"), pending, 1000)
+
+	// every step must make one allocation, the allocation is confirmed before the next step
+	for i := 1; i <= scenario.allocs; i++ {
+		ms.scheduler.MultiStepSchedule(1)
+		ms.mockRM.WaitForAllocations(t, i, 1000)
+	}
+	// the cluster is full or all asks are allocated
+	ms.scheduler.MultiStepSchedule(1)
+	ms.mockRM.WaitForAllocations(t, scenario.allocs, 1000)
+
+	total := float64(scenario.allocs * 10)
+	for queueName, expected := range scenario.queues {
+		queue := ms.getSchedulingQueue(queueName)
+		if queue == nil {
+			t.Fatalf("queue %s not found", queueName)
+		}
+		share := float64(queue.GetAllocatedResource().Resources[resources.MEMORY]) / total
+		if math.Abs(share-expected) > fairnessTolerance {
+			t.Errorf("queue %s share %.2f, expected %.2f (tolerance %.2f)", queueName, share, expected, fairnessTolerance)
+		}
+	}
+	for appID, expected := range scenario.appShares {
+		share := float64(ms.getSchedulingApplication(appID).GetAllocatedResource().Resources[resources.MEMORY]) / total
+		if math.Abs(share-expected) > fairnessTolerance {
+			t.Errorf("application %s share %.2f, expected %.2f (tolerance %.2f)", appID, share, expected, fairnessTolerance)
+		}
+	}
+}
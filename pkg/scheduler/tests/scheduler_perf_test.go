@@ -20,6 +20,7 @@ package tests
 
 import (
 	"fmt"
+	"runtime"
 	"strconv"
 	"testing"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/entrypoint"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/testutils"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -60,7 +62,7 @@ partitions:
                 vcore: 10000
 `
 	configs.MockSchedulerConfigByData([]byte(configData))
-	mockRM := NewMockRMCallbackHandler()
+	mockRM := testutils.NewMockRMCallbackHandler()
 
 	_, err := proxy.RegisterResourceManager(
 		&si.RegisterResourceManagerRequest{
@@ -81,8 +83,8 @@ partitions:
 	if err != nil {
 		b.Fatalf("UpdateRequest application failed: %v", err)
 	}
-	mockRM.waitForAcceptedApplication(b, "app-1", 1000)
-	mockRM.waitForAcceptedApplication(b, "app-2", 1000)
+	mockRM.WaitForAcceptedApplication(b, "app-1", 1000)
+	mockRM.WaitForAcceptedApplication(b, "app-2", 1000)
 
 	// Calculate node resources to make sure all required pods can be allocated
 	requestMem := 10
@@ -120,7 +122,7 @@ partitions:
 
 	// Wait for all nodes to be accepted
 	startTime := time.Now()
-	mockRM.waitForMinAcceptedNodes(b, numNodes, 5000)
+	mockRM.WaitForMinAcceptedNodes(b, numNodes, 5000)
 	duration := time.Since(startTime)
 	b.Logf("Total time to add %d node in %s, %f per second", numNodes, duration, float64(numNodes)/duration.Seconds())
 
@@ -166,18 +168,23 @@ partitions:
 		b.Error(err.Error())
 	}
 
-	// Reset  timer for this benchmark
+	// Reset  timer for this benchmark, the GC stats are used to report the GC pauses during the allocation
+	var gcBefore, gcAfter runtime.MemStats
+	runtime.ReadMemStats(&gcBefore)
 	startTime = time.Now()
 	b.ResetTimer()
 
 	// Wait for all pods to be allocated
-	mockRM.waitForMinAllocations(b, numPods, 300000)
+	mockRM.WaitForMinAllocations(b, numPods, 300000)
 
 	// Stop timer and calculate duration
 	b.StopTimer()
 	duration = time.Since(startTime)
+	runtime.ReadMemStats(&gcAfter)
 
 	b.Logf("Total time to allocate %d containers in %s, %f per second", numPods, duration, float64(numPods)/duration.Seconds())
+	b.Logf("Total GC pause while allocating %s in %d cycles, %d allocations", time.Duration(gcAfter.PauseTotalNs-gcBefore.PauseTotalNs),
+		gcAfter.NumGC-gcBefore.NumGC, gcAfter.Mallocs-gcBefore.Mallocs)
 }
 
 func BenchmarkScheduling(b *testing.B) {
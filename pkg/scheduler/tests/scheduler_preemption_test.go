@@ -95,8 +95,8 @@ func TestBasicPreemption(t *testing.T) {
 		t.Fatalf("Adding node 2 to scheduler failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	err = ms.addApp("app-1", "root.a", "")
 	if err != nil {
@@ -107,8 +107,8 @@ func TestBasicPreemption(t *testing.T) {
 		t.Fatalf("Adding application 2 to scheduler failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedApplication(t, "app-2", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-2", 1000)
 
 	// Check scheduling queue root
 	schedulerQueueRoot := ms.getSchedulingQueue("root")
@@ -150,7 +150,7 @@ func TestBasicPreemption(t *testing.T) {
 	scheduler.MultiStepSchedule(20)
 
 	// We should be able to get 20 allocations.
-	ms.mockRM.waitForAllocations(t, 20, 1000)
+	ms.mockRM.WaitForAllocations(t, 20, 1000)
 
 	// Make sure pending resource updated to 0
 	waitForPendingQueueResource(t, schedulerQueueA, 0, 1000)
@@ -27,6 +27,7 @@ import (
 	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
 	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
 	"github.com/apache/incubator-yunikorn-core/pkg/entrypoint"
+	"github.com/apache/incubator-yunikorn-core/pkg/testutils"
 	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
 )
 
@@ -108,9 +109,9 @@ partitions:
 		t.Fatalf("UpdateRequest nodes and app failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	// Get scheduling app
 	schedulingApp := ms.getSchedulingApplication("app-1")
@@ -149,7 +150,7 @@ partitions:
 
 	ms.scheduler.MultiStepSchedule(16)
 
-	ms.mockRM.waitForAllocations(t, 2, 1000)
+	ms.mockRM.WaitForAllocations(t, 2, 1000)
 
 	// Make sure pending resource updated to 0
 	waitForPendingQueueResource(t, schedulerQueueA, 0, 1000)
@@ -210,7 +211,7 @@ partitions:
 	// Now app-1 uses 20 resource, and queue-a's max = 150, so it can get two 50 container allocated.
 	ms.scheduler.MultiStepSchedule(16)
 
-	ms.mockRM.waitForAllocations(t, 4, 3000)
+	ms.mockRM.WaitForAllocations(t, 4, 3000)
 
 	// Check pending resource, should be 200 now.
 	waitForPendingQueueResource(t, schedulerQueueA, 200, 1000)
@@ -253,7 +254,7 @@ partitions:
 						"vcore":  {Value: 20},
 					},
 				},
-				ExistingAllocations: mockRM.nodeAllocations["node-1:1234"],
+				ExistingAllocations: mockRM.GetNodeAllocations("node-1:1234"),
 			},
 			{
 				NodeID: "node-2:1234",
@@ -267,7 +268,7 @@ partitions:
 						"vcore":  {Value: 20},
 					},
 				},
-				ExistingAllocations: mockRM.nodeAllocations["node-2:1234"],
+				ExistingAllocations: mockRM.GetNodeAllocations("node-2:1234"),
 			},
 		},
 		NewApplications: newAddAppRequest(map[string]string{"app-1": "root.a"}),
@@ -279,9 +280,9 @@ partitions:
 	}
 
 	// waiting for recovery
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	// verify partition info
 	t.Log("verifying partition info")
@@ -296,8 +297,8 @@ partitions:
 	// verify nodes
 	t.Logf("verifying recovered nodes, counts %d", partition.GetTotalNodeCount())
 	assert.Equal(t, 2, partition.GetTotalNodeCount())
-	node1Allocations := mockRM.nodeAllocations["node-1:1234"]
-	node2Allocations := mockRM.nodeAllocations["node-2:1234"]
+	node1Allocations := mockRM.GetNodeAllocations("node-1:1234")
+	node2Allocations := mockRM.GetNodeAllocations("node-2:1234")
 
 	t.Logf("verifying allocations on node-1, expected %d, actual %d",
 		len(node1Allocations), len(partition.GetNode("node-1:1234").GetAllAllocations()))
@@ -333,7 +334,7 @@ partitions:
 
 	// verify scheduler clusterInfo
 	t.Log("verifying scheduling app")
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
 	recoveredApp := ms.getSchedulingApplication("app-1")
 	assert.Assert(t, recoveredApp != nil)
 	assert.Equal(t, recoveredApp.ApplicationInfo.GetAllocatedResource().Resources[resources.MEMORY], resources.Quantity(120))
@@ -342,7 +343,7 @@ partitions:
 	// there should be no pending resources
 	assert.Equal(t, recoveredApp.GetPendingResource().Resources[resources.MEMORY], resources.Quantity(0))
 	assert.Equal(t, recoveredApp.GetPendingResource().Resources[resources.VCORE], resources.Quantity(0))
-	for _, existingAllocation := range mockRM.Allocations {
+	for _, existingAllocation := range mockRM.GetAllocations() {
 		schedulingAllocation := recoveredApp.GetSchedulingAllocationAsk(existingAllocation.AllocationKey)
 		assert.Assert(t, schedulingAllocation != nil)
 	}
@@ -448,8 +449,8 @@ partitions:
 
 	// waiting for recovery
 	// node-1 should be rejected as some of allocations cannot be recovered
-	ms.mockRM.waitForRejectedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForRejectedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	// verify partition resources
 	partition := ms.clusterInfo.GetPartition("[rm:123]default")
@@ -504,7 +505,7 @@ partitions:
 		t.Fatalf("UpdateRequest re-register nodes and app failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
 
 	assert.Equal(t, partition.GetTotalNodeCount(), 2)
 	assert.Equal(t, partition.GetTotalApplicationCount(), 1)
@@ -546,7 +547,7 @@ partitions:
                 vcore: 20
 `
 	configs.MockSchedulerConfigByData([]byte(configData))
-	mockRM := NewMockRMCallbackHandler()
+	mockRM := testutils.NewMockRMCallbackHandler()
 
 	_, err := proxy.RegisterResourceManager(
 		&si.RegisterResourceManagerRequest{
@@ -598,8 +599,8 @@ partitions:
 	}
 
 	// waiting for recovery
-	mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	app01 := serviceContext.Scheduler.GetClusterSchedulingContext().
 		GetSchedulingApplication("app-1", "[rm:123]default")
@@ -631,7 +632,7 @@ partitions:
                 vcore: 20
 `
 	configs.MockSchedulerConfigByData([]byte(configData))
-	mockRM := NewMockRMCallbackHandler()
+	mockRM := testutils.NewMockRMCallbackHandler()
 
 	_, err := proxy.RegisterResourceManager(
 		&si.RegisterResourceManagerRequest{
@@ -654,8 +655,8 @@ partitions:
 		t.Fatalf("UpdateRequest app failed: %v", err)
 	}
 
-	mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	mockRM.waitForAcceptedApplication(t, "app-2", 1000)
+	mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	mockRM.WaitForAcceptedApplication(t, "app-2", 1000)
 
 	// verify app state
 	apps := serviceContext.Cache.GetPartition("[rm:123]default").GetApplications()
@@ -761,9 +762,9 @@ partitions:
 		t.Fatalf("UpdateRequest nodes and apps failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	// now the queue should have been created under root.app-1-namespace
 	assert.Equal(t, len(schedulerQueueRoot.GetCopyOfChildren()), 1)
@@ -800,7 +801,7 @@ partitions:
 
 	ms.scheduler.MultiStepSchedule(16)
 
-	ms.mockRM.waitForAllocations(t, 2, 1000)
+	ms.mockRM.WaitForAllocations(t, 2, 1000)
 
 	// Make sure pending resource updated to 0
 	waitForPendingQueueResource(t, appQueue, 0, 1000)
@@ -848,11 +849,12 @@ partitions:
 	}
 
 	// waiting for recovery
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
 
 	// mock existing allocations
 	recoveringAllocations := make(map[string][]*si.Allocation)
-	for nodeID, allocations := range mockRM.nodeAllocations {
+	for _, nodeID := range mockRM.GetAllocatedNodes() {
+		allocations := mockRM.GetNodeAllocations(nodeID)
 		existingAllocations := make([]*si.Allocation, 0)
 		for _, previousAllocation := range allocations {
 			// except for queue name, copy from previous allocation
@@ -913,6 +915,6 @@ partitions:
 	}
 
 	// waiting for recovery
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 }
@@ -102,7 +102,7 @@ func TestBasicReservation(t *testing.T) {
 		}
 		nodes = append(nodes, node)
 	}
-	ms.mockRM.waitForMinAcceptedNodes(t, 2, 1000)
+	ms.mockRM.WaitForMinAcceptedNodes(t, 2, 1000)
 
 	appID := "app-1"
 	queueName := "root.leaf-1"
@@ -110,7 +110,7 @@ func TestBasicReservation(t *testing.T) {
 	if err != nil {
 		t.Fatalf("adding app to scheduler failed: %v", err)
 	}
-	ms.mockRM.waitForAcceptedApplication(t, appID, 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, appID, 1000)
 	// Get scheduling app
 	app := ms.getSchedulingApplication(appID)
 
@@ -125,7 +125,7 @@ func TestBasicReservation(t *testing.T) {
 
 	// Allocate for app
 	ms.scheduler.MultiStepSchedule(20)
-	ms.mockRM.waitForAllocations(t, 4, 1000)
+	ms.mockRM.WaitForAllocations(t, 4, 1000)
 
 	// Verify that all 4 requests are satisfied
 	mem := int(app.GetAllocatedResource().Resources[resources.MEMORY])
@@ -138,7 +138,7 @@ func TestBasicReservation(t *testing.T) {
 	ms.scheduler.MultiStepSchedule(10)
 
 	// Allocation should not change (still 4)
-	ms.mockRM.waitForAllocations(t, 4, 1000)
+	ms.mockRM.WaitForAllocations(t, 4, 1000)
 
 	// check objects have reservations assigned,
 	assert.Equal(t, 2, len(app.GetReservations()), "reservations missing from app")
@@ -197,7 +197,7 @@ func TestReservationForTwoQueues(t *testing.T) {
 		}
 		nodes = append(nodes, node)
 	}
-	ms.mockRM.waitForMinAcceptedNodes(t, 2, 1000)
+	ms.mockRM.WaitForMinAcceptedNodes(t, 2, 1000)
 
 	// add the first scheduling app
 	app1ID := "app-1"
@@ -206,7 +206,7 @@ func TestReservationForTwoQueues(t *testing.T) {
 	if err != nil {
 		t.Fatalf("adding app 1 to scheduler failed: %v", err)
 	}
-	ms.mockRM.waitForAcceptedApplication(t, app1ID, 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, app1ID, 1000)
 	app1 := ms.getSchedulingApplication(app1ID)
 	// add the second scheduling app
 	app2ID := "app-2"
@@ -215,7 +215,7 @@ func TestReservationForTwoQueues(t *testing.T) {
 	if err != nil {
 		t.Fatalf("adding app 2 to scheduler failed: %v", err)
 	}
-	ms.mockRM.waitForAcceptedApplication(t, app2ID, 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, app2ID, 1000)
 	app2 := ms.getSchedulingApplication(app2ID)
 
 	// add the second scheduling app
@@ -224,7 +224,7 @@ func TestReservationForTwoQueues(t *testing.T) {
 	if err != nil {
 		t.Fatalf("adding app 3 to scheduler failed: %v", err)
 	}
-	ms.mockRM.waitForAcceptedApplication(t, app3ID, 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, app3ID, 1000)
 	app3 := ms.getSchedulingApplication(app3ID)
 
 	leaf1 := ms.getSchedulingQueue("root.leaf-1")
@@ -243,7 +243,7 @@ func TestReservationForTwoQueues(t *testing.T) {
 
 	// Allocate for app 1
 	ms.scheduler.MultiStepSchedule(8)
-	ms.mockRM.waitForAllocations(t, 4, 1000)
+	ms.mockRM.WaitForAllocations(t, 4, 1000)
 	mem := int(app1.GetAllocatedResource().Resources[resources.MEMORY])
 	assert.Equal(t, 80, mem, "allocated resource after alloc not correct")
 	waitForNodesAllocatedResource(t, ms.clusterInfo, ms.partitionName, nodes, 80, 1000)
@@ -266,7 +266,7 @@ func TestReservationForTwoQueues(t *testing.T) {
 	ms.scheduler.MultiStepSchedule(6)
 
 	// Allocation should not change
-	ms.mockRM.waitForAllocations(t, 4, 1000)
+	ms.mockRM.WaitForAllocations(t, 4, 1000)
 
 	// both reservations should be for the same app
 	appResCounter := ms.getPartitionReservations()
@@ -282,12 +282,12 @@ func TestReservationForTwoQueues(t *testing.T) {
 	}
 
 	// Check allocated resource of queue, should be 0 now
-	ms.mockRM.waitForAllocations(t, 0, 1000)
+	ms.mockRM.WaitForAllocations(t, 0, 1000)
 	waitForAllocatedQueueResource(t, leaf1, 0, 1000)
 
 	// Allocate twice, which we should see two reservations filled
 	ms.scheduler.MultiStepSchedule(2)
-	ms.mockRM.waitForAllocations(t, 2, 1000)
+	ms.mockRM.WaitForAllocations(t, 2, 1000)
 
 	// Check allocated resource of queue, should be 50 now
 	waitForAllocatedQueueResource(t, leaf2, 40, 1000)
@@ -327,7 +327,7 @@ func TestRemoveReservedNode(t *testing.T) {
 		}
 		nodes = append(nodes, node)
 	}
-	ms.mockRM.waitForMinAcceptedNodes(t, 2, 1000)
+	ms.mockRM.WaitForMinAcceptedNodes(t, 2, 1000)
 
 	appID := "app-1"
 	queueName := "root.leaf-1"
@@ -335,7 +335,7 @@ func TestRemoveReservedNode(t *testing.T) {
 	if err != nil {
 		t.Fatalf("adding app to scheduler failed: %v", err)
 	}
-	ms.mockRM.waitForAcceptedApplication(t, appID, 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, appID, 1000)
 	// Get scheduling app
 	app := ms.getSchedulingApplication(appID)
 
@@ -351,7 +351,7 @@ func TestRemoveReservedNode(t *testing.T) {
 	// Allocate for app
 	ms.scheduler.MultiStepSchedule(20)
 	waitForAllocatedAppResource(t, app, 80, 1000)
-	ms.mockRM.waitForAllocations(t, 4, 1000)
+	ms.mockRM.WaitForAllocations(t, 4, 1000)
 	waitForPendingAppResource(t, app, 40, 1000)
 	// check objects have reservations assigned,
 	assert.Equal(t, 2, len(app.GetReservations()), "reservations missing from app")
@@ -366,7 +366,7 @@ func TestRemoveReservedNode(t *testing.T) {
 	}
 	waitForRemovedSchedulerNode(t, ms.serviceContext.Scheduler.GetClusterSchedulingContext(), nodes[1], ms.partitionName, 1000)
 	waitForAllocatedAppResource(t, app, 40, 1000)
-	ms.mockRM.waitForAllocations(t, 2, 1000)
+	ms.mockRM.WaitForAllocations(t, 2, 1000)
 	assert.Equal(t, 1, len(app.GetReservations()), "reservations missing from app")
 	assert.Equal(t, 1, len(ms.getSchedulingNode("node-1").GetReservations()), "reservation missing on node-1")
 	assert.Equal(t, 1, ms.getPartitionReservations()[appID], "reservations not removed from partition")
@@ -394,7 +394,7 @@ func TestAddNewNode(t *testing.T) {
 		}
 		nodes = append(nodes, node)
 	}
-	ms.mockRM.waitForMinAcceptedNodes(t, 3, 1000)
+	ms.mockRM.WaitForMinAcceptedNodes(t, 3, 1000)
 	ms.clusterInfo.GetPartition(ms.partitionName).GetNode("node-3").SetSchedulable(false)
 
 	appID := "app-1"
@@ -403,7 +403,7 @@ func TestAddNewNode(t *testing.T) {
 	if err != nil {
 		t.Fatalf("adding app to scheduler failed: %v", err)
 	}
-	ms.mockRM.waitForAcceptedApplication(t, appID, 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, appID, 1000)
 	// Get scheduling app
 	app := ms.getSchedulingApplication(appID)
 
@@ -418,7 +418,7 @@ func TestAddNewNode(t *testing.T) {
 
 	// Allocate for app
 	ms.scheduler.MultiStepSchedule(20)
-	ms.mockRM.waitForAllocations(t, 4, 1000)
+	ms.mockRM.WaitForAllocations(t, 4, 1000)
 	// check objects have reservations assigned,
 	assert.Equal(t, 2, len(app.GetReservations()), "reservations missing from app")
 	assert.Equal(t, 1, len(ms.getSchedulingNode(nodes[0]).GetReservations()), "reservation missing on node-1")
@@ -430,7 +430,7 @@ func TestAddNewNode(t *testing.T) {
 
 	// start allocating: both reservations should be moved to the 3rd node
 	ms.scheduler.MultiStepSchedule(10)
-	ms.mockRM.waitForAllocations(t, 6, 1000)
+	ms.mockRM.WaitForAllocations(t, 6, 1000)
 	waitForAllocatedAppResource(t, app, 120, 1000)
 	waitForPendingQueueResource(t, leafQueue, 0, 1000)
 	waitForPendingAppResource(t, app, 0, 1000)
@@ -137,6 +137,22 @@ partitions:
 		t.Errorf("scheduling queue root.tobeadded is not found")
 	}
 
+	// the RM is told about the changed queues
+	ms.mockRM.WaitForQueueUpdates(t, 1, 1000)
+	updated := make(map[string]string)
+	for _, update := range ms.mockRM.GetQueueUpdates() {
+		if update.PartitionName == "[rm:123]default" {
+			updated[update.QueueName] = update.State
+		}
+	}
+	// root is included: its guarantee is derived from the changed guarantee of root.base
+	assert.DeepEqual(t, updated, map[string]string{
+		"root":             cache.Active.String(),
+		"root.base":        cache.Active.String(),
+		"root.tobedeleted": cache.Draining.String(),
+		"root.tobeadded":   cache.Active.String(),
+	})
+
 	// Check queues of cache and scheduler for the newly added partition
 	partitionInfo = ms.clusterInfo.GetPartition("[rm:123]gpu")
 	if partitionInfo == nil {
@@ -234,9 +250,9 @@ partitions:
 		t.Fatalf("UpdateRequest failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	// Get scheduling app
 	schedulingApp := ms.getSchedulingApplication("app-1")
@@ -278,7 +294,7 @@ partitions:
 
 	ms.scheduler.MultiStepSchedule(16)
 
-	ms.mockRM.waitForAllocations(t, 2, 1000)
+	ms.mockRM.WaitForAllocations(t, 2, 1000)
 
 	// Make sure pending resource updated to 0
 	waitForPendingQueueResource(t, schedulerQueueA, 0, 1000)
@@ -338,7 +354,7 @@ partitions:
 	// Now app-1 uses 20 resource, and queue-a's max = 150, so it can get two 50 container allocated.
 	ms.scheduler.MultiStepSchedule(16)
 
-	ms.mockRM.waitForAllocations(t, 4, 1000)
+	ms.mockRM.WaitForAllocations(t, 4, 1000)
 
 	// Check pending resource, should be 200 now.
 	waitForPendingQueueResource(t, schedulerQueueA, 200, 1000)
@@ -361,7 +377,7 @@ partitions:
 	}
 
 	// Release all allocations
-	for _, v := range ms.mockRM.getAllocations() {
+	for _, v := range ms.mockRM.GetAllocations() {
 		updateRequest.Releases.AllocationsToRelease = append(updateRequest.Releases.AllocationsToRelease, &si.AllocationReleaseRequest{
 			UUID:          v.UUID,
 			ApplicationID: v.ApplicationID,
@@ -376,7 +392,7 @@ partitions:
 		t.Fatalf("UpdateRequest 4 failed: %v", err)
 	}
 
-	ms.mockRM.waitForAllocations(t, 0, 1000)
+	ms.mockRM.WaitForAllocations(t, 0, 1000)
 
 	// Check pending resource, should be 200 (same)
 	waitForPendingQueueResource(t, schedulerQueueA, 200, 1000)
@@ -478,9 +494,9 @@ partitions:
 		t.Fatalf("UpdateRequest failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	err = ms.proxy.Update(&si.UpdateRequest{
 		Asks: []*si.AllocationAsk{
@@ -503,7 +519,7 @@ partitions:
 		t.Fatalf("UpdateRequest 2 failed: %v", err)
 	}
 
-	ms.mockRM.waitForAllocations(t, 15, 1000)
+	ms.mockRM.WaitForAllocations(t, 15, 1000)
 
 	// Check scheduling queue root
 	schedulerQueueRoot := ms.getSchedulingQueue("root")
@@ -592,10 +608,10 @@ partitions:
 		t.Fatalf("UpdateRequest failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedApplication(t, "app-2", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-2", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	err = ms.proxy.Update(&si.UpdateRequest{
 		Asks: []*si.AllocationAsk{
@@ -648,7 +664,7 @@ partitions:
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	ms.mockRM.waitForAllocations(t, 20, 1000)
+	ms.mockRM.WaitForAllocations(t, 20, 1000)
 
 	// Make sure pending resource updated to 0
 	waitForPendingQueueResource(t, schedulerQueueA, 100, 1000)
@@ -722,10 +738,10 @@ partitions:
 		t.Fatalf("UpdateRequest failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedApplication(t, "app-2", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-2", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	err = ms.proxy.Update(&si.UpdateRequest{
 		Asks: []*si.AllocationAsk{
@@ -783,7 +799,7 @@ partitions:
 		time.Sleep(100 * time.Millisecond)
 	}
 
-	ms.mockRM.waitForAllocations(t, 20, 1000)
+	ms.mockRM.WaitForAllocations(t, 20, 1000)
 
 	// Make sure pending resource updated to 100, which means
 	waitForPendingQueueResource(t, schedulerQueueA, 200, 1000)
@@ -862,8 +878,8 @@ partitions:
 		t.Fatalf("UpdateRequest failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	err = ms.proxy.Update(&si.UpdateRequest{
 		NewApplications: newAddAppRequest(map[string]string{"app-reject-1": "root.non-exist-queue"}),
@@ -874,7 +890,7 @@ partitions:
 		t.Fatalf("UpdateRequest 2 failed: %v", err)
 	}
 
-	ms.mockRM.waitForRejectedApplication(t, "app-reject-1", 1000)
+	ms.mockRM.WaitForRejectedApplication(t, "app-reject-1", 1000)
 
 	err = ms.proxy.Update(&si.UpdateRequest{
 		NewApplications: newAddAppRequest(map[string]string{"app-added-2": "root.a"}),
@@ -885,7 +901,58 @@ partitions:
 		t.Fatalf("UpdateRequest 3 failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-added-2", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-added-2", 1000)
+}
+
+// The RM uses whole cores, the scheduler milli cores: an ask that is not a whole number of cores after the ask default
+// resource is applied is rejected, the allocations are sent to the RM in whole cores.
+func TestRMUnitsPartialAsk(t *testing.T) {
+	configData := `
+partitions:
+  - name: default
+    resourceunits:
+      vcore: 1000
+    rmresourceunits:
+      vcore: 1
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: partial
+            properties:
+              ask.default.resource: "[vcore:1.5]"
+          - name: whole
+            properties:
+              ask.default.resource: "[vcore:2]"
+`
+	ms := &mockScheduler{}
+	defer ms.Stop()
+	err := ms.Init(configData, false)
+	assert.NilError(t, err, "RegisterResourceManager failed")
+
+	err = ms.addNode("node-1:1234", &si.Resource{Resources: map[string]*si.Quantity{"memory": {Value: 100}, "vcore": {Value: 10}}})
+	assert.NilError(t, err, "NewNode failed")
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	err = ms.addApp("app-1", "root.partial", "default")
+	assert.NilError(t, err, "AddApplication failed")
+	err = ms.addApp("app-2", "root.whole", "default")
+	assert.NilError(t, err, "AddApplication failed")
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-2", 1000)
+
+	res := &si.Resource{Resources: map[string]*si.Quantity{"memory": {Value: 10}}}
+	err = ms.addAppRequest("app-1", "alloc-1", res, 1)
+	assert.NilError(t, err, "AllocationRequest failed")
+	ms.mockRM.WaitForRejectedAsk(t, "alloc-1", 1000)
+
+	err = ms.addAppRequest("app-2", "alloc-2", res, 1)
+	assert.NilError(t, err, "AllocationRequest failed")
+	waitForPendingAppResource(t, ms.getSchedulingApplication("app-2"), 10, 1000)
+	ms.scheduler.MultiStepSchedule(5)
+	ms.mockRM.WaitForAllocations(t, 1, 1000)
+	for _, alloc := range ms.mockRM.GetAllocations() {
+		assert.Equal(t, alloc.ResourcePerAlloc.Resources["vcore"].Value, int64(2), "allocation not sent in RM units")
+	}
 }
 
 func TestSchedulingOverMaxCapacity(t *testing.T) {
@@ -970,7 +1037,7 @@ partitions:
 				t.Fatalf("UpdateRequest failed in run %s: %v", param.name, err)
 			}
 
-			ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
+			ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
 
 			err = ms.proxy.Update(&si.UpdateRequest{
 				Asks: []*si.AllocationAsk{
@@ -1012,11 +1079,11 @@ partitions:
 
 			assert.Equal(t, len(app1.GetAllAllocations()), 10)
 			assert.Assert(t, app1.GetAllocatedResource().Resources[resources.MEMORY] == 100)
-			assert.Equal(t, len(ms.mockRM.getAllocations()), 10)
+			assert.Equal(t, len(ms.mockRM.GetAllocations()), 10)
 
 			// release all allocated allocations
 			allocReleases := make([]*si.AllocationReleaseRequest, 0)
-			for _, alloc := range ms.mockRM.getAllocations() {
+			for _, alloc := range ms.mockRM.GetAllocations() {
 				allocReleases = append(allocReleases, &si.AllocationReleaseRequest{
 					PartitionName: "default",
 					ApplicationID: "app-1",
@@ -1043,7 +1110,7 @@ partitions:
 			}
 
 			waitForPendingQueueResource(t, schedulingQueue, 0, 1000)
-			assert.Equal(t, len(ms.mockRM.getAllocations()), 2)
+			assert.Equal(t, len(ms.mockRM.GetAllocations()), 2)
 		})
 	}
 }
@@ -1110,8 +1177,8 @@ partitions:
 		t.Fatalf("UpdateRequest failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	// verify scheduling nodes
 	context := ms.scheduler.GetClusterSchedulingContext()
@@ -1254,10 +1321,10 @@ partitions:
 		t.Fatalf("UpdateRequest failed: %v", err)
 	}
 
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
-	ms.mockRM.waitForAcceptedApplication(t, "app-2", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-1:1234", 1000)
-	ms.mockRM.waitForAcceptedNode(t, "node-2:1234", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-2", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
 
 	err = ms.proxy.Update(&si.UpdateRequest{
 		Asks: []*si.AllocationAsk{
@@ -1308,7 +1375,7 @@ partitions:
 
 	for i := 1; i < 10; i++ {
 		ms.scheduler.MultiStepSchedule(1)
-		ms.mockRM.waitForAllocations(t, i, 1000)
+		ms.mockRM.WaitForAllocations(t, i, 1000)
 	}
 
 	node1Alloc := ms.clusterInfo.GetPartition("[rm:123]default").GetNode("node-1:1234").GetAllocatedResource().Resources[resources.MEMORY]
@@ -1373,9 +1440,9 @@ partitions:
 	}
 
 	// verify app and all nodes are accepted
-	ms.mockRM.waitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
 	for _, node := range nodes {
-		ms.mockRM.waitForAcceptedNode(t, node.NodeID, 1000)
+		ms.mockRM.WaitForAcceptedNode(t, node.NodeID, 1000)
 	}
 
 	for _, node := range nodes {
@@ -1415,7 +1482,7 @@ partitions:
 	}
 
 	// Verify all requests are satisfied
-	ms.mockRM.waitForAllocations(t, 20, 1000)
+	ms.mockRM.WaitForAllocations(t, 20, 1000)
 	waitForPendingQueueResource(t, schedulerQueueA, 0, 1000)
 	waitForPendingAppResource(t, schedulingApp1, 0, 1000)
 	assert.Assert(t, schedulingApp1.ApplicationInfo.GetAllocatedResource().Resources[resources.MEMORY] == 200)
@@ -1426,3 +1493,170 @@ partitions:
 		assert.Assert(t, schedulingNode.GetAllocatedResource().Resources[resources.MEMORY] == 20)
 	}
 }
+
+func TestSchedulingMultiplePartitions(t *testing.T) {
+	configData := `
+partitions:
+  - name: default
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+  - name: gpu
+    queues:
+      - name: root
+        submitacl: "*"
+        queues:
+          - name: a
+`
+	ms := &mockScheduler{}
+	defer ms.Stop()
+
+	err := ms.Init(configData, false)
+	if err != nil {
+		t.Fatalf("RegisterResourceManager failed: %v", err)
+	}
+
+	// one node and one app in each partition
+	nodeRes := &si.Resource{
+		Resources: map[string]*si.Quantity{
+			"memory": {Value: 100},
+			"vcore":  {Value: 10},
+		},
+	}
+	err = ms.proxy.Update(&si.UpdateRequest{
+		NewSchedulableNodes: []*si.NewNodeInfo{
+			{
+				NodeID: "node-1:1234",
+				Attributes: map[string]string{
+					"si.io/hostname":       "node-1",
+					"si.io/rackname":       "rack-1",
+					"si.io/node-partition": "default",
+				},
+				SchedulableResource: nodeRes,
+			},
+			{
+				NodeID: "node-2:1234",
+				Attributes: map[string]string{
+					"si.io/hostname":       "node-2",
+					"si.io/rackname":       "rack-1",
+					"si.io/node-partition": "gpu",
+				},
+				SchedulableResource: nodeRes,
+			},
+		},
+		NewApplications: []*si.AddApplicationRequest{
+			{
+				ApplicationID: "app-1",
+				QueueName:     "root.a",
+				PartitionName: "default",
+				Ugi:           &si.UserGroupInformation{User: "testuser"},
+			},
+			{
+				ApplicationID: "app-2",
+				QueueName:     "root.a",
+				PartitionName: "gpu",
+				Ugi:           &si.UserGroupInformation{User: "testuser"},
+			},
+		},
+		RmID: "rm:123",
+	})
+	assert.NilError(t, err, "UpdateRequest nodes and apps failed")
+
+	ms.mockRM.WaitForAcceptedApplication(t, "app-1", 1000)
+	ms.mockRM.WaitForAcceptedApplication(t, "app-2", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-1:1234", 1000)
+	ms.mockRM.WaitForAcceptedNode(t, "node-2:1234", 1000)
+
+	askRes := &si.Resource{
+		Resources: map[string]*si.Quantity{
+			"memory": {Value: 10},
+			"vcore":  {Value: 1},
+		},
+	}
+	err = ms.proxy.Update(&si.UpdateRequest{
+		Asks: []*si.AllocationAsk{
+			{
+				AllocationKey:  "alloc-1",
+				ResourceAsk:    askRes,
+				MaxAllocations: 5,
+				ApplicationID:  "app-1",
+				PartitionName:  "default",
+			},
+			{
+				AllocationKey:  "alloc-1",
+				ResourceAsk:    askRes,
+				MaxAllocations: 5,
+				ApplicationID:  "app-2",
+				PartitionName:  "gpu",
+			},
+		},
+		RmID: "rm:123",
+	})
+	assert.NilError(t, err, "UpdateRequest asks failed")
+
+	gpuPartition := common.GetNormalizedPartitionName("gpu", "rm:123")
+	waitForPendingQueueResource(t, ms.getSchedulingQueue("root.a"), 50, 1000)
+	waitForPendingQueueResource(t, ms.getSchedulingQueuePartition("root.a", gpuPartition), 50, 1000)
+
+	// each cycle schedules both partitions in parallel: one allocation per partition
+	ms.scheduler.MultiStepSchedule(5)
+	ms.mockRM.WaitForAllocations(t, 10, 1000)
+
+	waitForNodesAllocatedResource(t, ms.clusterInfo, "[rm:123]default", []string{"node-1:1234"}, 50, 1000)
+	waitForNodesAllocatedResource(t, ms.clusterInfo, gpuPartition, []string{"node-2:1234"}, 50, 1000)
+}
+
+// Allocations that cannot be delivered to the RM are rolled back and the ask is scheduled again
+func TestAllocationDeliveryRollback(t *testing.T) {
+	ms := &mockScheduler{}
+	defer ms.Stop()
+
+	err := ms.Init(SingleQueueConfig, false)
+	assert.NilError(t, err, "RegisterResourceManager failed")
+
+	nodeID := "node-1"
+	err = ms.addNode(nodeID, &si.Resource{
+		Resources: map[string]*si.Quantity{
+			"memory": {Value: 100},
+			"vcore":  {Value: 100},
+		},
+	})
+	assert.NilError(t, err, "node creation failed")
+	ms.mockRM.WaitForAcceptedNode(t, nodeID, 1000)
+
+	appID := "app-1"
+	queueName := "root.leaf-1"
+	err = ms.addApp(appID, queueName, "default")
+	assert.NilError(t, err, "adding app to scheduler failed")
+	ms.mockRM.WaitForAcceptedApplication(t, appID, 1000)
+
+	res := &si.Resource{Resources: map[string]*si.Quantity{"memory": {Value: 20}, "vcore": {Value: 20}}}
+	err = ms.addAppRequest(appID, "alloc-1", res, 1)
+	assert.NilError(t, err, "adding request to app failed")
+	app := ms.getSchedulingApplication(appID)
+	leafQueue := ms.getSchedulingQueue(queueName)
+	waitForPendingAppResource(t, app, 20, 1000)
+
+	// the RM fails to receive the allocation
+	ms.mockRM.FailNext(1, nil)
+	ms.scheduler.MultiStepSchedule(1)
+	err = common.WaitFor(10*time.Millisecond, time.Second, func() bool {
+		return ms.mockRM.GetFailedCount() == 1
+	})
+	assert.NilError(t, err, "allocation should have failed to reach the RM")
+
+	// the allocation is removed from the cache and the ask is back to pending
+	waitForPendingAppResource(t, app, 20, 1000)
+	waitForPendingQueueResource(t, leafQueue, 20, 1000)
+	waitForNodesAllocatedResource(t, ms.clusterInfo, ms.partitionName, []string{nodeID}, 0, 1000)
+	waitForAllocatedQueueResource(t, leafQueue, 0, 1000)
+	assert.Equal(t, len(ms.mockRM.GetAllocations()), 0, "no allocations should have been delivered")
+
+	// the ask is allocated again on the next cycle
+	ms.scheduler.MultiStepSchedule(5)
+	ms.mockRM.WaitForAllocations(t, 1, 1000)
+	waitForPendingAppResource(t, app, 0, 1000)
+	waitForNodesAllocatedResource(t, ms.clusterInfo, ms.partitionName, []string{nodeID}, 20, 1000)
+}
@@ -0,0 +1,55 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"math/rand"
+)
+
+// Move a random sample of the nodes to the front of the list, the order of the rest of the list is not defined.
+// This is a partial Fisher-Yates shuffle: only the positions in the sample are filled.
+func sampleNodes(nodes []*SchedulingNode, size int) {
+	if size >= len(nodes) {
+		return
+	}
+	for i := 0; i < size; i++ {
+		j := i + rand.Intn(len(nodes)-i)
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+}
+
+// Apply the throughput mode of the partition to the queues: in throughput mode the pending resources of a queue are
+// passed on to its parents in batches. When the mode is switched off the outstanding batches are passed on directly.
+// Lock free call all locks are taken when needed in called functions
+func (psc *partitionSchedulingContext) updatePendingBatching() {
+	enabled, _ := psc.partition.GetThroughput()
+	psc.root.setBatchPending(enabled)
+	if !enabled {
+		psc.root.flushPendingBatch()
+	}
+}
+
+// Pass the pending resources batched in the queues on to their parents.
+// This is called once at the start of each scheduling cycle, nothing is batched if the throughput mode is off.
+// Lock free call all locks are taken when needed in called functions
+func (psc *partitionSchedulingContext) flushPendingBatch() {
+	if enabled, _ := psc.partition.GetThroughput(); enabled {
+		psc.root.flushPendingBatch()
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+)
+
+func TestSampleNodes(t *testing.T) {
+	nodes := make([]*SchedulingNode, 10)
+	for i := range nodes {
+		nodes[i] = newNode(fmt.Sprintf("node-%d", i), map[string]resources.Quantity{"first": 10})
+	}
+	// a sample as large as the list leaves the list as is
+	sampled := make([]*SchedulingNode, len(nodes))
+	copy(sampled, nodes)
+	sampleNodes(sampled, len(nodes))
+	for i, node := range sampled {
+		assert.Equal(t, node, nodes[i], "list changed by a sample of the full list")
+	}
+
+	// a sample only changes the order: all nodes are still in the list once
+	sampleNodes(sampled, 3)
+	seen := make(map[string]bool)
+	for _, node := range sampled {
+		seen[node.NodeID] = true
+	}
+	assert.Equal(t, len(seen), len(nodes), "nodes lost or duplicated by the sample")
+}
+
+func TestThroughputNodeIterator(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	// node-0 has the least free resources, node-9 the most
+	nodes := make([]*SchedulingNode, 10)
+	for i := range nodes {
+		nodes[i] = newNode(fmt.Sprintf("node-%d", i), map[string]resources.Quantity{"first": resources.Quantity(10 * (i + 1))})
+	}
+	iterator := partition.getNodeIterator(nodes, nil)
+	assert.Equal(t, iterator.Next().NodeID, "node-9", "largest node should be first without throughput mode")
+
+	// the unfairness is bounded: the first node is the best node of the sample, it is never one of the nodes that
+	// can only be smaller than all other nodes in the sample
+	sampleSize := 3
+	cache.SetThroughput(partition.partition, true, sampleSize)
+	for run := 0; run < 100; run++ {
+		iterator = partition.getNodeIterator(nodes, nil)
+		count := 0
+		var previous *SchedulingNode
+		for iterator.HasNext() {
+			node := iterator.Next()
+			if count > 0 && count < sampleSize {
+				assert.Assert(t, previous.getAvailableResource().Resources["first"] >= node.getAvailableResource().Resources["first"], "sample not sorted")
+			}
+			if count == 0 {
+				assert.Assert(t, node.getAvailableResource().Resources["first"] >= resources.Quantity(10*sampleSize), "first node %s is not the best of the sample", node.NodeID)
+			}
+			previous = node
+			count++
+		}
+		assert.Equal(t, count, len(nodes), "all nodes should be returned in throughput mode")
+	}
+	// the original list is not changed
+	for i, node := range nodes {
+		assert.Equal(t, node.NodeID, fmt.Sprintf("node-%d", i), "node list changed by the iterator")
+	}
+}
+
+func TestPendingBatch(t *testing.T) {
+	root, err := createRootQueue(nil)
+	assert.NilError(t, err, "failed to create root queue")
+	var parent, leaf *SchedulingQueue
+	parent, err = createManagedQueue(root, "parent", true, nil)
+	assert.NilError(t, err, "failed to create parent queue")
+	leaf, err = createManagedQueue(parent, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+
+	// without batching the parents are updated directly
+	leaf.incPendingResource(res)
+	assert.Assert(t, resources.Equals(root.GetPendingResource(), res), "root pending not updated directly")
+
+	// with batching the parents are only updated when the batch is flushed
+	root.setBatchPending(true)
+	assert.Assert(t, leaf.isBatchingPending(), "batching not set on the leaf")
+	leaf.incPendingResource(res)
+	leaf.incPendingResource(res)
+	leaf.decPendingResource(res)
+	assert.Equal(t, leaf.GetPendingResource().Resources["first"], resources.Quantity(10), "leaf pending should be updated directly")
+	assert.Equal(t, root.GetPendingResource().Resources["first"], resources.Quantity(5), "root pending should not be updated before the flush")
+	root.flushPendingBatch()
+	assert.Equal(t, parent.GetPendingResource().Resources["first"], resources.Quantity(10), "parent pending not updated by the flush")
+	assert.Equal(t, root.GetPendingResource().Resources["first"], resources.Quantity(10), "root pending not updated by the flush")
+	root.flushPendingBatch()
+	assert.Equal(t, root.GetPendingResource().Resources["first"], resources.Quantity(10), "second flush should not change the pending")
+
+	// a new queue follows the parent, the batch of a removed queue is passed on to the parent
+	var other *SchedulingQueue
+	other, err = createManagedQueue(parent, "other", false, nil)
+	assert.NilError(t, err, "failed to create other queue")
+	assert.Assert(t, other.isBatchingPending(), "new queue should batch the pending resources")
+	leaf.decPendingResource(resources.Multiply(res, 2))
+	other.QueueInfo.MarkQueueForRemoval()
+	leaf.QueueInfo.MarkQueueForRemoval()
+	assert.Assert(t, leaf.removeQueue(), "leaf queue should have been removed")
+	assert.Assert(t, resources.IsZero(parent.GetPendingResource()), "parent pending not updated by the queue removal")
+	root.flushPendingBatch()
+	assert.Assert(t, resources.IsZero(root.GetPendingResource()), "root pending not updated after the queue removal")
+
+	// switching batching off updates the parents directly
+	root.setBatchPending(false)
+	other.incPendingResource(res)
+	assert.Assert(t, resources.Equals(root.GetPendingResource(), res), "root pending not updated directly")
+}
+
+func TestPendingBatchPartition(t *testing.T) {
+	partition, err := newTestPartition()
+	assert.NilError(t, err, "test partition create failed")
+	var leaf *SchedulingQueue
+	leaf, err = createManagedQueue(partition.root, "leaf", false, nil)
+	assert.NilError(t, err, "failed to create leaf queue")
+	res := resources.NewResourceFromMap(map[string]resources.Quantity{"first": 5})
+
+	cache.SetThroughput(partition.partition, true, cache.DefaultThroughputSampleSize)
+	partition.updatePendingBatching()
+	leaf.incPendingResource(res)
+	assert.Assert(t, resources.IsZero(partition.root.GetPendingResource()), "root pending should not be updated before the flush")
+	partition.flushPendingBatch()
+	assert.Assert(t, resources.Equals(partition.root.GetPendingResource(), res), "root pending not updated by the flush")
+
+	// switching the mode off passes the outstanding batches on
+	leaf.incPendingResource(res)
+	cache.SetThroughput(partition.partition, false, 0)
+	partition.updatePendingBatching()
+	assert.Assert(t, !leaf.isBatchingPending(), "batching should be off")
+	assert.Assert(t, resources.Equals(partition.root.GetPendingResource(), resources.Multiply(res, 2)), "root pending not updated when the mode is switched off")
+}
@@ -0,0 +1,413 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package testutils provides helpers for integration tests against the scheduler core.
+// Shims and downstream projects can use the mock RM callback to register a resource manager with the core and
+// check the responses without a real RM.
+package testutils
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-core/pkg/common"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+// The interval between the checks of the wait helpers
+const waitInterval = 10 * time.Millisecond
+
+// Mock RM callback that tracks the state of the applications, nodes and allocations as reported by the core.
+// All responses, preemption notifications, queue updates and autoscale events that are processed are captured in the order they were received.
+// Faults can be injected: callbacks can be delayed or fail. A failed callback is not processed or captured.
+type MockRMCallback struct {
+	acceptedApplications map[string]bool
+	rejectedApplications map[string]bool
+	acceptedNodes        map[string]bool
+	rejectedNodes        map[string]bool
+	rejectedAsks         map[string]bool
+	nodeAllocations      map[string][]*si.Allocation
+	allocations          map[string]*si.Allocation
+	responses            []*si.UpdateResponse
+	notifications        []*api.PreemptionNotification
+	queueUpdates         []*api.QueueUpdate
+	autoscaleEvents      []*api.AutoscaleEvent
+	delay                time.Duration // delay before each callback is handled
+	failCount            int           // number of callbacks to fail, negative means all
+	failErr              error         // error returned by a failing callback
+	failed               int           // number of callbacks that failed
+
+	sync.RWMutex
+}
+
+func NewMockRMCallbackHandler() *MockRMCallback {
+	return &MockRMCallback{
+		acceptedApplications: make(map[string]bool),
+		rejectedApplications: make(map[string]bool),
+		acceptedNodes:        make(map[string]bool),
+		rejectedNodes:        make(map[string]bool),
+		rejectedAsks:         make(map[string]bool),
+		nodeAllocations:      make(map[string][]*si.Allocation),
+		allocations:          make(map[string]*si.Allocation),
+	}
+}
+
+// Delay all callbacks by the duration to simulate a slow RM, 0 removes the delay.
+// The core is blocked while the callback is delayed.
+func (m *MockRMCallback) SetDelay(delay time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.delay = delay
+}
+
+// Fail the next count callbacks with the error, a negative count fails all callbacks until reset.
+// A count of 0 resets the failure injection.
+func (m *MockRMCallback) FailNext(count int, err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.failCount = count
+	m.failErr = err
+	if m.failErr == nil {
+		m.failErr = fmt.Errorf("injected callback failure")
+	}
+}
+
+// Check the injected faults for the callback: sleeps for the delay and returns the error if the callback must fail.
+// Must be called without holding the lock.
+func (m *MockRMCallback) injectFault() error {
+	m.RLock()
+	delay := m.delay
+	m.RUnlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.failCount == 0 {
+		return nil
+	}
+	if m.failCount > 0 {
+		m.failCount--
+	}
+	m.failed++
+	return m.failErr
+}
+
+func (m *MockRMCallback) RecvUpdateResponse(response *si.UpdateResponse) error {
+	if err := m.injectFault(); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.responses = append(m.responses, response)
+
+	for _, app := range response.AcceptedApplications {
+		m.acceptedApplications[app.ApplicationID] = true
+		delete(m.rejectedApplications, app.ApplicationID)
+	}
+
+	for _, app := range response.RejectedApplications {
+		m.rejectedApplications[app.ApplicationID] = true
+		delete(m.acceptedApplications, app.ApplicationID)
+	}
+
+	for _, node := range response.AcceptedNodes {
+		m.acceptedNodes[node.NodeID] = true
+		delete(m.rejectedNodes, node.NodeID)
+	}
+
+	for _, node := range response.RejectedNodes {
+		m.rejectedNodes[node.NodeID] = true
+		delete(m.acceptedNodes, node.NodeID)
+	}
+
+	for _, ask := range response.RejectedAllocations {
+		m.rejectedAsks[ask.AllocationKey] = true
+	}
+
+	for _, alloc := range response.NewAllocations {
+		m.allocations[alloc.UUID] = alloc
+		m.nodeAllocations[alloc.NodeID] = append(m.nodeAllocations[alloc.NodeID], alloc)
+	}
+
+	for _, alloc := range response.ReleasedAllocations {
+		delete(m.allocations, alloc.UUID)
+	}
+
+	return nil
+}
+
+func (m *MockRMCallback) RecvPreemptionNotification(notifications []*api.PreemptionNotification) error {
+	if err := m.injectFault(); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.notifications = append(m.notifications, notifications...)
+	return nil
+}
+
+func (m *MockRMCallback) RecvQueueUpdate(updates []*api.QueueUpdate) error {
+	if err := m.injectFault(); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.queueUpdates = append(m.queueUpdates, updates...)
+	return nil
+}
+
+func (m *MockRMCallback) RecvAutoscaleEvents(events []*api.AutoscaleEvent) error {
+	if err := m.injectFault(); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.autoscaleEvents = append(m.autoscaleEvents, events...)
+	return nil
+}
+
+// Get a copy of the current allocations keyed by UUID.
+func (m *MockRMCallback) GetAllocations() map[string]*si.Allocation {
+	m.RLock()
+	defer m.RUnlock()
+
+	allocations := make(map[string]*si.Allocation)
+	for key, value := range m.allocations {
+		allocations[key] = value
+	}
+	return allocations
+}
+
+// Get a copy of all allocations ever made on the node in the order they were received, released allocations included.
+func (m *MockRMCallback) GetNodeAllocations(nodeID string) []*si.Allocation {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]*si.Allocation{}, m.nodeAllocations[nodeID]...)
+}
+
+// Get the IDs of all nodes that received allocations.
+func (m *MockRMCallback) GetAllocatedNodes() []string {
+	m.RLock()
+	defer m.RUnlock()
+	nodes := make([]string, 0, len(m.nodeAllocations))
+	for nodeID := range m.nodeAllocations {
+		nodes = append(nodes, nodeID)
+	}
+	return nodes
+}
+
+// Get all responses processed in the order they were received.
+func (m *MockRMCallback) GetResponses() []*si.UpdateResponse {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]*si.UpdateResponse{}, m.responses...)
+}
+
+// Get all preemption notifications processed in the order they were received.
+func (m *MockRMCallback) GetPreemptionNotifications() []*api.PreemptionNotification {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]*api.PreemptionNotification{}, m.notifications...)
+}
+
+// Get all queue updates processed in the order they were received.
+func (m *MockRMCallback) GetQueueUpdates() []*api.QueueUpdate {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]*api.QueueUpdate{}, m.queueUpdates...)
+}
+
+// Get all autoscale events processed in the order they were received.
+func (m *MockRMCallback) GetAutoscaleEvents() []*api.AutoscaleEvent {
+	m.RLock()
+	defer m.RUnlock()
+	return append([]*api.AutoscaleEvent{}, m.autoscaleEvents...)
+}
+
+// Get the number of callbacks that failed due to the injected failure.
+func (m *MockRMCallback) GetFailedCount() int {
+	m.RLock()
+	defer m.RUnlock()
+	return m.failed
+}
+
+// Wait until the condition is true, fails the test on timeout with the description.
+// The condition is called while holding the read lock on the mock.
+func (m *MockRMCallback) WaitFor(tb testing.TB, description string, timeoutMs int, condition func() bool) {
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		return condition()
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for %s, called from: %s", description, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForAcceptedApplication(tb testing.TB, appID string, timeoutMs int) {
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		return m.acceptedApplications[appID]
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for accepted application: %s, called from: %s", appID, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForRejectedApplication(tb testing.TB, appID string, timeoutMs int) {
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		return m.rejectedApplications[appID]
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for rejected application: %s, called from: %s", appID, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForAcceptedNode(tb testing.TB, nodeID string, timeoutMs int) {
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		return m.acceptedNodes[nodeID]
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for node state to become accepted: %s, called from: %s", nodeID, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForMinAcceptedNodes(tb testing.TB, minNumNode int, timeoutMs int) {
+	var numNodes int
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		numNodes = len(m.acceptedNodes)
+		return numNodes >= minNumNode
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for min accepted nodes, expected %d, actual %d, called from: %s", minNumNode, numNodes, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForRejectedNode(tb testing.TB, nodeID string, timeoutMs int) {
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		return m.rejectedNodes[nodeID]
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for node state to become rejected: %s, called from: %s", nodeID, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForRejectedAsk(tb testing.TB, allocKey string, timeoutMs int) {
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		return m.rejectedAsks[allocKey]
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for rejected ask: %s, called from: %s", allocKey, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForAllocations(tb testing.TB, nAlloc int, timeoutMs int) {
+	var allocLen int
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		allocLen = len(m.allocations)
+		return allocLen == nAlloc
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for allocations, expected %d, actual %d, called from: %s", nAlloc, allocLen, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForMinAllocations(tb testing.TB, nAlloc int, timeoutMs int) {
+	var allocLen int
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		allocLen = len(m.allocations)
+		return allocLen >= nAlloc
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for min allocations expected %d, actual %d, called from: %s", nAlloc, allocLen, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForPreemptionNotifications(tb testing.TB, minNotifications int, timeoutMs int) {
+	var numNotifications int
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		numNotifications = len(m.notifications)
+		return numNotifications >= minNotifications
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for preemption notifications, expected %d, actual %d, called from: %s", minNotifications, numNotifications, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForQueueUpdates(tb testing.TB, minUpdates int, timeoutMs int) {
+	var numUpdates int
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		numUpdates = len(m.queueUpdates)
+		return numUpdates >= minUpdates
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for queue updates, expected %d, actual %d, called from: %s", minUpdates, numUpdates, caller())
+	}
+}
+
+func (m *MockRMCallback) WaitForAutoscaleEvents(tb testing.TB, minEvents int, timeoutMs int) {
+	var numEvents int
+	err := common.WaitFor(waitInterval, time.Duration(timeoutMs)*time.Millisecond, func() bool {
+		m.RLock()
+		defer m.RUnlock()
+		numEvents = len(m.autoscaleEvents)
+		return numEvents >= minEvents
+	})
+	if err != nil {
+		tb.Fatalf("Failed to wait for autoscale events, expected %d, actual %d, called from: %s", minEvents, numEvents, caller())
+	}
+}
+
+// Get the function and location of the test that called the wait helper, for the failure messages.
+func caller() string {
+	pc, file, line, ok := runtime.Caller(2)
+	funcName := "unknown"
+	if ok {
+		name := runtime.FuncForPC(pc).Name()
+		name = name[strings.LastIndex(name, ".")+1:]
+		file = file[strings.LastIndex(file, string(os.PathSeparator))+1:]
+		funcName = fmt.Sprintf("%s in %s:%d", name, file, line)
+	}
+	return funcName
+}
@@ -0,0 +1,110 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package testutils
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
+	"github.com/apache/incubator-yunikorn-scheduler-interface/lib/go/si"
+)
+
+func TestMockRMCallbackCapture(t *testing.T) {
+	mockRM := NewMockRMCallbackHandler()
+	responses := []*si.UpdateResponse{
+		{
+			AcceptedApplications: []*si.AcceptedApplication{{ApplicationID: "app-1"}},
+			RejectedApplications: []*si.RejectedApplication{{ApplicationID: "app-2"}},
+			AcceptedNodes:        []*si.AcceptedNode{{NodeID: "node-1"}},
+			RejectedNodes:        []*si.RejectedNode{{NodeID: "node-2"}},
+		},
+		{
+			NewAllocations: []*si.Allocation{{UUID: "uuid-1", NodeID: "node-1"}, {UUID: "uuid-2", NodeID: "node-1"}},
+		},
+		{
+			ReleasedAllocations: []*si.AllocationReleaseResponse{{UUID: "uuid-1"}},
+		},
+	}
+	for _, response := range responses {
+		assert.NilError(t, mockRM.RecvUpdateResponse(response), "callback should not fail")
+	}
+	mockRM.WaitForAcceptedApplication(t, "app-1", 100)
+	mockRM.WaitForRejectedApplication(t, "app-2", 100)
+	mockRM.WaitForAcceptedNode(t, "node-1", 100)
+	mockRM.WaitForRejectedNode(t, "node-2", 100)
+	mockRM.WaitForMinAcceptedNodes(t, 1, 100)
+	mockRM.WaitForAllocations(t, 1, 100)
+	mockRM.WaitFor(t, "three responses", 100, func() bool {
+		return len(mockRM.responses) == 3
+	})
+	assert.DeepEqual(t, mockRM.GetResponses(), responses)
+	assert.Equal(t, len(mockRM.GetAllocations()), 1, "released allocation should have been removed")
+	// node allocations keep the history
+	assert.Equal(t, len(mockRM.GetNodeAllocations("node-1")), 2, "node allocations should include released allocations")
+	assert.DeepEqual(t, mockRM.GetAllocatedNodes(), []string{"node-1"})
+
+	notifications := []*api.PreemptionNotification{{UUID: "uuid-2"}}
+	assert.NilError(t, mockRM.RecvPreemptionNotification(notifications), "notification should not fail")
+	mockRM.WaitForPreemptionNotifications(t, 1, 100)
+	assert.DeepEqual(t, mockRM.GetPreemptionNotifications(), notifications)
+
+	updates := []*api.QueueUpdate{{PartitionName: "default", QueueName: "root.a"}}
+	assert.NilError(t, mockRM.RecvQueueUpdate(updates), "queue update should not fail")
+	mockRM.WaitForQueueUpdates(t, 1, 100)
+	assert.DeepEqual(t, mockRM.GetQueueUpdates(), updates)
+
+	events := []*api.AutoscaleEvent{{PartitionName: "default", QueueName: "root.a"}}
+	assert.NilError(t, mockRM.RecvAutoscaleEvents(events), "autoscale event should not fail")
+	mockRM.WaitForAutoscaleEvents(t, 1, 100)
+	assert.DeepEqual(t, mockRM.GetAutoscaleEvents(), events)
+}
+
+func TestMockRMCallbackFaults(t *testing.T) {
+	mockRM := NewMockRMCallbackHandler()
+	response := &si.UpdateResponse{
+		AcceptedApplications: []*si.AcceptedApplication{{ApplicationID: "app-1"}},
+	}
+	// fail the next two callbacks: nothing is processed
+	injected := fmt.Errorf("test failure")
+	mockRM.FailNext(2, injected)
+	assert.Equal(t, mockRM.RecvUpdateResponse(response), injected)
+	assert.Equal(t, mockRM.RecvPreemptionNotification(nil), injected)
+	assert.Equal(t, mockRM.GetFailedCount(), 2, "unexpected failed count")
+	assert.Equal(t, len(mockRM.GetResponses()), 0, "failed callback should not be captured")
+	assert.NilError(t, mockRM.RecvUpdateResponse(response), "only two callbacks should fail")
+	assert.Equal(t, len(mockRM.GetResponses()), 1, "response should have been captured")
+
+	// fail all until reset, default error
+	mockRM.FailNext(-1, nil)
+	for i := 0; i < 3; i++ {
+		assert.ErrorContains(t, mockRM.RecvUpdateResponse(response), "injected")
+	}
+	mockRM.FailNext(0, nil)
+	assert.NilError(t, mockRM.RecvUpdateResponse(response), "failures should have been reset")
+
+	// delayed callback
+	mockRM.SetDelay(50 * time.Millisecond)
+	start := time.Now()
+	assert.NilError(t, mockRM.RecvUpdateResponse(response), "delayed callback should not fail")
+	assert.Assert(t, time.Since(start) >= 50*time.Millisecond, "callback was not delayed")
+}
@@ -23,13 +23,31 @@ type ApplicationsDAOInfo struct {
 }
 
 type ApplicationDAOInfo struct {
-	ApplicationID  string              `json:"applicationID"`
-	UsedResource   string              `json:"usedResource"`
-	Partition      string              `json:"partition"`
-	QueueName      string              `json:"queueName"`
-	SubmissionTime int64               `json:"submissionTime"`
-	Allocations    []AllocationDAOInfo `json:"allocations"`
-	State          string              `json:"applicationState"`
+	ApplicationID  string                 `json:"applicationID"`
+	UsedResource   string                 `json:"usedResource"`
+	Partition      string                 `json:"partition"`
+	QueueName      string                 `json:"queueName"`
+	SubmissionTime int64                  `json:"submissionTime"`
+	Allocations    []AllocationDAOInfo    `json:"allocations"`
+	State          string                 `json:"applicationState"`
+	Requests       []AllocationAskDAOInfo `json:"requests"`
+	Held           bool                   `json:"held"`
+	StateHistory   []StateChangeDAOInfo   `json:"stateHistory,omitempty"`
+}
+
+type StateChangeDAOInfo struct {
+	Time  int64  `json:"time"`
+	Event string `json:"event"`
+	State string `json:"applicationState"`
+}
+
+type AllocationAskDAOInfo struct {
+	AllocationKey   string `json:"allocationKey"`
+	ResourcePerAsk  string `json:"resource"`
+	Priority        string `json:"priority"`
+	RemainingRepeat int32  `json:"remainingRepeat"`
+	CreateTime      int64  `json:"createTime"`
+	PlacementOnly   bool   `json:"placementOnly,omitempty"`
 }
 
 type AllocationDAOInfo struct {
@@ -42,4 +60,5 @@ type AllocationDAOInfo struct {
 	NodeID           string            `json:"nodeId"`
 	ApplicationID    string            `json:"applicationId"`
 	Partition        string            `json:"partition"`
+	PlacementOnly    bool              `json:"placementOnly,omitempty"`
 }
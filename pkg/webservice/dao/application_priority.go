@@ -0,0 +1,39 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+type ApplicationPriorityDAOInfo struct {
+	ApplicationID  string               `json:"applicationID"`
+	Partition      string               `json:"partition"`
+	QueueName      string               `json:"queueName"`
+	Asks           []AskPriorityDAOInfo `json:"asks"`
+	SelectionOrder []string             `json:"selectionOrder"`
+	Diagnostics    []DiagnosticDAOInfo  `json:"diagnostics"`
+}
+
+type AskPriorityDAOInfo struct {
+	AllocationKey     string `json:"allocationKey"`
+	Priority          int32  `json:"priority"`
+	EffectivePriority int32  `json:"effectivePriority"`
+}
+
+type DiagnosticDAOInfo struct {
+	Time    int64  `json:"time"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,35 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package dao
+
+type ExplainDAOInfo struct {
+	Partition        string                 `json:"partition"`
+	QueueName        string                 `json:"queueName"`
+	User             string                 `json:"user"`
+	Resource         string                 `json:"resource"`
+	Schedulable      bool                   `json:"schedulable"`
+	BlockedBy        string                 `json:"blockedBy,omitempty"`
+	Reason           string                 `json:"reason,omitempty"`
+	LimitingQueue    string                 `json:"limitingQueue,omitempty"`
+	LimitingResource string                 `json:"limitingResource,omitempty"`
+	Queues           []QueueHeadRoomDAOInfo `json:"queues,omitempty"`
+	NodesEvaluated   int                    `json:"nodesEvaluated"`
+	NodesFiltered    map[string]int         `json:"nodesFiltered"`
+	Nodes            []string               `json:"nodes"`
+}
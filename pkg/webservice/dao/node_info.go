@@ -27,9 +27,33 @@ type NodeDAOInfo struct {
 	NodeID      string               `json:"nodeID"`
 	HostName    string               `json:"hostName"`
 	RackName    string               `json:"RackName"`
+	Pool        string               `json:"pool"`
 	Capacity    string               `json:"capacity"`
 	Allocated   string               `json:"allocated"`
 	Available   string               `json:"available"`
 	Allocations []*AllocationDAOInfo `json:"allocations"`
 	Schedulable bool                 `json:"schedulable"`
+	Quarantined int64                `json:"quarantinedUntil,omitempty"`
+}
+
+// The node an allocation is placed on, the reverse of the allocations listed for a node.
+type AllocationNodeDAOInfo struct {
+	UUID          string `json:"uuid"`
+	Partition     string `json:"partition"`
+	ApplicationID string `json:"applicationId"`
+	QueueName     string `json:"queueName"`
+	Resource      string `json:"resource"`
+	NodeID        string `json:"nodeID"`
+	HostName      string `json:"hostName"`
+	RackName      string `json:"rackName"`
+}
+
+// A node with allocations that drifted from the allocations confirmed by the RM.
+type NodeDriftDAOInfo struct {
+	NodeID          string `json:"nodeID"`
+	Partition       string `json:"partition"`
+	DriftType       string `json:"driftType"`
+	CoreAllocations int    `json:"coreAllocations"`
+	RMAllocations   int    `json:"rmAllocations"`
+	Since           int64  `json:"since"`
 }
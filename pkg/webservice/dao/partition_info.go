@@ -18,10 +18,17 @@
 package dao
 
 type PartitionDAOInfo struct {
-	PartitionName string            `json:"partitionName"`
-	Capacity      PartitionCapacity `json:"capacity"`
-	Nodes         []NodeInfo        `json:"nodes"`
-	Queues        []QueueDAOInfo    `json:"queues"`
+	PartitionName    string                   `json:"partitionName"`
+	Capacity         PartitionCapacity        `json:"capacity"`
+	Nodes            []NodeInfo               `json:"nodes"`
+	Queues           []QueueDAOInfo           `json:"queues"`
+	IgnoredResources []IgnoredResourceDAOInfo `json:"ignoredResources,omitempty"`
+}
+
+// A resource type that is not enforced in the node fit checks, with the number of allocations it would have blocked.
+type IgnoredResourceDAOInfo struct {
+	ResourceType     string `json:"resourceType"`
+	WouldHaveBlocked int64  `json:"wouldHaveBlocked"`
 }
 
 type PartitionCapacity struct {
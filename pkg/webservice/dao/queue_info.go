@@ -18,15 +18,23 @@
 package dao
 
 type QueueDAOInfo struct {
-	QueueName   string         `json:"queuename"`
-	Status      string         `json:"status"`
-	Capacities  QueueCapacity  `json:"capacities"`
-	ChildQueues []QueueDAOInfo `json:"queues"`
+	QueueName    string         `json:"queuename"`
+	Status       string         `json:"status"`
+	StartDelayed bool           `json:"startdelayed,omitempty"`
+	Capacities   QueueCapacity  `json:"capacities"`
+	ChildQueues  []QueueDAOInfo `json:"queues"`
 }
 
 type QueueCapacity struct {
 	Capacity        string `json:"capacity"`
 	MaxCapacity     string `json:"maxcapacity"`
+	SoftMaxCapacity string `json:"softmaxcapacity,omitempty"`
+	OverSoftMax     bool   `json:"oversoftmax,omitempty"`
+	OverMax         bool   `json:"overmax,omitempty"`
 	UsedCapacity    string `json:"usedcapacity"`
 	AbsUsedCapacity string `json:"absusedcapacity"`
+	// used as a ratio of the max and the guaranteed resource per resource type
+	MaxUtilization        map[string]float64 `json:"maxutilization,omitempty"`
+	GuaranteedUtilization map[string]float64 `json:"guaranteedutilization,omitempty"`
+	OverGuaranteed        bool               `json:"overguaranteed,omitempty"`
 }
@@ -18,16 +18,25 @@
 package webservice
 
 import (
+	"fmt"
 	"encoding/json"
 	"net/http"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/apache/incubator-yunikorn-core/pkg/api"
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
+	"github.com/apache/incubator-yunikorn-core/pkg/common"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/configs"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/resources"
+	"github.com/apache/incubator-yunikorn-core/pkg/common/security"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/scheduler"
 	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
 )
 
@@ -51,10 +60,7 @@ func GetStackInfo(w http.ResponseWriter, r *http.Request) {
 func GetQueueInfo(w http.ResponseWriter, r *http.Request) {
 	writeHeaders(w)
 
-	lists := gClusterInfo.ListPartitions()
-	for _, k := range lists {
-		partitionInfo := getPartitionJSON(k)
-
+	for _, partitionInfo := range getPartitionsJSON() {
 		if err := json.NewEncoder(w).Encode(partitionInfo); err != nil {
 			panic(err)
 		}
@@ -64,9 +70,7 @@ func GetQueueInfo(w http.ResponseWriter, r *http.Request) {
 func GetClusterInfo(w http.ResponseWriter, r *http.Request) {
 	writeHeaders(w)
 
-	lists := gClusterInfo.ListPartitions()
-	for _, k := range lists {
-		clusterInfo := getClusterJSON(k)
+	for _, clusterInfo := range getClustersJSON() {
 		var clustersInfo []dao.ClusterDAOInfo
 		clustersInfo = append(clustersInfo, *clusterInfo)
 
@@ -79,6 +83,665 @@ func GetClusterInfo(w http.ResponseWriter, r *http.Request) {
 func GetApplicationsInfo(w http.ResponseWriter, r *http.Request) {
 	writeHeaders(w)
 
+	if err := json.NewEncoder(w).Encode(getApplicationsJSON()); err != nil {
+		panic(err)
+	}
+}
+
+func GetNodesInfo(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+
+	if err := json.NewEncoder(w).Encode(getNodesJSON()); err != nil {
+		panic(err)
+	}
+}
+
+// Get a snapshot of the queue, cluster, application and node information.
+// The snapshot is stored outside the scheduler and served by a read only mirror instance, see NewMirrorWebApp.
+func GetStateSnapshot(w http.ResponseWriter, r *http.Request) {
+	writeHeaders(w)
+
+	snapshot := &dao.StateSnapshotDAOInfo{
+		Time:         time.Now().UnixNano(),
+		Clusters:     getClustersJSON(),
+		Partitions:   getPartitionsJSON(),
+		Applications: getApplicationsJSON(),
+		Nodes:        getNodesJSON(),
+	}
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		panic(err)
+	}
+}
+
+// Find the node an allocation is placed on.
+// The uuid query parameter is required, the optional partition query parameter limits the search to one partition.
+func GetAllocationNodeInfo(w http.ResponseWriter, r *http.Request) {
+	uuid := r.URL.Query().Get("uuid")
+	if uuid == "" {
+		buildJSONErrorResponse(w, "uuid must be specified", http.StatusBadRequest)
+		return
+	}
+	partitionName := r.URL.Query().Get("partition")
+	names := gClusterInfo.ListPartitions()
+	sort.Strings(names)
+	found := false
+	for _, name := range names {
+		if partitionName != "" && partitionName != name {
+			continue
+		}
+		found = true
+		partition := gClusterInfo.GetPartition(name)
+		if partition == nil {
+			continue
+		}
+		alloc := partition.GetAllocation(uuid)
+		if alloc == nil {
+			continue
+		}
+		info := &dao.AllocationNodeDAOInfo{
+			UUID:          uuid,
+			Partition:     alloc.AllocationProto.PartitionName,
+			ApplicationID: alloc.ApplicationID,
+			QueueName:     alloc.AllocationProto.QueueName,
+			Resource:      alloc.AllocatedResource.DAOString(),
+			NodeID:        alloc.AllocationProto.NodeID,
+		}
+		if node := partition.GetNode(info.NodeID); node != nil {
+			info.HostName = node.Hostname
+			info.RackName = node.Rackname
+		}
+		writeHeaders(w)
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if partitionName != "" && !found {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	buildJSONErrorResponse(w, "allocation not found", http.StatusNotFound)
+}
+
+// List the nodes with allocations that drifted from the allocations confirmed by the RM, sorted by node.
+// The optional partition query parameter limits the output to one partition.
+func GetAllocationDriftInfo(w http.ResponseWriter, r *http.Request) {
+	partitionName := r.URL.Query().Get("partition")
+	names := gClusterInfo.ListPartitions()
+	sort.Strings(names)
+	found := false
+	drifts := make([]*dao.NodeDriftDAOInfo, 0)
+	for _, name := range names {
+		if partitionName != "" && partitionName != name {
+			continue
+		}
+		found = true
+		partition := gClusterInfo.GetPartition(name)
+		if partition == nil {
+			continue
+		}
+		var nodeDrifts []*dao.NodeDriftDAOInfo
+		for _, node := range partition.GetNodes() {
+			drift := node.GetAllocationDrift()
+			if drift == nil {
+				continue
+			}
+			nodeDrifts = append(nodeDrifts, &dao.NodeDriftDAOInfo{
+				NodeID:          node.NodeID,
+				Partition:       name,
+				DriftType:       drift.GetType(),
+				CoreAllocations: drift.CoreAllocations,
+				RMAllocations:   drift.RMAllocations,
+				Since:           drift.Since.UnixNano(),
+			})
+		}
+		sort.Slice(nodeDrifts, func(i, j int) bool {
+			return nodeDrifts[i].NodeID < nodeDrifts[j].NodeID
+		})
+		drifts = append(drifts, nodeDrifts...)
+	}
+	if partitionName != "" && !found {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(drifts); err != nil {
+		panic(err)
+	}
+}
+
+// Get the last scheduling attempt trace for the asks of an application.
+// The application must have tracing enabled, see scheduler.TraceApplicationTag.
+// Both the partition and application query parameters are required.
+func GetApplicationTraceInfo(w http.ResponseWriter, r *http.Request) {
+	partition := r.URL.Query().Get("partition")
+	appID := r.URL.Query().Get("application")
+	if partition == "" || appID == "" {
+		buildJSONErrorResponse(w, "partition and application must be specified", http.StatusBadRequest)
+		return
+	}
+	app := gSchedulingContext.GetSchedulingApplication(appID, partition)
+	if app == nil {
+		buildJSONErrorResponse(w, "application not found", http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	traceInfo := &dao.ApplicationTraceDAOInfo{
+		ApplicationID: appID,
+		Partition:     partition,
+		Traces:        app.GetAskTraces(),
+	}
+	if err := json.NewEncoder(w).Encode(traceInfo); err != nil {
+		panic(err)
+	}
+}
+
+// Get the scheduling statistics of an application.
+// Both the partition and application query parameters are required.
+func GetApplicationStatsInfo(w http.ResponseWriter, r *http.Request) {
+	partition := r.URL.Query().Get("partition")
+	appID := r.URL.Query().Get("application")
+	if partition == "" || appID == "" {
+		buildJSONErrorResponse(w, "partition and application must be specified", http.StatusBadRequest)
+		return
+	}
+	app := gSchedulingContext.GetSchedulingApplication(appID, partition)
+	if app == nil {
+		buildJSONErrorResponse(w, "application not found", http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(app.GetStatistics()); err != nil {
+		panic(err)
+	}
+}
+
+// Get the requested and effective priority of the asks of an application and the application diagnostics.
+// The effective priority differs from the requested priority if the queue clamped it to its priority range.
+// Both the partition and application query parameters are required.
+func GetApplicationPriorityInfo(w http.ResponseWriter, r *http.Request) {
+	partition := r.URL.Query().Get("partition")
+	appID := r.URL.Query().Get("application")
+	if partition == "" || appID == "" {
+		buildJSONErrorResponse(w, "partition and application must be specified", http.StatusBadRequest)
+		return
+	}
+	app := gSchedulingContext.GetSchedulingApplication(appID, partition)
+	if app == nil {
+		buildJSONErrorResponse(w, "application not found", http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(app.GetPriorityInfo()); err != nil {
+		panic(err)
+	}
+}
+
+// Get the hierarchical fair share of all queues as calculated in the last scheduling cycle.
+// The optional partition query parameter limits the output to one partition.
+func GetFairShareInfo(w http.ResponseWriter, r *http.Request) {
+	partitionName := r.URL.Query().Get("partition")
+	names := gClusterInfo.ListPartitions()
+	sort.Strings(names)
+	found := false
+	fairShares := make([]*dao.FairShareDAOInfo, 0)
+	for _, name := range names {
+		if partitionName != "" && partitionName != name {
+			continue
+		}
+		found = true
+		fairShares = append(fairShares, gSchedulingContext.GetFairShareInfos(name)...)
+	}
+	if partitionName != "" && !found {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(fairShares); err != nil {
+		panic(err)
+	}
+}
+
+// Get the combined pending and allocated resources of the application groups, applications that are accounted
+// together as one job. The optional partition query parameter limits the output to one partition.
+func GetApplicationGroupsInfo(w http.ResponseWriter, r *http.Request) {
+	partitionName := r.URL.Query().Get("partition")
+	names := gClusterInfo.ListPartitions()
+	sort.Strings(names)
+	found := false
+	groups := make([]*dao.ApplicationGroupDAOInfo, 0)
+	for _, name := range names {
+		if partitionName != "" && partitionName != name {
+			continue
+		}
+		found = true
+		groups = append(groups, gSchedulingContext.GetApplicationGroupInfos(name)...)
+	}
+	if partitionName != "" && !found {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		panic(err)
+	}
+}
+
+// List the smoothed load index of the partitions with the batch size and reservation delay used.
+// The optional partition query parameter limits the output to one partition.
+func GetPartitionLoadInfo(w http.ResponseWriter, r *http.Request) {
+	partitionName := r.URL.Query().Get("partition")
+	names := gClusterInfo.ListPartitions()
+	sort.Strings(names)
+	found := false
+	loads := make([]*dao.LoadIndexDAOInfo, 0)
+	for _, name := range names {
+		if partitionName != "" && partitionName != name {
+			continue
+		}
+		found = true
+		if info := gSchedulingContext.GetLoadIndexInfo(name); info != nil {
+			loads = append(loads, info)
+		}
+	}
+	if partitionName != "" && !found {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(loads); err != nil {
+		panic(err)
+	}
+}
+
+// List the reservations outstanding in the scheduler with the application, node, ask and age.
+// The optional partition query parameter limits the output to one partition.
+func GetReservationsInfo(w http.ResponseWriter, r *http.Request) {
+	partitionName := r.URL.Query().Get("partition")
+	names := gClusterInfo.ListPartitions()
+	sort.Strings(names)
+	found := false
+	reservations := make([]*dao.ReservationDAOInfo, 0)
+	for _, name := range names {
+		if partitionName != "" && partitionName != name {
+			continue
+		}
+		found = true
+		reservations = append(reservations, gSchedulingContext.GetReservationInfos(name)...)
+	}
+	if partitionName != "" && !found {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(reservations); err != nil {
+		panic(err)
+	}
+}
+
+// Stream the autoscale events as they are sent: one JSON object per event, the connection stays open until the client
+// closes it. The optional partition query parameter limits the events to one partition.
+func GetAutoscaleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		buildJSONErrorResponse(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	partitionName := r.URL.Query().Get("partition")
+	if partitionName != "" && gClusterInfo.GetPartition(partitionName) == nil {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	events, unsubscribe := gSchedulingContext.SubscribeAutoscaleEvents()
+	defer unsubscribe()
+	writeHeaders(w)
+	flusher.Flush()
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			if partitionName != "" && partitionName != event.PartitionName {
+				continue
+			}
+			if err := encoder.Encode(getAutoscaleEventJSON(event)); err != nil {
+				log.Logger().Info("autoscale event stream closed", zap.Error(err))
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Stream the events of a partition as server-sent events: allocations, releases, application state and node changes.
+// The partition query parameter is required.
+func GetPartitionEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		buildJSONErrorResponse(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	partitionName := r.URL.Query().Get("partition")
+	if partitionName == "" {
+		buildJSONErrorResponse(w, "partition is required", http.StatusBadRequest)
+		return
+	}
+	partition := gClusterInfo.GetPartition(partitionName)
+	if partition == nil {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	events, unsubscribe := partition.SubscribeEvents()
+	defer unsubscribe()
+	w.Header().Set("Cache-Control", "no-cache")
+	writeHeadersWithContentType(w, "text/event-stream")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			data, err := json.Marshal(getPartitionEventJSON(event))
+			if err != nil {
+				log.Logger().Warn("failed to marshal partition event", zap.Error(err))
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				log.Logger().Info("partition event stream closed", zap.Error(err))
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// Force the removal of one reservation, independent of its age.
+// The partition, application, node and allocationKey query parameters are required.
+func ExpireReservation(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	partition := query.Get("partition")
+	appID := query.Get("application")
+	nodeID := query.Get("node")
+	allocKey := query.Get("allocationKey")
+	if partition == "" || appID == "" || nodeID == "" || allocKey == "" {
+		buildJSONErrorResponse(w, "partition, application, node and allocationKey must be specified", http.StatusBadRequest)
+		return
+	}
+	if err := gSchedulingContext.ExpireReservation(partition, appID, nodeID, allocKey); err != nil {
+		buildJSONErrorResponse(w, err.Error(), getErrorStatus(err, http.StatusNotFound))
+		return
+	}
+	writeHeaders(w)
+}
+
+// Kill an application, releasing all its allocations.
+// Both the partition and application query parameters are required.
+func KillApplication(w http.ResponseWriter, r *http.Request) {
+	partition := r.URL.Query().Get("partition")
+	appID := r.URL.Query().Get("application")
+	if partition == "" || appID == "" {
+		buildJSONErrorResponse(w, "partition and application must be specified", http.StatusBadRequest)
+		return
+	}
+	if err := gClusterInfo.KillApplication(partition, appID); err != nil {
+		buildJSONErrorResponse(w, err.Error(), getErrorStatus(err, http.StatusNotFound))
+		return
+	}
+	writeHeaders(w)
+}
+
+// Move a running application to another leaf queue, the allocated and pending resources move with the application.
+// The partition, application and queue query parameters are required.
+func MoveApplication(w http.ResponseWriter, r *http.Request) {
+	partition := r.URL.Query().Get("partition")
+	appID := r.URL.Query().Get("application")
+	queueName := r.URL.Query().Get("queue")
+	if partition == "" || appID == "" || queueName == "" {
+		buildJSONErrorResponse(w, "partition, application and queue must be specified", http.StatusBadRequest)
+		return
+	}
+	if err := gSchedulingContext.MoveApplication(partition, appID, queueName); err != nil {
+		buildJSONErrorResponse(w, err.Error(), getErrorStatus(err, http.StatusNotFound))
+		return
+	}
+	writeHeaders(w)
+}
+
+// Put an application on hold, see cache.ApplicationInfo.SetHeld.
+// The partition and application query parameters are required.
+func HoldApplication(w http.ResponseWriter, r *http.Request) {
+	setApplicationHeld(w, r, true)
+}
+
+// Release an application that is on hold.
+// The partition and application query parameters are required.
+func ReleaseApplication(w http.ResponseWriter, r *http.Request) {
+	setApplicationHeld(w, r, false)
+}
+
+func setApplicationHeld(w http.ResponseWriter, r *http.Request, held bool) {
+	partition := r.URL.Query().Get("partition")
+	appID := r.URL.Query().Get("application")
+	if partition == "" || appID == "" {
+		buildJSONErrorResponse(w, "partition and application must be specified", http.StatusBadRequest)
+		return
+	}
+	if err := gClusterInfo.SetApplicationHeld(partition, appID, held); err != nil {
+		buildJSONErrorResponse(w, err.Error(), getErrorStatus(err, http.StatusNotFound))
+		return
+	}
+	writeHeaders(w)
+}
+
+// Pause scheduling in a partition, see cache.PartitionInfo.SetPaused.
+// The partition query parameter is required.
+func PausePartition(w http.ResponseWriter, r *http.Request) {
+	setPartitionPaused(w, r, true)
+}
+
+// Resume scheduling in a paused partition.
+// The partition query parameter is required.
+func ResumePartition(w http.ResponseWriter, r *http.Request) {
+	setPartitionPaused(w, r, false)
+}
+
+func setPartitionPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	partitionName := r.URL.Query().Get("partition")
+	if partitionName == "" {
+		buildJSONErrorResponse(w, "partition must be specified", http.StatusBadRequest)
+		return
+	}
+	partition := gClusterInfo.GetPartition(partitionName)
+	if partition == nil {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	if err := partition.SetPaused(paused); err != nil {
+		buildJSONErrorResponse(w, err.Error(), getErrorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	writeHeaders(w)
+}
+
+// Get the state of the active instance to replicate to a standby instance.
+// The process that embeds the standby instance stores the state with ClusterInfo.SetReplicatedState: the REST API
+// does not accept the state, it would let any caller replace the configuration the standby instance starts with.
+func GetReplicatedState(w http.ResponseWriter, r *http.Request) {
+	state, err := gClusterInfo.GetReplicatedState()
+	if err != nil {
+		buildJSONErrorResponse(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeHeaders(w)
+	if err = json.NewEncoder(w).Encode(state); err != nil {
+		panic(err)
+	}
+}
+
+// Explain if a hypothetical ask could be scheduled right now without changing the scheduler state.
+// The partition, queue, user and resource query parameters are required. The resource uses the canonical resource
+// string format, for example "[memory:1024 vcore:1]". The optional groups parameter is a comma separated list of
+// groups, the optional nodeSelector parameter is a comma separated list of key=value node attributes. The optional
+// tolerations parameter lists the tolerated node taints in the format of the ask tag.
+func GetExplainInfo(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	partition := query.Get("partition")
+	queueName := query.Get("queue")
+	user := query.Get("user")
+	if partition == "" || queueName == "" || user == "" || query.Get("resource") == "" {
+		buildJSONErrorResponse(w, "partition, queue, user and resource must be specified", http.StatusBadRequest)
+		return
+	}
+	res, err := resources.ParseResource(query.Get("resource"))
+	if err != nil {
+		buildJSONErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ask := &scheduler.ExplainAsk{
+		PartitionName: partition,
+		QueueName:     queueName,
+		User:          security.UserGroup{User: user},
+		Resource:      res,
+		NodeSelector:  make(map[string]string),
+		Tolerations:   query.Get("tolerations"),
+	}
+	if groups := query.Get("groups"); groups != "" {
+		ask.User.Groups = strings.Split(groups, ",")
+	}
+	if selector := query.Get("nodeSelector"); selector != "" {
+		for _, entry := range strings.Split(selector, ",") {
+			kv := strings.SplitN(entry, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				buildJSONErrorResponse(w, "invalid node selector entry: "+entry, http.StatusBadRequest)
+				return
+			}
+			ask.NodeSelector[kv[0]] = kv[1]
+		}
+	}
+	writeHeaders(w)
+	if err = json.NewEncoder(w).Encode(gSchedulingContext.ExplainAsk(ask)); err != nil {
+		panic(err)
+	}
+}
+
+// Export the effective configuration of the partitions as YAML.
+// The configuration is built from the running scheduler: defaults, merged properties and queues created by
+// placement rules are included. The optional partition query parameter limits the output to one partition.
+func GetConfigInfo(w http.ResponseWriter, r *http.Request) {
+	partitionName := r.URL.Query().Get("partition")
+	names := gClusterInfo.ListPartitions()
+	sort.Strings(names)
+	partitions := make([]configs.PartitionConfig, 0, len(names))
+	for _, name := range names {
+		if partitionName != "" && partitionName != name && partitionName != common.GetPartitionNameWithoutClusterID(name) {
+			continue
+		}
+		if partition := gClusterInfo.GetPartition(name); partition != nil {
+			partitions = append(partitions, partition.GetEffectiveConfig())
+		}
+	}
+	if partitionName != "" && len(partitions) == 0 {
+		buildJSONErrorResponse(w, "partition not found", http.StatusNotFound)
+		return
+	}
+	out, err := configs.ExportSchedulerConfig(partitions)
+	if err != nil {
+		buildJSONErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeHeadersWithContentType(w, "application/x-yaml; charset=UTF-8")
+	if _, err = w.Write(out); err != nil {
+		log.Logger().Error("GetConfigInfo error", zap.Error(err))
+	}
+}
+
+func writeHeaders(w http.ResponseWriter) {
+	writeHeadersWithContentType(w, "application/json; charset=UTF-8")
+}
+
+func writeHeadersWithContentType(w http.ResponseWriter, contentType string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	w.Header().Set("Access-Control-Allow-Methods", "GET,POST,HEAD,OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "X-Requested-With,Content-Type,Accept,Origin")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Get the HTTP status for an error returned by the cache or the scheduler.
+// Errors that are not of a known kind get the default status.
+func getErrorStatus(err error, defaultStatus int) int {
+	switch api.GetErrorKind(err) {
+	case api.ErrQueueNotFound:
+		return http.StatusNotFound
+	case api.ErrOverQueueMax:
+		return http.StatusUnprocessableEntity
+	case api.ErrInvalidState:
+		return http.StatusConflict
+	}
+	return defaultStatus
+}
+
+func buildJSONErrorResponse(w http.ResponseWriter, detail string, code int) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	errorInfo := dao.YAPIError{
+		StatusCode:  code,
+		Message:     http.StatusText(code),
+		Description: detail,
+	}
+	if err := json.NewEncoder(w).Encode(errorInfo); err != nil {
+		log.Logger().Error("failed to encode error response", zap.Error(err))
+	}
+}
+
+func getAutoscaleEventJSON(event *api.AutoscaleEvent) *dao.AutoscaleEventDAOInfo {
+	return &dao.AutoscaleEventDAOInfo{
+		Partition:     event.PartitionName,
+		QueueName:     event.QueueName,
+		Pending:       resources.NewResourceFromProto(event.Pending).DAOString(),
+		Unsatisfiable: resources.NewResourceFromProto(event.Unsatisfiable).DAOString(),
+		Watermark:     resources.NewResourceFromProto(event.Watermark).DAOString(),
+		AboveSince:    event.AboveSince.UnixNano(),
+	}
+}
+
+func getPartitionEventJSON(event *cache.PartitionEvent) *dao.PartitionEventDAOInfo {
+	info := &dao.PartitionEventDAOInfo{
+		Type:          event.Type,
+		Time:          event.Time.UnixNano(),
+		Partition:     event.Partition,
+		ApplicationID: event.ApplicationID,
+		QueueName:     event.QueueName,
+		NodeID:        event.NodeID,
+		UUID:          event.UUID,
+		Event:         event.Event,
+		State:         event.State,
+	}
+	if event.Resource != nil {
+		info.Resource = event.Resource.DAOString()
+	}
+	return info
+}
+
+func getClustersJSON() []*dao.ClusterDAOInfo {
+	var result []*dao.ClusterDAOInfo
+	for _, k := range gClusterInfo.ListPartitions() {
+		result = append(result, getClusterJSON(k))
+	}
+	return result
+}
+
+func getPartitionsJSON() []*dao.PartitionDAOInfo {
+	var result []*dao.PartitionDAOInfo
+	for _, k := range gClusterInfo.ListPartitions() {
+		result = append(result, getPartitionJSON(k))
+	}
+	return result
+}
+
+func getApplicationsJSON() []*dao.ApplicationDAOInfo {
 	var appsDao []*dao.ApplicationDAOInfo
 	lists := gClusterInfo.ListPartitions()
 	for _, k := range lists {
@@ -86,18 +749,16 @@ func GetApplicationsInfo(w http.ResponseWriter, r *http.Request) {
 		appList := partition.GetApplications()
 		for _, app := range appList {
 			appDao := getApplicationJSON(app)
+			if schedulingApp := gSchedulingContext.GetSchedulingApplication(app.ApplicationID, k); schedulingApp != nil {
+				appDao.Requests = schedulingApp.GetRequestInfos()
+			}
 			appsDao = append(appsDao, appDao)
 		}
 	}
-
-	if err := json.NewEncoder(w).Encode(appsDao); err != nil {
-		panic(err)
-	}
+	return appsDao
 }
 
-func GetNodesInfo(w http.ResponseWriter, r *http.Request) {
-	writeHeaders(w)
-
+func getNodesJSON() []*dao.NodesDAOInfo {
 	var result []*dao.NodesDAOInfo
 	lists := gClusterInfo.ListPartitions()
 	for _, k := range lists {
@@ -112,19 +773,7 @@ func GetNodesInfo(w http.ResponseWriter, r *http.Request) {
 			Nodes:         nodesDao,
 		})
 	}
-
-	if err := json.NewEncoder(w).Encode(result); err != nil {
-		panic(err)
-	}
-}
-
-func writeHeaders(w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Credentials", "true")
-	w.Header().Set("Access-Control-Allow-Methods", "GET,POST,HEAD,OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "X-Requested-With,Content-Type,Accept,Origin")
-	w.WriteHeader(http.StatusOK)
+	return result
 }
 
 func getClusterJSON(name string) *dao.ClusterDAOInfo {
@@ -147,6 +796,7 @@ func getPartitionJSON(name string) *dao.PartitionDAOInfo {
 
 	partitionContext := gClusterInfo.GetPartition(name)
 	queueDAOInfo := partitionContext.GetQueueInfos()
+	gSchedulingContext.AddQueueUtilization(name, queueDAOInfo)
 
 	partitionInfo.PartitionName = partitionContext.Name
 	partitionInfo.Capacity = dao.PartitionCapacity{
@@ -154,6 +804,13 @@ func getPartitionJSON(name string) *dao.PartitionDAOInfo {
 		UsedCapacity: "0",
 	}
 	partitionInfo.Queues = queueDAOInfo
+	blocked := partitionContext.GetIgnoredResourceBlocks()
+	for _, resType := range partitionContext.GetIgnoredResourceTypes() {
+		partitionInfo.IgnoredResources = append(partitionInfo.IgnoredResources, dao.IgnoredResourceDAOInfo{
+			ResourceType:     resType,
+			WouldHaveBlocked: blocked[resType],
+		})
+	}
 
 	return partitionInfo
 }
@@ -166,24 +823,35 @@ func getApplicationJSON(app *cache.ApplicationInfo) *dao.ApplicationDAOInfo {
 			AllocationKey:    alloc.AllocationProto.AllocationKey,
 			AllocationTags:   alloc.AllocationProto.AllocationTags,
 			UUID:             alloc.AllocationProto.UUID,
-			ResourcePerAlloc: strings.Trim(alloc.AllocatedResource.String(), "map"),
+			ResourcePerAlloc: alloc.AllocatedResource.DAOString(),
 			Priority:         alloc.AllocationProto.Priority.String(),
 			QueueName:        alloc.AllocationProto.QueueName,
 			NodeID:           alloc.AllocationProto.NodeID,
 			ApplicationID:    alloc.AllocationProto.ApplicationID,
 			Partition:        alloc.AllocationProto.PartitionName,
+			PlacementOnly:    alloc.IsPlacementOnly(),
 		}
 		allocationInfos = append(allocationInfos, allocInfo)
 	}
+	var stateHistory []dao.StateChangeDAOInfo
+	for _, change := range app.GetStateHistory() {
+		stateHistory = append(stateHistory, dao.StateChangeDAOInfo{
+			Time:  change.Time.UnixNano(),
+			Event: change.Event,
+			State: change.State,
+		})
+	}
 
 	return &dao.ApplicationDAOInfo{
 		ApplicationID:  app.ApplicationID,
-		UsedResource:   strings.Trim(app.GetAllocatedResource().String(), "map"),
+		UsedResource:   app.GetAllocatedResource().DAOString(),
 		Partition:      app.Partition,
 		QueueName:      app.QueueName,
 		SubmissionTime: app.SubmissionTime,
 		Allocations:    allocationInfos,
 		State:          app.GetApplicationState(),
+		Held:           app.IsHeld(),
+		StateHistory:   stateHistory,
 	}
 }
 
@@ -194,24 +862,35 @@ func getNodeJSON(nodeInfo *cache.NodeInfo) *dao.NodeDAOInfo {
 			AllocationKey:    alloc.AllocationProto.AllocationKey,
 			AllocationTags:   alloc.AllocationProto.AllocationTags,
 			UUID:             alloc.AllocationProto.UUID,
-			ResourcePerAlloc: strings.Trim(alloc.AllocatedResource.String(), "map"),
+			ResourcePerAlloc: alloc.AllocatedResource.DAOString(),
 			Priority:         alloc.AllocationProto.Priority.String(),
 			QueueName:        alloc.AllocationProto.QueueName,
 			NodeID:           alloc.AllocationProto.NodeID,
 			ApplicationID:    alloc.AllocationProto.ApplicationID,
 			Partition:        alloc.AllocationProto.PartitionName,
+			PlacementOnly:    alloc.IsPlacementOnly(),
 		}
 		allocations = append(allocations, allocInfo)
 	}
+	// stable output: the allocations are stored in a map on the node
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].UUID < allocations[j].UUID
+	})
+	var quarantined int64
+	if end := nodeInfo.GetQuarantineEnd(); !end.IsZero() {
+		quarantined = end.UnixNano()
+	}
 
 	return &dao.NodeDAOInfo{
 		NodeID:      nodeInfo.NodeID,
 		HostName:    nodeInfo.Hostname,
 		RackName:    nodeInfo.Rackname,
-		Capacity:    strings.Trim(nodeInfo.GetCapacity().String(), "map"),
-		Allocated:   strings.Trim(nodeInfo.GetAllocatedResource().String(), "map"),
-		Available:   strings.Trim(nodeInfo.GetAvailableResource().String(), "map"),
+		Pool:        nodeInfo.Pool,
+		Capacity:    nodeInfo.GetCapacity().DAOString(),
+		Allocated:   nodeInfo.GetAllocatedResource().DAOString(),
+		Available:   nodeInfo.GetAvailableResource().DAOString(),
 		Allocations: allocations,
 		Schedulable: nodeInfo.IsSchedulable(),
+		Quarantined: quarantined,
 	}
 }
@@ -0,0 +1,214 @@
+/*
+ Licensed to the Apache Software Foundation (ASF) under one
+ or more contributor license agreements.  See the NOTICE file
+ distributed with this work for additional information
+ regarding copyright ownership.  The ASF licenses this file
+ to you under the Apache License, Version 2.0 (the
+ "License"); you may not use this file except in compliance
+ with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package webservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/webservice/dao"
+)
+
+// Load the latest state snapshot, as returned by GetStateSnapshot of the live scheduler, from where it is stored.
+type SnapshotLoader func() (*dao.StateSnapshotDAOInfo, error)
+
+// Return a loader that reads the snapshot from a JSON file.
+func FileSnapshotLoader(path string) SnapshotLoader {
+	return func() (*dao.StateSnapshotDAOInfo, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshot := &dao.StateSnapshotDAOInfo{}
+		if err = json.Unmarshal(data, snapshot); err != nil {
+			return nil, fmt.Errorf("invalid snapshot in %s: %v", path, err)
+		}
+		return snapshot, nil
+	}
+}
+
+// A read only mirror of the REST responses of the scheduler. The snapshot is reloaded periodically, a failed reload
+// keeps the last snapshot. The mirror runs without a scheduler: only the responses that are part of the snapshot and
+// the process information of the mirror itself are served.
+type snapshotMirror struct {
+	load     SnapshotLoader
+	interval time.Duration
+	snapshot *dao.StateSnapshotDAOInfo
+	stop     chan struct{}
+
+	sync.RWMutex
+}
+
+// Routes of the live scheduler served from the snapshot.
+func (sm *snapshotMirror) handlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/ws/v1/queues":   sm.getQueueInfo,
+		"/ws/v1/clusters": sm.getClusterInfo,
+		"/ws/v1/apps":     sm.getApplicationsInfo,
+		"/ws/v1/nodes":    sm.getNodesInfo,
+		"/ws/v1/snapshot": sm.getStateSnapshot,
+	}
+}
+
+// Routes of the live scheduler that describe the process and are served by the mirror for itself.
+var mirrorProcessRoutes = map[string]bool{
+	"/ws/v1/stack":   true,
+	"/ws/v1/metrics": true,
+}
+
+// Create a router with the same routes as the live scheduler. The routes that need the scheduler return a service
+// unavailable error.
+func (sm *snapshotMirror) newRouter() *mux.Router {
+	router := mux.NewRouter().StrictSlash(true)
+	handlers := sm.handlers()
+	for _, route := range routes {
+		var handler http.Handler
+		switch {
+		case route.Method == "GET" && handlers[route.Pattern] != nil:
+			handler = handlers[route.Pattern]
+		case route.Name == "System" || mirrorProcessRoutes[route.Pattern]:
+			handler = route.HandlerFunc
+		default:
+			handler = http.HandlerFunc(mirrorUnavailable)
+		}
+		handler = Logger(handler, route.Name)
+
+		router.
+			Methods(route.Method).
+			Path(route.Pattern).
+			Name(route.Name).
+			Handler(handler)
+	}
+	return router
+}
+
+// Load the snapshot and keep reloading it until the mirror is stopped.
+func (sm *snapshotMirror) start() {
+	sm.reload()
+	go func() {
+		ticker := time.NewTicker(sm.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sm.stop:
+				return
+			case <-ticker.C:
+				sm.reload()
+			}
+		}
+	}()
+}
+
+func (sm *snapshotMirror) reload() {
+	snapshot, err := sm.load()
+	if err != nil {
+		log.Logger().Warn("failed to load state snapshot, keeping the last snapshot",
+			zap.Error(err))
+		return
+	}
+	sm.Lock()
+	defer sm.Unlock()
+	sm.snapshot = snapshot
+	log.Logger().Debug("state snapshot loaded",
+		zap.Time("snapshotTime", time.Unix(0, snapshot.Time)))
+}
+
+// Return the current snapshot, writes an error response and returns nil if no snapshot was loaded yet.
+func (sm *snapshotMirror) getSnapshot(w http.ResponseWriter) *dao.StateSnapshotDAOInfo {
+	sm.RLock()
+	defer sm.RUnlock()
+	if sm.snapshot == nil {
+		buildJSONErrorResponse(w, "no state snapshot loaded", http.StatusServiceUnavailable)
+		return nil
+	}
+	return sm.snapshot
+}
+
+func (sm *snapshotMirror) getQueueInfo(w http.ResponseWriter, r *http.Request) {
+	snapshot := sm.getSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	writeHeaders(w)
+	for _, partitionInfo := range snapshot.Partitions {
+		if err := json.NewEncoder(w).Encode(partitionInfo); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (sm *snapshotMirror) getClusterInfo(w http.ResponseWriter, r *http.Request) {
+	snapshot := sm.getSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	writeHeaders(w)
+	for _, clusterInfo := range snapshot.Clusters {
+		clustersInfo := []dao.ClusterDAOInfo{*clusterInfo}
+		if err := json.NewEncoder(w).Encode(clustersInfo); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (sm *snapshotMirror) getApplicationsInfo(w http.ResponseWriter, r *http.Request) {
+	snapshot := sm.getSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(snapshot.Applications); err != nil {
+		panic(err)
+	}
+}
+
+func (sm *snapshotMirror) getNodesInfo(w http.ResponseWriter, r *http.Request) {
+	snapshot := sm.getSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(snapshot.Nodes); err != nil {
+		panic(err)
+	}
+}
+
+// The time in the snapshot shows how old the mirrored responses are.
+func (sm *snapshotMirror) getStateSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot := sm.getSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	writeHeaders(w)
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		panic(err)
+	}
+}
+
+func mirrorUnavailable(w http.ResponseWriter, r *http.Request) {
+	buildJSONErrorResponse(w, "read only mirror: the request must be sent to the scheduler", http.StatusServiceUnavailable)
+}
@@ -41,6 +41,12 @@ var routes = Routes{
 		"/ws/v1/queues",
 		GetQueueInfo,
 	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/queues/fairshare",
+		GetFairShareInfo,
+	},
 	Route{
 		"Cluster",
 		"GET",
@@ -59,6 +65,134 @@ var routes = Routes{
 		"/ws/v1/nodes",
 		GetNodesInfo,
 	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/snapshot",
+		GetStateSnapshot,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/nodes/allocation",
+		GetAllocationNodeInfo,
+	},
+
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/nodes/drift",
+		GetAllocationDriftInfo,
+	},
+
+	Route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/apps/kill",
+		KillApplication,
+	},
+	Route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/apps/hold",
+		HoldApplication,
+	},
+	Route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/apps/move",
+		MoveApplication,
+	},
+	Route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/apps/release",
+		ReleaseApplication,
+	},
+	Route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/partitions/pause",
+		PausePartition,
+	},
+	Route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/partitions/resume",
+		ResumePartition,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/apps/trace",
+		GetApplicationTraceInfo,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/apps/stats",
+		GetApplicationStatsInfo,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/apps/priority",
+		GetApplicationPriorityInfo,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/appgroups",
+		GetApplicationGroupsInfo,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/partitions/load",
+		GetPartitionLoadInfo,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/reservations",
+		GetReservationsInfo,
+	},
+	Route{
+		"Scheduler",
+		"POST",
+		"/ws/v1/reservations/expire",
+		ExpireReservation,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/explain",
+		GetExplainInfo,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/autoscale/events",
+		GetAutoscaleEvents,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/partitions/events",
+		GetPartitionEvents,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/replication/state",
+		GetReplicatedState,
+	},
+	Route{
+		"Scheduler",
+		"GET",
+		"/ws/v1/config",
+		GetConfigInfo,
+	},
 
 	// endpoint to retrieve goroutines info
 	Route{
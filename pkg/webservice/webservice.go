@@ -29,13 +29,16 @@ import (
 
 	"github.com/apache/incubator-yunikorn-core/pkg/cache"
 	"github.com/apache/incubator-yunikorn-core/pkg/log"
+	"github.com/apache/incubator-yunikorn-core/pkg/scheduler"
 )
 
 var gClusterInfo *cache.ClusterInfo
+var gSchedulingContext *scheduler.ClusterSchedulingContext
 
 type WebService struct {
 	httpServer  *http.Server
 	clusterInfo *cache.ClusterInfo
+	mirror      *snapshotMirror // set if the web service is a read only mirror
 	lock        sync.RWMutex
 }
 
@@ -69,7 +72,13 @@ func Logger(inner http.Handler, name string) http.Handler {
 
 // TODO we need the port to be configurable
 func (m *WebService) StartWebApp() {
-	router := NewRouter()
+	var router *mux.Router
+	if m.mirror != nil {
+		m.mirror.start()
+		router = m.mirror.newRouter()
+	} else {
+		router = NewRouter()
+	}
 	m.httpServer = &http.Server{Addr: ":9080", Handler: router}
 
 	log.Logger().Info("web-app started", zap.Int("port", 9080))
@@ -82,13 +91,30 @@ func (m *WebService) StartWebApp() {
 	}()
 }
 
-func NewWebApp(clusterInfo *cache.ClusterInfo) *WebService {
+func NewWebApp(clusterInfo *cache.ClusterInfo, schedulingContext *scheduler.ClusterSchedulingContext) *WebService {
 	m := &WebService{}
 	gClusterInfo = clusterInfo
+	gSchedulingContext = schedulingContext
 	return m
 }
 
+// Create a read only mirror of the web service that runs without a scheduler. The REST responses for the queues,
+// clusters, applications and nodes are served from the state snapshot returned by the loader, the snapshot is
+// reloaded at the interval. Requests that need the scheduler are rejected.
+func NewMirrorWebApp(load SnapshotLoader, interval time.Duration) *WebService {
+	return &WebService{
+		mirror: &snapshotMirror{
+			load:     load,
+			interval: interval,
+			stop:     make(chan struct{}),
+		},
+	}
+}
+
 func (m *WebService) StopWebApp() error {
+	if m.mirror != nil {
+		close(m.mirror.stop)
+	}
 	if m.httpServer != nil {
 		// graceful shutdown in 5 seconds
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)